@@ -0,0 +1,139 @@
+package aggregation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// Trip is the denormalized view of one ride, assembled from all of its events.
+// Timestamps are zero when the corresponding event was never seen.
+type Trip struct {
+	TripID          string
+	PassengerID     string
+	DriverID        string
+	FinalState      events.RideState
+	PickupLocation  string
+	DropoffLocation string
+	PickupZone      events.ZoneID // the pricing zone of the request; see RideRequestedPayload.Zone
+	RequestedAt     time.Time
+	AcceptedAt      time.Time
+	StartedAt       time.Time
+	CompletedAt     time.Time
+	CancelledAt     time.Time
+	DistanceKM      float64
+	FareUSD         float64
+	CancelledBy     string
+	CancelReason    string
+	lastSeen        time.Time
+}
+
+// AcceptLatency is the time from the request until a driver accepted it.
+func (t Trip) AcceptLatency() (time.Duration, bool) {
+	if t.RequestedAt.IsZero() || t.AcceptedAt.IsZero() {
+		return 0, false
+	}
+	return t.AcceptedAt.Sub(t.RequestedAt), true
+}
+
+// PickupWait is the time from the request until the trip started.
+func (t Trip) PickupWait() (time.Duration, bool) {
+	if t.RequestedAt.IsZero() || t.StartedAt.IsZero() {
+		return 0, false
+	}
+	return t.StartedAt.Sub(t.RequestedAt), true
+}
+
+// Duration is the time spent driving, from start to completion.
+func (t Trip) Duration() (time.Duration, bool) {
+	if t.StartedAt.IsZero() || t.CompletedAt.IsZero() {
+		return 0, false
+	}
+	return t.CompletedAt.Sub(t.StartedAt), true
+}
+
+// TripAssembler collects the events of in-flight trips and hands back a complete
+// Trip once a terminal event arrives.
+type TripAssembler struct {
+	mu    sync.Mutex
+	trips map[string]*Trip
+}
+
+// NewTripAssembler returns an empty TripAssembler.
+func NewTripAssembler() *TripAssembler {
+	return &TripAssembler{trips: make(map[string]*Trip)}
+}
+
+// Add applies e to its trip. When e completes or cancels the trip, the assembled
+// Trip is returned with true and its state is released.
+func (a *TripAssembler) Add(e events.RideEvent) (Trip, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	t, ok := a.trips[e.TripID]
+	if !ok {
+		t = &Trip{TripID: e.TripID}
+		a.trips[e.TripID] = t
+	}
+	if e.PassengerID != "" {
+		t.PassengerID = e.PassengerID
+	}
+	if e.DriverID != "" {
+		t.DriverID = e.DriverID
+	}
+	t.FinalState = e.State
+	t.lastSeen = time.Now()
+
+	switch p := e.Payload.(type) {
+	case events.RideRequestedPayload:
+		t.RequestedAt = e.OccurredAt
+		t.PickupLocation = p.PickupLocation
+		t.DropoffLocation = p.DropoffLocation
+		t.PickupZone = p.Zone()
+	case events.RideAcceptedPayload:
+		t.AcceptedAt = e.OccurredAt
+		if p.DriverID != "" {
+			t.DriverID = p.DriverID
+		}
+	case events.RideStartedPayload:
+		t.StartedAt = e.OccurredAt
+	case events.RideCompletedPayload:
+		t.CompletedAt = e.OccurredAt
+		t.DistanceKM = p.DistanceKM
+		t.FareUSD = p.Fare.Float64()
+	case events.RideCancelledPayload:
+		t.CancelledAt = e.OccurredAt
+		t.CancelledBy = string(p.CancelledBy)
+		t.CancelReason = string(p.Reason)
+	}
+
+	if e.Type != events.EventTripCompleted && e.Type != events.EventTripCancelled {
+		return Trip{}, false
+	}
+	delete(a.trips, e.TripID)
+	return *t, true
+}
+
+// Evict drops trips that have not received an event since before cutoff, for
+// example because their terminal event was lost, and returns how many were dropped.
+func (a *TripAssembler) Evict(cutoff time.Time) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	evicted := 0
+	for id, t := range a.trips {
+		if t.lastSeen.Before(cutoff) {
+			delete(a.trips, id)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Pending returns the number of trips still waiting for a terminal event.
+func (a *TripAssembler) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.trips)
+}
@@ -0,0 +1,86 @@
+package aggregation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestTripAssembler_CompletedTrip(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := NewTripAssembler()
+
+	lifecycle := []events.RideEvent{
+		{TripID: "trip-1", PassengerID: "rider-1", Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: base,
+			Payload: events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "A", DropoffLocation: "B",
+				Pickup: &events.Coordinate{Lat: 40.751, Lng: -73.987}}},
+		{TripID: "trip-1", DriverID: "driver-1", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: base.Add(30 * time.Second),
+			Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+		{TripID: "trip-1", Type: events.EventTripStarted, State: events.StateInProgress, OccurredAt: base.Add(5 * time.Minute),
+			Payload: events.RideStartedPayload{}},
+	}
+	for _, e := range lifecycle {
+		if _, done := a.Add(e); done {
+			t.Fatalf("trip finished early on %s", e.Type)
+		}
+	}
+
+	trip, done := a.Add(events.RideEvent{TripID: "trip-1", Type: events.EventTripCompleted, State: events.StateCompleted, OccurredAt: base.Add(20 * time.Minute),
+		Payload: events.RideCompletedPayload{DistanceKM: 12.5, Fare: events.NewMoney(1500, events.USD)}})
+	if !done {
+		t.Fatal("expected completed trip to be emitted")
+	}
+	if trip.DriverID != "driver-1" || trip.PassengerID != "rider-1" || trip.FinalState != events.StateCompleted {
+		t.Errorf("unexpected trip identity: %+v", trip)
+	}
+	if lat, ok := trip.AcceptLatency(); !ok || lat != 30*time.Second {
+		t.Errorf("expected 30s accept latency, got %v (%v)", lat, ok)
+	}
+	if wait, ok := trip.PickupWait(); !ok || wait != 5*time.Minute {
+		t.Errorf("expected 5m pickup wait, got %v (%v)", wait, ok)
+	}
+	if dur, ok := trip.Duration(); !ok || dur != 15*time.Minute {
+		t.Errorf("expected 15m duration, got %v (%v)", dur, ok)
+	}
+	if trip.DistanceKM != 12.5 || trip.FareUSD != 15 || trip.PickupLocation != "A" {
+		t.Errorf("unexpected trip details: %+v", trip)
+	}
+	if trip.PickupZone != "40.74,-74.00" {
+		t.Errorf("expected the grid cell of the pickup as zone, got %q", trip.PickupZone)
+	}
+	if a.Pending() != 0 {
+		t.Errorf("expected no pending trips, got %d", a.Pending())
+	}
+}
+
+func TestTripAssembler_CancelledTrip(t *testing.T) {
+	base := time.Now()
+	a := NewTripAssembler()
+
+	a.Add(events.RideEvent{TripID: "trip-2", Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: base,
+		Payload: events.RideRequestedPayload{Passenger: "rider-2"}})
+	trip, done := a.Add(events.RideEvent{TripID: "trip-2", Type: events.EventTripCancelled, State: events.StateCancelled, OccurredAt: base.Add(time.Minute),
+		Payload: events.RideCancelledPayload{CancelledBy: "passenger", Reason: events.ReasonPassengerNoShow}})
+	if !done {
+		t.Fatal("expected cancelled trip to be emitted")
+	}
+	if trip.CancelledBy != "passenger" || trip.CancelReason != "passenger_no_show" || trip.CancelledAt.IsZero() {
+		t.Errorf("unexpected cancellation info: %+v", trip)
+	}
+	if _, ok := trip.Duration(); ok {
+		t.Error("cancelled trip should have no duration")
+	}
+}
+
+func TestTripAssembler_EvictStaleTrips(t *testing.T) {
+	a := NewTripAssembler()
+	a.Add(events.RideEvent{TripID: "stale", Type: events.EventRideRequested, OccurredAt: time.Now()})
+
+	if n := a.Evict(time.Now().Add(time.Second)); n != 1 {
+		t.Errorf("expected 1 evicted trip, got %d", n)
+	}
+	if a.Pending() != 0 {
+		t.Errorf("expected no pending trips after eviction, got %d", a.Pending())
+	}
+}
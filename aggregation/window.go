@@ -0,0 +1,206 @@
+package aggregation
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// WindowResult is the aggregate of one event type over one tumbling window.
+// IsCorrection is set when the window had already been emitted and a late event
+// changed it, so downstream tables should overwrite the earlier row.
+type WindowResult struct {
+	Start        time.Time
+	End          time.Time
+	EventType    events.RideEventType
+	Count        int64
+	FareTotal    events.Money // in the currency of the window's first fare
+	IsCorrection bool
+}
+
+type windowKey struct {
+	start     time.Time
+	eventType events.RideEventType
+}
+
+type windowState struct {
+	result  WindowResult
+	emitted bool
+}
+
+// Config controls window sizing and how late events are treated.
+type Config struct {
+	// Size is the length of each tumbling window.
+	Size time.Duration
+	// MaxOutOfOrder is how far behind the newest event time the watermark trails.
+	// Windows are emitted once the watermark passes their end.
+	MaxOutOfOrder time.Duration
+	// Grace is how long after a window is emitted late events may still correct it.
+	// Events arriving after that are counted as dropped.
+	Grace time.Duration
+}
+
+// DefaultConfig is the windowing of ride_event_windows: windows are one minute
+// wide; events may arrive up to 30s out of order before a window is emitted, and
+// late events correct it for 5 more minutes.
+var DefaultConfig = Config{
+	Size:          time.Minute,
+	MaxOutOfOrder: 30 * time.Second,
+	Grace:         5 * time.Minute,
+}
+
+// Aggregator counts ride events per type in tumbling event-time windows using a
+// watermark and grace period, so out-of-order events land in the right window.
+type Aggregator struct {
+	mu      sync.Mutex
+	cfg     Config
+	windows map[windowKey]*windowState
+	maxSeen time.Time
+	dropped int64
+}
+
+// NewAggregator returns an Aggregator using cfg.
+func NewAggregator(cfg Config) *Aggregator {
+	return &Aggregator{cfg: cfg, windows: make(map[windowKey]*windowState)}
+}
+
+// Watermark is the event time before which the aggregator assumes all events have arrived.
+func (a *Aggregator) Watermark() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.watermark()
+}
+
+func (a *Aggregator) watermark() time.Time {
+	if a.maxSeen.IsZero() {
+		return time.Time{}
+	}
+	return a.maxSeen.Add(-a.cfg.MaxOutOfOrder)
+}
+
+// Dropped returns how many events arrived after their window's grace period expired.
+func (a *Aggregator) Dropped() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dropped
+}
+
+// Add folds e into its window and returns every result that became final or was
+// corrected as a consequence, ordered by window start and event type. The returned
+// bool is false when e was too late to be counted.
+func (a *Aggregator) Add(e events.RideEvent) ([]WindowResult, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	start := e.OccurredAt.Truncate(a.cfg.Size)
+	end := start.Add(a.cfg.Size)
+	if wm := a.watermark(); !wm.IsZero() && !end.Add(a.cfg.Grace).After(wm) {
+		a.dropped++
+		return nil, false
+	}
+
+	key := windowKey{start: start, eventType: e.Type}
+	w, ok := a.windows[key]
+	if !ok {
+		w = &windowState{result: WindowResult{Start: start, End: end, EventType: e.Type}}
+		a.windows[key] = w
+	}
+	w.result.Count++
+	if p, ok := events.PayloadAs[events.RideCompletedPayload](e); ok {
+		// A fare in another currency cannot be summed, so it is left out
+		if total, err := w.result.FareTotal.Add(p.Fare); err == nil {
+			w.result.FareTotal = total
+		}
+	}
+
+	var out []WindowResult
+	if w.emitted {
+		// Late but within grace: re-emit the window as a correction
+		corrected := w.result
+		corrected.IsCorrection = true
+		out = append(out, corrected)
+	}
+
+	if e.OccurredAt.After(a.maxSeen) {
+		a.maxSeen = e.OccurredAt
+	}
+	return append(out, a.advance()...), true
+}
+
+// Window returns the bounds of e's window and whether the aggregator holds it,
+// open or within its grace period.
+func (a *Aggregator) Window(e events.RideEvent) (start, end time.Time, held bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	start = e.OccurredAt.Truncate(a.cfg.Size)
+	_, held = a.windows[windowKey{start: start, eventType: e.Type}]
+	return start, start.Add(a.cfg.Size), held
+}
+
+// Restore puts back windows stored before a restart, so that events added to
+// them count on top of the stored totals instead of replacing them. Windows
+// the aggregator already holds, or whose grace period has expired, are left
+// alone. A restored window counts as emitted, so the events added to it
+// re-emit it as a correction.
+func (a *Aggregator) Restore(results ...WindowResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	wm := a.watermark()
+	for _, r := range results {
+		if !wm.IsZero() && !r.End.Add(a.cfg.Grace).After(wm) {
+			continue
+		}
+		key := windowKey{start: r.Start, eventType: r.EventType}
+		if _, ok := a.windows[key]; !ok {
+			a.windows[key] = &windowState{result: r, emitted: true}
+		}
+	}
+}
+
+// Flush emits every window that has not been emitted yet, regardless of the
+// watermark. It is used on shutdown so partial windows are not lost.
+func (a *Aggregator) Flush() []WindowResult {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var out []WindowResult
+	for _, w := range a.windows {
+		if !w.emitted {
+			w.emitted = true
+			out = append(out, w.result)
+		}
+	}
+	sortResults(out)
+	return out
+}
+
+// advance emits windows the watermark has passed and forgets windows whose grace
+// period has expired.
+func (a *Aggregator) advance() []WindowResult {
+	wm := a.watermark()
+	var out []WindowResult
+	for key, w := range a.windows {
+		if !w.emitted && !w.result.End.After(wm) {
+			w.emitted = true
+			out = append(out, w.result)
+		}
+		if w.emitted && !w.result.End.Add(a.cfg.Grace).After(wm) {
+			delete(a.windows, key)
+		}
+	}
+	sortResults(out)
+	return out
+}
+
+func sortResults(results []WindowResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].Start.Equal(results[j].Start) {
+			return results[i].Start.Before(results[j].Start)
+		}
+		return results[i].EventType < results[j].EventType
+	})
+}
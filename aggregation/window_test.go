@@ -0,0 +1,140 @@
+package aggregation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func eventAt(ts time.Time, typ events.RideEventType) events.RideEvent {
+	return events.RideEvent{TripID: "trip", Type: typ, OccurredAt: ts}
+}
+
+func TestAggregator_EmitsWindowWhenWatermarkPasses(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	agg := NewAggregator(Config{Size: time.Minute, MaxOutOfOrder: 10 * time.Second, Grace: 2 * time.Minute})
+
+	if out, _ := agg.Add(eventAt(base.Add(5*time.Second), events.EventRideRequested)); len(out) != 0 {
+		t.Fatalf("expected no output before watermark passes, got %+v", out)
+	}
+	agg.Add(eventAt(base.Add(30*time.Second), events.EventRideRequested))
+
+	// Watermark = 12:01:15 - 10s = 12:01:05, which closes the 12:00 window
+	out, _ := agg.Add(eventAt(base.Add(75*time.Second), events.EventRideRequested))
+	if len(out) != 1 {
+		t.Fatalf("expected 1 emitted window, got %d", len(out))
+	}
+	if out[0].Count != 2 || !out[0].Start.Equal(base) || out[0].IsCorrection {
+		t.Errorf("unexpected window result: %+v", out[0])
+	}
+}
+
+func TestAggregator_OutOfOrderWithinBoundIsNotACorrection(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	agg := NewAggregator(Config{Size: time.Minute, MaxOutOfOrder: 30 * time.Second, Grace: time.Minute})
+
+	agg.Add(eventAt(base.Add(50*time.Second), events.EventRideAccepted))
+	agg.Add(eventAt(base.Add(65*time.Second), events.EventRideAccepted))
+	// Arrives after a newer event but before the watermark closes its window
+	agg.Add(eventAt(base.Add(40*time.Second), events.EventRideAccepted))
+
+	out, _ := agg.Add(eventAt(base.Add(95*time.Second), events.EventRideAccepted))
+	if len(out) != 1 || out[0].Count != 2 || out[0].IsCorrection {
+		t.Fatalf("expected one final window with count 2, got %+v", out)
+	}
+}
+
+func TestAggregator_LateEventWithinGraceEmitsCorrection(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	agg := NewAggregator(Config{Size: time.Minute, Grace: 2 * time.Minute})
+
+	agg.Add(events.RideEvent{Type: events.EventTripCompleted, OccurredAt: base.Add(10 * time.Second), Payload: events.RideCompletedPayload{Fare: events.NewMoney(1000, events.USD)}})
+	agg.Add(eventAt(base.Add(90*time.Second), events.EventTripCompleted)) // closes 12:00
+
+	out, ok := agg.Add(events.RideEvent{Type: events.EventTripCompleted, OccurredAt: base.Add(20 * time.Second), Payload: events.RideCompletedPayload{Fare: events.NewMoney(550, events.USD)}})
+	if !ok {
+		t.Fatal("expected late event within grace to be accepted")
+	}
+	if len(out) != 1 {
+		t.Fatalf("expected 1 correction, got %+v", out)
+	}
+	got := out[0]
+	if !got.IsCorrection || got.Count != 2 || got.FareTotal != events.NewMoney(1550, events.USD) {
+		t.Errorf("unexpected correction: %+v", got)
+	}
+}
+
+func TestAggregator_DropsEventsPastGrace(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	agg := NewAggregator(Config{Size: time.Minute, Grace: time.Minute})
+
+	agg.Add(eventAt(base, events.EventTripStarted))
+	agg.Add(eventAt(base.Add(5*time.Minute), events.EventTripStarted))
+
+	out, ok := agg.Add(eventAt(base.Add(10*time.Second), events.EventTripStarted))
+	if ok || len(out) != 0 {
+		t.Errorf("expected event past grace to be dropped, got ok=%v out=%+v", ok, out)
+	}
+	if agg.Dropped() != 1 {
+		t.Errorf("expected 1 dropped event, got %d", agg.Dropped())
+	}
+}
+
+func TestAggregator_FlushEmitsOpenWindows(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	agg := NewAggregator(Config{Size: time.Minute, Grace: time.Minute})
+
+	agg.Add(eventAt(base, events.EventRideRequested))
+	agg.Add(eventAt(base, events.EventTripCancelled))
+
+	out := agg.Flush()
+	if len(out) != 2 {
+		t.Fatalf("expected 2 flushed windows, got %d", len(out))
+	}
+	if len(agg.Flush()) != 0 {
+		t.Error("expected second flush to be empty")
+	}
+}
+
+func TestAggregator_RestoreContinuesStoredWindow(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	cfg := Config{Size: time.Minute, MaxOutOfOrder: 10 * time.Second, Grace: time.Minute}
+
+	// The first process flushes the open window on shutdown
+	before := NewAggregator(cfg)
+	before.Add(eventAt(base.Add(5*time.Second), events.EventRideRequested))
+	before.Add(eventAt(base.Add(10*time.Second), events.EventRideRequested))
+	stored := before.Flush()
+
+	after := NewAggregator(cfg)
+	e := eventAt(base.Add(20*time.Second), events.EventRideRequested)
+	if start, end, held := after.Window(e); held || !start.Equal(base) || !end.Equal(base.Add(time.Minute)) {
+		t.Fatalf("Window = %v, %v, %v; want the unheld 12:00 window", start, end, held)
+	}
+	after.Restore(stored...)
+	if _, _, held := after.Window(e); !held {
+		t.Fatal("expected the restored window to be held")
+	}
+	out, _ := after.Add(e)
+	if len(out) != 1 || out[0].Count != 3 || !out[0].IsCorrection {
+		t.Fatalf("expected the stored window corrected to count 3, got %+v", out)
+	}
+
+	// Restoring again does not reset the counts
+	after.Restore(stored...)
+	if out, _ := after.Add(e); len(out) != 1 || out[0].Count != 4 {
+		t.Errorf("expected count 4 after a second restore, got %+v", out)
+	}
+}
+
+func TestAggregator_RestoreSkipsWindowsPastGrace(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	agg := NewAggregator(Config{Size: time.Minute, Grace: time.Minute})
+
+	agg.Add(eventAt(base.Add(5*time.Minute), events.EventTripStarted))
+	agg.Restore(WindowResult{Start: base, End: base.Add(time.Minute), EventType: events.EventTripStarted, Count: 2})
+	if _, _, held := agg.Window(eventAt(base, events.EventTripStarted)); held {
+		t.Error("expected a window past its grace period not to be restored")
+	}
+}
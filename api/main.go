@@ -0,0 +1,11 @@
+// Command api serves the rides database over REST, GraphQL, and gRPC; see the
+// services/api package. `rideshare api` runs the same.
+package main
+
+import (
+	"os"
+
+	"github.com/pedeveaux/kafkarideshare/services/api"
+)
+
+func main() { api.Run(os.Args[1:]) }
@@ -0,0 +1,313 @@
+// Package chaos is a TCP proxy for fault injection in tests. Put a Proxy
+// between a service and Redpanda or Postgres, point the service at the
+// proxy's address, and the test can slow the link down, cut it off, or reset
+// its connections while the service runs, to check that the pipeline's
+// retries, dead-lettering, and backpressure hold up.
+//
+// The proxy knows nothing of the protocols it carries. A Kafka client only
+// bootstraps through the address it is given and then connects to the
+// brokers the cluster advertises, so a broker behind a Proxy must advertise
+// the proxy's address; Listen the proxy first and set its upstream once the
+// broker is up.
+package chaos
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+// chunkSize is the most a link reads at a time.
+const chunkSize = 32 << 10
+
+// queued is how many chunks a direction of a link holds before it stops
+// reading, leaving the sender to TCP's flow control.
+const queued = 64
+
+// Proxy forwards the TCP connections it accepts to an upstream address,
+// with the faults set on it. Its methods are safe for concurrent use.
+type Proxy struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	upstream string
+	latency  time.Duration
+	jitter   time.Duration
+	down     bool
+	// healed is closed while the proxy is not partitioned.
+	healed chan struct{}
+	links  map[*link]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// Listen returns a Proxy listening on addr, such as "127.0.0.1:0", that
+// forwards to upstream. upstream may be empty until SetUpstream is called,
+// and connections accepted until then are reset.
+func Listen(addr, upstream string) (*Proxy, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	healed := make(chan struct{})
+	close(healed)
+	p := &Proxy{ln: ln, upstream: upstream, healed: healed, links: make(map[*link]struct{})}
+	p.wg.Add(1)
+	go p.accept()
+	return p, nil
+}
+
+// Addr returns the address the proxy listens on.
+func (p *Proxy) Addr() string { return p.ln.Addr().String() }
+
+// SetUpstream sets the address new connections are forwarded to.
+func (p *Proxy) SetUpstream(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.upstream = addr
+}
+
+// SetLatency delays everything forwarded, both ways, by latency plus up to
+// jitter. Data keeps its order, so jitter only ever adds to the delay of
+// what follows. Zero removes the delay.
+func (p *Proxy) SetLatency(latency, jitter time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency, p.jitter = latency, jitter
+}
+
+// Partition stops forwarding without closing anything, as a network
+// partition does: connections stay open and new ones are accepted, but
+// what is sent is held until Heal, or until the peers give up on it.
+func (p *Proxy) Partition() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.healed:
+		p.healed = make(chan struct{})
+	default:
+	}
+}
+
+// Heal ends a Partition, delivering what was held.
+func (p *Proxy) Heal() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.healed:
+	default:
+		close(p.healed)
+	}
+}
+
+// Down resets every open connection and every new one until Up, as when
+// the upstream has gone away.
+func (p *Proxy) Down() {
+	p.mu.Lock()
+	p.down = true
+	p.mu.Unlock()
+	p.ResetConnections()
+}
+
+// Up accepts connections again after Down.
+func (p *Proxy) Up() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = false
+}
+
+// ResetConnections resets the open connections, both ways, and returns how
+// many there were. New ones are accepted as before.
+func (p *Proxy) ResetConnections() int {
+	p.mu.Lock()
+	links := make([]*link, 0, len(p.links))
+	for l := range p.links {
+		links = append(links, l)
+	}
+	p.mu.Unlock()
+	for _, l := range links {
+		l.reset()
+	}
+	return len(links)
+}
+
+// Conns returns how many connections are open through the proxy.
+func (p *Proxy) Conns() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.links)
+}
+
+// Close stops listening, resets the open connections, and waits for them to
+// finish.
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	err := p.ln.Close()
+	p.ResetConnections()
+	p.wg.Wait()
+	return err
+}
+
+func (p *Proxy) accept() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Warn("Chaos proxy stopped accepting", "addr", p.Addr(), "error", err)
+			}
+			return
+		}
+		p.mu.Lock()
+		down, upstream, closed := p.down, p.upstream, p.closed
+		p.mu.Unlock()
+		if down || upstream == "" || closed {
+			resetConn(conn)
+			continue
+		}
+		p.wg.Add(1)
+		go p.forward(conn, upstream)
+	}
+}
+
+// forward links client to upstream until either side closes or the link is
+// reset.
+func (p *Proxy) forward(client net.Conn, upstream string) {
+	defer p.wg.Done()
+	server, err := net.DialTimeout("tcp", upstream, 5*time.Second)
+	if err != nil {
+		slog.Debug("Chaos proxy could not reach upstream", "upstream", upstream, "error", err)
+		resetConn(client)
+		return
+	}
+	l := &link{client: client, server: server, done: make(chan struct{})}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		l.reset()
+		return
+	}
+	p.links[l] = struct{}{}
+	p.mu.Unlock()
+
+	var pipes sync.WaitGroup
+	pipes.Add(2)
+	go func() { defer pipes.Done(); p.pipe(l, server, client) }()
+	go func() { defer pipes.Done(); p.pipe(l, client, server) }()
+	pipes.Wait()
+	l.close()
+
+	p.mu.Lock()
+	delete(p.links, l)
+	p.mu.Unlock()
+}
+
+type chunk struct {
+	data []byte
+	due  time.Time
+}
+
+// pipe copies src to dst through a queue, delaying and holding each chunk as
+// the proxy's faults say. When src ends, dst's write side is closed once the
+// queue has drained; when either fails, the whole link is closed.
+func (p *Proxy) pipe(l *link, dst, src net.Conn) {
+	q := make(chan chunk, queued)
+	go func() {
+		defer close(q)
+		var last time.Time
+		for {
+			buf := make([]byte, chunkSize)
+			n, err := src.Read(buf)
+			if n > 0 {
+				p.mu.Lock()
+				due := time.Now().Add(p.latency)
+				if p.jitter > 0 {
+					due = due.Add(rand.N(p.jitter))
+				}
+				p.mu.Unlock()
+				if due.Before(last) {
+					due = last
+				}
+				last = due
+				select {
+				case q <- chunk{buf[:n], due}:
+				case <-l.done:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for c := range q {
+		if wait := time.Until(c.due); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-l.done:
+				timer.Stop()
+				return
+			}
+		}
+		p.mu.Lock()
+		healed := p.healed
+		p.mu.Unlock()
+		select {
+		case <-healed:
+		case <-l.done:
+			return
+		}
+		if _, err := dst.Write(c.data); err != nil {
+			l.close()
+			return
+		}
+	}
+	select {
+	case <-l.done:
+	default:
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+			return
+		}
+		l.close()
+	}
+}
+
+// link is a client connection and its upstream connection.
+type link struct {
+	client, server net.Conn
+	once           sync.Once
+	done           chan struct{}
+}
+
+func (l *link) close() {
+	l.once.Do(func() {
+		close(l.done)
+		l.client.Close()
+		l.server.Close()
+	})
+}
+
+// reset closes both connections with a TCP reset rather than a FIN.
+func (l *link) reset() {
+	l.once.Do(func() {
+		close(l.done)
+		resetConn(l.client)
+		resetConn(l.server)
+	})
+}
+
+// resetConn closes conn so that its peer sees a reset.
+func resetConn(conn net.Conn) {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
@@ -0,0 +1,190 @@
+package chaos
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// echoServer answers each line it reads with the line, until the test ends.
+func echoServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func newProxy(t *testing.T, upstream string) *Proxy {
+	t.Helper()
+	p, err := Listen("127.0.0.1:0", upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// client is a connection through a proxy that sends and reads back lines.
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	err  error // from dialing, which a proxy that is down may reset
+}
+
+func dial(t *testing.T, p *Proxy) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", p.Addr())
+	if err != nil {
+		return &client{err: err}
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &client{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// echo sends line and returns what came back, or the error reading it
+// within timeout.
+func (c *client) echo(line string, timeout time.Duration) (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := io.WriteString(c.conn, line+"\n"); err != nil {
+		return "", err
+	}
+	got, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return got[:len(got)-1], nil
+}
+
+func TestProxy_Forwards(t *testing.T) {
+	p := newProxy(t, echoServer(t))
+	c := dial(t, p)
+	for _, line := range []string{"one", "two"} {
+		if got, err := c.echo(line, time.Second); got != line || err != nil {
+			t.Errorf("echo %q = %q, %v", line, got, err)
+		}
+	}
+	if n := p.Conns(); n != 1 {
+		t.Errorf("conns = %d, want 1", n)
+	}
+}
+
+func TestProxy_Latency(t *testing.T) {
+	p := newProxy(t, echoServer(t))
+	c := dial(t, p)
+	p.SetLatency(50*time.Millisecond, 10*time.Millisecond)
+	start := time.Now()
+	if _, err := c.echo("slow", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// Delayed on the way there and on the way back
+	if took := time.Since(start); took < 100*time.Millisecond {
+		t.Errorf("round trip took %s, want at least 100ms", took)
+	}
+
+	p.SetLatency(0, 0)
+	start = time.Now()
+	if _, err := c.echo("fast", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took > 50*time.Millisecond {
+		t.Errorf("round trip took %s without latency", took)
+	}
+}
+
+func TestProxy_PartitionAndHeal(t *testing.T) {
+	p := newProxy(t, echoServer(t))
+	c := dial(t, p)
+	if _, err := c.echo("before", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Partition()
+	var timeout net.Error
+	if _, err := c.echo("held", 100*time.Millisecond); !errors.As(err, &timeout) || !timeout.Timeout() {
+		t.Fatalf("echo during a partition = %v, want a timeout", err)
+	}
+	// A new connection is accepted but goes nowhere either
+	if _, err := dial(t, p).echo("new", 100*time.Millisecond); err == nil {
+		t.Error("a new connection got through the partition")
+	}
+
+	p.Heal()
+	c.conn.SetDeadline(time.Now().Add(time.Second))
+	if got, err := c.r.ReadString('\n'); got != "held\n" || err != nil {
+		t.Errorf("after healing read %q, %v, want what was held", got, err)
+	}
+	if got, err := c.echo("after", time.Second); got != "after" || err != nil {
+		t.Errorf("echo after healing = %q, %v", got, err)
+	}
+}
+
+func TestProxy_ResetConnections(t *testing.T) {
+	p := newProxy(t, echoServer(t))
+	c := dial(t, p)
+	if _, err := c.echo("hello", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.ResetConnections(); n != 1 {
+		t.Errorf("reset %d connections, want 1", n)
+	}
+	if _, err := c.echo("gone", time.Second); !errors.Is(err, syscall.ECONNRESET) && !errors.Is(err, io.EOF) {
+		t.Errorf("echo after a reset = %v, want a reset", err)
+	}
+	if got, err := dial(t, p).echo("again", time.Second); got != "again" || err != nil {
+		t.Errorf("echo on a new connection = %q, %v", got, err)
+	}
+}
+
+func TestProxy_DownAndUp(t *testing.T) {
+	p := newProxy(t, echoServer(t))
+	c := dial(t, p)
+	if _, err := c.echo("hello", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Down()
+	if _, err := c.echo("open", time.Second); err == nil {
+		t.Error("an open connection survived Down")
+	}
+	if _, err := dial(t, p).echo("new", time.Second); err == nil {
+		t.Error("a new connection got through while down")
+	}
+
+	p.Up()
+	if got, err := dial(t, p).echo("back", time.Second); got != "back" || err != nil {
+		t.Errorf("echo after Up = %q, %v", got, err)
+	}
+}
+
+func TestProxy_SetUpstream(t *testing.T) {
+	p := newProxy(t, "")
+	if _, err := dial(t, p).echo("nowhere", time.Second); err == nil {
+		t.Error("a connection got through without an upstream")
+	}
+	p.SetUpstream(echoServer(t))
+	if got, err := dial(t, p).echo("somewhere", time.Second); got != "somewhere" || err != nil {
+		t.Errorf("echo = %q, %v", got, err)
+	}
+}
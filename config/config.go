@@ -0,0 +1,274 @@
+// Package config reads the tunable settings of a service from a YAML file and
+// reloads them while it runs, so a long simulation can be slowed down, sped
+// up, or made to log more without a restart.
+//
+// Settings are named as their environment variables are, and the file may
+// nest them: log.level and log_level both set LOG_LEVEL. The environment,
+// with .env loaded into it, takes precedence over the file, so a setting the
+// file is to tune must be left out of both.
+//
+// Watch follows the file with fsnotify. It watches the file's directory as well
+// as the file, so it follows a Kubernetes ConfigMap mounted as a volume, which
+// is updated by swapping a symlink, as well as a file bind-mounted by Compose
+// or edited in place.
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// settleDelay is how long Watch waits for the events of a change to the file
+// to stop before reloading it, as editors and the kubelet make a change in
+// several steps.
+const settleDelay = 100 * time.Millisecond
+
+type subscription struct {
+	keys []string
+	fn   func()
+}
+
+// Config is the settings of a file, looked up under the environment. Its
+// methods are safe for concurrent use.
+type Config struct {
+	path string
+
+	mu     sync.Mutex
+	data   []byte
+	values map[string]string
+	subs   []subscription
+}
+
+// Load reads the settings of the YAML file at path. An empty path gives a
+// Config of the environment alone.
+func Load(path string) (*Config, error) {
+	c := &Config{path: path}
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if c.values, err = Parse(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.data = data
+	return c, nil
+}
+
+// Path returns the path of the file, or "" without one.
+func (c *Config) Path() string { return c.path }
+
+// Lookup returns the value of the setting key: that of the environment when
+// it is set and not empty, or else the file's.
+func (c *Config) Lookup(key string) (string, bool) {
+	if v := os.Getenv(key); v != "" {
+		return v, true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// Get returns the value of the setting key, or "" when it is not set.
+func (c *Config) Get(key string) string {
+	v, _ := c.Lookup(key)
+	return v
+}
+
+// OnChange calls fn after each reload that changes the value of any of keys,
+// as Lookup returns it; a change to a setting the environment overrides is
+// not one. fn runs on the goroutine that reloaded, so services that are not
+// safe for concurrent use should have it signal their own goroutine.
+func (c *Config) OnChange(fn func(), keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs = append(c.subs, subscription{keys: keys, fn: fn})
+}
+
+// Reload reads the file again and, if it changed, applies its settings and
+// calls the OnChange functions of those that changed, which it returns. A
+// file that cannot be read or parsed leaves the settings as they were.
+func (c *Config) Reload() ([]string, error) {
+	if c.path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if bytes.Equal(data, c.data) {
+		c.mu.Unlock()
+		return nil, nil
+	}
+	values, err := Parse(data)
+	if err != nil {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%s: %w", c.path, err)
+	}
+	old := c.values
+	c.data, c.values = data, values
+	subs := slices.Clone(c.subs)
+	c.mu.Unlock()
+
+	var changed []string
+	for _, key := range slices.Sorted(maps.Keys(unionKeys(old, values))) {
+		if os.Getenv(key) == "" && !sameValue(old, values, key) {
+			changed = append(changed, key)
+		}
+	}
+	for _, s := range subs {
+		if slices.ContainsFunc(s.keys, func(k string) bool { return slices.Contains(changed, k) }) {
+			s.fn()
+		}
+	}
+	return changed, nil
+}
+
+// Watch reloads the file whenever it changes, until ctx is cancelled, logging
+// the settings that changed and any failure to reload. It returns an error
+// only when it cannot watch the file.
+func (c *Config) Watch(ctx context.Context) error {
+	if c.path == "" {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	// The directory, for the file being replaced, and the file, for writes to
+	// it through another path, as through a bind mount
+	if err := w.Add(filepath.Dir(c.path)); err != nil {
+		return err
+	}
+	if err := w.Add(c.path); err != nil {
+		return err
+	}
+
+	settle := time.NewTimer(settleDelay)
+	settle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("Config file watch failed", "path", c.path, "error", err)
+		case _, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			settle.Reset(settleDelay)
+		case <-settle.C:
+			// Watch the file again, in case it was replaced: the watch goes with it
+			if err := w.Add(c.path); err != nil {
+				slog.Warn("Failed to watch the new config file", "path", c.path, "error", err)
+			}
+			changed, err := c.Reload()
+			if err != nil {
+				slog.Warn("Failed to reload config, keeping the settings as they were", "path", c.path, "error", err)
+				continue
+			}
+			if len(changed) > 0 {
+				slog.Info("Reloaded config", "path", c.path, "changed", changed)
+			}
+		}
+	}
+}
+
+// Parse returns the settings of a YAML document, flattening nested mappings
+// into the names of environment variables: keys are joined with underscores
+// and upper-cased, and dashes become underscores. Lists of scalars become
+// comma-separated values.
+func Parse(data []byte) (map[string]string, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	if err := flatten(values, "", doc); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flatten(values map[string]string, prefix string, m map[string]any) error {
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		name := strings.ToUpper(strings.ReplaceAll(k, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		switch v := m[k].(type) {
+		case map[string]any:
+			if err := flatten(values, name, v); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				s, err := scalar(item)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				items[i] = s
+			}
+			values[name] = strings.Join(items, ",")
+		default:
+			s, err := scalar(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			values[name] = s
+		}
+	}
+	return nil
+}
+
+func scalar(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case map[string]any, []any:
+		return "", errors.New("lists may only hold plain values")
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+func unionKeys(a, b map[string]string) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
+
+func sameValue(a, b map[string]string, key string) bool {
+	va, oka := a[key]
+	vb, okb := b[key]
+	return oka == okb && va == vb
+}
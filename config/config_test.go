@@ -0,0 +1,207 @@
+package config
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	got, err := Parse([]byte(`
+tick_interval: 500ms
+max-rides: 20
+log:
+  level: debug
+  levels: [matcher=debug, "rideconsumer=warn"]
+outbox:
+  batch_size: 50
+surge_multiplier: 1.5
+dry_run: true
+empty:
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"TICK_INTERVAL":     "500ms",
+		"MAX_RIDES":         "20",
+		"LOG_LEVEL":         "debug",
+		"LOG_LEVELS":        "matcher=debug,rideconsumer=warn",
+		"OUTBOX_BATCH_SIZE": "50",
+		"SURGE_MULTIPLIER":  "1.5",
+		"DRY_RUN":           "true",
+		"EMPTY":             "",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("Parse = %v, want %v", got, want)
+	}
+
+	if _, err := Parse([]byte("brokers: [{host: a}]")); err == nil {
+		t.Error("Parse of a list of mappings succeeded, want an error")
+	}
+	if _, err := Parse([]byte("- a\n- b")); err == nil {
+		t.Error("Parse of a top-level list succeeded, want an error")
+	}
+	if got, err := Parse([]byte("# nothing yet\n")); err != nil || len(got) != 0 {
+		t.Errorf("Parse of a comment = %v, %v, want no settings", got, err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "tick_interval: 2s\nmax_rides: 5\n")
+	t.Setenv("MAX_RIDES", "9")
+	t.Setenv("TICK_INTERVAL", "")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("TICK_INTERVAL"); got != "2s" {
+		t.Errorf("TICK_INTERVAL = %q, want the file's 2s", got)
+	}
+	if got := c.Get("MAX_RIDES"); got != "9" {
+		t.Errorf("MAX_RIDES = %q, want the environment's 9", got)
+	}
+	if _, ok := c.Lookup("CONFIG_TEST_UNSET"); ok {
+		t.Error("Lookup of an unset key reported it set")
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load of a missing file succeeded, want an error")
+	}
+	empty, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := empty.Reload(); err != nil || changed != nil {
+		t.Errorf("Reload without a file = %v, %v", changed, err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "tick_interval: 1s\nmax_rides: 5\nlog:\n  level: info\n")
+	t.Setenv("MAX_RIDES", "9")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ticks, logs int
+	c.OnChange(func() { ticks++ }, "TICK_INTERVAL", "MAX_RIDES")
+	c.OnChange(func() { logs++ }, "LOG_LEVEL")
+
+	// MAX_RIDES is the environment's, so changing it in the file changes nothing
+	writeFile(t, path, "tick_interval: 250ms\nmax_rides: 7\nlog:\n  level: info\n")
+	changed, err := c.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changed, []string{"TICK_INTERVAL"}) {
+		t.Errorf("changed = %v, want [TICK_INTERVAL]", changed)
+	}
+	if ticks != 1 || logs != 0 {
+		t.Errorf("OnChange calls = %d ticks, %d logs, want 1 and 0", ticks, logs)
+	}
+	if got := c.Get("TICK_INTERVAL"); got != "250ms" {
+		t.Errorf("TICK_INTERVAL = %q after reload, want 250ms", got)
+	}
+
+	if changed, err := c.Reload(); err != nil || changed != nil {
+		t.Errorf("Reload of an unchanged file = %v, %v", changed, err)
+	}
+
+	writeFile(t, path, "tick_interval: [")
+	if _, err := c.Reload(); err == nil {
+		t.Error("Reload of invalid YAML succeeded, want an error")
+	}
+	if got := c.Get("TICK_INTERVAL"); got != "250ms" {
+		t.Errorf("TICK_INTERVAL = %q after a bad reload, want it kept", got)
+	}
+
+	// A removed setting is a change, back to the default
+	writeFile(t, path, "tick_interval: 250ms\n")
+	if changed, _ := c.Reload(); !slices.Equal(changed, []string{"LOG_LEVEL"}) {
+		t.Errorf("changed = %v, want [LOG_LEVEL]", changed)
+	}
+	if logs != 1 {
+		t.Errorf("LOG_LEVEL OnChange calls = %d, want 1", logs)
+	}
+}
+
+// TestWatch swaps the file for another, as Kubernetes updates a mounted
+// ConfigMap, and waits for Watch to pick it up.
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, filepath.Join(dir, "v1.yaml"), "tick_interval: 1s\n")
+	if err := os.Symlink("v1.yaml", path); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := make(chan struct{}, 1)
+	c.OnChange(func() { reloaded <- struct{}{} }, "TICK_INTERVAL")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx)
+	time.Sleep(50 * time.Millisecond) // for the watch to be set up
+
+	writeFile(t, filepath.Join(dir, "v2.yaml"), "tick_interval: 3s\n")
+	if err := os.Symlink("v2.yaml", filepath.Join(dir, "next")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "next"), path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not pick up the new file")
+	}
+	if got := c.Get("TICK_INTERVAL"); got != "3s" {
+		t.Errorf("TICK_INTERVAL = %q, want 3s", got)
+	}
+}
+
+// TestWatch_InPlace writes the file in place, as through a bind mount.
+func TestWatch_InPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "outbox:\n  batch_size: 100\n")
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := make(chan struct{}, 1)
+	c.OnChange(func() { reloaded <- struct{}{} }, "OUTBOX_BATCH_SIZE")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	writeFile(t, path, "outbox:\n  batch_size: 20\n")
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not pick up the write")
+	}
+	if got := c.Get("OUTBOX_BATCH_SIZE"); got != "20" {
+		t.Errorf("OUTBOX_BATCH_SIZE = %q, want 20", got)
+	}
+}
@@ -0,0 +1,11 @@
+// Command dashboard serves a live operations page; see the services/dashboard
+// package. `rideshare dashboard` runs the same.
+package main
+
+import (
+	"os"
+
+	"github.com/pedeveaux/kafkarideshare/services/dashboard"
+)
+
+func main() { dashboard.Run(os.Args[1:]) }
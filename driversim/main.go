@@ -0,0 +1,135 @@
+// Command driversim simulates the drivers of the ride platform. It puts a
+// fleet on shift, answers the matcher's offers to its drivers by each
+// driver's profile, and drives the rides they accept, sending their location
+// updates and the STARTED and COMPLETED events. Run it with the matcher in
+// offer mode (MATCH_OFFERS=true) and ridersim.
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+const (
+	defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID        = "driversim"
+)
+
+// fleetFromEnv reads the simulation settings: FLEET_SIZE, the number of
+// drivers (default 50); DRIVERSIM_SPEEDUP, how many times faster than real
+// time they drive (default 10); TICK_INTERVAL, how often they move and report
+// (default 1s); and CITIES, as the producer reads it. Invalid values are
+// logged and left to the defaults. DRIVERSIM_SPEEDUP and TICK_INTERVAL are
+// read again when the config file changes them.
+func fleetFromEnv() (size int, speedup float64, tick time.Duration, cities []string) {
+	return runtime.EnvInt("FLEET_SIZE", 50),
+		runtime.EnvFloat("DRIVERSIM_SPEEDUP", 10),
+		runtime.EnvDuration("TICK_INTERVAL", time.Second),
+		runtime.EnvList("CITIES")
+}
+
+func main() {
+	svc := runtime.Start("driversim")
+	defer svc.Stop()
+	slog.Info("Starting driver simulator")
+	instance, _ := os.Hostname()
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		logger.Fatal("Failed to create producer", "error", err)
+	}
+	logger.OnShutdown("kafka producer", func(context.Context) error {
+		if n := producer.Flush(5000); n > 0 {
+			slog.Warn("Driver events left undelivered", "count", n)
+		}
+		producer.Close()
+		return nil
+	})
+	logger.Go("delivery reports", func() {
+		for e := range producer.Events() {
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				slog.Error("Delivery failed", "key", string(m.Key), "error", m.TopicPartition.Error)
+			}
+		}
+	})
+
+	// The fleet starts fresh each run, so offers made before it are not its own
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          groupID,
+		"auto.offset.reset": "latest",
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error { return consumer.Close() })
+	if err := consumer.SubscribeTopics([]string{topics.DispatchOffers, topics.RideEvents}, nil); err != nil {
+		logger.Fatal("Failed to subscribe", "error", err)
+	}
+
+	size, speedup, tick, cities := fleetFromEnv()
+	sim := NewSim(size, cities, producer, instance, speedup, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	if err := sim.StartShifts(time.Now()); err != nil {
+		logger.Fatal("Failed to start shifts", "error", err)
+	}
+	// Registered after the producer, so it runs first and the events are flushed
+	logger.OnShutdown("driver shifts", func(context.Context) error { return sim.EndShifts(time.Now()) })
+	slog.Info("Drivers on shift", "drivers", size, "cities", cities, "speedup", speedup)
+
+	ctx := svc.Context()
+
+	// Changes from the config file are applied between ticks
+	reload := make(chan struct{}, 1)
+	svc.OnChange(func() {
+		select {
+		case reload <- struct{}{}:
+		default:
+		}
+	}, "DRIVERSIM_SPEEDUP", "TICK_INTERVAL")
+
+	logger.Go("drive", func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reload:
+				_, speedup, tick, _ = fleetFromEnv()
+				sim.SetSpeedup(speedup)
+				ticker.Reset(tick)
+				slog.Info("Applied new drive settings", "speedup", speedup, "tick_interval", tick.String())
+			case <-ticker.C:
+				if err := sim.Tick(time.Now(), tick); err != nil {
+					slog.Error("Failed to send driver events", "error", err)
+				}
+			}
+		}
+	})
+
+	for ctx.Err() == nil {
+		msg, err := consumer.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if !errors.As(err, &kerr) || kerr.Code() != kafka.ErrTimedOut {
+				slog.Error("Consumer error", "error", err)
+			}
+			continue
+		}
+		if err := sim.Handle(msg, time.Now()); err != nil {
+			slog.Error("Failed to answer offer", "key", string(msg.Key), "error", err)
+		}
+	}
+	slog.Info("Driver simulator stopped")
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/google/uuid"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/matching"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/ridesim"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+// Profile is how a simulated driver answers offers and drives.
+type Profile struct {
+	Name string
+	// AcceptRate is the chance the driver takes an offer they are free for
+	// and near enough to.
+	AcceptRate float64
+	// MaxPickupM is how far, in meters, the driver will drive to a pickup.
+	MaxPickupM float64
+	SpeedKPH   float64
+}
+
+// profiles are given out to drivers at random.
+var profiles = []Profile{
+	{Name: "eager", AcceptRate: 0.95, MaxPickupM: 8000, SpeedKPH: 35},
+	{Name: "steady", AcceptRate: 0.8, MaxPickupM: 5000, SpeedKPH: 30},
+	{Name: "picky", AcceptRate: 0.5, MaxPickupM: 2000, SpeedKPH: 25},
+}
+
+// trip is the ride a driver took, from their acceptance until it ends.
+type trip struct {
+	ID            string
+	PassengerID   string
+	CorrelationID string
+	LastEventID   string
+	Route         events.Route // pickup, then dropoff
+	// Multiplier is the surge the ride was quoted at, which the fare is
+	// charged at
+	Multiplier float64
+	// State is ACCEPTED while the driver heads to the pickup, DRIVER_ARRIVED
+	// while they wait there, and IN_PROGRESS once the trip started.
+	State events.RideState
+}
+
+// driver is a simulated driver on shift.
+type driver struct {
+	ID       string
+	City     string
+	Profile  Profile
+	Location events.Coordinate
+	Trip     *trip
+}
+
+// Sim is a fleet of simulated drivers. It answers the matcher's offers to its
+// drivers, and drives the rides they take: it reports their location on the
+// ride topic along the way, arrives at the pickup, picks the passenger up and
+// starts the trip, and completes it at the dropoff. It is safe for concurrent
+// use.
+type Sim struct {
+	sink     rideconsumer.Sink
+	instance string
+	// surges follows SURGE_UPDATED events, to charge surge fares on rides
+	// that were not quoted
+	surges *pricing.Surges
+
+	mu sync.Mutex
+	// quotes holds the multiplier of each PRICE_QUOTE until its ride ends,
+	// which the pricer checks the fare against
+	quotes map[string]float64
+	// speedup is how many times faster than the clock the drivers drive
+	speedup float64
+	rand    *rand.Rand
+	drivers []*driver
+	byID    map[string]*driver
+}
+
+// NewSim returns a fleet of size drivers at random places, spread across
+// cities as the producer spreads rides, that send their events to sink.
+func NewSim(size int, cities []string, sink rideconsumer.Sink, instance string, speedup float64, r *rand.Rand) *Sim {
+	s := &Sim{sink: sink, instance: instance, speedup: speedup, surges: pricing.NewSurges(), quotes: make(map[string]float64), rand: r, byID: make(map[string]*driver)}
+	for range size {
+		d := &driver{
+			ID:       uuid.NewString(),
+			Profile:  profiles[r.IntN(len(profiles))],
+			Location: ridesim.RandomCoordinate(),
+		}
+		if len(cities) > 0 {
+			d.City = cities[r.IntN(len(cities))]
+		}
+		s.drivers = append(s.drivers, d)
+		s.byID[d.ID] = d
+	}
+	return s
+}
+
+// SetSpeedup changes how many times faster than the clock the drivers drive,
+// from the next Tick on.
+func (s *Sim) SetSpeedup(speedup float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.speedup = speedup
+}
+
+// StartShifts sends a SHIFT_STARTED event for every driver.
+func (s *Sim) StartShifts(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, d := range s.drivers {
+		errs = append(errs, s.publishDriver(d, events.EventShiftStarted, now,
+			events.ShiftStartedPayload{VehicleID: "car-" + d.ID[:8], Location: d.Location}))
+	}
+	return errors.Join(errs...)
+}
+
+// EndShifts sends a SHIFT_ENDED event for every driver.
+func (s *Sim) EndShifts(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, d := range s.drivers {
+		errs = append(errs, s.publishDriver(d, events.EventShiftEnded, now, events.ShiftEndedPayload{}))
+	}
+	return errors.Join(errs...)
+}
+
+// Handle processes a message from the offer or the ride topic: it answers
+// offers to the fleet's drivers, follows the surge of each zone and the quote
+// of each ride, and frees drivers whose ride was cancelled.
+// Messages that cannot be decoded are logged and skipped; the error is from
+// sending an answer.
+func (s *Sim) Handle(msg *kafka.Message, now time.Time) error {
+	switch topic := *msg.TopicPartition.Topic; topic {
+	case topics.DispatchOffers:
+		var o matching.Offer
+		if err := json.Unmarshal(msg.Value, &o); err != nil {
+			slog.Warn("Skipping undecodable offer", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		return s.answer(o, now)
+	case topics.RideEvents:
+		var e events.RideEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		switch e.Type {
+		case events.EventSurgeUpdated:
+			s.surges.Apply(e)
+		case events.EventPriceQuoted:
+			s.quoted(e)
+		case events.EventTripCompleted:
+			s.ended(e.TripID)
+		case events.EventTripCancelled:
+			s.cancelled(e)
+		}
+		return nil
+	default:
+		return fmt.Errorf("driversim: message from unexpected topic %q", topic)
+	}
+}
+
+// answer decides whether the driver of an open offer takes it, by their
+// profile, and sends the ACCEPTED event or the declined offer. Offers to
+// other fleets' drivers, and expired ones, are ignored. A ride taken is
+// charged at the multiplier it was quoted at, or else at the surge of its
+// pickup zone now.
+func (s *Sim) answer(o matching.Offer, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.byID[o.DriverID]
+	if !ok || o.Status != matching.OfferOpen || now.After(o.ExpiresAt) {
+		return nil
+	}
+	var reason string
+	switch {
+	case d.Trip != nil:
+		reason = matching.DeclineBusy
+	case o.DistanceM > d.Profile.MaxPickupM:
+		reason = matching.DeclineTooFar
+	case s.rand.Float64() >= d.Profile.AcceptRate:
+		reason = matching.DeclineRejected
+	}
+	if reason != "" {
+		slog.Info("Declined offer", "tripID", o.TripID, "driverID", d.ID, "profile", d.Profile.Name, "reason", reason)
+		msg, err := o.Decline(reason).Message(topics.DispatchOffers)
+		if err != nil {
+			return err
+		}
+		return s.sink.Produce(msg, nil)
+	}
+
+	accepted := o.Accept(now, s.instance)
+	pickup, dropoff, multiplier := d.Location, ridesim.RandomCoordinate(), 1.0
+	if o.Pickup != nil {
+		pickup, multiplier = *o.Pickup, s.surges.Multiplier(pricing.ZoneOf(*o.Pickup))
+	}
+	if o.Dropoff != nil {
+		dropoff = *o.Dropoff
+	}
+	if quoted, ok := s.quotes[o.TripID]; ok {
+		multiplier = quoted
+	}
+	d.Trip = &trip{
+		ID:            o.TripID,
+		PassengerID:   o.PassengerID,
+		CorrelationID: accepted.Meta.CorrelationID,
+		LastEventID:   accepted.ID,
+		Route:         events.Route{pickup, dropoff},
+		Multiplier:    multiplier,
+		State:         events.StateAccepted,
+	}
+	slog.Info("Accepted offer", "tripID", o.TripID, "driverID", d.ID, "profile", d.Profile.Name)
+	return ridesim.PublishRide(s.sink, topics.RideEvents, accepted)
+}
+
+// quoted records the multiplier of a PRICE_QUOTE event.
+func (s *Sim) quoted(e events.RideEvent) {
+	p, ok := events.PayloadAs[events.PriceQuotePayload](e)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotes[e.TripID] = max(p.Multiplier, 1)
+}
+
+// ended forgets the quote of a ride that ended.
+func (s *Sim) ended(tripID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.quotes, tripID)
+}
+
+// cancelled frees the driver of a cancelled ride.
+func (s *Sim) cancelled(e events.RideEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.quotes, e.TripID)
+	for _, d := range s.drivers {
+		if d.Trip != nil && d.Trip.ID == e.TripID {
+			d.Trip = nil
+			return
+		}
+	}
+}
+
+// Tick moves every driver on a ride on by elapsed, sped up, and sends their
+// location, then a heartbeat for every driver. A driver reaching the pickup
+// arrives, picks the passenger up a tick later and starts the trip, and one
+// reaching the dropoff completes it and is free.
+func (s *Sim) Tick(now time.Time, elapsed time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, d := range s.drivers {
+		if d.Trip != nil {
+			errs = append(errs, s.drive(d, now, elapsed))
+		}
+		errs = append(errs, s.publishDriver(d, events.EventHeartbeat, now,
+			events.HeartbeatPayload{Location: d.Location, Available: d.Trip == nil}))
+	}
+	return errors.Join(errs...)
+}
+
+// drive moves d along their trip. s.mu is held.
+func (s *Sim) drive(d *driver, now time.Time, elapsed time.Duration) error {
+	t := d.Trip
+	if t.State == events.StateDriverArrived {
+		at := d.Location
+		t.State = events.StateInProgress
+		if err := s.publishRide(d, events.NewPickedUp(t.ID, append(s.options(d, now), events.WithLocation(&at))...)); err != nil {
+			return err
+		}
+		return s.publishRide(d, events.NewTripStarted(t.ID, s.options(d, now)...))
+	}
+
+	target := t.Route[0]
+	if t.State == events.StateInProgress {
+		target = t.Route[1]
+	}
+	meters := d.Profile.SpeedKPH * 1000 / 3600 * elapsed.Seconds() * s.speedup
+	heading := ridesim.Heading(d.Location, target)
+	var arrived bool
+	d.Location, arrived = ridesim.Toward(d.Location, target, meters)
+	if err := s.publishRide(d, events.NewLocationUpdated(t.ID, t.State, d.Location, heading, d.Profile.SpeedKPH, s.options(d, now)...)); err != nil {
+		return err
+	}
+	if !arrived {
+		return nil
+	}
+
+	if t.State == events.StateAccepted {
+		at := d.Location
+		t.State = events.StateDriverArrived
+		return s.publishRide(d, events.NewDriverArrived(t.ID, d.ID, append(s.options(d, now), events.WithLocation(&at))...))
+	}
+	distance := ridesim.TripDistance(t.Route)
+	completed := events.NewTripCompleted(t.ID, distance, ridesim.Fare(distance, t.Multiplier), s.options(d, now)...)
+	d.Trip = nil
+	delete(s.quotes, t.ID)
+	return ridesim.PublishRide(s.sink, topics.RideEvents, completed)
+}
+
+// options returns the options of an event of d's trip at now, caused by the
+// trip's last event.
+func (s *Sim) options(d *driver, now time.Time) []events.Option {
+	t := d.Trip
+	return []events.Option{
+		events.WithTime(now),
+		events.WithDriver(d.ID),
+		events.WithPassenger(t.PassengerID),
+		events.WithCity(d.City),
+		events.WithMeta(events.Meta{CorrelationID: t.CorrelationID, CausationID: t.LastEventID, ProducerInstance: s.instance}),
+	}
+}
+
+// publishRide sends an event of d's trip, which becomes the cause of the next.
+func (s *Sim) publishRide(d *driver, e events.RideEvent) error {
+	if e.Type.IsLifecycle() {
+		d.Trip.LastEventID = e.ID
+	}
+	return ridesim.PublishRide(s.sink, topics.RideEvents, e)
+}
+
+// publishDriver sends a driver event of type typ for d.
+func (s *Sim) publishDriver(d *driver, typ events.DriverEventType, now time.Time, payload events.DriverEventPayload) error {
+	return ridesim.PublishDriver(s.sink, topics.DriverEvents, events.DriverEvent{
+		ID:         uuid.NewString(),
+		DriverID:   d.ID,
+		Type:       typ,
+		OccurredAt: now,
+		City:       d.City,
+		Payload:    payload,
+		Meta:       events.Meta{CorrelationID: d.ID, ProducerInstance: s.instance},
+	})
+}
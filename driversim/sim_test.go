@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/matching"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/ridesim"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// offerTo returns an offer of trip tripID to the driver, from distanceM away.
+func offerTo(t *testing.T, d *driver, tripID string, distanceM float64) *kafka.Message {
+	t.Helper()
+	pickup := d.Location
+	dropoff := events.Coordinate{Lat: pickup.Lat + 0.01, Lng: pickup.Lng}
+	o := matching.Offer{
+		ID:          tripID + "-offer",
+		Status:      matching.OfferOpen,
+		TripID:      tripID,
+		DriverID:    d.ID,
+		PassengerID: "rider-1",
+		Pickup:      &pickup,
+		Dropoff:     &dropoff,
+		DistanceM:   distanceM,
+		OfferedAt:   now,
+		ExpiresAt:   now.Add(time.Minute),
+		Meta:        events.Meta{CorrelationID: tripID, CausationID: "request-1"},
+	}
+	msg, err := o.Message(topics.DispatchOffers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// newSim returns a one-driver fleet with the given profile.
+func newSim(t *testing.T, p Profile) (*Sim, *driver, *ridetest.Broker) {
+	t.Helper()
+	broker := ridetest.NewBroker()
+	s := NewSim(1, nil, broker, "driversim-1", 1, rand.New(rand.NewPCG(1, 1)))
+	d := s.drivers[0]
+	d.Profile = p
+	return s, d, broker
+}
+
+func TestSim_DrivesAcceptedRide(t *testing.T) {
+	s, d, broker := newSim(t, Profile{Name: "test", AcceptRate: 1, MaxPickupM: 5000, SpeedKPH: 36})
+	// The ride is quoted at 1.5x, and the fare is charged at it even though
+	// the pickup zone surges further before the offer
+	quote := events.NewPriceQuoted("trip-1", events.PriceQuotePayload{QuoteID: "quote-1", Multiplier: 1.5}, events.WithTime(now))
+	surge := events.NewSurgeUpdated(pricing.ZoneOf(d.Location), 2, events.WithTime(now))
+	for _, e := range []events.RideEvent{quote, surge} {
+		if err := s.Handle(ridetest.Message(t, topics.RideEvents, e), now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Handle(offerTo(t, d, "trip-1", 100), now); err != nil {
+		t.Fatal(err)
+	}
+	// At 36 km/h the driver covers 10m a second, and the trip is about 1.1km
+	for i := 1; d.Trip != nil && i < 200; i++ {
+		if err := s.Tick(now.Add(time.Duration(i)*10*time.Second), 10*time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d.Trip != nil {
+		t.Fatal("ride did not complete")
+	}
+
+	evts, err := broker.Events(topics.RideEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lifecycle []events.RideEventType
+	updates := 0
+	for i, e := range evts {
+		if err := e.Validate(); err != nil {
+			t.Errorf("event %d: %v", i, err)
+		}
+		if e.DriverID != d.ID || e.Meta.CorrelationID != "trip-1" {
+			t.Errorf("event %d: driver %q, meta %+v", i, e.DriverID, e.Meta)
+		}
+		if e.Type == events.EventLocationUpdated {
+			updates++
+		} else {
+			lifecycle = append(lifecycle, e.Type)
+		}
+	}
+	want := []events.RideEventType{events.EventRideAccepted, events.EventDriverArrived, events.EventPickedUp,
+		events.EventTripStarted, events.EventTripCompleted}
+	if !slices.Equal(lifecycle, want) {
+		t.Errorf("lifecycle = %v, want %v", lifecycle, want)
+	}
+	if updates < 10 {
+		t.Errorf("%d location updates, want one a tick", updates)
+	}
+	if evts[0].Meta.CausationID != "request-1" {
+		t.Errorf("acceptance caused by %q, want the request", evts[0].Meta.CausationID)
+	}
+	if p, _ := events.PayloadAs[events.RideCompletedPayload](evts[len(evts)-1]); p.DistanceKM == 0 || p.Fare != ridesim.Fare(p.DistanceKM, 1.5) {
+		t.Errorf("completed: %+v, want the fare at the quoted 1.5x", p)
+	}
+	if len(s.quotes) != 0 {
+		t.Errorf("quotes = %v, want the completed ride's forgotten", s.quotes)
+	}
+
+	// Heartbeats report the driver busy during the ride and free after it
+	var hb events.DriverEvent
+	msgs := broker.Messages(topics.DriverEvents)
+	if err := json.Unmarshal(msgs[len(msgs)-1].Value, &hb); err != nil {
+		t.Fatal(err)
+	}
+	if p := hb.Payload.(events.HeartbeatPayload); !p.Available {
+		t.Error("driver still busy after the ride")
+	}
+}
+
+func TestSim_DeclinesByProfile(t *testing.T) {
+	s, d, broker := newSim(t, Profile{Name: "picky", AcceptRate: 1, MaxPickupM: 1000, SpeedKPH: 30})
+	declined := func() matching.Offer {
+		t.Helper()
+		msgs := broker.Messages(topics.DispatchOffers)
+		var o matching.Offer
+		if err := json.Unmarshal(msgs[len(msgs)-1].Value, &o); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	if err := s.Handle(offerTo(t, d, "trip-1", 3000), now); err != nil {
+		t.Fatal(err)
+	}
+	if o := declined(); o.Status != matching.OfferDeclined || o.Reason != matching.DeclineTooFar {
+		t.Errorf("far offer: got %+v", o)
+	}
+
+	if err := s.Handle(offerTo(t, d, "trip-2", 100), now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Handle(offerTo(t, d, "trip-3", 100), now); err != nil {
+		t.Fatal(err)
+	}
+	if o := declined(); o.TripID != "trip-3" || o.Reason != matching.DeclineBusy {
+		t.Errorf("offer while busy: got %+v", o)
+	}
+
+	// A cancelled ride frees the driver
+	cancelled := events.NewTripCancelled("trip-2", events.ActorPassenger, events.ReasonChangedPlans, events.WithTime(now))
+	if err := s.Handle(ridetest.Message(t, topics.RideEvents, cancelled), now); err != nil {
+		t.Fatal(err)
+	}
+	if d.Trip != nil {
+		t.Error("driver still on the cancelled ride")
+	}
+
+	// Expired offers and offers to other drivers are not answered
+	n := len(broker.Messages(topics.DispatchOffers))
+	if err := s.Handle(offerTo(t, d, "trip-4", 100), now.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Handle(offerTo(t, &driver{ID: "someone-else"}, "trip-5", 100), now); err != nil {
+		t.Fatal(err)
+	}
+	if len(broker.Messages(topics.DispatchOffers)) != n || d.Trip != nil {
+		t.Error("answered an expired offer or another fleet's")
+	}
+}
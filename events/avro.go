@@ -0,0 +1,331 @@
+package events
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// RideEventAvroSchema is the canonical Avro schema of a RideEvent, the source of
+// truth for the Schema Registry. Each built-in payload is a branch of the payload
+// union, a record named after its payload_type. Payloads added with
+// RegisterPayload have no branch, so events carrying them cannot be encoded.
+//
+// Changes must stay backward compatible, so that consumers on the new schema
+// can read events written with the old: add fields only with defaults, and
+// never change or remove a field's type.
+//
+//go:embed ride_event.avsc
+var RideEventAvroSchema []byte
+
+var rideEventAvro = sync.OnceValues(func() (*avroSchema, error) {
+	return newAvroSchema(RideEventAvroSchema)
+})
+
+// MarshalAvro encodes e in the Avro binary encoding of RideEventAvroSchema,
+// without a Schema Registry header.
+func MarshalAvro(e RideEvent) ([]byte, error) {
+	s, err := rideEventAvro()
+	if err != nil {
+		return nil, err
+	}
+	return s.marshal(e)
+}
+
+// UnmarshalAvro decodes an event MarshalAvro encoded. It reads only the current
+// RideEventAvroSchema; use an AvroReader for events written with another
+// version of it, as named by their Schema Registry header.
+func UnmarshalAvro(data []byte) (RideEvent, error) {
+	s, err := rideEventAvro()
+	if err != nil {
+		return RideEvent{}, err
+	}
+	return s.unmarshal(data)
+}
+
+// AvroReader decodes events written with one version of RideEventAvroSchema,
+// earlier or later than this build's. Events are decoded with the writer's
+// schema into the JSON encoding of RideEvent, so fields the writer did not
+// know take their zero values and fields this build does not know are
+// dropped, as when reading JSON events.
+type AvroReader struct {
+	s *avroSchema
+}
+
+// NewAvroReader returns a reader of events written with schema.
+func NewAvroReader(schema []byte) (*AvroReader, error) {
+	s, err := newAvroSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("events: parse Avro schema: %w", err)
+	}
+	return &AvroReader{s: s}, nil
+}
+
+// Unmarshal decodes an event written with the reader's schema.
+func (r *AvroReader) Unmarshal(data []byte) (RideEvent, error) {
+	return r.s.unmarshal(data)
+}
+
+// avroSchema converts between the JSON encoding of RideEvent, decoded into maps,
+// and the native values goavro encodes, following the parsed schema.
+type avroSchema struct {
+	codec *goavro.Codec
+	root  any
+	named map[string]map[string]any // named types by short and full name
+	full  map[string]string         // full names of named types by short name
+}
+
+func newAvroSchema(schema []byte) (*avroSchema, error) {
+	codec, err := goavro.NewCodec(string(schema))
+	if err != nil {
+		return nil, err
+	}
+	s := &avroSchema{codec: codec, named: map[string]map[string]any{}, full: map[string]string{}}
+	if err := json.Unmarshal(schema, &s.root); err != nil {
+		return nil, err
+	}
+	s.collect(s.root, "")
+	return s, nil
+}
+
+// marshal encodes e with s.
+func (s *avroSchema) marshal(e RideEvent) ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	native, err := s.toNative(s.root, doc)
+	if err != nil {
+		return nil, fmt.Errorf("events: encode %s as Avro: %w", e.Type, err)
+	}
+	return s.codec.BinaryFromNative(nil, native)
+}
+
+// unmarshal decodes an event encoded with s.
+func (s *avroSchema) unmarshal(data []byte) (RideEvent, error) {
+	var e RideEvent
+	native, rest, err := s.codec.NativeFromBinary(data)
+	if err != nil {
+		return e, err
+	}
+	if len(rest) > 0 {
+		return e, fmt.Errorf("events: %d bytes after the Avro event", len(rest))
+	}
+	doc, err := s.fromNative(s.root, native)
+	if err != nil {
+		return e, err
+	}
+	data, err = json.Marshal(doc)
+	if err != nil {
+		return e, err
+	}
+	err = json.Unmarshal(data, &e)
+	return e, err
+}
+
+// collect records the named types declared in t.
+func (s *avroSchema) collect(t any, namespace string) {
+	switch t := t.(type) {
+	case []any:
+		for _, branch := range t {
+			s.collect(branch, namespace)
+		}
+	case map[string]any:
+		name, _ := t["name"].(string)
+		if ns, ok := t["namespace"].(string); ok {
+			namespace = ns
+		}
+		if name != "" {
+			s.named[name] = t
+			s.named[namespace+"."+name] = t
+			s.full[name] = namespace + "." + name
+		}
+		if fields, ok := t["fields"].([]any); ok {
+			for _, f := range fields {
+				s.collect(f.(map[string]any)["type"], namespace)
+			}
+		}
+	}
+}
+
+// resolve returns the definition of t when it names a declared type.
+func (s *avroSchema) resolve(t any) any {
+	if name, ok := t.(string); ok {
+		if def, ok := s.named[name]; ok {
+			return def
+		}
+	}
+	return t
+}
+
+// typeName returns the name goavro keys union branches of type t by.
+func (s *avroSchema) typeName(t any) string {
+	switch t := s.resolve(t).(type) {
+	case string:
+		return t
+	case map[string]any:
+		if name, ok := t["name"].(string); ok {
+			return s.full[name]
+		}
+		return t["type"].(string) + "." + t["logicalType"].(string)
+	}
+	return ""
+}
+
+func (s *avroSchema) toNative(t, v any) (any, error) {
+	t = s.resolve(t)
+	switch t := t.(type) {
+	case string:
+		return primitiveToNative(t, v)
+	case []any:
+		var want string
+		if hint, ok := v.(payloadHint); ok {
+			want, _ = hint.name.(string)
+			v = hint.value
+		}
+		if v == nil {
+			return nil, nil
+		}
+		branch, err := s.unionBranch(t, want)
+		if err != nil {
+			return nil, err
+		}
+		native, err := s.toNative(branch, v)
+		if err != nil {
+			return nil, err
+		}
+		return goavro.Union(s.typeName(branch), native), nil
+	case map[string]any:
+		if t["logicalType"] == "timestamp-micros" {
+			str, _ := v.(string)
+			return time.Parse(time.RFC3339Nano, str)
+		}
+		doc, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s is %T, not an object", t["name"], v)
+		}
+		record := map[string]any{}
+		for _, f := range t["fields"].([]any) {
+			field := f.(map[string]any)
+			name := field["name"].(string)
+			fv, ok := doc[name]
+			if !ok {
+				if fv, ok = field["default"]; !ok {
+					return nil, fmt.Errorf("%s.%s is required", t["name"], name)
+				}
+			}
+			if name == "payload" {
+				// Pick the branch payload_type names
+				fv = payloadHint{doc["payload_type"], fv}
+			}
+			native, err := s.toNative(field["type"], fv)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			record[name] = native
+		}
+		return record, nil
+	}
+	return nil, fmt.Errorf("unsupported Avro type %v", t)
+}
+
+// payloadHint carries the payload_type of an event to the payload union.
+type payloadHint struct {
+	name  any
+	value any
+}
+
+// unionBranch returns the branch of union t that a value is encoded as: the
+// record named want, or when want is empty, the only branch that is not null.
+func (s *avroSchema) unionBranch(t []any, want string) (any, error) {
+	var branches []any
+	for _, branch := range t {
+		if branch != "null" {
+			branches = append(branches, branch)
+		}
+	}
+	if want == "" && len(branches) == 1 {
+		return branches[0], nil
+	}
+	for _, branch := range branches {
+		if def, ok := s.resolve(branch).(map[string]any); ok && def["name"] == want {
+			return branch, nil
+		}
+	}
+	return nil, fmt.Errorf("no Avro record for payload type %q", want)
+}
+
+func primitiveToNative(t string, v any) (any, error) {
+	switch t {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a string", v)
+		}
+		return str, nil
+	case "int", "long", "double":
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a number", v)
+		}
+		switch t {
+		case "int":
+			return int32(f), nil
+		case "long":
+			return int64(f), nil
+		}
+		return f, nil
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a boolean", v)
+		}
+		return b, nil
+	case "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported Avro type %s", t)
+}
+
+func (s *avroSchema) fromNative(t, v any) (any, error) {
+	t = s.resolve(t)
+	switch t := t.(type) {
+	case []any:
+		union, ok := v.(map[string]any)
+		if v == nil || !ok {
+			return nil, nil
+		}
+		for _, branch := range t {
+			if value, ok := union[s.typeName(branch)]; ok {
+				return s.fromNative(branch, value)
+			}
+		}
+		return nil, fmt.Errorf("unknown union branch in %v", union)
+	case map[string]any:
+		if t["logicalType"] == "timestamp-micros" {
+			ts, _ := v.(time.Time)
+			return ts.Format(time.RFC3339Nano), nil
+		}
+		record, _ := v.(map[string]any)
+		doc := map[string]any{}
+		for _, f := range t["fields"].([]any) {
+			field := f.(map[string]any)
+			name := field["name"].(string)
+			value, err := s.fromNative(field["type"], record[name])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			doc[name] = value
+		}
+		return doc, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,246 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAvro_RoundTrip(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 123456000, time.UTC)
+	at := &Coordinate{Lat: 40.75, Lng: -73.98}
+	cases := []RideEvent{
+		{ID: "id1", TripID: "trip1", Type: EventRideRequested, OccurredAt: now, State: StateRequested, PassengerID: "rider-1", City: "nyc",
+			Payload: RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St", Pickup: at}},
+		{ID: "id2", TripID: "trip1", Type: EventRideAccepted, OccurredAt: now, State: StateAccepted, DriverID: "driver-1",
+			Payload: RideAcceptedPayload{DriverID: "driver-1"}},
+		{ID: "id3", TripID: "trip1", Type: EventDriverArrived, OccurredAt: now, State: StateDriverArrived,
+			Payload: DriverArrivedPayload{DriverID: "driver-1", Location: at}},
+		{ID: "id4", TripID: "trip1", Type: EventPickedUp, OccurredAt: now, State: StatePickedUp, Payload: PickedUpPayload{PickupTime: now}},
+		{ID: "id5", TripID: "trip1", Type: EventTripStarted, OccurredAt: now, State: StateInProgress, Payload: RideStartedPayload{StartTime: now}},
+		{ID: "id6", TripID: "trip1", Type: EventLocationUpdated, OccurredAt: now, State: StateInProgress,
+			Payload: LocationUpdatedPayload{Location: *at, HeadingDeg: 87.5, SpeedKPH: 31, AccuracyM: 4}},
+		{ID: "id7", TripID: "trip1", Type: EventTripCompleted, OccurredAt: now, State: StateCompleted,
+			Payload: RideCompletedPayload{EndTime: now, DistanceKM: 5.2, Fare: NewMoney(1875, USD)}},
+		{ID: "id8", TripID: "trip1", Type: EventPaymentProcessed, OccurredAt: now, State: StateCompleted,
+			Payload: PaymentPayload{PaymentID: "pay-1", Amount: NewMoney(1875, USD), Method: "card", Status: "captured"}},
+		{ID: "id9", TripID: "trip1", Type: EventRideRated, OccurredAt: now, State: StateCompleted,
+			Payload: RatingPayload{RatedBy: "passenger", Stars: 5, Comment: "great"}},
+		{ID: "id10", TripID: "trip1", Type: EventTipAdded, OccurredAt: now, State: StateCompleted, Payload: TipPayload{DriverID: "driver-1", Amount: NewMoney(300, USD)}},
+		{ID: "id11", TripID: "trip2", Type: EventTripCancelled, OccurredAt: now, State: StateCancelled,
+			Payload: RideCancelledPayload{CancelledBy: "driver", Reason: ReasonDriverNoShow}},
+		{ID: "id12", Type: EventSurgeUpdated, OccurredAt: now, Payload: SurgeUpdatedPayload{ZoneID: "midtown", Multiplier: 1.4}},
+		{ID: "id16", TripID: "trip4", Type: EventPriceQuoted, OccurredAt: now, State: StateNew,
+			Payload: PriceQuotePayload{QuoteID: "quote-1", ZoneID: "midtown", Multiplier: 1.5, DistanceKM: 5.2,
+				Breakdown: FareBreakdown{Base: NewMoney(250, USD), Distance: NewMoney(520, USD), Time: NewMoney(400, USD)},
+				Total:     NewMoney(1755, USD), ExpiresAt: now.Add(QuoteTTL)}},
+		{ID: "id13", TripID: "trip3", Type: EventTripStarted, OccurredAt: now, State: StateInProgress},
+		{ID: "id14", TripID: "trip3", Type: EventTripStarted, OccurredAt: now, State: StateInProgress, Payload: RideStartedPayload{StartTime: now},
+			Meta: Meta{CorrelationID: "trip3", CausationID: "id13", ProducerInstance: "producer-1"}},
+		{ID: "id15", TripID: "trip3", Type: EventTripStarted, OccurredAt: now, State: StateInProgress, RecordedAt: now.Add(1500 * time.Millisecond)},
+	}
+	for _, want := range cases {
+		t.Run(want.ID, func(t *testing.T) {
+			data, err := MarshalAvro(want)
+			if err != nil {
+				t.Fatalf("MarshalAvro failed: %v", err)
+			}
+			got, err := UnmarshalAvro(data)
+			if err != nil {
+				t.Fatalf("UnmarshalAvro failed: %v", err)
+			}
+			want.SchemaVersion = SchemaVersion
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip changed the event:\n got %#v\nwant %#v", got, want)
+			}
+		})
+	}
+
+	if _, err := MarshalAvro(RideEvent{ID: "id1", Type: eventRated, OccurredAt: now, Payload: ratedPayload{Stars: 4}}); err == nil {
+		t.Error("expected a registered payload without an Avro record to fail")
+	}
+}
+
+func TestAvroReader_EarlierSchema(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "avro", "ride_event.5.avsc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, err := newAvroSchema(data)
+	if err != nil {
+		t.Fatalf("parse Avro schema: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	want := RideEvent{ID: "id1", TripID: "trip1", Type: EventTripCancelled, OccurredAt: now, State: StateCancelled,
+		Payload: RideCancelledPayload{CancelledBy: "driver", Reason: ReasonDriverNoShow}}
+	encoded, err := writer.marshal(want)
+	if err != nil {
+		t.Fatalf("marshal with the earlier schema failed: %v", err)
+	}
+
+	r, err := NewAvroReader(data)
+	if err != nil {
+		t.Fatalf("NewAvroReader failed: %v", err)
+	}
+	got, err := r.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want.SchemaVersion = SchemaVersion
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+
+	if _, err := NewAvroReader([]byte(`{"type": "record"}`)); err == nil {
+		t.Error("expected an invalid schema to fail")
+	}
+}
+
+func TestAvro_SchemaCoversPayloads(t *testing.T) {
+	s, err := rideEventAvro()
+	if err != nil {
+		t.Fatalf("parse Avro schema: %v", err)
+	}
+	payloadsMu.RLock()
+	defer payloadsMu.RUnlock()
+	for typ, newPayload := range payloads {
+		if strings.HasPrefix(string(typ), "TEST_") {
+			continue
+		}
+		name := PayloadTypeName(newPayload())
+		record, ok := s.named[name]
+		if !ok {
+			t.Errorf("no Avro record for %s", name)
+			continue
+		}
+		var avroFields []string
+		for _, f := range record["fields"].([]any) {
+			avroFields = append(avroFields, f.(map[string]any)["name"].(string))
+		}
+		var jsonFields []string
+		for field := range objectSchema(reflect.TypeOf(newPayload()).Elem())["properties"].(map[string]any) {
+			jsonFields = append(jsonFields, field)
+		}
+		slices.Sort(avroFields)
+		slices.Sort(jsonFields)
+		if !slices.Equal(avroFields, jsonFields) {
+			t.Errorf("%s has Avro fields %v, want the JSON fields %v", name, avroFields, jsonFields)
+		}
+	}
+}
+
+// TestAvro_BackwardCompatible checks that the current schema can read events
+// written with every earlier one. Before changing ride_event.avsc, copy it into
+// testdata/avro as the next ride_event.N.avsc.
+func TestAvro_BackwardCompatible(t *testing.T) {
+	current, err := newAvroSchema(RideEventAvroSchema)
+	if err != nil {
+		t.Fatalf("parse Avro schema: %v", err)
+	}
+	paths, err := filepath.Glob(filepath.Join("testdata", "avro", "*.avsc"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no earlier schemas in testdata/avro: %v", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		old, err := newAvroSchema(data)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		for _, problem := range avroReadable(current, old, current.root, old.root, "RideEvent") {
+			t.Errorf("cannot read events written with %s: %s", filepath.Base(path), problem)
+		}
+	}
+
+	// A field added without a default breaks old events
+	var changed map[string]any
+	if err := json.Unmarshal(RideEventAvroSchema, &changed); err != nil {
+		t.Fatal(err)
+	}
+	changed["fields"] = append(changed["fields"].([]any), map[string]any{"name": "fleet_id", "type": "string"})
+	data, _ := json.Marshal(changed)
+	reader, err := newAvroSchema(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := avroReadable(reader, current, reader.root, current.root, "RideEvent"); len(problems) != 1 {
+		t.Errorf("expected the field without a default to be reported, got %v", problems)
+	}
+}
+
+// avroReadable returns why data of writer type w cannot be read as reader type
+// r under Avro schema resolution, covering the types RideEventAvroSchema uses.
+func avroReadable(rs, ws *avroSchema, r, w any, path string) []string {
+	r, w = rs.resolve(r), ws.resolve(w)
+	if wu, ok := w.([]any); ok {
+		var problems []string
+		for _, branch := range wu {
+			problems = append(problems, avroReadable(rs, ws, r, branch, path)...)
+		}
+		return problems
+	}
+	if ru, ok := r.([]any); ok {
+		for _, branch := range ru {
+			if len(avroReadable(rs, ws, branch, w, path)) == 0 {
+				return nil
+			}
+		}
+		return []string{path + ": no branch of the reader's union reads " + ws.typeName(w)}
+	}
+
+	rm, rIsMap := r.(map[string]any)
+	wm, wIsMap := w.(map[string]any)
+	if rIsMap && wIsMap && rm["type"] == "record" && wm["type"] == "record" {
+		if rm["name"] != wm["name"] {
+			return []string{path + ": record " + wm["name"].(string) + " is read as " + rm["name"].(string)}
+		}
+		written := map[string]any{}
+		for _, f := range wm["fields"].([]any) {
+			field := f.(map[string]any)
+			written[field["name"].(string)] = field["type"]
+		}
+		var problems []string
+		for _, f := range rm["fields"].([]any) {
+			field := f.(map[string]any)
+			name := field["name"].(string)
+			if wt, ok := written[name]; ok {
+				problems = append(problems, avroReadable(rs, ws, field["type"], wt, path+"."+name)...)
+			} else if _, ok := field["default"]; !ok {
+				problems = append(problems, path+"."+name+": added without a default")
+			}
+		}
+		return problems
+	}
+
+	// Primitives, and logical types by their underlying type
+	primitive := func(t any) any {
+		if m, ok := t.(map[string]any); ok && m["type"] != "record" {
+			return m["type"]
+		}
+		return t
+	}
+	promotions := map[string][]string{
+		"int":    {"int", "long", "float", "double"},
+		"long":   {"long", "float", "double"},
+		"float":  {"float", "double"},
+		"string": {"string", "bytes"},
+		"bytes":  {"bytes", "string"},
+	}
+	rp, wp := primitive(r), primitive(w)
+	if rp == wp {
+		return nil
+	}
+	if wName, ok := wp.(string); ok && slices.Contains(promotions[wName], fmt.Sprint(rp)) {
+		return nil
+	}
+	return []string{path + ": " + ws.typeName(w) + " is read as " + rs.typeName(r)}
+}
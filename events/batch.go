@@ -0,0 +1,101 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// BatchVersion is the version of the RideEventBatch encoding this package writes.
+const BatchVersion = 1
+
+// Message header keys of batches. HeaderBatch marks a message whose value is a
+// RideEventBatch rather than a single RideEvent, and holds its BatchVersion.
+// HeaderBatchIndex holds the position in its batch of an event a consumer
+// unpacked into a message of its own.
+const (
+	HeaderBatch      = "ride-event-batch"
+	HeaderBatchIndex = "ride-event-batch-index"
+)
+
+// ErrNotBatch is returned when decoding a batch from a value that is not one,
+// such as a single RideEvent.
+var ErrNotBatch = errors.New("events: not a ride event batch")
+
+// RideEventBatch packs several events into one message, so high-throughput
+// producers pay the per-message overhead of Kafka once per batch. It encodes
+// as {"batch_version":1,"events":[...]}, with each event in the RideEvent
+// encoding, and is sent with the HeaderBatch header (see BatchHeaders).
+// Consumers unpack it with SplitBatch and handle its events one at a time.
+type RideEventBatch struct {
+	Events []RideEvent
+}
+
+type batchJSON[T any] struct {
+	BatchVersion int `json:"batch_version"`
+	Events       []T `json:"events"`
+}
+
+// MarshalJSON encodes b stamped with BatchVersion.
+func (b RideEventBatch) MarshalJSON() ([]byte, error) {
+	events := b.Events
+	if events == nil {
+		events = []RideEvent{}
+	}
+	return json.Marshal(batchJSON[RideEvent]{BatchVersion: BatchVersion, Events: events})
+}
+
+// UnmarshalJSON decodes a batch, decoding each event like RideEvent.UnmarshalJSON.
+func (b *RideEventBatch) UnmarshalJSON(data []byte) error {
+	return b.unmarshal(data, false)
+}
+
+// UnmarshalBatchStrict decodes data into b, decoding each event with
+// UnmarshalStrict.
+func UnmarshalBatchStrict(data []byte, b *RideEventBatch) error {
+	return b.unmarshal(data, true)
+}
+
+func (b *RideEventBatch) unmarshal(data []byte, strict bool) error {
+	raws, err := SplitBatch(data)
+	if err != nil {
+		return err
+	}
+	b.Events = make([]RideEvent, len(raws))
+	for i, raw := range raws {
+		if err := b.Events[i].unmarshal(raw, strict); err != nil {
+			return fmt.Errorf("events: batch event %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// SplitBatch returns the events of the batch encoded in data without decoding
+// them, so consumers can validate and decode each on its own. It fails with
+// ErrNotBatch if data has no batch_version, and rejects versions newer than
+// BatchVersion.
+func SplitBatch(data []byte) ([]json.RawMessage, error) {
+	var aux batchJSON[json.RawMessage]
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return nil, err
+	}
+	switch {
+	case aux.BatchVersion == 0:
+		return nil, ErrNotBatch
+	case aux.BatchVersion > BatchVersion:
+		return nil, fmt.Errorf("events: unknown batch version %d", aux.BatchVersion)
+	}
+	return aux.Events, nil
+}
+
+// BatchHeaders returns the message headers a batch is sent with.
+func BatchHeaders() map[string]string {
+	return map[string]string{HeaderBatch: strconv.Itoa(BatchVersion)}
+}
+
+// IsBatch reports whether message headers mark the value as a RideEventBatch.
+func IsBatch(headers map[string]string) bool {
+	_, ok := headers[HeaderBatch]
+	return ok
+}
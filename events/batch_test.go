@@ -0,0 +1,84 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRideEventBatch_RoundTrip(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	batch := RideEventBatch{Events: []RideEvent{
+		NewRideAccepted("trip-1", "driver-1", WithTime(at)),
+		NewTripCompleted("trip-2", 3.2, NewMoney(570, USD), WithTime(at)),
+	}}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"batch_version":1,"events":[{`) {
+		t.Errorf("unexpected encoding: %s", data)
+	}
+
+	var got RideEventBatch
+	if err := UnmarshalBatchStrict(data, &got); err != nil {
+		t.Fatalf("UnmarshalBatchStrict failed: %v", err)
+	}
+	if len(got.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(got.Events))
+	}
+	for i, e := range got.Events {
+		if e.SchemaVersion != SchemaVersion {
+			t.Errorf("event %d: SchemaVersion = %d, want %d", i, e.SchemaVersion, SchemaVersion)
+		}
+		if d := Diff(e, batch.Events[i]); len(d) != 0 {
+			t.Errorf("event %d differs: %v", i, d)
+		}
+	}
+
+	empty, err := json.Marshal(RideEventBatch{})
+	if err != nil || string(empty) != `{"batch_version":1,"events":[]}` {
+		t.Errorf("empty batch encoded as %s, %v", empty, err)
+	}
+}
+
+func TestSplitBatch(t *testing.T) {
+	raws, err := SplitBatch([]byte(`{"batch_version":1,"events":[{"id":"a"},{"id":"b"}]}`))
+	if err != nil {
+		t.Fatalf("SplitBatch failed: %v", err)
+	}
+	want := []json.RawMessage{json.RawMessage(`{"id":"a"}`), json.RawMessage(`{"id":"b"}`)}
+	if !reflect.DeepEqual(raws, want) {
+		t.Errorf("SplitBatch = %s, want %s", raws, want)
+	}
+
+	single, _ := json.Marshal(NewRideAccepted("trip-1", "driver-1"))
+	if _, err := SplitBatch(single); !errors.Is(err, ErrNotBatch) {
+		t.Errorf("expected ErrNotBatch for a single event, got %v", err)
+	}
+	if _, err := SplitBatch([]byte(`{"batch_version":2,"events":[]}`)); err == nil {
+		t.Error("expected an unknown batch version to fail")
+	}
+
+	var b RideEventBatch
+	err = json.Unmarshal([]byte(`{"batch_version":1,"events":[{"event_type":"ACCEPTED","payload":{"driver_id":"d"}},{"event_type":"ACCEPTED","extra":1}]}`), &b)
+	if err != nil {
+		t.Errorf("expected lenient decoding to succeed, got %v", err)
+	}
+	err = UnmarshalBatchStrict([]byte(`{"batch_version":1,"events":[{"event_type":"ACCEPTED","payload":{"driver_id":"d"}},{"event_type":"ACCEPTED","extra":1}]}`), &b)
+	if err == nil || !strings.Contains(err.Error(), "batch event 1") {
+		t.Errorf("expected strict decoding to fail on event 1, got %v", err)
+	}
+}
+
+func TestIsBatch(t *testing.T) {
+	if !IsBatch(BatchHeaders()) {
+		t.Error("expected BatchHeaders to mark a batch")
+	}
+	if IsBatch(NewRideAccepted("trip-1", "driver-1").Meta.Headers()) {
+		t.Error("expected event headers not to mark a batch")
+	}
+}
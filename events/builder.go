@@ -0,0 +1,206 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Option sets an optional field of an event built by one of the New
+// constructors. Options that set payload fields do nothing for events of other
+// types.
+type Option func(*RideEvent)
+
+// WithID sets the event ID in place of a new random UUID, for example to
+// rebuild an event whose ID is already known.
+func WithID(id string) Option {
+	return func(e *RideEvent) { e.ID = id }
+}
+
+// WithTime sets the event time in place of the current time. Payload times that
+// mark the event itself, such as a completed ride's end time, follow it.
+func WithTime(t time.Time) Option {
+	return func(e *RideEvent) { e.OccurredAt = t }
+}
+
+// WithDriver sets the driver of the ride.
+func WithDriver(driverID string) Option {
+	return func(e *RideEvent) { e.DriverID = driverID }
+}
+
+// WithPassenger sets the passenger of the ride.
+func WithPassenger(passengerID string) Option {
+	return func(e *RideEvent) { e.PassengerID = passengerID }
+}
+
+// WithCity sets the city of the ride in multi-city mode.
+func WithCity(city string) Option {
+	return func(e *RideEvent) { e.City = city }
+}
+
+// WithState sets the ride state of an event that does not change it, such as a
+// payment, in place of the default.
+func WithState(state RideState) Option {
+	return func(e *RideEvent) { e.State = state }
+}
+
+// WithCoordinates sets the pickup and dropoff coordinates of a REQUESTED event.
+func WithCoordinates(pickup, dropoff *Coordinate) Option {
+	return func(e *RideEvent) {
+		if p, ok := e.Payload.(RideRequestedPayload); ok {
+			p.Pickup, p.Dropoff = pickup, dropoff
+			e.Payload = p
+		}
+	}
+}
+
+// WithLocation sets where a DRIVER_ARRIVED or PICKED_UP event happened.
+func WithLocation(at *Coordinate) Option {
+	return func(e *RideEvent) {
+		switch p := e.Payload.(type) {
+		case DriverArrivedPayload:
+			p.Location = at
+			e.Payload = p
+		case PickedUpPayload:
+			p.Location = at
+			e.Payload = p
+		}
+	}
+}
+
+// WithZoneName sets the display name of the zone of a SURGE_UPDATED event.
+func WithZoneName(name string) Option {
+	return func(e *RideEvent) {
+		if p, ok := e.Payload.(SurgeUpdatedPayload); ok {
+			p.ZoneName = name
+			e.Payload = p
+		}
+	}
+}
+
+// NewRideRequested returns the REQUESTED event that opens trip tripID.
+func NewRideRequested(tripID, passengerID, pickupLocation, dropoffLocation string, opts ...Option) RideEvent {
+	return newEvent(tripID, EventRideRequested, RideRequestedPayload{
+		Passenger:       passengerID,
+		PickupLocation:  pickupLocation,
+		DropoffLocation: dropoffLocation,
+	}, append([]Option{WithPassenger(passengerID)}, opts...))
+}
+
+// NewRideAccepted returns the ACCEPTED event of driverID taking trip tripID.
+func NewRideAccepted(tripID, driverID string, opts ...Option) RideEvent {
+	return newEvent(tripID, EventRideAccepted, RideAcceptedPayload{DriverID: driverID},
+		append([]Option{WithDriver(driverID)}, opts...))
+}
+
+// NewDriverArrived returns the DRIVER_ARRIVED event of driverID reaching the
+// pickup point of trip tripID.
+func NewDriverArrived(tripID, driverID string, opts ...Option) RideEvent {
+	return newEvent(tripID, EventDriverArrived, DriverArrivedPayload{DriverID: driverID},
+		append([]Option{WithDriver(driverID)}, opts...))
+}
+
+// NewPickedUp returns the PICKED_UP event of the passenger of trip tripID
+// getting in, at the event time.
+func NewPickedUp(tripID string, opts ...Option) RideEvent {
+	return newEvent(tripID, EventPickedUp, PickedUpPayload{}, opts)
+}
+
+// NewTripStarted returns the STARTED event of trip tripID, starting at the
+// event time.
+func NewTripStarted(tripID string, opts ...Option) RideEvent {
+	return newEvent(tripID, EventTripStarted, RideStartedPayload{}, opts)
+}
+
+// NewTripCompleted returns the COMPLETED event of trip tripID, ending at the
+// event time.
+func NewTripCompleted(tripID string, distanceKM float64, fare Money, opts ...Option) RideEvent {
+	return newEvent(tripID, EventTripCompleted, RideCompletedPayload{DistanceKM: distanceKM, Fare: fare}, opts)
+}
+
+// NewTripCancelled returns the CANCELLED event of trip tripID, cancelled by
+// cancelledBy for reason, which may be empty.
+func NewTripCancelled(tripID string, cancelledBy Actor, reason CancelReason, opts ...Option) RideEvent {
+	return newEvent(tripID, EventTripCancelled, RideCancelledPayload{CancelledBy: cancelledBy, Reason: reason}, opts)
+}
+
+// NewLocationUpdated returns a LOCATION_UPDATED event of the driver of trip
+// tripID, which is in state.
+func NewLocationUpdated(tripID string, state RideState, at Coordinate, headingDeg, speedKPH float64, opts ...Option) RideEvent {
+	return newEvent(tripID, EventLocationUpdated, LocationUpdatedPayload{Location: at, HeadingDeg: headingDeg, SpeedKPH: speedKPH},
+		append([]Option{WithState(state)}, opts...))
+}
+
+// NewPaymentProcessed returns the PAYMENT_PROCESSED event of trip tripID. The
+// ride state defaults to COMPLETED.
+func NewPaymentProcessed(tripID string, payment PaymentPayload, opts ...Option) RideEvent {
+	return newEvent(tripID, EventPaymentProcessed, payment, append([]Option{WithState(StateCompleted)}, opts...))
+}
+
+// NewRideRated returns the RIDE_RATED event of trip tripID. The ride state
+// defaults to COMPLETED.
+func NewRideRated(tripID string, rating RatingPayload, opts ...Option) RideEvent {
+	return newEvent(tripID, EventRideRated, rating, append([]Option{WithState(StateCompleted)}, opts...))
+}
+
+// NewTipAdded returns the TIP_ADDED event of the passenger of trip tripID
+// tipping driverID. The ride state defaults to COMPLETED.
+func NewTipAdded(tripID, driverID string, amount Money, opts ...Option) RideEvent {
+	return newEvent(tripID, EventTipAdded, TipPayload{DriverID: driverID, Amount: amount},
+		append([]Option{WithState(StateCompleted), WithDriver(driverID)}, opts...))
+}
+
+// NewSurgeUpdated returns the SURGE_UPDATED event setting the multiplier of
+// zoneID from the event time.
+func NewSurgeUpdated(zoneID ZoneID, multiplier float64, opts ...Option) RideEvent {
+	return newEvent("", EventSurgeUpdated, SurgeUpdatedPayload{ZoneID: zoneID, Multiplier: multiplier}, opts)
+}
+
+// QuoteTTL is how long a price quote built by NewPriceQuoted is valid for,
+// unless it sets its own expiry.
+const QuoteTTL = 5 * time.Minute
+
+// NewPriceQuoted returns the PRICE_QUOTE event of quote for trip tripID. A
+// zero Total is computed from the breakdown and multiplier, and a zero
+// ExpiresAt is QuoteTTL after the event time. The ride state defaults to NEW.
+func NewPriceQuoted(tripID string, quote PriceQuotePayload, opts ...Option) RideEvent {
+	if quote.Total.IsZero() {
+		quote.Total, _ = quote.Breakdown.Total(quote.Multiplier) // Validate reports mixed currencies
+	}
+	return newEvent(tripID, EventPriceQuoted, quote, append([]Option{WithState(StateNew)}, opts...))
+}
+
+// newEvent builds an event of type t with a new ID, the current time, and the
+// state t leads to, then applies opts.
+func newEvent(tripID string, t RideEventType, payload RideEventPayload, opts []Option) RideEvent {
+	e := RideEvent{
+		ID:         uuid.NewString(),
+		TripID:     tripID,
+		Type:       t,
+		OccurredAt: time.Now().UTC(),
+		State:      stateAfter[t],
+		Payload:    payload,
+	}
+	for _, opt := range opts {
+		opt(&e)
+	}
+
+	// These payload times are the time of the event itself
+	switch p := e.Payload.(type) {
+	case PickedUpPayload:
+		p.PickupTime = e.OccurredAt
+		e.Payload = p
+	case RideStartedPayload:
+		p.StartTime = e.OccurredAt
+		e.Payload = p
+	case RideCompletedPayload:
+		p.EndTime = e.OccurredAt
+		e.Payload = p
+	case PriceQuotePayload:
+		if p.ExpiresAt.IsZero() {
+			p.ExpiresAt = e.OccurredAt.Add(QuoteTTL)
+			e.Payload = p
+		}
+	}
+	return e
+}
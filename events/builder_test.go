@@ -0,0 +1,71 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewEvents_AreValid(t *testing.T) {
+	at := &Coordinate{Lat: 40.75, Lng: -73.98}
+	tests := []struct {
+		name  string
+		event RideEvent
+		state RideState
+	}{
+		{"requested", NewRideRequested("trip-1", "rider-1", "Main St", "Elm St", WithCoordinates(at, at)), StateRequested},
+		{"accepted", NewRideAccepted("trip-1", "driver-1"), StateAccepted},
+		{"driver arrived", NewDriverArrived("trip-1", "driver-1", WithLocation(at)), StateDriverArrived},
+		{"picked up", NewPickedUp("trip-1"), StatePickedUp},
+		{"started", NewTripStarted("trip-1"), StateInProgress},
+		{"completed", NewTripCompleted("trip-1", 4.2, NewMoney(1250, USD)), StateCompleted},
+		{"cancelled", NewTripCancelled("trip-1", ActorDriver, ReasonPassengerNoShow), StateCancelled},
+		{"location updated", NewLocationUpdated("trip-1", StateInProgress, *at, 90, 30), StateInProgress},
+		{"payment", NewPaymentProcessed("trip-1", PaymentPayload{PaymentID: "pay-1", Amount: NewMoney(1250, USD), Method: "card", Status: "captured"}), StateCompleted},
+		{"rating", NewRideRated("trip-1", RatingPayload{RatedBy: "passenger", Stars: 5}), StateCompleted},
+		{"tip", NewTipAdded("trip-1", "driver-1", NewMoney(200, USD)), StateCompleted},
+		{"surge", NewSurgeUpdated("midtown", 1.5, WithZoneName("Midtown")), ""},
+		{"price quote", NewPriceQuoted("trip-1", PriceQuotePayload{QuoteID: "quote-1", Multiplier: 1.25, DistanceKM: 4.2,
+			Breakdown: FareBreakdown{Base: NewMoney(250, USD), Distance: NewMoney(420, USD), Time: NewMoney(300, USD)}}), StateNew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.event.Validate(); err != nil {
+				t.Errorf("Validate() = %v", err)
+			}
+			if tt.event.State != tt.state {
+				t.Errorf("state = %q, want %q", tt.event.State, tt.state)
+			}
+		})
+	}
+}
+
+func TestNewEvents_Options(t *testing.T) {
+	when := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	e := NewTripCompleted("trip-1", 4.2, NewMoney(1250, USD),
+		WithID("id-1"), WithTime(when), WithDriver("driver-1"), WithPassenger("rider-1"), WithCity("nyc"))
+	if e.ID != "id-1" || !e.OccurredAt.Equal(when) || e.DriverID != "driver-1" || e.PassengerID != "rider-1" || e.City != "nyc" {
+		t.Errorf("options not applied: %+v", e)
+	}
+	if p, _ := PayloadAs[RideCompletedPayload](e); !p.EndTime.Equal(when) {
+		t.Errorf("end time = %v, want the event time %v", p.EndTime, when)
+	}
+
+	quote := NewPriceQuoted("trip-1", PriceQuotePayload{QuoteID: "quote-1", Multiplier: 1.5,
+		Breakdown: FareBreakdown{Base: NewMoney(250, USD), Distance: NewMoney(420, USD), Time: NewMoney(301, USD)}}, WithTime(when))
+	if p, _ := PayloadAs[PriceQuotePayload](quote); p.Total != NewMoney(1457, USD) || !p.ExpiresAt.Equal(when.Add(QuoteTTL)) {
+		t.Errorf("expected the total and expiry to be filled in, got %v and %v", p.Total, p.ExpiresAt)
+	}
+
+	a, b := NewTripStarted("trip-1"), NewTripStarted("trip-1")
+	if a.ID == "" || a.ID == b.ID {
+		t.Errorf("expected distinct generated IDs, got %q and %q", a.ID, b.ID)
+	}
+
+	// Payload options for another type are ignored
+	if got := NewRideAccepted("trip-1", "driver-1", WithCoordinates(&Coordinate{}, nil)); got.Payload != (RideAcceptedPayload{DriverID: "driver-1"}) {
+		t.Errorf("unexpected payload %#v", got.Payload)
+	}
+	if got := NewLocationUpdated("trip-1", StateAccepted, Coordinate{}, 0, 0, WithState(StatePickedUp)); got.State != StatePickedUp {
+		t.Errorf("WithState did not override the state, got %q", got.State)
+	}
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Actor is who cancelled a ride.
+type Actor string
+
+const (
+	ActorPassenger Actor = "passenger"
+	ActorDriver    Actor = "driver"
+	// ActorSystem is the platform itself, such as when no driver accepts a
+	// request in time or a payment fails.
+	ActorSystem Actor = "system"
+)
+
+// CancelReason is a code for why a ride was cancelled, from a fixed list so
+// that reports can group by it.
+type CancelReason string
+
+const (
+	ReasonChangedPlans      CancelReason = "changed_plans"
+	ReasonDriverTooFar      CancelReason = "driver_too_far"
+	ReasonPassengerNoShow   CancelReason = "passenger_no_show"
+	ReasonWrongPickup       CancelReason = "wrong_pickup"
+	ReasonDriverNoShow      CancelReason = "driver_no_show"
+	ReasonNoDriverAvailable CancelReason = "no_driver_available"
+	ReasonPaymentFailed     CancelReason = "payment_failed"
+	// ReasonOther is any other reason, and the reason free-text reasons of
+	// version 1 payloads are read as when they match no code.
+	ReasonOther CancelReason = "other"
+)
+
+var (
+	actors        = map[Actor]bool{ActorPassenger: true, ActorDriver: true, ActorSystem: true}
+	cancelReasons = map[CancelReason]bool{
+		ReasonChangedPlans: true, ReasonDriverTooFar: true, ReasonPassengerNoShow: true, ReasonWrongPickup: true,
+		ReasonDriverNoShow: true, ReasonNoDriverAvailable: true, ReasonPaymentFailed: true, ReasonOther: true,
+	}
+)
+
+// ErrUnknownActor and ErrUnknownCancelReason are returned by ParseActor and
+// ParseCancelReason, and so when decoding a cancellation, for values that are
+// not on their lists.
+var (
+	ErrUnknownActor        = errors.New("events: unknown actor")
+	ErrUnknownCancelReason = errors.New("events: unknown cancel reason")
+)
+
+// ParseActor returns the actor named s, ignoring case and surrounding space.
+// Other values fail with ErrUnknownActor.
+func ParseActor(s string) (Actor, error) {
+	a := Actor(strings.ToLower(strings.TrimSpace(s)))
+	if !actors[a] {
+		return "", fmt.Errorf("%w %q", ErrUnknownActor, s)
+	}
+	return a, nil
+}
+
+// ParseCancelReason returns the reason code s, ignoring case and surrounding
+// space. The empty string is no reason. Other values fail with
+// ErrUnknownCancelReason.
+func ParseCancelReason(s string) (CancelReason, error) {
+	r := CancelReason(strings.ToLower(strings.TrimSpace(s)))
+	if r != "" && !cancelReasons[r] {
+		return "", fmt.Errorf("%w %q", ErrUnknownCancelReason, s)
+	}
+	return r, nil
+}
+
+// IsKnown reports whether a is one of the actors.
+func (a Actor) IsKnown() bool { return actors[a] }
+
+func (a Actor) String() string { return string(a) }
+
+// MarshalText returns a as it is.
+func (a Actor) MarshalText() ([]byte, error) { return []byte(a), nil }
+
+// UnmarshalText sets a with ParseActor, rejecting unknown actors.
+func (a *Actor) UnmarshalText(text []byte) error {
+	v, err := ParseActor(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// IsKnown reports whether r is one of the reason codes or empty.
+func (r CancelReason) IsKnown() bool { return r == "" || cancelReasons[r] }
+
+func (r CancelReason) String() string { return string(r) }
+
+// MarshalText returns r as it is.
+func (r CancelReason) MarshalText() ([]byte, error) { return []byte(r), nil }
+
+// UnmarshalText sets r with ParseCancelReason, rejecting unknown reasons.
+func (r *CancelReason) UnmarshalText(text []byte) error {
+	v, err := ParseCancelReason(string(text))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// legacyCancelReasons maps the free-text reasons producers wrote before
+// reasons were codes, once normalized by cancelReasonCode, to their codes.
+var legacyCancelReasons = map[string]CancelReason{
+	"no_show":      ReasonPassengerNoShow,
+	"no_driver":    ReasonNoDriverAvailable,
+	"changed_mind": ReasonChangedPlans,
+}
+
+// cancelReasonCode returns the code of a version 1 free-text reason: the code
+// it spells, ignoring case and with spaces and hyphens as underscores, or
+// ReasonOther.
+func cancelReasonCode(text string) CancelReason {
+	normalized := strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(text)))
+	if r := CancelReason(normalized); r == "" || cancelReasons[r] {
+		return r
+	}
+	if r, ok := legacyCancelReasons[normalized]; ok {
+		return r
+	}
+	return ReasonOther
+}
+
+// cancelReasonToCode is the up-converter of RideCancelledPayload from version
+// 1, whose reason was free text, to version 2, whose reason is a CancelReason.
+func cancelReasonToCode(raw json.RawMessage) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) != nil {
+		return raw, nil // Not an object; leave it to the payload's decoding
+	}
+	value, ok := fields["reason"]
+	if !ok {
+		return raw, nil
+	}
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return nil, fmt.Errorf("field reason: %w", err)
+	}
+	code := cancelReasonCode(text)
+	if string(code) == text {
+		return raw, nil
+	}
+	fields["reason"], _ = json.Marshal(code)
+	return json.Marshal(fields)
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseActorAndCancelReason(t *testing.T) {
+	if a, err := ParseActor(" System "); err != nil || a != ActorSystem {
+		t.Errorf("ParseActor = %q, %v", a, err)
+	}
+	if _, err := ParseActor("dispatcher"); !errors.Is(err, ErrUnknownActor) {
+		t.Errorf("expected ErrUnknownActor, got %v", err)
+	}
+	if r, err := ParseCancelReason("PASSENGER_NO_SHOW"); err != nil || r != ReasonPassengerNoShow {
+		t.Errorf("ParseCancelReason = %q, %v", r, err)
+	}
+	if r, err := ParseCancelReason(""); err != nil || r != "" {
+		t.Errorf("expected no reason to parse, got %q, %v", r, err)
+	}
+	if _, err := ParseCancelReason("bored"); !errors.Is(err, ErrUnknownCancelReason) {
+		t.Errorf("expected ErrUnknownCancelReason, got %v", err)
+	}
+}
+
+func TestRideCancelledPayload_Decoding(t *testing.T) {
+	cases := []struct {
+		name string
+		json string
+		want RideCancelledPayload
+	}{
+		{"version 1 free text", `{"id":"id1","event_type":"CANCELLED","payload_version":1,"payload":{"cancelled_by":"passenger","reason":"Passenger no-show"}}`,
+			RideCancelledPayload{CancelledBy: ActorPassenger, Reason: ReasonPassengerNoShow}},
+		{"version 1 producer code", `{"id":"id1","event_type":"CANCELLED","payload":{"cancelled_by":"passenger","reason":"no_show"}}`,
+			RideCancelledPayload{CancelledBy: ActorPassenger, Reason: ReasonPassengerNoShow}},
+		{"version 1 unknown text", `{"id":"id1","event_type":"CANCELLED","payload":{"cancelled_by":"driver","reason":"car broke down"}}`,
+			RideCancelledPayload{CancelledBy: ActorDriver, Reason: ReasonOther}},
+		{"version 2", `{"id":"id1","event_type":"CANCELLED","payload_version":2,"payload":{"cancelled_by":"system","reason":"no_driver_available"}}`,
+			RideCancelledPayload{CancelledBy: ActorSystem, Reason: ReasonNoDriverAvailable}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var e RideEvent
+			if err := json.Unmarshal([]byte(tc.json), &e); err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			if e.Payload != tc.want {
+				t.Errorf("payload = %+v, want %+v", e.Payload, tc.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{
+		`{"id":"id1","event_type":"CANCELLED","payload_version":2,"payload":{"cancelled_by":"passenger","reason":"bored"}}`,
+		`{"id":"id1","event_type":"CANCELLED","payload":{"cancelled_by":"dispatcher"}}`,
+	} {
+		var e RideEvent
+		if err := json.Unmarshal([]byte(bad), &e); err == nil {
+			t.Errorf("expected %s to fail to decode", bad)
+		}
+	}
+
+	p, err := DecodePayload(EventTripCancelled, []byte(`{"cancelled_by":"driver","reason":"wrong pickup"}`))
+	if err != nil || p != (RideCancelledPayload{CancelledBy: ActorDriver, Reason: ReasonWrongPickup}) {
+		t.Errorf("DecodePayload = %+v, %v", p, err)
+	}
+}
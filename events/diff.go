@@ -0,0 +1,108 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Difference is a field in which two events differ.
+type Difference struct {
+	Field string // JSON name, with payload fields prefixed by "payload."
+	A, B  any    // the field's values in each event
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %v != %v", d.Field, d.A, d.B)
+}
+
+// volatileFields are the fields of an event that differ between copies of it
+// without changing what it means: its ID and times, which systems assign and
+// round, its encoding version, and its envelope metadata.
+var volatileFields = []string{"id", "event_time", "recorded_at", "schema_version", "meta"}
+
+// Equal reports whether a and b are the same event apart from their volatile
+// fields; see Diff.
+func Equal(a, b RideEvent, ignore ...string) bool {
+	return len(Diff(a, b, ignore...)) == 0
+}
+
+// Diff returns the fields in which a and b differ, such as a produced event
+// and the copy read back from a store. It ignores the volatile fields: the ID,
+// the event and recorded times and any other times in the payload, the schema
+// version, and the meta. It also ignores the fields named in ignore, by their
+// JSON names as in Difference, such as "city" or "payload.zone_name". Payloads
+// of different types differ in "payload" alone.
+func Diff(a, b RideEvent, ignore ...string) []Difference {
+	d := differ{ignore: append(slices.Clone(volatileFields), ignore...)}
+	d.value("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return d.diffs
+}
+
+type differ struct {
+	ignore []string
+	diffs  []Difference
+}
+
+// value compares a and b, values of the same type, as the field named field.
+func (d *differ) value(field string, a, b reflect.Value) {
+	if slices.Contains(d.ignore, field) || a.Type() == timeType {
+		return
+	}
+	switch a.Kind() {
+	case reflect.Struct:
+		d.fields(field, a, b)
+	case reflect.Interface, reflect.Pointer:
+		switch {
+		case a.IsNil() && b.IsNil():
+		case a.IsNil() || b.IsNil() || a.Elem().Type() != b.Elem().Type():
+			d.add(field, a, b)
+		default:
+			d.value(field, a.Elem(), b.Elem())
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			d.add(field, a, b)
+		}
+	}
+}
+
+// fields compares the exported fields of structs a and b, which are named by
+// their JSON names after prefix.
+func (d *differ) fields(prefix string, a, b reflect.Value) {
+	if prefix != "" {
+		prefix += "."
+	}
+	for i := range a.NumField() {
+		f := a.Type().Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case !f.IsExported() || name == "-":
+			continue
+		case f.Anonymous && name == "":
+			// Embedded fields, such as PayloadMarker, are flattened like JSON does
+			d.value(strings.TrimSuffix(prefix, "."), a.Field(i), b.Field(i))
+			continue
+		case name == "":
+			name = f.Name
+		}
+		d.value(prefix+name, a.Field(i), b.Field(i))
+	}
+}
+
+func (d *differ) add(field string, a, b reflect.Value) {
+	d.diffs = append(d.diffs, Difference{Field: field, A: describe(a), B: describe(b)})
+}
+
+// describe returns v for a Difference: nil for nil, the payload type for a
+// payload, and the value otherwise.
+func describe(v reflect.Value) any {
+	if (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) && v.IsNil() {
+		return nil
+	}
+	if p, ok := v.Interface().(RideEventPayload); ok && v.Kind() == reflect.Interface {
+		return PayloadTypeName(p)
+	}
+	return v.Interface()
+}
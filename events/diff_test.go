@@ -0,0 +1,72 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	produced := NewRideRequested("trip-1", "rider-1", "Main St", "Elm St",
+		WithTime(at), WithCity("nyc"), WithMeta(Meta{CorrelationID: "trip-1"}),
+		WithCoordinates(&Coordinate{Lat: 40.75, Lng: -73.98}, nil))
+
+	// The copy a store returns has its own ID, rounded times, and no meta
+	stored := produced
+	stored.ID = "other"
+	stored.OccurredAt = at.Add(time.Microsecond)
+	stored.RecordedAt = at.Add(time.Second)
+	stored.SchemaVersion = SchemaVersion
+	stored.Meta = Meta{}
+	if d := Diff(produced, stored); len(d) != 0 || !Equal(produced, stored) {
+		t.Errorf("expected volatile fields to be ignored, got %v", d)
+	}
+
+	p := stored.Payload.(RideRequestedPayload)
+	p.DropoffLocation = "Oak St"
+	p.Pickup = &Coordinate{Lat: 40.76, Lng: -73.98}
+	p.Dropoff = &Coordinate{}
+	stored.Payload = p
+	stored.City = ""
+	want := []Difference{
+		{Field: "city", A: "nyc", B: ""},
+		{Field: "payload.dropoff_location", A: "Elm St", B: "Oak St"},
+		{Field: "payload.pickup.lat", A: 40.75, B: 40.76},
+		{Field: "payload.dropoff", A: nil, B: &Coordinate{}},
+	}
+	if got := Diff(produced, stored); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff =\n%v\nwant\n%v", got, want)
+	}
+	if got := Diff(produced, stored, "city", "payload.pickup", "payload.dropoff"); len(got) != 1 || got[0].Field != "payload.dropoff_location" {
+		t.Errorf("expected ignored fields to be skipped, got %v", got)
+	}
+
+	// Payload times are volatile too, and payloads of other types differ as a whole
+	completed := NewTripCompleted("trip-1", 3, NewMoney(950, USD), WithTime(at))
+	later := NewTripCompleted("trip-1", 3, NewMoney(950, USD), WithTime(at.Add(time.Hour)))
+	if !Equal(completed, later) {
+		t.Errorf("expected end times to be ignored, got %v", Diff(completed, later))
+	}
+	later.Payload = RideStartedPayload{StartTime: at}
+	later.Type = EventTripStarted
+	want = []Difference{
+		{Field: "event_type", A: EventTripCompleted, B: EventTripStarted},
+		{Field: "payload", A: "RideCompletedPayload", B: "RideStartedPayload"},
+	}
+	if got := Diff(completed, later); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestDiff_RegisteredPayload(t *testing.T) {
+	a := RideEvent{Type: eventRated, Payload: ratedPayload{Stars: 4}}
+	b := RideEvent{Type: eventRated, Payload: ratedPayload{Stars: 5}}
+	if got := Diff(a, b); len(got) != 1 || got[0].Field != "payload.stars" {
+		t.Errorf("expected payload.stars to differ, got %v", got)
+	}
+	b.Payload = nil
+	if got := Diff(a, b); len(got) != 1 || got[0].String() != "payload: ratedPayload != <nil>" {
+		t.Errorf("expected a missing payload to differ, got %v", got)
+	}
+}
@@ -0,0 +1,290 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DriverEventPayload is a marker interface for the payloads of driver events.
+type DriverEventPayload interface {
+	isDriverPayload()
+}
+
+// ShiftStartedPayload holds data for when a driver goes online
+type ShiftStartedPayload struct {
+	VehicleID string     `json:"vehicle_id"`
+	Location  Coordinate `json:"location"`
+}
+
+func (ShiftStartedPayload) isDriverPayload() {}
+
+// ShiftEndedPayload holds data for when a driver goes offline
+type ShiftEndedPayload struct {
+	TripsCompleted int    `json:"trips_completed"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+func (ShiftEndedPayload) isDriverPayload() {}
+
+// HeartbeatPayload holds a driver's position and availability while online
+type HeartbeatPayload struct {
+	Location  Coordinate `json:"location"`
+	Available bool       `json:"available"` // false while on a trip
+}
+
+func (HeartbeatPayload) isDriverPayload() {}
+
+// RelocatedPayload holds data for when an idle driver moves to another area,
+// such as toward a surge zone
+type RelocatedPayload struct {
+	From   Coordinate `json:"from"`
+	To     Coordinate `json:"to"`
+	ZoneID ZoneID     `json:"zone_id,omitempty"` // the zone moved to, if any
+}
+
+func (RelocatedPayload) isDriverPayload() {}
+
+// DriverEventType is a string-based enum for the supply-side event types.
+type DriverEventType string
+
+const (
+	EventShiftStarted DriverEventType = "SHIFT_STARTED"
+	EventShiftEnded   DriverEventType = "SHIFT_ENDED"
+	EventHeartbeat    DriverEventType = "HEARTBEAT"
+	EventRelocated    DriverEventType = "RELOCATED"
+)
+
+// driverPayloads maps each driver event type to its payload struct.
+var driverPayloads = map[DriverEventType]func() DriverEventPayload{
+	EventShiftStarted: func() DriverEventPayload { return &ShiftStartedPayload{} },
+	EventShiftEnded:   func() DriverEventPayload { return &ShiftEndedPayload{} },
+	EventHeartbeat:    func() DriverEventPayload { return &HeartbeatPayload{} },
+	EventRelocated:    func() DriverEventPayload { return &RelocatedPayload{} },
+}
+
+// ErrUnknownDriverEventType is returned by ParseDriverEventType and
+// UnmarshalDriverStrict for a value that is not a DriverEventType.
+var ErrUnknownDriverEventType = errors.New("events: unknown driver event type")
+
+// ParseDriverEventType returns the driver event type named s, ignoring case and
+// surrounding space. Other values fail with ErrUnknownDriverEventType.
+func ParseDriverEventType(s string) (DriverEventType, error) {
+	t := DriverEventType(strings.ToUpper(strings.TrimSpace(s)))
+	if _, ok := driverPayloads[t]; !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownDriverEventType, s)
+	}
+	return t, nil
+}
+
+func (t DriverEventType) String() string { return string(t) }
+
+// MarshalText returns t as it is.
+func (t DriverEventType) MarshalText() ([]byte, error) { return []byte(t), nil }
+
+// UnmarshalText sets t with ParseDriverEventType, rejecting unknown types.
+func (t *DriverEventType) UnmarshalText(text []byte) error {
+	v, err := ParseDriverEventType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// DriverSchemaVersion is the version of the DriverEvent JSON encoding this
+// package writes. Version 1 is the first. Change it the way SchemaVersion is
+// changed.
+const DriverSchemaVersion = 1
+
+// DriverEvent is a change in a driver's supply, such as going online or
+// moving, as opposed to a RideEvent, which belongs to a ride. Its envelope
+// mirrors RideEvent's, keyed by driver rather than trip.
+type DriverEvent struct {
+	ID         string             `json:"id"`
+	DriverID   string             `json:"driver_id"`
+	Type       DriverEventType    `json:"event_type"`
+	OccurredAt time.Time          `json:"event_time"`
+	City       string             `json:"city,omitempty"` // empty outside multi-city mode
+	Payload    DriverEventPayload `json:"payload,omitempty"`
+
+	// SchemaVersion is the encoding version the event was decoded from, or zero
+	// for an event built in code. Encoding always writes DriverSchemaVersion.
+	SchemaVersion int `json:"schema_version,omitempty"`
+
+	Meta Meta `json:"meta,omitzero"`
+
+	// RecordedAt is the processing time, as for RideEvent.
+	RecordedAt time.Time `json:"recorded_at,omitzero"`
+}
+
+// MarshalJSON encodes e stamped with DriverSchemaVersion.
+func (e DriverEvent) MarshalJSON() ([]byte, error) {
+	type Alias DriverEvent // Prevent recursion
+	a := Alias(e)
+	a.SchemaVersion = DriverSchemaVersion
+	return json.Marshal(a)
+}
+
+// UnmarshalJSON decodes the payload by event type. Unknown types decode with a
+// nil Payload; use UnmarshalDriverStrict to reject them.
+func (e *DriverEvent) UnmarshalJSON(data []byte) error {
+	return e.unmarshal(data, false)
+}
+
+// UnmarshalDriverStrict decodes data into e like UnmarshalStrict does ride
+// events, rejecting unknown fields and failing with ErrUnknownDriverEventType
+// for unknown types.
+func UnmarshalDriverStrict(data []byte, e *DriverEvent) error {
+	return e.unmarshal(data, true)
+}
+
+func (e *DriverEvent) unmarshal(data []byte, strict bool) error {
+	type Alias DriverEvent // Prevent recursion
+	aux := &struct {
+		Payload json.RawMessage `json:"payload"`
+		Type    string          `json:"event_type"` // unknown values are left to Validate
+		*Alias
+	}{
+		Alias: (*Alias)(e),
+	}
+	if err := decodeJSON(data, aux, strict); err != nil {
+		return err
+	}
+	e.Type = DriverEventType(aux.Type)
+
+	newPayload, ok := driverPayloads[e.Type]
+	switch {
+	case !ok && strict:
+		return fmt.Errorf("%w %q", ErrUnknownDriverEventType, e.Type)
+	case !ok || len(aux.Payload) == 0 || string(aux.Payload) == "null":
+		e.Payload = nil
+		return nil
+	}
+	p := newPayload()
+	if err := decodeJSON(aux.Payload, p, strict); err != nil {
+		return fmt.Errorf("events: %s payload: %w", e.Type, err)
+	}
+	e.Payload = derefDriverPayload(p)
+	return nil
+}
+
+// derefDriverPayload returns the struct p points to, so decoded payloads are
+// values like those built in code.
+func derefDriverPayload(p DriverEventPayload) DriverEventPayload {
+	switch p := p.(type) {
+	case *ShiftStartedPayload:
+		return *p
+	case *ShiftEndedPayload:
+		return *p
+	case *HeartbeatPayload:
+		return *p
+	case *RelocatedPayload:
+		return *p
+	}
+	return p
+}
+
+// Validate checks e against the DriverEvent contract: an ID, a driver, a known
+// type, an event time, and a payload of the type's struct with its own fields
+// in range. Problems are returned like RideEvent.Validate returns them.
+func (e DriverEvent) Validate() error {
+	var errs []error
+	invalid := func(field, reason string) {
+		errs = append(errs, &ValidationError{Field: field, Reason: reason})
+	}
+
+	if e.ID == "" {
+		invalid("id", "is required")
+	}
+	if e.DriverID == "" {
+		invalid("driver_id", "is required")
+	}
+	if e.OccurredAt.IsZero() {
+		invalid("event_time", "is required")
+	}
+	newPayload, known := driverPayloads[e.Type]
+	switch {
+	case !known:
+		invalid("event_type", fmt.Sprintf("%q is not a known driver event type", e.Type))
+	case e.Payload == nil:
+		invalid("payload", "is required")
+	default:
+		if want := reflect.TypeOf(derefDriverPayload(newPayload())); reflect.TypeOf(e.Payload) != want {
+			invalid("payload", fmt.Sprintf("is %T, want %s for %s", e.Payload, want, e.Type))
+		}
+	}
+	if p, ok := e.Payload.(interface{ Validate() error }); ok {
+		errs = append(errs, p.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that the vehicle is set and the location is in range.
+func (p ShiftStartedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.VehicleID != "", "vehicle_id", "is required")
+	errs.check(p.Location.Valid(), "location", "is out of range")
+	return errs.err()
+}
+
+// Validate checks that the trip count is not negative.
+func (p ShiftEndedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.TripsCompleted >= 0, "trips_completed", "is negative")
+	return errs.err()
+}
+
+// Validate checks that the location is in range.
+func (p HeartbeatPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.Location.Valid(), "location", "is out of range")
+	return errs.err()
+}
+
+// Validate checks that both ends of the move are in range.
+func (p RelocatedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.From.Valid(), "from", "is out of range")
+	errs.check(p.To.Valid(), "to", "is out of range")
+	return errs.err()
+}
+
+// Redacted returns a copy of e that is safe to log, with the driver's
+// positions coarsened like RideEvent.Redacted coarsens coordinates.
+func (e DriverEvent) Redacted() DriverEvent {
+	switch p := e.Payload.(type) {
+	case ShiftStartedPayload:
+		p.Location = p.Location.coarse()
+		e.Payload = p
+	case HeartbeatPayload:
+		p.Location = p.Location.coarse()
+		e.Payload = p
+	case RelocatedPayload:
+		p.From, p.To = p.From.coarse(), p.To.coarse()
+		e.Payload = p
+	}
+	return e
+}
+
+// LogValue implements slog.LogValuer, so driver events are logged redacted.
+func (e DriverEvent) LogValue() slog.Value {
+	r := e.Redacted()
+	attrs := []slog.Attr{
+		slog.String("id", r.ID),
+		slog.String("driver_id", r.DriverID),
+		slog.String("type", string(r.Type)),
+		slog.Time("event_time", r.OccurredAt),
+	}
+	if r.City != "" {
+		attrs = append(attrs, slog.String("city", r.City))
+	}
+	if r.Payload != nil {
+		attrs = append(attrs, slog.Any("payload", r.Payload))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDriverEvent_RoundTrip(t *testing.T) {
+	at := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	for _, e := range []DriverEvent{
+		{ID: "d1", DriverID: "driver-1", Type: EventShiftStarted, OccurredAt: at, City: "nyc",
+			Payload: ShiftStartedPayload{VehicleID: "car-7", Location: Coordinate{Lat: 40.75, Lng: -73.98}}},
+		{ID: "d2", DriverID: "driver-1", Type: EventHeartbeat, OccurredAt: at,
+			Payload: HeartbeatPayload{Location: Coordinate{Lat: 40.76, Lng: -73.97}, Available: true}},
+		{ID: "d3", DriverID: "driver-1", Type: EventRelocated, OccurredAt: at,
+			Payload: RelocatedPayload{From: Coordinate{Lat: 40.76, Lng: -73.97}, To: Coordinate{Lat: 40.64, Lng: -73.78}, ZoneID: "jfk"}},
+		{ID: "d4", DriverID: "driver-1", Type: EventShiftEnded, OccurredAt: at,
+			Payload: ShiftEndedPayload{TripsCompleted: 12, Reason: "end_of_day"}},
+	} {
+		t.Run(string(e.Type), func(t *testing.T) {
+			if err := e.Validate(); err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			var got DriverEvent
+			if err := UnmarshalDriverStrict(data, &got); err != nil {
+				t.Fatalf("UnmarshalDriverStrict failed: %v", err)
+			}
+			e.SchemaVersion = DriverSchemaVersion
+			if !reflect.DeepEqual(got, e) {
+				t.Errorf("round trip:\ngot  %+v\nwant %+v", got, e)
+			}
+		})
+	}
+}
+
+func TestDriverEvent_Decoding(t *testing.T) {
+	unknown := []byte(`{"id":"d1","driver_id":"driver-1","event_type":"TELEPORTED","event_time":"2025-06-01T08:00:00Z","payload":{}}`)
+	var e DriverEvent
+	if err := json.Unmarshal(unknown, &e); err != nil || e.Payload != nil || e.Type != "TELEPORTED" {
+		t.Errorf("expected lenient decoding to keep the type with a nil payload, got %+v, %v", e, err)
+	}
+	if err := UnmarshalDriverStrict(unknown, &e); !errors.Is(err, ErrUnknownDriverEventType) {
+		t.Errorf("expected ErrUnknownDriverEventType, got %v", err)
+	}
+	extra := []byte(`{"id":"d1","driver_id":"driver-1","event_type":"SHIFT_ENDED","event_time":"2025-06-01T08:00:00Z","payload":{"trips_completed":1,"rating":5}}`)
+	if err := UnmarshalDriverStrict(extra, &e); err == nil {
+		t.Error("expected an unknown payload field to fail strict decoding")
+	}
+}
+
+func TestDriverEvent_Validate(t *testing.T) {
+	e := DriverEvent{Type: EventHeartbeat, Payload: ShiftEndedPayload{TripsCompleted: -1}}
+	err := e.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, field := range []string{"id", "driver_id", "event_time", "payload is events.ShiftEndedPayload", "payload.trips_completed"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %q in %v", field, err)
+		}
+	}
+	if t2, err := ParseDriverEventType(" heartbeat "); err != nil || t2 != EventHeartbeat {
+		t.Errorf("ParseDriverEventType = %q, %v", t2, err)
+	}
+}
+
+func TestDriverEvent_Redacted(t *testing.T) {
+	e := DriverEvent{ID: "d1", DriverID: "driver-1", Type: EventHeartbeat,
+		Payload: HeartbeatPayload{Location: Coordinate{Lat: 40.758896, Lng: -73.985130}}}
+	if got := e.Redacted().Payload.(HeartbeatPayload).Location; got != (Coordinate{Lat: 40.76, Lng: -73.99}) {
+		t.Errorf("expected a coarse location, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownRideState is returned by ParseRideState for a value that is not a
+// RideState.
+var ErrUnknownRideState = errors.New("events: unknown ride state")
+
+// ParseRideEventType returns the registered event type named s, ignoring case
+// and surrounding space. Other values fail with ErrUnknownEventType.
+func ParseRideEventType(s string) (RideEventType, error) {
+	t := RideEventType(strings.ToUpper(strings.TrimSpace(s)))
+	if !t.IsKnown() {
+		return "", fmt.Errorf("%w %q", ErrUnknownEventType, s)
+	}
+	return t, nil
+}
+
+// ParseRideState returns the ride state named s, ignoring case and surrounding
+// space. Other values fail with ErrUnknownRideState.
+func ParseRideState(s string) (RideState, error) {
+	st := RideState(strings.ToUpper(strings.TrimSpace(s)))
+	if !rideStates[st] {
+		return "", fmt.Errorf("%w %q", ErrUnknownRideState, s)
+	}
+	return st, nil
+}
+
+func (t RideEventType) String() string { return string(t) }
+
+// MarshalText returns t as it is, so an unknown type read leniently is written
+// back unchanged.
+func (t RideEventType) MarshalText() ([]byte, error) { return []byte(t), nil }
+
+// UnmarshalText sets t with ParseRideEventType, rejecting unknown types.
+func (t *RideEventType) UnmarshalText(text []byte) error {
+	v, err := ParseRideEventType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+func (s RideState) String() string { return string(s) }
+
+// MarshalText returns s as it is.
+func (s RideState) MarshalText() ([]byte, error) { return []byte(s), nil }
+
+// UnmarshalText sets s with ParseRideState, rejecting unknown states.
+func (s *RideState) UnmarshalText(text []byte) error {
+	v, err := ParseRideState(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
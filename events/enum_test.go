@@ -0,0 +1,74 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestParseRideEventType(t *testing.T) {
+	for _, in := range []string{"COMPLETED", "completed", " Completed ", "PAYMENT_PROCESSED"} {
+		if _, err := ParseRideEventType(in); err != nil {
+			t.Errorf("ParseRideEventType(%q) = %v", in, err)
+		}
+	}
+	if got, _ := ParseRideEventType("surge_updated"); got != EventSurgeUpdated {
+		t.Errorf("ParseRideEventType(surge_updated) = %q", got)
+	}
+	for _, in := range []string{"", "TELEPORTED", "IN_PROGRESS"} {
+		if _, err := ParseRideEventType(in); !errors.Is(err, ErrUnknownEventType) {
+			t.Errorf("ParseRideEventType(%q) = %v, want ErrUnknownEventType", in, err)
+		}
+	}
+}
+
+func TestParseRideState(t *testing.T) {
+	for st := range rideStates {
+		got, err := ParseRideState(st.String())
+		if err != nil || got != st {
+			t.Errorf("ParseRideState(%q) = %q, %v", st, got, err)
+		}
+	}
+	if got, _ := ParseRideState(" in_progress "); got != StateInProgress {
+		t.Errorf("ParseRideState(in_progress) = %q", got)
+	}
+	for _, in := range []string{"", "DONE", "STARTED"} {
+		if _, err := ParseRideState(in); !errors.Is(err, ErrUnknownRideState) {
+			t.Errorf("ParseRideState(%q) = %v, want ErrUnknownRideState", in, err)
+		}
+	}
+}
+
+func TestEnums_TextRoundTrip(t *testing.T) {
+	// Through JSON
+	in := struct {
+		Type  RideEventType `json:"type"`
+		State RideState     `json:"state"`
+	}{EventTipAdded, StateCompleted}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := in
+	out.Type, out.State = "", ""
+	if err := json.Unmarshal(data, &out); err != nil || out != in {
+		t.Errorf("round trip = %+v, %v; want %+v", out, err, in)
+	}
+	if err := json.Unmarshal([]byte(`{"state":"DONE"}`), &out); !errors.Is(err, ErrUnknownRideState) {
+		t.Errorf("expected an unknown state to fail, got %v", err)
+	}
+
+	// Through flags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var state RideState
+	fs.TextVar(&state, "state", StateNew, "")
+	if err := fs.Parse([]string{"-state", "cancelled"}); err != nil || state != StateCancelled {
+		t.Errorf("-state cancelled = %q, %v", state, err)
+	}
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse([]string{"-state", "gone"}); err == nil {
+		t.Error("expected an unknown state flag to fail")
+	}
+}
@@ -0,0 +1,200 @@
+// Package eventstest generates ride events for tests. RandomRideEvent and
+// RandomTrip build valid events of any built-in type from a seeded source, and
+// Canonical returns the events of testdata/canonical.ndjson, the fixed messages
+// the consumer and store tests share so that every layer sees the same bytes.
+package eventstest
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// Epoch is the earliest event time of generated events. They fall within the
+// day after it.
+var Epoch = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// CanonicalSeed is the seed testdata/canonical.ndjson is generated from.
+const CanonicalSeed = 1
+
+//go:embed testdata/canonical.ndjson
+var canonicalNDJSON []byte
+
+// Canonical returns the canonical events, decoded from testdata/canonical.ndjson:
+// a completed trip with every event a trip can have, a cancelled trip, and a
+// surge update.
+func Canonical() []events.RideEvent {
+	var evts []events.RideEvent
+	scanner := bufio.NewScanner(bytes.NewReader(canonicalNDJSON))
+	for scanner.Scan() {
+		var e events.RideEvent
+		if err := events.UnmarshalStrict(scanner.Bytes(), &e); err != nil {
+			panic(fmt.Sprintf("eventstest: decoding canonical event %d: %v", len(evts)+1, err))
+		}
+		evts = append(evts, e)
+	}
+	return evts
+}
+
+// CanonicalNDJSON returns the canonical events as they are encoded, one JSON
+// object per line.
+func CanonicalNDJSON() []byte {
+	return bytes.Clone(canonicalNDJSON)
+}
+
+// generateCanonical returns the events testdata/canonical.ndjson holds.
+func generateCanonical() []events.RideEvent {
+	r := rand.New(rand.NewPCG(CanonicalSeed, CanonicalSeed))
+	evts := RandomTrip(r,
+		events.EventRideRequested, events.EventRideAccepted, events.EventDriverArrived, events.EventPickedUp,
+		events.EventTripStarted, events.EventLocationUpdated, events.EventTripCompleted,
+		events.EventPaymentProcessed, events.EventRideRated, events.EventTipAdded)
+	evts = append(evts, RandomTrip(r, events.EventRideRequested, events.EventRideAccepted, events.EventTripCancelled)...)
+	return append(evts, RandomRideEvent(r, events.EventSurgeUpdated))
+}
+
+// RandomRideEvent returns a valid event of type t, drawn from r. It panics for
+// types without a payload of this module, such as ones registered by tests.
+func RandomRideEvent(r *rand.Rand, t events.RideEventType) events.RideEvent {
+	return RandomTrip(r, t)[0]
+}
+
+// RandomTrip returns one valid event of each of types, in order, drawn from r.
+// The events share a trip, passenger, driver, and route, their event times
+// advance by a few minutes each, and their states follow the lifecycle events
+// among them. Surge updates among types belong to no trip, and price quotes are
+// of a NEW ride.
+func RandomTrip(r *rand.Rand, types ...events.RideEventType) []events.RideEvent {
+	var (
+		tripID    = randomUUID(r)
+		passenger = fmt.Sprintf("rider-%04d", r.IntN(10000))
+		driver    = fmt.Sprintf("driver-%04d", r.IntN(10000))
+		pickup    = randomCoordinate(r)
+		dropoff   = randomCoordinate(r)
+		at        = Epoch.Add(time.Duration(r.IntN(24*60)) * time.Minute)
+		state     = events.StateInProgress
+		assigned  bool
+		fare      events.Money
+	)
+	distance := math.Round(events.Route{pickup, dropoff}.DistanceKM()*1.3*100) / 100
+	fare, _ = events.NewMoney(250, events.USD).Add(events.NewMoney(100, events.USD).Mul(distance))
+
+	evts := make([]events.RideEvent, 0, len(types))
+	for _, t := range types {
+		at = at.Add(time.Duration(60+r.IntN(240)) * time.Second)
+		if t != events.EventRideRequested && t != events.EventTripCancelled && t != events.EventSurgeUpdated && t != events.EventPriceQuoted {
+			assigned = true
+		}
+		opts := []events.Option{events.WithID(randomUUID(r)), events.WithTime(at), events.WithPassenger(passenger)}
+		if assigned {
+			opts = append(opts, events.WithDriver(driver))
+		}
+
+		var e events.RideEvent
+		switch t {
+		case events.EventRideRequested:
+			e = events.NewRideRequested(tripID, passenger, pick(r, streets), pick(r, streets),
+				append(opts, events.WithCoordinates(&pickup, &dropoff))...)
+		case events.EventRideAccepted:
+			e = events.NewRideAccepted(tripID, driver, opts...)
+		case events.EventDriverArrived:
+			e = events.NewDriverArrived(tripID, driver, append(opts, events.WithLocation(&pickup))...)
+		case events.EventPickedUp:
+			e = events.NewPickedUp(tripID, append(opts, events.WithLocation(&pickup))...)
+		case events.EventTripStarted:
+			e = events.NewTripStarted(tripID, opts...)
+		case events.EventLocationUpdated:
+			e = events.NewLocationUpdated(tripID, state, randomCoordinate(r), float64(r.IntN(360)), float64(10+r.IntN(50)), opts...)
+		case events.EventTripCompleted:
+			e = events.NewTripCompleted(tripID, distance, fare, opts...)
+		case events.EventTripCancelled:
+			e = events.NewTripCancelled(tripID, events.Actor(pick(r, []string{"passenger", "driver"})),
+				events.CancelReason(pick(r, cancelReasons)), opts...)
+		case events.EventPaymentProcessed:
+			e = events.NewPaymentProcessed(tripID, events.PaymentPayload{
+				PaymentID: randomUUID(r),
+				Amount:    fare,
+				Method:    pick(r, []string{"card", "cash", "wallet"}),
+				Status:    "captured",
+			}, append(opts, events.WithState(state))...)
+		case events.EventRideRated:
+			e = events.NewRideRated(tripID, events.RatingPayload{RatedBy: "passenger", Stars: 1 + r.IntN(5)},
+				append(opts, events.WithState(state))...)
+		case events.EventTipAdded:
+			e = events.NewTipAdded(tripID, driver, events.NewMoney(int64(100+r.IntN(900)), events.USD),
+				append(opts, events.WithState(state))...)
+		case events.EventPriceQuoted:
+			multiplier := float64(10+r.IntN(11)) / 10
+			e = events.NewPriceQuoted(tripID, events.PriceQuotePayload{
+				QuoteID:    randomUUID(r),
+				ZoneID:     events.ZoneID(pick(r, zones)),
+				Multiplier: multiplier,
+				DistanceKM: distance,
+				Breakdown: events.FareBreakdown{
+					Base:     events.NewMoney(250, events.USD),
+					Distance: events.NewMoney(100, events.USD).Mul(distance),
+					Time:     events.NewMoney(int64(50*(5+r.IntN(40))), events.USD),
+				},
+			}, append(opts, events.WithState(events.StateNew))...)
+		case events.EventSurgeUpdated:
+			e = events.NewSurgeUpdated(events.ZoneID(pick(r, zones)), float64(10+r.IntN(21))/10,
+				events.WithID(randomUUID(r)), events.WithTime(at))
+		default:
+			panic(fmt.Sprintf("eventstest: no generator for event type %s", t))
+		}
+		if t.IsLifecycle() || t == events.EventDriverArrived || t == events.EventPickedUp {
+			state = e.State
+		}
+		evts = append(evts, e)
+	}
+	return evts
+}
+
+var (
+	streets       = []string{"Main St", "Elm St", "Broadway", "5th Ave", "Park Ave", "Canal St", "Houston St", "Lexington Ave"}
+	cancelReasons = []string{"changed_plans", "driver_too_far", "passenger_no_show", "wrong_pickup"}
+	zones         = []string{"midtown", "downtown", "jfk", "lga", "brooklyn-heights"}
+)
+
+func pick(r *rand.Rand, from []string) string {
+	return from[r.IntN(len(from))]
+}
+
+// randomCoordinate returns a point in New York City, to six decimal places.
+func randomCoordinate(r *rand.Rand) events.Coordinate {
+	return events.Coordinate{
+		Lat: math.Round((40.60+r.Float64()*0.25)*1e6) / 1e6,
+		Lng: math.Round((-74.05+r.Float64()*0.30)*1e6) / 1e6,
+	}
+}
+
+// randomUUID returns a version 4 UUID drawn from r.
+func randomUUID(r *rand.Rand) string {
+	var u uuid.UUID
+	for i := range u {
+		u[i] = byte(r.Uint32())
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return u.String()
+}
+
+// encodeNDJSON encodes evts one per line.
+func encodeNDJSON(evts []events.RideEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range evts {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,114 @@
+package eventstest
+
+import (
+	"bytes"
+	"flag"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/canonical.ndjson")
+
+// TestCanonical_UpToDate checks that testdata/canonical.ndjson is what the
+// generator and the current encoding produce. After changing either, run
+// go test ./events/eventstest -update and review the diff.
+func TestCanonical_UpToDate(t *testing.T) {
+	got, err := encodeNDJSON(generateCanonical())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(filepath.Join("testdata", "canonical.ndjson"), got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if !bytes.Equal(got, canonicalNDJSON) {
+		t.Errorf("testdata/canonical.ndjson is out of date; run go test ./events/eventstest -update\ngot:\n%s", got)
+	}
+
+	// Decoding and encoding again changes nothing
+	again, err := encodeNDJSON(Canonical())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, canonicalNDJSON) {
+		t.Errorf("canonical events do not round-trip:\n%s", again)
+	}
+}
+
+func TestCanonical_Valid(t *testing.T) {
+	evts := Canonical()
+	if len(evts) != 14 {
+		t.Fatalf("expected 14 canonical events, got %d", len(evts))
+	}
+	for i, e := range evts {
+		if err := e.Validate(); err != nil {
+			t.Errorf("event %d (%s): %v", i+1, e.Type, err)
+		}
+	}
+	if evts[6].State != events.StateCompleted || evts[8].State != events.StateCompleted || evts[12].State != events.StateCancelled {
+		t.Errorf("unexpected states: %s, %s, %s", evts[6].State, evts[8].State, evts[12].State)
+	}
+}
+
+// eventTypes are the types RandomRideEvent generates.
+var eventTypes = []events.RideEventType{
+	events.EventRideRequested, events.EventRideAccepted, events.EventDriverArrived, events.EventPickedUp,
+	events.EventTripStarted, events.EventLocationUpdated, events.EventTripCompleted, events.EventTripCancelled,
+	events.EventPaymentProcessed, events.EventRideRated, events.EventTipAdded, events.EventSurgeUpdated, events.EventPriceQuoted,
+}
+
+func TestRandomRideEvent(t *testing.T) {
+	for _, typ := range eventTypes {
+		r := rand.New(rand.NewPCG(7, 7))
+		e := RandomRideEvent(r, typ)
+		if e.Type != typ {
+			t.Errorf("expected %s, got %s", typ, e.Type)
+		}
+		if err := e.Validate(); err != nil {
+			t.Errorf("%s: %v", typ, err)
+		}
+		if again := RandomRideEvent(rand.New(rand.NewPCG(7, 7)), typ); !reflect.DeepEqual(e, again) {
+			t.Errorf("%s: the same seed gave different events:\n%+v\n%+v", typ, e, again)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected an unknown type to panic")
+		}
+	}()
+	RandomRideEvent(rand.New(rand.NewPCG(7, 7)), "UNKNOWN")
+}
+
+// TestCanonical_Proto checks that the canonical events, and random events of
+// every type, come back unchanged from their protobuf form.
+func TestCanonical_Proto(t *testing.T) {
+	evts := Canonical()
+	r := rand.New(rand.NewPCG(11, 11))
+	for _, typ := range eventTypes {
+		for range 10 {
+			evts = append(evts, RandomRideEvent(r, typ))
+		}
+	}
+	for i, want := range evts {
+		m, err := events.ToProto(want)
+		if err != nil {
+			t.Fatalf("event %d (%s): ToProto failed: %v", i, want.Type, err)
+		}
+		got, err := events.FromProto(m)
+		if err != nil {
+			t.Fatalf("event %d (%s): FromProto failed: %v", i, want.Type, err)
+		}
+		want.SchemaVersion = events.SchemaVersion
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("event %d (%s) changed in protobuf:\n got %#v\nwant %#v", i, want.Type, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"fmt"
+	"math"
+)
+
+// earthRadiusM is the mean radius of the Earth in meters.
+const earthRadiusM = 6_371_000.0
+
+// Coordinate is a WGS 84 position in decimal degrees.
+type Coordinate struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Valid reports whether c is within the range of latitudes and longitudes.
+func (c Coordinate) Valid() bool {
+	return c.Lat >= -90 && c.Lat <= 90 && c.Lng >= -180 && c.Lng <= 180
+}
+
+// DistanceM returns the great-circle distance from c to to in meters, by the
+// haversine formula.
+func (c Coordinate) DistanceM(to Coordinate) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(to.Lat - c.Lat)
+	dLng := toRad(to.Lng - c.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(c.Lat))*math.Cos(toRad(to.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusM * math.Asin(math.Sqrt(min(h, 1)))
+}
+
+// DistanceKM returns the great-circle distance from c to to in kilometers.
+func (c Coordinate) DistanceKM(to Coordinate) float64 {
+	return c.DistanceM(to) / 1000
+}
+
+// Route is a path through its points in order. It encodes as a JSON array of
+// coordinates.
+type Route []Coordinate
+
+// DistanceM returns the length of r in meters, the sum of the great-circle
+// distances between its consecutive points. Routes of fewer than two points
+// have no length.
+func (r Route) DistanceM() float64 {
+	var total float64
+	for i := 1; i < len(r); i++ {
+		total += r[i-1].DistanceM(r[i])
+	}
+	return total
+}
+
+// DistanceKM returns the length of r in kilometers.
+func (r Route) DistanceKM() float64 {
+	return r.DistanceM() / 1000
+}
+
+// Valid reports whether every point of r is a valid coordinate.
+func (r Route) Valid() bool {
+	for _, c := range r {
+		if !c.Valid() {
+			return false
+		}
+	}
+	return true
+}
+
+// ZoneID identifies a pricing zone, such as "midtown", in surge updates and
+// zone reference data.
+type ZoneID string
+
+func (z ZoneID) String() string { return string(z) }
+
+// ZoneSize is the side of a pricing zone in degrees of latitude and
+// longitude, about 2km by 1.7km in New York.
+const ZoneSize = 0.02
+
+// Zone returns the pricing zone c is in: the cell of a grid of ZoneSize
+// named by its south-west corner, such as "40.74,-74.00".
+func (c Coordinate) Zone() ZoneID {
+	corner := func(deg float64) float64 { return math.Floor(deg/ZoneSize+1e-9) * ZoneSize }
+	return ZoneID(fmt.Sprintf("%.2f,%.2f", corner(c.Lat), corner(c.Lng)))
+}
+
+// Zone returns the pricing zone of the request: that of its pickup coordinate,
+// or else its pickup location.
+func (p RideRequestedPayload) Zone() ZoneID {
+	if p.Pickup != nil {
+		return p.Pickup.Zone()
+	}
+	return ZoneID(p.PickupLocation)
+}
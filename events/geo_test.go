@@ -0,0 +1,56 @@
+package events
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestCoordinate_Distance(t *testing.T) {
+	timesSquare := Coordinate{Lat: 40.7580, Lng: -73.9855}
+	tests := []struct {
+		name string
+		to   Coordinate
+		km   float64
+	}{
+		{"same point", timesSquare, 0},
+		{"jfk", Coordinate{Lat: 40.6413, Lng: -73.7781}, 21.77},
+		{"antipode", Coordinate{Lat: -40.7580, Lng: 106.0145}, math.Pi * earthRadiusM / 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := timesSquare.DistanceKM(tt.to); math.Abs(got-tt.km) > 0.01 {
+				t.Errorf("DistanceKM = %.3f, want %.3f", got, tt.km)
+			}
+			if a, b := timesSquare.DistanceM(tt.to), tt.to.DistanceM(timesSquare); math.Abs(a-b) > 1e-6 {
+				t.Errorf("distance is not symmetric: %v and %v", a, b)
+			}
+		})
+	}
+}
+
+func TestRoute(t *testing.T) {
+	a, b, c := Coordinate{Lat: 0, Lng: 0}, Coordinate{Lat: 0, Lng: 1}, Coordinate{Lat: 1, Lng: 1}
+	r := Route{a, b, c}
+	if want := a.DistanceM(b) + b.DistanceM(c); math.Abs(r.DistanceM()-want) > 1e-6 {
+		t.Errorf("DistanceM = %v, want %v", r.DistanceM(), want)
+	}
+	if got := (Route{a}).DistanceKM(); got != 0 {
+		t.Errorf("a single point has distance %v", got)
+	}
+	if !r.Valid() || (Route{a, {Lat: 91}}).Valid() {
+		t.Error("Valid did not check every point")
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"lat":0,"lng":0},{"lat":0,"lng":1},{"lat":1,"lng":1}]`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	var back Route
+	if err := json.Unmarshal(data, &back); err != nil || len(back) != 3 || back[2] != c {
+		t.Errorf("Unmarshal = %v, %v", back, err)
+	}
+}
@@ -0,0 +1,66 @@
+package events
+
+// Meta is the envelope metadata of an event: where it came from and how it
+// relates to other events and traces. All of it is optional, and none of it
+// changes what the event means. The encoding version stays in
+// RideEvent.SchemaVersion.
+type Meta struct {
+	// CorrelationID ties together every event of one flow, such as a trip.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// CausationID is the ID of the event or command that caused this one.
+	CausationID string `json:"causation_id,omitempty"`
+	// ProducerInstance names the process that produced the event, such as a host name.
+	ProducerInstance string `json:"producer_instance,omitempty"`
+	// TraceParent and TraceState are the W3C Trace Context of the span that
+	// produced the event.
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
+}
+
+// Message header keys Meta is carried under, so it can be read without
+// decoding the event.
+const (
+	HeaderCorrelationID    = "correlation-id"
+	HeaderCausationID      = "causation-id"
+	HeaderProducerInstance = "producer-instance"
+	HeaderTraceParent      = "traceparent"
+	HeaderTraceState       = "tracestate"
+)
+
+// IsZero reports whether m has no fields set.
+func (m Meta) IsZero() bool {
+	return m == Meta{}
+}
+
+// Headers returns the message headers that carry m, leaving out empty fields.
+func (m Meta) Headers() map[string]string {
+	headers := map[string]string{}
+	for key, value := range map[string]string{
+		HeaderCorrelationID:    m.CorrelationID,
+		HeaderCausationID:      m.CausationID,
+		HeaderProducerInstance: m.ProducerInstance,
+		HeaderTraceParent:      m.TraceParent,
+		HeaderTraceState:       m.TraceState,
+	} {
+		if value != "" {
+			headers[key] = value
+		}
+	}
+	return headers
+}
+
+// MetaFromHeaders reads Meta from message headers, ignoring headers it does not know.
+func MetaFromHeaders(headers map[string]string) Meta {
+	return Meta{
+		CorrelationID:    headers[HeaderCorrelationID],
+		CausationID:      headers[HeaderCausationID],
+		ProducerInstance: headers[HeaderProducerInstance],
+		TraceParent:      headers[HeaderTraceParent],
+		TraceState:       headers[HeaderTraceState],
+	}
+}
+
+// WithMeta sets the envelope metadata of the event.
+func WithMeta(m Meta) Option {
+	return func(e *RideEvent) { e.Meta = m }
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMeta_Headers(t *testing.T) {
+	m := Meta{
+		CorrelationID:    "trip-1",
+		CausationID:      "event-1",
+		ProducerInstance: "producer-7f9c",
+		TraceParent:      "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	headers := m.Headers()
+	if len(headers) != 4 || headers[HeaderTraceParent] != m.TraceParent {
+		t.Errorf("Headers() = %v, want the four fields that are set", headers)
+	}
+	headers["unrelated"] = "x"
+	if got := MetaFromHeaders(headers); got != m {
+		t.Errorf("MetaFromHeaders() = %+v, want %+v", got, m)
+	}
+	if !MetaFromHeaders(nil).IsZero() {
+		t.Error("expected no headers to give zero Meta")
+	}
+}
+
+func TestMeta_JSON(t *testing.T) {
+	e := NewTripStarted("trip-1")
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if strings.Contains(string(data), `"meta"`) {
+		t.Errorf("expected zero Meta to be left out, got %s", data)
+	}
+
+	e = NewTripStarted("trip-1", WithMeta(Meta{CorrelationID: "trip-1", CausationID: "event-1"}))
+	if data, err = json.Marshal(e); err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var got RideEvent
+	if err := UnmarshalStrict(data, &got); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.Meta != e.Meta {
+		t.Errorf("Meta = %+v, want %+v", got.Meta, e.Meta)
+	}
+}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// USD is the currency code of US dollars, the currency the simulated rides are
+// charged in.
+const USD = "USD"
+
+// ErrCurrencyMismatch is returned by arithmetic on amounts in different currencies.
+var ErrCurrencyMismatch = errors.New("events: currency mismatch")
+
+// Money is an amount of money in minor units, such as cents, of an ISO 4217
+// currency. Every currency is taken to have two decimal places. Amounts are
+// integers so that sums of fares and tips are exact. The zero Money is zero in
+// no currency, and adds to an amount in any currency, so it can start a sum.
+type Money struct {
+	Amount   int64  `json:"amount_minor"`
+	Currency string `json:"currency"`
+}
+
+// NewMoney returns amount minor units of currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// MoneyFromFloat returns amount units of currency, such as 12.5 dollars,
+// rounded to the nearest minor unit. It is for amounts that were floats, such
+// as fares from before Money.
+func MoneyFromFloat(amount float64, currency string) Money {
+	return Money{Amount: int64(math.Round(amount * 100)), Currency: currency}
+}
+
+// ParseMoney parses an amount written by Money.String, such as "12.50 USD".
+func ParseMoney(s string) (Money, error) {
+	amount, currency, ok := strings.Cut(strings.TrimSpace(s), " ")
+	whole, frac, hasFrac := strings.Cut(amount, ".")
+	if !ok || !validCurrency(currency) || whole == "" || whole == "-" || (hasFrac && len(frac) != 2) {
+		return Money{}, fmt.Errorf("events: invalid amount of money %q", s)
+	}
+	minor, err := strconv.ParseInt(whole+frac+strings.Repeat("0", 2-len(frac)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("events: invalid amount of money %q", s)
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Float64 returns m in units, such as dollars, for reports and the stores'
+// numeric columns.
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / 100
+}
+
+// IsZero reports whether m is zero, in any currency.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add returns m+o. It fails with ErrCurrencyMismatch if they are in different
+// currencies, unless one is the zero Money.
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := m.sameCurrency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: currency}, nil
+}
+
+// Sub returns m-o, failing like Add.
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(Money{Amount: -o.Amount, Currency: o.Currency})
+}
+
+// Mul returns m multiplied by factor, such as a surge multiplier, rounded to
+// the nearest minor unit.
+func (m Money) Mul(factor float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * factor)), Currency: m.Currency}
+}
+
+// String formats m as its amount in units and its currency, such as "12.50 USD".
+func (m Money) String() string {
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%02d %s", sign, amount/100, amount%100, m.Currency)
+}
+
+func (m Money) sameCurrency(o Money) (string, error) {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency, nil
+	case m == Money{}:
+		return o.Currency, nil
+	case o == Money{}:
+		return m.Currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+}
+
+// validCurrency reports whether c has the form of an ISO 4217 code.
+func validCurrency(c string) bool {
+	if len(c) != 3 {
+		return false
+	}
+	for _, r := range c {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMoney_Arithmetic(t *testing.T) {
+	fare := NewMoney(1250, USD)
+	tip := NewMoney(199, USD)
+
+	sum, err := fare.Add(tip)
+	if err != nil || sum != NewMoney(1449, USD) {
+		t.Errorf("Add = %v, %v", sum, err)
+	}
+	if diff, err := tip.Sub(fare); err != nil || diff != NewMoney(-1051, USD) {
+		t.Errorf("Sub = %v, %v", diff, err)
+	}
+	if got := fare.Mul(1.35); got != NewMoney(1688, USD) {
+		t.Errorf("Mul = %v", got)
+	}
+	if got, _ := (Money{}).Add(tip); got != tip {
+		t.Errorf("the zero Money did not take the currency of the sum, got %v", got)
+	}
+	if _, err := fare.Add(NewMoney(100, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch, got %v", err)
+	}
+
+	// Ten dimes are exactly a dollar, unlike ten 0.1 floats
+	var total Money
+	for range 10 {
+		total, _ = total.Add(MoneyFromFloat(0.1, USD))
+	}
+	if total != NewMoney(100, USD) || total.Float64() != 1 {
+		t.Errorf("ten dimes = %v", total)
+	}
+}
+
+func TestMoney_StringAndParse(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{NewMoney(1250, USD), "12.50 USD"},
+		{NewMoney(5, "EUR"), "0.05 EUR"},
+		{NewMoney(-1051, USD), "-10.51 USD"},
+		{NewMoney(0, USD), "0.00 USD"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+		if got, err := ParseMoney(tt.want); err != nil || got != tt.m {
+			t.Errorf("ParseMoney(%q) = %v, %v", tt.want, got, err)
+		}
+	}
+	if got, err := ParseMoney("12 USD"); err != nil || got != NewMoney(1200, USD) {
+		t.Errorf("ParseMoney(12 USD) = %v, %v", got, err)
+	}
+	for _, s := range []string{"", "12.50", "12.5 USD", "12.50 usd", "abc USD", "- USD", "1.234 USD"} {
+		if _, err := ParseMoney(s); err == nil {
+			t.Errorf("ParseMoney(%q) succeeded", s)
+		}
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(NewMoney(1875, USD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount_minor":1875,"currency":"USD"}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	var m Money
+	if err := json.Unmarshal(data, &m); err != nil || m != NewMoney(1875, USD) {
+		t.Errorf("Unmarshal = %v, %v", m, err)
+	}
+}
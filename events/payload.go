@@ -0,0 +1,14 @@
+package events
+
+// PayloadAs returns e's payload as a T, and whether it is one:
+//
+//	if p, ok := events.PayloadAs[events.RideCompletedPayload](e); ok {
+//		fare += p.Fare.Amount
+//	}
+//
+// T is the struct itself for the payloads of this package, and whatever
+// DecodePayload returns for registered ones.
+func PayloadAs[T RideEventPayload](e RideEvent) (T, bool) {
+	p, ok := e.Payload.(T)
+	return p, ok
+}
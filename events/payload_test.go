@@ -0,0 +1,21 @@
+package events
+
+import "testing"
+
+func TestPayloadAs(t *testing.T) {
+	e := RideEvent{Type: EventTripCompleted, Payload: RideCompletedPayload{Fare: NewMoney(1250, USD)}}
+	if p, ok := PayloadAs[RideCompletedPayload](e); !ok || p.Fare.Amount != 1250 {
+		t.Errorf("PayloadAs[RideCompletedPayload] = %#v, %v", p, ok)
+	}
+	if p, ok := PayloadAs[RideStartedPayload](e); ok || !p.StartTime.IsZero() {
+		t.Errorf("expected no RideStartedPayload, got %#v, %v", p, ok)
+	}
+	if _, ok := PayloadAs[RideCompletedPayload](RideEvent{Type: EventTripCompleted}); ok {
+		t.Error("expected no payload from an event without one")
+	}
+
+	noted := RideEvent{Type: eventNoted, Payload: &pointerPayload{Note: "hi"}}
+	if p, ok := PayloadAs[*pointerPayload](noted); !ok || p.Note != "hi" {
+		t.Errorf("PayloadAs[*pointerPayload] = %#v, %v", p, ok)
+	}
+}
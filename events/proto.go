@@ -0,0 +1,375 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/pedeveaux/kafkarideshare/proto/rideshare/v1"
+)
+
+// ToProto converts e to its protobuf form, rideshare.v1.RideEvent, so services
+// can send the same events over gRPC or in protobuf messages as in JSON. Each
+// built-in payload is a field of the payload oneof. Payloads added with
+// RegisterPayload have none, so, as with MarshalAvro, events carrying them
+// cannot be converted. SchemaVersion is not carried.
+func ToProto(e RideEvent) (*pb.RideEvent, error) {
+	out := &pb.RideEvent{
+		Id:          e.ID,
+		TripId:      e.TripID,
+		EventType:   string(e.Type),
+		EventTime:   timestampToProto(e.OccurredAt),
+		RideState:   string(e.State),
+		DriverId:    e.DriverID,
+		PassengerId: e.PassengerID,
+		City:        e.City,
+		RecordedAt:  timestampToProto(e.RecordedAt),
+		Meta:        metaToProto(e.Meta),
+	}
+	switch p := e.Payload.(type) {
+	case nil:
+	case RideRequestedPayload:
+		out.Payload = &pb.RideEvent_Requested{Requested: &pb.RideRequested{
+			Passenger:       p.Passenger,
+			PickupLocation:  p.PickupLocation,
+			DropoffLocation: p.DropoffLocation,
+			Pickup:          coordinatePtrToProto(p.Pickup),
+			Dropoff:         coordinatePtrToProto(p.Dropoff),
+		}}
+	case RideAcceptedPayload:
+		out.Payload = &pb.RideEvent_Accepted{Accepted: &pb.RideAccepted{DriverId: p.DriverID}}
+	case DriverArrivedPayload:
+		out.Payload = &pb.RideEvent_DriverArrived{DriverArrived: &pb.DriverArrived{
+			DriverId: p.DriverID,
+			Location: coordinatePtrToProto(p.Location),
+		}}
+	case PickedUpPayload:
+		out.Payload = &pb.RideEvent_PickedUp{PickedUp: &pb.PickedUp{
+			PickupTime: timestampToProto(p.PickupTime),
+			Location:   coordinatePtrToProto(p.Location),
+		}}
+	case LocationUpdatedPayload:
+		out.Payload = &pb.RideEvent_LocationUpdated{LocationUpdated: &pb.LocationUpdated{
+			Location:   coordinateToProto(p.Location),
+			HeadingDeg: p.HeadingDeg,
+			SpeedKph:   p.SpeedKPH,
+			AccuracyM:  p.AccuracyM,
+		}}
+	case RideStartedPayload:
+		out.Payload = &pb.RideEvent_Started{Started: &pb.RideStarted{StartTime: timestampToProto(p.StartTime)}}
+	case RideCompletedPayload:
+		completed := &pb.RideCompleted{
+			EndTime:    timestampToProto(p.EndTime),
+			DistanceKm: p.DistanceKM,
+			Fare:       moneyToProto(p.Fare),
+		}
+		if p.Fare.Currency == USD {
+			completed.FareUsd = p.Fare.Float64()
+		}
+		out.Payload = &pb.RideEvent_Completed{Completed: completed}
+	case RideCancelledPayload:
+		out.Payload = &pb.RideEvent_Cancelled{Cancelled: &pb.RideCancelled{
+			CancelledBy: string(p.CancelledBy),
+			Reason:      string(p.Reason),
+		}}
+	case SurgeUpdatedPayload:
+		out.Payload = &pb.RideEvent_SurgeUpdated{SurgeUpdated: &pb.SurgeUpdated{
+			ZoneId:     string(p.ZoneID),
+			ZoneName:   p.ZoneName,
+			Multiplier: p.Multiplier,
+		}}
+	case PriceQuotePayload:
+		out.Payload = &pb.RideEvent_PriceQuote{PriceQuote: &pb.PriceQuote{
+			QuoteId:    p.QuoteID,
+			ZoneId:     string(p.ZoneID),
+			Multiplier: p.Multiplier,
+			DistanceKm: p.DistanceKM,
+			Breakdown: &pb.FareBreakdown{
+				Base:     moneyToProto(p.Breakdown.Base),
+				Distance: moneyToProto(p.Breakdown.Distance),
+				Time:     moneyToProto(p.Breakdown.Time),
+			},
+			Total:     moneyToProto(p.Total),
+			ExpiresAt: timestampToProto(p.ExpiresAt),
+		}}
+	case PaymentPayload:
+		out.Payload = &pb.RideEvent_Payment{Payment: &pb.Payment{
+			PaymentId: p.PaymentID,
+			Amount:    moneyToProto(p.Amount),
+			Method:    p.Method,
+			Status:    p.Status,
+		}}
+	case RatingPayload:
+		out.Payload = &pb.RideEvent_Rating{Rating: &pb.Rating{
+			RatedBy: p.RatedBy,
+			Stars:   int32(p.Stars),
+			Comment: p.Comment,
+		}}
+	case TipPayload:
+		out.Payload = &pb.RideEvent_Tip{Tip: &pb.Tip{DriverId: p.DriverID, Amount: moneyToProto(p.Amount)}}
+	default:
+		return nil, fmt.Errorf("events: no protobuf form for %s payload", PayloadTypeName(p))
+	}
+	return out, nil
+}
+
+// FromProto converts the protobuf form of an event back to a RideEvent, stamped
+// with the current SchemaVersion like an event decoded from JSON. Unknown
+// cancellation actors and reasons fail as they do decoding JSON, an empty actor
+// is left for Validate to report, and a
+// RideCompleted without fare has its fare read from fare_usd.
+func FromProto(m *pb.RideEvent) (RideEvent, error) {
+	e := RideEvent{
+		ID:            m.GetId(),
+		TripID:        m.GetTripId(),
+		Type:          RideEventType(m.GetEventType()),
+		OccurredAt:    timestampFromProto(m.GetEventTime()),
+		State:         RideState(m.GetRideState()),
+		DriverID:      m.GetDriverId(),
+		PassengerID:   m.GetPassengerId(),
+		City:          m.GetCity(),
+		SchemaVersion: SchemaVersion,
+		Meta:          metaFromProto(m.GetMeta()),
+		RecordedAt:    timestampFromProto(m.GetRecordedAt()),
+	}
+	switch p := m.GetPayload().(type) {
+	case *pb.RideEvent_Requested:
+		e.Payload = RideRequestedPayload{
+			Passenger:       p.Requested.GetPassenger(),
+			PickupLocation:  p.Requested.GetPickupLocation(),
+			DropoffLocation: p.Requested.GetDropoffLocation(),
+			Pickup:          coordinatePtrFromProto(p.Requested.GetPickup()),
+			Dropoff:         coordinatePtrFromProto(p.Requested.GetDropoff()),
+		}
+	case *pb.RideEvent_Accepted:
+		e.Payload = RideAcceptedPayload{DriverID: p.Accepted.GetDriverId()}
+	case *pb.RideEvent_DriverArrived:
+		e.Payload = DriverArrivedPayload{
+			DriverID: p.DriverArrived.GetDriverId(),
+			Location: coordinatePtrFromProto(p.DriverArrived.GetLocation()),
+		}
+	case *pb.RideEvent_PickedUp:
+		e.Payload = PickedUpPayload{
+			PickupTime: timestampFromProto(p.PickedUp.GetPickupTime()),
+			Location:   coordinatePtrFromProto(p.PickedUp.GetLocation()),
+		}
+	case *pb.RideEvent_LocationUpdated:
+		e.Payload = LocationUpdatedPayload{
+			Location:   coordinateFromProto(p.LocationUpdated.GetLocation()),
+			HeadingDeg: p.LocationUpdated.GetHeadingDeg(),
+			SpeedKPH:   p.LocationUpdated.GetSpeedKph(),
+			AccuracyM:  p.LocationUpdated.GetAccuracyM(),
+		}
+	case *pb.RideEvent_Started:
+		e.Payload = RideStartedPayload{StartTime: timestampFromProto(p.Started.GetStartTime())}
+	case *pb.RideEvent_Completed:
+		fare := moneyFromProto(p.Completed.GetFare())
+		if p.Completed.GetFare() == nil {
+			fare = MoneyFromFloat(p.Completed.GetFareUsd(), USD)
+		}
+		e.Payload = RideCompletedPayload{
+			EndTime:    timestampFromProto(p.Completed.GetEndTime()),
+			DistanceKM: p.Completed.GetDistanceKm(),
+			Fare:       fare,
+		}
+	case *pb.RideEvent_Cancelled:
+		var cancelled RideCancelledPayload
+		if by := p.Cancelled.GetCancelledBy(); by != "" {
+			if err := cancelled.CancelledBy.UnmarshalText([]byte(by)); err != nil {
+				return e, err
+			}
+		}
+		if err := cancelled.Reason.UnmarshalText([]byte(p.Cancelled.GetReason())); err != nil {
+			return e, err
+		}
+		e.Payload = cancelled
+	case *pb.RideEvent_SurgeUpdated:
+		e.Payload = SurgeUpdatedPayload{
+			ZoneID:     ZoneID(p.SurgeUpdated.GetZoneId()),
+			ZoneName:   p.SurgeUpdated.GetZoneName(),
+			Multiplier: p.SurgeUpdated.GetMultiplier(),
+		}
+	case *pb.RideEvent_PriceQuote:
+		breakdown := p.PriceQuote.GetBreakdown()
+		e.Payload = PriceQuotePayload{
+			QuoteID:    p.PriceQuote.GetQuoteId(),
+			ZoneID:     ZoneID(p.PriceQuote.GetZoneId()),
+			Multiplier: p.PriceQuote.GetMultiplier(),
+			DistanceKM: p.PriceQuote.GetDistanceKm(),
+			Breakdown: FareBreakdown{
+				Base:     moneyFromProto(breakdown.GetBase()),
+				Distance: moneyFromProto(breakdown.GetDistance()),
+				Time:     moneyFromProto(breakdown.GetTime()),
+			},
+			Total:     moneyFromProto(p.PriceQuote.GetTotal()),
+			ExpiresAt: timestampFromProto(p.PriceQuote.GetExpiresAt()),
+		}
+	case *pb.RideEvent_Payment:
+		e.Payload = PaymentPayload{
+			PaymentID: p.Payment.GetPaymentId(),
+			Amount:    moneyFromProto(p.Payment.GetAmount()),
+			Method:    p.Payment.GetMethod(),
+			Status:    p.Payment.GetStatus(),
+		}
+	case *pb.RideEvent_Rating:
+		e.Payload = RatingPayload{
+			RatedBy: p.Rating.GetRatedBy(),
+			Stars:   int(p.Rating.GetStars()),
+			Comment: p.Rating.GetComment(),
+		}
+	case *pb.RideEvent_Tip:
+		e.Payload = TipPayload{DriverID: p.Tip.GetDriverId(), Amount: moneyFromProto(p.Tip.GetAmount())}
+	}
+	return e, nil
+}
+
+// DriverToProto converts e to its protobuf form, rideshare.v1.DriverEvent.
+func DriverToProto(e DriverEvent) (*pb.DriverEvent, error) {
+	out := &pb.DriverEvent{
+		Id:         e.ID,
+		DriverId:   e.DriverID,
+		EventType:  string(e.Type),
+		EventTime:  timestampToProto(e.OccurredAt),
+		City:       e.City,
+		RecordedAt: timestampToProto(e.RecordedAt),
+		Meta:       metaToProto(e.Meta),
+	}
+	switch p := e.Payload.(type) {
+	case nil:
+	case ShiftStartedPayload:
+		out.Payload = &pb.DriverEvent_ShiftStarted{ShiftStarted: &pb.ShiftStarted{
+			VehicleId: p.VehicleID,
+			Location:  coordinateToProto(p.Location),
+		}}
+	case ShiftEndedPayload:
+		out.Payload = &pb.DriverEvent_ShiftEnded{ShiftEnded: &pb.ShiftEnded{
+			TripsCompleted: int32(p.TripsCompleted),
+			Reason:         p.Reason,
+		}}
+	case HeartbeatPayload:
+		out.Payload = &pb.DriverEvent_Heartbeat{Heartbeat: &pb.Heartbeat{
+			Location:  coordinateToProto(p.Location),
+			Available: p.Available,
+		}}
+	case RelocatedPayload:
+		out.Payload = &pb.DriverEvent_Relocated{Relocated: &pb.Relocated{
+			From:   coordinateToProto(p.From),
+			To:     coordinateToProto(p.To),
+			ZoneId: string(p.ZoneID),
+		}}
+	default:
+		return nil, fmt.Errorf("events: no protobuf form for driver payload %T", p)
+	}
+	return out, nil
+}
+
+// DriverFromProto converts the protobuf form of a driver event back to a
+// DriverEvent, stamped with DriverSchemaVersion.
+func DriverFromProto(m *pb.DriverEvent) (DriverEvent, error) {
+	e := DriverEvent{
+		ID:            m.GetId(),
+		DriverID:      m.GetDriverId(),
+		Type:          DriverEventType(m.GetEventType()),
+		OccurredAt:    timestampFromProto(m.GetEventTime()),
+		City:          m.GetCity(),
+		SchemaVersion: DriverSchemaVersion,
+		Meta:          metaFromProto(m.GetMeta()),
+		RecordedAt:    timestampFromProto(m.GetRecordedAt()),
+	}
+	switch p := m.GetPayload().(type) {
+	case *pb.DriverEvent_ShiftStarted:
+		e.Payload = ShiftStartedPayload{
+			VehicleID: p.ShiftStarted.GetVehicleId(),
+			Location:  coordinateFromProto(p.ShiftStarted.GetLocation()),
+		}
+	case *pb.DriverEvent_ShiftEnded:
+		e.Payload = ShiftEndedPayload{
+			TripsCompleted: int(p.ShiftEnded.GetTripsCompleted()),
+			Reason:         p.ShiftEnded.GetReason(),
+		}
+	case *pb.DriverEvent_Heartbeat:
+		e.Payload = HeartbeatPayload{
+			Location:  coordinateFromProto(p.Heartbeat.GetLocation()),
+			Available: p.Heartbeat.GetAvailable(),
+		}
+	case *pb.DriverEvent_Relocated:
+		e.Payload = RelocatedPayload{
+			From:   coordinateFromProto(p.Relocated.GetFrom()),
+			To:     coordinateFromProto(p.Relocated.GetTo()),
+			ZoneID: ZoneID(p.Relocated.GetZoneId()),
+		}
+	}
+	return e, nil
+}
+
+// timestampToProto leaves zero times unset.
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// timestampFromProto reads unset timestamps as zero times, and others in UTC.
+func timestampFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func coordinateToProto(c Coordinate) *pb.Coordinate {
+	return &pb.Coordinate{Lat: c.Lat, Lng: c.Lng}
+}
+
+func coordinateFromProto(c *pb.Coordinate) Coordinate {
+	return Coordinate{Lat: c.GetLat(), Lng: c.GetLng()}
+}
+
+func coordinatePtrToProto(c *Coordinate) *pb.Coordinate {
+	if c == nil {
+		return nil
+	}
+	return coordinateToProto(*c)
+}
+
+func coordinatePtrFromProto(c *pb.Coordinate) *Coordinate {
+	if c == nil {
+		return nil
+	}
+	coord := coordinateFromProto(c)
+	return &coord
+}
+
+func moneyToProto(m Money) *pb.Money {
+	return &pb.Money{AmountMinor: m.Amount, Currency: m.Currency}
+}
+
+func moneyFromProto(m *pb.Money) Money {
+	return Money{Amount: m.GetAmountMinor(), Currency: m.GetCurrency()}
+}
+
+// metaToProto leaves empty metadata unset.
+func metaToProto(m Meta) *pb.Meta {
+	if m.IsZero() {
+		return nil
+	}
+	return &pb.Meta{
+		CorrelationId:    m.CorrelationID,
+		CausationId:      m.CausationID,
+		ProducerInstance: m.ProducerInstance,
+		Traceparent:      m.TraceParent,
+		Tracestate:       m.TraceState,
+	}
+}
+
+func metaFromProto(m *pb.Meta) Meta {
+	return Meta{
+		CorrelationID:    m.GetCorrelationId(),
+		CausationID:      m.GetCausationId(),
+		ProducerInstance: m.GetProducerInstance(),
+		TraceParent:      m.GetTraceparent(),
+		TraceState:       m.GetTracestate(),
+	}
+}
@@ -0,0 +1,219 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "github.com/pedeveaux/kafkarideshare/proto/rideshare/v1"
+)
+
+func TestProto_RoundTrip(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 123456789, time.UTC)
+	at := &Coordinate{Lat: 40.75, Lng: -73.98}
+	to := &Coordinate{Lat: 40.71, Lng: -74.01}
+	cases := []RideEvent{
+		{ID: "id1", TripID: "trip1", Type: EventRideRequested, OccurredAt: now, State: StateRequested, PassengerID: "rider-1", City: "nyc",
+			Payload: RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St", Pickup: at, Dropoff: to}},
+		{ID: "id2", TripID: "trip1", Type: EventRideAccepted, OccurredAt: now, State: StateAccepted, DriverID: "driver-1",
+			Payload: RideAcceptedPayload{DriverID: "driver-1"}},
+		{ID: "id3", TripID: "trip1", Type: EventDriverArrived, OccurredAt: now, State: StateDriverArrived,
+			Payload: DriverArrivedPayload{DriverID: "driver-1", Location: at}},
+		{ID: "id4", TripID: "trip1", Type: EventPickedUp, OccurredAt: now, State: StatePickedUp, Payload: PickedUpPayload{PickupTime: now}},
+		{ID: "id5", TripID: "trip1", Type: EventTripStarted, OccurredAt: now, State: StateInProgress, Payload: RideStartedPayload{StartTime: now}},
+		{ID: "id6", TripID: "trip1", Type: EventLocationUpdated, OccurredAt: now, State: StateInProgress,
+			Payload: LocationUpdatedPayload{Location: *at, HeadingDeg: 87.5, SpeedKPH: 31, AccuracyM: 4}},
+		{ID: "id7", TripID: "trip1", Type: EventTripCompleted, OccurredAt: now, State: StateCompleted,
+			Payload: RideCompletedPayload{EndTime: now, DistanceKM: 5.2, Fare: NewMoney(1875, USD)}},
+		{ID: "id8", TripID: "trip1", Type: EventPaymentProcessed, OccurredAt: now, State: StateCompleted,
+			Payload: PaymentPayload{PaymentID: "pay-1", Amount: NewMoney(1875, USD), Method: "card", Status: "captured"}},
+		{ID: "id9", TripID: "trip1", Type: EventRideRated, OccurredAt: now, State: StateCompleted,
+			Payload: RatingPayload{RatedBy: "passenger", Stars: 5, Comment: "great"}},
+		{ID: "id10", TripID: "trip1", Type: EventTipAdded, OccurredAt: now, State: StateCompleted, Payload: TipPayload{DriverID: "driver-1", Amount: NewMoney(300, USD)}},
+		{ID: "id11", TripID: "trip2", Type: EventTripCancelled, OccurredAt: now, State: StateCancelled,
+			Payload: RideCancelledPayload{CancelledBy: ActorDriver, Reason: ReasonDriverNoShow}},
+		{ID: "id12", Type: EventSurgeUpdated, OccurredAt: now, Payload: SurgeUpdatedPayload{ZoneID: "midtown", ZoneName: "Midtown", Multiplier: 1.4}},
+		{ID: "id13", TripID: "trip4", Type: EventPriceQuoted, OccurredAt: now, State: StateNew,
+			Payload: PriceQuotePayload{QuoteID: "quote-1", ZoneID: "midtown", Multiplier: 1.5, DistanceKM: 5.2,
+				Breakdown: FareBreakdown{Base: NewMoney(250, USD), Distance: NewMoney(520, USD), Time: NewMoney(400, USD)},
+				Total:     NewMoney(1755, USD), ExpiresAt: now.Add(QuoteTTL)}},
+		{ID: "id14", TripID: "trip3", Type: EventTripStarted, OccurredAt: now, State: StateInProgress},
+		{ID: "id15", TripID: "trip3", Type: EventTripStarted, OccurredAt: now, State: StateInProgress, Payload: RideStartedPayload{StartTime: now},
+			Meta: Meta{CorrelationID: "trip3", CausationID: "id14", ProducerInstance: "producer-1", TraceParent: "00-abc-def-01", TraceState: "k=v"}},
+		{ID: "id16", TripID: "trip3", Type: EventTripStarted, OccurredAt: now, State: StateInProgress, RecordedAt: now.Add(1500 * time.Millisecond)},
+		{ID: "id17", TripID: "trip5", Type: EventTripCompleted, OccurredAt: now, State: StateCompleted,
+			Payload: RideCompletedPayload{EndTime: now, DistanceKM: 3, Fare: NewMoney(1200, "EUR")}},
+	}
+	for _, want := range cases {
+		t.Run(want.ID, func(t *testing.T) {
+			m, err := ToProto(want)
+			if err != nil {
+				t.Fatalf("ToProto failed: %v", err)
+			}
+			// Go through the wire, as another service would
+			data, err := proto.Marshal(m)
+			if err != nil {
+				t.Fatalf("proto.Marshal failed: %v", err)
+			}
+			var decoded pb.RideEvent
+			if err := proto.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("proto.Unmarshal failed: %v", err)
+			}
+			got, err := FromProto(&decoded)
+			if err != nil {
+				t.Fatalf("FromProto failed: %v", err)
+			}
+			want.SchemaVersion = SchemaVersion
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip changed the event:\n got %#v\nwant %#v", got, want)
+			}
+		})
+	}
+
+	if _, err := ToProto(RideEvent{ID: "id1", Type: eventRated, OccurredAt: now, Payload: ratedPayload{Stars: 4}}); err == nil {
+		t.Error("expected a registered payload without a protobuf message to fail")
+	}
+}
+
+// TestProto_MatchesJSON checks that an event sent as JSON and one sent as
+// protobuf decode to the same RideEvent.
+func TestProto_MatchesJSON(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	e := NewTripCompleted("trip1", 5.2, NewMoney(1875, USD), WithID("id1"), WithTime(now), WithDriver("driver-1"),
+		WithMeta(Meta{CorrelationID: "trip1"}))
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON RideEvent
+	if err := UnmarshalStrict(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ToProto(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromProto, err := FromProto(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := Diff(fromJSON, fromProto); len(diff) > 0 {
+		t.Errorf("JSON and protobuf decode differently: %v", diff)
+	}
+}
+
+// TestProto_CoversPayloads checks that every built-in payload has a message in
+// the payload oneof with a field for each of its JSON fields, so that none is
+// dropped in conversion.
+func TestProto_CoversPayloads(t *testing.T) {
+	check := func(oneof protoreflect.OneofDescriptor, payload any) {
+		t.Helper()
+		name := strings.TrimSuffix(reflect.TypeOf(payload).Elem().Name(), "Payload")
+		var msg protoreflect.MessageDescriptor
+		for i := 0; i < oneof.Fields().Len(); i++ {
+			if f := oneof.Fields().Get(i); string(f.Message().Name()) == name {
+				msg = f.Message()
+			}
+		}
+		if msg == nil {
+			t.Errorf("no %s field in %s", name, oneof.FullName())
+			return
+		}
+		for field := range objectSchema(reflect.TypeOf(payload).Elem())["properties"].(map[string]any) {
+			if msg.Fields().ByName(protoreflect.Name(field)) == nil {
+				t.Errorf("%s has no field %s", msg.FullName(), field)
+			}
+		}
+	}
+
+	rideOneof := (&pb.RideEvent{}).ProtoReflect().Descriptor().Oneofs().ByName("payload")
+	payloadsMu.RLock()
+	for typ, newPayload := range payloads {
+		if !strings.HasPrefix(string(typ), "TEST_") {
+			check(rideOneof, newPayload())
+		}
+	}
+	payloadsMu.RUnlock()
+
+	driverOneof := (&pb.DriverEvent{}).ProtoReflect().Descriptor().Oneofs().ByName("payload")
+	for _, newPayload := range driverPayloads {
+		check(driverOneof, newPayload())
+	}
+}
+
+func TestFromProto_FareUSD(t *testing.T) {
+	m := &pb.RideEvent{Id: "id1", EventType: string(EventTripCompleted),
+		Payload: &pb.RideEvent_Completed{Completed: &pb.RideCompleted{DistanceKm: 5.2, FareUsd: 18.75}}}
+	e, err := FromProto(m)
+	if err != nil {
+		t.Fatalf("FromProto failed: %v", err)
+	}
+	if p, ok := PayloadAs[RideCompletedPayload](e); !ok || p.Fare != NewMoney(1875, USD) {
+		t.Errorf("payload = %#v, want a fare of 18.75 USD", e.Payload)
+	}
+
+	m, err = ToProto(RideEvent{ID: "id2", Type: EventTripCompleted, Payload: RideCompletedPayload{Fare: NewMoney(1200, "EUR")}})
+	if err != nil {
+		t.Fatalf("ToProto failed: %v", err)
+	}
+	if got := m.GetCompleted().GetFareUsd(); got != 0 {
+		t.Errorf("fare_usd = %v for a EUR fare, want unset", got)
+	}
+}
+
+func TestFromProto_Cancellation(t *testing.T) {
+	cancelled := func(by, reason string) *pb.RideEvent {
+		return &pb.RideEvent{Id: "id1", EventType: string(EventTripCancelled),
+			Payload: &pb.RideEvent_Cancelled{Cancelled: &pb.RideCancelled{CancelledBy: by, Reason: reason}}}
+	}
+	if _, err := FromProto(cancelled("bystander", "")); !errors.Is(err, ErrUnknownActor) {
+		t.Errorf("unknown actor: err = %v, want ErrUnknownActor", err)
+	}
+	if _, err := FromProto(cancelled("driver", "traffic")); !errors.Is(err, ErrUnknownCancelReason) {
+		t.Errorf("unknown reason: err = %v, want ErrUnknownCancelReason", err)
+	}
+	e, err := FromProto(cancelled("", ""))
+	if err != nil {
+		t.Fatalf("FromProto failed: %v", err)
+	}
+	if err := e.Validate(); err == nil {
+		t.Error("expected Validate to report the missing actor")
+	}
+}
+
+func TestDriverProto_RoundTrip(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := Coordinate{Lat: 40.75, Lng: -73.98}
+	cases := []DriverEvent{
+		{ID: "d1", DriverID: "driver-1", Type: EventShiftStarted, OccurredAt: now, City: "nyc",
+			Payload: ShiftStartedPayload{VehicleID: "car-1", Location: at}},
+		{ID: "d2", DriverID: "driver-1", Type: EventHeartbeat, OccurredAt: now, Payload: HeartbeatPayload{Location: at, Available: true}},
+		{ID: "d3", DriverID: "driver-1", Type: EventRelocated, OccurredAt: now,
+			Payload: RelocatedPayload{From: at, To: Coordinate{Lat: 40.71, Lng: -74.01}, ZoneID: "downtown"}},
+		{ID: "d4", DriverID: "driver-1", Type: EventShiftEnded, OccurredAt: now, RecordedAt: now.Add(time.Second),
+			Meta: Meta{ProducerInstance: "driver-app"}, Payload: ShiftEndedPayload{TripsCompleted: 12, Reason: "end of day"}},
+		{ID: "d5", DriverID: "driver-1", Type: EventHeartbeat, OccurredAt: now},
+	}
+	for _, want := range cases {
+		t.Run(want.ID, func(t *testing.T) {
+			m, err := DriverToProto(want)
+			if err != nil {
+				t.Fatalf("DriverToProto failed: %v", err)
+			}
+			got, err := DriverFromProto(m)
+			if err != nil {
+				t.Fatalf("DriverFromProto failed: %v", err)
+			}
+			want.SchemaVersion = DriverSchemaVersion
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip changed the event:\n got %#v\nwant %#v", got, want)
+			}
+		})
+	}
+}
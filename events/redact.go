@@ -0,0 +1,108 @@
+package events
+
+import (
+	"log/slog"
+	"math"
+)
+
+// RedactedText replaces free text that may identify a person, such as a name
+// or a street address, in redacted events.
+const RedactedText = "[redacted]"
+
+// redactedDecimals is how many decimal places of a coordinate redaction keeps:
+// two, about a kilometer, enough to tell the zone but not the door.
+const redactedDecimals = 2
+
+// Redacted returns a copy of e that is safe to log, with the personal data in
+// its payload masked: names, addresses, and comments become RedactedText and
+// coordinates are rounded to about a kilometer. IDs are pseudonymous and kept,
+// so redacted events can still be traced. Payloads mask themselves through a
+// Redacted method returning the masked copy, which payloads registered outside
+// this package implement to be masked too.
+func (e RideEvent) Redacted() RideEvent {
+	if p, ok := e.Payload.(interface{ Redacted() RideEventPayload }); ok {
+		e.Payload = p.Redacted()
+	}
+	return e
+}
+
+// LogValue implements slog.LogValuer, so events passed to slog are logged
+// redacted (see Redacted) rather than with their full payloads.
+func (e RideEvent) LogValue() slog.Value {
+	r := e.Redacted()
+	attrs := []slog.Attr{
+		slog.String("id", r.ID),
+		slog.String("type", string(r.Type)),
+		slog.Time("event_time", r.OccurredAt),
+	}
+	for _, a := range []slog.Attr{
+		slog.String("trip_id", r.TripID),
+		slog.String("state", string(r.State)),
+		slog.String("city", r.City),
+	} {
+		if a.Value.String() != "" {
+			attrs = append(attrs, a)
+		}
+	}
+	if r.Payload != nil {
+		attrs = append(attrs, slog.Any("payload", r.Payload))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// redactText returns RedactedText in place of s, unless s is empty.
+func redactText(s string) string {
+	if s == "" {
+		return ""
+	}
+	return RedactedText
+}
+
+// coarse returns c rounded to redactedDecimals decimal places.
+func (c Coordinate) coarse() Coordinate {
+	scale := math.Pow(10, redactedDecimals)
+	return Coordinate{Lat: math.Round(c.Lat*scale) / scale, Lng: math.Round(c.Lng*scale) / scale}
+}
+
+// redactCoordinate returns a coarse copy of c, or nil for nil.
+func redactCoordinate(c *Coordinate) *Coordinate {
+	if c == nil {
+		return nil
+	}
+	coarse := c.coarse()
+	return &coarse
+}
+
+// Redacted masks the passenger's name and both addresses, and coarsens the coordinates.
+func (p RideRequestedPayload) Redacted() RideEventPayload {
+	p.Passenger = redactText(p.Passenger)
+	p.PickupLocation = redactText(p.PickupLocation)
+	p.DropoffLocation = redactText(p.DropoffLocation)
+	p.Pickup = redactCoordinate(p.Pickup)
+	p.Dropoff = redactCoordinate(p.Dropoff)
+	return p
+}
+
+// Redacted coarsens the location.
+func (p DriverArrivedPayload) Redacted() RideEventPayload {
+	p.Location = redactCoordinate(p.Location)
+	return p
+}
+
+// Redacted coarsens the location.
+func (p PickedUpPayload) Redacted() RideEventPayload {
+	p.Location = redactCoordinate(p.Location)
+	return p
+}
+
+// Redacted coarsens the location.
+func (p LocationUpdatedPayload) Redacted() RideEventPayload {
+	p.Location = p.Location.coarse()
+	return p
+}
+
+// Redacted masks the comment, which is free text.
+func (p RatingPayload) Redacted() RideEventPayload {
+	p.Comment = redactText(p.Comment)
+	return p
+}
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedacted(t *testing.T) {
+	e := NewRideRequested("trip-1", "Jane Doe", "12 Main St", "34 Elm St",
+		WithTime(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)),
+		WithCoordinates(&Coordinate{Lat: 40.758896, Lng: -73.985130}, nil))
+
+	r := e.Redacted()
+	p := r.Payload.(RideRequestedPayload)
+	if p.Passenger != RedactedText || p.PickupLocation != RedactedText || p.DropoffLocation != RedactedText {
+		t.Errorf("expected names and addresses to be redacted, got %+v", p)
+	}
+	if *p.Pickup != (Coordinate{Lat: 40.76, Lng: -73.99}) || p.Dropoff != nil {
+		t.Errorf("expected coarse coordinates, got %v and %v", p.Pickup, p.Dropoff)
+	}
+	if orig := e.Payload.(RideRequestedPayload); orig.Passenger != "Jane Doe" || orig.Pickup.Lat != 40.758896 {
+		t.Errorf("expected the original to be unchanged, got %+v", orig)
+	}
+	if r.ID != e.ID || r.TripID != e.TripID || r.PassengerID != e.PassengerID {
+		t.Error("expected IDs to be kept")
+	}
+
+	rating := RideEvent{Type: EventRideRated, Payload: RatingPayload{RatedBy: "driver", Stars: 5, Comment: "Lovely chat with Jane"}}
+	if got := rating.Redacted().Payload.(RatingPayload); got.Comment != RedactedText || got.Stars != 5 {
+		t.Errorf("expected only the comment to be redacted, got %+v", got)
+	}
+	accepted := NewRideAccepted("trip-1", "driver-1")
+	if got := accepted.Redacted(); !Equal(got, accepted) {
+		t.Errorf("expected a payload without personal data to be unchanged, got %v", Diff(got, accepted))
+	}
+}
+
+func TestRideEvent_LogValue(t *testing.T) {
+	e := NewRideRequested("trip-1", "Jane Doe", "12 Main St", "34 Elm St", WithCity("nyc"))
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("handled", "event", e)
+
+	if strings.Contains(buf.String(), "Jane") || strings.Contains(buf.String(), "Main St") {
+		t.Errorf("expected personal data to stay out of the log, got %s", buf.String())
+	}
+	var line struct {
+		Event map[string]any `json:"event"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("decoding log line: %v", err)
+	}
+	if line.Event["trip_id"] != "trip-1" || line.Event["city"] != "nyc" || line.Event["payload"] == nil {
+		t.Errorf("unexpected logged event: %v", line.Event)
+	}
+}
@@ -0,0 +1,233 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// PayloadMarker makes a struct a RideEventPayload when embedded in it, for
+// payloads declared outside this package:
+//
+//	type PromoPayload struct {
+//		events.PayloadMarker
+//		Code string `json:"code"`
+//	}
+type PayloadMarker struct{}
+
+func (PayloadMarker) isPayload() {}
+
+var (
+	payloadsMu sync.RWMutex
+	payloads   = map[RideEventType]func() RideEventPayload{
+		EventRideRequested: func() RideEventPayload { return &RideRequestedPayload{} },
+		EventRideAccepted:  func() RideEventPayload { return &RideAcceptedPayload{} },
+		EventTripStarted:   func() RideEventPayload { return &RideStartedPayload{} },
+		EventTripCompleted: func() RideEventPayload { return &RideCompletedPayload{} },
+		EventTripCancelled: func() RideEventPayload { return &RideCancelledPayload{} },
+		EventSurgeUpdated:  func() RideEventPayload { return &SurgeUpdatedPayload{} },
+
+		EventDriverArrived:   func() RideEventPayload { return &DriverArrivedPayload{} },
+		EventPickedUp:        func() RideEventPayload { return &PickedUpPayload{} },
+		EventLocationUpdated: func() RideEventPayload { return &LocationUpdatedPayload{} },
+
+		EventPaymentProcessed: func() RideEventPayload { return &PaymentPayload{} },
+		EventRideRated:        func() RideEventPayload { return &RatingPayload{} },
+		EventTipAdded:         func() RideEventPayload { return &TipPayload{} },
+
+		EventPriceQuoted: func() RideEventPayload { return &PriceQuotePayload{} },
+	}
+
+	// payloadNames maps each payload_type to its payload struct, and versions
+	// the event types that also accept it to those types.
+	payloadNames = map[string]func() RideEventPayload{}
+	versions     = map[RideEventType][]string{}
+)
+
+func init() {
+	for _, newPayload := range payloads {
+		payloadNames[PayloadTypeName(newPayload())] = newPayload
+	}
+}
+
+// PayloadTypeName returns the payload_type MarshalJSON writes for p: the name of
+// its struct type, such as "RideCompletedPayload".
+func PayloadTypeName(p RideEventPayload) string {
+	t := reflect.TypeOf(p)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// RegisterPayload makes the payload of events of type t decode into what
+// newPayload returns, which must be a pointer to a new, empty payload struct.
+// Decoded payloads are that struct by value when it implements RideEventPayload
+// by value, as the payloads of this package do, so type switches name the
+// struct itself. Encoded events name the struct in payload_type (see
+// PayloadTypeName). RegisterPayload panics if t already has a payload, newPayload
+// does not return a pointer, or another struct of the same name is registered;
+// call it from an init function.
+func RegisterPayload(t RideEventType, newPayload func() RideEventPayload) {
+	name := checkPayload(t, newPayload)
+	payloadsMu.Lock()
+	defer payloadsMu.Unlock()
+	if _, dup := payloads[t]; dup {
+		panic(fmt.Sprintf("events: payload for %s registered twice", t))
+	}
+	registerName(name, newPayload)
+	payloads[t] = newPayload
+}
+
+// RegisterPayloadVersion lets events of type t carry what newPayload returns in
+// place of the payload registered for t, so a payload can change shape without a
+// new event type. Such events are decoded by their payload_type, and Validate
+// accepts either payload. It panics like RegisterPayload, and if the struct is
+// already a version of t.
+func RegisterPayloadVersion(t RideEventType, newPayload func() RideEventPayload) {
+	name := checkPayload(t, newPayload)
+	payloadsMu.Lock()
+	defer payloadsMu.Unlock()
+	for _, v := range versions[t] {
+		if v == name {
+			panic(fmt.Sprintf("events: payload %s for %s registered twice", name, t))
+		}
+	}
+	registerName(name, newPayload)
+	versions[t] = append(versions[t], name)
+}
+
+// checkPayload panics unless newPayload returns a pointer, and returns its
+// payload_type.
+func checkPayload(t RideEventType, newPayload func() RideEventPayload) string {
+	p := newPayload()
+	if p == nil || reflect.TypeOf(p).Kind() != reflect.Pointer {
+		panic(fmt.Sprintf("events: payload for %s is %T, not a pointer", t, p))
+	}
+	return PayloadTypeName(p)
+}
+
+// registerName records the payload_type of newPayload, panicking if the name
+// belongs to another struct. payloadsMu must be held.
+func registerName(name string, newPayload func() RideEventPayload) {
+	if existing, ok := payloadNames[name]; ok {
+		if reflect.TypeOf(existing()) != reflect.TypeOf(newPayload()) {
+			panic(fmt.Sprintf("events: payload type %s registered by two structs", name))
+		}
+		return
+	}
+	payloadNames[name] = newPayload
+}
+
+func payloadFactory(t RideEventType) (func() RideEventPayload, bool) {
+	payloadsMu.RLock()
+	defer payloadsMu.RUnlock()
+	newPayload, ok := payloads[t]
+	return newPayload, ok
+}
+
+// payloadFactoryByName returns the payload struct registered as payload_type name.
+func payloadFactoryByName(name string) (func() RideEventPayload, bool) {
+	payloadsMu.RLock()
+	defer payloadsMu.RUnlock()
+	newPayload, ok := payloadNames[name]
+	return newPayload, ok
+}
+
+// IsKnown reports whether t has a registered payload, built in or added with
+// RegisterPayload.
+func (t RideEventType) IsKnown() bool {
+	_, ok := payloadFactory(t)
+	return ok
+}
+
+// ErrUnknownEventType is returned by UnmarshalStrict for an event type without a
+// registered payload.
+var ErrUnknownEventType = errors.New("events: unknown event type")
+
+// ErrUnknownPayloadType is returned by UnmarshalStrict for a payload_type no
+// struct is registered as.
+var ErrUnknownPayloadType = errors.New("events: unknown payload type")
+
+// DecodePayload decodes raw into the payload registered for eventType. Unknown
+// event types and empty or null payloads decode to nil. Payloads such as those
+// already stored carry no version, so raw goes through every up-converter of
+// the payload first.
+func DecodePayload(eventType RideEventType, raw []byte) (RideEventPayload, error) {
+	newPayload, ok := payloadFactory(eventType)
+	if !ok {
+		return nil, nil
+	}
+	raw, err := upgradePayload(PayloadTypeName(newPayload()), 1, raw, false)
+	if err != nil {
+		return nil, err
+	}
+	return decodePayload(eventType, raw, false)
+}
+
+// decodePayload is DecodePayload, failing on unknown event types and fields
+// when strict.
+func decodePayload(eventType RideEventType, raw []byte, strict bool) (RideEventPayload, error) {
+	newPayload, ok := payloadFactory(eventType)
+	if !ok && strict {
+		return nil, fmt.Errorf("%w %q", ErrUnknownEventType, eventType)
+	}
+	if !ok || len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	p := newPayload()
+	if err := decodeJSON(raw, p, strict); err != nil {
+		return nil, fmt.Errorf("events: %s payload: %w", eventType, err)
+	}
+	return byValue(p), nil
+}
+
+// decodeNamedPayload decodes raw into the struct registered as payload_type
+// name. Payload types this build does not know fall back to the payload of
+// eventType, unless strict.
+func decodeNamedPayload(name string, eventType RideEventType, raw []byte, strict bool) (RideEventPayload, error) {
+	if strict && !eventType.IsKnown() {
+		return nil, fmt.Errorf("%w %q", ErrUnknownEventType, eventType)
+	}
+	newPayload, ok := payloadFactoryByName(name)
+	if !ok {
+		if strict {
+			return nil, fmt.Errorf("%w %q", ErrUnknownPayloadType, name)
+		}
+		return decodePayload(eventType, raw, false)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	p := newPayload()
+	if err := decodeJSON(raw, p, strict); err != nil {
+		return nil, fmt.Errorf("events: %s payload: %w", name, err)
+	}
+	return byValue(p), nil
+}
+
+// byValue returns the struct p points to when it is itself a RideEventPayload,
+// and p otherwise.
+func byValue(p RideEventPayload) RideEventPayload {
+	if v, ok := reflect.ValueOf(p).Elem().Interface().(RideEventPayload); ok {
+		return v
+	}
+	return p
+}
+
+// payloadTypes returns the types a decoded payload of t may have: that of the
+// payload registered for t, then those of its versions.
+func payloadTypes(t RideEventType) ([]reflect.Type, bool) {
+	payloadsMu.RLock()
+	defer payloadsMu.RUnlock()
+	newPayload, ok := payloads[t]
+	if !ok {
+		return nil, false
+	}
+	types := []reflect.Type{reflect.TypeOf(byValue(newPayload()))}
+	for _, name := range versions[t] {
+		types = append(types, reflect.TypeOf(byValue(payloadNames[name]())))
+	}
+	return types, true
+}
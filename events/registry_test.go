@@ -0,0 +1,140 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// ratedPayload stands in for a payload declared outside this package.
+type ratedPayload struct {
+	PayloadMarker
+	Stars int `json:"stars"`
+}
+
+// ratedPayloadV2 is a later version of ratedPayload.
+type ratedPayloadV2 struct {
+	PayloadMarker
+	Stars  float64 `json:"stars"`
+	Source string  `json:"source"`
+}
+
+// pointerPayload implements RideEventPayload only through its pointer.
+type pointerPayload struct {
+	Note string `json:"note"`
+}
+
+func (*pointerPayload) isPayload() {}
+
+const (
+	eventRated   RideEventType = "TEST_RATED"
+	eventNoted   RideEventType = "TEST_NOTED"
+	eventInvalid RideEventType = "TEST_INVALID"
+)
+
+func init() {
+	RegisterPayload(eventRated, func() RideEventPayload { return &ratedPayload{} })
+	RegisterPayload(eventNoted, func() RideEventPayload { return &pointerPayload{} })
+	RegisterPayloadVersion(eventRated, func() RideEventPayload { return &ratedPayloadV2{} })
+}
+
+func TestRegisterPayload_Decodes(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(RideEvent{ID: "id1", TripID: "trip1", Type: eventRated, OccurredAt: now, Payload: ratedPayload{Stars: 5}})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var e RideEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if p, ok := e.Payload.(ratedPayload); !ok || p.Stars != 5 {
+		t.Errorf("expected ratedPayload by value, got %#v", e.Payload)
+	}
+	if err := e.Validate(); err != nil {
+		t.Errorf("expected a registered type to validate, got %v", err)
+	}
+
+	p, err := DecodePayload(eventNoted, []byte(`{"note":"hi"}`))
+	if err != nil {
+		t.Fatalf("DecodePayload failed: %v", err)
+	}
+	if p, ok := p.(*pointerPayload); !ok || p.Note != "hi" {
+		t.Errorf("expected *pointerPayload, got %#v", p)
+	}
+
+	if p, err := DecodePayload("TEST_UNREGISTERED", []byte(`{}`)); p != nil || err != nil {
+		t.Errorf("expected an unregistered type to decode to nil, got %#v, %v", p, err)
+	}
+	if !eventRated.IsKnown() || RideEventType("TEST_UNREGISTERED").IsKnown() {
+		t.Error("IsKnown does not follow the registry")
+	}
+}
+
+func TestRideEventJSON_PayloadType(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(RideEvent{ID: "id1", TripID: "trip1", Type: eventRated, OccurredAt: now, Payload: ratedPayloadV2{Stars: 4.5, Source: "app"}})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var written struct {
+		PayloadType string `json:"payload_type"`
+	}
+	if err := json.Unmarshal(data, &written); err != nil || written.PayloadType != "ratedPayloadV2" {
+		t.Fatalf("expected payload_type ratedPayloadV2, got %s", data)
+	}
+
+	var e RideEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if p, ok := e.Payload.(ratedPayloadV2); !ok || p.Stars != 4.5 {
+		t.Errorf("expected the payload decoded by payload_type, got %#v", e.Payload)
+	}
+	if err := e.Validate(); err != nil {
+		t.Errorf("expected a payload version to validate, got %v", err)
+	}
+	e.Type = EventTripCompleted
+	if err := e.Validate(); err == nil {
+		t.Error("expected a version of another event type's payload to be invalid")
+	}
+
+	// A payload type this build does not know falls back to the event type
+	unknown := []byte(`{"id":"id1","event_type":"TEST_RATED","payload_type":"ratedPayloadV9","payload":{"stars":3}}`)
+	if err := json.Unmarshal(unknown, &e); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if p, ok := e.Payload.(ratedPayload); !ok || p.Stars != 3 {
+		t.Errorf("expected ratedPayload, got %#v", e.Payload)
+	}
+	if err := UnmarshalStrict(unknown, &e); !errors.Is(err, ErrUnknownPayloadType) {
+		t.Errorf("expected ErrUnknownPayloadType, got %v", err)
+	}
+}
+
+func TestRegisterPayload_Panics(t *testing.T) {
+	// Shares its name with the built-in payload
+	type RideCompletedPayload struct{ PayloadMarker }
+
+	tests := []struct {
+		name       string
+		typ        RideEventType
+		newPayload func() RideEventPayload
+	}{
+		{"built-in type", EventTripCompleted, func() RideEventPayload { return &ratedPayload{} }},
+		{"registered twice", eventRated, func() RideEventPayload { return &ratedPayload{} }},
+		{"not a pointer", eventInvalid, func() RideEventPayload { return ratedPayload{} }},
+		{"name taken by another struct", eventInvalid, func() RideEventPayload { return &RideCompletedPayload{} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected RegisterPayload to panic")
+				}
+			}()
+			RegisterPayload(tt.typ, tt.newPayload)
+		})
+	}
+}
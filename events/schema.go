@@ -0,0 +1,9 @@
+package events
+
+import _ "embed"
+
+// RideEventJSONSchema is the JSON Schema (draft-07) describing a serialized RideEvent
+// and the payload expected for each event type.
+//
+//go:embed ride_event.schema.json
+var RideEventJSONSchema []byte
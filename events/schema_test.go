@@ -0,0 +1,42 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRideEventJSONSchema_ListsAllEnums(t *testing.T) {
+	var schema struct {
+		Properties struct {
+			EventType struct {
+				Enum []string `json:"enum"`
+			} `json:"event_type"`
+			RideState struct {
+				Enum []string `json:"enum"`
+			} `json:"ride_state"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(RideEventJSONSchema, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	contains := func(list []string, v string) bool {
+		for _, s := range list {
+			if s == v {
+				return true
+			}
+		}
+		return false
+	}
+	for _, typ := range []RideEventType{EventRideRequested, EventRideAccepted, EventDriverArrived, EventPickedUp, EventTripStarted,
+		EventTripCompleted, EventTripCancelled, EventLocationUpdated, EventPaymentProcessed, EventRideRated, EventTipAdded, EventSurgeUpdated, EventPriceQuoted} {
+		if !contains(schema.Properties.EventType.Enum, string(typ)) {
+			t.Errorf("schema event_type enum missing %s", typ)
+		}
+	}
+	for _, state := range []RideState{StateNew, StateRequested, StateAccepted, StateDriverArrived, StatePickedUp, StateInProgress, StateCompleted, StateCancelled} {
+		if !contains(schema.Properties.RideState.Enum, string(state)) {
+			t.Errorf("schema ride_state enum missing %s", state)
+		}
+	}
+}
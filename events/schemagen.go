@@ -0,0 +1,167 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+const generatedSchemaBase = "https://github.com/pedeveaux/kafkarideshare/schemas/"
+
+// GenerateJSONSchemas derives JSON Schema (draft-07) documents from the Go types
+// of RideEvent and of every registered payload, keyed by file name:
+// ride_event.json for the event, with each payload under definitions, and
+// <PayloadTypeName>.json for each payload on its own. They describe the
+// encoding this build writes: fields without omitempty or omitzero are
+// required, and event_type and ride_state list the known values. Value rules
+// such as ranges live only in the hand-written RideEventJSONSchema.
+func GenerateJSONSchemas() (map[string][]byte, error) {
+	payloadsMu.RLock()
+	defer payloadsMu.RUnlock()
+
+	docs := map[string]any{}
+	definitions := map[string]any{}
+	for name, newPayload := range payloadNames {
+		schema := typeSchema(reflect.TypeOf(newPayload()))
+		definitions[name] = schema
+
+		doc := map[string]any{
+			"$schema": "http://json-schema.org/draft-07/schema#",
+			"$id":     generatedSchemaBase + name + ".json",
+			"title":   name,
+		}
+		for k, v := range schema {
+			doc[k] = v
+		}
+		docs[name+".json"] = doc
+	}
+
+	event := objectSchema(reflect.TypeOf(RideEvent{}))
+	properties := event["properties"].(map[string]any)
+	properties["payload"] = map[string]any{"type": "object"}
+	properties["payload_type"] = map[string]any{"type": "string", "enum": sortedKeys(payloadNames)}
+	properties["payload_version"] = map[string]any{"type": "integer", "minimum": 1}
+	event["required"] = append(event["required"].([]string), "schema_version")
+
+	var allOf []any
+	for _, t := range sortedKeys(payloads) {
+		names := append([]string{PayloadTypeName(payloads[RideEventType(t)]())}, versions[RideEventType(t)]...)
+		for _, name := range names {
+			allOf = append(allOf, map[string]any{
+				"if": map[string]any{"properties": map[string]any{
+					"event_type":   map[string]any{"const": t},
+					"payload_type": map[string]any{"const": name},
+				}},
+				"then": map[string]any{"properties": map[string]any{
+					"payload": map[string]any{"$ref": "#/definitions/" + name},
+				}},
+			})
+		}
+	}
+	event["$schema"] = "http://json-schema.org/draft-07/schema#"
+	event["$id"] = generatedSchemaBase + "ride_event.json"
+	event["title"] = "RideEvent"
+	event["definitions"] = definitions
+	event["allOf"] = allOf
+	docs["ride_event.json"] = event
+
+	out := make(map[string][]byte, len(docs))
+	for name, doc := range docs {
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		out[name] = append(data, '\n')
+	}
+	return out, nil
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	eventTypeType = reflect.TypeOf(RideEventType(""))
+	rideStateType = reflect.TypeOf(RideState(""))
+	actorType     = reflect.TypeOf(Actor(""))
+	reasonType    = reflect.TypeOf(CancelReason(""))
+)
+
+// typeSchema returns the schema of the JSON encoding of values of type t.
+// payloadsMu must be held.
+func typeSchema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case eventTypeType:
+		return map[string]any{"type": "string", "enum": sortedKeys(payloads)}
+	case rideStateType:
+		return map[string]any{"type": "string", "enum": sortedKeys(rideStates)}
+	case actorType:
+		return map[string]any{"type": "string", "enum": sortedKeys(actors)}
+	case reasonType:
+		return map[string]any{"type": "string", "enum": sortedKeys(cancelReasons)}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return objectSchema(t)
+	}
+	return map[string]any{}
+}
+
+// objectSchema returns the schema of a struct, following encoding/json's rules
+// for field names and embedded structs.
+func objectSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := range t.NumField() {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				addFields(f.Type)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = typeSchema(f.Type)
+			if o := strings.Split(opts, ","); !slices.Contains(o, "omitempty") && !slices.Contains(o, "omitzero") {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, string(k))
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+func TestGenerateJSONSchemas(t *testing.T) {
+	docs, err := GenerateJSONSchemas()
+	if err != nil {
+		t.Fatalf("GenerateJSONSchemas failed: %v", err)
+	}
+	for _, name := range []string{"ride_event.json", "RideCompletedPayload.json", "ratedPayloadV2.json"} {
+		if _, ok := docs[name]; !ok {
+			t.Errorf("expected a %s document", name)
+		}
+	}
+
+	compiler := jsonschema.NewCompiler()
+	for name, doc := range docs {
+		if err := compiler.AddResource(name, bytes.NewReader(doc)); err != nil {
+			t.Fatalf("load %s: %v", name, err)
+		}
+	}
+	schema, err := compiler.Compile("ride_event.json")
+	if err != nil {
+		t.Fatalf("compile ride_event.json: %v", err)
+	}
+	validate := func(e RideEvent) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		var doc any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		return schema.Validate(doc)
+	}
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	valid := []RideEvent{
+		{ID: "id1", TripID: "trip1", Type: EventRideRequested, OccurredAt: now, State: StateRequested,
+			Payload: RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St", Pickup: &Coordinate{Lat: 40.7, Lng: -74}}},
+		{ID: "id2", TripID: "trip1", Type: EventTripCompleted, OccurredAt: now, State: StateCompleted,
+			Payload: RideCompletedPayload{EndTime: now, DistanceKM: 4, Fare: NewMoney(950, USD)}},
+		{ID: "id3", Type: EventSurgeUpdated, OccurredAt: now, Payload: SurgeUpdatedPayload{ZoneID: "midtown", Multiplier: 1.5}},
+		{ID: "id4", Type: eventRated, OccurredAt: now, Payload: ratedPayloadV2{Stars: 4.5, Source: "app"}},
+	}
+	for _, e := range valid {
+		if err := validate(e); err != nil {
+			t.Errorf("%s event does not match the generated schema: %v", e.Type, err)
+		}
+	}
+
+	// A payload of the wrong shape for its payload_type
+	raw := []byte(`{"id":"id1","event_type":"COMPLETED","event_time":"2025-06-01T12:00:00Z","schema_version":5,
+		"payload_type":"RideCompletedPayload","payload":{"end_time":"2025-06-01T12:00:00Z","distance_km":4,"fare":"9.50"}}`)
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if err := schema.Validate(doc); err == nil {
+		t.Error("expected a malformed payload to fail the generated schema")
+	}
+}
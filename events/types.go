@@ -1,7 +1,10 @@
 package events
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"time"
 )
 
@@ -12,9 +15,11 @@ type RideEventPayload interface {
 
 // RideRequestedPayload holds data for when a ride is requested
 type RideRequestedPayload struct {
-	Passenger       string `json:"passenger"`
-	PickupLocation  string `json:"pickup_location"`
-	DropoffLocation string `json:"dropoff_location"`
+	Passenger       string      `json:"passenger"`
+	PickupLocation  string      `json:"pickup_location"`
+	DropoffLocation string      `json:"dropoff_location"`
+	Pickup          *Coordinate `json:"pickup,omitempty"`
+	Dropoff         *Coordinate `json:"dropoff,omitempty"`
 }
 
 func (RideRequestedPayload) isPayload() {}
@@ -26,6 +31,32 @@ type RideAcceptedPayload struct {
 
 func (RideAcceptedPayload) isPayload() {}
 
+// DriverArrivedPayload holds data for when the driver reaches the pickup point
+type DriverArrivedPayload struct {
+	DriverID string      `json:"driver_id"`
+	Location *Coordinate `json:"location,omitempty"`
+}
+
+func (DriverArrivedPayload) isPayload() {}
+
+// PickedUpPayload holds data for when the passenger gets in
+type PickedUpPayload struct {
+	PickupTime time.Time   `json:"pickup_time"`
+	Location   *Coordinate `json:"location,omitempty"`
+}
+
+func (PickedUpPayload) isPayload() {}
+
+// LocationUpdatedPayload holds a driver's position during a ride
+type LocationUpdatedPayload struct {
+	Location   Coordinate `json:"location"`
+	HeadingDeg float64    `json:"heading_deg"` // clockwise from true north, in [0, 360)
+	SpeedKPH   float64    `json:"speed_kph"`
+	AccuracyM  float64    `json:"accuracy_m,omitempty"` // radius of the fix's uncertainty
+}
+
+func (LocationUpdatedPayload) isPayload() {}
+
 // RideStartedPayload holds data for when a ride begins
 type RideStartedPayload struct {
 	StartTime time.Time `json:"start_time"`
@@ -37,19 +68,96 @@ func (RideStartedPayload) isPayload() {}
 type RideCompletedPayload struct {
 	EndTime    time.Time `json:"end_time"`
 	DistanceKM float64   `json:"distance_km"`
-	FareUSD    float64   `json:"fare_usd"`
+	Fare       Money     `json:"fare"`
 }
 
 func (RideCompletedPayload) isPayload() {}
 
 // RideCancelledPayload holds data for when a ride is cancelled
 type RideCancelledPayload struct {
-	CancelledBy string `json:"cancelled_by"` // "passenger" or "driver"
-	Reason      string `json:"reason,omitempty"`
+	CancelledBy Actor        `json:"cancelled_by"`
+	Reason      CancelReason `json:"reason,omitempty"`
 }
 
 func (RideCancelledPayload) isPayload() {}
 
+// SurgeUpdatedPayload holds a zone's new surge multiplier, in effect from the
+// event time until the zone's next update
+type SurgeUpdatedPayload struct {
+	ZoneID     ZoneID  `json:"zone_id"`
+	ZoneName   string  `json:"zone_name,omitempty"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+func (SurgeUpdatedPayload) isPayload() {}
+
+// FareBreakdown holds the parts of a fare before surge, all in one currency
+type FareBreakdown struct {
+	Base     Money `json:"base"`
+	Distance Money `json:"distance"`
+	Time     Money `json:"time"`
+}
+
+// Subtotal returns the sum of the parts, failing with ErrCurrencyMismatch if
+// they are in different currencies.
+func (b FareBreakdown) Subtotal() (Money, error) {
+	sum, err := b.Base.Add(b.Distance)
+	if err != nil {
+		return Money{}, err
+	}
+	return sum.Add(b.Time)
+}
+
+// Total returns the subtotal with multiplier applied, rounded like Money.Mul.
+func (b FareBreakdown) Total(multiplier float64) (Money, error) {
+	sum, err := b.Subtotal()
+	if err != nil {
+		return Money{}, err
+	}
+	return sum.Mul(multiplier), nil
+}
+
+// PriceQuotePayload holds the fare quoted to a passenger for a trip, priced
+// with the surge multiplier of its pickup zone at the time
+type PriceQuotePayload struct {
+	QuoteID    string        `json:"quote_id"`
+	ZoneID     ZoneID        `json:"zone_id,omitempty"`
+	Multiplier float64       `json:"multiplier"` // 1 without surge
+	DistanceKM float64       `json:"distance_km"`
+	Breakdown  FareBreakdown `json:"breakdown"`
+	Total      Money         `json:"total"` // Breakdown.Total(Multiplier)
+	ExpiresAt  time.Time     `json:"expires_at"`
+}
+
+func (PriceQuotePayload) isPayload() {}
+
+// PaymentPayload holds the outcome of charging the passenger for a trip
+type PaymentPayload struct {
+	PaymentID string `json:"payment_id"`
+	Amount    Money  `json:"amount"`
+	Method    string `json:"method"` // "card", "cash", or "wallet"
+	Status    string `json:"status"` // "captured", "declined", or "refunded"
+}
+
+func (PaymentPayload) isPayload() {}
+
+// RatingPayload holds one side's rating of the other after a trip
+type RatingPayload struct {
+	RatedBy string `json:"rated_by"` // "passenger" or "driver"
+	Stars   int    `json:"stars"`    // 1 to 5
+	Comment string `json:"comment,omitempty"`
+}
+
+func (RatingPayload) isPayload() {}
+
+// TipPayload holds a tip the passenger added for the driver
+type TipPayload struct {
+	DriverID string `json:"driver_id"`
+	Amount   Money  `json:"amount"`
+}
+
+func (TipPayload) isPayload() {}
+
 // RideEventType is a string-based enum for Kafka event types.
 type RideEventType string
 
@@ -59,80 +167,224 @@ const (
 	EventTripStarted   RideEventType = "STARTED"
 	EventTripCompleted RideEventType = "COMPLETED"
 	EventTripCancelled RideEventType = "CANCELLED"
+
+	// EventDriverArrived and EventPickedUp split the wait between ACCEPTED and
+	// STARTED into the driver reaching the pickup point and the passenger getting in.
+	EventDriverArrived RideEventType = "DRIVER_ARRIVED"
+	EventPickedUp      RideEventType = "PICKED_UP"
+
+	// EventLocationUpdated reports the driver's position during a ride without
+	// changing its state, so its State is the ride's current state.
+	EventLocationUpdated RideEventType = "LOCATION_UPDATED"
+
+	// EventPaymentProcessed, EventRideRated, and EventTipAdded follow a trip
+	// without changing its state, so like EventLocationUpdated their State is
+	// the ride's state, usually COMPLETED.
+	EventPaymentProcessed RideEventType = "PAYMENT_PROCESSED"
+	EventRideRated        RideEventType = "RIDE_RATED"
+	EventTipAdded         RideEventType = "TIP_ADDED"
+
+	// EventSurgeUpdated carries reference data rather than a ride transition, so
+	// its TripID and State are empty.
+	EventSurgeUpdated RideEventType = "SURGE_UPDATED"
+
+	// EventPriceQuoted is the fare quoted for a trip, usually before it is
+	// requested, so its State is the ride's state, NEW or REQUESTED.
+	EventPriceQuoted RideEventType = "PRICE_QUOTE"
 )
 
+// IsLifecycle reports whether t is one of the ride transitions stored in
+// ride_events and folded into rides and trips, as opposed to reference data such
+// as surge updates. DRIVER_ARRIVED and PICKED_UP are not stored yet, though
+// the columns fit them, and LOCATION_UPDATED, PRICE_QUOTE, and the payment,
+// rating, and tip events never are.
+func (t RideEventType) IsLifecycle() bool {
+	switch t {
+	case EventRideRequested, EventRideAccepted, EventTripStarted, EventTripCompleted, EventTripCancelled:
+		return true
+	}
+	return false
+}
+
 // RideState represents the state of a ride in the FSM.
 type RideState string
 
 const (
-	StateNew        RideState = "NEW"
-	StateRequested  RideState = "REQUESTED"
-	StateAccepted   RideState = "ACCEPTED"
-	StateInProgress RideState = "IN_PROGRESS"
-	StateCompleted  RideState = "COMPLETED"
-	StateCancelled  RideState = "CANCELLED"
+	StateNew           RideState = "NEW"
+	StateRequested     RideState = "REQUESTED"
+	StateAccepted      RideState = "ACCEPTED"
+	StateDriverArrived RideState = "DRIVER_ARRIVED"
+	StatePickedUp      RideState = "PICKED_UP"
+	StateInProgress    RideState = "IN_PROGRESS"
+	StateCompleted     RideState = "COMPLETED"
+	StateCancelled     RideState = "CANCELLED"
 )
 
+// SchemaVersion is the version of the RideEvent JSON encoding this package
+// writes. The versions so far:
+//
+//  1. The original encoding, without schema_version. Early producers named the
+//     passenger rider_id rather than passenger_id.
+//  2. Adds schema_version and names the passenger passenger_id only.
+//  3. Adds payload_type, naming the payload struct, which the payload is
+//     decoded by in place of event_type.
+//  4. Adds meta, the optional envelope metadata (see Meta).
+//  5. Writes amounts of money as Money objects: fare in place of fare_usd, and
+//     amount in place of amount_usd, which were floats of dollars.
+//  6. Adds recorded_at, the optional processing time (see RideEvent.RecordedAt).
+//  7. Adds payload_version, the version of the payload struct, which older
+//     payloads are converted from (see RegisterUpConverter).
+//
+// To change the encoding, bump SchemaVersion, describe the new version above,
+// and make UnmarshalJSON upgrade every older version into the current struct,
+// so events still in the topic, its retry topics, or the dead-letter topic stay
+// readable. Update ride_event.schema.json to accept both encodings. A change to
+// one payload struct needs no new SchemaVersion: register an up-converter for
+// it with RegisterUpConverter instead.
+const SchemaVersion = 7
+
 // RideEvent represents a single state transition in the ride lifecycle.
 type RideEvent struct {
-	ID          string           `json:"id"`
-	TripID      string           `json:"trip_id"`
-	Type        RideEventType    `json:"event_type"`
-	Timestamp   time.Time        `json:"event_time"`
-	State       RideState        `json:"ride_state"`
+	ID     string        `json:"id"`
+	TripID string        `json:"trip_id,omitempty"`
+	Type   RideEventType `json:"event_type"`
+	// OccurredAt is the event time: when the transition happened, as the
+	// producer saw it. Windows, trips, and ordering go by it.
+	OccurredAt  time.Time        `json:"event_time"`
+	State       RideState        `json:"ride_state,omitempty"`
 	DriverID    string           `json:"driver_id,omitempty"`
 	PassengerID string           `json:"passenger_id,omitempty"`
+	City        string           `json:"city,omitempty"`    // empty outside multi-city mode
 	Payload     RideEventPayload `json:"payload,omitempty"` // use type switches on deserialization
+
+	// SchemaVersion is the encoding version the event was decoded from, or zero
+	// for an event built in code. Encoding always writes the current SchemaVersion.
+	SchemaVersion int `json:"schema_version,omitempty"`
+
+	Meta Meta `json:"meta,omitzero"`
+
+	// RecordedAt is the processing time: when the event was appended to the
+	// log, which consumers take from the Kafka message timestamp. It is zero
+	// when unknown, such as for an event built in code and not yet produced.
+	RecordedAt time.Time `json:"recorded_at,omitzero"`
 }
 
-// UnmarshalJSON customizes the unmarshalling of RideEvent to handle the Payload field.
+// Lag returns how long after it occurred e was recorded, or zero when
+// RecordedAt is unknown. Producer clock skew can make it negative.
+func (e RideEvent) Lag() time.Duration {
+	if e.RecordedAt.IsZero() || e.OccurredAt.IsZero() {
+		return 0
+	}
+	return e.RecordedAt.Sub(e.OccurredAt)
+}
+
+// IsLate reports whether e was recorded more than allowed after it occurred,
+// as events buffered on a phone while offline are. Events with an unknown
+// RecordedAt are not late.
+func (e RideEvent) IsLate(allowed time.Duration) bool {
+	return e.Lag() > allowed
+}
+
+// MarshalJSON encodes e in the current encoding, stamped with SchemaVersion and
+// with the payload's PayloadTypeName as payload_type and PayloadVersion as
+// payload_version.
+func (e RideEvent) MarshalJSON() ([]byte, error) {
+	type Alias RideEvent // Prevent recursion
+	a := struct {
+		Alias
+		PayloadType    string `json:"payload_type,omitempty"`
+		PayloadVersion int    `json:"payload_version,omitempty"`
+	}{Alias: Alias(e)}
+	a.SchemaVersion = SchemaVersion
+	if e.Payload != nil {
+		a.PayloadType = PayloadTypeName(e.Payload)
+		a.PayloadVersion = PayloadVersion(e.Payload)
+	}
+	return json.Marshal(a)
+}
+
+// UnmarshalJSON customizes the unmarshalling of RideEvent to handle the Payload
+// field, and upgrades events written in older encodings.
 func (e *RideEvent) UnmarshalJSON(data []byte) error {
+	return e.unmarshal(data, false)
+}
+
+// UnmarshalStrict decodes data into e like json.Unmarshal, but rejects what
+// json.Unmarshal lets through: fields that neither the event nor its payload
+// declares, and event types without a registered payload, which fail with
+// ErrUnknownEventType rather than decoding to a nil Payload. Consumers use it to
+// catch producers whose schema has drifted from theirs.
+func UnmarshalStrict(data []byte, e *RideEvent) error {
+	return e.unmarshal(data, true)
+}
+
+func (e *RideEvent) unmarshal(data []byte, strict bool) error {
 	type Alias RideEvent // Prevent recursion
 	aux := &struct {
 		Payload json.RawMessage `json:"payload"`
+		RiderID string          `json:"rider_id"` // version 1 name of passenger_id
+		// Version 3 onwards; older events decode their payload by event type
+		PayloadType string `json:"payload_type"`
+		// Version 7 onwards; older payloads are converted from version 1
+		PayloadVersion int `json:"payload_version"`
+		// Read as plain strings so unknown values are left to Validate rather
+		// than failing UnmarshalText
+		Type  string `json:"event_type"`
+		State string `json:"ride_state"`
 		*Alias
 	}{
 		Alias: (*Alias)(e),
 	}
 
-	if err := json.Unmarshal(data, &aux); err != nil {
+	if err := decodeJSON(data, aux, strict); err != nil {
 		return err
 	}
+	e.Type, e.State = RideEventType(aux.Type), RideState(aux.State)
+	if e.SchemaVersion == 0 {
+		e.SchemaVersion = 1
+	}
+	if e.PassengerID == "" {
+		e.PassengerID = aux.RiderID
+	}
 
-	switch e.Type {
-	case EventRideRequested:
-		var p RideRequestedPayload
-		if err := json.Unmarshal(aux.Payload, &p); err != nil {
-			return err
-		}
-		e.Payload = p
-	case EventRideAccepted:
-		var p RideAcceptedPayload
-		if err := json.Unmarshal(aux.Payload, &p); err != nil {
-			return err
-		}
-		e.Payload = p
-	case EventTripStarted:
-		var p RideStartedPayload
-		if err := json.Unmarshal(aux.Payload, &p); err != nil {
-			return err
-		}
-		e.Payload = p
-	case EventTripCompleted:
-		var p RideCompletedPayload
-		if err := json.Unmarshal(aux.Payload, &p); err != nil {
-			return err
+	// Payloads without a known payload_type are decoded, and so converted, as
+	// the payload of their event type
+	name := aux.PayloadType
+	if _, ok := payloadFactoryByName(name); !ok {
+		if newPayload, ok := payloadFactory(e.Type); ok {
+			name = PayloadTypeName(newPayload())
 		}
-		e.Payload = p
-	case EventTripCancelled:
-		var p RideCancelledPayload
-		if err := json.Unmarshal(aux.Payload, &p); err != nil {
-			return err
-		}
-		e.Payload = p
-	default:
-		// Unknown type, leave as nil or handle as needed
-		e.Payload = nil
+	}
+	raw, err := upgradePayload(name, aux.PayloadVersion, aux.Payload, strict)
+	if err != nil {
+		return err
+	}
+
+	var payload RideEventPayload
+	if aux.PayloadType != "" {
+		payload, err = decodeNamedPayload(aux.PayloadType, e.Type, raw, strict)
+	} else {
+		payload, err = decodePayload(e.Type, raw, strict)
+	}
+	if err != nil {
+		return err
+	}
+	e.Payload = payload
+	return nil
+}
+
+// decodeJSON is json.Unmarshal, failing on unknown fields when strict.
+func decodeJSON(data []byte, v any, strict bool) error {
+	if !strict {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("events: unexpected data after the event")
 	}
 	return nil
 }
@@ -2,7 +2,9 @@ package events
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -13,6 +15,13 @@ func TestRideEventPayloadImplementations(t *testing.T) {
 	var _ RideEventPayload = RideStartedPayload{}
 	var _ RideEventPayload = RideCompletedPayload{}
 	var _ RideEventPayload = RideCancelledPayload{}
+	var _ RideEventPayload = SurgeUpdatedPayload{}
+	var _ RideEventPayload = DriverArrivedPayload{}
+	var _ RideEventPayload = PickedUpPayload{}
+	var _ RideEventPayload = LocationUpdatedPayload{}
+	var _ RideEventPayload = PaymentPayload{}
+	var _ RideEventPayload = RatingPayload{}
+	var _ RideEventPayload = TipPayload{}
 }
 
 func TestRideStatesAndEventsConstants(t *testing.T) {
@@ -39,7 +48,7 @@ func TestRideEventJSONMarshalling_AllTypes(t *testing.T) {
 				ID:          "id1",
 				TripID:      "trip1",
 				Type:        EventRideRequested,
-				Timestamp:   now,
+				OccurredAt:  now,
 				State:       StateRequested,
 				PassengerID: "rider-1",
 				Payload:     RideRequestedPayload{Passenger: "rider-1", PickupLocation: "A", DropoffLocation: "B"},
@@ -49,52 +58,135 @@ func TestRideEventJSONMarshalling_AllTypes(t *testing.T) {
 		{
 			name: "Accepted",
 			event: RideEvent{
-				ID:        "id2",
-				TripID:    "trip2",
-				Type:      EventRideAccepted,
-				Timestamp: now,
-				State:     StateAccepted,
-				DriverID:  "driver-1",
-				Payload:   RideAcceptedPayload{DriverID: "driver-1"},
+				ID:         "id2",
+				TripID:     "trip2",
+				Type:       EventRideAccepted,
+				OccurredAt: now,
+				State:      StateAccepted,
+				DriverID:   "driver-1",
+				Payload:    RideAcceptedPayload{DriverID: "driver-1"},
 			},
 			wantTyp: RideAcceptedPayload{},
 		},
 		{
 			name: "Started",
 			event: RideEvent{
-				ID:        "id3",
-				TripID:    "trip3",
-				Type:      EventTripStarted,
-				Timestamp: now,
-				State:     StateInProgress,
-				Payload:   RideStartedPayload{StartTime: now},
+				ID:         "id3",
+				TripID:     "trip3",
+				Type:       EventTripStarted,
+				OccurredAt: now,
+				State:      StateInProgress,
+				Payload:    RideStartedPayload{StartTime: now},
 			},
 			wantTyp: RideStartedPayload{},
 		},
 		{
 			name: "Completed",
 			event: RideEvent{
-				ID:        "id4",
-				TripID:    "trip4",
-				Type:      EventTripCompleted,
-				Timestamp: now,
-				State:     StateCompleted,
-				Payload:   RideCompletedPayload{EndTime: now, DistanceKM: 10.5, FareUSD: 25.0},
+				ID:         "id4",
+				TripID:     "trip4",
+				Type:       EventTripCompleted,
+				OccurredAt: now,
+				State:      StateCompleted,
+				Payload:    RideCompletedPayload{EndTime: now, DistanceKM: 10.5, Fare: NewMoney(2500, USD)},
 			},
 			wantTyp: RideCompletedPayload{},
 		},
 		{
 			name: "Cancelled",
 			event: RideEvent{
-				ID:        "id5",
-				TripID:    "trip5",
-				Type:      EventTripCancelled,
-				Timestamp: now,
-				State:     StateCancelled,
-				Payload:   RideCancelledPayload{CancelledBy: "driver", Reason: "no show"},
+				ID:         "id5",
+				TripID:     "trip5",
+				Type:       EventTripCancelled,
+				OccurredAt: now,
+				State:      StateCancelled,
+				Payload:    RideCancelledPayload{CancelledBy: "driver", Reason: ReasonDriverNoShow},
 			},
 			wantTyp: RideCancelledPayload{},
 		},
+		{
+			name: "SurgeUpdated",
+			event: RideEvent{
+				ID:         "id6",
+				Type:       EventSurgeUpdated,
+				OccurredAt: now,
+				Payload:    SurgeUpdatedPayload{ZoneID: "midtown", ZoneName: "Midtown", Multiplier: 1.8},
+			},
+			wantTyp: SurgeUpdatedPayload{},
+		},
+		{
+			name: "DriverArrived",
+			event: RideEvent{
+				ID:         "id7",
+				TripID:     "trip7",
+				Type:       EventDriverArrived,
+				OccurredAt: now,
+				State:      StateDriverArrived,
+				DriverID:   "driver-1",
+				Payload:    DriverArrivedPayload{DriverID: "driver-1", Location: &Coordinate{Lat: 40.75, Lng: -73.98}},
+			},
+			wantTyp: DriverArrivedPayload{},
+		},
+		{
+			name: "PickedUp",
+			event: RideEvent{
+				ID:         "id8",
+				TripID:     "trip8",
+				Type:       EventPickedUp,
+				OccurredAt: now,
+				State:      StatePickedUp,
+				Payload:    PickedUpPayload{PickupTime: now},
+			},
+			wantTyp: PickedUpPayload{},
+		},
+		{
+			name: "LocationUpdated",
+			event: RideEvent{
+				ID:         "id9",
+				TripID:     "trip9",
+				Type:       EventLocationUpdated,
+				OccurredAt: now,
+				State:      StateInProgress,
+				Payload:    LocationUpdatedPayload{Location: Coordinate{Lat: 40.75, Lng: -73.98}, HeadingDeg: 90, SpeedKPH: 32.5},
+			},
+			wantTyp: LocationUpdatedPayload{},
+		},
+		{
+			name: "PaymentProcessed",
+			event: RideEvent{
+				ID:         "id10",
+				TripID:     "trip10",
+				Type:       EventPaymentProcessed,
+				OccurredAt: now,
+				State:      StateCompleted,
+				Payload:    PaymentPayload{PaymentID: "pay-1", Amount: NewMoney(2340, USD), Method: "card", Status: "captured"},
+			},
+			wantTyp: PaymentPayload{},
+		},
+		{
+			name: "RideRated",
+			event: RideEvent{
+				ID:         "id11",
+				TripID:     "trip11",
+				Type:       EventRideRated,
+				OccurredAt: now,
+				State:      StateCompleted,
+				Payload:    RatingPayload{RatedBy: "passenger", Stars: 5, Comment: "smooth ride"},
+			},
+			wantTyp: RatingPayload{},
+		},
+		{
+			name: "TipAdded",
+			event: RideEvent{
+				ID:         "id12",
+				TripID:     "trip12",
+				Type:       EventTipAdded,
+				OccurredAt: now,
+				State:      StateCompleted,
+				Payload:    TipPayload{DriverID: "driver-1", Amount: NewMoney(400, USD)},
+			},
+			wantTyp: TipPayload{},
+		},
 	}
 
 	for _, tc := range cases {
@@ -122,3 +214,156 @@ func TestRideEventJSONMarshalling_AllTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestRideEventJSON_SchemaVersions(t *testing.T) {
+	data, err := json.Marshal(RideEvent{ID: "id1", TripID: "trip1", Type: EventTripStarted, PassengerID: "rider-1"})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var written struct {
+		SchemaVersion int    `json:"schema_version"`
+		PassengerID   string `json:"passenger_id"`
+	}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if written.SchemaVersion != SchemaVersion || written.PassengerID != "rider-1" {
+		t.Errorf("expected version %d with passenger_id, got %s", SchemaVersion, data)
+	}
+
+	cases := []struct {
+		name          string
+		json          string
+		wantVersion   int
+		wantPassenger string
+	}{
+		{"version 1 rider_id", `{"id":"id1","event_type":"STARTED","rider_id":"rider-1"}`, 1, "rider-1"},
+		{"version 1 passenger_id", `{"id":"id1","event_type":"STARTED","passenger_id":"rider-1"}`, 1, "rider-1"},
+		{"passenger_id wins", `{"id":"id1","event_type":"STARTED","passenger_id":"rider-1","rider_id":"rider-2"}`, 1, "rider-1"},
+		{"version 2", `{"id":"id1","event_type":"STARTED","passenger_id":"rider-1","schema_version":2}`, 2, "rider-1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var e RideEvent
+			if err := json.Unmarshal([]byte(tc.json), &e); err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			if e.SchemaVersion != tc.wantVersion || e.PassengerID != tc.wantPassenger {
+				t.Errorf("got version %d and passenger %q, want %d and %q", e.SchemaVersion, e.PassengerID, tc.wantVersion, tc.wantPassenger)
+			}
+		})
+	}
+}
+
+func TestRideEventJSON_MoneyBeforeVersion5(t *testing.T) {
+	cases := []struct {
+		name string
+		json string
+		want RideEventPayload
+	}{
+		{"fare", `{"id":"id1","event_type":"COMPLETED","schema_version":4,"payload_type":"RideCompletedPayload","payload":{"distance_km":3,"fare_usd":18.75}}`,
+			RideCompletedPayload{DistanceKM: 3, Fare: NewMoney(1875, USD)}},
+		{"fare without payload_type", `{"id":"id1","event_type":"COMPLETED","payload":{"fare_usd":0.1}}`,
+			RideCompletedPayload{Fare: NewMoney(10, USD)}},
+		{"payment", `{"id":"id1","event_type":"PAYMENT_PROCESSED","schema_version":3,"payload":{"payment_id":"p","amount_usd":7,"method":"card","status":"captured"}}`,
+			PaymentPayload{PaymentID: "p", Amount: NewMoney(700, USD), Method: "card", Status: "captured"}},
+		{"tip", `{"id":"id1","event_type":"TIP_ADDED","schema_version":4,"payload":{"driver_id":"d","amount_usd":2.5}}`,
+			TipPayload{DriverID: "d", Amount: NewMoney(250, USD)}},
+		{"version 5 is not upgraded", `{"id":"id1","event_type":"TIP_ADDED","schema_version":5,"payload":{"driver_id":"d","amount":{"amount_minor":3,"currency":"EUR"}}}`,
+			TipPayload{DriverID: "d", Amount: NewMoney(3, "EUR")}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var e RideEvent
+			if err := UnmarshalStrict([]byte(tc.json), &e); err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			if e.Payload != tc.want {
+				t.Errorf("payload = %#v, want %#v", e.Payload, tc.want)
+			}
+		})
+	}
+
+	p, err := DecodePayload(EventTripCompleted, []byte(`{"distance_km":3,"fare_usd":5.5}`))
+	if err != nil || p != (RideCompletedPayload{DistanceKM: 3, Fare: NewMoney(550, USD)}) {
+		t.Errorf("DecodePayload of a stored payload = %#v, %v", p, err)
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	const started = `"event_time":"2025-06-01T12:00:00Z","ride_state":"IN_PROGRESS"`
+	cases := []struct {
+		name    string
+		json    string
+		wantErr bool
+		unknown bool // the error is ErrUnknownEventType
+	}{
+		{"known fields", `{"id":"id1","trip_id":"trip1","event_type":"STARTED",` + started + `,"payload":{"start_time":"2025-06-01T12:00:00Z"}}`, false, false},
+		{"legacy rider_id", `{"id":"id1","event_type":"STARTED","rider_id":"rider-1"}`, false, false},
+		{"unknown event field", `{"id":"id1","event_type":"STARTED","vehicle":"sedan"}`, true, false},
+		{"unknown payload field", `{"id":"id1","event_type":"STARTED","payload":{"start_time":"2025-06-01T12:00:00Z","odometer":12}}`, true, false},
+		{"unknown event type", `{"id":"id1","event_type":"TELEPORTED","payload":{}}`, true, true},
+		{"trailing data", `{"id":"id1","event_type":"STARTED"}{}`, true, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var e RideEvent
+			err := UnmarshalStrict([]byte(tc.json), &e)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("UnmarshalStrict() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if errors.Is(err, ErrUnknownEventType) != tc.unknown {
+				t.Errorf("errors.Is(%v, ErrUnknownEventType) = %v, want %v", err, !tc.unknown, tc.unknown)
+			}
+			// The lenient decoder accepts all but the trailing data
+			if tc.name != "trailing data" {
+				var lenient RideEvent
+				if err := json.Unmarshal([]byte(tc.json), &lenient); err != nil {
+					t.Errorf("json.Unmarshal failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRideEvent_Lag(t *testing.T) {
+	occurred := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		recorded time.Time
+		lag      time.Duration
+		late     bool
+	}{
+		{"unknown", time.Time{}, 0, false},
+		{"on time", occurred.Add(200 * time.Millisecond), 200 * time.Millisecond, false},
+		{"late", occurred.Add(10 * time.Minute), 10 * time.Minute, true},
+		{"clock skew", occurred.Add(-time.Second), -time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := RideEvent{OccurredAt: occurred, RecordedAt: tt.recorded}
+			if got := e.Lag(); got != tt.lag {
+				t.Errorf("Lag() = %v, want %v", got, tt.lag)
+			}
+			if got := e.IsLate(time.Minute); got != tt.late {
+				t.Errorf("IsLate(1m) = %v, want %v", got, tt.late)
+			}
+		})
+	}
+
+	// RecordedAt is encoded only when known
+	data, err := json.Marshal(RideEvent{ID: "id1", Type: EventTripStarted, OccurredAt: occurred})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "recorded_at") {
+		t.Errorf("expected no recorded_at, got %s", data)
+	}
+	var e RideEvent
+	if err := UnmarshalStrict([]byte(`{"id":"id1","event_type":"STARTED","event_time":"2025-06-01T12:00:00Z","recorded_at":"2025-06-01T12:00:02Z"}`), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Lag() != 2*time.Second {
+		t.Errorf("Lag() of a decoded event = %v", e.Lag())
+	}
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PayloadUpConverter rewrites the JSON of a payload from one version of its
+// struct to the next, such as renaming a field, so payloads written before the
+// struct changed still decode. Payloads stored outside of events carry no
+// version, and DecodePayload runs every converter on them, so a converter must
+// return a payload already in the newer shape unchanged.
+type PayloadUpConverter func(raw json.RawMessage) (json.RawMessage, error)
+
+// ErrUnknownPayloadVersion is returned by UnmarshalStrict for a payload_version
+// newer than this build's payload struct.
+var ErrUnknownPayloadVersion = errors.New("events: unknown payload version")
+
+// upConverters holds, by payload_type, the converter from version i+1 to i+2 at
+// index i. Guarded by payloadsMu.
+var upConverters = map[string][]PayloadUpConverter{
+	// Version 2 holds amounts as Money rather than floats of dollars
+	"RideCompletedPayload": {dollarsToMoney("fare_usd", "fare")},
+	"PaymentPayload":       {dollarsToMoney("amount_usd", "amount")},
+	"TipPayload":           {dollarsToMoney("amount_usd", "amount")},
+	// Version 2 holds a CancelReason code rather than free text
+	"RideCancelledPayload": {cancelReasonToCode},
+}
+
+// RegisterUpConverter registers up as the converter of payloads of type
+// payloadType (see PayloadTypeName) from version from to from+1, which becomes
+// the payload_version MarshalJSON writes. Events carrying an older version are
+// converted before their payload is decoded. Converters are registered in
+// order from version 1, and RegisterUpConverter panics if from is not the
+// payload's current version; call it from an init function.
+func RegisterUpConverter(payloadType string, from int, up PayloadUpConverter) {
+	payloadsMu.Lock()
+	defer payloadsMu.Unlock()
+	if current := len(upConverters[payloadType]) + 1; from != current {
+		panic(fmt.Sprintf("events: up-converter for %s from version %d registered at version %d", payloadType, from, current))
+	}
+	upConverters[payloadType] = append(upConverters[payloadType], up)
+}
+
+// PayloadVersion returns the payload_version MarshalJSON writes for p: one more
+// than the number of its up-converters.
+func PayloadVersion(p RideEventPayload) int {
+	payloadsMu.RLock()
+	defer payloadsMu.RUnlock()
+	return len(upConverters[PayloadTypeName(p)]) + 1
+}
+
+// upgradePayload converts raw, a payload of type name in version, to the
+// current version. A version of zero is version 1. Versions newer than this
+// build knows are returned as they are, unless strict.
+func upgradePayload(name string, version int, raw json.RawMessage, strict bool) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return raw, nil
+	}
+	payloadsMu.RLock()
+	converters := upConverters[name]
+	payloadsMu.RUnlock()
+	version = max(version, 1)
+	if version > len(converters)+1 {
+		if strict {
+			return nil, fmt.Errorf("%w %d of %s", ErrUnknownPayloadVersion, version, name)
+		}
+		return raw, nil
+	}
+	for i, up := range converters[version-1:] {
+		var err error
+		if raw, err = up(raw); err != nil {
+			return nil, fmt.Errorf("events: converting %s payload to version %d: %w", name, version+i+1, err)
+		}
+	}
+	return raw, nil
+}
+
+// dollarsToMoney returns the converter that replaces the float of dollars in
+// field old with Money in USD in field new.
+func dollarsToMoney(old, new string) PayloadUpConverter {
+	return func(raw json.RawMessage) (json.RawMessage, error) {
+		if !bytes.Contains(raw, []byte(`"`+old+`"`)) {
+			return raw, nil
+		}
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(raw, &fields) != nil {
+			return raw, nil // Not an object; leave it to the payload's decoding
+		}
+		value, ok := fields[old]
+		if !ok {
+			return raw, nil
+		}
+		var dollars float64
+		if err := json.Unmarshal(value, &dollars); err != nil {
+			return nil, fmt.Errorf("field %s: %w", old, err)
+		}
+		delete(fields, old)
+		if _, ok := fields[new]; !ok {
+			money, err := json.Marshal(MoneyFromFloat(dollars, USD))
+			if err != nil {
+				return nil, err
+			}
+			fields[new] = money
+		}
+		return json.Marshal(fields)
+	}
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// routePayload is at version 3: version 1 named the route full_name, and
+// version 2 gave its length in kilometers.
+type routePayload struct {
+	PayloadMarker
+	Name    string  `json:"name"`
+	LengthM float64 `json:"length_m"`
+}
+
+const eventRouted RideEventType = "TEST_ROUTED"
+
+func init() {
+	RegisterPayload(eventRouted, func() RideEventPayload { return &routePayload{} })
+	RegisterUpConverter("routePayload", 1, renameField("full_name", "name"))
+	RegisterUpConverter("routePayload", 2, func(raw json.RawMessage) (json.RawMessage, error) {
+		var fields map[string]any
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, err
+		}
+		if km, ok := fields["length_km"].(float64); ok {
+			delete(fields, "length_km")
+			fields["length_m"] = km * 1000
+		}
+		return json.Marshal(fields)
+	})
+}
+
+// renameField returns the converter that renames field old to new.
+func renameField(old, new string) PayloadUpConverter {
+	return func(raw json.RawMessage) (json.RawMessage, error) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, err
+		}
+		if v, ok := fields[old]; ok {
+			delete(fields, old)
+			fields[new] = v
+		}
+		return json.Marshal(fields)
+	}
+}
+
+func TestUpConverters(t *testing.T) {
+	want := routePayload{Name: "airport run", LengthM: 2500}
+	cases := []struct {
+		name string
+		json string
+	}{
+		{"version 1", `{"id":"id1","event_type":"TEST_ROUTED","payload_type":"routePayload","payload_version":1,"payload":{"full_name":"airport run","length_km":2.5}}`},
+		{"no version", `{"id":"id1","event_type":"TEST_ROUTED","payload":{"full_name":"airport run","length_km":2.5}}`},
+		{"version 2", `{"id":"id1","event_type":"TEST_ROUTED","payload_type":"routePayload","payload_version":2,"payload":{"name":"airport run","length_km":2.5}}`},
+		{"current", `{"id":"id1","event_type":"TEST_ROUTED","payload_type":"routePayload","payload_version":3,"payload":{"name":"airport run","length_m":2500}}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var e RideEvent
+			if err := UnmarshalStrict([]byte(tc.json), &e); err != nil {
+				t.Fatalf("UnmarshalStrict failed: %v", err)
+			}
+			if e.Payload != want {
+				t.Errorf("payload = %#v, want %#v", e.Payload, want)
+			}
+		})
+	}
+
+	if v := PayloadVersion(want); v != 3 {
+		t.Errorf("PayloadVersion = %d, want 3", v)
+	}
+	data, err := json.Marshal(RideEvent{ID: "id1", Type: eventRouted, Payload: want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"payload_version":3`) {
+		t.Errorf("expected payload_version 3, got %s", data)
+	}
+
+	// Stored payloads have no version and go through every converter
+	p, err := DecodePayload(eventRouted, []byte(`{"full_name":"airport run","length_km":2.5}`))
+	if err != nil || p != want {
+		t.Errorf("DecodePayload = %#v, %v", p, err)
+	}
+}
+
+func TestUpConverters_NewerVersion(t *testing.T) {
+	raw := []byte(`{"id":"id1","event_type":"TEST_ROUTED","payload_type":"routePayload","payload_version":4,"payload":{"name":"airport run"}}`)
+	var e RideEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		t.Errorf("expected lenient decoding to accept a newer version, got %v", err)
+	}
+	if err := UnmarshalStrict(raw, &e); !errors.Is(err, ErrUnknownPayloadVersion) {
+		t.Errorf("expected ErrUnknownPayloadVersion, got %v", err)
+	}
+}
+
+func TestRegisterUpConverter_Panics(t *testing.T) {
+	for _, from := range []int{1, 2, 4} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a converter from version %d to panic", from)
+				}
+			}()
+			RegisterUpConverter("routePayload", from, renameField("a", "b"))
+		}()
+	}
+}
+
+func TestBuiltInUpConverters_AreIdempotent(t *testing.T) {
+	current := []byte(`{"end_time":"2025-06-01T12:00:00Z","distance_km":3,"fare":{"amount_minor":950,"currency":"EUR"}}`)
+	for _, version := range []int{0, 1} {
+		raw, err := upgradePayload("RideCompletedPayload", version, current, true)
+		if err != nil || string(raw) != string(current) {
+			t.Errorf("version %d: converting a current payload changed it to %s, %v", version, raw, err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// ValidationError reports a field of an event that breaks its contract.
+type ValidationError struct {
+	Field  string // JSON name, with payload fields prefixed by "payload."
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("events: %s %s", e.Field, e.Reason)
+}
+
+// stateAfter is the ride state each lifecycle event type leaves a ride in.
+var stateAfter = map[RideEventType]RideState{
+	EventRideRequested: StateRequested,
+	EventRideAccepted:  StateAccepted,
+	EventTripStarted:   StateInProgress,
+	EventTripCompleted: StateCompleted,
+	EventTripCancelled: StateCancelled,
+	EventDriverArrived: StateDriverArrived,
+	EventPickedUp:      StatePickedUp,
+}
+
+// tripAttached are the event types that belong to a trip without changing its
+// state, so their State is whatever the ride's state is.
+var tripAttached = map[RideEventType]bool{
+	EventLocationUpdated:  true,
+	EventPaymentProcessed: true,
+	EventRideRated:        true,
+	EventTipAdded:         true,
+	EventPriceQuoted:      true,
+}
+
+// rideStates lists every RideState.
+var rideStates = map[RideState]bool{
+	StateNew: true, StateRequested: true, StateAccepted: true, StateDriverArrived: true,
+	StatePickedUp: true, StateInProgress: true, StateCompleted: true, StateCancelled: true,
+}
+
+// Validate checks e against the RideEvent contract: an ID, a known type (see
+// RegisterPayload), and an event time; for lifecycle events a trip and the state
+// the type leads to, and for other events of a trip, such as payments, a trip
+// and a known state; and a payload of the type's registered payload struct that
+// passes its own Validate method, if it has one. It returns every problem found,
+// each as a *ValidationError, joined with errors.Join.
+func (e RideEvent) Validate() error {
+	var errs []error
+	invalid := func(field, reason string) {
+		errs = append(errs, &ValidationError{Field: field, Reason: reason})
+	}
+
+	if e.ID == "" {
+		invalid("id", "is required")
+	}
+	if e.OccurredAt.IsZero() {
+		invalid("event_time", "is required")
+	}
+	want, known := payloadTypes(e.Type)
+	if !known {
+		invalid("event_type", fmt.Sprintf("%q is not a known event type", e.Type))
+	}
+	if s, ok := stateAfter[e.Type]; ok {
+		if e.TripID == "" {
+			invalid("trip_id", "is required")
+		}
+		if e.State != s {
+			invalid("ride_state", fmt.Sprintf("is %q, want %q for %s", e.State, s, e.Type))
+		}
+	}
+	if tripAttached[e.Type] {
+		if e.TripID == "" {
+			invalid("trip_id", "is required")
+		}
+		if !rideStates[e.State] {
+			invalid("ride_state", fmt.Sprintf("%q is not a known ride state", e.State))
+		}
+	}
+
+	if known {
+		switch {
+		case e.Payload == nil:
+			invalid("payload", "is required")
+		case !slices.Contains(want, reflect.TypeOf(e.Payload)):
+			invalid("payload", fmt.Sprintf("is %T, want %s for %s", e.Payload, want[0], e.Type))
+		}
+	}
+	if p, ok := e.Payload.(interface{ Validate() error }); ok {
+		errs = append(errs, p.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+// payloadErrors collects the problems of one payload.
+type payloadErrors []error
+
+func (pe *payloadErrors) check(ok bool, field, reason string) {
+	if !ok {
+		*pe = append(*pe, &ValidationError{Field: "payload." + field, Reason: reason})
+	}
+}
+
+// money checks that m, the payload's field, is in a currency unless it is zero.
+func (pe *payloadErrors) money(m Money, field string) {
+	pe.check(m.IsZero() || validCurrency(m.Currency), field+".currency", "must be an ISO 4217 code")
+}
+
+func (pe payloadErrors) err() error { return errors.Join(pe...) }
+
+// Validate checks that the passenger and both locations are set and that any
+// coordinates are in range.
+func (p RideRequestedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.Passenger != "", "passenger", "is required")
+	errs.check(p.PickupLocation != "", "pickup_location", "is required")
+	errs.check(p.DropoffLocation != "", "dropoff_location", "is required")
+	errs.check(p.Pickup == nil || p.Pickup.Valid(), "pickup", "is out of range")
+	errs.check(p.Dropoff == nil || p.Dropoff.Valid(), "dropoff", "is out of range")
+	return errs.err()
+}
+
+// Validate checks that the driver is set.
+func (p RideAcceptedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.DriverID != "", "driver_id", "is required")
+	return errs.err()
+}
+
+// Validate checks that the driver is set and the location, if any, is in range.
+func (p DriverArrivedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.DriverID != "", "driver_id", "is required")
+	errs.check(p.Location == nil || p.Location.Valid(), "location", "is out of range")
+	return errs.err()
+}
+
+// Validate checks that the pickup time is set and the location, if any, is in range.
+func (p PickedUpPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(!p.PickupTime.IsZero(), "pickup_time", "is required")
+	errs.check(p.Location == nil || p.Location.Valid(), "location", "is out of range")
+	return errs.err()
+}
+
+// Validate checks that the location is in range, the heading is in [0, 360),
+// and the speed and accuracy are not negative.
+func (p LocationUpdatedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.Location.Valid(), "location", "is out of range")
+	errs.check(p.HeadingDeg >= 0 && p.HeadingDeg < 360, "heading_deg", "must be in [0, 360)")
+	errs.check(p.SpeedKPH >= 0, "speed_kph", "is negative")
+	errs.check(p.AccuracyM >= 0, "accuracy_m", "is negative")
+	return errs.err()
+}
+
+// Validate checks that the payment and amount are set and the method and status
+// are known.
+func (p PaymentPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.PaymentID != "", "payment_id", "is required")
+	errs.money(p.Amount, "amount")
+	errs.check(p.Amount.Amount >= 0, "amount", "is negative")
+	errs.check(p.Method == "card" || p.Method == "cash" || p.Method == "wallet", "method", `must be "card", "cash", or "wallet"`)
+	errs.check(p.Status == "captured" || p.Status == "declined" || p.Status == "refunded", "status", `must be "captured", "declined", or "refunded"`)
+	return errs.err()
+}
+
+// Validate checks that the rating is by the passenger or the driver and has 1 to 5 stars.
+func (p RatingPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.RatedBy == "passenger" || p.RatedBy == "driver", "rated_by", `must be "passenger" or "driver"`)
+	errs.check(p.Stars >= 1 && p.Stars <= 5, "stars", "must be from 1 to 5")
+	return errs.err()
+}
+
+// Validate checks that the driver is set and the amount is positive.
+func (p TipPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.DriverID != "", "driver_id", "is required")
+	errs.money(p.Amount, "amount")
+	errs.check(p.Amount.Amount > 0, "amount", "must be positive")
+	return errs.err()
+}
+
+// Validate checks that the start time is set.
+func (p RideStartedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(!p.StartTime.IsZero(), "start_time", "is required")
+	return errs.err()
+}
+
+// Validate checks that the end time is set, the distance and fare are not
+// negative, and the fare is in US dollars, the currency trips, rides, and the
+// windows store fares in.
+func (p RideCompletedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(!p.EndTime.IsZero(), "end_time", "is required")
+	errs.check(p.DistanceKM >= 0, "distance_km", "is negative")
+	errs.money(p.Fare, "fare")
+	errs.check(p.Fare.IsZero() || !validCurrency(p.Fare.Currency) || p.Fare.Currency == USD, "fare.currency", "must be "+USD)
+	errs.check(p.Fare.Amount >= 0, "fare", "is negative")
+	return errs.err()
+}
+
+// Validate checks that the ride was cancelled by a known actor, for a known
+// reason if any.
+func (p RideCancelledPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.CancelledBy.IsKnown(), "cancelled_by", `must be "passenger", "driver", or "system"`)
+	errs.check(p.Reason.IsKnown(), "reason", "is not a known reason code")
+	return errs.err()
+}
+
+// Validate checks that the quote is set, the multiplier is at least 1, the
+// distance is not negative, the expiry is set, and the total is the breakdown's
+// total with the multiplier applied.
+func (p PriceQuotePayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.QuoteID != "", "quote_id", "is required")
+	errs.check(p.Multiplier >= 1, "multiplier", "must be at least 1")
+	errs.check(p.DistanceKM >= 0, "distance_km", "is negative")
+	errs.check(!p.ExpiresAt.IsZero(), "expires_at", "is required")
+	errs.money(p.Total, "total")
+	total, err := p.Breakdown.Total(p.Multiplier)
+	errs.check(err == nil, "breakdown", "must be in one currency")
+	errs.check(err != nil || total == p.Total, "total", fmt.Sprintf("is %s, want %s from the breakdown", p.Total, total))
+	return errs.err()
+}
+
+// Validate checks that the zone is set and the multiplier is positive.
+func (p SurgeUpdatedPayload) Validate() error {
+	var errs payloadErrors
+	errs.check(p.ZoneID != "", "zone_id", "is required")
+	errs.check(p.Multiplier > 0, "multiplier", "must be positive")
+	return errs.err()
+}
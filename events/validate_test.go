@@ -0,0 +1,190 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRideEvent_Validate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	valid := func() RideEvent {
+		return RideEvent{
+			ID: "id1", TripID: "trip1", Type: EventTripCompleted, State: StateCompleted, OccurredAt: now,
+			Payload: RideCompletedPayload{EndTime: now, DistanceKM: 4, Fare: NewMoney(950, USD)},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(e *RideEvent)
+		fields []string // fields reported invalid, in order
+	}{
+		{name: "valid", modify: func(e *RideEvent) {}},
+		{
+			name: "surge update without trip or state",
+			modify: func(e *RideEvent) {
+				*e = RideEvent{ID: "id1", Type: EventSurgeUpdated, OccurredAt: now, Payload: SurgeUpdatedPayload{ZoneID: "midtown", Multiplier: 1.5}}
+			},
+		},
+		{name: "missing id and time", modify: func(e *RideEvent) { e.ID = ""; e.OccurredAt = time.Time{} }, fields: []string{"id", "event_time"}},
+		{name: "unknown type", modify: func(e *RideEvent) { e.Type = "TELEPORTED" }, fields: []string{"event_type"}},
+		{name: "missing trip", modify: func(e *RideEvent) { e.TripID = "" }, fields: []string{"trip_id"}},
+		{name: "state does not follow type", modify: func(e *RideEvent) { e.State = StateInProgress }, fields: []string{"ride_state"}},
+		{name: "missing payload", modify: func(e *RideEvent) { e.Payload = nil }, fields: []string{"payload"}},
+		{
+			name:   "payload of another type",
+			modify: func(e *RideEvent) { e.Payload = RideStartedPayload{StartTime: now} },
+			fields: []string{"payload"},
+		},
+		{
+			name:   "invalid payload",
+			modify: func(e *RideEvent) { e.Payload = RideCompletedPayload{DistanceKM: -1, Fare: NewMoney(200, USD)} },
+			fields: []string{"payload.end_time", "payload.distance_km"},
+		},
+		{
+			name: "requested with a coordinate out of range",
+			modify: func(e *RideEvent) {
+				e.Type, e.State = EventRideRequested, StateRequested
+				e.Payload = RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St",
+					Pickup: &Coordinate{Lat: 91, Lng: 0}}
+			},
+			fields: []string{"payload.pickup"},
+		},
+		{
+			name: "cancelled by someone else",
+			modify: func(e *RideEvent) {
+				e.Type, e.State = EventTripCancelled, StateCancelled
+				e.Payload = RideCancelledPayload{CancelledBy: "dispatcher"}
+			},
+			fields: []string{"payload.cancelled_by"},
+		},
+		{
+			name: "driver arrived",
+			modify: func(e *RideEvent) {
+				e.Type, e.State = EventDriverArrived, StateDriverArrived
+				e.Payload = DriverArrivedPayload{DriverID: "driver-1"}
+			},
+		},
+		{
+			name: "picked up without a time",
+			modify: func(e *RideEvent) {
+				e.Type, e.State = EventPickedUp, StatePickedUp
+				e.Payload = PickedUpPayload{}
+			},
+			fields: []string{"payload.pickup_time"},
+		},
+		{
+			name: "location update keeps the ride state",
+			modify: func(e *RideEvent) {
+				e.Type, e.State = EventLocationUpdated, StateInProgress
+				e.Payload = LocationUpdatedPayload{Location: Coordinate{Lat: 40.75, Lng: -73.98}, HeadingDeg: 359.5, SpeedKPH: 20}
+			},
+		},
+		{
+			name: "location update out of range",
+			modify: func(e *RideEvent) {
+				e.Type, e.State = EventLocationUpdated, "PARKED"
+				e.Payload = LocationUpdatedPayload{Location: Coordinate{Lat: 0, Lng: 181}, HeadingDeg: 360, SpeedKPH: -1}
+			},
+			fields: []string{"ride_state", "payload.location", "payload.heading_deg", "payload.speed_kph"},
+		},
+		{
+			name: "payment",
+			modify: func(e *RideEvent) {
+				e.Type = EventPaymentProcessed
+				e.Payload = PaymentPayload{PaymentID: "pay-1", Amount: NewMoney(1200, USD), Method: "wallet", Status: "captured"}
+			},
+		},
+		{
+			name: "payment without trip or method",
+			modify: func(e *RideEvent) {
+				e.Type, e.TripID = EventPaymentProcessed, ""
+				e.Payload = PaymentPayload{PaymentID: "pay-1", Amount: NewMoney(1200, USD), Status: "pending"}
+			},
+			fields: []string{"trip_id", "payload.method", "payload.status"},
+		},
+		{
+			name: "rating out of range",
+			modify: func(e *RideEvent) {
+				e.Type = EventRideRated
+				e.Payload = RatingPayload{RatedBy: "driver", Stars: 6}
+			},
+			fields: []string{"payload.stars"},
+		},
+		{
+			name: "empty tip",
+			modify: func(e *RideEvent) {
+				e.Type = EventTipAdded
+				e.Payload = TipPayload{DriverID: "driver-1"}
+			},
+			fields: []string{"payload.amount"},
+		},
+		{
+			name: "price quote whose total does not match its breakdown",
+			modify: func(e *RideEvent) {
+				e.Type, e.State = EventPriceQuoted, StateNew
+				e.Payload = PriceQuotePayload{QuoteID: "quote-1", Multiplier: 2, ExpiresAt: now,
+					Breakdown: FareBreakdown{Base: NewMoney(250, USD), Distance: NewMoney(400, USD)},
+					Total:     NewMoney(650, USD)}
+			},
+			fields: []string{"payload.total"},
+		},
+		{
+			name: "price quote in two currencies without surge",
+			modify: func(e *RideEvent) {
+				e.Type, e.State = EventPriceQuoted, StateRequested
+				e.Payload = PriceQuotePayload{QuoteID: "quote-1", Multiplier: 0.5, ExpiresAt: now,
+					Breakdown: FareBreakdown{Base: NewMoney(250, USD), Distance: NewMoney(400, "EUR")}}
+			},
+			fields: []string{"payload.multiplier", "payload.breakdown"},
+		},
+		{
+			name: "fare without currency",
+			modify: func(e *RideEvent) {
+				e.Payload = RideCompletedPayload{EndTime: e.OccurredAt, Fare: Money{Amount: 950}}
+			},
+			fields: []string{"payload.fare.currency"},
+		},
+		{
+			name: "fare in another currency",
+			modify: func(e *RideEvent) {
+				e.Payload = RideCompletedPayload{EndTime: e.OccurredAt, Fare: NewMoney(950, "EUR")}
+			},
+			fields: []string{"payload.fare.currency"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := valid()
+			tt.modify(&e)
+			err := e.Validate()
+			got := validationFields(err)
+			if len(got) != len(tt.fields) {
+				t.Fatalf("Validate() = %v, want errors for %v", err, tt.fields)
+			}
+			for i := range got {
+				if got[i] != tt.fields[i] {
+					t.Errorf("error %d is for %s, want %s", i, got[i], tt.fields[i])
+				}
+			}
+		})
+	}
+}
+
+// validationFields returns the fields of the ValidationErrors in err, which may
+// itself join several.
+func validationFields(err error) []string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var fields []string
+		for _, err := range joined.Unwrap() {
+			fields = append(fields, validationFields(err)...)
+		}
+		return fields
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return []string{ve.Field}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvWriter writes a header row and then one line per record. Nulls are empty
+// fields and times are RFC 3339 in UTC.
+type csvWriter struct {
+	w    *csv.Writer
+	cols []Column
+	rec  []string
+}
+
+// NewCSVWriter returns a RecordWriter that writes cols to w as CSV, starting
+// with a header row.
+func NewCSVWriter(w io.Writer, cols []Column) (RecordWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), cols: cols, rec: make([]string, len(cols))}
+	for i, c := range cols {
+		cw.rec[i] = c.Name
+	}
+	if err := cw.w.Write(cw.rec); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(record []any) error {
+	if len(record) != len(cw.cols) {
+		return fmt.Errorf("export: record has %d values, want %d", len(record), len(cw.cols))
+	}
+	for i, v := range record {
+		switch v := v.(type) {
+		case nil:
+			cw.rec[i] = ""
+		case string:
+			cw.rec[i] = v
+		case float64:
+			cw.rec[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			cw.rec[i] = v.UTC().Format(time.RFC3339Nano)
+		default:
+			return fmt.Errorf("export: column %s: unsupported value %T", cw.cols[i].Name, v)
+		}
+	}
+	return cw.w.Write(cw.rec)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
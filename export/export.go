@@ -0,0 +1,181 @@
+// Package export streams trips and ride events out of the store as CSV or
+// Parquet files, for analysts who do not have database access.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// Store is the part of rides_db.RideStore an export reads from.
+type Store interface {
+	ExportTrips(ctx context.Context, tr rides_db.TimeRange, fn func(aggregation.Trip) error) error
+	ExportRideEvents(ctx context.Context, tr rides_db.TimeRange, fn func(events.RideEvent) error) error
+}
+
+// Table names what is exported.
+type Table string
+
+const (
+	TableTrips      Table = "trips"
+	TableRideEvents Table = "ride_events"
+)
+
+// Format is the file format an export is written in.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// Kind is the type of a column's values. Every column may also be null.
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindFloat
+	KindTime
+)
+
+// Column describes one exported column.
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// TripColumns are the columns of a trips export.
+var TripColumns = []Column{
+	{"trip_id", KindString},
+	{"passenger_id", KindString},
+	{"driver_id", KindString},
+	{"final_state", KindString},
+	{"pickup_location", KindString},
+	{"dropoff_location", KindString},
+	{"requested_at", KindTime},
+	{"accepted_at", KindTime},
+	{"started_at", KindTime},
+	{"completed_at", KindTime},
+	{"cancelled_at", KindTime},
+	{"distance_km", KindFloat},
+	{"fare_usd", KindFloat},
+	{"cancelled_by", KindString},
+	{"cancel_reason", KindString},
+}
+
+// RideEventColumns are the columns of a ride_events export. The payload is
+// written as JSON.
+var RideEventColumns = []Column{
+	{"id", KindString},
+	{"trip_id", KindString},
+	{"event_type", KindString},
+	{"ride_state", KindString},
+	{"event_time", KindTime},
+	{"driver_id", KindString},
+	{"passenger_id", KindString},
+	{"payload", KindString},
+}
+
+// Columns returns the columns exported for table.
+func Columns(table Table) ([]Column, error) {
+	switch table {
+	case TableTrips:
+		return TripColumns, nil
+	case TableRideEvents:
+		return RideEventColumns, nil
+	default:
+		return nil, fmt.Errorf("unknown export table %q", table)
+	}
+}
+
+// RecordWriter writes rows whose values follow its columns: a string, float64,
+// or time.Time, or nil for null. Close must be called to finish the file.
+type RecordWriter interface {
+	Write(record []any) error
+	Close() error
+}
+
+// NewWriter returns a RecordWriter for format writing cols to w.
+func NewWriter(w io.Writer, format Format, cols []Column) (RecordWriter, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w, cols)
+	case FormatParquet:
+		return NewParquetWriter(w, cols), nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// Run streams the rows of table within tr from store to w and returns how many
+// were written. It does not close w.
+func Run(ctx context.Context, store Store, table Table, tr rides_db.TimeRange, w RecordWriter) (int64, error) {
+	var n int64
+	switch table {
+	case TableTrips:
+		err := store.ExportTrips(ctx, tr, func(t aggregation.Trip) error {
+			n++
+			return w.Write(tripRecord(t))
+		})
+		return n, err
+	case TableRideEvents:
+		err := store.ExportRideEvents(ctx, tr, func(e events.RideEvent) error {
+			record, err := rideEventRecord(e)
+			if err != nil {
+				return err
+			}
+			n++
+			return w.Write(record)
+		})
+		return n, err
+	default:
+		return 0, fmt.Errorf("unknown export table %q", table)
+	}
+}
+
+// tripRecord returns t's values in TripColumns order.
+func tripRecord(t aggregation.Trip) []any {
+	return []any{
+		t.TripID, optString(t.PassengerID), optString(t.DriverID), string(t.FinalState),
+		optString(t.PickupLocation), optString(t.DropoffLocation),
+		optTime(t.RequestedAt), optTime(t.AcceptedAt), optTime(t.StartedAt), optTime(t.CompletedAt), optTime(t.CancelledAt),
+		t.DistanceKM, t.FareUSD, optString(t.CancelledBy), optString(t.CancelReason),
+	}
+}
+
+// rideEventRecord returns e's values in RideEventColumns order.
+func rideEventRecord(e events.RideEvent) ([]any, error) {
+	var payload any
+	if e.Payload != nil {
+		b, err := json.Marshal(e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = string(b)
+	}
+	return []any{
+		e.ID, e.TripID, string(e.Type), string(e.State), e.OccurredAt,
+		optString(e.DriverID), optString(e.PassengerID), payload,
+	}, nil
+}
+
+func optString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func optTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,203 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+type fakeStore struct {
+	trips  []aggregation.Trip
+	events []events.RideEvent
+	ranges []rides_db.TimeRange
+}
+
+func (f *fakeStore) ExportTrips(ctx context.Context, tr rides_db.TimeRange, fn func(aggregation.Trip) error) error {
+	f.ranges = append(f.ranges, tr)
+	for _, t := range f.trips {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) ExportRideEvents(ctx context.Context, tr rides_db.TimeRange, fn func(events.RideEvent) error) error {
+	f.ranges = append(f.ranges, tr)
+	for _, e := range f.events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var base = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRun_CSV(t *testing.T) {
+	store := &fakeStore{
+		trips: []aggregation.Trip{
+			{TripID: "trip-1", PassengerID: "p-1", DriverID: "d-1", FinalState: events.StateCompleted,
+				PickupLocation: "Main St", DropoffLocation: "Elm St",
+				RequestedAt: base, AcceptedAt: base.Add(time.Minute), StartedAt: base.Add(2 * time.Minute),
+				CompletedAt: base.Add(20 * time.Minute), DistanceKM: 7.5, FareUSD: 10},
+			{TripID: "trip-2", FinalState: events.StateCancelled, RequestedAt: base.Add(time.Hour),
+				CancelledAt: base.Add(61 * time.Minute), CancelledBy: "passenger", CancelReason: "no_show"},
+		},
+		events: []events.RideEvent{
+			{ID: "e-1", TripID: "trip-1", Type: events.EventTripCompleted, State: events.StateCompleted, OccurredAt: base,
+				DriverID: "d-1", Payload: events.RideCompletedPayload{EndTime: base, DistanceKM: 7.5, Fare: events.NewMoney(1000, events.USD)}},
+		},
+	}
+	tr := rides_db.TimeRange{From: base, To: base.Add(24 * time.Hour)}
+
+	tests := []struct {
+		table Table
+		want  string
+		rows  int64
+	}{
+		{
+			table: TableTrips,
+			want: "trip_id,passenger_id,driver_id,final_state,pickup_location,dropoff_location,requested_at,accepted_at,started_at,completed_at,cancelled_at,distance_km,fare_usd,cancelled_by,cancel_reason\n" +
+				"trip-1,p-1,d-1,COMPLETED,Main St,Elm St,2025-06-01T12:00:00Z,2025-06-01T12:01:00Z,2025-06-01T12:02:00Z,2025-06-01T12:20:00Z,,7.5,10,,\n" +
+				"trip-2,,,CANCELLED,,,2025-06-01T13:00:00Z,,,,2025-06-01T13:01:00Z,0,0,passenger,no_show\n",
+			rows: 2,
+		},
+		{
+			table: TableRideEvents,
+			want: "id,trip_id,event_type,ride_state,event_time,driver_id,passenger_id,payload\n" +
+				`e-1,trip-1,COMPLETED,COMPLETED,2025-06-01T12:00:00Z,d-1,,"{""end_time"":""2025-06-01T12:00:00Z"",""distance_km"":7.5,""fare"":{""amount_minor"":1000,""currency"":""USD""}}"` + "\n",
+			rows: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.table), func(t *testing.T) {
+			cols, err := Columns(tt.table)
+			if err != nil {
+				t.Fatalf("Columns failed: %v", err)
+			}
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, FormatCSV, cols)
+			if err != nil {
+				t.Fatalf("NewWriter failed: %v", err)
+			}
+			n, err := Run(context.Background(), store, tt.table, tr, w)
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if n != tt.rows {
+				t.Errorf("Run wrote %d rows, want %d", n, tt.rows)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("unexpected CSV:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+	if got := store.ranges[0]; got != tr {
+		t.Errorf("store was asked for %v, want %v", got, tr)
+	}
+}
+
+func TestParquetWriter_ReadBack(t *testing.T) {
+	cols := []Column{{"id", KindString}, {"fare", KindFloat}, {"at", KindTime}}
+	records := [][]any{
+		{"a", 1.5, base},
+		{"b", nil, nil},
+		{"c", 2.25, base.Add(time.Second)},
+	}
+
+	var buf bytes.Buffer
+	w := NewParquetWriter(&buf, cols)
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.Write([]any{1, 2.0, base}); err == nil {
+		t.Error("expected a value of the wrong kind to be rejected")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	if got := f.NumRows(); got != int64(len(records)) {
+		t.Errorf("file has %d rows, want %d", got, len(records))
+	}
+	fields := f.Schema().Fields()
+	if len(fields) != len(cols) {
+		t.Fatalf("schema has %d columns, want %d", len(fields), len(cols))
+	}
+	for i, c := range cols {
+		if fields[i].Name() != c.Name || !fields[i].Optional() {
+			t.Errorf("schema column %d is %v, want optional %q", i, fields[i], c.Name)
+		}
+	}
+	if lt := fields[2].Type().LogicalType(); lt == nil || lt.Timestamp == nil || lt.Timestamp.Unit.Micros == nil {
+		t.Errorf("at is %v, want a microsecond timestamp", fields[2].Type())
+	}
+
+	rows := make([]parquet.Row, len(records)+1)
+	n, err := parquet.NewReader(f).ReadRows(rows)
+	if err != nil && err != io.EOF {
+		t.Fatalf("ReadRows failed: %v", err)
+	}
+	if n != len(records) {
+		t.Fatalf("read %d rows, want %d", n, len(records))
+	}
+	for i, r := range records {
+		row := rows[i]
+		if got := row[0].String(); got != r[0] {
+			t.Errorf("row %d: id %q, want %q", i, got, r[0])
+		}
+		if r[1] == nil {
+			if !row[1].IsNull() || !row[2].IsNull() {
+				t.Errorf("row %d: fare %v at %v, want nulls", i, row[1], row[2])
+			}
+			continue
+		}
+		if got := row[1].Double(); got != r[1] {
+			t.Errorf("row %d: fare %v, want %v", i, got, r[1])
+		}
+		if got := time.UnixMicro(row[2].Int64()).UTC(); !got.Equal(r[2].(time.Time)) {
+			t.Errorf("row %d: at %v, want %v", i, got, r[2])
+		}
+	}
+}
+
+func TestParquetWriter_RowGroups(t *testing.T) {
+	cols := []Column{{"id", KindString}}
+	var buf bytes.Buffer
+	w := NewParquetWriter(&buf, cols)
+	for range parquetRowGroupRows + 1 {
+		if err := w.Write([]any{"a"}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	groups := f.RowGroups()
+	if len(groups) != 2 || groups[0].NumRows() != parquetRowGroupRows || groups[1].NumRows() != 1 {
+		t.Errorf("got %d row groups, want one of %d rows and one of 1", len(groups), parquetRowGroupRows)
+	}
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupRows is how many rows are buffered before they are written out
+// as a row group, which bounds the writer's memory use.
+const parquetRowGroupRows = 50000
+
+// parquetWriter writes a Parquet file with every column optional, uncompressed,
+// and one row group per parquetRowGroupRows rows. Strings are UTF8 byte arrays,
+// floats doubles, and times INT64 microseconds since the epoch in UTC.
+type parquetWriter struct {
+	w    *parquet.Writer
+	cols []Column
+	row  parquet.Row
+}
+
+// NewParquetWriter returns a RecordWriter that writes cols to w as Parquet. The
+// file is complete only once Close returns.
+func NewParquetWriter(w io.Writer, cols []Column) RecordWriter {
+	schema := parquet.NewSchema("schema", parquetSchema(cols))
+	return &parquetWriter{
+		w: parquet.NewWriter(w, schema,
+			parquet.MaxRowsPerRowGroup(parquetRowGroupRows),
+			parquet.CreatedBy("kafkarideshare export", "", "")),
+		cols: cols,
+		row:  make(parquet.Row, len(cols)),
+	}
+}
+
+func (pw *parquetWriter) Write(record []any) error {
+	if len(record) != len(pw.cols) {
+		return fmt.Errorf("export: record has %d values, want %d", len(record), len(pw.cols))
+	}
+	for i, v := range record {
+		value, err := parquetValue(pw.cols[i], v)
+		if err != nil {
+			return err
+		}
+		definition := 1
+		if v == nil {
+			definition = 0
+		}
+		pw.row[i] = value.Level(0, definition, i)
+	}
+	_, err := pw.w.WriteRows([]parquet.Row{pw.row})
+	return err
+}
+
+// Close writes any buffered rows and the file footer. It does not close the
+// underlying writer.
+func (pw *parquetWriter) Close() error {
+	return pw.w.Close()
+}
+
+// parquetValue returns v as the Parquet value of column c, or an error if v is
+// not of c's kind.
+func parquetValue(c Column, v any) (parquet.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return parquet.NullValue(), nil
+	case string:
+		if c.Kind == KindString {
+			return parquet.ByteArrayValue([]byte(v)), nil
+		}
+	case float64:
+		if c.Kind == KindFloat {
+			return parquet.DoubleValue(v), nil
+		}
+	case time.Time:
+		if c.Kind == KindTime {
+			return parquet.Int64Value(v.UnixMicro()), nil
+		}
+	}
+	return parquet.Value{}, fmt.Errorf("export: column %s: unsupported value %T", c.Name, v)
+}
+
+// parquetSchema returns the schema's root node, with a field per column in
+// cols order.
+func parquetSchema(cols []Column) parquet.Node {
+	group := orderedGroup{Group: parquet.Group{}}
+	for _, c := range cols {
+		var node parquet.Node
+		switch c.Kind {
+		case KindFloat:
+			node = parquet.Leaf(parquet.DoubleType)
+		case KindTime:
+			node = parquet.Timestamp(parquet.Microsecond)
+		default:
+			node = parquet.String()
+		}
+		group.Group[c.Name] = parquet.Optional(node)
+		group.names = append(group.names, c.Name)
+	}
+	return group
+}
+
+// orderedGroup is a parquet.Group whose fields keep the order they were added
+// in, where parquet.Group sorts them by name.
+type orderedGroup struct {
+	parquet.Group
+	names []string
+}
+
+func (g orderedGroup) Fields() []parquet.Field {
+	fields := g.Group.Fields()
+	slices.SortFunc(fields, func(a, b parquet.Field) int {
+		return slices.Index(g.names, a.Name()) - slices.Index(g.names, b.Name())
+	})
+	return fields
+}
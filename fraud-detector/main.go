@@ -0,0 +1,110 @@
+// Command fraud-detector watches the ride stream for rides that look wrong:
+// drivers moving faster than they could, drivers on two rides at once, and
+// fares far from what a ride's distance comes to. It sends an alert for each
+// to the fraud-alerts topic, and stores every alert on that topic, the
+// pricer's included, in fraud_alerts; see the fraud package.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+const (
+	defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID        = "fraud-detector"
+)
+
+// configFromEnv reads the detector settings: FRAUD_MAX_SPEED_KPH, the fastest
+// a driver can go between location updates, and FRAUD_MAX_FARE_RATIO, how many
+// times more or less than its distance comes to a fare may be. Invalid values
+// are logged and left to the defaults.
+func configFromEnv() fraud.Config {
+	cfg := fraud.Config{
+		RideTopic:  topics.RideEvents,
+		AlertTopic: topics.FraudAlerts,
+		ExpectedFare: func(distanceKM float64) (events.Money, error) {
+			return pricing.DefaultRates.Fare(distanceKM, 0, 1)
+		},
+	}
+	cfg.MaxSpeedKPH = runtime.EnvFloat("FRAUD_MAX_SPEED_KPH", fraud.DefaultMaxSpeedKPH)
+	if raw := os.Getenv("FRAUD_MAX_FARE_RATIO"); raw != "" {
+		if f, err := strconv.ParseFloat(raw, 64); err != nil || f <= 1 {
+			slog.Warn("Invalid FRAUD_MAX_FARE_RATIO, using default", "value", raw, "default", fraud.DefaultMaxFareRatio)
+		} else {
+			cfg.MaxFareRatio = f
+		}
+	}
+	cfg.Instance, _ = os.Hostname()
+	return cfg
+}
+
+func main() {
+	svc := runtime.Start("fraud-detector")
+	defer svc.Stop()
+	slog.Info("Starting fraud detector")
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+
+	// A new group starts from the latest events, as replaying the ride history
+	// would raise its alerts again under new IDs
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          groupID,
+		"auto.offset.reset": "latest",
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error { return consumer.Close() })
+	if err := consumer.SubscribeTopics([]string{topics.RideEvents, topics.FraudAlerts}, nil); err != nil {
+		logger.Fatal("Failed to subscribe", "error", err)
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		logger.Fatal("Failed to create producer", "error", err)
+	}
+	logger.OnShutdown("kafka producer", func(context.Context) error {
+		if n := producer.Flush(5000); n > 0 {
+			slog.Warn("Alerts left undelivered", "count", n)
+		}
+		producer.Close()
+		return nil
+	})
+	logger.Go("delivery reports", func() {
+		for e := range producer.Events() {
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				slog.Error("Delivery failed", "key", string(m.Key), "error", m.TopicPartition.Error)
+			}
+		}
+	})
+
+	svc.Serve(":2118")
+
+	ctx := svc.Context()
+
+	if err := fraud.NewDetector(configFromEnv(), producer, store).Run(ctx, consumer); err != nil {
+		logger.Fatal("Fraud detector stopped", "error", err)
+	}
+	slog.Info("Fraud detector stopped")
+}
@@ -0,0 +1,78 @@
+// Package fraud watches the ride stream for rides that look wrong, such as a
+// fare that does not match its distance, and raises alerts about them.
+package fraud
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/google/uuid"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// EventAlert is the type of every Alert, so alerts can be told from other
+// events on a shared topic.
+const EventAlert = "ALERT"
+
+// AlertKind is what an Alert is about.
+type AlertKind string
+
+const (
+	// KindFareMismatch is a completed ride charged a fare other than the one
+	// its distance, duration, and quoted surge come to.
+	KindFareMismatch AlertKind = "fare_mismatch"
+	// KindFareOutlier is a completed ride charged far more or far less than
+	// its distance comes to at any surge.
+	KindFareOutlier AlertKind = "fare_outlier"
+	// KindImpossibleSpeed is a driver whose location updates put them further
+	// apart than they could have driven in the time between.
+	KindImpossibleSpeed AlertKind = "impossible_speed"
+	// KindDuplicateTrip is a driver accepting a ride while still on another.
+	KindDuplicateTrip AlertKind = "duplicate_trip"
+)
+
+// Alert is a ride that looks wrong, sent to the fraud topic keyed by its
+// trip for someone to look into.
+type Alert struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"` // EventAlert
+	Kind       AlertKind `json:"kind"`
+	TripID     string    `json:"trip_id"`
+	DriverID   string    `json:"driver_id,omitempty"`
+	City       string    `json:"city,omitempty"`
+	DetectedAt time.Time `json:"detected_at"`
+	Detail     string    `json:"detail"` // what was found, for people to read
+	// Fare and Expected are the fare charged and the fare it should have been,
+	// for fare alerts.
+	Fare     *events.Money `json:"fare,omitempty"`
+	Expected *events.Money `json:"expected,omitempty"`
+	// Meta correlates the alert with its ride; its cause is the event that
+	// raised it.
+	Meta events.Meta `json:"meta,omitzero"`
+}
+
+// NewAlert returns an alert of kind about trip tripID, detected at at, with a
+// new ID.
+func NewAlert(kind AlertKind, tripID string, at time.Time, detail string) Alert {
+	return Alert{ID: uuid.NewString(), Type: EventAlert, Kind: kind, TripID: tripID, DetectedAt: at, Detail: detail}
+}
+
+// Message returns the alert as a message to topic, keyed by its trip, with
+// its meta as headers.
+func (a Alert) Message(topic string) (*kafka.Message, error) {
+	value, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(a.TripID),
+		Value:          value,
+	}
+	for k, v := range a.Meta.Headers() {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return msg, nil
+}
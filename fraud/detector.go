@@ -0,0 +1,304 @@
+package fraud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+var (
+	alertsRaised = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "fraud_alerts_raised_total",
+		Help: "Number of alerts the detector raised, by kind.",
+	}, []string{"kind"})
+
+	alertsStored = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "fraud_alerts_stored_total",
+		Help: "Number of alerts read from the alert topic and stored, by kind, whoever raised them.",
+	}, []string{"kind"})
+)
+
+// Defaults of Config.
+const (
+	DefaultRideTopic       = "ride-events"
+	DefaultAlertTopic      = "fraud-alerts"
+	DefaultMaxSpeedKPH     = 200.0
+	DefaultMaxTripDuration = 2 * time.Hour
+	DefaultMaxFareRatio    = 3.0
+)
+
+// Source and Sink are rideconsumer.Source and rideconsumer.Sink, which
+// *kafka.Consumer and *kafka.Producer satisfy. They are declared here because
+// rides_db imports this package for Alert, and rideconsumer's tests import
+// rides_db.
+type (
+	Source interface {
+		ReadMessage(timeout time.Duration) (*kafka.Message, error)
+	}
+	Sink interface {
+		Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error
+	}
+)
+
+// Store is where the detector keeps the alerts it reads; rides_db stores
+// them in fraud_alerts.
+type Store interface {
+	InsertFraudAlert(ctx context.Context, a Alert) error
+}
+
+// Config configures a Detector. Zero values take the defaults.
+type Config struct {
+	RideTopic  string
+	AlertTopic string
+	// MaxSpeedKPH is the fastest a driver can go between two location updates.
+	MaxSpeedKPH float64
+	// MaxTripDuration is how long a ride lasts at most. A driver who accepts a
+	// ride while on one accepted earlier than that is on two at once; one
+	// accepted longer ago is taken to have ended without the detector seeing it.
+	MaxTripDuration time.Duration
+	// ExpectedFare returns what a ride of distanceKM kilometers costs without
+	// surge. A completed ride charged more than MaxFareRatio times that, or
+	// less than that divided by MaxFareRatio, is an outlier. Nil skips the
+	// check.
+	ExpectedFare func(distanceKM float64) (events.Money, error)
+	MaxFareRatio float64
+	// Instance names this detector in the meta of the alerts it sends.
+	Instance string
+	// Now returns the current time; nil uses time.Now.
+	Now func() time.Time
+}
+
+func (c *Config) setDefaults() {
+	if c.RideTopic == "" {
+		c.RideTopic = DefaultRideTopic
+	}
+	if c.AlertTopic == "" {
+		c.AlertTopic = DefaultAlertTopic
+	}
+	if c.MaxSpeedKPH <= 0 {
+		c.MaxSpeedKPH = DefaultMaxSpeedKPH
+	}
+	if c.MaxTripDuration <= 0 {
+		c.MaxTripDuration = DefaultMaxTripDuration
+	}
+	if c.MaxFareRatio <= 1 {
+		c.MaxFareRatio = DefaultMaxFareRatio
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+// Detector watches the ride topic for rides that look wrong: drivers moving
+// faster than they could between location updates, drivers on two rides at
+// once, and fares far from what the ride's distance comes to. It sends an
+// alert for each to the alert topic, and stores every alert it reads from
+// that topic, its own and those of other services such as the pricer.
+type Detector struct {
+	cfg   Config
+	sink  Sink
+	store Store
+
+	mu      sync.Mutex
+	drivers map[string]*driver
+	// onTrip holds the driver of each ride they accepted, until it ends, as
+	// not every event of a ride names its driver
+	onTrip map[string]string
+	// flagged holds the rides already alerted on for speed, until they end,
+	// so a speeding driver raises one alert a ride rather than one an update
+	flagged map[string]bool
+}
+
+// driver is what the detector knows of a driver.
+type driver struct {
+	tripID     string // the ride the driver is on, if any
+	acceptedAt time.Time
+	location   *events.Coordinate // as of their last location update
+	locatedAt  time.Time
+}
+
+// NewDetector returns a Detector that sends its alerts to sink and stores
+// those it reads in store.
+func NewDetector(cfg Config, sink Sink, store Store) *Detector {
+	cfg.setDefaults()
+	return &Detector{cfg: cfg, sink: sink, store: store, drivers: make(map[string]*driver),
+		onTrip: make(map[string]string), flagged: make(map[string]bool)}
+}
+
+// Run handles messages from src, subscribed to the ride and the alert topic,
+// until ctx is cancelled.
+func (d *Detector) Run(ctx context.Context, src Source) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			slog.Error("Consumer error", "error", err)
+			continue
+		}
+		if err := d.Handle(ctx, msg); err != nil {
+			slog.Error("Failed to handle message", "topic", *msg.TopicPartition.Topic,
+				"key", string(msg.Key), "offset", msg.TopicPartition.Offset, "error", err)
+		}
+	}
+}
+
+// Handle processes one message from the ride or the alert topic. Messages
+// that cannot be decoded are logged and skipped; the error is from sending or
+// storing an alert.
+func (d *Detector) Handle(ctx context.Context, msg *kafka.Message) error {
+	switch topic := *msg.TopicPartition.Topic; topic {
+	case d.cfg.AlertTopic:
+		var a Alert
+		if err := json.Unmarshal(msg.Value, &a); err != nil || a.Type != EventAlert || a.ID == "" {
+			slog.Warn("Skipping undecodable alert", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		if err := d.store.InsertFraudAlert(ctx, a); err != nil {
+			return fmt.Errorf("fraud: store alert %s: %w", a.ID, err)
+		}
+		alertsStored.WithLabelValues(string(a.Kind)).Inc()
+		return nil
+	case d.cfg.RideTopic:
+		var e events.RideEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		var errs []error
+		for _, a := range d.Observe(e) {
+			errs = append(errs, d.publish(a))
+		}
+		return errors.Join(errs...)
+	default:
+		return fmt.Errorf("fraud: message from unexpected topic %q", topic)
+	}
+}
+
+// Observe applies a ride event and returns the alerts it raises.
+func (d *Detector) Observe(e events.RideEvent) []Alert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var alerts []Alert
+	switch e.Type {
+	case events.EventRideAccepted:
+		if e.DriverID == "" {
+			return nil
+		}
+		drv := d.driver(e.DriverID)
+		if drv.tripID != "" && drv.tripID != e.TripID && e.OccurredAt.Sub(drv.acceptedAt) < d.cfg.MaxTripDuration {
+			alerts = append(alerts, d.alert(KindDuplicateTrip, e,
+				fmt.Sprintf("accepted while on trip %s, accepted %s earlier", drv.tripID, e.OccurredAt.Sub(drv.acceptedAt).Round(time.Second))))
+		}
+		drv.tripID, drv.acceptedAt = e.TripID, e.OccurredAt
+		d.onTrip[e.TripID] = e.DriverID
+	case events.EventLocationUpdated:
+		p, ok := events.PayloadAs[events.LocationUpdatedPayload](e)
+		if !ok || e.DriverID == "" {
+			return nil
+		}
+		drv := d.driver(e.DriverID)
+		if drv.location != nil && !e.OccurredAt.After(drv.locatedAt) {
+			return nil // out of order; the later update stands
+		}
+		if drv.location != nil && !d.flagged[e.TripID] {
+			km := drv.location.DistanceKM(p.Location)
+			if kph := km / e.OccurredAt.Sub(drv.locatedAt).Hours(); kph > d.cfg.MaxSpeedKPH {
+				d.flagged[e.TripID] = true
+				alerts = append(alerts, d.alert(KindImpossibleSpeed, e,
+					fmt.Sprintf("moved %.2f km in %s, %.0f km/h", km, e.OccurredAt.Sub(drv.locatedAt).Round(time.Millisecond), kph)))
+			}
+		}
+		drv.location, drv.locatedAt = &p.Location, e.OccurredAt
+	case events.EventTripCompleted:
+		if a, ok := d.checkFare(e); ok {
+			alerts = append(alerts, a)
+		}
+		d.end(e)
+	case events.EventTripCancelled:
+		d.end(e)
+	}
+	for _, a := range alerts {
+		alertsRaised.WithLabelValues(string(a.Kind)).Inc()
+		slog.Warn("Fraud alert", "kind", a.Kind, "tripID", a.TripID, "driverID", a.DriverID, "detail", a.Detail)
+	}
+	return alerts
+}
+
+// checkFare returns an alert if a completed ride was charged far more or far
+// less than its distance comes to. d.mu is held.
+func (d *Detector) checkFare(e events.RideEvent) (Alert, bool) {
+	p, ok := events.PayloadAs[events.RideCompletedPayload](e)
+	if !ok || d.cfg.ExpectedFare == nil || p.DistanceKM <= 0 {
+		return Alert{}, false
+	}
+	expected, err := d.cfg.ExpectedFare(p.DistanceKM)
+	if err != nil || expected.Amount <= 0 {
+		return Alert{}, false
+	}
+	ratio := float64(p.Fare.Amount) / float64(expected.Amount)
+	if ratio <= d.cfg.MaxFareRatio && ratio >= 1/d.cfg.MaxFareRatio {
+		return Alert{}, false
+	}
+	a := d.alert(KindFareOutlier, e, fmt.Sprintf("charged %s for %.2f km, %.1f times the %s it comes to", p.Fare, p.DistanceKM, ratio, expected))
+	a.Fare, a.Expected = &p.Fare, &expected
+	return a, true
+}
+
+// end forgets the ride of e, freeing its driver. d.mu is held.
+func (d *Detector) end(e events.RideEvent) {
+	delete(d.flagged, e.TripID)
+	if drv, ok := d.drivers[d.onTrip[e.TripID]]; ok && drv.tripID == e.TripID {
+		drv.tripID = ""
+	}
+	delete(d.onTrip, e.TripID)
+}
+
+// driver returns what is known of driver id, adding them if new. d.mu is held.
+func (d *Detector) driver(id string) *driver {
+	drv, ok := d.drivers[id]
+	if !ok {
+		drv = &driver{}
+		d.drivers[id] = drv
+	}
+	return drv
+}
+
+// alert returns an alert of kind raised by e.
+func (d *Detector) alert(kind AlertKind, e events.RideEvent, detail string) Alert {
+	a := NewAlert(kind, e.TripID, d.cfg.Now(), detail)
+	a.DriverID, a.City = e.DriverID, e.City
+	correlation := e.Meta.CorrelationID
+	if correlation == "" {
+		correlation = e.TripID
+	}
+	a.Meta = events.Meta{CorrelationID: correlation, CausationID: e.ID, ProducerInstance: d.cfg.Instance}
+	return a
+}
+
+// publish sends an alert to the alert topic, where the detector reads it back
+// to store it.
+func (d *Detector) publish(a Alert) error {
+	msg, err := a.Message(d.cfg.AlertTopic)
+	if err != nil {
+		return err
+	}
+	return d.sink.Produce(msg, nil)
+}
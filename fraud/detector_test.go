@@ -0,0 +1,117 @@
+package fraud_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// flatFare is $2.50 and $1.00 per kilometer.
+func flatFare(distanceKM float64) (events.Money, error) {
+	return events.NewMoney(250, events.USD).Add(events.NewMoney(100, events.USD).Mul(distanceKM))
+}
+
+// alertStore keeps alerts by ID.
+type alertStore map[string]fraud.Alert
+
+func (s alertStore) InsertFraudAlert(ctx context.Context, a fraud.Alert) error {
+	if _, ok := s[a.ID]; !ok {
+		s[a.ID] = a
+	}
+	return nil
+}
+
+// kinds returns the kinds of alerts.
+func kinds(alerts []fraud.Alert) []fraud.AlertKind {
+	var out []fraud.AlertKind
+	for _, a := range alerts {
+		out = append(out, a.Kind)
+	}
+	return out
+}
+
+func TestDetector_Observe(t *testing.T) {
+	d := fraud.NewDetector(fraud.Config{ExpectedFare: flatFare, Instance: "fraud-1", Now: func() time.Time { return now }}, nil, nil)
+	at := func(sec int) events.Option { return events.WithTime(now.Add(time.Duration(sec) * time.Second)) }
+	located := func(tripID string, sec int, lat float64) events.RideEvent {
+		return events.NewLocationUpdated(tripID, events.StateInProgress, events.Coordinate{Lat: lat, Lng: -73.98}, 0, 40,
+			at(sec), events.WithDriver("driver-1"))
+	}
+
+	tests := []struct {
+		name string
+		e    events.RideEvent
+		want []fraud.AlertKind
+	}{
+		{"accepted", events.NewRideAccepted("trip-1", "driver-1", at(0)), nil},
+		{"first location", located("trip-1", 10, 40.750), nil},
+		// About 110m in 10s is 40 km/h
+		{"driving", located("trip-1", 20, 40.751), nil},
+		// About 11km in 10s is 4000 km/h
+		{"teleported", located("trip-1", 30, 40.851), []fraud.AlertKind{fraud.KindImpossibleSpeed}},
+		{"teleported again", located("trip-1", 40, 40.751), nil},
+		{"late update", located("trip-1", 35, 41.5), nil},
+		{"second ride at once", events.NewRideAccepted("trip-2", "driver-1", at(50)), []fraud.AlertKind{fraud.KindDuplicateTrip}},
+		{"fair fare", events.NewTripCompleted("trip-1", 10, events.NewMoney(1250, events.USD), at(60), events.WithDriver("driver-1")), nil},
+		{"cancelled without a driver", events.NewTripCancelled("trip-2", events.ActorPassenger, events.ReasonChangedPlans, at(60)), nil},
+		{"next ride", events.NewRideAccepted("trip-3", "driver-1", at(70)), nil},
+		{"fare ten times over", events.NewTripCompleted("trip-3", 10, events.NewMoney(12500, events.USD), at(80), events.WithDriver("driver-1")),
+			[]fraud.AlertKind{fraud.KindFareOutlier}},
+		{"free ride", events.NewTripCompleted("trip-4", 10, events.NewMoney(100, events.USD), at(90)), []fraud.AlertKind{fraud.KindFareOutlier}},
+	}
+	for _, tt := range tests {
+		got := d.Observe(tt.e)
+		if len(got) != len(tt.want) || (len(got) == 1 && got[0].Kind != tt.want[0]) {
+			t.Errorf("%s: alerts %v, want %v", tt.name, kinds(got), tt.want)
+			continue
+		}
+		for _, a := range got {
+			if a.Type != fraud.EventAlert || a.ID == "" || a.TripID != tt.e.TripID || a.Meta.CausationID != tt.e.ID || !a.DetectedAt.Equal(now) {
+				t.Errorf("%s: alert %+v", tt.name, a)
+			}
+			if a.Kind == fraud.KindFareOutlier && (a.Fare == nil || a.Expected == nil || *a.Expected != events.NewMoney(1250, events.USD)) {
+				t.Errorf("%s: fares %v and %v", tt.name, a.Fare, a.Expected)
+			}
+		}
+	}
+}
+
+func TestDetector_SendsAndStoresAlerts(t *testing.T) {
+	broker := ridetest.NewBroker()
+	store := alertStore{}
+	d := fraud.NewDetector(fraud.Config{ExpectedFare: flatFare}, broker, store)
+	ctx := context.Background()
+
+	completed := events.NewTripCompleted("trip-1", 1, events.NewMoney(10000, events.USD), events.WithTime(now))
+	if err := d.Handle(ctx, ridetest.Message(t, fraud.DefaultRideTopic, completed)); err != nil {
+		t.Fatal(err)
+	}
+	sent := broker.Messages(fraud.DefaultAlertTopic)
+	if len(sent) != 1 || string(sent[0].Key) != "trip-1" {
+		t.Fatalf("sent %d alerts, want one keyed by the trip", len(sent))
+	}
+
+	// The alert is stored when read back, along with those of other services,
+	// and once however often it is delivered
+	mismatch := fraud.NewAlert(fraud.KindFareMismatch, "trip-2", now, "charged 20.00 USD, want 10.00 USD")
+	for _, msg := range []*kafka.Message{sent[0], ridetest.Message(t, fraud.DefaultAlertTopic, mismatch), sent[0]} {
+		if err := d.Handle(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Messages on the alert topic that are not alerts are skipped
+	if err := d.Handle(ctx, ridetest.Message(t, fraud.DefaultAlertTopic, completed)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store) != 2 || store[mismatch.ID].Kind != fraud.KindFareMismatch {
+		t.Errorf("stored %v, want the outlier and the mismatch", store)
+	}
+}
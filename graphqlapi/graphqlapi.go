@@ -0,0 +1,49 @@
+// Package graphqlapi serves the rides read model as a GraphQL API, so
+// dashboards can select exactly the trips, events, drivers, and aggregates they
+// need in one request. The schema is in schema.graphql.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxPageSize caps the first argument of paginated fields.
+const maxPageSize = 500
+
+// Store is the part of rides_db.RideStore the API reads from.
+type Store interface {
+	GetTrip(ctx context.Context, tripID string) (aggregation.Trip, error)
+	ListTrips(ctx context.Context, f rides_db.TripFilter) ([]aggregation.Trip, error)
+	GetTripEvents(ctx context.Context, tripID string) ([]events.RideEvent, error)
+	GetRide(ctx context.Context, tripID string) (rides_db.Ride, error)
+	ListActiveRides(ctx context.Context, limit, offset int) ([]rides_db.Ride, error)
+	ListRidesByDriver(ctx context.Context, driverID string, tr rides_db.TimeRange) ([]rides_db.Ride, error)
+	RevenueByDay(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.DailyRevenue, error)
+	CancellationRateByHour(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.HourlyCancellationRate, error)
+	AvgFareByZone(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.ZoneFare, error)
+	TopDriversByTrips(ctx context.Context, tr rides_db.TimeRange, limit int) ([]rides_db.DriverTrips, error)
+}
+
+// NewSchema parses the schema with resolvers reading from store. Queries are
+// limited in depth so nested trip/driver cycles cannot fan out without bound.
+func NewSchema(store Store) *graphql.Schema {
+	return graphql.MustParseSchema(schemaSDL, &queryResolver{store: store}, graphql.MaxDepth(8))
+}
+
+// NewHandler serves the schema over HTTP, taking POST requests with a JSON
+// body of query, operationName, and variables.
+func NewHandler(store Store) http.Handler {
+	return &relay.Handler{Schema: NewSchema(store)}
+}
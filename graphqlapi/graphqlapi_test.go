@@ -0,0 +1,138 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+func newTestStore(t *testing.T) *rides_db.SQLiteStore {
+	t.Helper()
+	store, err := rides_db.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	ctx := context.Background()
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		tripID := fmt.Sprintf("trip-%d", i+1)
+		at := base.Add(time.Duration(i) * time.Hour)
+		evts := []events.RideEvent{
+			{ID: tripID + "-1", TripID: tripID, Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: at, PassengerID: "rider-1",
+				Payload: events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St"}},
+			{ID: tripID + "-2", TripID: tripID, Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: at.Add(time.Minute), DriverID: "driver-1",
+				Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+			{ID: tripID + "-3", TripID: tripID, Type: events.EventTripCompleted, State: events.StateCompleted, OccurredAt: at.Add(20 * time.Minute),
+				Payload: events.RideCompletedPayload{EndTime: at.Add(20 * time.Minute), DistanceKM: 6, Fare: events.NewMoney(1000, events.USD)}},
+		}
+		for _, e := range evts {
+			if _, err := store.InsertRideEvent(ctx, e); err != nil {
+				t.Fatalf("InsertRideEvent failed: %v", err)
+			}
+			if err := store.UpsertRideState(ctx, e); err != nil {
+				t.Fatalf("UpsertRideState failed: %v", err)
+			}
+		}
+		if err := store.RefreshTrip(ctx, tripID); err != nil {
+			t.Fatalf("RefreshTrip failed: %v", err)
+		}
+	}
+	return store
+}
+
+func TestSchema_Queries(t *testing.T) {
+	schema := NewSchema(newTestStore(t))
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "trip with event payloads",
+			query: `{ trip(id: "trip-1") { fareUsd cancelledAt events { type payload { ... on RideRequested { pickupLocation } ... on RideCompleted { fareUsd } } } } }`,
+			want:  `{"trip":{"fareUsd":10,"cancelledAt":null,"events":[{"type":"REQUESTED","payload":{"pickupLocation":"Main St"}},{"type":"ACCEPTED","payload":{}},{"type":"COMPLETED","payload":{"fareUsd":10}}]}}`,
+		},
+		{
+			name:  "missing trip is null",
+			query: `{ trip(id: "nope") { id } }`,
+			want:  `{"trip":null}`,
+		},
+		{
+			name:  "paginated trips",
+			query: `{ trips(filter: {driverId: "driver-1"}, first: 2) { items { id } pageInfo { hasNextPage } } }`,
+			want:  `{"trips":{"items":[{"id":"trip-3"},{"id":"trip-2"}],"pageInfo":{"hasNextPage":true}}}`,
+		},
+		{
+			name:  "last page",
+			query: `{ trips(first: 2, offset: 2) { items { id } pageInfo { offset hasNextPage } } }`,
+			want:  `{"trips":{"items":[{"id":"trip-1"}],"pageInfo":{"offset":2,"hasNextPage":false}}}`,
+		},
+		{
+			name:  "driver earnings and aggregates",
+			query: `{ driver(id: "driver-1") { earnings { completedRides earningsUsd } } topDrivers(limit: 1) { driver { id } trips } avgFareByZone { zone avgFareUsd } }`,
+			want:  `{"driver":{"earnings":{"completedRides":3,"earningsUsd":30}},"topDrivers":[{"driver":{"id":"driver-1"},"trips":3}],"avgFareByZone":[{"zone":"Main St","avgFareUsd":10}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := schema.Exec(context.Background(), tt.query, "", nil)
+			if len(resp.Errors) > 0 {
+				t.Fatalf("query failed: %v", resp.Errors)
+			}
+			if string(resp.Data) != tt.want {
+				t.Errorf("got  %s\nwant %s", resp.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema_RejectsOversizedPages(t *testing.T) {
+	resp := NewSchema(newTestStore(t)).Exec(context.Background(), `{ activeRides(first: 10000) { items { id } } }`, "", nil)
+	if len(resp.Errors) == 0 {
+		t.Error("expected an error for first above the page size cap")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(newTestStore(t)))
+	defer srv.Close()
+
+	body, _ := json.Marshal(map[string]any{
+		"query":     `query($id: ID!) { ride(id: $id) { state fareUsd } }`,
+		"variables": map[string]any{"id": "trip-2"},
+	})
+	resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Data struct {
+			Ride struct {
+				State   string
+				FareUSD float64
+			}
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decoding failed: %v", err)
+	}
+	if out.Data.Ride.State != "COMPLETED" || out.Data.Ride.FareUSD != 10 {
+		t.Errorf("unexpected response %+v", out)
+	}
+}
@@ -0,0 +1,499 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+type queryResolver struct {
+	store Store
+}
+
+// timeRangeInput is the TimeRange input type.
+type timeRangeInput struct {
+	From *graphql.Time
+	To   *graphql.Time
+}
+
+func (in *timeRangeInput) toTimeRange() rides_db.TimeRange {
+	var tr rides_db.TimeRange
+	if in == nil {
+		return tr
+	}
+	if in.From != nil {
+		tr.From = in.From.Time
+	}
+	if in.To != nil {
+		tr.To = in.To.Time
+	}
+	return tr
+}
+
+// tripFilterInput is the TripFilter input type.
+type tripFilterInput struct {
+	From     *graphql.Time
+	To       *graphql.Time
+	State    *string
+	DriverID *graphql.ID
+}
+
+func (q *queryResolver) Trip(ctx context.Context, args struct{ ID graphql.ID }) (*tripResolver, error) {
+	t, err := q.store.GetTrip(ctx, string(args.ID))
+	if errors.Is(err, rides_db.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tripResolver{store: q.store, t: t}, nil
+}
+
+func (q *queryResolver) Trips(ctx context.Context, args struct {
+	Filter *tripFilterInput
+	First  int32
+	Offset int32
+}) (*tripPageResolver, error) {
+	var f rides_db.TripFilter
+	if in := args.Filter; in != nil {
+		tr := (&timeRangeInput{From: in.From, To: in.To}).toTimeRange()
+		f.From, f.To = tr.From, tr.To
+		if in.State != nil {
+			state, err := events.ParseRideState(*in.State)
+			if err != nil {
+				return nil, err
+			}
+			f.State = state
+		}
+		if in.DriverID != nil {
+			f.DriverID = string(*in.DriverID)
+		}
+	}
+	return listTrips(ctx, q.store, f, args.First, args.Offset)
+}
+
+func (q *queryResolver) Ride(ctx context.Context, args struct{ ID graphql.ID }) (*rideResolver, error) {
+	r, err := q.store.GetRide(ctx, string(args.ID))
+	if errors.Is(err, rides_db.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rideResolver{store: q.store, r: r}, nil
+}
+
+func (q *queryResolver) ActiveRides(ctx context.Context, args struct{ First, Offset int32 }) (*ridePageResolver, error) {
+	if err := checkPage(args.First, args.Offset); err != nil {
+		return nil, err
+	}
+	// One extra row tells whether there is a next page
+	rides, err := q.store.ListActiveRides(ctx, int(args.First)+1, int(args.Offset))
+	if err != nil {
+		return nil, err
+	}
+	page := newPageInfo(args.First, args.Offset, len(rides))
+	if page.hasNext {
+		rides = rides[:args.First]
+	}
+	return &ridePageResolver{items: rideResolvers(q.store, rides), page: page}, nil
+}
+
+func (q *queryResolver) Driver(args struct{ ID graphql.ID }) *driverResolver {
+	return &driverResolver{store: q.store, id: string(args.ID)}
+}
+
+func (q *queryResolver) RevenueByDay(ctx context.Context, args struct{ Range *timeRangeInput }) ([]*dailyRevenueResolver, error) {
+	days, err := q.store.RevenueByDay(ctx, args.Range.toTimeRange())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*dailyRevenueResolver, len(days))
+	for i, d := range days {
+		out[i] = &dailyRevenueResolver{d}
+	}
+	return out, nil
+}
+
+func (q *queryResolver) CancellationRateByHour(ctx context.Context, args struct{ Range *timeRangeInput }) ([]*cancellationRateResolver, error) {
+	hours, err := q.store.CancellationRateByHour(ctx, args.Range.toTimeRange())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*cancellationRateResolver, len(hours))
+	for i, h := range hours {
+		out[i] = &cancellationRateResolver{h}
+	}
+	return out, nil
+}
+
+func (q *queryResolver) AvgFareByZone(ctx context.Context, args struct{ Range *timeRangeInput }) ([]*zoneFareResolver, error) {
+	zones, err := q.store.AvgFareByZone(ctx, args.Range.toTimeRange())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*zoneFareResolver, len(zones))
+	for i, z := range zones {
+		out[i] = &zoneFareResolver{z}
+	}
+	return out, nil
+}
+
+func (q *queryResolver) TopDrivers(ctx context.Context, args struct {
+	Range *timeRangeInput
+	Limit int32
+}) ([]*driverTripsResolver, error) {
+	if args.Limit < 1 || args.Limit > maxPageSize {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+	}
+	drivers, err := q.store.TopDriversByTrips(ctx, args.Range.toTimeRange(), int(args.Limit))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*driverTripsResolver, len(drivers))
+	for i, d := range drivers {
+		out[i] = &driverTripsResolver{store: q.store, d: d}
+	}
+	return out, nil
+}
+
+// listTrips runs f for one page of trips.
+func listTrips(ctx context.Context, store Store, f rides_db.TripFilter, first, offset int32) (*tripPageResolver, error) {
+	if err := checkPage(first, offset); err != nil {
+		return nil, err
+	}
+	f.Limit, f.Offset = int(first)+1, int(offset)
+	trips, err := store.ListTrips(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	page := newPageInfo(first, offset, len(trips))
+	if page.hasNext {
+		trips = trips[:first]
+	}
+	items := make([]*tripResolver, len(trips))
+	for i, t := range trips {
+		items[i] = &tripResolver{store: store, t: t}
+	}
+	return &tripPageResolver{items: items, page: page}, nil
+}
+
+func checkPage(first, offset int32) error {
+	if first < 1 || first > maxPageSize {
+		return fmt.Errorf("first must be between 1 and %d", maxPageSize)
+	}
+	if offset < 0 {
+		return errors.New("offset must not be negative")
+	}
+	return nil
+}
+
+type pageInfoResolver struct {
+	offset, limit int32
+	hasNext       bool
+}
+
+// newPageInfo describes a page fetched with one row more than first.
+func newPageInfo(first, offset int32, fetched int) *pageInfoResolver {
+	return &pageInfoResolver{offset: offset, limit: first, hasNext: fetched > int(first)}
+}
+
+func (p *pageInfoResolver) Offset() int32     { return p.offset }
+func (p *pageInfoResolver) Limit() int32      { return p.limit }
+func (p *pageInfoResolver) HasNextPage() bool { return p.hasNext }
+
+type tripPageResolver struct {
+	items []*tripResolver
+	page  *pageInfoResolver
+}
+
+func (p *tripPageResolver) Items() []*tripResolver      { return p.items }
+func (p *tripPageResolver) PageInfo() *pageInfoResolver { return p.page }
+
+type ridePageResolver struct {
+	items []*rideResolver
+	page  *pageInfoResolver
+}
+
+func (p *ridePageResolver) Items() []*rideResolver      { return p.items }
+func (p *ridePageResolver) PageInfo() *pageInfoResolver { return p.page }
+
+type tripResolver struct {
+	store Store
+	t     aggregation.Trip
+}
+
+func (r *tripResolver) ID() graphql.ID             { return graphql.ID(r.t.TripID) }
+func (r *tripResolver) PassengerID() *string       { return optionalString(r.t.PassengerID) }
+func (r *tripResolver) DriverID() *string          { return optionalString(r.t.DriverID) }
+func (r *tripResolver) FinalState() string         { return string(r.t.FinalState) }
+func (r *tripResolver) PickupLocation() *string    { return optionalString(r.t.PickupLocation) }
+func (r *tripResolver) DropoffLocation() *string   { return optionalString(r.t.DropoffLocation) }
+func (r *tripResolver) RequestedAt() *graphql.Time { return optionalTime(r.t.RequestedAt) }
+func (r *tripResolver) AcceptedAt() *graphql.Time  { return optionalTime(r.t.AcceptedAt) }
+func (r *tripResolver) StartedAt() *graphql.Time   { return optionalTime(r.t.StartedAt) }
+func (r *tripResolver) CompletedAt() *graphql.Time { return optionalTime(r.t.CompletedAt) }
+func (r *tripResolver) CancelledAt() *graphql.Time { return optionalTime(r.t.CancelledAt) }
+func (r *tripResolver) DistanceKM() float64        { return r.t.DistanceKM }
+func (r *tripResolver) FareUSD() float64           { return r.t.FareUSD }
+func (r *tripResolver) CancelledBy() *string       { return optionalString(r.t.CancelledBy) }
+func (r *tripResolver) CancelReason() *string      { return optionalString(r.t.CancelReason) }
+
+func (r *tripResolver) Events(ctx context.Context) ([]*eventResolver, error) {
+	return tripEvents(ctx, r.store, r.t.TripID)
+}
+
+func (r *tripResolver) Driver() *driverResolver {
+	if r.t.DriverID == "" {
+		return nil
+	}
+	return &driverResolver{store: r.store, id: r.t.DriverID}
+}
+
+type rideResolver struct {
+	store Store
+	r     rides_db.Ride
+}
+
+func rideResolvers(store Store, rides []rides_db.Ride) []*rideResolver {
+	out := make([]*rideResolver, len(rides))
+	for i, r := range rides {
+		out[i] = &rideResolver{store: store, r: r}
+	}
+	return out
+}
+
+func (r *rideResolver) ID() graphql.ID               { return graphql.ID(r.r.TripID) }
+func (r *rideResolver) State() string                { return string(r.r.State) }
+func (r *rideResolver) LastEventType() string        { return string(r.r.LastEventType) }
+func (r *rideResolver) LastEventAt() graphql.Time    { return graphql.Time{Time: r.r.LastEventAt} }
+func (r *rideResolver) DriverID() *string            { return optionalString(r.r.DriverID) }
+func (r *rideResolver) PassengerID() *string         { return optionalString(r.r.PassengerID) }
+func (r *rideResolver) RequestedAt() *graphql.Time   { return optionalTime(r.r.RequestedAt) }
+func (r *rideResolver) AcceptedAt() *graphql.Time    { return optionalTime(r.r.AcceptedAt) }
+func (r *rideResolver) StartedAt() *graphql.Time     { return optionalTime(r.r.StartedAt) }
+func (r *rideResolver) EndedAt() *graphql.Time       { return optionalTime(r.r.EndedAt) }
+func (r *rideResolver) FareUSD() float64             { return r.r.FareUSD }
+func (r *rideResolver) Pickup() *coordinateResolver  { return newCoordinate(r.r.Pickup) }
+func (r *rideResolver) Dropoff() *coordinateResolver { return newCoordinate(r.r.Dropoff) }
+
+func (r *rideResolver) Events(ctx context.Context) ([]*eventResolver, error) {
+	return tripEvents(ctx, r.store, r.r.TripID)
+}
+
+func (r *rideResolver) Trip(ctx context.Context) (*tripResolver, error) {
+	q := &queryResolver{store: r.store}
+	return q.Trip(ctx, struct{ ID graphql.ID }{graphql.ID(r.r.TripID)})
+}
+
+type coordinateResolver struct {
+	c events.Coordinate
+}
+
+func newCoordinate(c *events.Coordinate) *coordinateResolver {
+	if c == nil {
+		return nil
+	}
+	return &coordinateResolver{*c}
+}
+
+func (c *coordinateResolver) Lat() float64 { return c.c.Lat }
+func (c *coordinateResolver) Lng() float64 { return c.c.Lng }
+
+func tripEvents(ctx context.Context, store Store, tripID string) ([]*eventResolver, error) {
+	evts, err := store.GetTripEvents(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*eventResolver, len(evts))
+	for i, e := range evts {
+		out[i] = &eventResolver{e}
+	}
+	return out, nil
+}
+
+type eventResolver struct {
+	e events.RideEvent
+}
+
+func (r *eventResolver) ID() graphql.ID       { return graphql.ID(r.e.ID) }
+func (r *eventResolver) TripID() graphql.ID   { return graphql.ID(r.e.TripID) }
+func (r *eventResolver) Type() string         { return string(r.e.Type) }
+func (r *eventResolver) Time() graphql.Time   { return graphql.Time{Time: r.e.OccurredAt} }
+func (r *eventResolver) State() string        { return string(r.e.State) }
+func (r *eventResolver) DriverID() *string    { return optionalString(r.e.DriverID) }
+func (r *eventResolver) PassengerID() *string { return optionalString(r.e.PassengerID) }
+
+func (r *eventResolver) Payload() *payloadResolver {
+	if r.e.Payload == nil {
+		return nil
+	}
+	return &payloadResolver{r.e.Payload}
+}
+
+// payloadResolver resolves the Payload union; graphql-go picks the member
+// through the To<Type> methods.
+type payloadResolver struct {
+	p events.RideEventPayload
+}
+
+func (r *payloadResolver) ToRideRequested() (*requestedResolver, bool) {
+	p, ok := r.p.(events.RideRequestedPayload)
+	return &requestedResolver{p}, ok
+}
+
+func (r *payloadResolver) ToRideAccepted() (*acceptedResolver, bool) {
+	p, ok := r.p.(events.RideAcceptedPayload)
+	return &acceptedResolver{p}, ok
+}
+
+func (r *payloadResolver) ToRideStarted() (*startedResolver, bool) {
+	p, ok := r.p.(events.RideStartedPayload)
+	return &startedResolver{p}, ok
+}
+
+func (r *payloadResolver) ToRideCompleted() (*completedResolver, bool) {
+	p, ok := r.p.(events.RideCompletedPayload)
+	return &completedResolver{p}, ok
+}
+
+func (r *payloadResolver) ToRideCancelled() (*cancelledResolver, bool) {
+	p, ok := r.p.(events.RideCancelledPayload)
+	return &cancelledResolver{p}, ok
+}
+
+type requestedResolver struct{ p events.RideRequestedPayload }
+
+func (r *requestedResolver) Passenger() string            { return r.p.Passenger }
+func (r *requestedResolver) PickupLocation() string       { return r.p.PickupLocation }
+func (r *requestedResolver) DropoffLocation() string      { return r.p.DropoffLocation }
+func (r *requestedResolver) Pickup() *coordinateResolver  { return newCoordinate(r.p.Pickup) }
+func (r *requestedResolver) Dropoff() *coordinateResolver { return newCoordinate(r.p.Dropoff) }
+
+type acceptedResolver struct{ p events.RideAcceptedPayload }
+
+func (r *acceptedResolver) DriverID() string { return r.p.DriverID }
+
+type startedResolver struct{ p events.RideStartedPayload }
+
+func (r *startedResolver) StartTime() graphql.Time { return graphql.Time{Time: r.p.StartTime} }
+
+type completedResolver struct{ p events.RideCompletedPayload }
+
+func (r *completedResolver) EndTime() graphql.Time { return graphql.Time{Time: r.p.EndTime} }
+func (r *completedResolver) DistanceKM() float64   { return r.p.DistanceKM }
+func (r *completedResolver) FareUSD() float64      { return r.p.Fare.Float64() }
+
+type cancelledResolver struct{ p events.RideCancelledPayload }
+
+func (r *cancelledResolver) CancelledBy() string { return string(r.p.CancelledBy) }
+func (r *cancelledResolver) Reason() *string     { return optionalString(string(r.p.Reason)) }
+
+type driverResolver struct {
+	store Store
+	id    string
+}
+
+func (r *driverResolver) ID() graphql.ID { return graphql.ID(r.id) }
+
+func (r *driverResolver) Trips(ctx context.Context, args struct {
+	Range  *timeRangeInput
+	State  *string
+	First  int32
+	Offset int32
+}) (*tripPageResolver, error) {
+	tr := args.Range.toTimeRange()
+	f := rides_db.TripFilter{From: tr.From, To: tr.To, DriverID: r.id}
+	if args.State != nil {
+		state, err := events.ParseRideState(*args.State)
+		if err != nil {
+			return nil, err
+		}
+		f.State = state
+	}
+	return listTrips(ctx, r.store, f, args.First, args.Offset)
+}
+
+func (r *driverResolver) Rides(ctx context.Context, args struct{ Range *timeRangeInput }) ([]*rideResolver, error) {
+	rides, err := r.store.ListRidesByDriver(ctx, r.id, args.Range.toTimeRange())
+	if err != nil {
+		return nil, err
+	}
+	return rideResolvers(r.store, rides), nil
+}
+
+func (r *driverResolver) Earnings(ctx context.Context, args struct{ Range *timeRangeInput }) (*earningsResolver, error) {
+	rides, err := r.store.ListRidesByDriver(ctx, r.id, args.Range.toTimeRange())
+	if err != nil {
+		return nil, err
+	}
+	e := &earningsResolver{}
+	for _, ride := range rides {
+		if ride.State == events.StateCompleted {
+			e.completed++
+			e.usd += ride.FareUSD
+		}
+	}
+	return e, nil
+}
+
+type earningsResolver struct {
+	completed int32
+	usd       float64
+}
+
+func (r *earningsResolver) CompletedRides() int32 { return r.completed }
+func (r *earningsResolver) EarningsUSD() float64  { return r.usd }
+
+type dailyRevenueResolver struct{ d rides_db.DailyRevenue }
+
+func (r *dailyRevenueResolver) Day() graphql.Time   { return graphql.Time{Time: r.d.Day} }
+func (r *dailyRevenueResolver) Trips() int32        { return int32(r.d.Trips) }
+func (r *dailyRevenueResolver) RevenueUSD() float64 { return r.d.RevenueUSD }
+
+type cancellationRateResolver struct {
+	h rides_db.HourlyCancellationRate
+}
+
+func (r *cancellationRateResolver) Hour() graphql.Time { return graphql.Time{Time: r.h.Hour} }
+func (r *cancellationRateResolver) Requested() int32   { return int32(r.h.Requested) }
+func (r *cancellationRateResolver) Cancelled() int32   { return int32(r.h.Cancelled) }
+func (r *cancellationRateResolver) Rate() float64      { return r.h.Rate }
+
+type zoneFareResolver struct{ z rides_db.ZoneFare }
+
+func (r *zoneFareResolver) Zone() string        { return r.z.Zone }
+func (r *zoneFareResolver) Trips() int32        { return int32(r.z.Trips) }
+func (r *zoneFareResolver) AvgFareUSD() float64 { return r.z.AvgFareUSD }
+
+type driverTripsResolver struct {
+	store Store
+	d     rides_db.DriverTrips
+}
+
+func (r *driverTripsResolver) Driver() *driverResolver {
+	return &driverResolver{store: r.store, id: r.d.DriverID}
+}
+func (r *driverTripsResolver) Trips() int32        { return int32(r.d.Trips) }
+func (r *driverTripsResolver) RevenueUSD() float64 { return r.d.RevenueUSD }
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalTime(t time.Time) *graphql.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: t}
+}
@@ -0,0 +1,65 @@
+package grpcapi
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	pb "github.com/pedeveaux/kafkarideshare/proto/rideshare/v1"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+func tripToProto(t aggregation.Trip) *pb.Trip {
+	return &pb.Trip{
+		TripId:          t.TripID,
+		PassengerId:     t.PassengerID,
+		DriverId:        t.DriverID,
+		FinalState:      string(t.FinalState),
+		PickupLocation:  t.PickupLocation,
+		DropoffLocation: t.DropoffLocation,
+		RequestedAt:     timestamp(t.RequestedAt),
+		AcceptedAt:      timestamp(t.AcceptedAt),
+		StartedAt:       timestamp(t.StartedAt),
+		CompletedAt:     timestamp(t.CompletedAt),
+		CancelledAt:     timestamp(t.CancelledAt),
+		DistanceKm:      t.DistanceKM,
+		FareUsd:         t.FareUSD,
+		CancelledBy:     t.CancelledBy,
+		CancelReason:    t.CancelReason,
+	}
+}
+
+func rideToProto(r rides_db.Ride) *pb.Ride {
+	return &pb.Ride{
+		TripId:        r.TripID,
+		State:         string(r.State),
+		LastEventType: string(r.LastEventType),
+		LastEventAt:   timestamp(r.LastEventAt),
+		DriverId:      r.DriverID,
+		PassengerId:   r.PassengerID,
+		RequestedAt:   timestamp(r.RequestedAt),
+		AcceptedAt:    timestamp(r.AcceptedAt),
+		StartedAt:     timestamp(r.StartedAt),
+		EndedAt:       timestamp(r.EndedAt),
+		FareUsd:       r.FareUSD,
+		Pickup:        coordinate(r.Pickup),
+		Dropoff:       coordinate(r.Dropoff),
+	}
+}
+
+// timestamp leaves zero times unset.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func coordinate(c *events.Coordinate) *pb.Coordinate {
+	if c == nil {
+		return nil
+	}
+	return &pb.Coordinate{Lat: c.Lat, Lng: c.Lng}
+}
@@ -0,0 +1,225 @@
+// Package grpcapi serves the rides read model over gRPC, using the
+// rideshare.v1 protobuf definitions shared with the event encoding.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	pb "github.com/pedeveaux/kafkarideshare/proto/rideshare/v1"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// Store is the part of rides_db.RideStore the server reads from.
+type Store interface {
+	GetTrip(ctx context.Context, tripID string) (aggregation.Trip, error)
+	ListTrips(ctx context.Context, f rides_db.TripFilter) ([]aggregation.Trip, error)
+	GetTripEvents(ctx context.Context, tripID string) ([]events.RideEvent, error)
+	GetRide(ctx context.Context, tripID string) (rides_db.Ride, error)
+	ListActiveRides(ctx context.Context, limit, offset int) ([]rides_db.Ride, error)
+}
+
+// Config tunes the server. Zero values fall back to the defaults.
+type Config struct {
+	// PollInterval is how often WatchTrip checks the store for new events.
+	PollInterval time.Duration
+	// DefaultLimit is the page size of list calls that do not set a limit.
+	DefaultLimit int
+}
+
+func (c *Config) setDefaults() {
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	if c.DefaultLimit <= 0 {
+		c.DefaultLimit = 100
+	}
+}
+
+// Server implements pb.RideQueryServiceServer.
+type Server struct {
+	pb.UnimplementedRideQueryServiceServer
+	store Store
+	cfg   Config
+}
+
+var _ pb.RideQueryServiceServer = (*Server)(nil)
+
+// NewServer returns a Server reading from store.
+func NewServer(store Store, cfg Config) *Server {
+	cfg.setDefaults()
+	return &Server{store: store, cfg: cfg}
+}
+
+// Register adds the query service to s.
+func (s *Server) Register(g *grpc.Server) {
+	pb.RegisterRideQueryServiceServer(g, s)
+}
+
+// GetTrip returns a finished trip.
+func (s *Server) GetTrip(ctx context.Context, req *pb.GetTripRequest) (*pb.Trip, error) {
+	if req.GetTripId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "trip_id is required")
+	}
+	t, err := s.store.GetTrip(ctx, req.GetTripId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return tripToProto(t), nil
+}
+
+// ListTrips returns finished trips, newest request first.
+func (s *Server) ListTrips(ctx context.Context, req *pb.ListTripsRequest) (*pb.ListTripsResponse, error) {
+	limit, offset, err := s.page(req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+	var state events.RideState
+	if req.GetState() != "" {
+		if state, err = events.ParseRideState(req.GetState()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	f := rides_db.TripFilter{
+		State:    state,
+		DriverID: req.GetDriverId(),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	if req.GetFrom() != nil {
+		f.From = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		f.To = req.GetTo().AsTime()
+	}
+	trips, err := s.store.ListTrips(ctx, f)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.ListTripsResponse{Trips: make([]*pb.Trip, len(trips))}
+	for i, t := range trips {
+		resp.Trips[i] = tripToProto(t)
+	}
+	return resp, nil
+}
+
+// GetTripEvents returns the stored events of a trip in event-time order.
+func (s *Server) GetTripEvents(ctx context.Context, req *pb.GetTripEventsRequest) (*pb.GetTripEventsResponse, error) {
+	if req.GetTripId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "trip_id is required")
+	}
+	evts, err := s.store.GetTripEvents(ctx, req.GetTripId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if len(evts) == 0 {
+		return nil, status.Error(codes.NotFound, "trip not found")
+	}
+	resp := &pb.GetTripEventsResponse{Events: make([]*pb.RideEvent, len(evts))}
+	for i, e := range evts {
+		if resp.Events[i], err = events.ToProto(e); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	return resp, nil
+}
+
+// GetRide returns the current state of a ride.
+func (s *Server) GetRide(ctx context.Context, req *pb.GetRideRequest) (*pb.Ride, error) {
+	if req.GetTripId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "trip_id is required")
+	}
+	r, err := s.store.GetRide(ctx, req.GetTripId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return rideToProto(r), nil
+}
+
+// ListActiveRides returns rides that have not ended.
+func (s *Server) ListActiveRides(ctx context.Context, req *pb.ListActiveRidesRequest) (*pb.ListActiveRidesResponse, error) {
+	limit, offset, err := s.page(req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+	rides, err := s.store.ListActiveRides(ctx, limit, offset)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.ListActiveRidesResponse{Rides: make([]*pb.Ride, len(rides))}
+	for i, r := range rides {
+		resp.Rides[i] = rideToProto(r)
+	}
+	return resp, nil
+}
+
+// WatchTrip sends the stored events of a trip, then polls for new ones until
+// the trip ends or the client goes away. Events are sent at most once each,
+// in the order they are found.
+func (s *Server) WatchTrip(req *pb.WatchTripRequest, stream grpc.ServerStreamingServer[pb.RideEvent]) error {
+	if req.GetTripId() == "" {
+		return status.Error(codes.InvalidArgument, "trip_id is required")
+	}
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	sent := make(map[string]bool)
+	for {
+		evts, err := s.store.GetTripEvents(ctx, req.GetTripId())
+		if err != nil {
+			return toStatus(err)
+		}
+		for _, e := range evts {
+			if sent[e.ID] {
+				continue
+			}
+			m, err := events.ToProto(e)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := stream.Send(m); err != nil {
+				return err
+			}
+			sent[e.ID] = true
+			if e.State == events.StateCompleted || e.State == events.StateCancelled {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) page(limit, offset int32) (int, int, error) {
+	if limit < 0 || offset < 0 {
+		return 0, 0, status.Error(codes.InvalidArgument, "limit and offset must not be negative")
+	}
+	if limit == 0 {
+		return s.cfg.DefaultLimit, int(offset), nil
+	}
+	return int(limit), int(offset), nil
+}
+
+// toStatus maps store errors to gRPC status codes.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, rides_db.ErrNotFound):
+		return status.Error(codes.NotFound, "not found")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
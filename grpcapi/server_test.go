@@ -0,0 +1,142 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	pb "github.com/pedeveaux/kafkarideshare/proto/rideshare/v1"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+func tripEvents(base time.Time) []events.RideEvent {
+	return []events.RideEvent{
+		{ID: "e1", TripID: "trip-1", Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: base, PassengerID: "rider-1",
+			Payload: events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St"}},
+		{ID: "e2", TripID: "trip-1", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: base.Add(time.Minute), DriverID: "driver-1",
+			Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+		{ID: "e3", TripID: "trip-1", Type: events.EventTripStarted, State: events.StateInProgress, OccurredAt: base.Add(5 * time.Minute),
+			Payload: events.RideStartedPayload{StartTime: base.Add(5 * time.Minute)}},
+		{ID: "e4", TripID: "trip-1", Type: events.EventTripCompleted, State: events.StateCompleted, OccurredAt: base.Add(20 * time.Minute),
+			Payload: events.RideCompletedPayload{EndTime: base.Add(20 * time.Minute), DistanceKM: 6, Fare: events.NewMoney(850, events.USD)}},
+	}
+}
+
+func persist(t *testing.T, store rides_db.RideStore, evts ...events.RideEvent) {
+	t.Helper()
+	ctx := context.Background()
+	for _, e := range evts {
+		if _, err := store.InsertRideEvent(ctx, e); err != nil {
+			t.Fatalf("InsertRideEvent failed: %v", err)
+		}
+		if err := store.UpsertRideState(ctx, e); err != nil {
+			t.Fatalf("UpsertRideState failed: %v", err)
+		}
+	}
+}
+
+func newTestClient(t *testing.T) (pb.RideQueryServiceClient, *rides_db.SQLiteStore) {
+	t.Helper()
+	store, err := rides_db.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	NewServer(store, Config{PollInterval: 10 * time.Millisecond}).Register(g)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewRideQueryServiceClient(conn), store
+}
+
+func TestServer_Queries(t *testing.T) {
+	client, store := newTestClient(t)
+	ctx := context.Background()
+	persist(t, store, tripEvents(time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC))...)
+	if err := store.RefreshTrip(ctx, "trip-1"); err != nil {
+		t.Fatalf("RefreshTrip failed: %v", err)
+	}
+
+	trip, err := client.GetTrip(ctx, &pb.GetTripRequest{TripId: "trip-1"})
+	if err != nil || trip.GetFareUsd() != 8.5 || trip.GetCancelledAt() != nil {
+		t.Errorf("GetTrip = %v, %v", trip, err)
+	}
+
+	evts, err := client.GetTripEvents(ctx, &pb.GetTripEventsRequest{TripId: "trip-1"})
+	if err != nil || len(evts.GetEvents()) != 4 || evts.GetEvents()[0].GetRequested().GetPickupLocation() != "Main St" {
+		t.Errorf("GetTripEvents = %v, %v", evts, err)
+	}
+
+	trips, err := client.ListTrips(ctx, &pb.ListTripsRequest{DriverId: "driver-1"})
+	if err != nil || len(trips.GetTrips()) != 1 {
+		t.Errorf("ListTrips = %v, %v", trips, err)
+	}
+
+	_, err = client.GetRide(ctx, &pb.GetRideRequest{TripId: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+	_, err = client.ListActiveRides(ctx, &pb.ListActiveRidesRequest{Limit: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+	_, err = client.ListTrips(ctx, &pb.ListTripsRequest{State: "DONE"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an unknown state, got %v", err)
+	}
+}
+
+func TestServer_WatchTripStreamsUntilTheTripEnds(t *testing.T) {
+	client, store := newTestClient(t)
+	evts := tripEvents(time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC))
+	persist(t, store, evts[:2]...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchTrip(ctx, &pb.WatchTripRequest{TripId: "trip-1"})
+	if err != nil {
+		t.Fatalf("WatchTrip failed: %v", err)
+	}
+
+	var got []string
+	for {
+		e, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed after %v: %v", got, err)
+		}
+		got = append(got, e.GetId())
+		// The rest of the trip arrives while the client is watching
+		if len(got) == 2 {
+			persist(t, store, evts[2:]...)
+		}
+	}
+	if len(got) != 4 || got[3] != "e4" {
+		t.Errorf("expected all four events in order, got %v", got)
+	}
+}
@@ -0,0 +1,74 @@
+// Command heatmap-builder counts demand and supply in each pricing zone over
+// time. It buckets the rides requested with a pickup in each zone, and the
+// free drivers that reported from it, and keeps the counts in heatmap_cells
+// for the API and the dashboard; see the heatmap package.
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/heatmap"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+const (
+	defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID        = "heatmap-builder"
+)
+
+// configFromEnv reads the builder settings: HEATMAP_BUCKET, the width of a
+// cell's time bucket, and HEATMAP_INTERVAL, how often changed cells are
+// written. Invalid values are logged and left to the defaults.
+func configFromEnv() heatmap.Config {
+	return heatmap.Config{
+		RideTopic:   topics.RideEvents,
+		DriverTopic: topics.DriverEvents,
+		Bucket:      runtime.EnvDuration("HEATMAP_BUCKET", heatmap.DefaultBucket),
+		Interval:    runtime.EnvDuration("HEATMAP_INTERVAL", heatmap.DefaultInterval),
+	}
+}
+
+func main() {
+	svc := runtime.Start("heatmap-builder")
+	defer svc.Stop()
+	slog.Info("Starting heatmap builder")
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+
+	// A new group starts from the latest events, as older ones fall in
+	// buckets past their grace
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          groupID,
+		"auto.offset.reset": "latest",
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error { return consumer.Close() })
+	if err := consumer.SubscribeTopics([]string{topics.RideEvents, topics.DriverEvents}, nil); err != nil {
+		logger.Fatal("Failed to subscribe", "error", err)
+	}
+
+	svc.Serve(":2121")
+
+	ctx := svc.Context()
+
+	if err := heatmap.New(configFromEnv(), store).Run(ctx, consumer); err != nil {
+		logger.Fatal("Heatmap builder stopped", "error", err)
+	}
+	slog.Info("Heatmap builder stopped")
+}
@@ -0,0 +1,300 @@
+// Package heatmap counts demand and supply in each pricing zone over time.
+// A Builder buckets the rides requested with a pickup in each zone, and the
+// free drivers that reported from it, into fixed time buckets, and writes
+// each bucket's counts to a Store as they change; rides_db keeps them in
+// heatmap_cells, where the API and the dashboard read them. Zones are those
+// of pricing.ZoneOf, the cells of the surge grid.
+package heatmap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+var (
+	cellsWritten = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "heatmap_cells_written_total",
+		Help: "Number of heatmap cells written to the store, counting each rewrite of a cell.",
+	})
+
+	lateEvents = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "heatmap_late_events_total",
+		Help: "Number of events skipped because their bucket had already been written for good.",
+	})
+)
+
+// Defaults of Config.
+const (
+	DefaultRideTopic   = "ride-events"
+	DefaultDriverTopic = "driver-events"
+	DefaultBucket      = 5 * time.Minute
+	DefaultInterval    = 30 * time.Second
+)
+
+// Store is where the builder writes its cells; rides_db keeps them in
+// heatmap_cells.
+type Store interface {
+	UpsertHeatmapCell(ctx context.Context, c rides_db.HeatmapCell) error
+}
+
+// Config configures a Builder. Zero values take the defaults.
+type Config struct {
+	RideTopic   string
+	DriverTopic string
+	// Bucket is the width of a cell's time bucket.
+	Bucket time.Duration
+	// Grace is how long after a bucket ends its events are still counted;
+	// later ones are skipped. It defaults to Bucket.
+	Grace time.Duration
+	// Interval is how often changed cells are written.
+	Interval time.Duration
+	// Now returns the current time; nil uses time.Now.
+	Now func() time.Time
+}
+
+func (c *Config) setDefaults() {
+	if c.RideTopic == "" {
+		c.RideTopic = DefaultRideTopic
+	}
+	if c.DriverTopic == "" {
+		c.DriverTopic = DefaultDriverTopic
+	}
+	if c.Bucket <= 0 {
+		c.Bucket = DefaultBucket
+	}
+	if c.Grace <= 0 {
+		c.Grace = c.Bucket
+	}
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+// Builder reads ride requests from the ride topic and driver events from the
+// driver topic, and every Interval writes the cells whose counts changed.
+type Builder struct {
+	cfg   Config
+	store Store
+
+	mu    sync.Mutex
+	cells map[cellKey]*cell
+	// available holds whether each driver was free as of their last report,
+	// for relocations, which do not say
+	available map[string]bool
+	next      time.Time // when changed cells are next written
+}
+
+type cellKey struct {
+	zone  events.ZoneID
+	start time.Time
+}
+
+// cell holds the trips and drivers counted in a cell, so a redelivered event
+// or a driver's every heartbeat counts once.
+type cell struct {
+	trips   map[string]bool
+	drivers map[string]bool
+	dirty   bool // changed since it was last written
+}
+
+// New returns a Builder that writes its cells to store.
+func New(cfg Config, store Store) *Builder {
+	cfg.setDefaults()
+	return &Builder{cfg: cfg, store: store, cells: make(map[cellKey]*cell), available: make(map[string]bool)}
+}
+
+// Run handles messages from src, subscribed to the ride and the driver topic,
+// and writes the changed cells every Interval, until ctx is cancelled. The
+// cells changed since the last write are written on the way out.
+func (b *Builder) Run(ctx context.Context, src rideconsumer.Source) error {
+	for {
+		select {
+		case <-ctx.Done():
+			// ctx is done, but the last counts are still worth keeping
+			if err := b.Flush(context.WithoutCancel(ctx)); err != nil {
+				slog.Error("Failed to write heatmap cells", "error", err)
+			}
+			return nil
+		default:
+		}
+		if now := b.cfg.Now(); !now.Before(b.next) {
+			b.next = now.Add(b.cfg.Interval)
+			if err := b.Flush(ctx); err != nil {
+				slog.Error("Failed to write heatmap cells", "error", err)
+			}
+		}
+
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			slog.Error("Consumer error", "error", err)
+			continue
+		}
+		if err := b.Handle(msg); err != nil {
+			slog.Error("Failed to handle message", "topic", *msg.TopicPartition.Topic,
+				"key", string(msg.Key), "offset", msg.TopicPartition.Offset, "error", err)
+		}
+	}
+}
+
+// Handle processes one message from the ride or the driver topic. Messages
+// that cannot be decoded are logged and skipped.
+func (b *Builder) Handle(msg *kafka.Message) error {
+	switch topic := *msg.TopicPartition.Topic; topic {
+	case b.cfg.DriverTopic:
+		var e events.DriverEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable driver event", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		b.observeDriver(e)
+		return nil
+	case b.cfg.RideTopic:
+		var e events.RideEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		b.observeRide(e)
+		return nil
+	default:
+		return fmt.Errorf("heatmap: message from unexpected topic %q", topic)
+	}
+}
+
+// observeRide counts a request in the cell of its pickup. Requests without a
+// pickup coordinate have no zone, and other events are ignored.
+func (b *Builder) observeRide(e events.RideEvent) {
+	p, ok := events.PayloadAs[events.RideRequestedPayload](e)
+	if !ok || p.Pickup == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.cell(pricing.ZoneOf(*p.Pickup), e.OccurredAt); c != nil && !c.trips[e.TripID] {
+		c.trips[e.TripID], c.dirty = true, true
+	}
+}
+
+// observeDriver counts a driver in the cell they reported from, if they were
+// free.
+func (b *Builder) observeDriver(e events.DriverEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var (
+		at        events.Coordinate
+		available bool
+	)
+	switch p := e.Payload.(type) {
+	case events.ShiftStartedPayload:
+		at, available = p.Location, true
+	case events.HeartbeatPayload:
+		at, available = p.Location, p.Available
+	case events.RelocatedPayload:
+		at, available = p.To, b.available[e.DriverID]
+	case events.ShiftEndedPayload:
+		delete(b.available, e.DriverID)
+		return
+	default:
+		return
+	}
+	b.available[e.DriverID] = available
+	if !available {
+		return
+	}
+	if c := b.cell(pricing.ZoneOf(at), e.OccurredAt); c != nil && !c.drivers[e.DriverID] {
+		c.drivers[e.DriverID], c.dirty = true, true
+	}
+}
+
+// cell returns the cell of zone at t, adding it if new, or nil if its bucket
+// is past its grace. b.mu is held.
+func (b *Builder) cell(zone events.ZoneID, t time.Time) *cell {
+	start := t.UTC().Truncate(b.cfg.Bucket)
+	if b.closed(start, b.cfg.Now()) {
+		lateEvents.Inc()
+		return nil
+	}
+	key := cellKey{zone, start}
+	c, ok := b.cells[key]
+	if !ok {
+		c = &cell{trips: make(map[string]bool), drivers: make(map[string]bool)}
+		b.cells[key] = c
+	}
+	return c
+}
+
+// closed reports whether the bucket starting at start is past its grace at
+// now.
+func (b *Builder) closed(start, now time.Time) bool {
+	return start.Add(b.cfg.Bucket + b.cfg.Grace).Before(now)
+}
+
+// Cells returns the cells held in memory, by start and zone. Flush forgets
+// those past their grace.
+func (b *Builder) Cells() []rides_db.HeatmapCell {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]rides_db.HeatmapCell, 0, len(b.cells))
+	for key, c := range b.cells {
+		out = append(out, b.toCell(key, c))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return out[i].ZoneID < out[j].ZoneID
+	})
+	return out
+}
+
+// toCell returns the counts of a cell. b.mu is held.
+func (b *Builder) toCell(key cellKey, c *cell) rides_db.HeatmapCell {
+	return rides_db.HeatmapCell{ZoneID: string(key.zone), Start: key.start, End: key.start.Add(b.cfg.Bucket),
+		Pickups: int64(len(c.trips)), Drivers: int64(len(c.drivers))}
+}
+
+// Flush writes the cells changed since they were last written, and forgets
+// those past their grace once written. A cell that fails to write is kept to
+// be written again.
+func (b *Builder) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.cfg.Now()
+	var errs []error
+	for key, c := range b.cells {
+		if c.dirty {
+			if err := b.store.UpsertHeatmapCell(ctx, b.toCell(key, c)); err != nil {
+				errs = append(errs, fmt.Errorf("heatmap: write cell %s at %s: %w", key.zone, key.start.Format(time.RFC3339), err))
+				continue
+			}
+			c.dirty = false
+			cellsWritten.Inc()
+		}
+		if b.closed(key.start, now) {
+			delete(b.cells, key)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,107 @@
+package heatmap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestBuilder_CountsPickupsAndFreeDrivers(t *testing.T) {
+	clock := now
+	store := ridetest.NewStore()
+	b := New(Config{Bucket: 5 * time.Minute, Now: func() time.Time { return clock }}, store)
+	midtown := events.Coordinate{Lat: 40.755, Lng: -73.985}
+	downtown := events.Coordinate{Lat: 40.705, Lng: -74.005}
+	handle := func(topic string, v any) {
+		t.Helper()
+		if err := b.Handle(ridetest.Message(t, topic, v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	request := func(tripID string, at time.Time) {
+		t.Helper()
+		handle(DefaultRideTopic, events.NewRideRequested(tripID, "rider-1", "A St", "B St",
+			events.WithTime(at), events.WithCoordinates(&midtown, &downtown)))
+	}
+	driverEvent := func(driverID string, typ events.DriverEventType, at time.Time, payload events.DriverEventPayload) {
+		t.Helper()
+		handle(DefaultDriverTopic, events.DriverEvent{ID: driverID + "-" + at.Format(time.RFC3339), DriverID: driverID,
+			Type: typ, OccurredAt: at, Payload: payload})
+	}
+
+	// Two requests in midtown, one of them redelivered, and one in the next
+	// bucket; two free drivers there, reporting twice, and a busy one
+	request("trip-1", now)
+	request("trip-2", now.Add(time.Minute))
+	request("trip-2", now.Add(time.Minute))
+	request("trip-3", now.Add(6*time.Minute))
+	driverEvent("driver-1", events.EventHeartbeat, now, events.HeartbeatPayload{Location: midtown, Available: true})
+	driverEvent("driver-1", events.EventHeartbeat, now.Add(time.Minute), events.HeartbeatPayload{Location: midtown, Available: true})
+	driverEvent("driver-2", events.EventShiftStarted, now, events.ShiftStartedPayload{Location: midtown})
+	driverEvent("driver-3", events.EventHeartbeat, now, events.HeartbeatPayload{Location: midtown, Available: false})
+	// A free driver moves downtown
+	driverEvent("driver-2", events.EventRelocated, now.Add(2*time.Minute), events.RelocatedPayload{From: midtown, To: downtown})
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	cells, err := store.ListHeatmapCells(context.Background(), rides_db.TimeRange{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mid, down := string(pricing.ZoneOf(midtown)), string(pricing.ZoneOf(downtown))
+	want := []rides_db.HeatmapCell{
+		{ZoneID: down, Start: now, End: now.Add(5 * time.Minute), Drivers: 1},
+		{ZoneID: mid, Start: now, End: now.Add(5 * time.Minute), Pickups: 2, Drivers: 2},
+		{ZoneID: mid, Start: now.Add(5 * time.Minute), End: now.Add(10 * time.Minute), Pickups: 1},
+	}
+	if len(cells) != len(want) {
+		t.Fatalf("cells %+v, want %+v", cells, want)
+	}
+	for i := range want {
+		if cells[i] != want[i] {
+			t.Errorf("cell %d is %+v, want %+v", i, cells[i], want[i])
+		}
+	}
+
+	// Once the first bucket is past its grace it is forgotten, and its late
+	// events skipped
+	clock = now.Add(11 * time.Minute)
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	request("trip-4", now.Add(2*time.Minute))
+	if got := b.Cells(); len(got) != 1 || !got[0].Start.Equal(now.Add(5*time.Minute)) {
+		t.Errorf("cells held %+v, want only the second bucket", got)
+	}
+}
+
+func TestBuilder_KeepsCellsThatFailToWrite(t *testing.T) {
+	store := ridetest.NewStore()
+	b := New(Config{Now: func() time.Time { return now }}, store)
+	pickup := events.Coordinate{Lat: 40.755, Lng: -73.985}
+	if err := b.Handle(ridetest.Message(t, DefaultRideTopic, events.NewRideRequested("trip-1", "rider-1", "A St", "B St",
+		events.WithTime(now), events.WithCoordinates(&pickup, &pickup)))); err != nil {
+		t.Fatal(err)
+	}
+
+	store.FailOn("UpsertHeatmapCell", errors.New("boom"))
+	if err := b.Flush(context.Background()); err == nil {
+		t.Fatal("Flush succeeded with the store failing")
+	}
+	store.FailOn("UpsertHeatmapCell", nil)
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if cells, _ := store.ListHeatmapCells(context.Background(), rides_db.TimeRange{}); len(cells) != 1 || cells[0].Pickups != 1 {
+		t.Errorf("cells %+v, want the request written on the second flush", cells)
+	}
+}
@@ -0,0 +1,352 @@
+// Package httpapi serves the rides read model over HTTP as JSON. It only reads
+// from the store; everything it returns was written by the consumer.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// Store is the part of rides_db.RideStore the API reads from.
+type Store interface {
+	GetTrip(ctx context.Context, tripID string) (aggregation.Trip, error)
+	ListTrips(ctx context.Context, f rides_db.TripFilter) ([]aggregation.Trip, error)
+	GetTripEvents(ctx context.Context, tripID string) ([]events.RideEvent, error)
+	GetRide(ctx context.Context, tripID string) (rides_db.Ride, error)
+	ListActiveRides(ctx context.Context, limit, offset int) ([]rides_db.Ride, error)
+	ListRidesByDriver(ctx context.Context, driverID string, tr rides_db.TimeRange) ([]rides_db.Ride, error)
+	RevenueByDay(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.DailyRevenue, error)
+	RevenueBySurge(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.SurgeRevenue, error)
+	ListHeatmapCells(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.HeatmapCell, error)
+	Health(ctx context.Context) error
+}
+
+var requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "ride_api_request_duration_seconds",
+	Help:    "Time taken to answer read API requests, by route and status code.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"route", "code"})
+
+// NewHandler returns the API routes backed by store:
+//
+//	GET /trips                  finished trips, filtered by from, to, state, driver_id, limit, offset
+//	GET /trips/{id}             one finished trip
+//	GET /trips/{id}/events      the stored events of a trip in order
+//	GET /rides/active           rides that have not ended, paged by limit and offset
+//	GET /rides/{id}             the current state of a ride
+//	GET /drivers/{id}/earnings  completed rides and fares of a driver between from and to
+//	GET /metrics/daily          trips and revenue per day between from and to
+//	GET /metrics/surge          trips and revenue per surge multiplier between from and to
+//	GET /heatmap                pickups and free drivers per zone and time bucket starting between from and to
+//	GET /healthz                200 when the database answers, 503 otherwise
+//
+// from and to accept RFC 3339 timestamps or dates such as 2025-01-31.
+func NewHandler(store Store) http.Handler {
+	h := &handler{store: store}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /trips", h.listTrips)
+	mux.HandleFunc("GET /trips/{id}", h.getTrip)
+	mux.HandleFunc("GET /trips/{id}/events", h.getTripEvents)
+	mux.HandleFunc("GET /rides/active", h.activeRides)
+	mux.HandleFunc("GET /rides/{id}", h.getRide)
+	mux.HandleFunc("GET /drivers/{id}/earnings", h.driverEarnings)
+	mux.HandleFunc("GET /metrics/daily", h.dailyMetrics)
+	mux.HandleFunc("GET /metrics/surge", h.surgeMetrics)
+	mux.HandleFunc("GET /heatmap", h.heatmap)
+	mux.HandleFunc("GET /healthz", h.healthz)
+	return instrument(mux)
+}
+
+type handler struct {
+	store Store
+}
+
+func (h *handler) getTrip(w http.ResponseWriter, r *http.Request) {
+	t, err := h.store.GetTrip(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTrip(t))
+}
+
+func (h *handler) listTrips(w http.ResponseWriter, r *http.Request) {
+	tr, err := timeRange(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	limit, offset, err := page(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	state, err := parseState(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	trips, err := h.store.ListTrips(r.Context(), rides_db.TripFilter{
+		From:     tr.From,
+		To:       tr.To,
+		State:    state,
+		DriverID: r.URL.Query().Get("driver_id"),
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	out := make([]Trip, len(trips))
+	for i, t := range trips {
+		out[i] = newTrip(t)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *handler) getTripEvents(w http.ResponseWriter, r *http.Request) {
+	evts, err := h.store.GetTripEvents(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(evts) == 0 {
+		writeError(w, r, rides_db.ErrNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, evts)
+}
+
+func (h *handler) activeRides(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := page(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	rides, err := h.store.ListActiveRides(r.Context(), limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newRides(rides))
+}
+
+func (h *handler) getRide(w http.ResponseWriter, r *http.Request) {
+	ride, err := h.store.GetRide(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newRide(ride))
+}
+
+func (h *handler) driverEarnings(w http.ResponseWriter, r *http.Request) {
+	tr, err := timeRange(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	driverID := r.PathValue("id")
+	rides, err := h.store.ListRidesByDriver(r.Context(), driverID, tr)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, earnings(driverID, tr, rides))
+}
+
+func (h *handler) dailyMetrics(w http.ResponseWriter, r *http.Request) {
+	tr, err := timeRange(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	days, err := h.store.RevenueByDay(r.Context(), tr)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	out := make([]DailyMetrics, len(days))
+	for i, d := range days {
+		out[i] = DailyMetrics{Day: d.Day.Format(time.DateOnly), Trips: d.Trips, RevenueUSD: d.RevenueUSD}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *handler) surgeMetrics(w http.ResponseWriter, r *http.Request) {
+	tr, err := timeRange(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	buckets, err := h.store.RevenueBySurge(r.Context(), tr)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	out := make([]SurgeMetrics, len(buckets))
+	for i, b := range buckets {
+		out[i] = SurgeMetrics{Multiplier: b.Multiplier, Trips: b.Trips, RevenueUSD: b.RevenueUSD}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *handler) heatmap(w http.ResponseWriter, r *http.Request) {
+	tr, err := timeRange(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	cells, err := h.store.ListHeatmapCells(r.Context(), tr)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	out := make([]HeatmapCell, len(cells))
+	for i, c := range cells {
+		out[i] = HeatmapCell{Zone: c.ZoneID, Start: c.Start, End: c.End, Pickups: c.Pickups, Drivers: c.Drivers}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *handler) healthz(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Health(r.Context()); err != nil {
+		slog.Warn("Database health check failed", "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// errBadRequest wraps query parameter errors so they are answered with 400.
+type errBadRequest struct{ err error }
+
+func (e errBadRequest) Error() string { return e.err.Error() }
+
+// timeRange reads the from and to query parameters.
+func timeRange(r *http.Request) (rides_db.TimeRange, error) {
+	var tr rides_db.TimeRange
+	var err error
+	if tr.From, err = parseTime(r, "from"); err != nil {
+		return tr, err
+	}
+	tr.To, err = parseTime(r, "to")
+	return tr, err
+}
+
+func parseTime(r *http.Request, key string) (time.Time, error) {
+	raw := r.URL.Query().Get(key)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, errBadRequest{errors.New(key + " must be an RFC 3339 timestamp or a date")}
+	}
+	return t, nil
+}
+
+// parseState reads the state query parameter, which may be missing.
+func parseState(r *http.Request) (events.RideState, error) {
+	raw := r.URL.Query().Get("state")
+	if raw == "" {
+		return "", nil
+	}
+	state, err := events.ParseRideState(raw)
+	if err != nil {
+		return "", errBadRequest{errors.New("state must be a ride state such as COMPLETED")}
+	}
+	return state, nil
+}
+
+// defaultLimit is the page size when the limit parameter is missing or zero.
+const defaultLimit = 100
+
+// page reads the limit and offset query parameters.
+func page(r *http.Request) (limit, offset int, err error) {
+	if limit, err = parseInt(r, "limit"); err != nil {
+		return 0, 0, err
+	}
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	offset, err = parseInt(r, "offset")
+	return limit, offset, err
+}
+
+func parseInt(r *http.Request, key string) (int, error) {
+	raw := r.URL.Query().Get(key)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, errBadRequest{errors.New(key + " must be a non-negative integer")}
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write API response", "error", err)
+	}
+}
+
+// writeError maps err to a status code. Store errors are logged and reported
+// without detail.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var bad errBadRequest
+	switch {
+	case errors.As(err, &bad):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": bad.Error()})
+	case errors.Is(err, rides_db.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	default:
+		slog.Error("API request failed", "path", r.URL.Path, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	}
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// instrument records the duration of every request against its route pattern,
+// so trip IDs do not end up as label values.
+func instrument(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		_, route := mux.Handler(r)
+		mux.ServeHTTP(rec, r)
+		if route == "" {
+			route = "unmatched"
+		}
+		requestDuration.WithLabelValues(route, strconv.Itoa(rec.code)).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,146 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	store, err := rides_db.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	ctx := context.Background()
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	evts := []events.RideEvent{
+		{ID: "e1", TripID: "trip-1", Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: base, PassengerID: "rider-1",
+			Payload: events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St"}},
+		{ID: "e2", TripID: "trip-1", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: base.Add(time.Minute), DriverID: "driver-1",
+			Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+		{ID: "e3", TripID: "trip-1", Type: events.EventTripStarted, State: events.StateInProgress, OccurredAt: base.Add(5 * time.Minute),
+			Payload: events.RideStartedPayload{StartTime: base.Add(5 * time.Minute)}},
+		{ID: "e4", TripID: "trip-1", Type: events.EventTripCompleted, State: events.StateCompleted, OccurredAt: base.Add(20 * time.Minute),
+			Payload: events.RideCompletedPayload{EndTime: base.Add(20 * time.Minute), DistanceKM: 6, Fare: events.NewMoney(850, events.USD)}},
+		{ID: "e5", TripID: "trip-2", Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: base.Add(time.Hour), PassengerID: "rider-2",
+			Payload: events.RideRequestedPayload{Passenger: "rider-2", PickupLocation: "Oak St", DropoffLocation: "Pine St"}},
+	}
+	for _, e := range evts {
+		if _, err := store.InsertRideEvent(ctx, e); err != nil {
+			t.Fatalf("InsertRideEvent failed: %v", err)
+		}
+		if err := store.UpsertRideState(ctx, e); err != nil {
+			t.Fatalf("UpsertRideState failed: %v", err)
+		}
+	}
+	if err := store.RefreshTrip(ctx, "trip-1"); err != nil {
+		t.Fatalf("RefreshTrip failed: %v", err)
+	}
+	if err := store.UpsertZone(ctx, rides_db.Zone{ID: "Main St", UpdatedAt: base.Add(-time.Hour)}); err != nil {
+		t.Fatalf("UpsertZone failed: %v", err)
+	}
+	if err := store.UpsertSurgeMultiplier(ctx, rides_db.SurgeMultiplier{ZoneID: "Main St", EffectiveFrom: base.Add(-time.Hour), Multiplier: 1.5}); err != nil {
+		t.Fatalf("UpsertSurgeMultiplier failed: %v", err)
+	}
+
+	cell := rides_db.HeatmapCell{ZoneID: "40.74,-74.00", Start: base, End: base.Add(5 * time.Minute), Pickups: 3, Drivers: 1}
+	if err := store.UpsertHeatmapCell(ctx, cell); err != nil {
+		t.Fatalf("UpsertHeatmapCell failed: %v", err)
+	}
+
+	srv := httptest.NewServer(NewHandler(store))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, srv *httptest.Server, path string, out any) int {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decoding %s failed: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestHandler_Routes(t *testing.T) {
+	srv := newTestServer(t)
+
+	var trip Trip
+	if code := get(t, srv, "/trips/trip-1", &trip); code != http.StatusOK || trip.FareUSD != 8.5 || trip.PickupLocation != "Main St" {
+		t.Errorf("GET /trips/trip-1 = %d %+v", code, trip)
+	}
+
+	var evts []map[string]any
+	if code := get(t, srv, "/trips/trip-1/events", &evts); code != http.StatusOK || len(evts) != 4 || evts[0]["event_type"] != string(events.EventRideRequested) {
+		t.Errorf("GET /trips/trip-1/events = %d %v", code, evts)
+	}
+
+	var active []Ride
+	if code := get(t, srv, "/rides/active", &active); code != http.StatusOK || len(active) != 1 || active[0].TripID != "trip-2" {
+		t.Errorf("GET /rides/active = %d %+v", code, active)
+	}
+
+	var earned Earnings
+	if code := get(t, srv, "/drivers/driver-1/earnings?from=2025-01-01", &earned); code != http.StatusOK || earned.CompletedRides != 1 || earned.EarningsUSD != 8.5 {
+		t.Errorf("GET earnings = %d %+v", code, earned)
+	}
+
+	var daily []DailyMetrics
+	if code := get(t, srv, "/metrics/daily?from=2025-01-01T00:00:00Z&to=2025-01-02", &daily); code != http.StatusOK || len(daily) != 1 || daily[0].Day != "2025-01-01" || daily[0].Trips != 1 {
+		t.Errorf("GET /metrics/daily = %d %+v", code, daily)
+	}
+
+	var surge []SurgeMetrics
+	if code := get(t, srv, "/metrics/surge", &surge); code != http.StatusOK || len(surge) != 1 || surge[0].Multiplier != 1.5 || surge[0].RevenueUSD != 8.5 {
+		t.Errorf("GET /metrics/surge = %d %+v", code, surge)
+	}
+
+	var cells []HeatmapCell
+	if code := get(t, srv, "/heatmap?from=2025-01-01", &cells); code != http.StatusOK || len(cells) != 1 || cells[0].Zone != "40.74,-74.00" || cells[0].Pickups != 3 {
+		t.Errorf("GET /heatmap = %d %+v", code, cells)
+	}
+	if code := get(t, srv, "/heatmap?from=2025-01-02", &cells); code != http.StatusOK || len(cells) != 0 {
+		t.Errorf("GET /heatmap from the next day = %d %+v", code, cells)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/trips/missing", http.StatusNotFound},
+		{"/trips/missing/events", http.StatusNotFound},
+		{"/rides/missing", http.StatusNotFound},
+		{"/trips?limit=-1", http.StatusBadRequest},
+		{"/trips?state=DONE", http.StatusBadRequest},
+		{"/metrics/daily?from=yesterday", http.StatusBadRequest},
+		{"/nowhere", http.StatusNotFound},
+		{"/healthz", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := get(t, srv, tt.path, nil); code != tt.code {
+			t.Errorf("GET %s = %d, want %d", tt.path, code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package httpapi
+
+import (
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// Trip is the JSON form of a finished trip. Timestamps that never happened are omitted.
+type Trip struct {
+	TripID          string           `json:"trip_id"`
+	PassengerID     string           `json:"passenger_id,omitempty"`
+	DriverID        string           `json:"driver_id,omitempty"`
+	FinalState      events.RideState `json:"final_state"`
+	PickupLocation  string           `json:"pickup_location,omitempty"`
+	DropoffLocation string           `json:"dropoff_location,omitempty"`
+	RequestedAt     *time.Time       `json:"requested_at,omitempty"`
+	AcceptedAt      *time.Time       `json:"accepted_at,omitempty"`
+	StartedAt       *time.Time       `json:"started_at,omitempty"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+	CancelledAt     *time.Time       `json:"cancelled_at,omitempty"`
+	DistanceKM      float64          `json:"distance_km"`
+	FareUSD         float64          `json:"fare_usd"`
+	CancelledBy     string           `json:"cancelled_by,omitempty"`
+	CancelReason    string           `json:"cancel_reason,omitempty"`
+}
+
+func newTrip(t aggregation.Trip) Trip {
+	return Trip{
+		TripID:          t.TripID,
+		PassengerID:     t.PassengerID,
+		DriverID:        t.DriverID,
+		FinalState:      t.FinalState,
+		PickupLocation:  t.PickupLocation,
+		DropoffLocation: t.DropoffLocation,
+		RequestedAt:     optionalTime(t.RequestedAt),
+		AcceptedAt:      optionalTime(t.AcceptedAt),
+		StartedAt:       optionalTime(t.StartedAt),
+		CompletedAt:     optionalTime(t.CompletedAt),
+		CancelledAt:     optionalTime(t.CancelledAt),
+		DistanceKM:      t.DistanceKM,
+		FareUSD:         t.FareUSD,
+		CancelledBy:     t.CancelledBy,
+		CancelReason:    t.CancelReason,
+	}
+}
+
+// Ride is the JSON form of the current state of a ride.
+type Ride struct {
+	TripID        string               `json:"trip_id"`
+	State         events.RideState     `json:"state"`
+	LastEventType events.RideEventType `json:"last_event_type"`
+	LastEventAt   time.Time            `json:"last_event_at"`
+	DriverID      string               `json:"driver_id,omitempty"`
+	PassengerID   string               `json:"passenger_id,omitempty"`
+	RequestedAt   *time.Time           `json:"requested_at,omitempty"`
+	AcceptedAt    *time.Time           `json:"accepted_at,omitempty"`
+	StartedAt     *time.Time           `json:"started_at,omitempty"`
+	EndedAt       *time.Time           `json:"ended_at,omitempty"`
+	FareUSD       float64              `json:"fare_usd"`
+	Pickup        *events.Coordinate   `json:"pickup,omitempty"`
+	Dropoff       *events.Coordinate   `json:"dropoff,omitempty"`
+}
+
+func newRide(r rides_db.Ride) Ride {
+	return Ride{
+		TripID:        r.TripID,
+		State:         r.State,
+		LastEventType: r.LastEventType,
+		LastEventAt:   r.LastEventAt,
+		DriverID:      r.DriverID,
+		PassengerID:   r.PassengerID,
+		RequestedAt:   optionalTime(r.RequestedAt),
+		AcceptedAt:    optionalTime(r.AcceptedAt),
+		StartedAt:     optionalTime(r.StartedAt),
+		EndedAt:       optionalTime(r.EndedAt),
+		FareUSD:       r.FareUSD,
+		Pickup:        r.Pickup,
+		Dropoff:       r.Dropoff,
+	}
+}
+
+func newRides(rides []rides_db.Ride) []Ride {
+	out := make([]Ride, len(rides))
+	for i, r := range rides {
+		out[i] = newRide(r)
+	}
+	return out
+}
+
+// Earnings sums the completed rides of a driver over a time range.
+type Earnings struct {
+	DriverID       string     `json:"driver_id"`
+	From           *time.Time `json:"from,omitempty"`
+	To             *time.Time `json:"to,omitempty"`
+	CompletedRides int        `json:"completed_rides"`
+	EarningsUSD    float64    `json:"earnings_usd"`
+}
+
+func earnings(driverID string, tr rides_db.TimeRange, rides []rides_db.Ride) Earnings {
+	e := Earnings{DriverID: driverID, From: optionalTime(tr.From), To: optionalTime(tr.To)}
+	for _, r := range rides {
+		if r.State == events.StateCompleted {
+			e.CompletedRides++
+			e.EarningsUSD += r.FareUSD
+		}
+	}
+	return e
+}
+
+// DailyMetrics are the completed trips and revenue of one UTC day.
+type DailyMetrics struct {
+	Day        string  `json:"day"`
+	Trips      int64   `json:"trips"`
+	RevenueUSD float64 `json:"revenue_usd"`
+}
+
+// SurgeMetrics are the completed trips and revenue requested under one surge
+// multiplier; 1 covers trips without surge.
+type SurgeMetrics struct {
+	Multiplier float64 `json:"multiplier"`
+	Trips      int64   `json:"trips"`
+	RevenueUSD float64 `json:"revenue_usd"`
+}
+
+// HeatmapCell is the demand and supply of one pricing zone in one time bucket:
+// the rides requested with a pickup in it, and the drivers that reported from
+// it free.
+type HeatmapCell struct {
+	Zone    string    `json:"zone"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Pickups int64     `json:"pickups"`
+	Drivers int64     `json:"drivers"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
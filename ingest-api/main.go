@@ -0,0 +1,58 @@
+// Command ingest-api takes ride requests and cancellations from clients over
+// HTTP on INGEST_ADDR and writes their events to the outbox, which the outbox
+// relay publishes to ride-events; see the ingest package.
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/ingest"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+func main() {
+	svc := runtime.Start("ingest-api")
+	defer svc.Stop()
+	slog.Info("Starting ingest API")
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+
+	ctx := svc.Context()
+
+	svc.Serve(":2124")
+
+	addr := runtime.EnvString("INGEST_ADDR", ":8090")
+	instance, _ := os.Hostname()
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           ingest.NewHandler(store, ingest.Config{Topic: topics.RideEvents, Instance: instance}),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Ingest API shutdown failed", "error", err)
+		}
+	}()
+
+	slog.Info("Serving ingest API", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal("Ingest API stopped", "error", err)
+	}
+	slog.Info("Ingest API stopped")
+}
@@ -0,0 +1,340 @@
+// Package ingest takes ride requests and cancellations from clients over
+// HTTP and feeds them to the pipeline. Each request is validated, given its
+// trip ID, and written as a REQUESTED or CANCELLED event to the outbox, from
+// which the outbox relay publishes it to ride-events.
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ingest_api_request_duration_seconds",
+		Help:    "Time taken to answer ingest API requests, by route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "code"})
+
+	eventsEnqueued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ingest_api_events_enqueued_total",
+		Help: "Number of events written to the outbox for clients, by event type.",
+	}, []string{"event_type"})
+)
+
+// maxBodyBytes bounds the request bodies read.
+const maxBodyBytes = 64 << 10
+
+// maxIDLength bounds the client IDs and place names accepted.
+const maxIDLength = 200
+
+// Store is the part of rides_db.RideStore the ingest API uses: the outbox,
+// written in a transaction, and the rides read model a cancellation is
+// checked against.
+type Store interface {
+	WithTx(ctx context.Context, fn func(tx rides_db.RideStore) error) error
+	GetRide(ctx context.Context, tripID string) (rides_db.Ride, error)
+	Health(ctx context.Context) error
+}
+
+// Config configures the handler. Zero values take the defaults.
+type Config struct {
+	// Topic is the topic the events are published to.
+	Topic string
+	// Instance names the process in the events' producer_instance.
+	Instance string
+	// Now returns the time the events occur at; nil uses time.Now.
+	Now func() time.Time
+}
+
+func (c *Config) setDefaults() {
+	if c.Topic == "" {
+		c.Topic = "ride-events"
+	}
+	if c.Instance == "" {
+		c.Instance = "ingest-api"
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+// RideRequest is the body of POST /rides. Pickup and Dropoff are optional,
+// and City is needed only where the pipeline runs several cities.
+type RideRequest struct {
+	PassengerID     string             `json:"passenger_id"`
+	PickupLocation  string             `json:"pickup_location"`
+	DropoffLocation string             `json:"dropoff_location"`
+	Pickup          *events.Coordinate `json:"pickup,omitempty"`
+	Dropoff         *events.Coordinate `json:"dropoff,omitempty"`
+	City            string             `json:"city,omitempty"`
+}
+
+// CancelRequest is the body of POST /rides/{id}/cancel, which may be empty:
+// CancelledBy defaults to the passenger, and Reason may be left out. Clients
+// of the API are riders, so CancelledBy can only be the passenger; drivers
+// and the platform cancel through their own services.
+type CancelRequest struct {
+	CancelledBy string `json:"cancelled_by,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// Accepted is the answer to an accepted request: the trip, and the event
+// that will be published for it.
+type Accepted struct {
+	TripID    string               `json:"trip_id"`
+	EventID   string               `json:"event_id"`
+	EventType events.RideEventType `json:"event_type"`
+	State     events.RideState     `json:"state"`
+}
+
+// NewHandler returns the ingest routes backed by store:
+//
+//	POST /rides               request a ride; answers 202 with the new trip ID
+//	POST /rides/{id}/cancel   cancel a ride that has not ended; answers 202
+//	GET  /healthz             200 when the database answers, 503 otherwise
+//
+// Requests are answered 202 Accepted once their event is in the outbox: it is
+// published shortly after, and the read API shows the ride once the consumer
+// has stored it.
+func NewHandler(store Store, cfg Config) http.Handler {
+	cfg.setDefaults()
+	h := &handler{store: store, cfg: cfg}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /rides", h.requestRide)
+	mux.HandleFunc("POST /rides/{id}/cancel", h.cancelRide)
+	mux.HandleFunc("GET /healthz", h.healthz)
+	return instrument(mux)
+}
+
+type handler struct {
+	store Store
+	cfg   Config
+}
+
+func (h *handler) requestRide(w http.ResponseWriter, r *http.Request) {
+	var req RideRequest
+	if err := decodeBody(w, r, &req, false); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tripID := uuid.NewString()
+	opts := h.eventOptions(r, tripID)
+	if req.Pickup != nil || req.Dropoff != nil {
+		opts = append(opts, events.WithCoordinates(req.Pickup, req.Dropoff))
+	}
+	if req.City != "" {
+		opts = append(opts, events.WithCity(req.City))
+	}
+	evt := events.NewRideRequested(tripID, req.PassengerID, req.PickupLocation, req.DropoffLocation, opts...)
+	h.enqueue(w, r, evt)
+}
+
+func (h *handler) cancelRide(w http.ResponseWriter, r *http.Request) {
+	var req CancelRequest
+	if err := decodeBody(w, r, &req, true); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if req.CancelledBy != "" {
+		if by, err := events.ParseActor(req.CancelledBy); err != nil || by != events.ActorPassenger {
+			writeError(w, r, errBadRequest{errors.New("cancelled_by must be passenger")})
+			return
+		}
+	}
+	var reason events.CancelReason
+	if req.Reason != "" {
+		var err error
+		if reason, err = events.ParseCancelReason(req.Reason); err != nil {
+			writeError(w, r, errBadRequest{fmt.Errorf("reason %q is not a known cancel reason", req.Reason)})
+			return
+		}
+	}
+
+	// The ride is known once the consumer has stored its request, which
+	// trails the request by the relay's and the consumer's delay
+	ride, err := h.store.GetRide(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if ride.State == events.StateCompleted || ride.State == events.StateCancelled {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "the ride is already " + strings.ToLower(string(ride.State))})
+		return
+	}
+	opts := append(h.eventOptions(r, ride.TripID), events.WithPassenger(ride.PassengerID), events.WithDriver(ride.DriverID))
+	h.enqueue(w, r, events.NewTripCancelled(ride.TripID, events.ActorPassenger, reason, opts...))
+}
+
+// eventOptions returns the options every event built for r takes: its time,
+// and its metadata, carrying on the trace of the request if it has one.
+func (h *handler) eventOptions(r *http.Request, tripID string) []events.Option {
+	return []events.Option{
+		events.WithTime(h.cfg.Now().UTC()),
+		events.WithMeta(events.Meta{
+			CorrelationID:    tripID,
+			ProducerInstance: h.cfg.Instance,
+			TraceParent:      r.Header.Get(events.HeaderTraceParent),
+			TraceState:       r.Header.Get(events.HeaderTraceState),
+		}),
+	}
+}
+
+// enqueue validates evt and writes it to the outbox, answering 202 with it.
+func (h *handler) enqueue(w http.ResponseWriter, r *http.Request, evt events.RideEvent) {
+	if err := evt.Validate(); err != nil {
+		writeError(w, r, errBadRequest{err})
+		return
+	}
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	err = h.store.WithTx(r.Context(), func(tx rides_db.RideStore) error {
+		return tx.EnqueueOutbox(r.Context(), rides_db.OutboxMessage{
+			Topic:   h.cfg.Topic,
+			Key:     evt.TripID,
+			Payload: payload,
+			Headers: evt.Meta.Headers(),
+		})
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	eventsEnqueued.WithLabelValues(string(evt.Type)).Inc()
+	slog.InfoContext(r.Context(), "Enqueued client event", "trip_id", evt.TripID, "event_id", evt.ID, "type", evt.Type)
+
+	w.Header().Set("Location", "/rides/"+evt.TripID)
+	writeJSON(w, http.StatusAccepted, Accepted{TripID: evt.TripID, EventID: evt.ID, EventType: evt.Type, State: evt.State})
+}
+
+func (h *handler) healthz(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Health(r.Context()); err != nil {
+		slog.Warn("Database health check failed", "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// validate checks what the event built from req cannot: the lengths of its
+// fields and its city's name.
+func (req RideRequest) validate() error {
+	for _, f := range []struct{ name, value string }{
+		{"passenger_id", req.PassengerID},
+		{"pickup_location", req.PickupLocation},
+		{"dropoff_location", req.DropoffLocation},
+	} {
+		switch {
+		case strings.TrimSpace(f.value) == "":
+			return errBadRequest{errors.New(f.name + " is required")}
+		case len(f.value) > maxIDLength:
+			return errBadRequest{fmt.Errorf("%s is longer than %d bytes", f.name, maxIDLength)}
+		}
+	}
+	for name, c := range map[string]*events.Coordinate{"pickup": req.Pickup, "dropoff": req.Dropoff} {
+		if c != nil && !c.Valid() {
+			return errBadRequest{errors.New(name + " is not a valid latitude and longitude")}
+		}
+	}
+	if req.City != "" {
+		if _, err := rides_db.CitySchema(req.City); err != nil {
+			return errBadRequest{errors.New("city must be lower case letters, digits, and underscores, starting with a letter")}
+		}
+	}
+	return nil
+}
+
+// errBadRequest wraps request errors so they are answered with 400.
+type errBadRequest struct{ err error }
+
+func (e errBadRequest) Error() string { return e.err.Error() }
+
+// decodeBody decodes the JSON body of r into v, rejecting unknown fields and
+// bodies over maxBodyBytes. An empty body is accepted if optional.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any, optional bool) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if optional && errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return errBadRequest{fmt.Errorf("invalid body: %w", err)}
+	}
+	if dec.More() {
+		return errBadRequest{errors.New("invalid body: more than one JSON value")}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write ingest API response", "error", err)
+	}
+}
+
+// writeError maps err to a status code. Store errors are logged and reported
+// without detail.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var bad errBadRequest
+	switch {
+	case errors.As(err, &bad):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": bad.Error()})
+	case errors.Is(err, rides_db.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	default:
+		slog.Error("Ingest API request failed", "path", r.URL.Path, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	}
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// instrument records the duration of every request against its route pattern,
+// so trip IDs do not end up as label values.
+func instrument(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		_, route := mux.Handler(r)
+		mux.ServeHTTP(rec, r)
+		if route == "" {
+			route = "unmatched"
+		}
+		requestDuration.WithLabelValues(route, strconv.Itoa(rec.code)).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,193 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+var testNow = time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+
+func newTestServer(t *testing.T) (*httptest.Server, *rides_db.SQLiteStore) {
+	t.Helper()
+	store, err := rides_db.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	srv := httptest.NewServer(NewHandler(store, Config{Now: func() time.Time { return testNow }}))
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+func post(t *testing.T, url, body string, header http.Header) (*http.Response, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decoding response failed: %v", err)
+	}
+	return resp, out
+}
+
+// outbox publishes the store's outbox, returning the messages in it.
+func outbox(t *testing.T, store *rides_db.SQLiteStore) []rides_db.OutboxMessage {
+	t.Helper()
+	var msgs []rides_db.OutboxMessage
+	_, err := store.ProcessOutbox(context.Background(), 100, func(m rides_db.OutboxMessage) error {
+		msgs = append(msgs, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ProcessOutbox failed: %v", err)
+	}
+	return msgs
+}
+
+func TestRequestRide(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	header := http.Header{}
+	header.Set(events.HeaderTraceParent, traceParent)
+	resp, body := post(t, srv.URL+"/rides",
+		`{"passenger_id":"rider-1","pickup_location":"Main St","dropoff_location":"Elm St","pickup":{"lat":40.7,"lng":-74.0},"city":"nyc"}`, header)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %v", resp.StatusCode, body)
+	}
+	tripID, _ := body["trip_id"].(string)
+	if tripID == "" {
+		t.Fatalf("no trip_id in %v", body)
+	}
+	if got := resp.Header.Get("Location"); got != "/rides/"+tripID {
+		t.Errorf("Location = %q, want /rides/%s", got, tripID)
+	}
+
+	msgs := outbox(t, store)
+	if len(msgs) != 1 {
+		t.Fatalf("got %d outbox messages, want 1", len(msgs))
+	}
+	m := msgs[0]
+	if m.Topic != "ride-events" || m.Key != tripID {
+		t.Errorf("message to %s keyed %s, want ride-events keyed %s", m.Topic, m.Key, tripID)
+	}
+	if m.Headers[events.HeaderTraceParent] != traceParent {
+		t.Errorf("traceparent header = %q, want the request's", m.Headers[events.HeaderTraceParent])
+	}
+	var evt events.RideEvent
+	if err := json.Unmarshal(m.Payload, &evt); err != nil {
+		t.Fatalf("decoding payload failed: %v", err)
+	}
+	if evt.Type != events.EventRideRequested || evt.TripID != tripID || evt.PassengerID != "rider-1" || evt.City != "nyc" {
+		t.Errorf("event = %+v", evt)
+	}
+	if !evt.OccurredAt.Equal(testNow) || evt.Meta.CorrelationID != tripID {
+		t.Errorf("event occurred at %v correlated by %q, want %v and the trip", evt.OccurredAt, evt.Meta.CorrelationID, testNow)
+	}
+}
+
+func TestRequestRide_Invalid(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	for _, body := range []string{
+		``,
+		`{"pickup_location":"Main St","dropoff_location":"Elm St"}`,
+		`{"passenger_id":"rider-1","pickup_location":"Main St","dropoff_location":" "}`,
+		`{"passenger_id":"rider-1","pickup_location":"Main St","dropoff_location":"Elm St","pickup":{"lat":91,"lng":0}}`,
+		`{"passenger_id":"rider-1","pickup_location":"Main St","dropoff_location":"Elm St","city":"New York"}`,
+		`{"passenger_id":"rider-1","pickup_location":"Main St","dropoff_location":"Elm St","fare":100}`,
+	} {
+		resp, out := post(t, srv.URL+"/rides", body, nil)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %v", body, resp.StatusCode, out)
+		}
+	}
+	if msgs := outbox(t, store); len(msgs) != 0 {
+		t.Errorf("invalid requests enqueued %d messages", len(msgs))
+	}
+}
+
+func TestCancelRide(t *testing.T) {
+	srv, store := newTestServer(t)
+	ctx := context.Background()
+	for _, e := range []events.RideEvent{
+		{ID: "e1", TripID: "trip-1", Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: testNow, PassengerID: "rider-1",
+			Payload: events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St"}},
+		{ID: "e2", TripID: "trip-1", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: testNow.Add(time.Minute), DriverID: "driver-1",
+			Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+	} {
+		if err := store.UpsertRideState(ctx, e); err != nil {
+			t.Fatalf("UpsertRideState failed: %v", err)
+		}
+	}
+
+	// Only the passenger cancels through the API
+	for _, body := range []string{`{"cancelled_by":"driver"}`, `{"cancelled_by":"system"}`, `{"cancelled_by":"dispatcher"}`} {
+		if resp, out := post(t, srv.URL+"/rides/trip-1/cancel", body, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %v", body, resp.StatusCode, out)
+		}
+	}
+
+	resp, body := post(t, srv.URL+"/rides/trip-1/cancel", `{"cancelled_by":"passenger"}`, nil)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %v", resp.StatusCode, body)
+	}
+	msgs := outbox(t, store)
+	if len(msgs) != 1 {
+		t.Fatalf("got %d outbox messages, want 1", len(msgs))
+	}
+	var evt events.RideEvent
+	if err := json.Unmarshal(msgs[0].Payload, &evt); err != nil {
+		t.Fatalf("decoding payload failed: %v", err)
+	}
+	if evt.Type != events.EventTripCancelled || evt.TripID != "trip-1" || evt.PassengerID != "rider-1" || evt.DriverID != "driver-1" {
+		t.Errorf("event = %+v", evt)
+	}
+	if p, _ := events.PayloadAs[events.RideCancelledPayload](evt); p.CancelledBy != events.ActorPassenger {
+		t.Errorf("cancelled by %q, want the passenger", p.CancelledBy)
+	}
+
+	if resp, _ := post(t, srv.URL+"/rides/trip-2/cancel", ``, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown ride: status = %d, want 404", resp.StatusCode)
+	}
+	if resp, _ := post(t, srv.URL+"/rides/trip-1/cancel", `{"reason":"bored"}`, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown reason: status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestCancelRide_Ended(t *testing.T) {
+	srv, store := newTestServer(t)
+	e := events.RideEvent{ID: "e1", TripID: "trip-1", Type: events.EventTripCompleted, State: events.StateCompleted, OccurredAt: testNow,
+		Payload: events.RideCompletedPayload{EndTime: testNow, DistanceKM: 6, Fare: events.NewMoney(850, events.USD)}}
+	if err := store.UpsertRideState(context.Background(), e); err != nil {
+		t.Fatalf("UpsertRideState failed: %v", err)
+	}
+
+	if resp, body := post(t, srv.URL+"/rides/trip-1/cancel", ``, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("status = %d, want 409: %v", resp.StatusCode, body)
+	}
+	if msgs := outbox(t, store); len(msgs) != 0 {
+		t.Errorf("cancelling an ended ride enqueued %d messages", len(msgs))
+	}
+}
@@ -0,0 +1,356 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/pedeveaux/kafkarideshare/chaos"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+// pipeline is the stack of a chaos test: Redpanda and Postgres, each behind
+// a chaos.Proxy that the producer and consumer go through, while the test
+// itself talks to them directly.
+type pipeline struct {
+	bin          string
+	env          []string
+	kafka, pg    *chaos.Proxy
+	brokers      string // Redpanda's own address
+	db           *sql.DB
+	stopConsumer func()
+}
+
+// startPipeline starts the containers and proxies, creates the topics, and
+// starts the consumer, returning once it has migrated the database.
+func startPipeline(ctx context.Context, t *testing.T) *pipeline {
+	t.Helper()
+	p := &pipeline{bin: buildServices(t)}
+
+	var err error
+	if p.kafka, err = chaos.Listen("127.0.0.1:0", ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.kafka.Close() })
+	p.brokers = startRedpandaBehind(ctx, t, p.kafka)
+	p.kafka.SetUpstream(p.brokers)
+	createTopics(ctx, t, p.brokers)
+
+	pgEnv, connString := startPostgres(ctx, t)
+	u, err := url.Parse(connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.pg, err = chaos.Listen("127.0.0.1:0", u.Host); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.pg.Close() })
+	_, proxyPort, _ := net.SplitHostPort(p.pg.Addr())
+
+	p.env = append(os.Environ(),
+		// Through the proxy: Redpanda advertises it, so every connection
+		// after the first goes through it too
+		"KAFKA_BROKERS="+p.kafka.Addr(),
+		"METRICS_ADDR=127.0.0.1:0",
+		"LOG_LEVEL=warn",
+	)
+	p.env = append(p.env, pgEnv...)
+	p.env = append(p.env, "POSTGRES_HOST=127.0.0.1", "POSTGRES_PORT="+proxyPort)
+
+	store, err := rides_db.Open(connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	p.db = store.DB()
+
+	consumer := exec.CommandContext(ctx, filepath.Join(p.bin, "consumer"))
+	consumer.Env = p.env
+	consumer.Stdout, consumer.Stderr = os.Stdout, os.Stderr
+	if err := consumer.Start(); err != nil {
+		t.Fatalf("consumer failed to start: %v", err)
+	}
+	stopped := false
+	p.stopConsumer = func() {
+		if !stopped {
+			stopped = true
+			consumer.Process.Signal(syscall.SIGTERM)
+			consumer.Wait()
+		}
+	}
+	t.Cleanup(p.stopConsumer)
+
+	// Faults before the migrations are done would only stop it starting
+	waitFor(t, 2*time.Minute, "the consumer's migrations", func() (bool, error) {
+		var n int
+		err := p.db.QueryRowContext(ctx, `SELECT count(*) FROM rides`).Scan(&n)
+		return err == nil, err
+	})
+	return p
+}
+
+// produce runs the producer until it has simulated the given number of
+// rides, in the background, and returns a channel that gets its result.
+func (p *pipeline) produce(ctx context.Context, rides int) <-chan error {
+	producer := exec.CommandContext(ctx, filepath.Join(p.bin, "producer"))
+	producer.Env = append(p.env, fmt.Sprintf("MAX_RIDES=%d", rides), "TICK_INTERVAL=50ms")
+	producer.Stdout, producer.Stderr = os.Stdout, os.Stderr
+	done := make(chan error, 1)
+	go func() { done <- producer.Run() }()
+	return done
+}
+
+// waitForRides waits until every ride has ended and has a trip.
+func (p *pipeline) waitForRides(ctx context.Context, t *testing.T, timeout time.Duration) {
+	t.Helper()
+	waitFor(t, timeout, fmt.Sprintf("%d ended rides and trips", rides), func() (bool, error) {
+		ended, trips, err := p.counts(ctx)
+		if err == nil && (ended != rides || trips != rides) {
+			err = fmt.Errorf("%d ended rides and %d trips", ended, trips)
+		}
+		return err == nil, err
+	})
+}
+
+// counts returns how many rides have ended and how many trips are stored.
+func (p *pipeline) counts(ctx context.Context) (ended, trips int, err error) {
+	err = p.db.QueryRowContext(ctx, `SELECT
+		(SELECT count(*) FROM rides WHERE state IN ('COMPLETED', 'CANCELLED')),
+		(SELECT count(*) FROM trips)`).Scan(&ended, &trips)
+	return ended, trips, err
+}
+
+// TestChaos_DatabaseOutage takes Postgres away while the rides are produced,
+// and brings it back before the retry tiers run out: every event the
+// consumer failed to store goes through them, and nothing is lost or
+// dead-lettered.
+func TestChaos_DatabaseOutage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	p := startPipeline(ctx, t)
+
+	p.pg.Down()
+	if err := <-p.produce(ctx, rides); err != nil {
+		t.Fatalf("producer failed: %v", err)
+	}
+	// and a little longer, for the last events to fail too
+	time.Sleep(3 * time.Second)
+	p.pg.Up()
+
+	p.waitForRides(ctx, t, 3*time.Minute)
+	p.stopConsumer()
+
+	checkRides(ctx, t, p.db)
+	checkEvents(ctx, t, p.db)
+	checkDLQ(t, p.brokers)
+	if n := highWatermark(t, p.brokers, rideconsumer.DefaultRetryTiers[0].Topic); n == 0 {
+		t.Error("nothing went through the retry tiers during the outage")
+	}
+}
+
+// TestChaos_DatabaseOutageDeadLetters keeps Postgres away for longer than
+// the retry tiers wait, so the events failing throughout are dead-lettered
+// as retries_exhausted.
+func TestChaos_DatabaseOutageDeadLetters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits out the retry tiers")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	p := startPipeline(ctx, t)
+
+	p.pg.Down()
+	if err := <-p.produce(ctx, 5); err != nil {
+		t.Fatalf("producer failed: %v", err)
+	}
+	waitFor(t, 3*time.Minute, "dead-lettered events", func() (bool, error) {
+		return highWatermark(t, p.brokers, topics.RideEventsDLQ) > 0, nil
+	})
+	p.pg.Up()
+	p.stopConsumer()
+
+	reasons := dlqReasons(t, p.brokers)
+	if len(reasons) != 1 || reasons["retries_exhausted"] == 0 {
+		t.Errorf("dead-letter reasons = %v, want only retries_exhausted", reasons)
+	}
+}
+
+// TestChaos_SlowDatabase slows every round trip to Postgres down. The
+// consumer stores events one at a time, so it falls behind the producer
+// rather than buffering or failing them, and catches up once the latency is
+// gone.
+func TestChaos_SlowDatabase(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	p := startPipeline(ctx, t)
+
+	p.pg.SetLatency(50*time.Millisecond, 10*time.Millisecond)
+	if err := <-p.produce(ctx, rides); err != nil {
+		t.Fatalf("producer failed: %v", err)
+	}
+	// A ride's last event is produced just before the producer exits
+	ended, _, err := p.counts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%d of %d rides stored as ended when the producer finished", ended, rides)
+	if ended == rides {
+		t.Error("the consumer kept up through a slow database, want it behind")
+	}
+
+	p.pg.SetLatency(0, 0)
+	p.waitForRides(ctx, t, 3*time.Minute)
+	p.stopConsumer()
+
+	checkRides(ctx, t, p.db)
+	checkEvents(ctx, t, p.db)
+	checkDLQ(t, p.brokers)
+	for _, tier := range rideconsumer.DefaultRetryTiers {
+		if n := highWatermark(t, p.brokers, tier.Topic); n != 0 {
+			t.Errorf("%d events went to %s, want slowness not to count as failure", n, tier.Topic)
+		}
+	}
+}
+
+// TestChaos_BrokerFaults partitions the producer and consumer from Redpanda
+// and resets their connections while the rides are produced. The clients
+// reconnect and deliver what they held, and every ride is stored once.
+func TestChaos_BrokerFaults(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	p := startPipeline(ctx, t)
+
+	produced := p.produce(ctx, rides)
+	waitFor(t, time.Minute, "the first stored events", func() (bool, error) {
+		var n int
+		err := p.db.QueryRowContext(ctx, `SELECT count(*) FROM ride_events`).Scan(&n)
+		return n > 0, err
+	})
+	p.kafka.Partition()
+	time.Sleep(10 * time.Second)
+	p.kafka.Heal()
+	time.Sleep(2 * time.Second)
+	if n := p.kafka.ResetConnections(); n == 0 {
+		t.Error("no connections to reset")
+	}
+	if err := <-produced; err != nil {
+		t.Fatalf("producer failed: %v", err)
+	}
+
+	p.waitForRides(ctx, t, 3*time.Minute)
+	p.stopConsumer()
+
+	checkRides(ctx, t, p.db)
+	checkEvents(ctx, t, p.db)
+	checkDLQ(t, p.brokers)
+}
+
+// startRedpandaBehind starts Redpanda advertising proxy's address, so that
+// clients connect to it through proxy, and returns its own address.
+// redpanda.Run advertises the container's mapped port, which the clients
+// would go to directly after bootstrapping.
+func startRedpandaBehind(ctx context.Context, t *testing.T, proxy *chaos.Proxy) string {
+	t.Helper()
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        redpandaImage,
+			ExposedPorts: []string{"9092/tcp"},
+			Cmd: []string{"redpanda", "start", "--mode", "dev-container", "--smp", "1",
+				"--kafka-addr", "0.0.0.0:9092", "--advertise-kafka-addr", proxy.Addr()},
+			WaitingFor: wait.ForAll(
+				wait.ForListeningPort("9092/tcp"),
+				wait.ForLog("Successfully started Redpanda!"),
+			),
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, c)
+	if err != nil {
+		t.Fatalf("start redpanda: %v", err)
+	}
+	host, err := c.Host(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := c.MappedPort(ctx, "9092/tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return net.JoinHostPort(host, port.Port())
+}
+
+// waitFor polls cond until it holds, failing the test with what was waited
+// for and cond's last error after timeout.
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() (bool, error)) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := cond()
+		if ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s (%v)", what, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// highWatermark returns the end offset of the first partition of topic.
+func highWatermark(t *testing.T, brokers, topic string) int64 {
+	t.Helper()
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{"bootstrap.servers": brokers, "group.id": "integration-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	_, high, err := c.QueryWatermarkOffsets(topic, 0, 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return high
+}
+
+// dlqReasons counts the dead-lettered events by their dlq-reason header.
+func dlqReasons(t *testing.T, brokers string) map[string]int {
+	t.Helper()
+	high := highWatermark(t, brokers, topics.RideEventsDLQ)
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{"bootstrap.servers": brokers, "group.id": "integration-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	topic := topics.RideEventsDLQ
+	if err := c.Assign([]kafka.TopicPartition{{Topic: &topic, Partition: 0, Offset: kafka.OffsetBeginning}}); err != nil {
+		t.Fatal(err)
+	}
+	reasons := map[string]int{}
+	for read := int64(0); read < high; read++ {
+		msg, err := c.ReadMessage(10 * time.Second)
+		if err != nil {
+			t.Fatalf("read the dead-letter topic: %v", err)
+		}
+		for _, h := range msg.Headers {
+			if h.Key == "dlq-reason" {
+				reasons[string(h.Value)]++
+			}
+		}
+	}
+	return reasons
+}
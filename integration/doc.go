@@ -0,0 +1,12 @@
+// Package integration runs the producer and consumer end to end, against
+// Redpanda and Postgres started in containers with testcontainers-go: the
+// producer simulates a number of rides, the consumer stores them, and the test
+// checks the rows, the rides' states, and that every ride's events follow the
+// state machine. The chaos tests run them again behind chaos proxies that
+// inject latency, partitions, and connection resets, and check the retries,
+// dead-lettering, and backpressure of the consumer. The store tests run the
+// Postgres queries that the sqlmock tests in rides_db only match as text. It
+// needs Docker, so the tests only build with the integration tag:
+//
+//	go test -tags integration ./integration
+package integration
@@ -0,0 +1,270 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/modules/redpanda"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+// rides is how many rides the producer simulates.
+const rides = 25
+
+const (
+	redpandaImage = "docker.redpanda.com/redpandadata/redpanda:v24.2.4"
+	postgresImage = "postgres:17"
+)
+
+// stateAfter is the state each lifecycle event leads to.
+var stateAfter = map[events.RideEventType]events.RideState{
+	events.EventRideRequested: events.StateRequested,
+	events.EventRideAccepted:  events.StateAccepted,
+	events.EventTripStarted:   events.StateInProgress,
+	events.EventTripCompleted: events.StateCompleted,
+	events.EventTripCancelled: events.StateCancelled,
+}
+
+// validSequences are the event sequences a ride may have, by the producer's
+// state machine.
+var validSequences = map[string]bool{
+	"REQUESTED,ACCEPTED,STARTED,COMPLETED": true,
+	"REQUESTED,CANCELLED":                  true,
+	"REQUESTED,ACCEPTED,CANCELLED":         true,
+}
+
+func TestEndToEnd(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	bin := buildServices(t)
+	brokers := startRedpanda(ctx, t)
+	pgEnv, connString := startPostgres(ctx, t)
+	createTopics(ctx, t, brokers)
+
+	env := append(os.Environ(),
+		"KAFKA_BROKERS="+brokers,
+		"METRICS_ADDR=127.0.0.1:0",
+		"LOG_LEVEL=warn",
+	)
+	env = append(env, pgEnv...)
+
+	// The producer runs first and exits once every ride has ended; the
+	// consumer reads the topic from the start
+	producer := exec.CommandContext(ctx, filepath.Join(bin, "producer"))
+	producer.Env = append(env, fmt.Sprintf("MAX_RIDES=%d", rides), "TICK_INTERVAL=50ms")
+	producer.Stdout, producer.Stderr = os.Stdout, os.Stderr
+	if err := producer.Run(); err != nil {
+		t.Fatalf("producer failed: %v", err)
+	}
+
+	consumer := exec.CommandContext(ctx, filepath.Join(bin, "consumer"))
+	consumer.Env = env
+	consumer.Stdout, consumer.Stderr = os.Stdout, os.Stderr
+	if err := consumer.Start(); err != nil {
+		t.Fatalf("consumer failed to start: %v", err)
+	}
+	stopped := false
+	stop := func() {
+		if !stopped {
+			stopped = true
+			consumer.Process.Signal(syscall.SIGTERM)
+			consumer.Wait()
+		}
+	}
+	t.Cleanup(stop)
+
+	store, err := rides_db.Open(connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	db := store.DB()
+
+	// Migrations run when the consumer starts, so the tables may not exist yet
+	deadline := time.Now().Add(2 * time.Minute)
+	for {
+		var ended, trips int
+		err := db.QueryRowContext(ctx, `SELECT
+			(SELECT count(*) FROM rides WHERE state IN ('COMPLETED', 'CANCELLED')),
+			(SELECT count(*) FROM trips)`).Scan(&ended, &trips)
+		if err == nil && ended == rides && trips == rides {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d ended rides and trips: got %d and %d (%v)", rides, ended, trips, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	stop()
+
+	checkRides(ctx, t, db)
+	checkEvents(ctx, t, db)
+	checkDLQ(t, brokers)
+}
+
+// buildServices builds the producer and consumer into a temporary directory.
+func buildServices(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	build := exec.Command("go", "build", "-o", dir, "../producer", "../consumer")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	return dir
+}
+
+func startRedpanda(ctx context.Context, t *testing.T) string {
+	t.Helper()
+	c, err := redpanda.Run(ctx, redpandaImage)
+	testcontainers.CleanupContainer(t, c)
+	if err != nil {
+		t.Fatalf("start redpanda: %v", err)
+	}
+	brokers, err := c.KafkaSeedBroker(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return brokers
+}
+
+// startPostgres returns the environment the services connect with, and the
+// connection string for the test's own queries.
+func startPostgres(ctx context.Context, t *testing.T) (env []string, connString string) {
+	t.Helper()
+	c, err := postgres.Run(ctx, postgresImage,
+		postgres.WithDatabase("rides"),
+		postgres.WithUsername("rides"),
+		postgres.WithPassword("rides"),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, c)
+	if err != nil {
+		t.Fatalf("start postgres: %v", err)
+	}
+	connString, err = c.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []string{
+		"DB_DRIVER=postgres",
+		"POSTGRES_HOST=" + u.Hostname(),
+		"POSTGRES_PORT=" + u.Port(),
+		"POSTGRES_USER=rides",
+		"POSTGRES_PASSWORD=rides",
+		"POSTGRES_DB=rides",
+	}, connString
+}
+
+// createTopics creates the topics as kafka-admin does, with one partition so
+// that the test does not wait on rebalancing.
+func createTopics(ctx context.Context, t *testing.T, brokers string) {
+	t.Helper()
+	admin, err := kafka.NewAdminClient(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	specs := topics.Specs(topics.Settings{Partitions: 1, ReplicationFactor: 1, Retention: time.Hour})
+	if _, err := topics.Ensure(ctx, admin, specs, topics.Options{}); err != nil {
+		t.Fatalf("create topics: %v", err)
+	}
+}
+
+// checkRides checks that every ride ended and that its trip agrees with it.
+func checkRides(ctx context.Context, t *testing.T, db *sql.DB) {
+	t.Helper()
+	var n, mismatched int
+	err := db.QueryRowContext(ctx, `SELECT count(*),
+		count(*) FILTER (WHERE t.trip_id IS NULL OR t.final_state <> r.state)
+		FROM rides r LEFT JOIN trips t USING (trip_id)`).Scan(&n, &mismatched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != rides || mismatched != 0 {
+		t.Errorf("got %d rides, %d without a matching trip; want %d rides", n, mismatched, rides)
+	}
+
+	var completed, withFare int
+	err = db.QueryRowContext(ctx, `SELECT count(*), count(fare_usd) FROM rides WHERE state = 'COMPLETED'`).Scan(&completed, &withFare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed != withFare {
+		t.Errorf("%d of %d completed rides have no fare", completed-withFare, completed)
+	}
+}
+
+// checkEvents checks that each ride's events follow the state machine, and
+// that each event records the state its type leads to.
+func checkEvents(ctx context.Context, t *testing.T, db *sql.DB) {
+	t.Helper()
+	rows, err := db.QueryContext(ctx, `SELECT trip_id, event_type, event_state FROM ride_events
+		ORDER BY trip_id, event_time,
+			array_position(ARRAY['REQUESTED', 'ACCEPTED', 'STARTED', 'COMPLETED', 'CANCELLED'], event_type::text)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	sequences := map[string][]string{}
+	for rows.Next() {
+		var tripID, eventType, state string
+		if err := rows.Scan(&tripID, &eventType, &state); err != nil {
+			t.Fatal(err)
+		}
+		if want := stateAfter[events.RideEventType(eventType)]; string(want) != state {
+			t.Errorf("trip %s: %s event in state %s, want %s", tripID, eventType, state, want)
+		}
+		sequences[tripID] = append(sequences[tripID], eventType)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sequences) != rides {
+		t.Errorf("got events for %d trips, want %d", len(sequences), rides)
+	}
+	for tripID, seq := range sequences {
+		if s := strings.Join(seq, ","); !validSequences[s] {
+			t.Errorf("trip %s: invalid transitions %s", tripID, s)
+		}
+	}
+}
+
+// checkDLQ checks that no event was dead-lettered.
+func checkDLQ(t *testing.T, brokers string) {
+	t.Helper()
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{"bootstrap.servers": brokers, "group.id": "integration-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	_, high, err := c.QueryWatermarkOffsets(topics.RideEventsDLQ, 0, 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if high != 0 {
+		t.Errorf("%d events were dead-lettered", high)
+	}
+}
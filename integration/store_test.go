@@ -0,0 +1,212 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// openStore starts Postgres and returns a migrated store on it, for the tests
+// that check the Postgres queries themselves rather than the pipeline.
+func openStore(ctx context.Context, t *testing.T) *rides_db.Store {
+	t.Helper()
+	_, connString := startPostgres(ctx, t)
+	store, err := rides_db.Open(connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	return store
+}
+
+// completedTrip returns the events of a trip requested at base with pickup
+// coordinate at, or without one if at is nil, that completes with fare.
+func completedTrip(tripID string, base time.Time, at *events.Coordinate, fare events.Money) []events.RideEvent {
+	return []events.RideEvent{
+		{ID: tripID + "-1", TripID: tripID, Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: base, PassengerID: "rider-1",
+			Payload: events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St", Pickup: at}},
+		{ID: tripID + "-2", TripID: tripID, Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: base.Add(time.Minute), DriverID: "driver-1",
+			Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+		{ID: tripID + "-3", TripID: tripID, Type: events.EventTripStarted, State: events.StateInProgress, OccurredAt: base.Add(5 * time.Minute),
+			Payload: events.RideStartedPayload{StartTime: base.Add(5 * time.Minute)}},
+		{ID: tripID + "-4", TripID: tripID, Type: events.EventTripCompleted, State: events.StateCompleted, OccurredAt: base.Add(20 * time.Minute),
+			Payload: events.RideCompletedPayload{EndTime: base.Add(20 * time.Minute), DistanceKM: 6, Fare: fare}},
+	}
+}
+
+func TestStore_RefreshTripMoneyFare(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	store := openStore(ctx, t)
+
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	if _, err := store.InsertRideEvents(ctx, completedTrip("trip-1", base, nil, events.NewMoney(1275, events.USD))); err != nil {
+		t.Fatalf("InsertRideEvents failed: %v", err)
+	}
+
+	// The stored payload is the Money encoding, without the old fare_usd key
+	var legacy bool
+	if err := store.DB().QueryRowContext(ctx,
+		`SELECT payload ? 'fare_usd' FROM ride_events WHERE id = 'trip-1-4'`).Scan(&legacy); err != nil {
+		t.Fatal(err)
+	}
+	if legacy {
+		t.Fatal("expected the completed payload to carry fare, not fare_usd")
+	}
+
+	if err := store.RefreshTrip(ctx, "trip-1"); err != nil {
+		t.Fatalf("RefreshTrip failed: %v", err)
+	}
+	trip, err := store.GetTrip(ctx, "trip-1")
+	if err != nil {
+		t.Fatalf("GetTrip failed: %v", err)
+	}
+	if trip.FareUSD != 12.75 || trip.DistanceKM != 6 {
+		t.Errorf("expected fare 12.75 over 6 km, got %v over %v km", trip.FareUSD, trip.DistanceKM)
+	}
+}
+
+// TestStore_RevenueBySurgeByPickupZone checks that trips requested with
+// coordinates are joined to the surges of their grid cell, both when the
+// consumer assembles them and when RefreshTrip rebuilds them in SQL.
+func TestStore_RevenueBySurgeByPickupZone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	store := openStore(ctx, t)
+
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	pickup := events.Coordinate{Lat: 40.7510, Lng: -73.9870}
+	zone := pickup.Zone()
+	if err := store.UpsertZone(ctx, rides_db.Zone{ID: string(zone), UpdatedAt: base.Add(-time.Hour)}); err != nil {
+		t.Fatalf("UpsertZone failed: %v", err)
+	}
+	if err := store.UpsertSurgeMultiplier(ctx, rides_db.SurgeMultiplier{ZoneID: string(zone), EffectiveFrom: base.Add(-time.Hour), Multiplier: 2}); err != nil {
+		t.Fatalf("UpsertSurgeMultiplier failed: %v", err)
+	}
+
+	assembled := completedTrip("trip-1", base, &pickup, events.NewMoney(1000, events.USD))
+	refreshed := completedTrip("trip-2", base, &pickup, events.NewMoney(1000, events.USD))
+	if _, err := store.InsertRideEvents(ctx, append(assembled, refreshed...)); err != nil {
+		t.Fatalf("InsertRideEvents failed: %v", err)
+	}
+	trips := aggregation.NewTripAssembler()
+	for _, e := range assembled {
+		if trip, done := trips.Add(e); done {
+			if err := store.InsertTrip(ctx, trip); err != nil {
+				t.Fatalf("InsertTrip failed: %v", err)
+			}
+		}
+	}
+	if err := store.RefreshTrip(ctx, "trip-2"); err != nil {
+		t.Fatalf("RefreshTrip failed: %v", err)
+	}
+	for _, id := range []string{"trip-1", "trip-2"} {
+		trip, err := store.GetTrip(ctx, id)
+		if err != nil {
+			t.Fatalf("GetTrip failed: %v", err)
+		}
+		if trip.PickupZone != zone {
+			t.Errorf("%s: pickup zone %q, want %q", id, trip.PickupZone, zone)
+		}
+	}
+
+	got, err := store.RevenueBySurge(ctx, rides_db.TimeRange{})
+	if err != nil {
+		t.Fatalf("RevenueBySurge failed: %v", err)
+	}
+	want := rides_db.SurgeRevenue{Multiplier: 2, Trips: 2, RevenueUSD: 20}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("RevenueBySurge = %+v, want %+v", got, want)
+	}
+}
+
+// TestStore_EraseDriverScrubsAlertsAndNotifications checks that no copy of an
+// erased driver's ID is left in fraud alerts, their details, or notification
+// recipients.
+func TestStore_EraseDriverScrubsAlertsAndNotifications(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	store := openStore(ctx, t)
+
+	at := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	alert := fraud.NewAlert(fraud.KindDuplicateTrip, "trip-1", at, "driver-1 accepted while on trip trip-0")
+	alert.DriverID = "driver-1"
+	if err := store.InsertFraudAlert(ctx, alert); err != nil {
+		t.Fatalf("InsertFraudAlert failed: %v", err)
+	}
+	err := store.UpsertNotificationDelivery(ctx, rides_db.NotificationDelivery{
+		NotificationID: "n-1", Channel: "sms", Recipient: "driver-1", TripID: "trip-1",
+		EventType: string(events.EventRideAccepted), Status: rides_db.DeliverySent, Attempts: 1,
+	})
+	if err != nil {
+		t.Fatalf("UpsertNotificationDelivery failed: %v", err)
+	}
+
+	erasure, err := store.EraseDriver(ctx, "driver-1")
+	if err != nil {
+		t.Fatalf("EraseDriver failed: %v", err)
+	}
+
+	var left int
+	err = store.DB().QueryRowContext(ctx, `
+		SELECT (SELECT count(*) FROM fraud_alerts WHERE driver_id = 'driver-1' OR detail LIKE '%driver-1%')
+		     + (SELECT count(*) FROM notification_deliveries WHERE recipient = 'driver-1')`).Scan(&left)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d rows still name the erased driver", left)
+	}
+	alerts, err := store.ListFraudAlerts(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListFraudAlerts failed: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].DriverID != erasure.Pseudonym {
+		t.Errorf("alerts after the erasure = %+v, want the driver as %s", alerts, erasure.Pseudonym)
+	}
+}
+
+// TestStore_ErasePassengerClearsLocationZone checks that erasing a passenger
+// clears the pricing zone of a trip requested without coordinates, which is
+// its pickup location, and keeps the grid cell of one requested with them.
+func TestStore_ErasePassengerClearsLocationZone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	store := openStore(ctx, t)
+
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	pickup := events.Coordinate{Lat: 40.7510, Lng: -73.9870}
+	evts := append(completedTrip("trip-1", base, nil, events.NewMoney(1000, events.USD)),
+		completedTrip("trip-2", base, &pickup, events.NewMoney(1000, events.USD))...)
+	if _, err := store.InsertRideEvents(ctx, evts); err != nil {
+		t.Fatalf("InsertRideEvents failed: %v", err)
+	}
+	for _, id := range []string{"trip-1", "trip-2"} {
+		if err := store.RefreshTrip(ctx, id); err != nil {
+			t.Fatalf("RefreshTrip failed: %v", err)
+		}
+	}
+
+	if _, err := store.ErasePassenger(ctx, "rider-1"); err != nil {
+		t.Fatalf("ErasePassenger failed: %v", err)
+	}
+	for id, want := range map[string]events.ZoneID{"trip-1": "", "trip-2": pickup.Zone()} {
+		trip, err := store.GetTrip(ctx, id)
+		if err != nil {
+			t.Fatalf("GetTrip failed: %v", err)
+		}
+		if trip.PickupZone != want || trip.PickupLocation != "" {
+			t.Errorf("%s: pickup zone %q at %q, want zone %q and no location", id, trip.PickupZone, trip.PickupLocation, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/retention"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+)
+
+// defaultInterval is how often the janitor archives when JANITOR_INTERVAL is unset.
+const defaultInterval = time.Hour
+
+func main() {
+	svc := runtime.Start("janitor")
+	defer svc.Stop()
+	slog.Info("Starting ride events janitor...")
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+	ctx := svc.Context()
+
+	archiver := retention.NewArchiver(store, configFromEnv())
+	svc.OnChange(func() {
+		size := runtime.EnvInt("JANITOR_BATCH_SIZE", 0)
+		archiver.SetBatchSize(size)
+		slog.Info("Applied new batch size", "batch_size", size)
+	}, "JANITOR_BATCH_SIZE")
+
+	// `janitor once` archives a single time, for running from cron
+	if len(os.Args) > 1 && os.Args[1] == "once" {
+		n, err := archiver.RunOnce(ctx, time.Now())
+		if err != nil {
+			logger.Fatal("Archiving ride events failed", "archived", n, "error", err)
+		}
+		slog.Info("Archived ride events", "count", n)
+		return
+	}
+
+	svc.Serve(":2114")
+
+	archiver.Run(ctx, runtime.EnvDuration("JANITOR_INTERVAL", defaultInterval))
+	slog.Info("Janitor stopped")
+}
+
+// configFromEnv reads RIDE_EVENTS_ARCHIVE_AFTER_DAYS and JANITOR_BATCH_SIZE;
+// unset or invalid values keep the archiver defaults. The config file can
+// change the batch size while the janitor runs.
+func configFromEnv() retention.Config {
+	return retention.Config{
+		ArchiveAfter: time.Duration(runtime.EnvInt("RIDE_EVENTS_ARCHIVE_AFTER_DAYS", 0)) * 24 * time.Hour,
+		BatchSize:    runtime.EnvInt("JANITOR_BATCH_SIZE", 0),
+	}
+}
@@ -0,0 +1,14 @@
+// Command kafka-admin creates the Kafka topics the ride services use and
+// brings the configs of existing topics in line; see the services/kafkaadmin
+// package. `rideshare admin` runs the same.
+//
+//	kafka-admin [-brokers B] [-dry-run] [-add-partitions] [-list]
+package main
+
+import (
+	"os"
+
+	"github.com/pedeveaux/kafkarideshare/services/kafkaadmin"
+)
+
+func main() { kafkaadmin.Run(os.Args[1:]) }
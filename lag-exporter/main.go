@@ -0,0 +1,51 @@
+// Command lag-exporter exports the lag of every consumer group in the
+// pipeline as Prometheus metrics. It polls the groups' committed offsets and
+// the end offsets of the topics they read every LAG_INTERVAL; see the lag
+// package.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/pedeveaux/kafkarideshare/lag"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+)
+
+const defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+
+// configFromEnv reads the exporter settings: LAG_GROUPS, the groups polled as
+// group:topic,... entries separated by semicolons (default lag.DefaultGroups),
+// and LAG_INTERVAL, how often they are polled. Invalid values are logged and
+// left to the defaults.
+func configFromEnv() lag.Config {
+	var cfg lag.Config
+	if raw := os.Getenv("LAG_GROUPS"); raw != "" {
+		if groups, err := lag.ParseGroups(raw); err != nil || len(groups) == 0 {
+			slog.Warn("Invalid LAG_GROUPS, using default", "value", raw, "error", err)
+		} else {
+			cfg.Groups = groups
+		}
+	}
+	cfg.Interval = runtime.EnvDuration("LAG_INTERVAL", lag.DefaultInterval)
+	return cfg
+}
+
+func main() {
+	svc := runtime.Start("lag-exporter")
+	defer svc.Stop()
+	slog.Info("Starting lag exporter")
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+	offsets := lag.NewKafkaOffsets(brokers)
+	logger.OnShutdown("kafka consumers", func(context.Context) error { return offsets.Close() })
+
+	svc.Serve(":2123")
+
+	ctx := svc.Context()
+
+	lag.New(configFromEnv(), offsets).Run(ctx)
+	slog.Info("Lag exporter stopped")
+}
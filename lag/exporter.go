@@ -0,0 +1,345 @@
+// Package lag exports the lag of the pipeline's consumer groups as
+// Prometheus metrics. An Exporter polls each group's committed offsets and
+// the end offsets of the topics it reads, for every group in one place, where
+// each service's client only knows its own. The Kafka client has no call to
+// list groups, so the groups are configured, DefaultGroups by default.
+package lag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+var (
+	committedOffset = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumergroup_committed_offset",
+		Help: "Offset committed by each consumer group on each partition it reads.",
+	}, []string{"group", "topic", "partition"})
+
+	groupLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumergroup_lag",
+		Help: "Messages on each partition after a consumer group's committed offset.",
+	}, []string{"group", "topic", "partition"})
+
+	endOffset = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_topic_partition_end_offset",
+		Help: "Offset of the next message written to each partition, its high watermark.",
+	}, []string{"topic", "partition"})
+
+	polls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lag_exporter_polls_total",
+		Help: "Number of polls of the consumer groups, by outcome: ok, or partial when some offsets could not be read.",
+	}, []string{"outcome"})
+)
+
+// Defaults of Config.
+const (
+	DefaultInterval = 15 * time.Second
+	DefaultTimeout  = 10 * time.Second
+)
+
+// Group is a consumer group and the topics it reads.
+type Group struct {
+	ID     string
+	Topics []string
+}
+
+// DefaultGroups are the consumer groups of the pipeline's services, with the
+// matcher's offers topic whether or not it offers rides.
+var DefaultGroups = defaultGroups()
+
+func defaultGroups() []Group {
+	groups := []Group{
+		{ID: "ride-consumer-group", Topics: []string{topics.RideEvents}},
+	}
+	// The consumer reads each retry tier in a group of its own
+	for _, tier := range rideconsumer.DefaultRetryTiers {
+		groups = append(groups, Group{ID: "ride-consumer-group-" + tier.Topic, Topics: []string{tier.Topic}})
+	}
+	return append(groups,
+		Group{ID: "matcher", Topics: []string{topics.RideEvents, topics.DriverEvents, topics.DispatchOffers}},
+		Group{ID: "driversim", Topics: []string{topics.DispatchOffers, topics.RideEvents}},
+		Group{ID: "ridersim", Topics: []string{topics.RideEvents}},
+		Group{ID: "producer", Topics: []string{topics.RideEvents}},
+		Group{ID: "pricer", Topics: []string{topics.RideEvents}},
+		Group{ID: "fraud-detector", Topics: []string{topics.RideEvents, topics.FraudAlerts}},
+		Group{ID: "surge-updater", Topics: []string{topics.RideEvents, topics.DriverEvents}},
+		Group{ID: "heatmap-builder", Topics: []string{topics.RideEvents, topics.DriverEvents}},
+		Group{ID: "notifier", Topics: []string{topics.RideEvents}},
+		Group{ID: "ride-state-processor", Topics: []string{topics.RideEvents}},
+		Group{ID: "driver-stats-processor", Topics: []string{topics.RideEventsByDriver}},
+	)
+}
+
+// ParseGroups parses a semicolon-separated list of group:topic,... entries,
+// such as "ride-consumer-group:ride-events;matcher:ride-events,driver-events".
+func ParseGroups(spec string) ([]Group, error) {
+	var out []Group
+	for _, entry := range strings.Split(spec, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		id, list, ok := strings.Cut(entry, ":")
+		g := Group{ID: strings.TrimSpace(id)}
+		for _, t := range strings.Split(list, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				g.Topics = append(g.Topics, t)
+			}
+		}
+		if !ok || g.ID == "" || len(g.Topics) == 0 {
+			return nil, fmt.Errorf("lag: group %q: want group:topic,...", entry)
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// Offsets is what the exporter asks the brokers. KafkaOffsets asks them
+// with the Kafka client.
+type Offsets interface {
+	// Partitions returns the partitions of topic.
+	Partitions(topic string, timeout time.Duration) ([]int32, error)
+	// EndOffset returns the offset of the next message written to a partition.
+	EndOffset(topic string, partition int32, timeout time.Duration) (int64, error)
+	// Committed returns the offsets group committed on partitions, which are
+	// negative where it has none.
+	Committed(group string, partitions []kafka.TopicPartition, timeout time.Duration) ([]kafka.TopicPartition, error)
+}
+
+// Config configures an Exporter. Zero values take the defaults.
+type Config struct {
+	// Groups are the groups polled; nil polls DefaultGroups.
+	Groups []Group
+	// Interval is how often the groups are polled.
+	Interval time.Duration
+	// Timeout bounds each request to the brokers.
+	Timeout time.Duration
+}
+
+func (c *Config) setDefaults() {
+	if c.Groups == nil {
+		c.Groups = DefaultGroups
+	}
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+}
+
+// Exporter polls the groups' offsets and sets the metrics from them.
+type Exporter struct {
+	cfg     Config
+	offsets Offsets
+}
+
+// New returns an Exporter that reads offsets from offsets.
+func New(cfg Config, offsets Offsets) *Exporter {
+	cfg.setDefaults()
+	return &Exporter{cfg: cfg, offsets: offsets}
+}
+
+// Run polls every Interval until ctx is cancelled.
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := e.Poll(); err != nil {
+			slog.Warn("Failed to read some consumer group offsets", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll reads every group's offsets once and sets the metrics. A topic or
+// group whose offsets cannot be read is left out, its series removed, and
+// the errors returned together.
+func (e *Exporter) Poll() error {
+	var errs []error
+	ends := make(map[string]map[int32]int64) // by topic, then partition; nil if unreadable
+	for _, g := range e.cfg.Groups {
+		var parts []kafka.TopicPartition
+		for _, topic := range g.Topics {
+			end, ok := ends[topic]
+			if !ok {
+				var err error
+				if end, err = e.endOffsets(topic); err != nil {
+					errs = append(errs, err)
+				}
+				ends[topic] = end
+			}
+			if end == nil {
+				e.forget(prometheus.Labels{"group": g.ID, "topic": topic})
+				continue
+			}
+			for p := range end {
+				parts = append(parts, kafka.TopicPartition{Topic: &topic, Partition: p})
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		committed, err := e.offsets.Committed(g.ID, parts, e.cfg.Timeout)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("lag: committed offsets of %s: %w", g.ID, err))
+			e.forget(prometheus.Labels{"group": g.ID})
+			continue
+		}
+		for _, tp := range committed {
+			labels := prometheus.Labels{"group": g.ID, "topic": *tp.Topic, "partition": strconv.Itoa(int(tp.Partition))}
+			if tp.Error != nil || tp.Offset < 0 {
+				// No commit yet, as for a group that has not started
+				committedOffset.Delete(labels)
+				groupLag.Delete(labels)
+				continue
+			}
+			committedOffset.With(labels).Set(float64(tp.Offset))
+			groupLag.With(labels).Set(float64(max(ends[*tp.Topic][tp.Partition]-int64(tp.Offset), 0)))
+		}
+	}
+	if len(errs) > 0 {
+		polls.WithLabelValues("partial").Inc()
+	} else {
+		polls.WithLabelValues("ok").Inc()
+	}
+	return errors.Join(errs...)
+}
+
+// endOffsets returns the end offset of each partition of topic, and sets
+// their metric.
+func (e *Exporter) endOffsets(topic string) (map[int32]int64, error) {
+	partitions, err := e.offsets.Partitions(topic, e.cfg.Timeout)
+	if err != nil {
+		endOffset.DeletePartialMatch(prometheus.Labels{"topic": topic})
+		return nil, fmt.Errorf("lag: partitions of %s: %w", topic, err)
+	}
+	out := make(map[int32]int64, len(partitions))
+	for _, p := range partitions {
+		end, err := e.offsets.EndOffset(topic, p, e.cfg.Timeout)
+		if err != nil {
+			endOffset.DeletePartialMatch(prometheus.Labels{"topic": topic})
+			return nil, fmt.Errorf("lag: end offset of %s/%d: %w", topic, p, err)
+		}
+		out[p] = end
+		endOffset.WithLabelValues(topic, strconv.Itoa(int(p))).Set(float64(end))
+	}
+	return out, nil
+}
+
+// forget removes the series of the groups and topics matching labels, so a
+// stale value is not mistaken for a current one.
+func (e *Exporter) forget(labels prometheus.Labels) {
+	committedOffset.DeletePartialMatch(labels)
+	groupLag.DeletePartialMatch(labels)
+}
+
+// KafkaOffsets asks the brokers for offsets with the Kafka client. Committed
+// offsets are read with a consumer per group, which never subscribes, so it
+// does not join the group or move its offsets.
+type KafkaOffsets struct {
+	brokers string
+
+	mu        sync.Mutex
+	consumers map[string]*kafka.Consumer // by group; "" for metadata
+}
+
+// NewKafkaOffsets returns a KafkaOffsets asking brokers.
+func NewKafkaOffsets(brokers string) *KafkaOffsets {
+	return &KafkaOffsets{brokers: brokers, consumers: make(map[string]*kafka.Consumer)}
+}
+
+// consumer returns the consumer of group, creating it the first time.
+func (k *KafkaOffsets) consumer(group string) (*kafka.Consumer, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if c, ok := k.consumers[group]; ok {
+		return c, nil
+	}
+	id := group
+	if id == "" {
+		id = "lag-exporter"
+	}
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  k.brokers,
+		"group.id":           id,
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	k.consumers[group] = c
+	return c, nil
+}
+
+// Partitions returns the partitions of topic.
+func (k *KafkaOffsets) Partitions(topic string, timeout time.Duration) ([]int32, error) {
+	c, err := k.consumer("")
+	if err != nil {
+		return nil, err
+	}
+	md, err := c.GetMetadata(&topic, false, int(timeout.Milliseconds()))
+	if err != nil {
+		return nil, err
+	}
+	tm, ok := md.Topics[topic]
+	if !ok {
+		return nil, kafka.NewError(kafka.ErrUnknownTopicOrPart, "unknown topic "+topic, false)
+	}
+	if tm.Error.Code() != kafka.ErrNoError {
+		return nil, tm.Error
+	}
+	out := make([]int32, 0, len(tm.Partitions))
+	for _, p := range tm.Partitions {
+		out = append(out, p.ID)
+	}
+	return out, nil
+}
+
+// EndOffset returns the high watermark of a partition.
+func (k *KafkaOffsets) EndOffset(topic string, partition int32, timeout time.Duration) (int64, error) {
+	c, err := k.consumer("")
+	if err != nil {
+		return 0, err
+	}
+	_, high, err := c.QueryWatermarkOffsets(topic, partition, int(timeout.Milliseconds()))
+	return high, err
+}
+
+// Committed returns the offsets group committed on partitions.
+func (k *KafkaOffsets) Committed(group string, partitions []kafka.TopicPartition, timeout time.Duration) ([]kafka.TopicPartition, error) {
+	c, err := k.consumer(group)
+	if err != nil {
+		return nil, err
+	}
+	return c.Committed(partitions, int(timeout.Milliseconds()))
+}
+
+// Close closes the consumers.
+func (k *KafkaOffsets) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var errs []error
+	for _, c := range k.consumers {
+		errs = append(errs, c.Close())
+	}
+	clear(k.consumers)
+	return errors.Join(errs...)
+}
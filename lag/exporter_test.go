@@ -0,0 +1,112 @@
+package lag
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeOffsets serves offsets from maps, failing for the topics and groups in
+// its fail set.
+type fakeOffsets struct {
+	ends      map[string][]int64            // end offset by topic, then partition
+	committed map[string]map[string][]int64 // by group, then topic, then partition; -1 for none
+	fail      map[string]bool
+}
+
+func (f *fakeOffsets) Partitions(topic string, _ time.Duration) ([]int32, error) {
+	if f.fail[topic] {
+		return nil, errors.New("unknown topic")
+	}
+	var out []int32
+	for p := range f.ends[topic] {
+		out = append(out, int32(p))
+	}
+	return out, nil
+}
+
+func (f *fakeOffsets) EndOffset(topic string, partition int32, _ time.Duration) (int64, error) {
+	return f.ends[topic][partition], nil
+}
+
+func (f *fakeOffsets) Committed(group string, partitions []kafka.TopicPartition, _ time.Duration) ([]kafka.TopicPartition, error) {
+	if f.fail[group] {
+		return nil, errors.New("coordinator not available")
+	}
+	out := make([]kafka.TopicPartition, len(partitions))
+	for i, tp := range partitions {
+		tp.Offset = kafka.Offset(f.committed[group][*tp.Topic][tp.Partition])
+		out[i] = tp
+	}
+	return out, nil
+}
+
+func TestPoll(t *testing.T) {
+	offsets := &fakeOffsets{
+		ends: map[string][]int64{"rides": {100, 40}, "drivers": {7}},
+		committed: map[string]map[string][]int64{
+			"consumer": {"rides": {90, -1}},
+			"matcher":  {"rides": {100, 40}, "drivers": {3}},
+		},
+		fail: map[string]bool{},
+	}
+	e := New(Config{Groups: []Group{
+		{ID: "consumer", Topics: []string{"rides"}},
+		{ID: "matcher", Topics: []string{"rides", "drivers"}},
+	}}, offsets)
+	if err := e.Poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		group, topic, partition string
+		lag                     float64
+	}{
+		{"consumer", "rides", "0", 10},
+		{"matcher", "rides", "0", 0},
+		{"matcher", "rides", "1", 0},
+		{"matcher", "drivers", "0", 4},
+	} {
+		if got := testutil.ToFloat64(groupLag.WithLabelValues(c.group, c.topic, c.partition)); got != c.lag {
+			t.Errorf("lag of %s on %s/%s = %v, want %v", c.group, c.topic, c.partition, got, c.lag)
+		}
+	}
+	if got := testutil.ToFloat64(endOffset.WithLabelValues("rides", "1")); got != 40 {
+		t.Errorf("end offset of rides/1 = %v, want 40", got)
+	}
+	// consumer has committed nothing on rides/1, so it has no series
+	if n := testutil.CollectAndCount(committedOffset); n != 4 {
+		t.Errorf("%d committed offset series, want 4", n)
+	}
+
+	// Once drivers and matcher's offsets cannot be read, their series go
+	offsets.fail["drivers"] = true
+	offsets.fail["matcher"] = true
+	if err := e.Poll(); err == nil {
+		t.Fatal("Poll succeeded, want the errors")
+	}
+	if n := testutil.CollectAndCount(groupLag); n != 1 {
+		t.Errorf("%d lag series, want consumer's 1", n)
+	}
+	if n := testutil.CollectAndCount(endOffset); n != 2 {
+		t.Errorf("%d end offset series, want rides' 2", n)
+	}
+}
+
+func TestParseGroups(t *testing.T) {
+	groups, err := ParseGroups(" consumer:ride-events ; matcher:ride-events, driver-events;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].ID != "consumer" || len(groups[1].Topics) != 2 || groups[1].Topics[1] != "driver-events" {
+		t.Errorf("groups %+v", groups)
+	}
+	for _, bad := range []string{"consumer", "consumer:", ":ride-events"} {
+		if _, err := ParseGroups(bad); err == nil {
+			t.Errorf("ParseGroups(%q) succeeded, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx carrying the attributes of args, given as
+// to slog.Logger.With, so they can be attached once and appear on every line
+// logged for ctx: by the logger FromContext returns, and by the loggers of this
+// package when logging with a context, as slog.InfoContext does. A key already
+// in ctx takes its new value.
+func WithFields(ctx context.Context, args ...any) context.Context {
+	added := slog.Group("", args...).Value.Group()
+	if len(added) == 0 {
+		return ctx
+	}
+	fields := Fields(ctx)
+	merged := make([]slog.Attr, 0, len(fields)+len(added))
+	for _, f := range fields {
+		if !hasKey(added, f.Key) {
+			merged = append(merged, f)
+		}
+	}
+	return context.WithValue(ctx, fieldsKey{}, append(merged, added...))
+}
+
+// WithEvent returns a copy of ctx carrying the correlation fields of e: its
+// trip_id, event_id, and event_type, and, from its Meta, the correlation_id and
+// the trace_id and span_id of its traceparent. Empty fields are left out.
+func WithEvent(ctx context.Context, e events.RideEvent) context.Context {
+	var args []any
+	add := func(key, value string) {
+		if value != "" {
+			args = append(args, key, value)
+		}
+	}
+	add("trip_id", e.TripID)
+	add("event_id", e.ID)
+	add("event_type", string(e.Type))
+	add("correlation_id", e.Meta.CorrelationID)
+	traceID, spanID := parseTraceParent(e.Meta.TraceParent)
+	add("trace_id", traceID)
+	add("span_id", spanID)
+	return WithFields(ctx, args...)
+}
+
+// Fields returns the attributes WithFields attached to ctx.
+func Fields(ctx context.Context) []slog.Attr {
+	fields, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	return fields
+}
+
+// FromContext returns the default logger with the fields of ctx, for code that
+// logs without passing the context on each call.
+func FromContext(ctx context.Context) *slog.Logger {
+	l := slog.Default()
+	for _, f := range Fields(ctx) {
+		l = l.With(f)
+	}
+	return l
+}
+
+// Handle drops the lines sampling leaves out (see SetSampleRate), and adds the
+// fields of ctx not already on the handler, so lines logged with
+// FromContext's logger do not carry them twice.
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !samples.allow(r) {
+		return nil
+	}
+	if fields := Fields(ctx); len(fields) > 0 {
+		r = r.Clone()
+		for _, f := range fields {
+			if !hasKey(h.attrs, f.Key) {
+				r.AddAttrs(f)
+			}
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func hasKey(attrs []slog.Attr, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTraceParent returns the trace and parent span IDs of a W3C traceparent,
+// version-traceid-spanid-flags, or empty strings if it is not one.
+func parseTraceParent(tp string) (traceID, spanID string) {
+	parts := strings.Split(tp, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", ""
+	}
+	return parts[1], parts[2]
+}
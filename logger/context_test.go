@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// lines decodes the JSON lines in out.
+func lines(t *testing.T, out string) []map[string]any {
+	t.Helper()
+	var decoded []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("bad log line %q: %v", line, err)
+		}
+		decoded = append(decoded, m)
+	}
+	return decoded
+}
+
+func TestWithEvent(t *testing.T) {
+	buf := capture(t)
+	defaultLevel.Set(slog.LevelInfo)
+	e := events.RideEvent{ID: "event-1", TripID: "trip-1", Type: events.EventRideAccepted,
+		Meta: events.Meta{CorrelationID: "corr-1", TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	ctx := WithEvent(context.Background(), e)
+	ctx = WithFields(ctx, "attempt", 2)
+
+	log := For("consumer")
+	log.InfoContext(ctx, "with context")
+	log.Info("without context")
+	old := slog.Default()
+	slog.SetDefault(log)
+	FromContext(ctx).Info("from context")
+	slog.SetDefault(old)
+
+	got := lines(t, buf.String())
+	if len(got) != 3 {
+		t.Fatalf("expected 3 lines, got %d:\n%s", len(got), buf)
+	}
+	want := map[string]any{
+		"trip_id": "trip-1", "event_id": "event-1", "event_type": "ACCEPTED", "correlation_id": "corr-1",
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7", "attempt": float64(2),
+	}
+	for _, i := range []int{0, 2} {
+		for k, v := range want {
+			if got[i][k] != v {
+				t.Errorf("line %d: %s = %v, want %v", i, k, got[i][k], v)
+			}
+		}
+	}
+	if _, ok := got[1]["trip_id"]; ok {
+		t.Errorf("line without context has fields: %v", got[1])
+	}
+	// FromContext's logger already has the fields, so they are not added twice
+	if n := strings.Count(strings.Split(buf.String(), "\n")[2], `"trip_id"`); n != 1 {
+		t.Errorf("trip_id logged %d times by FromContext's logger", n)
+	}
+}
+
+func TestWithFields_Replaces(t *testing.T) {
+	ctx := WithFields(context.Background(), "trip_id", "trip-1", "stage", "decode")
+	ctx = WithFields(ctx, "stage", "handle")
+	fields := Fields(ctx)
+	if len(fields) != 2 || fields[0].Key != "trip_id" || fields[1].String() != "stage=handle" {
+		t.Errorf("fields = %v", fields)
+	}
+	if got := Fields(WithFields(context.Background())); got != nil {
+		t.Errorf("no fields: got %v", got)
+	}
+}
+
+func TestWithEvent_BadTraceParent(t *testing.T) {
+	ctx := WithEvent(context.Background(), events.RideEvent{ID: "event-1", Meta: events.Meta{TraceParent: "not-a-traceparent"}})
+	for _, f := range Fields(ctx) {
+		if f.Key == "trace_id" || f.Key == "span_id" || f.Key == "trip_id" {
+			t.Errorf("unexpected field %v", f)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Protocols an Exporter can send logs in.
+const (
+	// ProtocolOTLP is OTLP/HTTP with the JSON encoding, sent to a collector's
+	// /v1/logs, such as http://otel-collector:4318/v1/logs.
+	ProtocolOTLP = "otlp"
+	// ProtocolLoki is the Loki push API, such as http://loki:3100/loki/api/v1/push.
+	ProtocolLoki = "loki"
+)
+
+// ExportConfig configures an Exporter.
+type ExportConfig struct {
+	Protocol string // ProtocolOTLP or ProtocolLoki
+	Endpoint string
+	// Headers are added to each request, such as for authentication.
+	Headers map[string]string
+
+	// ServiceName and Instance are the resource attributes service.name and
+	// service.instance.id, or the service and instance labels for Loki.
+	// ServiceName defaults to the component SetComponent names, and Instance to
+	// the host name.
+	ServiceName string
+	Instance    string
+
+	BatchSize     int           // lines per request; default 512
+	FlushInterval time.Duration // longest a line waits; default 1s
+	MaxQueue      int           // lines held while the endpoint is slow, beyond which new ones are dropped; default 10000
+	Client        *http.Client  // default has a 10s timeout
+}
+
+// Exporter is an slog.Handler that sends records in batches to an OTLP
+// collector or Loki, so that logs land in the same backend as traces and
+// metrics. Records carrying trace_id and span_id fields (see WithEvent) are
+// linked to their trace. Sending happens in the background; failures are
+// reported on stderr, since logging them would loop.
+type Exporter struct {
+	cfg     ExportConfig
+	service atomic.Value // string
+
+	mu      sync.Mutex
+	queue   []exportRecord
+	dropped uint64
+	kick    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// exportRecord is a record flattened for export, with its attributes under
+// dotted keys.
+type exportRecord struct {
+	time    time.Time
+	level   slog.Level
+	message string
+	attrs   []slog.Attr
+}
+
+// NewExporter returns an Exporter for cfg and starts sending.
+func NewExporter(cfg ExportConfig) (*Exporter, error) {
+	if cfg.Protocol != ProtocolOTLP && cfg.Protocol != ProtocolLoki {
+		return nil, fmt.Errorf("logger: unknown export protocol %q", cfg.Protocol)
+	}
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("logger: no export endpoint")
+	}
+	if cfg.Instance == "" {
+		cfg.Instance, _ = os.Hostname()
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.MaxQueue <= 0 {
+		cfg.MaxQueue = 10000
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	e := &Exporter{cfg: cfg, kick: make(chan struct{}, 1), done: make(chan struct{}), stopped: make(chan struct{})}
+	e.service.Store(cfg.ServiceName)
+	go e.run()
+	return e, nil
+}
+
+// exporterFromEnv returns the Exporter LOG_EXPORT selects, "otlp" or "loki",
+// sending to LOG_EXPORT_ENDPOINT, with OTEL_SERVICE_NAME as the service name
+// when set. It returns nil when LOG_EXPORT is unset.
+func exporterFromEnv() (*Exporter, error) {
+	protocol := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_EXPORT")))
+	if protocol == "" {
+		return nil, nil
+	}
+	return NewExporter(ExportConfig{
+		Protocol:    protocol,
+		Endpoint:    os.Getenv("LOG_EXPORT_ENDPOINT"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+	})
+}
+
+// setService sets the service name, unless the config gave one.
+func (e *Exporter) setService(name string) {
+	if e.cfg.ServiceName == "" {
+		e.service.Store(name)
+	}
+}
+
+func (e *Exporter) Enabled(context.Context, slog.Level) bool { return true }
+
+func (e *Exporter) Handle(_ context.Context, r slog.Record) error {
+	rec := exportRecord{time: r.Time, level: r.Level, message: r.Message}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.attrs = flatten(rec.attrs, "", a)
+		return true
+	})
+	e.enqueue(rec)
+	return nil
+}
+
+func (e *Exporter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &boundExporter{Exporter: e, attrs: flattenAll(attrs, "")}
+}
+
+func (e *Exporter) WithGroup(name string) slog.Handler {
+	return (&boundExporter{Exporter: e}).WithGroup(name)
+}
+
+// boundExporter is an Exporter with attributes and a group from WithAttrs and
+// WithGroup.
+type boundExporter struct {
+	*Exporter
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (b *boundExporter) Handle(_ context.Context, r slog.Record) error {
+	rec := exportRecord{time: r.Time, level: r.Level, message: r.Message, attrs: append([]slog.Attr(nil), b.attrs...)}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.attrs = flatten(rec.attrs, b.prefix, a)
+		return true
+	})
+	b.enqueue(rec)
+	return nil
+}
+
+func (b *boundExporter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *b
+	c.attrs = append(append([]slog.Attr(nil), b.attrs...), flattenAll(attrs, b.prefix)...)
+	return &c
+}
+
+func (b *boundExporter) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return b
+	}
+	c := *b
+	c.prefix = b.prefix + name + "."
+	return &c
+}
+
+// flatten appends a to attrs, redacted as Init's handlers redact it, with
+// groups spelled out as dotted keys under prefix.
+func flatten(attrs []slog.Attr, prefix string, a slog.Attr) []slog.Attr {
+	a = redactAttr(nil, a)
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		return append(attrs, flattenAll(a.Value.Group(), p)...)
+	}
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	a.Key = prefix + a.Key
+	return append(attrs, a)
+}
+
+func flattenAll(attrs []slog.Attr, prefix string) []slog.Attr {
+	var out []slog.Attr
+	for _, a := range attrs {
+		out = flatten(out, prefix, a)
+	}
+	return out
+}
+
+func (e *Exporter) enqueue(rec exportRecord) {
+	e.mu.Lock()
+	if len(e.queue) >= e.cfg.MaxQueue {
+		e.dropped++
+		e.mu.Unlock()
+		return
+	}
+	e.queue = append(e.queue, rec)
+	full := len(e.queue) >= e.cfg.BatchSize
+	e.mu.Unlock()
+	if full {
+		select {
+		case e.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.done:
+			e.flush()
+			return
+		case <-ticker.C:
+		case <-e.kick:
+		}
+		e.flush()
+	}
+}
+
+// flush sends the queued records, a batch at a time.
+func (e *Exporter) flush() {
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), e.cfg.BatchSize)
+		batch := e.queue[:n:n]
+		e.queue = e.queue[n:]
+		e.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: export of %d lines failed: %v\n", n, err)
+		}
+	}
+}
+
+// Close sends what is queued and stops the Exporter, waiting until ctx is done
+// at most. Records handled afterwards are dropped.
+func (e *Exporter) Close(ctx context.Context) error {
+	e.once.Do(func() { close(e.done) })
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dropped returns how many records were dropped because the queue was full.
+func (e *Exporter) Dropped() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dropped
+}
+
+func (e *Exporter) send(batch []exportRecord) error {
+	var body any
+	if e.cfg.Protocol == ProtocolLoki {
+		body = e.lokiBody(batch)
+	} else {
+		body = e.otlpBody(batch)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.cfg.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", e.cfg.Endpoint, resp.Status)
+	}
+	return nil
+}
+
+// otlpValue is an OTLP AnyValue.
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // int64 as a string, as OTLP/JSON encodes it
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+func otlpAttr(a slog.Attr) otlpKeyValue {
+	var v otlpValue
+	switch a.Value.Kind() {
+	case slog.KindInt64:
+		s := strconv.FormatInt(a.Value.Int64(), 10)
+		v.IntValue = &s
+	case slog.KindUint64:
+		s := strconv.FormatUint(a.Value.Uint64(), 10)
+		v.IntValue = &s
+	case slog.KindFloat64:
+		f := a.Value.Float64()
+		v.DoubleValue = &f
+	case slog.KindBool:
+		b := a.Value.Bool()
+		v.BoolValue = &b
+	default:
+		s := a.Value.String()
+		v.StringValue = &s
+	}
+	return otlpKeyValue{Key: a.Key, Value: v}
+}
+
+func stringAttr(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+// otlpSeverity maps slog levels onto OTLP severity numbers: DEBUG is 5, INFO
+// 9, WARN 13, and ERROR 17, with the levels between in steps of one.
+func otlpSeverity(l slog.Level) int {
+	return min(max(9+int(l), 1), 24)
+}
+
+func (e *Exporter) otlpBody(batch []exportRecord) any {
+	type logRecord struct {
+		TimeUnixNano   string         `json:"timeUnixNano"`
+		SeverityNumber int            `json:"severityNumber"`
+		SeverityText   string         `json:"severityText"`
+		Body           otlpValue      `json:"body"`
+		Attributes     []otlpKeyValue `json:"attributes,omitempty"`
+		TraceID        string         `json:"traceId,omitempty"`
+		SpanID         string         `json:"spanId,omitempty"`
+	}
+	records := make([]logRecord, len(batch))
+	for i, rec := range batch {
+		msg := rec.message
+		lr := logRecord{
+			TimeUnixNano:   strconv.FormatInt(rec.time.UnixNano(), 10),
+			SeverityNumber: otlpSeverity(rec.level),
+			SeverityText:   rec.level.String(),
+			Body:           otlpValue{StringValue: &msg},
+		}
+		for _, a := range rec.attrs {
+			switch a.Key {
+			case "trace_id":
+				lr.TraceID = a.Value.String()
+			case "span_id":
+				lr.SpanID = a.Value.String()
+			default:
+				lr.Attributes = append(lr.Attributes, otlpAttr(a))
+			}
+		}
+		records[i] = lr
+	}
+	resource := []otlpKeyValue{stringAttr("service.instance.id", e.cfg.Instance)}
+	if service, _ := e.service.Load().(string); service != "" {
+		resource = append(resource, stringAttr("service.name", service))
+	}
+	return map[string]any{"resourceLogs": []any{map[string]any{
+		"resource": map[string]any{"attributes": resource},
+		"scopeLogs": []any{map[string]any{
+			"scope":      map[string]any{"name": "github.com/pedeveaux/kafkarideshare/logger"},
+			"logRecords": records,
+		}},
+	}}}
+}
+
+// lokiBody groups the batch into one stream per level, labelled with the
+// service, instance, and level. Each line is the record as a JSON object.
+func (e *Exporter) lokiBody(batch []exportRecord) any {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	service, _ := e.service.Load().(string)
+	byLevel := map[slog.Level]*stream{}
+	var streams []*stream
+	for _, rec := range batch {
+		s, ok := byLevel[rec.level]
+		if !ok {
+			s = &stream{Stream: map[string]string{"instance": e.cfg.Instance, "level": strings.ToLower(rec.level.String())}}
+			if service != "" {
+				s.Stream["service"] = service
+			}
+			byLevel[rec.level] = s
+			streams = append(streams, s)
+		}
+		line := map[string]any{"msg": rec.message}
+		for _, a := range rec.attrs {
+			line[a.Key] = a.Value.Any()
+		}
+		data, err := json.Marshal(line)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"msg": rec.message, "export_error": err.Error()})
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(rec.time.UnixNano(), 10), string(data)})
+	}
+	return map[string]any{"streams": streams}
+}
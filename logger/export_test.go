@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector records the bodies posted to it.
+type collector struct {
+	mu     sync.Mutex
+	bodies []map[string]any
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	c := &collector{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("invalid body %s: %v", data, err)
+		}
+		c.mu.Lock()
+		c.bodies = append(c.bodies, body)
+		c.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func TestExporter_OTLP(t *testing.T) {
+	c, srv := newCollector(t)
+	exp, err := NewExporter(ExportConfig{Protocol: ProtocolOTLP, Endpoint: srv.URL, Instance: "host-1", FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp.setService("consumer")
+	log := slog.New(exp).With("component", "consumer").WithGroup("kafka")
+	ctx := WithFields(context.Background(), "trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", "span_id", "00f067aa0ba902b7")
+	log.Warn("Commit failed", "partition", 3)
+	slog.New(&levelHandler{Handler: exp}).InfoContext(ctx, "Consumed message")
+	if err := exp.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.bodies) != 1 {
+		t.Fatalf("expected one request, got %d", len(c.bodies))
+	}
+	data, _ := json.Marshal(c.bodies[0])
+	for _, want := range []string{
+		`{"key":"service.name","value":{"stringValue":"consumer"}}`,
+		`{"key":"service.instance.id","value":{"stringValue":"host-1"}}`,
+		`"severityNumber":13`,
+		`"body":{"stringValue":"Commit failed"}`,
+		`{"key":"component","value":{"stringValue":"consumer"}}`,
+		`{"key":"kafka.partition","value":{"intValue":"3"}}`,
+		`"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`,
+		`"spanId":"00f067aa0ba902b7"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %s in %s", want, data)
+		}
+	}
+}
+
+func TestExporter_Loki(t *testing.T) {
+	c, srv := newCollector(t)
+	exp, err := NewExporter(ExportConfig{Protocol: ProtocolLoki, Endpoint: srv.URL, ServiceName: "api", Instance: "host-1", BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp.setService("ignored")
+	log := slog.New(exp)
+	log.Info("one", "n", 1)
+	log.Error("two")
+	log.Info("three")
+	if err := exp.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.bodies) != 2 {
+		t.Fatalf("expected a request per batch of 2, got %d", len(c.bodies))
+	}
+	streams := c.bodies[0]["streams"].([]any)
+	if len(streams) != 2 {
+		t.Fatalf("expected a stream per level, got %v", streams)
+	}
+	info := streams[0].(map[string]any)
+	labels, _ := json.Marshal(info["stream"])
+	if string(labels) != `{"instance":"host-1","level":"info","service":"api"}` {
+		t.Errorf("labels = %s", labels)
+	}
+	line := info["values"].([]any)[0].([]any)[1].(string)
+	if line != `{"msg":"one","n":1}` {
+		t.Errorf("line = %s", line)
+	}
+}
+
+func TestExporter_Config(t *testing.T) {
+	if _, err := NewExporter(ExportConfig{Protocol: "syslog", Endpoint: "http://localhost"}); err == nil {
+		t.Error("expected an error for an unknown protocol")
+	}
+	if _, err := NewExporter(ExportConfig{Protocol: ProtocolOTLP}); err == nil {
+		t.Error("expected an error without an endpoint")
+	}
+	if exp, err := exporterFromEnv(); exp != nil || err != nil {
+		t.Errorf("LOG_EXPORT unset: got %v, %v", exp, err)
+	}
+	t.Setenv("LOG_EXPORT", "OTLP")
+	t.Setenv("LOG_EXPORT_ENDPOINT", "http://otel-collector:4318/v1/logs")
+	t.Setenv("OTEL_SERVICE_NAME", "rides")
+	exp, err := exporterFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exp.Close(context.Background())
+	if exp.cfg.Protocol != ProtocolOTLP || exp.service.Load() != "rides" {
+		t.Errorf("got %+v", exp.cfg)
+	}
+}
@@ -0,0 +1,219 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// minLevel is the level of the handlers Init builds, below any level a
+// component can be set to, so that levelHandler alone decides what is logged.
+const minLevel = slog.LevelDebug - 4
+
+var (
+	defaultLevel slog.LevelVar
+
+	levelsMu sync.RWMutex
+	levels   = map[string]slog.Level{} // component levels, overriding defaultLevel
+)
+
+// levelHandler logs records at or above the level of its component, with the
+// fields of their context (see WithFields).
+type levelHandler struct {
+	slog.Handler
+	component string
+
+	attrs   []slog.Attr // added outside any group, which context fields skip
+	grouped bool
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= Level(h.component) && h.Handler.Enabled(ctx, l)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.Handler = h.Handler.WithAttrs(attrs)
+	if !h.grouped {
+		c.attrs = append(slices.Clip(h.attrs), attrs...)
+	}
+	return &c
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.Handler = h.Handler.WithGroup(name)
+	c.grouped = c.grouped || name != ""
+	return &c
+}
+
+// Level returns the level component logs at: its own, or the default level for
+// a component without one and for "".
+func Level(component string) slog.Level {
+	levelsMu.RLock()
+	l, ok := levels[component]
+	levelsMu.RUnlock()
+	if !ok {
+		return defaultLevel.Level()
+	}
+	return l
+}
+
+// SetLevel sets the level of component, or the default level for "".
+func SetLevel(component string, level slog.Level) {
+	if component == "" {
+		defaultLevel.Set(level)
+		return
+	}
+	levelsMu.Lock()
+	levels[component] = level
+	levelsMu.Unlock()
+}
+
+// SetLevels replaces the component levels with those of spec, a comma-separated
+// list of component=level pairs such as "producer=debug,consumer=info". Levels
+// are slog level names, in any case.
+func SetLevels(spec string) error {
+	parsed, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+	levelsMu.Lock()
+	levels = parsed
+	levelsMu.Unlock()
+	return nil
+}
+
+// ParseLevels parses the component levels of spec, as SetLevels takes them.
+func ParseLevels(spec string) (map[string]slog.Level, error) {
+	parsed := map[string]slog.Level{}
+	for _, pair := range strings.Split(spec, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		component, name, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(component) == "" {
+			return nil, fmt.Errorf("logger: %q is not component=level", pair)
+		}
+		l, err := parseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		parsed[strings.TrimSpace(component)] = l
+	}
+	return parsed, nil
+}
+
+func parseLevel(name string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return 0, fmt.Errorf("logger: unknown level %q", name)
+	}
+	return l, nil
+}
+
+// applyEnv sets the default level from LOG_LEVEL, the component levels from
+// LOG_LEVELS, the sample rates from LOG_SAMPLE (see SetSampleRates), and the
+// rate limit from LOG_RATE_LIMIT, keeping the current ones when a variable is
+// unset or invalid.
+func applyEnv() {
+	if raw := os.Getenv("LOG_LEVEL"); raw != "" {
+		if l, err := parseLevel(raw); err != nil {
+			slog.Warn("Ignoring invalid LOG_LEVEL", "value", raw, "error", err)
+		} else {
+			defaultLevel.Set(l)
+		}
+	}
+	if raw, ok := os.LookupEnv("LOG_LEVELS"); ok {
+		if err := SetLevels(raw); err != nil {
+			slog.Warn("Ignoring invalid LOG_LEVELS", "value", raw, "error", err)
+		}
+	}
+	if raw, ok := os.LookupEnv("LOG_SAMPLE"); ok {
+		if err := SetSampleRates(raw); err != nil {
+			slog.Warn("Ignoring invalid LOG_SAMPLE", "value", raw, "error", err)
+		}
+	}
+	if raw := os.Getenv("LOG_RATE_LIMIT"); raw != "" {
+		if n, err := strconv.Atoi(raw); err != nil || n < 0 {
+			slog.Warn("Ignoring invalid LOG_RATE_LIMIT", "value", raw)
+		} else {
+			SetRateLimit(n)
+		}
+	}
+}
+
+var watchOnce sync.Once
+
+// watchSIGHUP reloads the levels on every SIGHUP, from the environment with
+// .env, if there is one, loaded over it.
+func watchSIGHUP() {
+	watchOnce.Do(func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := godotenv.Overload(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					slog.Warn("Failed to reload .env", "error", err)
+				}
+				applyEnv()
+				slog.Info("Reloaded log levels", "levels", levelsView())
+			}
+		}()
+	})
+}
+
+// levelsJSON is the body of LevelHandler's responses.
+type levelsJSON struct {
+	Default    slog.Level            `json:"default"`
+	Components map[string]slog.Level `json:"components"`
+}
+
+func levelsView() levelsJSON {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	return levelsJSON{Default: defaultLevel.Level(), Components: maps.Clone(levels)}
+}
+
+// LevelHandler serves the log levels. GET returns the default level and the
+// component levels as JSON. PUT sets the level named by the level query
+// parameter, for the component named by the component parameter or by default
+// for all:
+//
+//	curl -X PUT 'localhost:2112/loglevel?component=rideconsumer&level=debug'
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			l, err := parseLevel(r.URL.Query().Get("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			component := r.URL.Query().Get("component")
+			SetLevel(component, l)
+			slog.Info("Changed log level", "component", component, "level", l)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(levelsView())
+	})
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// capture points the loggers at a buffer and restores the levels afterwards.
+func capture(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	oldBase, oldDefault := base, defaultLevel.Level()
+	base = slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: minLevel})
+	t.Cleanup(func() {
+		base = oldBase
+		defaultLevel.Set(oldDefault)
+		_ = SetLevels("")
+	})
+	return &buf
+}
+
+func TestParseLevels(t *testing.T) {
+	got, err := ParseLevels(" producer=debug, consumer=INFO ,,rideconsumer=warn")
+	if err != nil {
+		t.Fatalf("ParseLevels failed: %v", err)
+	}
+	want := map[string]slog.Level{"producer": slog.LevelDebug, "consumer": slog.LevelInfo, "rideconsumer": slog.LevelWarn}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for component, l := range want {
+		if got[component] != l {
+			t.Errorf("%s: got %s, want %s", component, got[component], l)
+		}
+	}
+
+	for _, bad := range []string{"producer", "=debug", "producer=loud"} {
+		if _, err := ParseLevels(bad); err == nil {
+			t.Errorf("ParseLevels(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestFor_ComponentLevels(t *testing.T) {
+	buf := capture(t)
+	defaultLevel.Set(slog.LevelInfo)
+	if err := SetLevels("producer=debug,consumer=error"); err != nil {
+		t.Fatal(err)
+	}
+
+	For("producer").Debug("producer debug")
+	For("consumer").Warn("consumer warn")
+	For("consumer").Error("consumer error")
+	For("api").Debug("api debug")
+	For("api").Info("api info")
+
+	out := buf.String()
+	for _, msg := range []string{"producer debug", "consumer error", "api info"} {
+		if !strings.Contains(out, msg) {
+			t.Errorf("expected %q to be logged:\n%s", msg, out)
+		}
+	}
+	for _, msg := range []string{"consumer warn", "api debug"} {
+		if strings.Contains(out, msg) {
+			t.Errorf("expected %q to be filtered:\n%s", msg, out)
+		}
+	}
+	if !strings.Contains(out, `"component":"producer"`) {
+		t.Errorf("expected a component attribute:\n%s", out)
+	}
+
+	// Levels apply to loggers already handed out, and to their children
+	log := For("api").With("trip_id", "trip-1")
+	SetLevel("api", slog.LevelDebug)
+	log.Debug("api debug later")
+	if !strings.Contains(buf.String(), "api debug later") {
+		t.Errorf("expected the new level to apply:\n%s", buf.String())
+	}
+}
+
+func TestInit_Env(t *testing.T) {
+	capture(t)
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_LEVELS", "producer=debug")
+	t.Setenv("LOG_FORMAT", "text")
+	Init(slog.LevelInfo, "json")
+	if _, ok := base.(*RedactHandler); !ok {
+		t.Errorf("expected redaction: got %T", base)
+	}
+	if hs := Handlers(); len(hs) != 2 {
+		t.Errorf("expected stdout and metrics, got %v", hs)
+	} else if _, ok := hs[0].(*slog.TextHandler); !ok {
+		t.Errorf("LOG_FORMAT=text: got %T", hs[0])
+	}
+	if Level("") != slog.LevelWarn || Level("api") != slog.LevelWarn || Level("producer") != slog.LevelDebug {
+		t.Errorf("levels: default %s, api %s, producer %s", Level(""), Level("api"), Level("producer"))
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	capture(t)
+	defaultLevel.Set(slog.LevelInfo)
+	srv := httptest.NewServer(LevelHandler())
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"?component=producer&level=debug", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Default    string            `json:"default"`
+		Components map[string]string `json:"components"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT: status %d, err %v", resp.StatusCode, err)
+	}
+	if body.Default != "INFO" || body.Components["producer"] != "DEBUG" {
+		t.Errorf("got %+v", body)
+	}
+	if Level("producer") != slog.LevelDebug {
+		t.Errorf("producer level = %s, want DEBUG", Level("producer"))
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, srv.URL+"?level=chatty", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown level: status %d, want 400", resp.StatusCode)
+	}
+}
@@ -1,29 +1,128 @@
 package logger
 
 import (
+	"context"
+	"io"
 	"log/slog"
 	"os"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
 )
 
 var Logger *slog.Logger
 
+// base is the handler Init built, which every component's logger shares.
+var base slog.Handler
+
+// exporter is the Exporter LOG_EXPORT selects, or nil.
+var exporter *Exporter
+
+// Init sets up Logger and the slog default logger. LOG_LEVEL and LOG_FORMAT,
+// when set, override level and format, and LOG_LEVELS sets the levels of
+// components (see For and SetLevels), so load .env before calling Init.
+// Levels can then be changed at runtime with SIGHUP, which reloads them from
+// the environment and .env, or through LevelHandler. Logs go to stdout, to a
+// RotatingFile, or to both as LOG_OUTPUT selects (see outputFromEnv), the file
+// in LOG_FILE_FORMAT if set, and also to an OTLP collector or Loki as
+// LOG_EXPORT selects (see exporterFromEnv); call Flush before exiting to send
+// the last of them. Warnings and errors are counted in log_lines_total. Services
+// can add sinks of their own with AddHandler.
+// Attributes with personal data are masked before they reach any sink (see
+// RedactHandler and LOG_REDACT_KEYS).
 func Init(level slog.Level, format string) {
-	var handler slog.Handler
-	switch format {
-	case "json":
-		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: level,
-		})
-	default:
-		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: level,
-		})
-	}
-	Logger = slog.New(handler)
+	if raw := os.Getenv("LOG_FORMAT"); raw != "" {
+		format = raw
+	}
+	stdout, file, outErr := outputFromEnv()
+	exp, expErr := exporterFromEnv()
+	var handlers []slog.Handler
+	if stdout != nil {
+		handlers = append(handlers, newHandler(stdout, format))
+	}
+	if file != nil {
+		fileFormat := format
+		if raw := os.Getenv("LOG_FILE_FORMAT"); raw != "" {
+			fileFormat = raw
+		}
+		handlers = append(handlers, newHandler(file, fileFormat))
+	}
+	if exp != nil {
+		exporter = exp
+		handlers = append(handlers, exp)
+	}
+	handlers = append(handlers, metricsHandler{})
+	sinks.setInit(handlers)
+	base = NewRedactHandler(sinks.handler(), redactKeysFromEnv()...)
+	defaultLevel.Set(level)
+	Logger = slog.New(&levelHandler{Handler: base})
 	slog.SetDefault(Logger)
+	if outErr != nil {
+		slog.Warn("Invalid log output settings, logging to stdout", "error", outErr)
+	}
+	if expErr != nil {
+		slog.Warn("Invalid log export settings, not exporting", "error", expErr)
+	}
+	applyEnv()
+	watchSIGHUP()
 }
 
-func Fatal(msg string, args ...any) {
-	Logger.Error(msg, args...)
-	os.Exit(1)
-}
\ No newline at end of file
+// SetComponent makes Logger and the slog default logger those of component,
+// such as the name of the service, so the whole process logs at its level.
+func SetComponent(component string) {
+	Logger = For(component)
+	slog.SetDefault(Logger)
+	if exporter != nil {
+		exporter.setService(component)
+	}
+}
+
+// Flush sends the lines still queued for export, waiting five seconds at most,
+// and stops exporting. Shutdown calls it after the shutdown hooks.
+func Flush() {
+	if exporter == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := exporter.Close(ctx); err != nil {
+		Logger.Warn("Log export did not finish", "error", err, "dropped", exporter.Dropped())
+	}
+}
+
+// For returns a logger for component, such as "producer" or "rideconsumer",
+// that adds a component attribute to its records and logs at the component's
+// level, or at the default level when the component has none.
+func For(component string) *slog.Logger {
+	h := base
+	if h == nil {
+		h = slog.Default().Handler()
+	}
+	return slog.New((&levelHandler{Handler: h, component: component}).WithAttrs([]slog.Attr{slog.String("component", component)}))
+}
+
+// newHandler returns a handler writing to w in format, "json" or "text".
+func newHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level:       minLevel, // levelHandler does the filtering
+		ReplaceAttr: redactAttr,
+	}
+	if format == "json" {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// redactAttr masks the personal data of payloads logged on their own. Events
+// redact themselves through events.RideEvent.LogValue.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	if p, ok := a.Value.Any().(interface {
+		Redacted() events.RideEventPayload
+	}); ok {
+		a.Value = slog.AnyValue(p.Redacted())
+	}
+	return a
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var logLines = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "log_lines_total",
+	Help: "Number of warning and error lines logged, by component and level.",
+}, []string{"component", "level"})
+
+// metricsHandler is the sink counting warning and error lines in
+// log_lines_total, so error-rate alerts need not parse logs. The component is
+// that of For, or "" for lines logged before SetComponent.
+type metricsHandler struct {
+	component string
+	grouped   bool
+}
+
+func (h metricsHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= slog.LevelWarn
+}
+
+func (h metricsHandler) Handle(_ context.Context, r slog.Record) error {
+	level := "warn"
+	if r.Level >= slog.LevelError {
+		level = "error"
+	}
+	logLines.WithLabelValues(h.component, level).Inc()
+	return nil
+}
+
+func (h metricsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.grouped {
+		return h
+	}
+	for _, a := range attrs {
+		if a.Key == "component" {
+			h.component = a.Value.String()
+		}
+	}
+	return h
+}
+
+func (h metricsHandler) WithGroup(name string) slog.Handler {
+	h.grouped = h.grouped || name != ""
+	return h
+}
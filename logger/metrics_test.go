@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	count := func(component, level string) float64 {
+		return testutil.ToFloat64(logLines.WithLabelValues(component, level))
+	}
+	warns, errs, errsDefault := count("janitor", "warn"), count("janitor", "error"), count("", "error")
+
+	log := slog.New(Tee(metricsHandler{})).With("component", "janitor")
+	log.Info("Sweeping")
+	log.Warn("Slow sweep")
+	log.WithGroup("db").With("component", "ignored").Error("Sweep failed")
+	log.Log(context.Background(), slog.LevelError+4, "Sweep failed badly")
+	slog.New(metricsHandler{}).Error("No component")
+
+	if got := count("janitor", "warn") - warns; got != 1 {
+		t.Errorf("warn lines = %v, want 1", got)
+	}
+	if got := count("janitor", "error") - errs; got != 2 {
+		t.Errorf("error lines = %v, want 2", got)
+	}
+	if got := count("", "error") - errsDefault; got != 1 {
+		t.Errorf("error lines without a component = %v, want 1", got)
+	}
+	if got := count("janitor", "info"); got != 0 {
+		t.Errorf("expected info lines not to be counted, got %v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// DefaultRedactKeys are the attribute keys RedactHandler masks unless given
+// others: those of the personal data in ride payloads.
+var DefaultRedactKeys = []string{"passenger", "pickup_location", "dropoff_location"}
+
+// RedactHandler passes records to Handler with the values of attributes whose
+// key is one of its keys replaced by events.RedactedText, at any depth of
+// groups. Keys match case-insensitively. It catches personal data logged as
+// plain attributes, such as slog.String("pickup_location", loc), which
+// events.RideEvent.LogValue cannot redact.
+type RedactHandler struct {
+	slog.Handler
+	keys map[string]bool
+}
+
+// NewRedactHandler returns a RedactHandler passing records to h with the
+// values of keys masked, or of DefaultRedactKeys when there are none.
+func NewRedactHandler(h slog.Handler, keys ...string) *RedactHandler {
+	if len(keys) == 0 {
+		keys = DefaultRedactKeys
+	}
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			set[k] = true
+		}
+	}
+	return &RedactHandler{Handler: h, keys: set}
+}
+
+// redactKeysFromEnv returns DefaultRedactKeys with those of LOG_REDACT_KEYS, a
+// comma-separated list, added.
+func redactKeysFromEnv() []string {
+	keys := append([]string(nil), DefaultRedactKeys...)
+	for _, k := range strings.Split(os.Getenv("LOG_REDACT_KEYS"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	masked := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		masked.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, masked)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = h.redact(a)
+	}
+	return &RedactHandler{Handler: h.Handler.WithAttrs(masked), keys: h.keys}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{Handler: h.Handler.WithGroup(name), keys: h.keys}
+}
+
+// redact returns a with its value masked if its key is one of h's, and with
+// the attributes of a group value redacted in turn.
+func (h *RedactHandler) redact(a slog.Attr) slog.Attr {
+	if h.keys[strings.ToLower(a.Key)] {
+		if a.Value.Kind() == slog.KindString && a.Value.String() == "" {
+			return a
+		}
+		return slog.String(a.Key, events.RedactedText)
+	}
+	if a.Value.Kind() == slog.KindLogValuer {
+		a.Value = a.Value.Resolve()
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		masked := make([]slog.Attr, len(group))
+		for i, g := range group {
+			masked[i] = h.redact(g)
+		}
+		a.Value = slog.GroupValue(masked...)
+	}
+	return a
+}
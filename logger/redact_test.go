@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// trip logs as a group holding a passenger, as a LogValuer.
+type trip struct{ passenger string }
+
+func (t trip) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", "trip-1"), slog.String("passenger", t.passenger))
+}
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewRedactHandler(slog.NewJSONHandler(&buf, nil))).With("Pickup_Location", "1 Main St")
+	ctx := WithFields(context.Background(), "dropoff_location", "2 High St")
+	slog.New(&levelHandler{Handler: log.Handler()}).InfoContext(ctx, "Ride requested",
+		"passenger", "rider-0001",
+		"passenger_id", "rider-0001",
+		"trip", trip{passenger: "rider-0002"},
+		slog.Group("payload", "pickup_location", "3 Low St", "fare", 12.5),
+		"dropoff_location", "",
+	)
+
+	out := buf.String()
+	for _, leaked := range []string{"1 Main St", "2 High St", "3 Low St", `"passenger":"rider`} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("%s leaked in %s", leaked, out)
+		}
+	}
+	for _, want := range []string{
+		`"Pickup_Location":"[redacted]"`,
+		`"passenger":"[redacted]"`,
+		`"passenger_id":"rider-0001"`,
+		`"trip":{"id":"trip-1","passenger":"[redacted]"}`,
+		`"payload":{"pickup_location":"[redacted]","fare":12.5}`,
+		`"dropoff_location":""`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in %s", want, out)
+		}
+	}
+}
+
+func TestRedactKeysFromEnv(t *testing.T) {
+	t.Setenv("LOG_REDACT_KEYS", " email, phone ,")
+	keys := redactKeysFromEnv()
+	if strings.Join(keys, ",") != "passenger,pickup_location,dropoff_location,email,phone" {
+		t.Errorf("keys = %v", keys)
+	}
+
+	var buf bytes.Buffer
+	slog.New(NewRedactHandler(slog.NewJSONHandler(&buf, nil), "email")).Info("Signed up", "email", "a@b.c", "passenger", "rider-0001")
+	if out := buf.String(); !strings.Contains(out, `"email":"[redacted]"`) || !strings.Contains(out, `"passenger":"rider-0001"`) {
+		t.Errorf("expected only the given keys to be redacted: %s", out)
+	}
+}
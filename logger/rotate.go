@@ -0,0 +1,247 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Defaults of RotatingFile, which LOG_FILE_MAX_SIZE_MB and LOG_FILE_MAX_BACKUPS override.
+const (
+	DefaultMaxSizeMB  = 100
+	DefaultMaxBackups = 5
+)
+
+// outputFromEnv returns where Init writes logs, as LOG_OUTPUT selects: to
+// stdout (the default), "file", or "both". stdout is nil when logging only to
+// the file, and file is nil when not logging to one. The file is a
+// RotatingFile at LOG_FILE, rotated at LOG_FILE_MAX_SIZE_MB megabytes, keeping
+// LOG_FILE_MAX_BACKUPS backups, gzipped when LOG_FILE_COMPRESS is true.
+// Invalid settings return stdout with the error.
+func outputFromEnv() (stdout io.Writer, file *RotatingFile, err error) {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_OUTPUT")))
+	switch mode {
+	case "", "stdout":
+		return os.Stdout, nil, nil
+	case "file", "both":
+	default:
+		return os.Stdout, nil, fmt.Errorf("unknown LOG_OUTPUT %q", mode)
+	}
+	f := &RotatingFile{
+		Path:       os.Getenv("LOG_FILE"),
+		MaxSize:    DefaultMaxSizeMB << 20,
+		MaxBackups: DefaultMaxBackups,
+	}
+	if f.Path == "" {
+		return os.Stdout, nil, errors.New("LOG_OUTPUT=" + mode + " needs LOG_FILE")
+	}
+	for key, set := range map[string]func(int){
+		"LOG_FILE_MAX_SIZE_MB": func(n int) { f.MaxSize = int64(n) << 20 },
+		"LOG_FILE_MAX_BACKUPS": func(n int) { f.MaxBackups = n },
+	} {
+		if raw := os.Getenv(key); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				return os.Stdout, nil, fmt.Errorf("invalid %s %q", key, raw)
+			}
+			set(n)
+		}
+	}
+	if raw := os.Getenv("LOG_FILE_COMPRESS"); raw != "" {
+		compress, err := strconv.ParseBool(raw)
+		if err != nil {
+			return os.Stdout, nil, fmt.Errorf("invalid LOG_FILE_COMPRESS %q", raw)
+		}
+		f.Compress = compress
+	}
+	// Fail now rather than on the first line if the file cannot be opened
+	if _, err := f.Write(nil); err != nil {
+		return os.Stdout, nil, err
+	}
+	if mode == "both" {
+		return os.Stdout, f, nil
+	}
+	return nil, f, nil
+}
+
+// RotatingFile is an io.WriteCloser appending to the file at Path, for hosts
+// without a log collector. When a write would take the file past MaxSize
+// bytes, the file is renamed to Path.1, earlier backups move up by one, those
+// past MaxBackups are deleted, and a new file is started. With Compress,
+// backups are gzipped, as Path.1.gz and so on, in the background so writes go
+// on meanwhile; the next rotation, and Close, wait for it. A backup that fails
+// to compress is reported on stderr and kept as it is.
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64 // in bytes; 0 never rotates
+	MaxBackups int   // 0 keeps none
+	Compress   bool
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	// compressing is closed once the backup being gzipped, if any, is.
+	compressing chan struct{}
+}
+
+// compressFile gzips a backup; tests replace it.
+var compressFile = gzipFile
+
+// Write appends p to the file, opening it on first use and rotating it first
+// if p would take it past MaxSize. A single write larger than MaxSize is
+// written whole to a new file.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close waits for the backup being compressed, if any, and closes the file. A
+// later Write opens it again.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waitCompress()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate closes the file, shifts the backups, and opens a new file.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	// The backups cannot move while the last one is being compressed
+	f.waitCompress()
+	// Drop the oldest backup, which shifting would push past MaxBackups
+	for _, ext := range []string{"", ".gz"} {
+		if err := removeIfExists(f.backup(f.MaxBackups) + ext); err != nil {
+			return err
+		}
+	}
+	for i := f.MaxBackups - 1; i >= 1; i-- {
+		for _, ext := range []string{"", ".gz"} {
+			if err := renameIfExists(f.backup(i)+ext, f.backup(i+1)+ext); err != nil {
+				return err
+			}
+		}
+	}
+	if f.MaxBackups == 0 {
+		if err := os.Remove(f.Path); err != nil {
+			return err
+		}
+	} else {
+		if err := os.Rename(f.Path, f.backup(1)); err != nil {
+			return err
+		}
+		if f.Compress {
+			f.compress(f.backup(1))
+		}
+	}
+	return f.open()
+}
+
+// compress gzips the backup at path in the background.
+func (f *RotatingFile) compress(path string) {
+	done := make(chan struct{})
+	f.compressing = done
+	go func() {
+		defer close(done)
+		if err := compressFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: compress %s: %v\n", path, err)
+		}
+	}()
+}
+
+// waitCompress waits for the backup being compressed, if any.
+func (f *RotatingFile) waitCompress() {
+	if f.compressing != nil {
+		<-f.compressing
+		f.compressing = nil
+	}
+}
+
+func (f *RotatingFile) backup(i int) string {
+	return fmt.Sprintf("%s.%d", f.Path, i)
+}
+
+// gzipFile replaces the file at path with path.gz.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func renameIfExists(from, to string) error {
+	if err := os.Rename(from, to); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
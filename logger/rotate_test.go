@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("gunzip %s: %v", path, err)
+		}
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "logs", "app.log")
+		f := &RotatingFile{Path: path, MaxSize: 10, MaxBackups: 2, Compress: compress}
+		for _, line := range []string{"line one\n", "line two\n", "line three\n", "line four\n"} {
+			if _, err := f.Write([]byte(line)); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		ext := ""
+		if compress {
+			ext = ".gz"
+		}
+		// Each line fills the file, so each write after the first rotates, and
+		// "line one" is dropped with the third rotation
+		want := map[string]string{path: "line four\n", path + ".1" + ext: "line three\n", path + ".2" + ext: "line two\n"}
+		for p, content := range want {
+			if got := readFile(t, p); got != content {
+				t.Errorf("compress=%v: %s = %q, want %q", compress, filepath.Base(p), got, content)
+			}
+		}
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		if len(entries) != len(want) {
+			t.Errorf("compress=%v: expected %d files, got %d", compress, len(want), len(entries))
+		}
+	}
+}
+
+func TestRotatingFile_WritesWhileCompressing(t *testing.T) {
+	release := make(chan struct{})
+	defer func(orig func(string) error) { compressFile = orig }(compressFile)
+	compressFile = func(path string) error {
+		<-release
+		return gzipFile(path)
+	}
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	f := &RotatingFile{Path: path, MaxSize: 15, MaxBackups: 2, Compress: true}
+	for _, line := range []string{"line one\n", "line two\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	// "line one" is being compressed, which must not hold up the next line
+	written := make(chan error)
+	go func() {
+		_, err := f.Write([]byte("more\n"))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("Write blocked while a backup was compressed")
+	}
+
+	close(release)
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path+".1.gz"); got != "line one\n" {
+		t.Errorf("app.log.1.gz = %q, want line one", got)
+	}
+	if got := readFile(t, path); got != "line two\nmore\n" {
+		t.Errorf("app.log = %q", got)
+	}
+}
+
+func TestRotatingFile_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("before\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := &RotatingFile{Path: path, MaxSize: 1 << 20}
+	if _, err := f.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if got := readFile(t, path); got != "before\nafter\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestOutputFromEnv(t *testing.T) {
+	if stdout, file, err := outputFromEnv(); stdout != os.Stdout || file != nil || err != nil {
+		t.Errorf("default: got %v, %v, %v", stdout, file, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv("LOG_OUTPUT", "file")
+	t.Setenv("LOG_FILE", path)
+	t.Setenv("LOG_FILE_MAX_SIZE_MB", "2")
+	t.Setenv("LOG_FILE_COMPRESS", "true")
+	stdout, f, err := outputFromEnv()
+	if err != nil {
+		t.Fatalf("outputFromEnv failed: %v", err)
+	}
+	if stdout != nil || f == nil || f.MaxSize != 2<<20 || f.MaxBackups != DefaultMaxBackups || !f.Compress {
+		t.Errorf("got %v, %#v", stdout, f)
+	}
+	f.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the file to be created: %v", err)
+	}
+
+	t.Setenv("LOG_OUTPUT", "both")
+	if stdout, f, err := outputFromEnv(); stdout != os.Stdout || f == nil || err != nil {
+		t.Errorf("both: got %v, %v, %v", stdout, f, err)
+	} else {
+		f.Close()
+	}
+
+	t.Setenv("LOG_FILE_MAX_BACKUPS", "-1")
+	if stdout, file, err := outputFromEnv(); stdout != os.Stdout || file != nil || err == nil {
+		t.Errorf("invalid backups: got %v, %v, %v", stdout, file, err)
+	}
+	t.Setenv("LOG_FILE", "")
+	if stdout, file, err := outputFromEnv(); stdout != os.Stdout || file != nil || err == nil {
+		t.Errorf("no LOG_FILE: got %v, %v, %v", stdout, file, err)
+	}
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sampler drops some of the lines below slog.LevelWarn, so per-message logging
+// on a hot path does not slow it down: lines whose message has a sample rate
+// of n are logged one in n, and no message is logged more than perSecond times
+// a second. Warnings and errors are always logged.
+type sampler struct {
+	mu        sync.Mutex
+	every     map[string]uint64 // sample rates by message
+	perSecond int               // 0 for no limit
+	seen      map[string]uint64 // lines seen by message, for every
+	second    time.Time         // the second counted in logged
+	logged    map[string]int    // lines logged by message in second
+	dropped   uint64
+}
+
+var samples = &sampler{every: map[string]uint64{}, seen: map[string]uint64{}, logged: map[string]int{}}
+
+// allow reports whether r is to be logged.
+func (s *sampler) allow(r slog.Record) bool {
+	if r.Level >= slog.LevelWarn {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := s.every[r.Message]; n > 1 {
+		s.seen[r.Message]++
+		if (s.seen[r.Message]-1)%n != 0 {
+			s.dropped++
+			return false
+		}
+	}
+	if s.perSecond > 0 {
+		if sec := r.Time.Truncate(time.Second); !sec.Equal(s.second) {
+			s.second = sec
+			clear(s.logged)
+		}
+		if s.logged[r.Message] >= s.perSecond {
+			s.dropped++
+			return false
+		}
+		s.logged[r.Message]++
+	}
+	return true
+}
+
+// SetSampleRate logs the lines below slog.LevelWarn with message msg one in
+// every, starting with the first. An every of 0 or 1 logs them all.
+func SetSampleRate(msg string, every int) {
+	samples.mu.Lock()
+	defer samples.mu.Unlock()
+	if every <= 1 {
+		delete(samples.every, msg)
+	} else {
+		samples.every[msg] = uint64(every)
+	}
+	delete(samples.seen, msg)
+}
+
+// SetSampleRates replaces the sample rates with those of spec, a
+// comma-separated list of message=every pairs such as
+// "Delivery successful=100,Consumed message=10".
+func SetSampleRates(spec string) error {
+	every := map[string]uint64{}
+	for _, pair := range strings.Split(spec, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		msg, raw, ok := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if !ok || strings.TrimSpace(msg) == "" || err != nil || n < 1 {
+			return fmt.Errorf("logger: %q is not message=every", pair)
+		}
+		if n > 1 {
+			every[strings.TrimSpace(msg)] = uint64(n)
+		}
+	}
+	samples.mu.Lock()
+	defer samples.mu.Unlock()
+	samples.every = every
+	clear(samples.seen)
+	return nil
+}
+
+// SetRateLimit logs at most perSecond lines below slog.LevelWarn a second with
+// any one message; 0 removes the limit.
+func SetRateLimit(perSecond int) {
+	samples.mu.Lock()
+	defer samples.mu.Unlock()
+	samples.perSecond = max(perSecond, 0)
+}
+
+// Dropped returns how many lines sampling and rate limiting have dropped.
+func Dropped() uint64 {
+	samples.mu.Lock()
+	defer samples.mu.Unlock()
+	return samples.dropped
+}
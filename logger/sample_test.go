@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// resetSampling clears the sample rates and rate limit after a test.
+func resetSampling(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		_ = SetSampleRates("")
+		SetRateLimit(0)
+	})
+}
+
+func TestSampleRate(t *testing.T) {
+	buf := capture(t)
+	resetSampling(t)
+	defaultLevel.Set(slog.LevelInfo)
+	SetSampleRate("Delivery successful", 10)
+
+	log := For("producer")
+	for range 25 {
+		log.Info("Delivery successful")
+		log.Info("Other")
+	}
+	log.Error("Delivery successful")
+	dropped := Dropped()
+
+	out := buf.String()
+	if n := strings.Count(out, `"level":"INFO","msg":"Delivery successful"`); n != 3 {
+		t.Errorf("logged %d of 25 sampled lines, want 3", n)
+	}
+	if n := strings.Count(out, `"msg":"Other"`); n != 25 {
+		t.Errorf("logged %d of 25 unsampled lines", n)
+	}
+	if !strings.Contains(out, `"level":"ERROR","msg":"Delivery successful"`) {
+		t.Error("expected errors to be logged whatever the sample rate")
+	}
+	if dropped < 22 {
+		t.Errorf("Dropped() = %d, want at least 22", dropped)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	buf := capture(t)
+	resetSampling(t)
+	defaultLevel.Set(slog.LevelInfo)
+	SetRateLimit(5)
+
+	log := For("consumer")
+	for range 20 {
+		log.Info("Consumed message")
+	}
+	log.Warn("Consumed message")
+
+	out := buf.String()
+	if n := strings.Count(out, `"level":"INFO"`); n < 5 || n > 10 {
+		// Two seconds may start during the loop, each allowing five
+		t.Errorf("logged %d of 20 lines with a limit of 5 a second", n)
+	}
+	if !strings.Contains(out, `"level":"WARN"`) {
+		t.Error("expected warnings to be logged whatever the limit")
+	}
+}
+
+func TestSetSampleRates(t *testing.T) {
+	resetSampling(t)
+	if err := SetSampleRates("Delivery successful=100, Consumed message=10,Noisy=1"); err != nil {
+		t.Fatalf("SetSampleRates failed: %v", err)
+	}
+	if samples.every["Delivery successful"] != 100 || samples.every["Consumed message"] != 10 || len(samples.every) != 2 {
+		t.Errorf("rates = %v", samples.every)
+	}
+	for _, bad := range []string{"Delivery successful", "=10", "Noisy=0", "Noisy=often"} {
+		if err := SetSampleRates(bad); err == nil {
+			t.Errorf("SetSampleRates(%q): expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShutdownTimeout bounds how long Shutdown waits for all hooks together.
+const ShutdownTimeout = 10 * time.Second
+
+// shutdownWait is ShutdownTimeout; tests shorten it.
+var shutdownWait = ShutdownTimeout
+
+type shutdownHook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+var (
+	hooksMu sync.Mutex
+	hooks   []shutdownHook
+)
+
+// exit ends the process after Fatal; tests replace it.
+var exit = os.Exit
+
+// OnShutdown registers fn, named name in the logs, to release a resource when
+// the process ends: flushing a Kafka producer, closing a database, and the
+// like. Hooks run when main returns, through a deferred Shutdown, and before
+// Fatal exits, which skips main's defers. Register a hook in place of a defer,
+// not alongside one, as its resource would be closed twice.
+func OnShutdown(name string, fn func(ctx context.Context) error) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, shutdownHook{name: name, fn: fn})
+}
+
+// Shutdown runs the hooks of OnShutdown, the last registered first as defers
+// are, within ShutdownTimeout, then flushes the log export (see Flush). Hook
+// errors are logged, and the remaining hooks still run. A hook still running
+// at the deadline, such as one that ignores its context, is logged and left
+// behind with those after it, so that a stuck close cannot keep the process,
+// or Fatal, from ending. Each hook runs once, however often Shutdown is called.
+func Shutdown() {
+	hooksMu.Lock()
+	pending := hooks
+	hooks = nil
+	hooksMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownWait)
+	defer cancel()
+	var running atomic.Pointer[string]
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, h := range slices.Backward(pending) {
+			running.Store(&h.name)
+			if err := h.fn(ctx); err != nil {
+				slog.Error("Shutdown hook failed", "hook", h.name, "error", err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Error("Shutdown hook timed out, not waiting for the rest", "hook", *running.Load(), "timeout", shutdownWait)
+	}
+	Flush()
+}
+
+// Fatal logs msg at the error level, runs the shutdown hooks (see Shutdown),
+// and exits with status 1.
+func Fatal(msg string, args ...any) {
+	Logger.Error(msg, args...)
+	Shutdown()
+	exit(1)
+}
@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	buf := capture(t)
+	defaultLevel.Set(slog.LevelInfo)
+	oldDefault := slog.Default()
+	slog.SetDefault(For("consumer"))
+	t.Cleanup(func() { slog.SetDefault(oldDefault) })
+
+	var ran []string
+	hook := func(name string, err error) {
+		OnShutdown(name, func(ctx context.Context) error {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Errorf("%s: expected a deadline", name)
+			}
+			ran = append(ran, name)
+			return err
+		})
+	}
+	hook("database", nil)
+	hook("windows", errors.New("insert failed"))
+	hook("kafka consumer", nil)
+	Shutdown()
+	Shutdown()
+
+	if got := strings.Join(ran, ","); got != "kafka consumer,windows,database" {
+		t.Errorf("hooks ran as %s, want once each, last registered first", got)
+	}
+	if out := buf.String(); !strings.Contains(out, `"msg":"Shutdown hook failed","component":"consumer","hook":"windows","error":"insert failed"`) {
+		t.Errorf("expected the failure to be logged: %s", out)
+	}
+}
+
+// TestShutdown_StuckHook checks that Shutdown stops waiting for a hook that
+// ignores its context once the timeout passes.
+func TestShutdown_StuckHook(t *testing.T) {
+	buf := capture(t)
+	oldDefault := slog.Default()
+	slog.SetDefault(For("consumer"))
+	t.Cleanup(func() { slog.SetDefault(oldDefault) })
+	defer func(d time.Duration) { shutdownWait = d }(shutdownWait)
+	shutdownWait = 50 * time.Millisecond
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	var ranAfter bool
+	OnShutdown("database", func(context.Context) error {
+		ranAfter = true
+		return nil
+	})
+	OnShutdown("kafka consumer", func(context.Context) error {
+		<-stuck
+		return nil
+	})
+
+	start := time.Now()
+	Shutdown()
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("Shutdown took %s, want it to give up after %s", took, shutdownWait)
+	}
+	if ranAfter {
+		t.Error("the hook after the stuck one ran")
+	}
+	if out := buf.String(); !strings.Contains(out, `"msg":"Shutdown hook timed out, not waiting for the rest"`) || !strings.Contains(out, `"hook":"kafka consumer"`) {
+		t.Errorf("expected the stuck hook to be logged: %s", out)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	capture(t)
+	oldLogger, oldExit := Logger, exit
+	Logger = For("api")
+	code := -1
+	exit = func(c int) { code = c }
+	t.Cleanup(func() { Logger, exit = oldLogger, oldExit })
+
+	closed := false
+	OnShutdown("database", func(context.Context) error {
+		closed = true
+		return nil
+	})
+	Fatal("Failed to listen", "addr", ":50051")
+	if !closed || code != 1 {
+		t.Errorf("expected the hooks to run before exiting with 1: closed %v, code %d", closed, code)
+	}
+}
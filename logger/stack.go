@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// maxFrames is the most stack frames WithStack and Go record.
+const maxFrames = 32
+
+// stackError is an error with the stack WithStack captured.
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+func (e *stackError) Error() string { return e.err.Error() }
+func (e *stackError) Unwrap() error { return e.err }
+
+// WithStack returns err with the stack of its caller attached, for Err to log.
+// An err that already has one, in its chain of wrapped errors, is returned as
+// is, as is nil.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *stackError
+	if errors.As(err, &se) {
+		return err
+	}
+	return &stackError{err: err, pcs: callers(3)}
+}
+
+// Stack returns the frames of the stack attached to err by WithStack, as
+// "function file:line", innermost first, or nil if it has none.
+func Stack(err error) []string {
+	var se *stackError
+	if !errors.As(err, &se) {
+		return nil
+	}
+	return frames(se.pcs)
+}
+
+// Err returns the "error" attribute for err: the error itself, or, when it
+// has a stack from WithStack, a group of its message under "msg" and its
+// frames under "stack".
+func Err(err error) slog.Attr {
+	stack := Stack(err)
+	if stack == nil {
+		return slog.Any("error", err)
+	}
+	return slog.Group("error", slog.String("msg", err.Error()), slog.Any("stack", stack))
+}
+
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxFrames)
+	return pcs[:runtime.Callers(skip, pcs)]
+}
+
+func frames(pcs []uintptr) []string {
+	var out []string
+	fs := runtime.CallersFrames(pcs)
+	for {
+		f, more := fs.Next()
+		out = append(out, fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line))
+		if !more {
+			return out
+		}
+	}
+}
+
+// restartDelay is how long Go waits before running a function that panicked
+// again, so one that panics at once does not spin.
+var restartDelay = time.Second
+
+// Go runs fn in a goroutine named name, such as "delivery reports", for a
+// background loop. If fn panics, the panic is logged with its stack and fn is
+// run again after a second, so one bad message does not silently stop the
+// loop or crash the process; once fn returns, Go is done.
+func Go(name string, fn func()) {
+	go func() {
+		for !runRecovered(name, fn) {
+			time.Sleep(restartDelay)
+		}
+	}()
+}
+
+// runRecovered runs fn, logging a panic as Recover does, and reports whether
+// fn returned rather than panicked.
+func runRecovered(name string, fn func()) (returned bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			logPanic(name, v, true)
+		}
+	}()
+	fn()
+	return true
+}
+
+// Recover, deferred at the top of a goroutine named name, logs a panic in it
+// with its stack and ends the goroutine instead of crashing the process.
+func Recover(name string) {
+	if v := recover(); v != nil {
+		logPanic(name, v, false)
+	}
+}
+
+func logPanic(name string, v any, restart bool) {
+	// Skip runtime.Callers, callers, logPanic, and the deferred function, then
+	// the runtime's panicking frames
+	stack := frames(callers(4))
+	for len(stack) > 1 && strings.HasPrefix(stack[0], "runtime.") {
+		stack = stack[1:]
+	}
+	attrs := []any{"goroutine", name, "panic", fmt.Sprint(v), "stack", stack, "restart", restart}
+	if err, ok := v.(error); ok {
+		attrs = append(attrs, Err(err))
+	}
+	slog.Error("Recovered from panic", attrs...)
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithStack(t *testing.T) {
+	cause := errors.New("insert failed")
+	err := fmt.Errorf("persist: %w", WithStack(cause))
+	if !errors.Is(err, cause) || err.Error() != "persist: insert failed" {
+		t.Errorf("expected the error to wrap as before, got %v", err)
+	}
+	stack := Stack(err)
+	if len(stack) == 0 || !strings.Contains(stack[0], "logger.TestWithStack") {
+		t.Fatalf("expected the stack to start at the caller, got %v", stack)
+	}
+	if again := WithStack(err); again != err {
+		t.Error("expected an error with a stack to be returned as is")
+	}
+	if WithStack(nil) != nil || Stack(cause) != nil {
+		t.Error("expected no stack for nil and plain errors")
+	}
+
+	buf := capture(t)
+	slog.New(base).Error("Handler failed", Err(err))
+	slog.New(base).Error("Handler failed", Err(cause))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"error":{"msg":"persist: insert failed","stack":["github.com/pedeveaux/kafkarideshare/logger.TestWithStack `) {
+		t.Errorf("expected the message and stack, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"error":"insert failed"`) {
+		t.Errorf("expected a plain error, got %s", lines[1])
+	}
+}
+
+func TestGo(t *testing.T) {
+	buf := capture(t)
+	defaultLevel.Set(slog.LevelInfo)
+	oldDefault, oldDelay := slog.Default(), restartDelay
+	slog.SetDefault(For("producer"))
+	restartDelay = time.Millisecond
+	t.Cleanup(func() { slog.SetDefault(oldDefault); restartDelay = oldDelay })
+
+	var runs atomic.Int32
+	done := make(chan struct{})
+	Go("delivery reports", func() {
+		if runs.Add(1) < 3 {
+			var m map[string]int
+			m["boom"]++ // panics
+		}
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the goroutine to be restarted until it returns")
+	}
+
+	out := buf.String()
+	if n := strings.Count(out, `"msg":"Recovered from panic"`); n != 2 {
+		t.Errorf("logged %d panics, want 2:\n%s", n, out)
+	}
+	for _, want := range []string{`"goroutine":"delivery reports"`, `"panic":"assignment to entry in nil map"`, `"restart":true`, `logger.TestGo.func`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in %s", want, out)
+		}
+	}
+	if strings.Contains(out, `"stack":["runtime.`) {
+		t.Errorf("expected the stack to start at the panic, got %s", out)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	buf := capture(t)
+	defaultLevel.Set(slog.LevelInfo)
+	oldDefault := slog.Default()
+	slog.SetDefault(For("api"))
+	t.Cleanup(func() { slog.SetDefault(oldDefault) })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer Recover("watcher")
+		panic(errors.New("closed channel"))
+	}()
+	<-done
+	out := buf.String()
+	for _, want := range []string{`"goroutine":"watcher"`, `"restart":false`, `"error":"closed channel"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in %s", want, out)
+		}
+	}
+}
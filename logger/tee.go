@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Tee returns a handler passing each record to every one of handlers that is
+// enabled for its level.
+func Tee(handlers ...slog.Handler) slog.Handler {
+	return teeHandler(slices.Clone(handlers))
+}
+
+// teeHandler passes records to each of its handlers.
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
+
+// chain is the set of handlers, or sinks, that Init's loggers write to: those
+// Init builds from the environment, then those added with AddHandler. Loggers
+// made before a change pick it up on their next record.
+type chain struct {
+	mu     sync.Mutex
+	init   []slog.Handler
+	added  []addedHandler
+	nextID int
+	// current is init followed by added, replaced as a whole on each change
+	current atomic.Pointer[[]slog.Handler]
+}
+
+type addedHandler struct {
+	id int
+	h  slog.Handler
+}
+
+var sinks = newChain()
+
+func newChain() *chain {
+	c := &chain{}
+	c.current.Store(&[]slog.Handler{})
+	return c
+}
+
+// setInit replaces the handlers Init built, keeping those added.
+func (c *chain) setInit(handlers []slog.Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init = handlers
+	c.publish()
+}
+
+func (c *chain) add(h slog.Handler) (remove func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	id := c.nextID
+	c.added = append(c.added, addedHandler{id: id, h: h})
+	c.publish()
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.added = slices.DeleteFunc(c.added, func(a addedHandler) bool { return a.id == id })
+		c.publish()
+	}
+}
+
+// publish stores the current handlers; c.mu is held.
+func (c *chain) publish() {
+	all := slices.Clone(c.init)
+	for _, a := range c.added {
+		all = append(all, a.h)
+	}
+	c.current.Store(&all)
+}
+
+// handler returns the handler writing to every sink of c.
+func (c *chain) handler() slog.Handler {
+	return &chainHandler{chain: c}
+}
+
+// chainHandler passes records to the current sinks of its chain, with the
+// attributes and groups of WithAttrs and WithGroup applied to each.
+type chainHandler struct {
+	chain *chain
+	with  []func(slog.Handler) slog.Handler
+	// built caches the sinks with "with" applied, for the handlers it was built from
+	built atomic.Pointer[builtSinks]
+}
+
+type builtSinks struct {
+	from *[]slog.Handler
+	tee  teeHandler
+}
+
+func (h *chainHandler) sinks() teeHandler {
+	current := h.chain.current.Load()
+	if b := h.built.Load(); b != nil && b.from == current {
+		return b.tee
+	}
+	tee := make(teeHandler, len(*current))
+	for i, s := range *current {
+		for _, w := range h.with {
+			s = w(s)
+		}
+		tee[i] = s
+	}
+	h.built.Store(&builtSinks{from: current, tee: tee})
+	return tee
+}
+
+func (h *chainHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.sinks().Enabled(ctx, l)
+}
+
+func (h *chainHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.sinks().Handle(ctx, r)
+}
+
+func (h *chainHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &chainHandler{chain: h.chain, with: append(slices.Clip(h.with), func(s slog.Handler) slog.Handler { return s.WithAttrs(attrs) })}
+}
+
+func (h *chainHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &chainHandler{chain: h.chain, with: append(slices.Clip(h.with), func(s slog.Handler) slog.Handler { return s.WithGroup(name) })}
+}
+
+// AddHandler adds h to the sinks the loggers of this package write to, such as
+// a handler of a service's own that forwards errors to an alerting system, and
+// returns a function removing it. Records reach h after level filtering,
+// sampling, and redaction, with the fields of their context added. Handlers
+// may be added before or after Init.
+func AddHandler(h slog.Handler) (remove func()) {
+	return sinks.add(h)
+}
+
+// Handlers returns the sinks the loggers of this package write to: those Init
+// built, for stdout, the log file, and the export, then those of AddHandler.
+func Handlers() []slog.Handler {
+	return slices.Clone(*sinks.current.Load())
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	var jsonBuf, textBuf bytes.Buffer
+	log := slog.New(Tee(
+		slog.NewJSONHandler(&jsonBuf, nil),
+		slog.NewTextHandler(&textBuf, &slog.HandlerOptions{Level: slog.LevelWarn}),
+	)).With("component", "api").WithGroup("req")
+	log.Info("Served", "status", 200)
+	log.Warn("Slow", "ms", 900)
+
+	if got := jsonBuf.String(); !strings.Contains(got, `"msg":"Served","component":"api","req":{"status":200}`) || !strings.Contains(got, `"msg":"Slow"`) {
+		t.Errorf("json: %s", got)
+	}
+	if got := textBuf.String(); strings.Contains(got, "Served") || !strings.Contains(got, "msg=Slow component=api req.ms=900") {
+		t.Errorf("text: %s", got)
+	}
+}
+
+func TestAddHandler(t *testing.T) {
+	var initBuf bytes.Buffer
+	oldBase := base
+	sinks.setInit([]slog.Handler{slog.NewJSONHandler(&initBuf, &slog.HandlerOptions{Level: minLevel})})
+	base = NewRedactHandler(sinks.handler())
+	t.Cleanup(func() { base = oldBase; sinks.setInit(nil) })
+	defaultLevel.Set(slog.LevelInfo)
+
+	// Made before the handler is added, so it must pick the change up
+	log := For("consumer").With("trip_id", "trip-1").WithGroup("kafka")
+	log.Info("before")
+
+	var added bytes.Buffer
+	remove := AddHandler(slog.NewTextHandler(&added, nil))
+	if n := len(Handlers()); n != 2 {
+		t.Errorf("expected 2 handlers, got %d", n)
+	}
+	log.Info("during", "partition", 3, "passenger", "rider-0001")
+	remove()
+	log.Info("after")
+
+	if got := added.String(); strings.Count(got, "\n") != 1 ||
+		!strings.Contains(got, "msg=during component=consumer trip_id=trip-1 kafka.partition=3 kafka.passenger=[redacted]") {
+		t.Errorf("added handler got %q", got)
+	}
+	if n := strings.Count(initBuf.String(), "\n"); n != 3 {
+		t.Errorf("expected Init's handler to get all 3 lines, got %d", n)
+	}
+	if n := len(Handlers()); n != 1 {
+		t.Errorf("expected 1 handler after removal, got %d", n)
+	}
+}
+
+func TestInit_FileFormat(t *testing.T) {
+	capture(t)
+	t.Cleanup(func() { sinks.setInit(nil) })
+	path := filepath.Join(t.TempDir(), "debug.log")
+	t.Setenv("LOG_OUTPUT", "both")
+	t.Setenv("LOG_FILE", path)
+	t.Setenv("LOG_FILE_FORMAT", "text")
+	Init(slog.LevelInfo, "json")
+
+	hs := Handlers()
+	if len(hs) != 3 {
+		t.Fatalf("expected stdout, the file, and metrics, got %v", hs)
+	}
+	if _, ok := hs[0].(*slog.JSONHandler); !ok {
+		t.Errorf("stdout: got %T", hs[0])
+	}
+	if _, ok := hs[1].(*slog.TextHandler); !ok {
+		t.Errorf("file: got %T", hs[1])
+	}
+	For("api").Info("to both")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `msg="to both" component=api`) {
+		t.Errorf("file: %s", data)
+	}
+}
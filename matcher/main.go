@@ -0,0 +1,11 @@
+// Command matcher dispatches ride requests to drivers; see the
+// services/matcher package. `rideshare match` runs the same.
+package main
+
+import (
+	"os"
+
+	"github.com/pedeveaux/kafkarideshare/services/matcher"
+)
+
+func main() { matcher.Run(os.Args[1:]) }
@@ -0,0 +1,449 @@
+package matching
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+var (
+	requestsMatched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "matching_requests_total",
+		Help: "Number of ride requests dispatched, by outcome: accepted or no_drivers.",
+	}, []string{"outcome"})
+
+	pickupDistance = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "matching_pickup_distance_meters",
+		Help:    "Distance from the matched driver to the pickup when the request was accepted.",
+		Buckets: []float64{100, 250, 500, 1000, 2000, 3000, 5000, 10000},
+	})
+
+	offers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "matching_offers_total",
+		Help: "Number of ride offers made to drivers in offer mode, by outcome: offered, declined, or expired.",
+	}, []string{"outcome"})
+
+	driversAvailable = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "matching_drivers_available",
+		Help: "Number of drivers on shift and free to be matched, as of the last request.",
+	})
+)
+
+// Defaults of Config.
+const (
+	DefaultRideTopic         = "ride-events"
+	DefaultDriverTopic       = "driver-events"
+	DefaultMaxPickupDistance = 5000.0 // meters
+	DefaultStaleAfter        = time.Minute
+	DefaultOfferTimeout      = 15 * time.Second
+	DefaultTripIdleTimeout   = time.Hour
+)
+
+// evictInterval is how often Run drops trips idle for longer than
+// TripIdleTimeout.
+const evictInterval = time.Minute
+
+// Config configures a Matcher. Zero values take the defaults.
+type Config struct {
+	RideTopic   string
+	DriverTopic string
+	// MaxPickupDistance is how far, in meters, a driver may be from the pickup.
+	MaxPickupDistance float64
+	// StaleAfter is how long a driver is matched after their last report;
+	// drivers who stop sending heartbeats are assumed to be gone.
+	StaleAfter time.Duration
+	// OfferTopic, when set, puts the matcher in offer mode: it offers each
+	// request to the nearest driver on OfferTopic rather than accepting it for
+	// them, and moves on to the next driver when the offer is declined or has
+	// not been answered within OfferTimeout.
+	OfferTopic   string
+	OfferTimeout time.Duration
+	// TripIdleTimeout is how long a trip is kept without an event before it
+	// is dropped and its driver freed, as when its terminal event was lost.
+	TripIdleTimeout time.Duration
+	// Instance names this matcher in the meta of the events it sends.
+	Instance string
+	// Now returns the current time; nil uses time.Now.
+	Now func() time.Time
+}
+
+func (c *Config) setDefaults() {
+	if c.RideTopic == "" {
+		c.RideTopic = DefaultRideTopic
+	}
+	if c.DriverTopic == "" {
+		c.DriverTopic = DefaultDriverTopic
+	}
+	if c.MaxPickupDistance <= 0 {
+		c.MaxPickupDistance = DefaultMaxPickupDistance
+	}
+	if c.StaleAfter <= 0 {
+		c.StaleAfter = DefaultStaleAfter
+	}
+	if c.OfferTimeout <= 0 {
+		c.OfferTimeout = DefaultOfferTimeout
+	}
+	if c.TripIdleTimeout <= 0 {
+		c.TripIdleTimeout = DefaultTripIdleTimeout
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+// Matcher reads ride and driver events and answers each ride request. It
+// learns where drivers are from the driver topic, and which are busy from the
+// ride topic: a driver is busy from their ACCEPTED event, whoever sent it,
+// until the trip completes or is cancelled. In offer mode a driver is also
+// busy while they have an open offer.
+type Matcher struct {
+	cfg  Config
+	pool *Pool
+	sink rideconsumer.Sink
+
+	mu sync.Mutex
+	// trips holds each request until its trip ends or goes idle, so a
+	// redelivered request gets the same answer rather than a second driver
+	trips map[string]*dispatch
+}
+
+// dispatch is what the matcher knows of a request.
+type dispatch struct {
+	request  events.RideEvent
+	answer   *events.RideEvent // the matcher's own ACCEPTED or CANCELLED
+	offer    *Offer            // the open offer, in offer mode
+	declined map[string]bool   // drivers who declined or let an offer expire
+	updated  time.Time         // when the trip last had an event
+}
+
+// New returns a Matcher that matches requests to drivers in pool and sends
+// its answers to sink.
+func New(cfg Config, pool *Pool, sink rideconsumer.Sink) *Matcher {
+	cfg.setDefaults()
+	return &Matcher{cfg: cfg, pool: pool, sink: sink, trips: make(map[string]*dispatch)}
+}
+
+// Run handles messages from src until ctx is cancelled, in offer mode expires
+// offers, and drops idle trips. src is subscribed to the ride and the driver
+// topic, and in offer mode to the offer topic.
+func (m *Matcher) Run(ctx context.Context, src rideconsumer.Source) error {
+	lastEvict := m.cfg.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if err := m.ExpireOffers(); err != nil {
+			slog.Error("Failed to re-offer expired offers", "error", err)
+		}
+		if now := m.cfg.Now(); now.Sub(lastEvict) >= evictInterval {
+			if n := m.Evict(now.Add(-m.cfg.TripIdleTimeout)); n > 0 {
+				slog.Warn("Evicted idle trips from matcher", "count", n)
+			}
+			lastEvict = now
+		}
+
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			slog.Error("Consumer error", "error", err)
+			continue
+		}
+		if err := m.Handle(msg); err != nil {
+			slog.Error("Failed to handle message", "topic", *msg.TopicPartition.Topic,
+				"key", string(msg.Key), "offset", msg.TopicPartition.Offset, "error", err)
+		}
+	}
+}
+
+// Handle processes one message from the ride, driver, or offer topic.
+// Messages that cannot be decoded are logged and skipped; the error is from
+// sending an answer.
+func (m *Matcher) Handle(msg *kafka.Message) error {
+	switch topic := *msg.TopicPartition.Topic; {
+	case m.cfg.OfferTopic != "" && topic == m.cfg.OfferTopic:
+		var o Offer
+		if err := json.Unmarshal(msg.Value, &o); err != nil {
+			slog.Warn("Skipping undecodable offer", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		if o.Status != OfferDeclined {
+			return nil // the matcher's own offers
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.declined(o, "declined")
+	case topic == m.cfg.DriverTopic:
+		var e events.DriverEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable driver event", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		m.pool.Apply(e)
+		return nil
+	case topic == m.cfg.RideTopic:
+		var e events.RideEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		return m.observe(e)
+	default:
+		return fmt.Errorf("matching: message from unexpected topic %q", topic)
+	}
+}
+
+// observe applies a ride event, answering it if it is a request.
+func (m *Matcher) observe(e events.RideEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d, ok := m.trips[e.TripID]; ok {
+		d.updated = m.cfg.Now()
+	}
+	switch e.Type {
+	case events.EventRideRequested:
+		d, ok := m.trips[e.TripID]
+		if !ok {
+			d = &dispatch{request: e, declined: make(map[string]bool), updated: m.cfg.Now()}
+			m.trips[e.TripID] = d
+			return m.dispatch(d)
+		}
+		switch {
+		case d.answer != nil:
+			return m.publish(*d.answer)
+		case d.offer != nil:
+			return m.publishOffer(*d.offer)
+		}
+	case events.EventRideAccepted:
+		m.pool.Assign(e.DriverID, e.TripID)
+		if d, ok := m.trips[e.TripID]; ok && d.offer != nil {
+			d.offer = nil
+			requestsMatched.WithLabelValues("accepted").Inc()
+		}
+	case events.EventTripCompleted, events.EventTripCancelled:
+		m.pool.Release(e.TripID, nil)
+		delete(m.trips, e.TripID)
+	}
+	return nil
+}
+
+// dispatch answers a request, or in offer mode offers it to the nearest
+// driver who has not declined it. m.mu is held.
+func (m *Matcher) dispatch(d *dispatch) error {
+	if m.cfg.OfferTopic == "" {
+		answer := m.Match(d.request)
+		d.answer = &answer
+		return m.publish(answer)
+	}
+	offer, ok := m.Offer(d.request, d.declined)
+	if !ok {
+		answer := m.noDriver(d.request)
+		d.answer = &answer
+		return m.publish(answer)
+	}
+	d.offer = &offer
+	offers.WithLabelValues("offered").Inc()
+	return m.publishOffer(offer)
+}
+
+// declined moves a request on from its open offer, if o is that offer,
+// to the next driver. m.mu is held.
+func (m *Matcher) declined(o Offer, outcome string) error {
+	d, ok := m.trips[o.TripID]
+	if !ok || d.offer == nil || d.offer.ID != o.ID {
+		return nil // already answered, or expired before the driver declined
+	}
+	offers.WithLabelValues(outcome).Inc()
+	slog.Info("Offer declined", "tripID", o.TripID, "driverID", o.DriverID, "reason", o.Reason)
+	d.declined[o.DriverID] = true
+	d.offer = nil
+	m.pool.Release(o.TripID, nil)
+	return m.dispatch(d)
+}
+
+// ExpireOffers takes offers open past their expiry as declined and offers
+// their requests to the next driver.
+func (m *Matcher) ExpireOffers() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.cfg.Now()
+	var errs []error
+	for _, d := range m.trips {
+		if d.offer != nil && now.After(d.offer.ExpiresAt) {
+			errs = append(errs, m.declined(d.offer.Decline(DeclineExpired), "expired"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Evict drops trips that have not had an event since before cutoff, for
+// example because their terminal event was lost, and frees their drivers. It
+// returns how many were dropped.
+func (m *Matcher) Evict(cutoff time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	evicted := 0
+	for id, d := range m.trips {
+		if d.updated.Before(cutoff) {
+			m.pool.Release(id, nil)
+			delete(m.trips, id)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Match answers a ride request with an ACCEPTED event for the nearest
+// available driver within MaxPickupDistance of the pickup, in the request's
+// city, and assigns the driver to the trip. Without one it answers with a
+// cancellation by the system for ReasonNoDriverAvailable, the NO_DRIVERS_FOUND
+// outcome, which ends the ride.
+func (m *Matcher) Match(req events.RideEvent) events.RideEvent {
+	driver, distance, ok := m.nearest(req, nil)
+	if !ok {
+		return m.noDriver(req)
+	}
+	m.pool.Assign(driver.ID, req.TripID)
+	requestsMatched.WithLabelValues("accepted").Inc()
+	if distance >= 0 {
+		pickupDistance.Observe(distance)
+	}
+	slog.Info("Matched driver", "tripID", req.TripID, "driverID", driver.ID)
+	return events.NewRideAccepted(req.TripID, driver.ID, append(m.answerOptions(req), events.WithDriver(driver.ID))...)
+}
+
+// Offer returns an offer of a ride request to the nearest available driver,
+// as Match would pick them, other than those in exclude, and assigns the
+// driver to the trip until they answer. It reports false if there is no such
+// driver.
+func (m *Matcher) Offer(req events.RideEvent, exclude map[string]bool) (Offer, bool) {
+	driver, distance, ok := m.nearest(req, exclude)
+	if !ok {
+		return Offer{}, false
+	}
+	m.pool.Assign(driver.ID, req.TripID)
+	if distance >= 0 {
+		pickupDistance.Observe(distance)
+	}
+	now := m.cfg.Now()
+	o := Offer{
+		ID:          uuid.NewString(),
+		Status:      OfferOpen,
+		TripID:      req.TripID,
+		DriverID:    driver.ID,
+		PassengerID: req.PassengerID,
+		City:        req.City,
+		DistanceM:   max(distance, 0),
+		OfferedAt:   now,
+		ExpiresAt:   now.Add(m.cfg.OfferTimeout),
+		Meta:        events.Meta{CorrelationID: correlationID(req), CausationID: req.ID, ProducerInstance: m.cfg.Instance},
+	}
+	if p, ok := events.PayloadAs[events.RideRequestedPayload](req); ok {
+		o.Pickup, o.Dropoff = p.Pickup, p.Dropoff
+	}
+	slog.Info("Offered ride", "tripID", req.TripID, "driverID", driver.ID)
+	return o, true
+}
+
+// nearest returns the driver Match picks for req, other than those in
+// exclude, and their distance to the pickup, or -1 if req has none.
+func (m *Matcher) nearest(req events.RideEvent, exclude map[string]bool) (Driver, float64, bool) {
+	var pickup *events.Coordinate
+	if p, ok := events.PayloadAs[events.RideRequestedPayload](req); ok {
+		pickup = p.Pickup
+	}
+	since := m.cfg.Now().Add(-m.cfg.StaleAfter)
+	driver, ok := m.pool.NearestExcept(req.City, pickup, m.cfg.MaxPickupDistance, since, exclude)
+	driversAvailable.Set(float64(m.available()))
+	switch {
+	case !ok:
+		return Driver{}, 0, false
+	case pickup == nil:
+		return driver, -1, true
+	}
+	return driver, pickup.DistanceM(driver.Location), true
+}
+
+// noDriver returns the cancellation by the system of a request no driver
+// was found for.
+func (m *Matcher) noDriver(req events.RideEvent) events.RideEvent {
+	requestsMatched.WithLabelValues("no_drivers").Inc()
+	slog.Info("No driver found", "tripID", req.TripID, "city", req.City)
+	return events.NewTripCancelled(req.TripID, events.ActorSystem, events.ReasonNoDriverAvailable, m.answerOptions(req)...)
+}
+
+// answerOptions returns the options of an answer to req, sent now by the
+// matcher and caused by req.
+func (m *Matcher) answerOptions(req events.RideEvent) []events.Option {
+	return []events.Option{
+		events.WithTime(m.cfg.Now()),
+		events.WithPassenger(req.PassengerID),
+		events.WithCity(req.City),
+		events.WithMeta(events.Meta{CorrelationID: correlationID(req), CausationID: req.ID, ProducerInstance: m.cfg.Instance}),
+	}
+}
+
+// correlationID returns the correlation ID of req's ride: its own, or else
+// the trip.
+func correlationID(req events.RideEvent) string {
+	if req.Meta.CorrelationID != "" {
+		return req.Meta.CorrelationID
+	}
+	return req.TripID
+}
+
+// available counts the drivers free to be matched.
+func (m *Matcher) available() int {
+	n := 0
+	for _, d := range m.pool.Drivers() {
+		if d.Available {
+			n++
+		}
+	}
+	return n
+}
+
+// publish sends an answer to the ride topic, keyed by its trip.
+func (m *Matcher) publish(e events.RideEvent) error {
+	if err := e.Validate(); err != nil {
+		return fmt.Errorf("matching: refusing to send invalid event: %w", err)
+	}
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &m.cfg.RideTopic, Partition: kafka.PartitionAny},
+		Key:            []byte(e.TripID),
+		Value:          value,
+	}
+	for k, v := range e.Meta.Headers() {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return m.sink.Produce(msg, nil)
+}
+
+// publishOffer sends an offer to the offer topic.
+func (m *Matcher) publishOffer(o Offer) error {
+	msg, err := o.Message(m.cfg.OfferTopic)
+	if err != nil {
+		return err
+	}
+	return m.sink.Produce(msg, nil)
+}
@@ -0,0 +1,262 @@
+package matching
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+var (
+	now     = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	midtown = events.Coordinate{Lat: 40.754, Lng: -73.984}
+)
+
+func shiftStarted(id string, at events.Coordinate, when time.Time) events.DriverEvent {
+	return events.DriverEvent{
+		ID:         id + "-shift",
+		DriverID:   id,
+		Type:       events.EventShiftStarted,
+		OccurredAt: when,
+		Payload:    events.ShiftStartedPayload{VehicleID: "car-" + id, Location: at},
+	}
+}
+
+func request(tripID string) events.RideEvent {
+	dropoff := events.Coordinate{Lat: 40.73, Lng: -73.99}
+	pickup := midtown
+	return events.NewRideRequested(tripID, "rider-1", "Main St", "Elm St",
+		events.WithTime(now.Add(-time.Second)), events.WithPassenger("rider-1"), events.WithCoordinates(&pickup, &dropoff))
+}
+
+func TestMatcher_NearestAvailableDriver(t *testing.T) {
+	broker := ridetest.NewBroker()
+	m := New(Config{Instance: "matcher-1", Now: func() time.Time { return now }}, NewPool(), broker)
+
+	drivers := []events.DriverEvent{
+		shiftStarted("near", events.Coordinate{Lat: 40.756, Lng: -73.986}, now),
+		shiftStarted("nearer-but-stale", midtown, now.Add(-time.Hour)),
+		shiftStarted("far", events.Coordinate{Lat: 40.78, Lng: -73.97}, now),
+		shiftStarted("too-far", events.Coordinate{Lat: 40.65, Lng: -73.80}, now),
+	}
+	for _, d := range drivers {
+		if err := m.Handle(ridetest.Message(t, DefaultDriverTopic, d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, trip := range []string{"trip-1", "trip-2", "trip-3"} {
+		if err := m.Handle(ridetest.Message(t, DefaultRideTopic, request(trip))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A redelivered request gets the answer it got before
+	if err := m.Handle(ridetest.Message(t, DefaultRideTopic, request("trip-1"))); err != nil {
+		t.Fatal(err)
+	}
+
+	answers, err := broker.Events(DefaultRideTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answers) != 4 {
+		t.Fatalf("got %d answers, want 4", len(answers))
+	}
+	want := []struct {
+		typ    events.RideEventType
+		driver string
+	}{
+		{events.EventRideAccepted, "near"},
+		{events.EventRideAccepted, "far"},
+		{events.EventTripCancelled, ""},
+	}
+	for i, w := range want {
+		a := answers[i]
+		if a.Type != w.typ || a.DriverID != w.driver {
+			t.Errorf("answer %d: got %s for %q, want %s for %q", i, a.Type, a.DriverID, w.typ, w.driver)
+		}
+		if err := a.Validate(); err != nil {
+			t.Errorf("answer %d: %v", i, err)
+		}
+		if a.Meta.ProducerInstance != "matcher-1" || a.Meta.CorrelationID != a.TripID {
+			t.Errorf("answer %d: meta %+v", i, a.Meta)
+		}
+	}
+	if p, _ := events.PayloadAs[events.RideCancelledPayload](answers[2]); p.CancelledBy != events.ActorSystem || p.Reason != events.ReasonNoDriverAvailable {
+		t.Errorf("no-driver answer: got %+v", p)
+	}
+	if answers[3].ID != answers[0].ID {
+		t.Errorf("redelivered request got a new answer %s, want %s", answers[3].ID, answers[0].ID)
+	}
+
+	// Once trip-1 completes, its driver is free again
+	completed := events.NewTripCompleted("trip-1", 3, events.NewMoney(550, events.USD), events.WithTime(now), events.WithDriver("near"))
+	if err := m.Handle(ridetest.Message(t, DefaultRideTopic, completed)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Handle(ridetest.Message(t, DefaultRideTopic, request("trip-4"))); err != nil {
+		t.Fatal(err)
+	}
+	answers, _ = broker.Events(DefaultRideTopic)
+	if last := answers[len(answers)-1]; last.Type != events.EventRideAccepted || last.DriverID != "near" {
+		t.Errorf("after completion: got %s for %q, want the freed driver", last.Type, last.DriverID)
+	}
+}
+
+func TestPool_HeartbeatsAndShifts(t *testing.T) {
+	pool := NewPool()
+	pool.Apply(shiftStarted("d1", midtown, now))
+	pool.Assign("d1", "trip-1")
+
+	// A heartbeat moves a matched driver but does not free them
+	moved := events.Coordinate{Lat: 40.76, Lng: -73.98}
+	pool.Apply(events.DriverEvent{ID: "hb", DriverID: "d1", Type: events.EventHeartbeat, OccurredAt: now.Add(time.Second),
+		Payload: events.HeartbeatPayload{Location: moved, Available: true}})
+	if _, ok := pool.Nearest("", &moved, 100, now); ok {
+		t.Error("a heartbeat freed a driver on a trip")
+	}
+	if !pool.Release("trip-1", nil) {
+		t.Fatal("Release found no driver for the trip")
+	}
+	if d, ok := pool.Nearest("", &moved, 100, now); !ok || d.ID != "d1" {
+		t.Errorf("after Release: got %+v, %v", d, ok)
+	}
+
+	// Drivers of other cities are not matched, and ended shifts leave the pool
+	if _, ok := pool.Nearest("boston", &moved, 100, now); ok {
+		t.Error("matched a driver in another city")
+	}
+	pool.Apply(events.DriverEvent{ID: "end", DriverID: "d1", Type: events.EventShiftEnded, OccurredAt: now.Add(2 * time.Second),
+		Payload: events.ShiftEndedPayload{}})
+	if n := len(pool.Drivers()); n != 0 {
+		t.Errorf("%d drivers left after the shift ended", n)
+	}
+}
+
+func TestMatcher_Offers(t *testing.T) {
+	const offerTopic = "dispatch-offers"
+	broker := ridetest.NewBroker()
+	clock := now
+	m := New(Config{Instance: "matcher-1", OfferTopic: offerTopic, Now: func() time.Time { return clock }}, NewPool(), broker)
+	for _, d := range []events.DriverEvent{
+		shiftStarted("picky", midtown, now),
+		shiftStarted("slow", events.Coordinate{Lat: 40.756, Lng: -73.986}, now),
+		shiftStarted("last", events.Coordinate{Lat: 40.76, Lng: -73.98}, now),
+	} {
+		if err := m.Handle(ridetest.Message(t, DefaultDriverTopic, d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lastOffer := func() Offer {
+		t.Helper()
+		msgs := broker.Messages(offerTopic)
+		if len(msgs) == 0 {
+			t.Fatal("no offers sent")
+		}
+		var o Offer
+		if err := json.Unmarshal(msgs[len(msgs)-1].Value, &o); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	if err := m.Handle(ridetest.Message(t, DefaultRideTopic, request("trip-1"))); err != nil {
+		t.Fatal(err)
+	}
+	if o := lastOffer(); o.DriverID != "picky" || o.Status != OfferOpen || o.Pickup == nil || o.Meta.CorrelationID != "trip-1" {
+		t.Fatalf("first offer: got %+v", o)
+	}
+	if answers, _ := broker.Events(DefaultRideTopic); len(answers) != 0 {
+		t.Fatalf("offer mode sent %d ride events, want none", len(answers))
+	}
+
+	// A declined offer goes to the next driver, and so does an expired one
+	if err := m.Handle(ridetest.Message(t, offerTopic, lastOffer().Decline(DeclineTooFar))); err != nil {
+		t.Fatal(err)
+	}
+	if o := lastOffer(); o.DriverID != "slow" {
+		t.Fatalf("after the decline: offered to %q, want slow", o.DriverID)
+	}
+	clock = clock.Add(DefaultOfferTimeout + time.Second)
+	if err := m.ExpireOffers(); err != nil {
+		t.Fatal(err)
+	}
+	offer := lastOffer()
+	if offer.DriverID != "last" {
+		t.Fatalf("after the expiry: offered to %q, want last", offer.DriverID)
+	}
+
+	// The driver takes the ride with its ACCEPTED event
+	accepted := offer.Accept(clock, "driversim-1")
+	if err := accepted.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if accepted.DriverID != "last" || accepted.Meta.CausationID == "" || accepted.Meta.ProducerInstance != "driversim-1" {
+		t.Errorf("acceptance: got %+v", accepted)
+	}
+	if err := m.Handle(ridetest.Message(t, DefaultRideTopic, accepted)); err != nil {
+		t.Fatal(err)
+	}
+	clock = clock.Add(DefaultOfferTimeout + time.Second)
+	if err := m.ExpireOffers(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(broker.Messages(offerTopic)); n != 3 {
+		t.Errorf("sent %d offers, want 3", n)
+	}
+
+	// Once every free driver has declined, the request is cancelled
+	if err := m.Handle(ridetest.Message(t, DefaultRideTopic, request("trip-2"))); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"picky", "slow"} {
+		o := lastOffer()
+		if o.TripID != "trip-2" || o.DriverID != want {
+			t.Fatalf("trip-2: offered to %q, want %q", o.DriverID, want)
+		}
+		if err := m.Handle(ridetest.Message(t, offerTopic, o.Decline(DeclineRejected))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	answers, _ := broker.Events(DefaultRideTopic)
+	if len(answers) != 1 || answers[0].Type != events.EventTripCancelled || answers[0].TripID != "trip-2" {
+		t.Fatalf("got %+v, want trip-2 cancelled", answers)
+	}
+}
+
+// TestMatcher_EvictIdleTrips checks that a trip whose end never arrives is
+// dropped once idle, freeing its driver, while a trip with recent events stays.
+func TestMatcher_EvictIdleTrips(t *testing.T) {
+	clock := now
+	broker := ridetest.NewBroker()
+	pool := NewPool()
+	m := New(Config{Now: func() time.Time { return clock }}, pool, broker)
+
+	handle := func(topic string, v any) {
+		t.Helper()
+		if err := m.Handle(ridetest.Message(t, topic, v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handle(DefaultDriverTopic, shiftStarted("idle", midtown, clock))
+	handle(DefaultRideTopic, request("trip-1"))
+
+	clock = now.Add(50 * time.Minute)
+	handle(DefaultDriverTopic, shiftStarted("busy", midtown, clock))
+	handle(DefaultRideTopic, request("trip-2"))
+
+	clock = now.Add(70 * time.Minute)
+	if n := m.Evict(clock.Add(-DefaultTripIdleTimeout)); n != 1 {
+		t.Errorf("evicted %d trips, want 1", n)
+	}
+	for _, d := range pool.Drivers() {
+		if want := d.ID == "idle"; d.Available != want {
+			t.Errorf("driver %s available = %v, want %v", d.ID, d.Available, want)
+		}
+	}
+	if len(m.trips) != 1 || m.trips["trip-2"] == nil {
+		t.Errorf("trips after eviction = %v, want trip-2 only", m.trips)
+	}
+}
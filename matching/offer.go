@@ -0,0 +1,82 @@
+package matching
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// OfferStatus is whether an Offer is open or was declined.
+type OfferStatus string
+
+const (
+	OfferOpen     OfferStatus = "OFFERED"
+	OfferDeclined OfferStatus = "DECLINED"
+)
+
+// Why drivers decline offers, in Offer.Reason.
+const (
+	DeclineBusy     = "busy"
+	DeclineTooFar   = "too_far"
+	DeclineRejected = "rejected"
+	DeclineExpired  = "expired"
+)
+
+// Offer is a ride request offered to one driver. In offer mode the matcher
+// sends it to the offer topic, keyed by driver, and the driver either takes
+// the ride by sending its ACCEPTED event to the ride topic or sends the offer
+// back declined. An offer that is not answered by ExpiresAt is taken as
+// declined, and the request is offered to the next driver.
+type Offer struct {
+	ID          string             `json:"id"`
+	Status      OfferStatus        `json:"status"`
+	TripID      string             `json:"trip_id"`
+	DriverID    string             `json:"driver_id"`
+	PassengerID string             `json:"passenger_id,omitempty"`
+	City        string             `json:"city,omitempty"`
+	Pickup      *events.Coordinate `json:"pickup,omitempty"`
+	Dropoff     *events.Coordinate `json:"dropoff,omitempty"`
+	DistanceM   float64            `json:"distance_m"` // from the driver to the pickup
+	OfferedAt   time.Time          `json:"offered_at"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+	Reason      string             `json:"reason,omitempty"` // why it was declined
+	// Meta correlates the offer with its ride; its cause is the request.
+	Meta events.Meta `json:"meta,omitzero"`
+}
+
+// Message returns the offer as a message to topic, keyed by its driver, with
+// its meta as headers.
+func (o Offer) Message(topic string) (*kafka.Message, error) {
+	value, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(o.DriverID),
+		Value:          value,
+	}
+	for k, v := range o.Meta.Headers() {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return msg, nil
+}
+
+// Decline returns the offer declined for reason, to send back to the matcher.
+func (o Offer) Decline(reason string) Offer {
+	o.Status, o.Reason = OfferDeclined, reason
+	return o
+}
+
+// Accept returns the ACCEPTED event of the driver taking the ride at time at,
+// caused by the request, sent by instance.
+func (o Offer) Accept(at time.Time, instance string) events.RideEvent {
+	return events.NewRideAccepted(o.TripID, o.DriverID,
+		events.WithTime(at),
+		events.WithPassenger(o.PassengerID),
+		events.WithCity(o.City),
+		events.WithMeta(events.Meta{CorrelationID: o.Meta.CorrelationID, CausationID: o.Meta.CausationID, ProducerInstance: instance}))
+}
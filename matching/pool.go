@@ -0,0 +1,151 @@
+// Package matching dispatches ride requests to drivers. A Pool tracks where
+// drivers are and whether they are free from their driver events, and a
+// Matcher answers each REQUESTED event with an ACCEPTED event for the nearest
+// free driver, or a system cancellation when there is none.
+package matching
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// Driver is a driver on shift, as last reported.
+type Driver struct {
+	ID        string
+	City      string // empty outside multi-city mode
+	Location  events.Coordinate
+	Available bool
+	UpdatedAt time.Time
+	TripID    string // the trip the driver was matched to, until it ends
+}
+
+// Pool is the drivers on shift. It is safe for concurrent use.
+type Pool struct {
+	mu      sync.Mutex
+	drivers map[string]*Driver
+}
+
+// NewPool returns an empty pool.
+func NewPool() *Pool {
+	return &Pool{drivers: make(map[string]*Driver)}
+}
+
+// Apply folds a driver event into the pool: a shift start adds the driver, a
+// heartbeat or relocation moves them, and a shift end removes them. Events
+// older than the driver's last update are ignored, and a heartbeat never
+// frees a driver matched to a trip that has not ended.
+func (p *Pool) Apply(e events.DriverEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d, ok := p.drivers[e.DriverID]
+	if ok && e.OccurredAt.Before(d.UpdatedAt) {
+		return
+	}
+	switch payload := e.Payload.(type) {
+	case events.ShiftStartedPayload:
+		p.drivers[e.DriverID] = &Driver{
+			ID:        e.DriverID,
+			City:      e.City,
+			Location:  payload.Location,
+			Available: true,
+			UpdatedAt: e.OccurredAt,
+		}
+	case events.ShiftEndedPayload:
+		delete(p.drivers, e.DriverID)
+	case events.HeartbeatPayload:
+		if !ok {
+			// Drivers already on shift when the matcher started
+			d = &Driver{ID: e.DriverID, City: e.City}
+			p.drivers[e.DriverID] = d
+		}
+		d.Location, d.UpdatedAt = payload.Location, e.OccurredAt
+		d.Available = payload.Available && d.TripID == ""
+	case events.RelocatedPayload:
+		if ok {
+			d.Location, d.UpdatedAt = payload.To, e.OccurredAt
+		}
+	}
+}
+
+// Nearest returns the available driver in city nearest to at, whose last
+// report is no older than since, and that is within maxDistanceM of at. With
+// at nil, any such driver may be returned; the one reported longest ago is.
+func (p *Pool) Nearest(city string, at *events.Coordinate, maxDistanceM float64, since time.Time) (Driver, bool) {
+	return p.NearestExcept(city, at, maxDistanceM, since, nil)
+}
+
+// NearestExcept is Nearest leaving out the drivers in exclude, such as those
+// who declined the ride.
+func (p *Pool) NearestExcept(city string, at *events.Coordinate, maxDistanceM float64, since time.Time, exclude map[string]bool) (Driver, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var (
+		best     *Driver
+		bestDist float64
+	)
+	for _, d := range p.drivers {
+		if !d.Available || d.City != city || d.UpdatedAt.Before(since) || exclude[d.ID] {
+			continue
+		}
+		var dist float64
+		if at != nil {
+			if dist = at.DistanceM(d.Location); dist > maxDistanceM {
+				continue
+			}
+		} else {
+			// Without a pickup, the driver reported longest ago goes first
+			dist = float64(d.UpdatedAt.UnixNano())
+		}
+		if best == nil || dist < bestDist || (dist == bestDist && d.ID < best.ID) {
+			best, bestDist = d, dist
+		}
+	}
+	if best == nil {
+		return Driver{}, false
+	}
+	return *best, true
+}
+
+// Assign marks the driver as matched to tripID, so they are not matched again
+// until Release. It reports whether the driver is in the pool.
+func (p *Pool) Assign(driverID, tripID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d, ok := p.drivers[driverID]
+	if ok {
+		d.Available, d.TripID = false, tripID
+	}
+	return ok
+}
+
+// Release frees the driver matched to tripID, at the given location when it
+// is not nil, and reports whether there was one.
+func (p *Pool) Release(tripID string, at *events.Coordinate) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, d := range p.drivers {
+		if d.TripID == tripID {
+			d.Available, d.TripID = true, ""
+			if at != nil {
+				d.Location = *at
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Drivers returns the drivers in the pool, by ID.
+func (p *Pool) Drivers() []Driver {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Driver, 0, len(p.drivers))
+	for _, d := range p.drivers {
+		out = append(out, *d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
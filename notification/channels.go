@@ -0,0 +1,159 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// DefaultHTTPTimeout bounds each request of the webhook and Slack channels.
+const DefaultHTTPTimeout = 10 * time.Second
+
+// Channel delivers notifications one way, such as by email. Send must be safe
+// to call again for a notification it failed to send; the notifier retries it.
+type Channel interface {
+	// Name names the channel in delivery records and metrics.
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// PermanentError marks a failure that retrying will not fix, such as a
+// request the receiving end rejects, so the notifier gives up on it at once.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// isPermanent reports whether err is, or wraps, a PermanentError.
+func isPermanent(err error) bool {
+	var perm *PermanentError
+	return errors.As(err, &perm)
+}
+
+// LogChannel writes notifications to the log, standing in for a real channel
+// in development.
+type LogChannel struct{}
+
+// Name returns "log".
+func (LogChannel) Name() string { return "log" }
+
+// Send logs n at info level.
+func (LogChannel) Send(ctx context.Context, n Notification) error {
+	slog.InfoContext(ctx, "Notification", "id", n.ID, "audience", n.Audience, "recipient", n.Recipient,
+		"trip_id", n.TripID, "subject", n.Subject, "body", n.Body)
+	return nil
+}
+
+// WebhookChannel posts each notification as JSON to URL.
+type WebhookChannel struct {
+	URL string
+	// Client sends the requests; nil uses a client with DefaultHTTPTimeout.
+	Client *http.Client
+}
+
+// Name returns "webhook".
+func (c *WebhookChannel) Name() string { return "webhook" }
+
+// Send posts n to the webhook. A 4xx answer other than 408 or 429 is a
+// PermanentError.
+func (c *WebhookChannel) Send(ctx context.Context, n Notification) error {
+	return postJSON(ctx, c.Client, c.URL, n)
+}
+
+// SlackChannel posts each notification to a Slack incoming webhook, as a
+// message naming its recipient, as the simulation has no Slack users to
+// message directly.
+type SlackChannel struct {
+	WebhookURL string
+	// Client sends the requests; nil uses a client with DefaultHTTPTimeout.
+	Client *http.Client
+}
+
+// Name returns "slack".
+func (c *SlackChannel) Name() string { return "slack" }
+
+// Send posts n to the Slack webhook. A 4xx answer other than 408 or 429 is a
+// PermanentError.
+func (c *SlackChannel) Send(ctx context.Context, n Notification) error {
+	text := fmt.Sprintf("*%s* to %s %s (trip %s)\n%s", n.Subject, n.Audience, n.Recipient, n.TripID, n.Body)
+	return postJSON(ctx, c.Client, c.WebhookURL, map[string]string{"text": text})
+}
+
+// postJSON posts v as JSON to url and fails unless the answer is a 2xx.
+func postJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return &PermanentError{err}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return &PermanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("notification: %s answered %s", req.URL.Host, resp.Status)
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &PermanentError{err}
+	}
+	return err
+}
+
+// SMTPChannel emails each notification through the SMTP server at Addr. The
+// simulation's riders and drivers have IDs rather than addresses, so it mails
+// recipient@Domain.
+type SMTPChannel struct {
+	Addr   string // host:port
+	From   string
+	Domain string
+	// Auth authenticates with the server; nil sends without.
+	Auth smtp.Auth
+	// SendMail sends the message; nil uses smtp.SendMail. Tests replace it.
+	SendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Name returns "smtp".
+func (c *SMTPChannel) Name() string { return "smtp" }
+
+// Send mails n to its recipient. smtp.SendMail takes no context, so ctx only
+// stops a send that has not begun.
+func (c *SMTPChannel) Send(ctx context.Context, n Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	to := n.Recipient + "@" + c.Domain
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", c.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", n.Subject)
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", n.ID, c.Domain)
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.CreatedAt.Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(n.Body, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	send := c.SendMail
+	if send == nil {
+		send = smtp.SendMail
+	}
+	return send(c.Addr, c.Auth, c.From, []string{to}, []byte(msg.String()))
+}
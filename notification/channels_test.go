@@ -0,0 +1,79 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+var note = Notification{ID: "evt-1-rider", Audience: Rider, Recipient: "rider-1", TripID: "trip-1", EventType: events.EventTripStarted,
+	Subject: "Your ride has started", Body: "Enjoy your ride.", CreatedAt: now}
+
+func TestWebhookChannel_Send(t *testing.T) {
+	var got Notification
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	c := &WebhookChannel{URL: srv.URL}
+
+	if err := c.Send(context.Background(), note); err != nil || got.ID != note.ID || got.Body != note.Body {
+		t.Errorf("sent %+v, %v", got, err)
+	}
+	status = http.StatusServiceUnavailable
+	if err := c.Send(context.Background(), note); err == nil || isPermanent(err) {
+		t.Errorf("503: %v, want an error worth retrying", err)
+	}
+	status = http.StatusBadRequest
+	if err := c.Send(context.Background(), note); !isPermanent(err) {
+		t.Errorf("400: %v, want a permanent error", err)
+	}
+}
+
+func TestSlackChannel_Send(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	if err := (&SlackChannel{WebhookURL: srv.URL}).Send(context.Background(), note); err != nil {
+		t.Fatal(err)
+	}
+	if want := "*Your ride has started* to rider rider-1 (trip trip-1)\nEnjoy your ride."; got["text"] != want {
+		t.Errorf("text %q, want %q", got["text"], want)
+	}
+}
+
+func TestSMTPChannel_Send(t *testing.T) {
+	var (
+		to  []string
+		msg string
+	)
+	c := &SMTPChannel{Addr: "mail:25", From: "rides@example.com", Domain: "riders.example.com",
+		SendMail: func(addr string, a smtp.Auth, from string, rcpt []string, m []byte) error {
+			to, msg = rcpt, string(m)
+			return nil
+		}}
+	if err := c.Send(context.Background(), note); err != nil {
+		t.Fatal(err)
+	}
+	if len(to) != 1 || to[0] != "rider-1@riders.example.com" {
+		t.Errorf("to %v", to)
+	}
+	if !strings.Contains(msg, "Subject: Your ride has started\r\n") || !strings.HasSuffix(msg, "\r\n\r\nEnjoy your ride.\r\n") {
+		t.Errorf("message %q", msg)
+	}
+}
@@ -0,0 +1,412 @@
+// Package notification tells riders and drivers how their rides are going. A
+// Notifier follows the ride topic, renders a notification from a template for
+// the rider and the driver of each selected event, and delivers it on every
+// configured Channel: the log, a webhook, Slack, or email. Each channel has
+// its own queue and retries, so one that is down does not hold up the others,
+// and the notifier records each delivery's status in a Store; rides_db keeps
+// them in notification_deliveries. The simulation's riders and drivers have
+// no phones, so the channels stand in for the messaging a platform would do.
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+var (
+	deliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_deliveries_total",
+		Help: "Number of notifications delivered or given up on, by channel and status.",
+	}, []string{"channel", "status"})
+
+	sendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "notification_send_duration_seconds",
+		Help:    "Time taken by each attempt to send a notification, by channel.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"channel"})
+)
+
+// Defaults of Config.
+const (
+	DefaultRideTopic   = "ride-events"
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = time.Second
+	DefaultQueueSize   = 100
+	DefaultRemember    = 10000
+)
+
+// Audience is who a notification is for.
+type Audience string
+
+const (
+	Rider  Audience = "rider"
+	Driver Audience = "driver"
+)
+
+// Notification is a message to one rider or driver about their ride.
+type Notification struct {
+	// ID is the event's ID and the audience, so the notification of a
+	// redelivered event has the ID it had the first time.
+	ID        string               `json:"id"`
+	Audience  Audience             `json:"audience"`
+	Recipient string               `json:"recipient"` // the rider's or driver's ID
+	TripID    string               `json:"trip_id"`
+	EventType events.RideEventType `json:"event_type"`
+	Subject   string               `json:"subject"`
+	Body      string               `json:"body"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// Store is where the notifier records deliveries; rides_db keeps them in
+// notification_deliveries.
+type Store interface {
+	UpsertNotificationDelivery(ctx context.Context, d rides_db.NotificationDelivery) error
+}
+
+// Retry bounds how often a channel tries a notification.
+type Retry struct {
+	// MaxAttempts is how many times a notification is tried, the first
+	// included.
+	MaxAttempts int
+	// Backoff is the wait after the first failure, doubled after each one
+	// after it.
+	Backoff time.Duration
+}
+
+// Config configures a Notifier. Zero values take the defaults.
+type Config struct {
+	RideTopic string
+	// Events are the event types notified of; nil notifies of every type
+	// Templates has.
+	Events []events.RideEventType
+	// Templates are the notifications of each event type; nil uses
+	// DefaultTemplates.
+	Templates Templates
+	// Retry is how each channel retries, unless ChannelRetry, by channel
+	// name, says otherwise.
+	Retry        Retry
+	ChannelRetry map[string]Retry
+	// QueueSize is how many notifications each channel holds before Handle
+	// waits for it.
+	QueueSize int
+	// Remember is how many notifications are remembered, so a redelivered
+	// event is not notified again.
+	Remember int
+	// Now returns the current time; nil uses time.Now.
+	Now func() time.Time
+}
+
+func (c *Config) setDefaults() {
+	if c.RideTopic == "" {
+		c.RideTopic = DefaultRideTopic
+	}
+	if c.Templates == nil {
+		c.Templates = DefaultTemplates
+	}
+	c.Retry = c.Retry.withDefaults()
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+	if c.Remember <= 0 {
+		c.Remember = DefaultRemember
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+func (r Retry) withDefaults() Retry {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = DefaultMaxAttempts
+	}
+	if r.Backoff <= 0 {
+		r.Backoff = DefaultBackoff
+	}
+	return r
+}
+
+// Notifier turns ride events into notifications and delivers them on its
+// channels.
+type Notifier struct {
+	cfg       Config
+	store     Store
+	channels  []*worker
+	templates map[events.RideEventType]map[Audience]parsed
+	wg        sync.WaitGroup
+
+	mu    sync.Mutex
+	trips map[string]*trip
+	// seen holds the IDs of the latest notifications, the oldest first in
+	// order, so a redelivered event is notified once
+	seen  map[string]bool
+	order []string
+}
+
+// trip is what the notifier knows of a ride, until it ends, as not every
+// event names its rider and driver.
+type trip struct {
+	passenger string
+	driver    string
+	pickup    string
+	dropoff   string
+}
+
+// worker delivers the notifications of one channel from its queue.
+type worker struct {
+	channel Channel
+	retry   Retry
+	queue   chan Notification
+}
+
+// New returns a Notifier that delivers on channels and records deliveries in
+// store. It fails if a template does not parse or an event selected has no
+// template.
+func New(cfg Config, store Store, channels ...Channel) (*Notifier, error) {
+	cfg.setDefaults()
+	templates, err := cfg.Templates.parse()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Events != nil {
+		for typ := range templates {
+			if !slices.Contains(cfg.Events, typ) {
+				delete(templates, typ)
+			}
+		}
+		for _, typ := range cfg.Events {
+			if _, ok := templates[typ]; !ok {
+				return nil, fmt.Errorf("notification: no template for %s", typ)
+			}
+		}
+	}
+	n := &Notifier{cfg: cfg, store: store, templates: templates,
+		trips: make(map[string]*trip), seen: make(map[string]bool)}
+	for _, c := range channels {
+		retry, ok := cfg.ChannelRetry[c.Name()]
+		if !ok {
+			retry = cfg.Retry
+		}
+		n.channels = append(n.channels, &worker{channel: c, retry: retry.withDefaults(),
+			queue: make(chan Notification, cfg.QueueSize)})
+	}
+	return n, nil
+}
+
+// Run starts the channels and handles messages from src, subscribed to the
+// ride topic, until ctx is cancelled. It then stops the channels; see Stop.
+func (n *Notifier) Run(ctx context.Context, src rideconsumer.Source) error {
+	n.Start(ctx)
+	defer n.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			slog.Error("Consumer error", "error", err)
+			continue
+		}
+		if err := n.Handle(msg); err != nil {
+			slog.Error("Failed to handle message", "topic", *msg.TopicPartition.Topic,
+				"key", string(msg.Key), "offset", msg.TopicPartition.Offset, "error", err)
+		}
+	}
+}
+
+// Start starts delivering on each channel until ctx is cancelled. Once it is,
+// the channels stop trying, and the notifications left are recorded pending.
+func (n *Notifier) Start(ctx context.Context) {
+	for _, w := range n.channels {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			for msg := range w.queue {
+				n.deliver(ctx, w, msg)
+			}
+		}()
+	}
+}
+
+// Stop waits for the channels to go through their queues, and must not be
+// called before Handle has returned for good.
+func (n *Notifier) Stop() {
+	for _, w := range n.channels {
+		close(w.queue)
+	}
+	n.wg.Wait()
+}
+
+// Handle processes one message from the ride topic, queueing the notifications
+// of its event on every channel. It waits while a channel's queue is full.
+// Messages that cannot be decoded are logged and skipped.
+func (n *Notifier) Handle(msg *kafka.Message) error {
+	if topic := *msg.TopicPartition.Topic; topic != n.cfg.RideTopic {
+		return fmt.Errorf("notification: message from unexpected topic %q", topic)
+	}
+	var e events.RideEvent
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+		return nil
+	}
+	for _, note := range n.notifications(e) {
+		for _, w := range n.channels {
+			n.record(w, note, rides_db.DeliveryPending, 0, nil)
+			w.queue <- note
+		}
+	}
+	return nil
+}
+
+// notifications follows the rider and driver of e's ride, and returns the
+// notifications of e for those known, but none already returned for it.
+func (n *Notifier) notifications(e events.RideEvent) []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if e.TripID == "" {
+		return nil
+	}
+	t := n.trips[e.TripID]
+	if t == nil {
+		t = &trip{}
+		n.trips[e.TripID] = t
+	}
+	d := Data{Event: e, TripID: e.TripID}
+	switch p := e.Payload.(type) {
+	case events.RideRequestedPayload:
+		t.passenger, t.pickup, t.dropoff = p.Passenger, p.PickupLocation, p.DropoffLocation
+	case events.RideAcceptedPayload:
+		t.driver = p.DriverID
+	case events.DriverArrivedPayload:
+		if p.DriverID != "" {
+			t.driver = p.DriverID
+		}
+	case events.RideCompletedPayload:
+		d.DistanceKM, d.Fare = p.DistanceKM, p.Fare
+	case events.RideCancelledPayload:
+		d.CancelledBy, d.Reason = p.CancelledBy, strings.ReplaceAll(string(p.Reason), "_", " ")
+	}
+	if e.PassengerID != "" {
+		t.passenger = e.PassengerID
+	}
+	if e.DriverID != "" {
+		t.driver = e.DriverID
+	}
+	d.Passenger, d.Driver, d.Pickup, d.Dropoff = t.passenger, t.driver, t.pickup, t.dropoff
+	if e.Type == events.EventTripCompleted || e.Type == events.EventTripCancelled {
+		delete(n.trips, e.TripID)
+	}
+
+	var out []Notification
+	for audience, recipient := range map[Audience]string{Rider: t.passenger, Driver: t.driver} {
+		tmpl, ok := n.templates[e.Type][audience]
+		if !ok || recipient == "" {
+			continue
+		}
+		id := e.ID + "-" + string(audience)
+		if n.seen[id] {
+			continue
+		}
+		subject, body, err := tmpl.render(d)
+		if err != nil {
+			slog.Error("Failed to render notification", "id", id, "error", err)
+			continue
+		}
+		n.remember(id)
+		out = append(out, Notification{ID: id, Audience: audience, Recipient: recipient, TripID: e.TripID,
+			EventType: e.Type, Subject: subject, Body: body, CreatedAt: n.cfg.Now().UTC()})
+	}
+	// The rider's first, whatever the map's order
+	slices.SortFunc(out, func(a, b Notification) int { return strings.Compare(string(b.Audience), string(a.Audience)) })
+	return out
+}
+
+// remember adds id to the notifications seen, forgetting the oldest past
+// Remember. n.mu is held.
+func (n *Notifier) remember(id string) {
+	n.seen[id] = true
+	n.order = append(n.order, id)
+	if len(n.order) > n.cfg.Remember {
+		delete(n.seen, n.order[0])
+		n.order = n.order[1:]
+	}
+}
+
+// deliver tries note on w's channel until it is sent, fails for good, or runs
+// out of attempts, recording each outcome.
+func (n *Notifier) deliver(ctx context.Context, w *worker, note Notification) {
+	name := w.channel.Name()
+	backoff := w.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			// Stopped: what is left stays pending
+			n.record(w, note, rides_db.DeliveryPending, attempt-1, err)
+			return
+		}
+		start := time.Now()
+		err := w.channel.Send(ctx, note)
+		sendDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+		switch {
+		case err == nil:
+			n.record(w, note, rides_db.DeliverySent, attempt, nil)
+			deliveries.WithLabelValues(name, string(rides_db.DeliverySent)).Inc()
+			return
+		case attempt >= w.retry.MaxAttempts || isPermanent(err):
+			slog.Warn("Failed to deliver notification", "channel", name, "id", note.ID, "attempts", attempt, "error", err)
+			n.record(w, note, rides_db.DeliveryFailed, attempt, err)
+			deliveries.WithLabelValues(name, string(rides_db.DeliveryFailed)).Inc()
+			return
+		}
+		n.record(w, note, rides_db.DeliveryPending, attempt, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// record stores the status of note on w's channel. A failure to store it is
+// logged, as the notification itself went out or did not regardless.
+func (n *Notifier) record(w *worker, note Notification, status rides_db.DeliveryStatus, attempts int, sendErr error) {
+	d := rides_db.NotificationDelivery{
+		NotificationID: note.ID,
+		Channel:        w.channel.Name(),
+		Recipient:      note.Recipient,
+		TripID:         note.TripID,
+		EventType:      string(note.EventType),
+		Status:         status,
+		Attempts:       attempts,
+	}
+	if sendErr != nil {
+		d.LastError = sendErr.Error()
+	}
+	// Stopping cancels the workers' context, but the last status is still
+	// worth recording
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.store.UpsertNotificationDelivery(ctx, d); err != nil {
+		slog.Error("Failed to record notification delivery", "channel", d.Channel, "id", d.NotificationID,
+			"status", status, "error", err)
+	}
+}
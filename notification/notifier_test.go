@@ -0,0 +1,149 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// channel records what it is sent, failing with the errors in fail first.
+type channel struct {
+	name string
+	mu   sync.Mutex
+	fail []error
+	sent []Notification
+}
+
+func (c *channel) Name() string { return c.name }
+
+func (c *channel) Send(ctx context.Context, n Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.fail) > 0 {
+		err := c.fail[0]
+		c.fail = c.fail[1:]
+		return err
+	}
+	c.sent = append(c.sent, n)
+	return nil
+}
+
+func TestNotifier_NotifiesRidersAndDrivers(t *testing.T) {
+	store := ridetest.NewStore()
+	log := &channel{name: "log"}
+	n, err := New(Config{Now: func() time.Time { return now }}, store, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Start(context.Background())
+	accepted := events.NewRideAccepted("trip-1", "driver-1")
+	for _, e := range []events.RideEvent{
+		events.NewRideRequested("trip-1", "rider-1", "12 Main St", "3 Elm St"),
+		accepted,
+		accepted, // redelivered
+		events.NewTripStarted("trip-1"),
+		events.NewTripCompleted("trip-1", 4.3, events.NewMoney(1225, events.USD)),
+		// No one knows the rider of a trip seen only from its end
+		events.NewTripCancelled("trip-2", events.ActorSystem, events.ReasonNoDriverAvailable),
+	} {
+		if err := n.Handle(ridetest.Message(t, DefaultRideTopic, e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n.Stop()
+
+	var got []string
+	for _, note := range log.sent {
+		got = append(got, string(note.EventType)+" "+note.Recipient+": "+note.Body)
+	}
+	want := []string{
+		"ACCEPTED rider-1: driver-1 accepted your ride and is heading to 12 Main St.",
+		"ACCEPTED driver-1: Pick up rider-1 at 12 Main St, going to 3 Elm St.",
+		"STARTED rider-1: Enjoy your ride to 3 Elm St.",
+		"COMPLETED rider-1: Your 4.3 km ride to 3 Elm St came to 12.25 USD.",
+		"COMPLETED driver-1: You completed a 4.3 km ride with rider-1 for 12.25 USD.",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("sent:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if id := log.sent[0].ID; id != accepted.ID+"-rider" {
+		t.Errorf("ID %q, want the event's and the audience", id)
+	}
+
+	deliveries, err := store.ListNotificationDeliveries(context.Background(), "trip-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 5 {
+		t.Fatalf("deliveries: %+v", deliveries)
+	}
+	for _, d := range deliveries {
+		if d.Status != rides_db.DeliverySent || d.Attempts != 1 || d.Channel != "log" {
+			t.Errorf("delivery %+v, want sent on the first attempt", d)
+		}
+	}
+}
+
+func TestNotifier_RetriesEachChannel(t *testing.T) {
+	store := ridetest.NewStore()
+	flaky := &channel{name: "webhook", fail: []error{errors.New("503"), errors.New("503")}}
+	down := &channel{name: "slack", fail: []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout")}}
+	rejected := &channel{name: "smtp", fail: []error{&PermanentError{errors.New("550 no such user")}}}
+	n, err := New(Config{
+		Events:       []events.RideEventType{events.EventTripCancelled},
+		Retry:        Retry{MaxAttempts: 3, Backoff: time.Millisecond},
+		ChannelRetry: map[string]Retry{"slack": {MaxAttempts: 2, Backoff: time.Millisecond}},
+	}, store, flaky, down, rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Start(context.Background())
+	for _, e := range []events.RideEvent{
+		events.NewRideRequested("trip-1", "rider-1", "12 Main St", "3 Elm St"),
+		events.NewTripCancelled("trip-1", events.ActorPassenger, events.ReasonChangedPlans),
+	} {
+		if err := n.Handle(ridetest.Message(t, DefaultRideTopic, e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n.Stop()
+
+	if len(flaky.sent) != 1 || flaky.sent[0].Body != "Your ride from 12 Main St was cancelled by passenger: changed plans." {
+		t.Errorf("webhook sent %+v", flaky.sent)
+	}
+	deliveries, err := store.ListNotificationDeliveries(context.Background(), "trip-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]rides_db.NotificationDelivery)
+	for _, d := range deliveries {
+		got[d.Channel] = d
+	}
+	if d := got["webhook"]; d.Status != rides_db.DeliverySent || d.Attempts != 3 {
+		t.Errorf("webhook %+v, want sent on the third attempt", d)
+	}
+	if d := got["slack"]; d.Status != rides_db.DeliveryFailed || d.Attempts != 2 || d.LastError != "timeout" {
+		t.Errorf("slack %+v, want failed after its two attempts", d)
+	}
+	if d := got["smtp"]; d.Status != rides_db.DeliveryFailed || d.Attempts != 1 {
+		t.Errorf("smtp %+v, want failed without retrying", d)
+	}
+}
+
+func TestNew_RejectsEventsWithoutTemplates(t *testing.T) {
+	if _, err := New(Config{Events: []events.RideEventType{events.EventTipAdded}}, ridetest.NewStore()); err == nil {
+		t.Error("want an error for TIP_ADDED")
+	}
+	if _, err := New(Config{Templates: Templates{events.EventTripStarted: {Rider: {Subject: "{{.Nope"}}}}, ridetest.NewStore()); err == nil {
+		t.Error("want an error for the broken template")
+	}
+}
@@ -0,0 +1,121 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// Template is the text of one kind of notification, as text/template
+// templates executed on a Data.
+type Template struct {
+	Subject string
+	Body    string
+}
+
+// Templates holds the notification sent to each audience for each event
+// type. An audience without a template for an event is not notified of it.
+type Templates map[events.RideEventType]map[Audience]Template
+
+// DefaultTemplates are the notifications of a ride's progress: riders hear of
+// each step, and drivers of new rides, their end, and cancellations.
+var DefaultTemplates = Templates{
+	events.EventRideAccepted: {
+		Rider: {
+			Subject: "Your driver is on the way",
+			Body:    "{{.Driver}} accepted your ride{{with .Pickup}} and is heading to {{.}}{{end}}.",
+		},
+		Driver: {
+			Subject: "New ride",
+			Body:    "Pick up {{.Passenger}}{{with .Pickup}} at {{.}}{{end}}{{with .Dropoff}}, going to {{.}}{{end}}.",
+		},
+	},
+	events.EventDriverArrived: {
+		Rider: {
+			Subject: "Your driver has arrived",
+			Body:    "{{.Driver}} is waiting for you{{with .Pickup}} at {{.}}{{end}}.",
+		},
+	},
+	events.EventTripStarted: {
+		Rider: {
+			Subject: "Your ride has started",
+			Body:    "Enjoy your ride{{with .Dropoff}} to {{.}}{{end}}.",
+		},
+	},
+	events.EventTripCompleted: {
+		Rider: {
+			Subject: "Thanks for riding",
+			Body:    "Your {{printf \"%.1f\" .DistanceKM}} km ride{{with .Dropoff}} to {{.}}{{end}} came to {{.Fare}}.",
+		},
+		Driver: {
+			Subject: "Ride complete",
+			Body:    "You completed a {{printf \"%.1f\" .DistanceKM}} km ride with {{.Passenger}} for {{.Fare}}.",
+		},
+	},
+	events.EventTripCancelled: {
+		Rider: {
+			Subject: "Your ride was cancelled",
+			Body:    "Your ride{{with .Pickup}} from {{.}}{{end}} was cancelled by {{.CancelledBy}}{{with .Reason}}: {{.}}{{end}}.",
+		},
+		Driver: {
+			Subject: "Ride cancelled",
+			Body:    "The ride with {{.Passenger}} was cancelled by {{.CancelledBy}}{{with .Reason}}: {{.}}{{end}}.",
+		},
+	},
+}
+
+// Data is what templates are executed on: the event and what the notifier
+// knows of its ride. Fields the event does not carry are zero.
+type Data struct {
+	Event       events.RideEvent
+	TripID      string
+	Passenger   string
+	Driver      string
+	Pickup      string // the pickup location as requested
+	Dropoff     string
+	DistanceKM  float64 // of a completed ride
+	Fare        events.Money
+	CancelledBy events.Actor
+	Reason      string // the cancel reason, with spaces for underscores
+}
+
+// parsed holds the parsed templates of one kind of notification.
+type parsed struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// parse parses every template of t.
+func (t Templates) parse() (map[events.RideEventType]map[Audience]parsed, error) {
+	out := make(map[events.RideEventType]map[Audience]parsed, len(t))
+	for typ, byAudience := range t {
+		out[typ] = make(map[Audience]parsed, len(byAudience))
+		for audience, tmpl := range byAudience {
+			name := fmt.Sprintf("%s/%s", typ, audience)
+			subject, err := template.New(name + "/subject").Parse(tmpl.Subject)
+			if err != nil {
+				return nil, fmt.Errorf("notification: template %s: %w", name, err)
+			}
+			body, err := template.New(name + "/body").Parse(tmpl.Body)
+			if err != nil {
+				return nil, fmt.Errorf("notification: template %s: %w", name, err)
+			}
+			out[typ][audience] = parsed{subject, body}
+		}
+	}
+	return out, nil
+}
+
+// render returns the subject and body of p for d.
+func (p parsed) render(d Data) (subject, body string, err error) {
+	var sb, bb strings.Builder
+	if err := p.subject.Execute(&sb, d); err != nil {
+		return "", "", err
+	}
+	if err := p.body.Execute(&bb, d); err != nil {
+		return "", "", err
+	}
+	return sb.String(), bb.String(), nil
+}
@@ -0,0 +1,131 @@
+// Command notifier tells riders and drivers how their rides are going. It
+// follows ride-events and sends a notification for each selected event on
+// the channels in NOTIFY_CHANNELS, tracking each delivery in
+// notification_deliveries; see the notification package.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/notification"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+const (
+	defaultBrokers  = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID         = "notifier"
+	defaultChannels = "log"
+	defaultSMTPFrom = "rides@example.com"
+	defaultDomain   = "example.com"
+)
+
+// configFromEnv reads the notifier settings: NOTIFY_EVENTS, the event types
+// to notify of (default all that have templates), NOTIFY_MAX_ATTEMPTS, how
+// often a channel tries each notification, and NOTIFY_BACKOFF, the wait after
+// its first failure. Invalid values are logged and left to the defaults.
+func configFromEnv() notification.Config {
+	cfg := notification.Config{RideTopic: topics.RideEvents}
+	for _, raw := range runtime.EnvList("NOTIFY_EVENTS") {
+		typ := events.RideEventType(strings.ToUpper(raw))
+		if _, ok := notification.DefaultTemplates[typ]; !ok {
+			slog.Warn("Invalid NOTIFY_EVENTS entry, skipping", "value", raw)
+			continue
+		}
+		cfg.Events = append(cfg.Events, typ)
+	}
+	cfg.Retry.MaxAttempts = runtime.EnvInt("NOTIFY_MAX_ATTEMPTS", notification.DefaultMaxAttempts)
+	cfg.Retry.Backoff = runtime.EnvDuration("NOTIFY_BACKOFF", notification.DefaultBackoff)
+	return cfg
+}
+
+// channelsFromEnv returns the channels named in NOTIFY_CHANNELS (default
+// log): log, webhook to NOTIFY_WEBHOOK_URL, slack to
+// NOTIFY_SLACK_WEBHOOK_URL, and smtp through NOTIFY_SMTP_ADDR, from
+// NOTIFY_SMTP_FROM to the recipient at NOTIFY_SMTP_DOMAIN. A channel named
+// without its settings, or not known, is fatal.
+func channelsFromEnv() []notification.Channel {
+	names := runtime.EnvList("NOTIFY_CHANNELS")
+	if len(names) == 0 {
+		names = []string{defaultChannels}
+	}
+	required := func(key, channel string) string {
+		v := os.Getenv(key)
+		if v == "" {
+			logger.Fatal("Channel needs a setting", "channel", channel, "setting", key)
+		}
+		return v
+	}
+	var out []notification.Channel
+	for _, name := range names {
+		switch name {
+		case "log":
+			out = append(out, notification.LogChannel{})
+		case "webhook":
+			out = append(out, &notification.WebhookChannel{URL: required("NOTIFY_WEBHOOK_URL", name)})
+		case "slack":
+			out = append(out, &notification.SlackChannel{WebhookURL: required("NOTIFY_SLACK_WEBHOOK_URL", name)})
+		case "smtp":
+			out = append(out, &notification.SMTPChannel{
+				Addr:   required("NOTIFY_SMTP_ADDR", name),
+				From:   runtime.EnvString("NOTIFY_SMTP_FROM", defaultSMTPFrom),
+				Domain: runtime.EnvString("NOTIFY_SMTP_DOMAIN", defaultDomain),
+			})
+		default:
+			logger.Fatal("Unknown notification channel", "channel", name)
+		}
+	}
+	return out
+}
+
+func main() {
+	svc := runtime.Start("notifier")
+	defer svc.Stop()
+	slog.Info("Starting notifier")
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+
+	n, err := notification.New(configFromEnv(), store, channelsFromEnv()...)
+	if err != nil {
+		logger.Fatal("Failed to create notifier", "error", err)
+	}
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+
+	// A new group starts from the latest events, rather than telling riders
+	// about rides long over
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          groupID,
+		"auto.offset.reset": "latest",
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error { return consumer.Close() })
+	if err := consumer.SubscribeTopics([]string{topics.RideEvents}, nil); err != nil {
+		logger.Fatal("Failed to subscribe", "error", err)
+	}
+
+	svc.Serve(":2122")
+
+	ctx := svc.Context()
+
+	if err := n.Run(ctx, consumer); err != nil {
+		logger.Fatal("Notifier stopped", "error", err)
+	}
+	slog.Info("Notifier stopped")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/outbox"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+)
+
+// defaultBrokers are the Kafka brokers unless KAFKA_BROKERS is set.
+const defaultBrokers = "redpanda:9092"
+
+func main() {
+	svc := runtime.Start("outbox-relay")
+	defer svc.Stop()
+	slog.Info("Starting outbox relay...")
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		logger.Fatal("Failed to create producer", "error", err)
+	}
+	logger.OnShutdown("kafka producer", func(context.Context) error {
+		producer.Close()
+		return nil
+	})
+
+	ctx := svc.Context()
+
+	svc.Serve(":2113")
+
+	relay := outbox.NewRelay(store, producer, configFromEnv())
+	svc.OnChange(func() {
+		size := runtime.EnvInt("OUTBOX_BATCH_SIZE", 0)
+		relay.SetBatchSize(size)
+		slog.Info("Applied new batch size", "batch_size", size)
+	}, "OUTBOX_BATCH_SIZE")
+	if err := relay.Run(ctx); err != nil {
+		logger.Fatal("Outbox relay stopped", "error", err)
+	}
+	slog.Info("Outbox relay stopped")
+}
+
+// configFromEnv reads OUTBOX_POLL_INTERVAL and OUTBOX_BATCH_SIZE; unset or
+// invalid values keep the relay defaults. The config file can change the batch
+// size while the relay runs.
+func configFromEnv() outbox.Config {
+	return outbox.Config{
+		PollInterval: runtime.EnvDuration("OUTBOX_POLL_INTERVAL", 0),
+		BatchSize:    runtime.EnvInt("OUTBOX_BATCH_SIZE", 0),
+	}
+}
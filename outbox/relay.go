@@ -0,0 +1,143 @@
+// Package outbox publishes rows written to the ride_outbox table to Kafka, so
+// services can emit events in the same transaction as their database writes.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+var (
+	messagesPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_relay_messages_published_total",
+		Help: "Number of outbox messages published to Kafka.",
+	})
+
+	publishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_relay_publish_failures_total",
+		Help: "Number of failed attempts to publish an outbox message.",
+	})
+)
+
+// Store is the part of rides_db.RideStore the relay needs.
+type Store interface {
+	ProcessOutbox(ctx context.Context, limit int, publish func(rides_db.OutboxMessage) error) (int, error)
+}
+
+// Producer publishes messages and reports delivery on deliveryChan.
+// *kafka.Producer and rideconsumer.MemoryBroker satisfy it.
+type Producer interface {
+	Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error
+}
+
+// Config tunes how often the outbox is polled and how many rows each batch takes.
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+func (c *Config) setDefaults() {
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+}
+
+// Relay moves messages from the outbox to Kafka. Delivery is at least once: a
+// crash between the broker acknowledging a message and the batch committing
+// publishes it again, so consumers must deduplicate.
+type Relay struct {
+	store    Store
+	producer Producer
+	cfg      Config
+	// batchSize is cfg.BatchSize, as SetBatchSize last changed it
+	batchSize atomic.Int64
+}
+
+// NewRelay returns a Relay publishing store's outbox through producer.
+func NewRelay(store Store, producer Producer, cfg Config) *Relay {
+	cfg.setDefaults()
+	r := &Relay{store: store, producer: producer, cfg: cfg}
+	r.batchSize.Store(int64(cfg.BatchSize))
+	return r
+}
+
+// SetBatchSize changes how many rows each batch takes from the next batch on,
+// back to the default for n <= 0. It is safe to call while the relay runs.
+func (r *Relay) SetBatchSize(n int) {
+	c := Config{BatchSize: n}
+	c.setDefaults()
+	r.batchSize.Store(int64(c.BatchSize))
+}
+
+// Run drains the outbox until ctx is cancelled, polling every PollInterval
+// once it is empty and backing off the same interval after a failure.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Drain(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Outbox relay batch failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain publishes batches until the outbox is empty or a batch fails, and
+// returns how many messages were published.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	var total int
+	for {
+		size := int(r.batchSize.Load())
+		n, err := r.store.ProcessOutbox(ctx, size, r.publish)
+		total += n
+		messagesPublished.Add(float64(n))
+		if err != nil {
+			return total, err
+		}
+		if n < size {
+			return total, nil
+		}
+	}
+}
+
+// publish sends m and waits for the broker to acknowledge it.
+func (r *Relay) publish(m rides_db.OutboxMessage) error {
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &m.Topic, Partition: kafka.PartitionAny},
+		Value:          m.Payload,
+	}
+	if m.Key != "" {
+		msg.Key = []byte(m.Key)
+	}
+	for k, v := range m.Headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	delivery := make(chan kafka.Event, 1)
+	if err := r.producer.Produce(msg, delivery); err != nil {
+		publishFailures.Inc()
+		return err
+	}
+	report := (<-delivery).(*kafka.Message)
+	if report.TopicPartition.Error != nil {
+		publishFailures.Inc()
+		return fmt.Errorf("deliver outbox message %d to %s: %w", m.ID, m.Topic, report.TopicPartition.Error)
+	}
+	return nil
+}
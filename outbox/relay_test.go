@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+func openTestStore(t *testing.T) *rides_db.SQLiteStore {
+	t.Helper()
+	store, err := rides_db.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	return store
+}
+
+func TestRelay_PublishesCommittedMessagesInOrder(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+
+	for _, key := range []string{"trip-1", "trip-2", "trip-3"} {
+		err := store.WithTx(ctx, func(tx rides_db.RideStore) error {
+			return tx.EnqueueOutbox(ctx, rides_db.OutboxMessage{
+				Topic:   "ride-events",
+				Key:     key,
+				Payload: []byte(`{"trip_id":"` + key + `"}`),
+				Headers: map[string]string{"source": "test"},
+			})
+		})
+		if err != nil {
+			t.Fatalf("EnqueueOutbox failed: %v", err)
+		}
+	}
+	// A rolled back transaction leaves nothing to publish
+	rollback := errors.New("rollback")
+	err := store.WithTx(ctx, func(tx rides_db.RideStore) error {
+		if err := tx.EnqueueOutbox(ctx, rides_db.OutboxMessage{Topic: "ride-events", Payload: []byte("{}")}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+
+	broker := rideconsumer.NewMemoryBroker()
+	relay := NewRelay(store, broker, Config{BatchSize: 2})
+	n, err := relay.Drain(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 published, got n=%d err=%v", n, err)
+	}
+
+	msgs := broker.Messages("ride-events")
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages on the topic, got %d", len(msgs))
+	}
+	for i, want := range []string{"trip-1", "trip-2", "trip-3"} {
+		if string(msgs[i].Key) != want {
+			t.Errorf("message %d: expected key %s, got %s", i, want, msgs[i].Key)
+		}
+	}
+	if h := msgs[0].Headers; len(h) != 1 || h[0].Key != "source" || string(h[0].Value) != "test" {
+		t.Errorf("unexpected headers: %v", h)
+	}
+
+	// Published rows are not sent again
+	if n, err := relay.Drain(ctx); err != nil || n != 0 {
+		t.Errorf("expected nothing left to publish, got n=%d err=%v", n, err)
+	}
+}
+
+type failingProducer struct{ err error }
+
+func (p failingProducer) Produce(*kafka.Message, chan kafka.Event) error { return p.err }
+
+func TestRelay_FailedPublishIsRetried(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	if err := store.EnqueueOutbox(ctx, rides_db.OutboxMessage{Topic: "ride-events", Payload: []byte("{}")}); err != nil {
+		t.Fatalf("EnqueueOutbox failed: %v", err)
+	}
+
+	down := errors.New("broker down")
+	if n, err := NewRelay(store, failingProducer{down}, Config{}).Drain(ctx); !errors.Is(err, down) || n != 0 {
+		t.Fatalf("expected broker error, got n=%d err=%v", n, err)
+	}
+
+	broker := rideconsumer.NewMemoryBroker()
+	if n, err := NewRelay(store, broker, Config{}).Drain(ctx); err != nil || n != 1 {
+		t.Fatalf("expected the message on retry, got n=%d err=%v", n, err)
+	}
+	if len(broker.Messages("ride-events")) != 1 {
+		t.Error("expected the message to be published once")
+	}
+}
+
+// limitsStore records the batch sizes the relay asks its store for.
+type limitsStore struct {
+	Store
+	limits []int
+}
+
+func (s *limitsStore) ProcessOutbox(ctx context.Context, limit int, publish func(rides_db.OutboxMessage) error) (int, error) {
+	s.limits = append(s.limits, limit)
+	return s.Store.ProcessOutbox(ctx, limit, publish)
+}
+
+func TestRelay_SetBatchSize(t *testing.T) {
+	ctx := context.Background()
+	store := &limitsStore{Store: openTestStore(t)}
+	relay := NewRelay(store, rideconsumer.NewMemoryBroker(), Config{BatchSize: 5})
+
+	if _, err := relay.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	relay.SetBatchSize(20)
+	if _, err := relay.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	relay.SetBatchSize(0)
+	if _, err := relay.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{5, 20, 100}; !slices.Equal(store.limits, want) {
+		t.Errorf("batch sizes = %v, want %v", store.limits, want)
+	}
+}
@@ -0,0 +1,11 @@
+// Command pricer quotes ride requests and checks completed fares; see the
+// services/pricer package. `rideshare price` runs the same.
+package main
+
+import (
+	"os"
+
+	"github.com/pedeveaux/kafkarideshare/services/pricer"
+)
+
+func main() { pricer.Run(os.Args[1:]) }
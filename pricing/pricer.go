@@ -0,0 +1,303 @@
+// Package pricing prices rides. Its Pricer quotes a fare for each ride
+// request, with the surge multiplier of the request's pickup zone, and checks
+// the fare each completed ride was charged against what its distance and
+// duration come to at that multiplier, raising a fraud alert when they differ.
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+var (
+	quotes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pricing_quotes_total",
+		Help: "Number of ride requests priced, by outcome: quoted, or unpriced for requests without pickup and dropoff coordinates.",
+	}, []string{"outcome"})
+
+	faresChecked = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pricing_fares_checked_total",
+		Help: "Number of completed rides whose fare was checked, by outcome: match, mismatch, or unknown for rides not seen requested.",
+	}, []string{"outcome"})
+
+	surgeZones = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pricing_surge_zones",
+		Help: "Number of zones with a surge multiplier above 1.",
+	})
+)
+
+// Defaults of Config.
+const (
+	DefaultRideTopic  = "ride-events"
+	DefaultFraudTopic = "fraud-alerts"
+	// DefaultDetourFactor is how much longer than the straight line a quote
+	// takes a ride to be, as the simulators drive it.
+	DefaultDetourFactor = 1.3
+	DefaultSpeedKPH     = 30.0
+	DefaultTolerance    = 0.05
+)
+
+// Config configures a Pricer. Zero values take the defaults.
+type Config struct {
+	RideTopic  string
+	FraudTopic string
+	Rates      Rates
+	// DetourFactor and SpeedKPH are how a quote estimates the distance and
+	// duration of a ride from its pickup and dropoff.
+	DetourFactor float64
+	SpeedKPH     float64
+	// Tolerance is the share of the expected fare a charged fare may be off
+	// by before it is flagged.
+	Tolerance float64
+	// Instance names this pricer in the meta of the events it sends.
+	Instance string
+	// Now returns the current time; nil uses time.Now.
+	Now func() time.Time
+}
+
+func (c *Config) setDefaults() {
+	if c.RideTopic == "" {
+		c.RideTopic = DefaultRideTopic
+	}
+	if c.FraudTopic == "" {
+		c.FraudTopic = DefaultFraudTopic
+	}
+	if c.Rates == (Rates{}) {
+		c.Rates = DefaultRates
+	}
+	if c.DetourFactor <= 0 {
+		c.DetourFactor = DefaultDetourFactor
+	}
+	if c.SpeedKPH <= 0 {
+		c.SpeedKPH = DefaultSpeedKPH
+	}
+	if c.Tolerance <= 0 {
+		c.Tolerance = DefaultTolerance
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+// Pricer reads the ride topic. It answers each request with a PRICE_QUOTE
+// event, learns the surge multiplier of each zone from SURGE_UPDATED events,
+// and checks the fare of each completed ride at the multiplier the ride was
+// requested at.
+type Pricer struct {
+	cfg   Config
+	sink  rideconsumer.Sink
+	surge *Surges
+
+	mu sync.Mutex
+	// trips holds each requested ride until it ends
+	trips map[string]*trip
+}
+
+// trip is what the pricer knows of a ride.
+type trip struct {
+	request    events.RideEvent
+	multiplier float64
+	quote      *events.RideEvent // nil for requests that could not be priced
+	startedAt  time.Time
+}
+
+// New returns a Pricer that sends its quotes and alerts to sink.
+func New(cfg Config, sink rideconsumer.Sink) *Pricer {
+	cfg.setDefaults()
+	return &Pricer{cfg: cfg, sink: sink, surge: NewSurges(), trips: make(map[string]*trip)}
+}
+
+// Run handles messages from src, subscribed to the ride topic, until ctx is
+// cancelled.
+func (p *Pricer) Run(ctx context.Context, src rideconsumer.Source) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			slog.Error("Consumer error", "error", err)
+			continue
+		}
+		if err := p.Handle(msg); err != nil {
+			slog.Error("Failed to handle message", "key", string(msg.Key), "offset", msg.TopicPartition.Offset, "error", err)
+		}
+	}
+}
+
+// Handle processes one message from the ride topic. Messages that cannot be
+// decoded are logged and skipped; the error is from sending a quote or alert.
+func (p *Pricer) Handle(msg *kafka.Message) error {
+	if topic := *msg.TopicPartition.Topic; topic != p.cfg.RideTopic {
+		return fmt.Errorf("pricing: message from unexpected topic %q", topic)
+	}
+	var e events.RideEvent
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+		return nil
+	}
+	return p.observe(e)
+}
+
+// observe applies a ride event, quoting it if it is a request and checking
+// its fare if it completes a ride.
+func (p *Pricer) observe(e events.RideEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch e.Type {
+	case events.EventSurgeUpdated:
+		if p.surge.Apply(e) {
+			surgeZones.Set(float64(p.surge.Len()))
+		}
+	case events.EventRideRequested:
+		if t, ok := p.trips[e.TripID]; ok {
+			// A redelivered request gets the quote it got before
+			if t.quote != nil {
+				return p.publish(*t.quote)
+			}
+			return nil
+		}
+		t := p.quote(e)
+		p.trips[e.TripID] = t
+		if t.quote != nil {
+			return p.publish(*t.quote)
+		}
+	case events.EventTripStarted:
+		if t, ok := p.trips[e.TripID]; ok {
+			t.startedAt = e.OccurredAt
+		}
+	case events.EventTripCompleted:
+		t, ok := p.trips[e.TripID]
+		delete(p.trips, e.TripID)
+		if !ok {
+			faresChecked.WithLabelValues("unknown").Inc()
+			return nil
+		}
+		return p.check(t, e)
+	case events.EventTripCancelled:
+		delete(p.trips, e.TripID)
+	}
+	return nil
+}
+
+// quote returns the trip of a request, with the PRICE_QUOTE event of its
+// fare at the surge of its pickup zone. Requests without pickup and dropoff
+// coordinates cannot be priced, and get no quote. p.mu is held.
+func (p *Pricer) quote(req events.RideEvent) *trip {
+	r, _ := events.PayloadAs[events.RideRequestedPayload](req)
+	zone := RequestZone(r)
+	t := &trip{request: req, multiplier: p.surge.Multiplier(zone)}
+	if r.Pickup == nil || r.Dropoff == nil {
+		quotes.WithLabelValues("unpriced").Inc()
+		return t
+	}
+	distance := math.Round(events.Route{*r.Pickup, *r.Dropoff}.DistanceKM()*p.cfg.DetourFactor*100) / 100
+	duration := time.Duration(distance / p.cfg.SpeedKPH * float64(time.Hour))
+	quote := events.NewPriceQuoted(req.TripID, events.PriceQuotePayload{
+		QuoteID:    uuid.NewString(),
+		ZoneID:     zone,
+		Multiplier: t.multiplier,
+		DistanceKM: distance,
+		Breakdown:  p.cfg.Rates.Breakdown(distance, duration),
+	}, append(p.options(req), events.WithState(events.StateRequested))...)
+	t.quote = &quote
+	quotes.WithLabelValues("quoted").Inc()
+	return t
+}
+
+// check recomputes the fare of a completed ride from its distance and
+// duration at the multiplier it was requested at, and sends an alert if the
+// fare it was charged is off by more than Tolerance. p.mu is held.
+func (p *Pricer) check(t *trip, completed events.RideEvent) error {
+	c, ok := events.PayloadAs[events.RideCompletedPayload](completed)
+	if !ok {
+		return nil
+	}
+	var took time.Duration
+	if !t.startedAt.IsZero() {
+		took = completed.OccurredAt.Sub(t.startedAt)
+	}
+	expected, err := p.cfg.Rates.Fare(c.DistanceKM, took, t.multiplier)
+	if err != nil {
+		return fmt.Errorf("pricing: rates of trip %s: %w", completed.TripID, err)
+	}
+	diff, err := c.Fare.Sub(expected)
+	if err == nil && math.Abs(float64(diff.Amount)) <= p.cfg.Tolerance*float64(expected.Amount) {
+		faresChecked.WithLabelValues("match").Inc()
+		return nil
+	}
+
+	faresChecked.WithLabelValues("mismatch").Inc()
+	slog.Warn("Fare mismatch", "tripID", completed.TripID, "fare", c.Fare, "expected", expected)
+	alert := fraud.NewAlert(fraud.KindFareMismatch, completed.TripID, p.cfg.Now(),
+		fmt.Sprintf("charged %s for %.2f km in %s at %gx, want %s", c.Fare, c.DistanceKM, took.Round(time.Second), t.multiplier, expected))
+	alert.DriverID, alert.City = completed.DriverID, completed.City
+	alert.Fare, alert.Expected = &c.Fare, &expected
+	alert.Meta = events.Meta{CorrelationID: correlationID(t.request), CausationID: completed.ID, ProducerInstance: p.cfg.Instance}
+	msg, err := alert.Message(p.cfg.FraudTopic)
+	if err != nil {
+		return err
+	}
+	return p.sink.Produce(msg, nil)
+}
+
+// options returns the options of a quote of req, sent now by the pricer and
+// caused by req.
+func (p *Pricer) options(req events.RideEvent) []events.Option {
+	return []events.Option{
+		events.WithTime(p.cfg.Now()),
+		events.WithPassenger(req.PassengerID),
+		events.WithCity(req.City),
+		events.WithMeta(events.Meta{CorrelationID: correlationID(req), CausationID: req.ID, ProducerInstance: p.cfg.Instance}),
+	}
+}
+
+// correlationID returns the correlation ID of req's ride: its own, or else
+// the trip.
+func correlationID(req events.RideEvent) string {
+	if req.Meta.CorrelationID != "" {
+		return req.Meta.CorrelationID
+	}
+	return req.TripID
+}
+
+// publish sends a quote to the ride topic, keyed by its trip.
+func (p *Pricer) publish(e events.RideEvent) error {
+	if err := e.Validate(); err != nil {
+		return fmt.Errorf("pricing: refusing to send invalid event: %w", err)
+	}
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.cfg.RideTopic, Partition: kafka.PartitionAny},
+		Key:            []byte(e.TripID),
+		Value:          value,
+	}
+	for k, v := range e.Meta.Headers() {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return p.sink.Produce(msg, nil)
+}
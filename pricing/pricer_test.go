@@ -0,0 +1,132 @@
+package pricing
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+var (
+	now     = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	midtown = events.Coordinate{Lat: 40.754, Lng: -73.984}
+)
+
+func handle(t *testing.T, p *Pricer, evts ...events.RideEvent) {
+	t.Helper()
+	for _, e := range evts {
+		if err := p.Handle(ridetest.Message(t, DefaultRideTopic, e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// request returns the request of a ride from midtown to about 2.2km south.
+func request(tripID string) events.RideEvent {
+	pickup, dropoff := midtown, events.Coordinate{Lat: 40.734, Lng: -73.984}
+	return events.NewRideRequested(tripID, "rider-1", "Main St", "Elm St",
+		events.WithTime(now.Add(-time.Second)), events.WithCoordinates(&pickup, &dropoff))
+}
+
+func TestZoneOf(t *testing.T) {
+	if got := ZoneOf(midtown); got != "40.74,-74.00" {
+		t.Errorf("ZoneOf(midtown) = %s", got)
+	}
+	if ZoneOf(midtown) != ZoneOf(events.Coordinate{Lat: 40.759, Lng: -73.999}) {
+		t.Error("points in one cell are in different zones")
+	}
+	if got := RequestZone(events.RideRequestedPayload{PickupLocation: "Main St"}); got != "Main St" {
+		t.Errorf("zone of a request without coordinates = %s", got)
+	}
+}
+
+func TestZoneBounds(t *testing.T) {
+	sw, ne, err := ZoneBounds(ZoneOf(midtown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sw.Lat > midtown.Lat || sw.Lng > midtown.Lng || ne.Lat <= midtown.Lat || ne.Lng <= midtown.Lng {
+		t.Errorf("bounds %v to %v do not hold midtown", sw, ne)
+	}
+	if ZoneOf(sw) != ZoneOf(midtown) {
+		t.Errorf("south-west corner %v is in zone %s", sw, ZoneOf(sw))
+	}
+	if _, _, err := ZoneBounds("Main St"); err == nil {
+		t.Error("Main St has bounds")
+	}
+}
+
+func TestPricer_QuotesAtSurge(t *testing.T) {
+	broker := ridetest.NewBroker()
+	p := New(Config{Instance: "pricer-1", Now: func() time.Time { return now }}, broker)
+	req := request("trip-1")
+	handle(t, p,
+		events.NewSurgeUpdated(ZoneOf(midtown), 1.5, events.WithTime(now.Add(-time.Minute))),
+		req,
+		req, // redelivered
+		events.NewRideRequested("trip-2", "rider-2", "Main St", "Elm St", events.WithTime(now)),
+	)
+
+	quotes, err := broker.Events(DefaultRideTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotes) != 2 || quotes[0].ID != quotes[1].ID {
+		t.Fatalf("got %d quotes, want the one quote twice and none for the ride without coordinates", len(quotes))
+	}
+	q := quotes[0]
+	if err := q.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := events.PayloadAs[events.PriceQuotePayload](q)
+	if payload.ZoneID != ZoneOf(midtown) || payload.Multiplier != 1.5 || payload.DistanceKM != 2.89 {
+		t.Errorf("quote = %+v", payload)
+	}
+	// $2.50 and $2.89 for the distance, at 1.5x
+	if want := events.NewMoney(809, events.USD); payload.Total != want {
+		t.Errorf("total = %s, want %s", payload.Total, want)
+	}
+	if q.State != events.StateRequested || q.Meta.CausationID != req.ID {
+		t.Errorf("quote state %s, meta %+v", q.State, q.Meta)
+	}
+}
+
+func TestPricer_FlagsFareMismatch(t *testing.T) {
+	broker := ridetest.NewBroker()
+	p := New(Config{Instance: "pricer-1", Now: func() time.Time { return now }}, broker)
+	handle(t, p, events.NewSurgeUpdated(ZoneOf(midtown), 2, events.WithTime(now)))
+
+	opts := []events.Option{events.WithTime(now), events.WithDriver("driver-1")}
+	fair := events.NewMoney(1000, events.USD) // $2.50 and $2.50 for 2.5km, at 2x
+	handle(t, p,
+		request("fair"), events.NewTripStarted("fair", opts...), events.NewTripCompleted("fair", 2.5, fair, opts...),
+		request("cheap"), events.NewTripCompleted("cheap", 2.5, fair.Mul(0.97), opts...),
+		request("dear"), events.NewTripCompleted("dear", 2.5, fair.Mul(2), opts...),
+		// A completion without its request is not checked
+		events.NewTripCompleted("unseen", 2.5, fair.Mul(2), opts...),
+	)
+
+	msgs := broker.Messages(DefaultFraudTopic)
+	if len(msgs) != 1 {
+		t.Fatalf("got %d alerts, want only the fare twice what it should be", len(msgs))
+	}
+	var a fraud.Alert
+	if err := json.Unmarshal(msgs[0].Value, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Type != fraud.EventAlert || a.Kind != fraud.KindFareMismatch || a.TripID != "dear" || a.DriverID != "driver-1" || string(msgs[0].Key) != "dear" {
+		t.Errorf("alert = %+v", a)
+	}
+	if a.Fare == nil || *a.Fare != fair.Mul(2) || a.Expected == nil || *a.Expected != fair {
+		t.Errorf("alert fares: charged %v, expected %v", a.Fare, a.Expected)
+	}
+	if a.Meta.CorrelationID != "dear" || a.Meta.CausationID == "" || a.Meta.ProducerInstance != "pricer-1" {
+		t.Errorf("alert meta = %+v", a.Meta)
+	}
+	if len(p.trips) != 0 {
+		t.Errorf("%d trips still held after they completed", len(p.trips))
+	}
+}
@@ -0,0 +1,67 @@
+package pricing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// Rates are what a ride costs before surge: a base fare, and a rate per
+// kilometer and per minute driven.
+type Rates struct {
+	Base      events.Money
+	PerKM     events.Money
+	PerMinute events.Money
+}
+
+// DefaultRates are the rates the simulators charge: a base fare of $2.50 and
+// $1.00 per kilometer, with nothing for time.
+var DefaultRates = Rates{
+	Base:      events.NewMoney(250, events.USD),
+	PerKM:     events.NewMoney(100, events.USD),
+	PerMinute: events.NewMoney(0, events.USD),
+}
+
+// Breakdown returns the parts of the fare of a ride of distanceKM kilometers
+// that took d.
+func (r Rates) Breakdown(distanceKM float64, d time.Duration) events.FareBreakdown {
+	return events.FareBreakdown{
+		Base:     r.Base,
+		Distance: r.PerKM.Mul(distanceKM),
+		Time:     r.PerMinute.Mul(d.Minutes()),
+	}
+}
+
+// Fare returns the fare of a ride of distanceKM kilometers that took d, with
+// the surge multiplier applied.
+func (r Rates) Fare(distanceKM float64, d time.Duration, multiplier float64) (events.Money, error) {
+	return r.Breakdown(distanceKM, d).Total(multiplier)
+}
+
+// ZoneSize is the side of a pricing zone in degrees of latitude and
+// longitude; see events.ZoneSize.
+const ZoneSize = events.ZoneSize
+
+// ZoneOf returns the pricing zone c is in: the cell of a grid of ZoneSize
+// named by its south-west corner, such as "40.74,-74.00". It is c.Zone().
+func ZoneOf(c events.Coordinate) events.ZoneID {
+	return c.Zone()
+}
+
+// ZoneBounds returns the south-west and north-east corners of a zone named by
+// ZoneOf. Other zone IDs, such as the pickup locations of requests without
+// coordinates, have no bounds.
+func ZoneBounds(z events.ZoneID) (sw, ne events.Coordinate, err error) {
+	if _, err := fmt.Sscanf(string(z), "%f,%f", &sw.Lat, &sw.Lng); err != nil {
+		return sw, ne, fmt.Errorf("pricing: zone %q is not a grid cell", z)
+	}
+	return sw, events.Coordinate{Lat: sw.Lat + ZoneSize, Lng: sw.Lng + ZoneSize}, nil
+}
+
+// RequestZone returns the pricing zone of a ride request: that of its pickup
+// coordinate, or else its pickup location, the zone the stores join trips to.
+// It is p.Zone().
+func RequestZone(p events.RideRequestedPayload) events.ZoneID {
+	return p.Zone()
+}
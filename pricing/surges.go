@@ -0,0 +1,52 @@
+package pricing
+
+import (
+	"sync"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// Surges is the surge multiplier of each zone, as set by the latest
+// SURGE_UPDATED event for it. Services that charge or check fares keep one
+// from the ride topic. It is safe for concurrent use.
+type Surges struct {
+	mu    sync.Mutex
+	zones map[events.ZoneID]float64
+}
+
+// NewSurges returns Surges with no zone surging.
+func NewSurges() *Surges {
+	return &Surges{zones: make(map[events.ZoneID]float64)}
+}
+
+// Apply records the multiplier of a SURGE_UPDATED event, and reports whether
+// e was one. Fares never discount, so a multiplier of 1 or below ends the
+// zone's surge.
+func (s *Surges) Apply(e events.RideEvent) bool {
+	p, ok := events.PayloadAs[events.SurgeUpdatedPayload](e)
+	if !ok {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.Multiplier <= 1 {
+		delete(s.zones, p.ZoneID)
+	} else {
+		s.zones[p.ZoneID] = p.Multiplier
+	}
+	return true
+}
+
+// Multiplier returns the multiplier of zone, 1 when it is not surging.
+func (s *Surges) Multiplier(zone events.ZoneID) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return max(s.zones[zone], 1)
+}
+
+// Len returns how many zones are surging.
+func (s *Surges) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.zones)
+}
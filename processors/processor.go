@@ -0,0 +1,283 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/replay"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+var (
+	messagesHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "processors_messages_total",
+		Help: "Number of input messages handled by the stream processors, by processor and outcome.",
+	}, []string{"processor", "outcome"})
+
+	tableRows = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "processors_table_rows",
+		Help: "Number of rows held in each state table, by changelog topic.",
+	}, []string{"table"})
+
+	restoreSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "processors_restore_seconds",
+		Help: "How long the last restore of each state table from its changelog took, by changelog topic.",
+	}, []string{"table"})
+
+	transactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "processors_transactions_total",
+		Help: "Number of transactions ended by the transactional processors, by processor and outcome.",
+	}, []string{"processor", "outcome"})
+)
+
+// Emitter produces messages to other topics, such as events re-keyed for a
+// downstream processor.
+type Emitter interface {
+	Emit(topic string, key, value []byte) error
+}
+
+// Handler handles one input message, reading and writing the processor's
+// tables and emitting through out. Errors are logged and counted, and the
+// message is not handled again.
+type Handler func(ctx context.Context, msg *kafka.Message, out Emitter) error
+
+// Config configures a Processor.
+type Config struct {
+	// Name is the consumer group of the input, and labels the metrics.
+	Name    string
+	Brokers string
+	// Input lists the topics handled.
+	Input   []string
+	Tables  []StateTable
+	Handler Handler
+	// Instance tells this instance's changelog writes from those of the
+	// other instances of the group; it defaults to the host name.
+	Instance string
+
+	// Transactional handles input in Kafka transactions: the changelog
+	// writes and emitted messages of each CommitInterval of input are
+	// committed together with its offsets, or not at all, and the input
+	// and changelogs are read committed. After a crash or an abort the
+	// tables and outputs resume exactly where the input does, so nothing
+	// is lost or written twice. A Source and Sink given with it must be a
+	// TxSource and a TxSink.
+	Transactional bool
+	// CommitInterval is how long a transaction collects input; it defaults
+	// to DefaultCommitInterval.
+	CommitInterval time.Duration
+
+	// Source and Sink replace the Kafka consumer of the input and the
+	// producer of the changelogs and emitted messages, for example with a
+	// rideconsumer.MemoryBroker. With a Source, Run returns once it returns
+	// io.EOF.
+	Source rideconsumer.Source
+	Sink   rideconsumer.Sink
+	// OpenChangelog opens a table's changelog to restore and follow it,
+	// returning io.EOF once at its end; nil uses the package's
+	// OpenChangelog with Brokers.
+	OpenChangelog func(topic string) (rideconsumer.Source, error)
+}
+
+// Processor consumes its input topics with a Handler that keeps state in
+// Tables backed by changelogs.
+type Processor struct {
+	cfg      Config
+	producer *kafka.Producer // nil when Config.Sink is set
+	sink     rideconsumer.Sink
+	tx       *transaction // the open transaction, if any
+}
+
+// New returns a Processor for cfg, binding its tables to it. Call Run to
+// start processing and Close when done.
+func New(cfg Config) (*Processor, error) {
+	if cfg.Name == "" || cfg.Handler == nil || len(cfg.Input) == 0 && cfg.Source == nil {
+		return nil, errors.New("processors: a processor needs a name, a handler, and input")
+	}
+	if cfg.Brokers == "" {
+		cfg.Brokers = "redpanda:9092"
+	}
+	if cfg.Instance == "" {
+		cfg.Instance, _ = os.Hostname()
+	}
+	if cfg.CommitInterval <= 0 {
+		cfg.CommitInterval = DefaultCommitInterval
+	}
+	if cfg.Transactional {
+		if _, ok := cfg.Sink.(TxSink); cfg.Sink != nil && !ok {
+			return nil, errors.New("processors: a transactional processor's Sink must be a TxSink")
+		}
+		if _, ok := cfg.Source.(TxSource); cfg.Source != nil && !ok {
+			return nil, errors.New("processors: a transactional processor's Source must be a TxSource")
+		}
+	}
+	if cfg.OpenChangelog == nil {
+		cfg.OpenChangelog = func(topic string) (rideconsumer.Source, error) {
+			return OpenChangelog(cfg.Brokers, topic, cfg.Name+"-"+topic)
+		}
+	}
+
+	p := &Processor{cfg: cfg, sink: cfg.Sink}
+	if p.sink == nil {
+		conf := &kafka.ConfigMap{"bootstrap.servers": cfg.Brokers}
+		if cfg.Transactional {
+			// Stable across restarts, so a new run fences off the last
+			// one's producer and aborts what it left open
+			conf.SetKey("transactional.id", cfg.Name+"-"+cfg.Instance)
+		}
+		producer, err := kafka.NewProducer(conf)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for e := range producer.Events() {
+				if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+					slog.Error("Delivery failed", "processor", cfg.Name, "topic", *m.TopicPartition.Topic,
+						"key", string(m.Key), "error", m.TopicPartition.Error)
+				}
+			}
+		}()
+		p.producer, p.sink = producer, producer
+	}
+	for _, t := range cfg.Tables {
+		t.bind(p.sink, cfg.Instance)
+	}
+	return p, nil
+}
+
+// Run restores the tables from their changelogs, then handles input until
+// ctx is cancelled, following the changelogs for the writes of other
+// instances meanwhile.
+func (p *Processor) Run(ctx context.Context) error {
+	if p.cfg.Transactional && p.producer != nil {
+		// Before restoring, so a transaction a crashed run left open is
+		// aborted rather than holding up the changelogs
+		if err := p.producer.InitTransactions(ctx); err != nil {
+			return fmt.Errorf("init transactions: %w", err)
+		}
+	}
+
+	followCtx, stop := context.WithCancel(ctx)
+	var following sync.WaitGroup
+	defer following.Wait()
+	defer stop()
+
+	for _, t := range p.cfg.Tables {
+		src, err := p.cfg.OpenChangelog(t.Topic())
+		if err != nil {
+			return fmt.Errorf("open %s: %w", t.Topic(), err)
+		}
+		start := time.Now()
+		n, err := restore(ctx, t, src)
+		if err != nil {
+			src.Close()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("restore %s: %w", t.Topic(), err)
+		}
+		restoreSeconds.WithLabelValues(t.Topic()).Set(time.Since(start).Seconds())
+		slog.Info("Restored state table", "processor", p.cfg.Name, "table", t.Topic(),
+			"records", n, "rows", t.Len(), "duration", time.Since(start))
+
+		following.Add(1)
+		go func() {
+			defer following.Done()
+			defer src.Close()
+			follow(followCtx, t, src, p.cfg.Instance)
+		}()
+	}
+
+	src := p.cfg.Source
+	if src == nil {
+		conf := &kafka.ConfigMap{
+			"bootstrap.servers": p.cfg.Brokers,
+			"group.id":          p.cfg.Name,
+			"auto.offset.reset": "earliest",
+		}
+		var rebalance kafka.RebalanceCb
+		if p.cfg.Transactional {
+			// Offsets are committed by the transactions
+			conf.SetKey("enable.auto.commit", false)
+			conf.SetKey("isolation.level", "read_committed")
+			rebalance = p.revoke(ctx)
+		}
+		consumer, err := kafka.NewConsumer(conf)
+		if err != nil {
+			return err
+		}
+		defer consumer.Close()
+		if err := consumer.SubscribeTopics(p.cfg.Input, rebalance); err != nil {
+			return err
+		}
+		src = consumer
+	}
+	if p.cfg.Transactional {
+		return p.consumeTx(ctx, src.(TxSource), p.sink.(TxSink))
+	}
+	return p.consume(ctx, src)
+}
+
+// consume handles messages from src until ctx is cancelled or src ends.
+func (p *Processor) consume(ctx context.Context, src rideconsumer.Source) error {
+	for ctx.Err() == nil {
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			if replay.IsTimeout(err) {
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			slog.Error("Consumer error", "processor", p.cfg.Name, "error", err)
+			continue
+		}
+		// Without transactions a failed message is skipped, as at-least-once
+		// processing has nothing to roll back
+		_ = p.handle(ctx, msg)
+	}
+	return nil
+}
+
+// handle runs the handler on msg, logging and counting its failure, and
+// returns the handler's error.
+func (p *Processor) handle(ctx context.Context, msg *kafka.Message) error {
+	if err := p.cfg.Handler(ctx, msg, p); err != nil {
+		messagesHandled.WithLabelValues(p.cfg.Name, "failed").Inc()
+		slog.Error("Failed to handle message", "processor", p.cfg.Name, "topic", *msg.TopicPartition.Topic,
+			"key", string(msg.Key), "offset", msg.TopicPartition.Offset, "error", err)
+		return err
+	}
+	messagesHandled.WithLabelValues(p.cfg.Name, "handled").Inc()
+	return nil
+}
+
+// Emit implements Emitter.
+func (p *Processor) Emit(topic string, key, value []byte) error {
+	return p.sink.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            key,
+		Value:          value,
+	}, nil)
+}
+
+// Close flushes the changelog writes and emitted messages still pending and
+// releases the producer.
+func (p *Processor) Close() {
+	if p.producer == nil {
+		return
+	}
+	if n := p.producer.Flush(5000); n > 0 {
+		slog.Warn("Processor writes left undelivered", "processor", p.cfg.Name, "count", n)
+	}
+	p.producer.Close()
+}
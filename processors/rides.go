@@ -0,0 +1,284 @@
+package processors
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/schemaregistry"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+// Names of the ride processors, their consumer groups.
+const (
+	RideStateName   = "ride-state-processor"
+	DriverStatsName = "driver-stats-processor"
+)
+
+// recentEventIDs is how many event IDs a driver's stats remember, so a
+// redelivered event is counted once.
+const recentEventIDs = 32
+
+// RideState is a row of the ride state table: a ride as its lifecycle
+// events left it, keyed by trip ID.
+type RideState struct {
+	TripID      string               `json:"trip_id"`
+	State       events.RideState     `json:"ride_state"`
+	LastEvent   events.RideEventType `json:"last_event_type"`
+	LastEventAt time.Time            `json:"last_event_at"`
+	PassengerID string               `json:"passenger_id,omitempty"`
+	DriverID    string               `json:"driver_id,omitempty"`
+	City        string               `json:"city,omitempty"`
+	RequestedAt time.Time            `json:"requested_at,omitzero"`
+	AcceptedAt  time.Time            `json:"accepted_at,omitzero"`
+	StartedAt   time.Time            `json:"started_at,omitzero"`
+	EndedAt     time.Time            `json:"ended_at,omitzero"`
+	Fare        events.Money         `json:"fare,omitzero"`
+	DistanceKM  float64              `json:"distance_km,omitempty"`
+	// EventIDs are those of the events applied, a handful per ride.
+	EventIDs []string `json:"event_ids"`
+}
+
+// Apply folds e into the ride, as rides_db does the rides table: an event
+// older than the last one applied fills in missing details but never moves
+// the state backwards. It reports false for an event already applied.
+func (r *RideState) Apply(e events.RideEvent) bool {
+	if slices.Contains(r.EventIDs, e.ID) {
+		return false
+	}
+	// Clipped, as the slice may be shared with the row in the table
+	r.EventIDs = append(slices.Clip(r.EventIDs), e.ID)
+	r.TripID = e.TripID
+	if !e.OccurredAt.Before(r.LastEventAt) {
+		r.State, r.LastEvent, r.LastEventAt = e.State, e.Type, e.OccurredAt
+	}
+	if e.DriverID != "" {
+		r.DriverID = e.DriverID
+	}
+	if e.PassengerID != "" {
+		r.PassengerID = e.PassengerID
+	}
+	if e.City != "" {
+		r.City = e.City
+	}
+	setOnce := func(t *time.Time) {
+		if t.IsZero() {
+			*t = e.OccurredAt
+		}
+	}
+	switch e.Type {
+	case events.EventRideRequested:
+		setOnce(&r.RequestedAt)
+		if p, ok := events.PayloadAs[events.RideRequestedPayload](e); ok && r.PassengerID == "" {
+			r.PassengerID = p.Passenger
+		}
+	case events.EventRideAccepted:
+		setOnce(&r.AcceptedAt)
+		if p, ok := events.PayloadAs[events.RideAcceptedPayload](e); ok && r.DriverID == "" {
+			r.DriverID = p.DriverID
+		}
+	case events.EventTripStarted:
+		setOnce(&r.StartedAt)
+	case events.EventTripCompleted:
+		setOnce(&r.EndedAt)
+		if p, ok := events.PayloadAs[events.RideCompletedPayload](e); ok {
+			r.Fare, r.DistanceKM = p.Fare, p.DistanceKM
+		}
+	case events.EventTripCancelled:
+		setOnce(&r.EndedAt)
+	}
+	return true
+}
+
+// TripMetrics are the metrics of a finished trip, as written to
+// topics.RideAggregates. The durations are zero when the events they span
+// were not seen.
+type TripMetrics struct {
+	TripID               string           `json:"trip_id"`
+	State                events.RideState `json:"ride_state"`
+	PassengerID          string           `json:"passenger_id,omitempty"`
+	DriverID             string           `json:"driver_id,omitempty"`
+	City                 string           `json:"city,omitempty"`
+	RequestedAt          time.Time        `json:"requested_at,omitzero"`
+	EndedAt              time.Time        `json:"ended_at"`
+	AcceptLatencySeconds float64          `json:"accept_latency_seconds,omitempty"`
+	PickupWaitSeconds    float64          `json:"pickup_wait_seconds,omitempty"`
+	DurationSeconds      float64          `json:"duration_seconds,omitempty"`
+	DistanceKM           float64          `json:"distance_km,omitempty"`
+	Fare                 events.Money     `json:"fare,omitzero"`
+}
+
+// Metrics returns the metrics of the ride as it stands.
+func (r RideState) Metrics() TripMetrics {
+	since := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return to.Sub(from).Seconds()
+	}
+	m := TripMetrics{
+		TripID:               r.TripID,
+		State:                r.State,
+		PassengerID:          r.PassengerID,
+		DriverID:             r.DriverID,
+		City:                 r.City,
+		RequestedAt:          r.RequestedAt,
+		EndedAt:              r.EndedAt,
+		AcceptLatencySeconds: since(r.RequestedAt, r.AcceptedAt),
+		PickupWaitSeconds:    since(r.RequestedAt, r.StartedAt),
+		DistanceKM:           r.DistanceKM,
+		Fare:                 r.Fare,
+	}
+	if r.State == events.StateCompleted {
+		m.DurationSeconds = since(r.StartedAt, r.EndedAt)
+	}
+	return m
+}
+
+// NewRideStateTable returns the ride state table, whose changelog is
+// topics.RideState.
+func NewRideStateTable() *Table[RideState] {
+	return NewTable(topics.RideState, JSONCodec[RideState]{})
+}
+
+// RideStateHandler folds the lifecycle events of the ride topic into rides.
+// The acceptance, completion, or cancellation of a ride with a driver is
+// also emitted to topics.RideEventsByDriver, keyed by the driver and with
+// the driver filled in, which completions and cancellations do not carry.
+// The first completion or cancellation of a ride emits its TripMetrics to
+// topics.RideAggregates, keyed by trip ID. Values that are not JSON ride
+// events, such as Avro ones, are skipped.
+func RideStateHandler(rides *Table[RideState]) Handler {
+	return func(ctx context.Context, msg *kafka.Message, out Emitter) error {
+		e, ok := decodeRideEvent(msg)
+		if !ok || !e.Type.IsLifecycle() || e.TripID == "" {
+			return nil
+		}
+		r, _ := rides.Get(e.TripID)
+		ended := !r.EndedAt.IsZero()
+		if !r.Apply(e) {
+			return nil
+		}
+		if !ended && !r.EndedAt.IsZero() {
+			value, err := json.Marshal(r.Metrics())
+			if err != nil {
+				return err
+			}
+			if err := out.Emit(topics.RideAggregates, []byte(r.TripID), value); err != nil {
+				return err
+			}
+		}
+		switch e.Type {
+		case events.EventRideAccepted, events.EventTripCompleted, events.EventTripCancelled:
+			if r.DriverID == "" {
+				break
+			}
+			e.DriverID = r.DriverID
+			value, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			// Emitted first, so a ride stored with the event was re-keyed
+			// too; outside transactions, a redelivery after a crash in
+			// between is counted once by the driver's stats
+			if err := out.Emit(topics.RideEventsByDriver, []byte(r.DriverID), value); err != nil {
+				return err
+			}
+		}
+		return rides.Set(e.TripID, r)
+	}
+}
+
+// DriverStats is a row of the driver stats table: the rides of a driver,
+// keyed by driver ID.
+type DriverStats struct {
+	DriverID  string `json:"driver_id"`
+	Accepted  int    `json:"accepted"`
+	Completed int    `json:"completed"`
+	Cancelled int    `json:"cancelled"`
+	// Earnings are the fares of the completed rides, in the currency of the
+	// first; fares in another currency are left out.
+	Earnings   events.Money `json:"earnings,omitzero"`
+	DistanceKM float64      `json:"distance_km"`
+	LastRideAt time.Time    `json:"last_ride_at,omitzero"`
+	// RecentEventIDs are those of the last events applied, oldest first.
+	RecentEventIDs []string `json:"recent_event_ids"`
+}
+
+// Apply counts e toward the driver's stats. It reports false for an event
+// applied recently, and for events other than acceptances, completions,
+// and cancellations.
+func (s *DriverStats) Apply(e events.RideEvent) bool {
+	if slices.Contains(s.RecentEventIDs, e.ID) {
+		return false
+	}
+	switch e.Type {
+	case events.EventRideAccepted:
+		s.Accepted++
+	case events.EventTripCompleted:
+		s.Completed++
+		if p, ok := events.PayloadAs[events.RideCompletedPayload](e); ok {
+			s.DistanceKM += p.DistanceKM
+			if s.Earnings.Currency == "" {
+				s.Earnings = p.Fare
+			} else if sum, err := s.Earnings.Add(p.Fare); err == nil {
+				s.Earnings = sum
+			} else {
+				slog.Warn("Fare left out of driver earnings", "driver_id", e.DriverID, "trip_id", e.TripID, "error", err)
+			}
+		}
+	case events.EventTripCancelled:
+		s.Cancelled++
+	default:
+		return false
+	}
+	s.DriverID = e.DriverID
+	if e.OccurredAt.After(s.LastRideAt) {
+		s.LastRideAt = e.OccurredAt
+	}
+	s.RecentEventIDs = append(slices.Clip(s.RecentEventIDs), e.ID)
+	if n := len(s.RecentEventIDs); n > recentEventIDs {
+		s.RecentEventIDs = s.RecentEventIDs[n-recentEventIDs:]
+	}
+	return true
+}
+
+// NewDriverStatsTable returns the driver stats table, whose changelog is
+// topics.DriverStats.
+func NewDriverStatsTable() *Table[DriverStats] {
+	return NewTable(topics.DriverStats, JSONCodec[DriverStats]{})
+}
+
+// DriverStatsHandler counts the events of topics.RideEventsByDriver toward
+// their driver's stats.
+func DriverStatsHandler(drivers *Table[DriverStats]) Handler {
+	return func(ctx context.Context, msg *kafka.Message, out Emitter) error {
+		e, ok := decodeRideEvent(msg)
+		if !ok || e.DriverID == "" {
+			return nil
+		}
+		s, _ := drivers.Get(e.DriverID)
+		if !s.Apply(e) {
+			return nil
+		}
+		return drivers.Set(e.DriverID, s)
+	}
+}
+
+// decodeRideEvent decodes a JSON ride event, logging values it cannot.
+func decodeRideEvent(msg *kafka.Message) (events.RideEvent, bool) {
+	if schemaregistry.IsFramed(msg.Value) {
+		return events.RideEvent{}, false
+	}
+	var e events.RideEvent
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+		return events.RideEvent{}, false
+	}
+	return e, true
+}
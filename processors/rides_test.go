@@ -0,0 +1,151 @@
+package processors
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+// runProcessor runs a processor over what topic holds, with its tables
+// restored from broker.
+func runProcessor(t *testing.T, broker *rideconsumer.MemoryBroker, name, topic string, table StateTable, h Handler) {
+	t.Helper()
+	p, err := New(Config{
+		Name:          name,
+		Input:         []string{topic},
+		Tables:        []StateTable{table},
+		Handler:       h,
+		Source:        &endingSource{Source: broker.Source(topic), left: len(broker.Messages(topic))},
+		Sink:          broker,
+		OpenChangelog: openChangelog(broker),
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+}
+
+func publish(t *testing.T, broker *rideconsumer.MemoryBroker, es ...events.RideEvent) {
+	t.Helper()
+	for _, e := range es {
+		value, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		broker.Publish(topics.RideEvents, []byte(e.TripID), value)
+	}
+}
+
+func TestRideAndDriverProcessors(t *testing.T) {
+	broker := rideconsumer.NewMemoryBroker()
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(min int) events.Option { return events.WithTime(start.Add(time.Duration(min) * time.Minute)) }
+
+	completed := events.NewTripCompleted("trip-1", 4.5, events.NewMoney(1250, events.USD), at(20))
+	publish(t, broker,
+		events.NewRideRequested("trip-1", "rider-1", "Main St", "Elm St", at(0)),
+		events.NewRideAccepted("trip-1", "driver-1", at(1)),
+		events.NewTripStarted("trip-1", at(5)),
+		completed,
+		completed, // redelivered
+		events.NewRideRequested("trip-2", "rider-2", "Oak St", "Pine St", at(30)),
+		events.NewRideAccepted("trip-2", "driver-1", at(31)),
+		events.NewTripCancelled("trip-2", events.ActorPassenger, "", at(32)),
+		events.NewRideRequested("trip-3", "rider-3", "Elm St", "Main St", at(40)),
+		events.NewTripCancelled("trip-3", events.ActorPassenger, "", at(41)),
+		events.NewSurgeUpdated("zone-1", 1.5, at(42)),
+	)
+	broker.Publish(topics.RideEvents, []byte("bad"), []byte(`{"event_type":`))
+
+	rides := NewRideStateTable()
+	runProcessor(t, broker, RideStateName, topics.RideEvents, rides, RideStateHandler(rides))
+
+	r, ok := rides.Get("trip-1")
+	if !ok || r.State != events.StateCompleted || r.DriverID != "driver-1" || r.PassengerID != "rider-1" ||
+		r.Fare.Amount != 1250 || !r.EndedAt.Equal(start.Add(20*time.Minute)) || len(r.EventIDs) != 4 {
+		t.Errorf("trip-1 = %+v, %v", r, ok)
+	}
+	if rides.Len() != 3 {
+		t.Errorf("rides = %d, want 3", rides.Len())
+	}
+	if n := len(broker.Messages(topics.RideState)); n != 9 {
+		t.Errorf("ride-state has %d records, want one per event applied", n)
+	}
+	aggregates := broker.Messages(topics.RideAggregates)
+	if len(aggregates) != 3 {
+		t.Fatalf("ride-aggregates has %d records, want one per finished ride", len(aggregates))
+	}
+	var m TripMetrics
+	if err := json.Unmarshal(aggregates[0].Value, &m); err != nil || string(aggregates[0].Key) != "trip-1" ||
+		m.AcceptLatencySeconds != 60 || m.PickupWaitSeconds != 300 || m.DurationSeconds != 900 || m.DistanceKM != 4.5 {
+		t.Errorf("trip-1 metrics = %+v, %v", m, err)
+	}
+
+	// Only the assigned rides were re-keyed, with the completion's driver
+	// filled in
+	byDriver := broker.Messages(topics.RideEventsByDriver)
+	if len(byDriver) != 4 {
+		t.Fatalf("ride-events-by-driver has %d events, want 4", len(byDriver))
+	}
+	for _, msg := range byDriver {
+		var e events.RideEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil || string(msg.Key) != "driver-1" || e.DriverID != "driver-1" {
+			t.Errorf("re-keyed event %s = %+v, %v", msg.Key, e, err)
+		}
+	}
+	// and redelivered once more, the driver's stats still count it once
+	broker.Produce(byDriver[1], nil)
+
+	drivers := NewDriverStatsTable()
+	runProcessor(t, broker, DriverStatsName, topics.RideEventsByDriver, drivers, DriverStatsHandler(drivers))
+	s, ok := drivers.Get("driver-1")
+	if !ok || s.Accepted != 2 || s.Completed != 1 || s.Cancelled != 1 || s.Earnings != events.NewMoney(1250, events.USD) ||
+		s.DistanceKM != 4.5 || !s.LastRideAt.Equal(start.Add(32*time.Minute)) {
+		t.Errorf("driver-1 = %+v, %v", s, ok)
+	}
+
+	// Restarted, both pick up from their changelogs
+	publish(t, broker, events.NewTripStarted("trip-1", at(6)))
+	rides = NewRideStateTable()
+	runProcessor(t, broker, RideStateName, topics.RideEvents, rides, RideStateHandler(rides))
+	if r, _ := rides.Get("trip-1"); r.State != events.StateCompleted || len(r.EventIDs) != 5 {
+		t.Errorf("trip-1 after a late start = %+v, want it left COMPLETED", r)
+	}
+	if n := len(broker.Messages(topics.RideAggregates)); n != 3 {
+		t.Errorf("ride-aggregates has %d records after a restart, want 3", n)
+	}
+	drivers = NewDriverStatsTable()
+	runProcessor(t, broker, DriverStatsName, topics.RideEventsByDriver, drivers, DriverStatsHandler(drivers))
+	if got, _ := drivers.Get("driver-1"); got.Completed != 1 || got.Accepted != 2 {
+		t.Errorf("driver-1 after a restart = %+v", got)
+	}
+}
+
+func TestDriverStats_Apply(t *testing.T) {
+	var s DriverStats
+	for i := range recentEventIDs + 5 {
+		e := events.NewRideAccepted("trip", "driver-1", events.WithID(string(rune('a'+i))))
+		if !s.Apply(e) {
+			t.Fatalf("event %d not applied", i)
+		}
+	}
+	if s.Accepted != recentEventIDs+5 || len(s.RecentEventIDs) != recentEventIDs {
+		t.Errorf("stats = %d accepted, %d recent IDs", s.Accepted, len(s.RecentEventIDs))
+	}
+	if s.Apply(events.NewTripStarted("trip", events.WithDriver("driver-1"))) {
+		t.Error("a STARTED event was counted")
+	}
+
+	s.Apply(events.NewTripCompleted("trip", 1, events.NewMoney(500, events.USD), events.WithDriver("driver-1")))
+	s.Apply(events.NewTripCompleted("trip", 1, events.NewMoney(900, "EUR"), events.WithDriver("driver-1")))
+	if s.Completed != 2 || s.Earnings != events.NewMoney(500, events.USD) {
+		t.Errorf("stats = %+v, want the EUR fare left out of the earnings", s)
+	}
+}
@@ -0,0 +1,303 @@
+// Package processors runs stateful stream processing on Kafka alone. A
+// Processor consumes its input topics with a Handler that reads and writes
+// keyed state Tables. Every write to a table is also produced to the table's
+// changelog, a compacted topic keyed like the table, and a Processor restores
+// its tables from their changelogs when it starts, so the state survives
+// restarts without a database.
+//
+// Each instance holds every row of its tables, following the changelog for
+// the writes of the other instances of its group. Input topics are keyed
+// like the tables they write, so a row is only ever written by the instance
+// consuming its key's partition.
+package processors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/replay"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+// HeaderInstance names the instance that wrote a changelog record, so
+// instances following a changelog skip their own writes.
+const HeaderInstance = "processor-instance"
+
+// Codec encodes the rows of a Table for its changelog.
+type Codec[V any] interface {
+	Encode(v V) ([]byte, error)
+	Decode(data []byte) (V, error)
+}
+
+// JSONCodec encodes rows as JSON.
+type JSONCodec[V any] struct{}
+
+// Encode implements Codec.
+func (JSONCodec[V]) Encode(v V) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Decode implements Codec.
+func (JSONCodec[V]) Decode(data []byte) (V, error) {
+	var v V
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// StateTable is a Table of any row type, as a Processor holds them.
+type StateTable interface {
+	// Topic returns the table's changelog topic.
+	Topic() string
+	// Len returns the number of rows.
+	Len() int
+
+	apply(msg *kafka.Message) error
+	bind(sink rideconsumer.Sink, instance string)
+	// begin, commit, and rollback bracket the writes of a transaction, so
+	// an aborted one leaves the rows as they were.
+	begin()
+	commit()
+	rollback()
+}
+
+// Table is a keyed state table held in memory and backed by a changelog.
+// Its methods are safe for concurrent use. Set and Delete fail until the
+// table is given to a Processor, which restores it before handling input.
+type Table[V any] struct {
+	topic string
+	codec Codec[V]
+
+	mu       sync.RWMutex
+	rows     map[string]V
+	sink     rideconsumer.Sink
+	instance string
+	// undo holds the rows written in the open transaction as they were
+	// before it; it is nil outside transactions.
+	undo map[string]undoRow[V]
+}
+
+type undoRow[V any] struct {
+	v  V
+	ok bool // whether there was a row
+}
+
+// NewTable returns an empty table whose changelog is topic.
+func NewTable[V any](topic string, codec Codec[V]) *Table[V] {
+	return &Table[V]{topic: topic, codec: codec, rows: make(map[string]V)}
+}
+
+// Topic implements StateTable.
+func (t *Table[V]) Topic() string {
+	return t.topic
+}
+
+// Len implements StateTable.
+func (t *Table[V]) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.rows)
+}
+
+// Get returns the row of key, and whether there is one.
+func (t *Table[V]) Get(key string) (V, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	v, ok := t.rows[key]
+	return v, ok
+}
+
+// Range calls fn with each row, in no particular order, until it returns
+// false. fn must not write to the table.
+func (t *Table[V]) Range(fn func(key string, v V) bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for k, v := range t.rows {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Set stores v as the row of key and produces it to the changelog.
+func (t *Table[V]) Set(key string, v V) error {
+	value, err := t.codec.Encode(v)
+	if err != nil {
+		return fmt.Errorf("%s: encode %q: %w", t.topic, key, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.produce(key, value); err != nil {
+		return err
+	}
+	t.remember(key)
+	t.rows[key] = v
+	tableRows.WithLabelValues(t.topic).Set(float64(len(t.rows)))
+	return nil
+}
+
+// Delete removes the row of key, producing a tombstone to the changelog so
+// compaction drops the key too.
+func (t *Table[V]) Delete(key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.produce(key, nil); err != nil {
+		return err
+	}
+	t.remember(key)
+	delete(t.rows, key)
+	tableRows.WithLabelValues(t.topic).Set(float64(len(t.rows)))
+	return nil
+}
+
+// produce writes a changelog record. t.mu is held.
+func (t *Table[V]) produce(key string, value []byte) error {
+	if t.sink == nil {
+		return fmt.Errorf("%s: table is not bound to a processor", t.topic)
+	}
+	topic := t.topic
+	return t.sink.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(key),
+		Value:          value,
+		Headers:        []kafka.Header{{Key: HeaderInstance, Value: []byte(t.instance)}},
+	}, nil)
+}
+
+// apply stores a changelog record without producing it again. A record
+// without a value is a tombstone.
+func (t *Table[V]) apply(msg *kafka.Message) error {
+	key := string(msg.Key)
+	var (
+		v   V
+		err error
+	)
+	if msg.Value != nil {
+		if v, err = t.codec.Decode(msg.Value); err != nil {
+			return fmt.Errorf("%s: decode %q: %w", t.topic, key, err)
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if msg.Value == nil {
+		delete(t.rows, key)
+	} else {
+		t.rows[key] = v
+	}
+	tableRows.WithLabelValues(t.topic).Set(float64(len(t.rows)))
+	return nil
+}
+
+// remember keeps the row of key as it was before the open transaction, if
+// any. t.mu is held.
+func (t *Table[V]) remember(key string) {
+	if t.undo == nil {
+		return
+	}
+	if _, ok := t.undo[key]; !ok {
+		v, ok := t.rows[key]
+		t.undo[key] = undoRow[V]{v, ok}
+	}
+}
+
+func (t *Table[V]) begin() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.undo = make(map[string]undoRow[V])
+}
+
+func (t *Table[V]) commit() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.undo = nil
+}
+
+func (t *Table[V]) rollback() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, row := range t.undo {
+		if row.ok {
+			t.rows[key] = row.v
+		} else {
+			delete(t.rows, key)
+		}
+	}
+	t.undo = nil
+	tableRows.WithLabelValues(t.topic).Set(float64(len(t.rows)))
+}
+
+func (t *Table[V]) bind(sink rideconsumer.Sink, instance string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sink, t.instance = sink, instance
+}
+
+// OpenChangelog returns a Source reading every partition of a changelog
+// topic from its start: it returns io.EOF once on reaching the end the
+// topic had when opened, then the records written since.
+func OpenChangelog(brokers, topic, group string) (rideconsumer.Source, error) {
+	return replay.Open(replay.Config{Brokers: brokers, GroupID: group, Topic: topic, Follow: true})
+}
+
+// restore applies the records of src to t until src returns io.EOF, and
+// returns how many it applied. Records that cannot be decoded are logged
+// and skipped.
+func restore(ctx context.Context, t StateTable, src rideconsumer.Source) (int, error) {
+	n := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		msg, err := src.ReadMessage(time.Second)
+		switch {
+		case errors.Is(err, io.EOF):
+			return n, nil
+		case replay.IsTimeout(err):
+			continue
+		case err != nil:
+			return n, fmt.Errorf("%s: %w", t.Topic(), err)
+		}
+		if err := t.apply(msg); err != nil {
+			slog.Warn("Skipping changelog record", "error", err)
+			continue
+		}
+		n++
+	}
+}
+
+// follow applies the records other instances write to t's changelog until
+// ctx is cancelled.
+func follow(ctx context.Context, t StateTable, src rideconsumer.Source, instance string) {
+	for ctx.Err() == nil {
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			if !replay.IsTimeout(err) && !errors.Is(err, io.EOF) {
+				slog.Error("Changelog error", "topic", t.Topic(), "error", err)
+			}
+			continue
+		}
+		if writtenBy(msg) == instance {
+			continue
+		}
+		if err := t.apply(msg); err != nil {
+			slog.Warn("Skipping changelog record", "error", err)
+		}
+	}
+}
+
+// writtenBy returns the instance that wrote a changelog record.
+func writtenBy(msg *kafka.Message) string {
+	for _, h := range msg.Headers {
+		if h.Key == HeaderInstance {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package processors
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+// changelogSource reads a topic of a MemoryBroker as OpenChangelog does: it
+// returns io.EOF once after the messages the topic had when opened.
+type changelogSource struct {
+	rideconsumer.Source
+	left int // -1 once io.EOF was returned
+}
+
+func openChangelog(broker *rideconsumer.MemoryBroker) func(string) (rideconsumer.Source, error) {
+	return func(topic string) (rideconsumer.Source, error) {
+		return &changelogSource{Source: broker.Source(topic), left: len(broker.Messages(topic))}, nil
+	}
+}
+
+func (s *changelogSource) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	if s.left == 0 {
+		s.left = -1
+		return nil, io.EOF
+	}
+	msg, err := s.Source.ReadMessage(timeout)
+	if err == nil && s.left > 0 {
+		s.left--
+	}
+	return msg, err
+}
+
+// endingSource yields the messages of a topic, then io.EOF.
+type endingSource struct {
+	rideconsumer.Source
+	left int
+}
+
+func (s *endingSource) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	if s.left == 0 {
+		return nil, io.EOF
+	}
+	s.left--
+	return s.Source.ReadMessage(timeout)
+}
+
+// counter is a table of counts kept by key, and its processor counting the
+// keys of the "input" topic.
+func counter(t *testing.T, broker *rideconsumer.MemoryBroker, instance string) (*Processor, *Table[int]) {
+	t.Helper()
+	counts := NewTable("counts", JSONCodec[int]{})
+	p, err := New(Config{
+		Name:     "counter",
+		Input:    []string{"input"},
+		Tables:   []StateTable{counts},
+		Instance: instance,
+		Handler: func(ctx context.Context, msg *kafka.Message, out Emitter) error {
+			n, _ := counts.Get(string(msg.Key))
+			if string(msg.Value) == "reset" {
+				return counts.Delete(string(msg.Key))
+			}
+			return counts.Set(string(msg.Key), n+1)
+		},
+		Source:        &endingSource{Source: broker.Source("input"), left: len(broker.Messages("input"))},
+		Sink:          broker,
+		OpenChangelog: openChangelog(broker),
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return p, counts
+}
+
+func TestProcessor_RestoresFromChangelog(t *testing.T) {
+	broker := rideconsumer.NewMemoryBroker()
+	for _, key := range []string{"a", "b", "a", "c"} {
+		broker.Publish("input", []byte(key), []byte("1"))
+	}
+	broker.Publish("input", []byte("c"), []byte("reset"))
+
+	p, counts := counter(t, broker, "one")
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n, _ := counts.Get("a"); n != 2 || counts.Len() != 2 {
+		t.Fatalf("a = %d with %d rows, want 2 with c deleted", n, counts.Len())
+	}
+	changelog := broker.Messages("counts")
+	if len(changelog) != 5 || changelog[4].Value != nil || writtenBy(changelog[0]) != "one" {
+		t.Fatalf("changelog = %v, want 5 records ending with a tombstone", changelog)
+	}
+
+	// A new instance starts from the changelog, not from scratch
+	broker.Publish("input", []byte("a"), []byte("1"))
+	p, counts = counter(t, broker, "two")
+	p.cfg.Source = &endingSource{Source: broker.Source("input"), left: len(broker.Messages("input"))}
+	restored := 0
+	p.cfg.Handler = func(ctx context.Context, msg *kafka.Message, out Emitter) error {
+		restored = counts.Len()
+		return nil
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n, _ := counts.Get("a"); n != 2 || restored != 2 {
+		t.Errorf("a = %d with %d rows restored, want the 2 rows restored before the input", n, restored)
+	}
+}
+
+func TestFollow_SkipsOwnWrites(t *testing.T) {
+	broker := rideconsumer.NewMemoryBroker()
+	counts := NewTable("counts", JSONCodec[int]{})
+	counts.bind(broker, "one")
+	other := NewTable("counts", JSONCodec[int]{})
+	other.bind(broker, "two")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		follow(ctx, counts, broker.Source("counts"), "one")
+	}()
+	if err := counts.Set("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := counts.Set("a", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Set("b", 7); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for counts.Len() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if n, _ := counts.Get("b"); n != 7 {
+		t.Errorf("b = %d, want the other instance's write followed", n)
+	}
+	if n, _ := counts.Get("a"); n != 2 {
+		t.Errorf("a = %d, want 2", n)
+	}
+}
+
+func TestTable_Unbound(t *testing.T) {
+	counts := NewTable("counts", JSONCodec[int]{})
+	if err := counts.Set("a", 1); err == nil {
+		t.Error("expected Set on an unbound table to fail")
+	}
+	if _, ok := counts.Get("a"); ok {
+		t.Error("a failed Set stored its row")
+	}
+}
@@ -0,0 +1,213 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/replay"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+// DefaultCommitInterval is how long a transaction collects input unless
+// Config.CommitInterval says otherwise. Longer transactions write less
+// often, but delay what read_committed consumers see by as much.
+const DefaultCommitInterval = 100 * time.Millisecond
+
+// commitAttempts is how many times a commit failing with a retriable error
+// is tried before the transaction is aborted.
+const commitAttempts = 3
+
+// TxSink is a Sink that writes in transactions, as a *kafka.Producer with a
+// transactional.id does.
+type TxSink interface {
+	rideconsumer.Sink
+	BeginTransaction() error
+	SendOffsetsToTransaction(ctx context.Context, offsets []kafka.TopicPartition, group *kafka.ConsumerGroupMetadata) error
+	CommitTransaction(ctx context.Context) error
+	AbortTransaction(ctx context.Context) error
+}
+
+// TxSource is a Source whose offsets a TxSink commits, as a *kafka.Consumer.
+type TxSource interface {
+	rideconsumer.Source
+	GetConsumerGroupMetadata() (*kafka.ConsumerGroupMetadata, error)
+	Seek(partition kafka.TopicPartition, timeoutMs int) error
+}
+
+var (
+	_ TxSink   = (*kafka.Producer)(nil)
+	_ TxSource = (*kafka.Consumer)(nil)
+)
+
+type partition struct {
+	topic string
+	id    int32
+}
+
+// transaction is the open transaction of a transactional processor.
+type transaction struct {
+	started time.Time
+	// first and next are the offsets of the first message handled in the
+	// transaction and of the one after the last, by partition.
+	first, next map[partition]kafka.Offset
+	// err is the handler error that failed the transaction, if any.
+	err error
+}
+
+func (tx *transaction) add(msg *kafka.Message) {
+	p := partition{*msg.TopicPartition.Topic, msg.TopicPartition.Partition}
+	if _, ok := tx.first[p]; !ok {
+		tx.first[p] = msg.TopicPartition.Offset
+	}
+	tx.next[p] = msg.TopicPartition.Offset + 1
+}
+
+// offsets returns the offsets to commit with the transaction.
+func (tx *transaction) offsets() []kafka.TopicPartition {
+	out := make([]kafka.TopicPartition, 0, len(tx.next))
+	for p, offset := range tx.next {
+		topic := p.topic
+		out = append(out, kafka.TopicPartition{Topic: &topic, Partition: p.id, Offset: offset})
+	}
+	return out
+}
+
+// consumeTx handles messages from src until ctx is cancelled or src ends,
+// in a transaction per CommitInterval of input.
+func (p *Processor) consumeTx(ctx context.Context, src TxSource, sink TxSink) error {
+	for {
+		if ctx.Err() != nil {
+			// What was handled is still worth committing
+			return p.endTx(context.WithoutCancel(ctx), src, sink, false)
+		}
+		wait := time.Second
+		if p.tx != nil {
+			if wait = time.Until(p.tx.started.Add(p.cfg.CommitInterval)); wait <= 0 {
+				if err := p.endTx(ctx, src, sink, true); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		msg, err := src.ReadMessage(wait)
+		if err != nil {
+			if replay.IsTimeout(err) {
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return p.endTx(ctx, src, sink, false)
+			}
+			slog.Error("Consumer error", "processor", p.cfg.Name, "error", err)
+			continue
+		}
+		if p.tx == nil {
+			if err := sink.BeginTransaction(); err != nil {
+				return fmt.Errorf("begin transaction: %w", err)
+			}
+			for _, t := range p.cfg.Tables {
+				t.begin()
+			}
+			p.tx = &transaction{started: time.Now(), first: map[partition]kafka.Offset{}, next: map[partition]kafka.Offset{}}
+		}
+		p.tx.add(msg)
+		if err := p.handle(ctx, msg); err != nil {
+			// The handler may have written part of its output and state
+			p.tx.err = err
+			if err := p.endTx(ctx, src, sink, true); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// endTx commits the open transaction with the offsets of its input. If a
+// handler failed in it or the commit fails, it aborts the transaction instead
+// and rolls the tables back, and with rewind seeks the input back to the
+// transaction's first messages to handle them again. Only errors the producer
+// cannot go on from, as when a newer instance with its transactional ID
+// fenced it off, are returned.
+func (p *Processor) endTx(ctx context.Context, src TxSource, sink TxSink, rewind bool) error {
+	tx := p.tx
+	if tx == nil {
+		return nil
+	}
+	p.tx = nil
+
+	err := tx.err
+	if err == nil {
+		err = commitTx(ctx, src, sink, tx)
+	}
+	if err == nil {
+		for _, t := range p.cfg.Tables {
+			t.commit()
+		}
+		transactions.WithLabelValues(p.cfg.Name, "committed").Inc()
+		return nil
+	}
+	var kerr kafka.Error
+	if errors.As(err, &kerr) && kerr.IsFatal() {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	slog.Warn("Aborting transaction", "processor", p.cfg.Name, "error", err)
+	transactions.WithLabelValues(p.cfg.Name, "aborted").Inc()
+	if err := sink.AbortTransaction(ctx); err != nil {
+		return fmt.Errorf("abort transaction: %w", err)
+	}
+	for _, t := range p.cfg.Tables {
+		t.rollback()
+	}
+	if !rewind {
+		return nil
+	}
+	for part, offset := range tx.first {
+		tp := kafka.TopicPartition{Topic: &part.topic, Partition: part.id, Offset: offset}
+		if err := src.Seek(tp, 5000); err != nil {
+			return fmt.Errorf("rewind %s [%d]: %w", part.topic, part.id, err)
+		}
+	}
+	return nil
+}
+
+// commitTx sends the offsets of tx's input to it and commits it, trying
+// again on retriable errors.
+func commitTx(ctx context.Context, src TxSource, sink TxSink, tx *transaction) error {
+	group, err := src.GetConsumerGroupMetadata()
+	if err != nil {
+		return err
+	}
+	if err := sink.SendOffsetsToTransaction(ctx, tx.offsets(), group); err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		err := sink.CommitTransaction(ctx)
+		var kerr kafka.Error
+		if err == nil || !errors.As(err, &kerr) || !kerr.IsRetriable() || attempt == commitAttempts {
+			return err
+		}
+	}
+}
+
+// revoke returns the rebalance callback of a transactional processor's
+// consumer, which ends the open transaction before its partitions go. The
+// new owners start from the offsets committed with it, so an aborted one is
+// not rewound: they handle its input again.
+func (p *Processor) revoke(ctx context.Context) kafka.RebalanceCb {
+	return func(c *kafka.Consumer, ev kafka.Event) error {
+		if _, ok := ev.(kafka.RevokedPartitions); !ok {
+			return nil
+		}
+		if err := p.endTx(context.WithoutCancel(ctx), c, p.sink.(TxSink), false); err != nil {
+			slog.Error("Failed to end transaction on rebalance", "processor", p.cfg.Name, "error", err)
+			return err
+		}
+		return nil
+	}
+}
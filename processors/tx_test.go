@@ -0,0 +1,224 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+// txBroker writes to a MemoryBroker in transactions: what is produced in one
+// is held until it commits, and dropped if it aborts.
+type txBroker struct {
+	*rideconsumer.MemoryBroker
+	open      bool
+	pending   []*kafka.Message
+	offsets   []kafka.TopicPartition
+	committed map[partition]kafka.Offset
+	// failCommits is how many commits are left to fail.
+	failCommits int
+	// failProduce is the number of the produce call to fail, counting from 1.
+	failProduce     int
+	produced        int
+	commits, aborts int
+}
+
+func newTxBroker() *txBroker {
+	return &txBroker{MemoryBroker: rideconsumer.NewMemoryBroker(), committed: map[partition]kafka.Offset{}}
+}
+
+func (b *txBroker) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	if !b.open {
+		return errors.New("produce outside a transaction")
+	}
+	if b.produced++; b.produced == b.failProduce {
+		return errors.New("produce failed")
+	}
+	b.pending = append(b.pending, msg)
+	return nil
+}
+
+func (b *txBroker) BeginTransaction() error {
+	if b.open {
+		return errors.New("transaction already open")
+	}
+	b.open = true
+	return nil
+}
+
+func (b *txBroker) SendOffsetsToTransaction(ctx context.Context, offsets []kafka.TopicPartition, group *kafka.ConsumerGroupMetadata) error {
+	b.offsets = offsets
+	return nil
+}
+
+func (b *txBroker) CommitTransaction(ctx context.Context) error {
+	if b.failCommits > 0 {
+		b.failCommits--
+		return kafka.NewError(kafka.ErrInvalidTxnState, "commit failed", false)
+	}
+	for _, msg := range b.pending {
+		b.MemoryBroker.Produce(msg, nil)
+	}
+	for _, tp := range b.offsets {
+		b.committed[partition{*tp.Topic, tp.Partition}] = tp.Offset
+	}
+	b.commits++
+	b.open, b.pending, b.offsets = false, nil, nil
+	return nil
+}
+
+func (b *txBroker) AbortTransaction(ctx context.Context) error {
+	b.aborts++
+	b.open, b.pending, b.offsets = false, nil, nil
+	return nil
+}
+
+// txSource reads a topic of a txBroker from its committed offset to its end.
+type txSource struct {
+	broker *txBroker
+	topic  string
+	next   int
+}
+
+func (s *txSource) ReadMessage(time.Duration) (*kafka.Message, error) {
+	msgs := s.broker.Messages(s.topic)
+	if s.next >= len(msgs) {
+		return nil, io.EOF
+	}
+	s.next++
+	return msgs[s.next-1], nil
+}
+
+func (s *txSource) Seek(tp kafka.TopicPartition, timeoutMs int) error {
+	s.next = int(tp.Offset)
+	return nil
+}
+
+func (s *txSource) GetConsumerGroupMetadata() (*kafka.ConsumerGroupMetadata, error) {
+	return &kafka.ConsumerGroupMetadata{}, nil
+}
+
+func (s *txSource) Close() error { return nil }
+
+// runTxCounter runs a transactional processor counting the keys of "input"
+// and emitting each count to "output", from the committed offset, and
+// returns its table.
+func runTxCounter(t *testing.T, broker *txBroker, commitInterval time.Duration) *Table[int] {
+	t.Helper()
+	counts := NewTable("counts", JSONCodec[int]{})
+	p, err := New(Config{
+		Name:           "counter",
+		Input:          []string{"input"},
+		Tables:         []StateTable{counts},
+		Transactional:  true,
+		CommitInterval: commitInterval,
+		Handler: func(ctx context.Context, msg *kafka.Message, out Emitter) error {
+			n, _ := counts.Get(string(msg.Key))
+			if err := counts.Set(string(msg.Key), n+1); err != nil {
+				return err
+			}
+			return out.Emit("output", msg.Key, msg.Value)
+		},
+		Source:        &txSource{broker: broker, topic: "input", next: int(broker.committed[partition{"input", 0}])},
+		Sink:          broker,
+		OpenChangelog: openChangelog(broker.MemoryBroker),
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return counts
+}
+
+func TestProcessor_TransactionAborted(t *testing.T) {
+	broker := newTxBroker()
+	for _, key := range []string{"a", "b", "a"} {
+		broker.Publish("input", []byte(key), []byte(key))
+	}
+	// A transaction per message, the first of which fails to commit
+	broker.failCommits = 1
+	counts := runTxCounter(t, broker, time.Nanosecond)
+
+	if n, _ := counts.Get("a"); n != 2 {
+		t.Errorf("a = %d, want 2 with the aborted message handled again", n)
+	}
+	if broker.aborts != 1 || broker.commits != 3 {
+		t.Errorf("%d aborts and %d commits, want 1 and 3", broker.aborts, broker.commits)
+	}
+	if n, m := len(broker.Messages("counts")), len(broker.Messages("output")); n != 3 || m != 3 {
+		t.Errorf("changelog has %d records and output %d, want 3 each", n, m)
+	}
+	if off := broker.committed[partition{"input", 0}]; off != 3 {
+		t.Errorf("committed offset = %d, want 3", off)
+	}
+}
+
+func TestProcessor_HandlerFailureAbortsTransaction(t *testing.T) {
+	broker := newTxBroker()
+	for _, key := range []string{"a", "b", "a"} {
+		broker.Publish("input", []byte(key), []byte(key))
+	}
+	// The second message's emit fails after its count was written
+	broker.failProduce = 4
+	counts := runTxCounter(t, broker, time.Hour)
+
+	if n, _ := counts.Get("a"); n != 2 {
+		t.Errorf("a = %d, want 2", n)
+	}
+	if n, _ := counts.Get("b"); n != 1 {
+		t.Errorf("b = %d, want 1 with the failed handling rolled back", n)
+	}
+	if broker.aborts != 1 || broker.commits != 1 {
+		t.Errorf("%d aborts and %d commits, want 1 and 1", broker.aborts, broker.commits)
+	}
+	if n, m := len(broker.Messages("counts")), len(broker.Messages("output")); n != 3 || m != 3 {
+		t.Errorf("changelog has %d records and output %d, want 3 each", n, m)
+	}
+	if off := broker.committed[partition{"input", 0}]; off != 3 {
+		t.Errorf("committed offset = %d, want 3", off)
+	}
+}
+
+func TestProcessor_TransactionLostInCrash(t *testing.T) {
+	broker := newTxBroker()
+	broker.Publish("input", []byte("a"), []byte("a"))
+	runTxCounter(t, broker, time.Nanosecond)
+
+	// The run handling these never commits, as when it crashes
+	broker.Publish("input", []byte("a"), []byte("a"))
+	broker.Publish("input", []byte("b"), []byte("b"))
+	broker.failCommits = 1
+	if counts := runTxCounter(t, broker, time.Hour); counts.Len() != 1 {
+		t.Errorf("rows = %d, want b rolled back", counts.Len())
+	}
+
+	// so the next run handles them again, from the state committed before
+	counts := runTxCounter(t, broker, time.Hour)
+	if a, _ := counts.Get("a"); a != 2 || counts.Len() != 2 {
+		t.Errorf("a = %d with %d rows, want 2 with 2 rows", a, counts.Len())
+	}
+	if n := len(broker.Messages("output")); n != 3 {
+		t.Errorf("output has %d messages, want each input written once", n)
+	}
+}
+
+func TestNew_TransactionalNeedsTxSink(t *testing.T) {
+	broker := rideconsumer.NewMemoryBroker()
+	_, err := New(Config{
+		Name:          "counter",
+		Input:         []string{"input"},
+		Transactional: true,
+		Handler:       func(context.Context, *kafka.Message, Emitter) error { return nil },
+		Sink:          broker,
+	})
+	if err == nil {
+		t.Error("expected a MemoryBroker Sink to be refused")
+	}
+}
@@ -0,0 +1,101 @@
+package rebuild
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// backfillBatchSize is how many ride events Backfill inserts per round trip.
+const backfillBatchSize = 500
+
+// EventSource yields replayed events, returning io.EOF once it has no more.
+type EventSource interface {
+	Next(ctx context.Context) (events.RideEvent, error)
+}
+
+// BackfillStore is the part of rides_db.RideStore a backfill writes to.
+type BackfillStore interface {
+	InsertRideEvents(ctx context.Context, evts []events.RideEvent) (int64, error)
+	UpsertZone(ctx context.Context, z rides_db.Zone) error
+	UpsertSurgeMultiplier(ctx context.Context, m rides_db.SurgeMultiplier) error
+}
+
+// BackfillReport summarizes a backfill.
+type BackfillReport struct {
+	Read     int64 // events read from the source
+	Inserted int64 // ride events that were not stored yet
+	Surges   int64 // surge updates applied
+	Skipped  int64 // events without the IDs they need
+}
+
+// Backfill stores every event src yields: ride lifecycle events go into
+// ride_events, where ones already stored are skipped as duplicates, and surge
+// updates into the zone and surge multiplier tables. Run the rebuild afterwards
+// to bring the derived tables in line.
+func Backfill(ctx context.Context, store BackfillStore, src EventSource) (BackfillReport, error) {
+	var (
+		report BackfillReport
+		batch  []events.RideEvent
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := store.InsertRideEvents(ctx, batch)
+		report.Inserted += n
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		e, err := src.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return report, flush()
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Read++
+
+		if e.Type == events.EventSurgeUpdated {
+			if err := applySurge(ctx, store, e); err != nil {
+				return report, err
+			}
+			report.Surges++
+			continue
+		}
+		if e.ID == "" || e.TripID == "" {
+			slog.Warn("Skipping replayed event without an ID or trip", "id", e.ID, "trip_id", e.TripID, "type", e.Type)
+			report.Skipped++
+			continue
+		}
+		batch = append(batch, e)
+		if len(batch) >= backfillBatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+}
+
+// applySurge records a SURGE_UPDATED event's zone and multiplier, as the
+// consumer does.
+func applySurge(ctx context.Context, store BackfillStore, e events.RideEvent) error {
+	p, ok := events.PayloadAs[events.SurgeUpdatedPayload](e)
+	if !ok {
+		return nil
+	}
+	if err := store.UpsertZone(ctx, rides_db.Zone{ID: string(p.ZoneID), Name: p.ZoneName, UpdatedAt: e.OccurredAt}); err != nil {
+		return err
+	}
+	return store.UpsertSurgeMultiplier(ctx, rides_db.SurgeMultiplier{
+		ZoneID:        string(p.ZoneID),
+		EffectiveFrom: e.OccurredAt,
+		Multiplier:    p.Multiplier,
+	})
+}
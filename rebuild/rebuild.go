@@ -0,0 +1,164 @@
+// Package rebuild regenerates the tables derived from ride_events (rides, trips,
+// and the aggregation windows), and backfills ride_events itself from a replay
+// of the ride events topic, so derived state can be recomputed after a logic
+// change or corruption.
+package rebuild
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// Store is the part of rides_db.RideStore a rebuild needs.
+type Store interface {
+	ExportRideEvents(ctx context.Context, tr rides_db.TimeRange, fn func(events.RideEvent) error) error
+	ResetDerived(ctx context.Context, tables ...rides_db.DerivedTable) error
+	UpsertRideState(ctx context.Context, e events.RideEvent) error
+	InsertTrip(ctx context.Context, t aggregation.Trip) error
+	RefreshTrip(ctx context.Context, tripID string) error
+	UpsertEventWindow(ctx context.Context, w aggregation.WindowResult) error
+	AppendAudit(ctx context.Context, e rides_db.AuditEntry) error
+}
+
+// Config selects what is rebuilt.
+type Config struct {
+	// Tables are the derived tables to rebuild; nil rebuilds all of them.
+	Tables []rides_db.DerivedTable
+	// Windows is how events are windowed; the zero value uses aggregation.DefaultConfig.
+	Windows aggregation.Config
+}
+
+func (c *Config) setDefaults() {
+	if c.Tables == nil {
+		c.Tables = rides_db.DerivedTables
+	}
+	if c.Windows == (aggregation.Config{}) {
+		c.Windows = aggregation.DefaultConfig
+	}
+}
+
+// Report summarizes a rebuild.
+type Report struct {
+	Events  int64 // ride events replayed
+	Trips   int64 // trips rows written
+	Windows int64 // window rows written
+	Took    time.Duration
+}
+
+// allTime spans every event time the supported databases can store.
+var allTime = rides_db.TimeRange{
+	From: time.Unix(0, 0).UTC(),
+	To:   time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC),
+}
+
+// Run deletes the rows of the configured tables that ride_events can reproduce
+// (see rides_db.Store.ResetDerived) and replays every stored event, in event-time
+// order, through the same logic the consumer applies, then records the rebuild
+// in the audit log. The consumer should be stopped while it runs, or it may
+// write rows the rebuild then overwrites.
+func Run(ctx context.Context, store Store, cfg Config) (Report, error) {
+	cfg.setDefaults()
+	start := time.Now()
+
+	if err := store.ResetDerived(ctx, cfg.Tables...); err != nil {
+		return Report{}, err
+	}
+	r := &rebuilder{
+		store:   store,
+		rides:   slices.Contains(cfg.Tables, rides_db.DerivedRides),
+		windows: slices.Contains(cfg.Tables, rides_db.DerivedWindows),
+		agg:     aggregation.NewAggregator(cfg.Windows),
+	}
+	if slices.Contains(cfg.Tables, rides_db.DerivedTrips) {
+		r.trips = aggregation.NewTripAssembler()
+	}
+	err := store.ExportRideEvents(ctx, allTime, func(e events.RideEvent) error {
+		return r.add(ctx, e)
+	})
+	if err == nil && r.windows {
+		err = r.storeWindows(ctx, r.agg.Flush())
+	}
+	r.report.Took = time.Since(start)
+	if err != nil {
+		return r.report, err
+	}
+
+	entry := rides_db.AuditEntry{Kind: rides_db.AuditRebuild, RecordedAt: start, Duration: r.report.Took, Rows: r.report.Events}
+	if err := store.AppendAudit(ctx, entry); err != nil {
+		slog.Error("Failed to record rebuild in the audit log", "error", err)
+	}
+	return r.report, nil
+}
+
+// rebuilder replays events into the derived tables.
+type rebuilder struct {
+	store   Store
+	rides   bool
+	windows bool
+	trips   *aggregation.TripAssembler // nil unless trips are rebuilt
+	agg     *aggregation.Aggregator
+
+	// cutoff is the oldest replayed event's time. Windows that start before it
+	// may also hold archived events, so they were kept and are not rewritten.
+	cutoff time.Time
+	report Report
+}
+
+func (r *rebuilder) add(ctx context.Context, e events.RideEvent) error {
+	r.report.Events++
+	if r.cutoff.IsZero() {
+		r.cutoff = e.OccurredAt
+	}
+
+	if r.rides {
+		if err := r.store.UpsertRideState(ctx, e); err != nil {
+			return err
+		}
+	}
+	if r.trips != nil {
+		if err := r.addTrip(ctx, e); err != nil {
+			return err
+		}
+	}
+	if r.windows {
+		// Events arrive in event-time order, so none is ever too late
+		results, _ := r.agg.Add(e)
+		if err := r.storeWindows(ctx, results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addTrip writes the trips row once e completes its trip, rebuilding it from
+// the stored events when the start of the trip was archived.
+func (r *rebuilder) addTrip(ctx context.Context, e events.RideEvent) error {
+	trip, done := r.trips.Add(e)
+	if !done {
+		return nil
+	}
+	r.report.Trips++
+	if trip.RequestedAt.IsZero() {
+		return r.store.RefreshTrip(ctx, trip.TripID)
+	}
+	return r.store.InsertTrip(ctx, trip)
+}
+
+func (r *rebuilder) storeWindows(ctx context.Context, results []aggregation.WindowResult) error {
+	for _, w := range results {
+		if w.Start.Before(r.cutoff) {
+			continue
+		}
+		if err := r.store.UpsertEventWindow(ctx, w); err != nil {
+			return err
+		}
+		r.report.Windows++
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package rebuild
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+type fakeStore struct {
+	events    []events.RideEvent
+	reset     []rides_db.DerivedTable
+	rides     []string
+	trips     []aggregation.Trip
+	refreshed []string
+	windows   []aggregation.WindowResult
+	audit     []rides_db.AuditEntry
+
+	inserted [][]events.RideEvent
+	zones    []rides_db.Zone
+	surges   []rides_db.SurgeMultiplier
+}
+
+func (f *fakeStore) ExportRideEvents(ctx context.Context, tr rides_db.TimeRange, fn func(events.RideEvent) error) error {
+	for _, e := range f.events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) ResetDerived(ctx context.Context, tables ...rides_db.DerivedTable) error {
+	f.reset = append(f.reset, tables...)
+	return nil
+}
+
+func (f *fakeStore) UpsertRideState(ctx context.Context, e events.RideEvent) error {
+	f.rides = append(f.rides, e.ID)
+	return nil
+}
+
+func (f *fakeStore) InsertTrip(ctx context.Context, t aggregation.Trip) error {
+	f.trips = append(f.trips, t)
+	return nil
+}
+
+func (f *fakeStore) RefreshTrip(ctx context.Context, tripID string) error {
+	f.refreshed = append(f.refreshed, tripID)
+	return nil
+}
+
+func (f *fakeStore) UpsertEventWindow(ctx context.Context, w aggregation.WindowResult) error {
+	f.windows = append(f.windows, w)
+	return nil
+}
+
+func (f *fakeStore) AppendAudit(ctx context.Context, e rides_db.AuditEntry) error {
+	f.audit = append(f.audit, e)
+	return nil
+}
+
+func (f *fakeStore) InsertRideEvents(ctx context.Context, evts []events.RideEvent) (int64, error) {
+	f.inserted = append(f.inserted, slices.Clone(evts))
+	return int64(len(evts)), nil
+}
+
+func (f *fakeStore) UpsertZone(ctx context.Context, z rides_db.Zone) error {
+	f.zones = append(f.zones, z)
+	return nil
+}
+
+func (f *fakeStore) UpsertSurgeMultiplier(ctx context.Context, m rides_db.SurgeMultiplier) error {
+	f.surges = append(f.surges, m)
+	return nil
+}
+
+var base = time.Date(2025, 6, 1, 8, 0, 30, 0, time.UTC)
+
+// storedEvents is a trip whose request was archived, followed by a whole trip.
+func storedEvents() []events.RideEvent {
+	return []events.RideEvent{
+		{ID: "a-2", TripID: "trip-a", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: base,
+			Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+		{ID: "a-3", TripID: "trip-a", Type: events.EventTripCancelled, State: events.StateCancelled, OccurredAt: base.Add(time.Minute),
+			Payload: events.RideCancelledPayload{CancelledBy: "driver"}},
+		{ID: "b-1", TripID: "trip-b", Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: base.Add(2 * time.Minute),
+			Payload: events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St"}},
+		{ID: "b-2", TripID: "trip-b", Type: events.EventTripCompleted, State: events.StateCompleted, OccurredAt: base.Add(10 * time.Minute),
+			Payload: events.RideCompletedPayload{DistanceKM: 4, Fare: events.NewMoney(700, events.USD)}},
+	}
+}
+
+func TestRun_RebuildsEveryTable(t *testing.T) {
+	store := &fakeStore{events: storedEvents()}
+
+	report, err := Run(context.Background(), store, Config{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if !slices.Equal(store.reset, rides_db.DerivedTables) {
+		t.Errorf("reset %v, want %v", store.reset, rides_db.DerivedTables)
+	}
+	if want := []string{"a-2", "a-3", "b-1", "b-2"}; !slices.Equal(store.rides, want) {
+		t.Errorf("ride states from %v, want %v", store.rides, want)
+	}
+	if !slices.Equal(store.refreshed, []string{"trip-a"}) {
+		t.Errorf("refreshed %v, want the trip whose request was archived", store.refreshed)
+	}
+	if len(store.trips) != 1 || store.trips[0].TripID != "trip-b" || store.trips[0].FareUSD != 7 {
+		t.Errorf("unexpected trips: %+v", store.trips)
+	}
+	// The first event's window may also hold archived events, so it is not rewritten
+	for _, w := range store.windows {
+		if w.Start.Before(base) {
+			t.Errorf("rewrote window starting %v, before the first stored event", w.Start)
+		}
+	}
+	if len(store.windows) != 3 {
+		t.Errorf("expected 3 windows, got %+v", store.windows)
+	}
+	if report.Events != 4 || report.Trips != 2 || report.Windows != 3 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(store.audit) != 1 || store.audit[0].Kind != rides_db.AuditRebuild || store.audit[0].Rows != 4 {
+		t.Errorf("unexpected audit entries: %+v", store.audit)
+	}
+}
+
+func TestRun_OnlySelectedTables(t *testing.T) {
+	store := &fakeStore{events: storedEvents()}
+
+	_, err := Run(context.Background(), store, Config{Tables: []rides_db.DerivedTable{rides_db.DerivedTrips}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(store.rides) != 0 || len(store.windows) != 0 {
+		t.Errorf("expected only trips to be written, got rides %v and windows %+v", store.rides, store.windows)
+	}
+	if len(store.trips)+len(store.refreshed) != 2 {
+		t.Errorf("expected both trips, got %+v and %v", store.trips, store.refreshed)
+	}
+}
+
+// sliceSource replays a fixed list of events.
+type sliceSource []events.RideEvent
+
+func (s *sliceSource) Next(ctx context.Context) (events.RideEvent, error) {
+	if len(*s) == 0 {
+		return events.RideEvent{}, io.EOF
+	}
+	e := (*s)[0]
+	*s = (*s)[1:]
+	return e, nil
+}
+
+type failingSource struct{ err error }
+
+func (s failingSource) Next(ctx context.Context) (events.RideEvent, error) {
+	return events.RideEvent{}, s.err
+}
+
+func TestBackfill(t *testing.T) {
+	var replay sliceSource
+	for i := 0; i < backfillBatchSize; i++ {
+		replay = append(replay, storedEvents()...)
+	}
+	replay = append(replay,
+		events.RideEvent{ID: "s-1", Type: events.EventSurgeUpdated, OccurredAt: base,
+			Payload: events.SurgeUpdatedPayload{ZoneID: "downtown", ZoneName: "Downtown", Multiplier: 1.5}},
+		events.RideEvent{ID: "", TripID: "trip-c", Type: events.EventRideRequested},
+	)
+	store := &fakeStore{}
+
+	report, err := Backfill(context.Background(), store, &replay)
+	if err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+
+	total := int64(4 * backfillBatchSize)
+	if report.Read != total+2 || report.Inserted != total || report.Surges != 1 || report.Skipped != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	for i, batch := range store.inserted {
+		if len(batch) > backfillBatchSize {
+			t.Errorf("batch %d holds %d events, more than %d", i, len(batch), backfillBatchSize)
+		}
+	}
+	if len(store.zones) != 1 || store.zones[0].Name != "Downtown" {
+		t.Errorf("unexpected zones: %+v", store.zones)
+	}
+	if len(store.surges) != 1 || store.surges[0].Multiplier != 1.5 || !store.surges[0].EffectiveFrom.Equal(base) {
+		t.Errorf("unexpected surge multipliers: %+v", store.surges)
+	}
+
+	boom := errors.New("broker down")
+	if _, err := Backfill(context.Background(), store, failingSource{boom}); !errors.Is(err, boom) {
+		t.Errorf("expected the source's error, got %v", err)
+	}
+}
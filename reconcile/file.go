@@ -0,0 +1,58 @@
+package reconcile
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// maxLine is the longest line a FileSource reads, well above any event.
+const maxLine = 1 << 20
+
+// FileSource reads ride events from a file of them, one JSON event a line, as
+// `rpk topic consume ride-events -f '%v\n'` writes them. Blank lines are
+// skipped, and lines that do not decode are logged and skipped.
+type FileSource struct {
+	name  string
+	lines *bufio.Scanner
+	line  int
+}
+
+// NewFileSource returns a FileSource reading r, which name names in positions.
+func NewFileSource(r io.Reader, name string) *FileSource {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 0, 64*1024), maxLine)
+	return &FileSource{name: name, lines: lines}
+}
+
+// Next returns the event on the next line that has one, or io.EOF at the end
+// of the file.
+func (f *FileSource) Next(ctx context.Context) (Record, error) {
+	for f.lines.Scan() {
+		f.line++
+		if err := ctx.Err(); err != nil {
+			return Record{}, err
+		}
+		line := strings.TrimSpace(f.lines.Text())
+		if line == "" {
+			continue
+		}
+		pos := fmt.Sprintf("%s:%d", f.name, f.line)
+		var e events.RideEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			slog.Warn("Skipping undecodable line", "position", pos, "error", err)
+			continue
+		}
+		return Record{Event: e, Position: pos}, nil
+	}
+	if err := f.lines.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
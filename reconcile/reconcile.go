@@ -0,0 +1,285 @@
+// Package reconcile checks the pipeline end to end. It compares the ride
+// events read from the topic, or from a file of them, with the rows the
+// consumer stored in ride_events, by event ID, and reports the events that
+// are missing from the table, the duplicates, and the events stored with
+// other content than was produced.
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// DefaultMaxIssues is how many issues of each kind a Report lists by default.
+const DefaultMaxIssues = 20
+
+// Store is the part of rides_db.RideStore a reconciliation reads.
+type Store interface {
+	ExportRideEvents(ctx context.Context, tr rides_db.TimeRange, fn func(events.RideEvent) error) error
+}
+
+// Record is an event read from a Source.
+type Record struct {
+	Event events.RideEvent
+	// Position says where the event was read, such as "ride-events/1@42" for
+	// a topic's partition and offset, or "events.jsonl:7" for a file's line.
+	Position string
+}
+
+// Source yields the events to reconcile. Next returns io.EOF after the last.
+type Source interface {
+	Next(ctx context.Context) (Record, error)
+}
+
+// Kind is what is wrong with an event.
+type Kind string
+
+const (
+	// KindMissing is an event read from the source that is not stored,
+	// under its ID or as a duplicate of another.
+	KindMissing Kind = "missing"
+	// KindDuplicated is an event read more than once from the source, or
+	// stored under another event's ID, as the consumer stores an event once
+	// per trip, type, and time.
+	KindDuplicated Kind = "duplicated"
+	// KindMutated is an event stored with other content than was read.
+	KindMutated Kind = "mutated"
+)
+
+// Issue is one event that does not reconcile.
+type Issue struct {
+	Kind      Kind                 `json:"kind"`
+	EventID   string               `json:"event_id"`
+	TripID    string               `json:"trip_id"`
+	EventType events.RideEventType `json:"event_type"`
+	Position  string               `json:"position"`
+	Detail    string               `json:"detail,omitempty"`
+}
+
+// Config configures a reconciliation. Zero values take the defaults.
+type Config struct {
+	// Range limits the events reconciled by event time. An open end is the
+	// earliest or latest time read from the source.
+	Range rides_db.TimeRange
+	// MaxIssues is how many issues of each kind the report lists; all are
+	// counted.
+	MaxIssues int
+}
+
+func (c *Config) setDefaults() {
+	if c.MaxIssues <= 0 {
+		c.MaxIssues = DefaultMaxIssues
+	}
+}
+
+// Report summarizes a reconciliation.
+type Report struct {
+	Range rides_db.TimeRange `json:"range"`
+	Read  int64              `json:"read"` // events read from the source within Range
+	// Skipped counts the events read that the consumer never stores, such as
+	// LOCATION_UPDATED; see events.RideEventType.IsLifecycle.
+	Skipped    int64   `json:"skipped"`
+	Matched    int64   `json:"matched"`
+	Missing    int64   `json:"missing"`
+	Duplicated int64   `json:"duplicated"`
+	Mutated    int64   `json:"mutated"`
+	Issues     []Issue `json:"issues,omitempty"` // up to MaxIssues of each kind
+}
+
+// OK reports whether every event read reconciled. Duplicates do not count
+// against it when the stored event matches, as the topic is delivered at
+// least once.
+func (r Report) OK() bool {
+	return r.Missing == 0 && r.Mutated == 0
+}
+
+// add counts an issue and lists it if there is room for its kind.
+func (r *Report) add(i Issue, max int) {
+	var n *int64
+	switch i.Kind {
+	case KindMissing:
+		n = &r.Missing
+	case KindDuplicated:
+		n = &r.Duplicated
+	case KindMutated:
+		n = &r.Mutated
+	}
+	*n++
+	if *n <= int64(max) {
+		r.Issues = append(r.Issues, i)
+	}
+}
+
+// WriteText writes r for people: the counts, then each issue listed.
+func (r Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Range:      %s to %s\n", r.Range.From.Format(time.RFC3339), r.Range.To.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Read:       %d (%d never stored)\n", r.Read, r.Skipped)
+	fmt.Fprintf(&b, "Matched:    %d\n", r.Matched)
+	fmt.Fprintf(&b, "Missing:    %d\n", r.Missing)
+	fmt.Fprintf(&b, "Duplicated: %d\n", r.Duplicated)
+	fmt.Fprintf(&b, "Mutated:    %d\n", r.Mutated)
+	for _, i := range r.Issues {
+		fmt.Fprintf(&b, "%-10s %s trip %s %s at %s", i.Kind, i.EventID, i.TripID, i.EventType, i.Position)
+		if i.Detail != "" {
+			fmt.Fprintf(&b, ": %s", i.Detail)
+		}
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// naturalKey is what the consumer stores an event once per.
+type naturalKey struct {
+	tripID    string
+	eventType events.RideEventType
+	at        time.Time
+}
+
+// Run reads src to its end and checks each event read against store.
+func Run(ctx context.Context, store Store, src Source, cfg Config) (Report, error) {
+	cfg.setDefaults()
+	var (
+		report      Report
+		order       []string // IDs in the order first read
+		reads       = make(map[string]Record)
+		first, last time.Time
+	)
+	for {
+		rec, err := src.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		e := rec.Event
+		if !within(e.OccurredAt, cfg.Range) {
+			continue
+		}
+		report.Read++
+		if !e.Type.IsLifecycle() {
+			report.Skipped++
+			continue
+		}
+		if r, ok := reads[e.ID]; ok {
+			detail := fmt.Sprintf("read again, first at %s", r.Position)
+			if diff := differences(r.Event, e); diff != "" {
+				detail += ", with other " + diff
+			}
+			report.add(issue(KindDuplicated, rec, detail), cfg.MaxIssues)
+			continue
+		}
+		reads[e.ID] = rec
+		order = append(order, e.ID)
+		if first.IsZero() || e.OccurredAt.Before(first) {
+			first = e.OccurredAt
+		}
+		if e.OccurredAt.After(last) {
+			last = e.OccurredAt
+		}
+	}
+
+	report.Range = cfg.Range
+	if report.Range.From.IsZero() {
+		report.Range.From = first
+	}
+	if report.Range.To.IsZero() {
+		report.Range.To = last
+	}
+	if len(reads) == 0 {
+		return report, nil
+	}
+
+	// The stored times may be rounded, so the stored range reaches a second
+	// past the events read either side
+	stored := make(map[string]events.RideEvent)
+	byKey := make(map[naturalKey]string)
+	tr := rides_db.TimeRange{From: first.Add(-time.Second), To: last.Add(time.Second)}
+	err := store.ExportRideEvents(ctx, tr, func(e events.RideEvent) error {
+		if _, ok := reads[e.ID]; ok {
+			stored[e.ID] = e
+		}
+		byKey[keyOf(e)] = e.ID
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, id := range order {
+		r := reads[id]
+		if s, ok := stored[id]; ok {
+			if diff := differences(r.Event, s); diff != "" {
+				report.add(issue(KindMutated, r, "stored with other "+diff), cfg.MaxIssues)
+				continue
+			}
+			report.Matched++
+			continue
+		}
+		if other, ok := byKey[keyOf(r.Event)]; ok {
+			report.add(issue(KindDuplicated, r, "stored as "+other), cfg.MaxIssues)
+			continue
+		}
+		report.add(issue(KindMissing, r, ""), cfg.MaxIssues)
+	}
+	sort.SliceStable(report.Issues, func(i, j int) bool { return report.Issues[i].Kind < report.Issues[j].Kind })
+	return report, nil
+}
+
+func issue(kind Kind, rec Record, detail string) Issue {
+	return Issue{Kind: kind, EventID: rec.Event.ID, TripID: rec.Event.TripID, EventType: rec.Event.Type,
+		Position: rec.Position, Detail: detail}
+}
+
+// within reports whether t is in tr, whose open ends are unbounded.
+func within(t time.Time, tr rides_db.TimeRange) bool {
+	return (tr.From.IsZero() || !t.Before(tr.From)) && (tr.To.IsZero() || t.Before(tr.To))
+}
+
+// keyOf returns e's natural key, at the microseconds the databases keep.
+func keyOf(e events.RideEvent) naturalKey {
+	return naturalKey{e.TripID, e.Type, e.OccurredAt.UTC().Truncate(time.Microsecond)}
+}
+
+// differences names the stored fields in which a and b differ, or returns ""
+// if they agree. Times are compared to the microsecond, as the databases
+// keep them, and payloads as JSON.
+func differences(a, b events.RideEvent) string {
+	var diff []string
+	if a.TripID != b.TripID {
+		diff = append(diff, "trip_id")
+	}
+	if a.Type != b.Type {
+		diff = append(diff, "event_type")
+	}
+	if a.State != b.State {
+		diff = append(diff, "ride_state")
+	}
+	if !a.OccurredAt.Truncate(time.Microsecond).Equal(b.OccurredAt.Truncate(time.Microsecond)) {
+		diff = append(diff, "event_time")
+	}
+	if a.DriverID != b.DriverID {
+		diff = append(diff, "driver_id")
+	}
+	if a.PassengerID != b.PassengerID {
+		diff = append(diff, "passenger_id")
+	}
+	pa, errA := json.Marshal(a.Payload)
+	pb, errB := json.Marshal(b.Payload)
+	if errA != nil || errB != nil || !bytes.Equal(pa, pb) {
+		diff = append(diff, "payload")
+	}
+	return strings.Join(diff, ", ")
+}
@@ -0,0 +1,101 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// file returns the lines of a file of evts, as rpk writes them.
+func file(t *testing.T, evts ...events.RideEvent) string {
+	t.Helper()
+	var b strings.Builder
+	for _, e := range evts {
+		line, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	at := func(min int) events.Option { return events.WithTime(now.Add(time.Duration(min) * time.Minute)) }
+	requested := events.NewRideRequested("trip-1", "rider-1", "A St", "B St", at(0))
+	accepted := events.NewRideAccepted("trip-1", "driver-1", at(1))
+	completed := events.NewTripCompleted("trip-1", 3, events.NewMoney(950, events.USD), at(9))
+	lost := events.NewTripStarted("trip-1", at(2))
+	// Produced twice under two IDs, and stored once
+	reproduced := events.NewRideRequested("trip-2", "rider-2", "C St", "D St", at(3))
+	again := reproduced
+	again.ID = "again"
+	location := events.NewLocationUpdated("trip-1", events.StateInProgress, events.Coordinate{Lat: 40.7, Lng: -74}, 0, 30, at(4))
+	late := events.NewRideRequested("trip-3", "rider-3", "E St", "F St", at(120))
+
+	store := ridetest.NewStore()
+	tampered := completed
+	tampered.Payload = events.RideCompletedPayload{DistanceKM: 3, Fare: events.NewMoney(9500, events.USD)}
+	for _, e := range []events.RideEvent{requested, accepted, tampered, reproduced} {
+		if _, err := store.InsertRideEvent(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := NewFileSource(strings.NewReader(file(t, requested, accepted, accepted, lost, completed, location, again, late)+"not json\n"), "events.jsonl")
+	report, err := Run(ctx, store, src, Config{Range: rides_db.TimeRange{To: now.Add(time.Hour)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Read != 7 || report.Skipped != 1 || report.Matched != 2 || report.Missing != 1 || report.Duplicated != 2 || report.Mutated != 1 {
+		t.Errorf("report %+v", report)
+	}
+	if report.OK() {
+		t.Error("report OK, want not with an event missing")
+	}
+	want := []string{
+		"duplicated " + accepted.ID + " events.jsonl:3 read again, first at events.jsonl:2",
+		"duplicated again events.jsonl:7 stored as " + reproduced.ID,
+		"missing " + lost.ID + " events.jsonl:4 ",
+		"mutated " + completed.ID + " events.jsonl:5 stored with other payload",
+	}
+	var got []string
+	for _, i := range report.Issues {
+		got = append(got, string(i.Kind)+" "+i.EventID+" "+i.Position+" "+i.Detail)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var text strings.Builder
+	if err := report.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "Missing:    1\n") || !strings.Contains(text.String(), "missing    "+lost.ID+" trip trip-1 STARTED at events.jsonl:4\n") {
+		t.Errorf("text report:\n%s", text.String())
+	}
+}
+
+func TestRun_ListsUpToMaxIssues(t *testing.T) {
+	var evts []events.RideEvent
+	for range 3 {
+		evts = append(evts, events.NewRideRequested("trip-1", "rider-1", "A St", "B St", events.WithTime(now)))
+	}
+	report, err := Run(context.Background(), ridetest.NewStore(), NewFileSource(strings.NewReader(file(t, evts...)), "f"), Config{MaxIssues: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Missing != 3 || len(report.Issues) != 2 {
+		t.Errorf("missing %d, listed %d; want 3 and 2", report.Missing, len(report.Issues))
+	}
+}
@@ -0,0 +1,205 @@
+// Package replay reads a topic again from a chosen point, as the rides CLI
+// does to rebuild and reconcile ride_events and the consumer does to replay
+// events into a schema of their own.
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// metadataTimeout bounds the broker round trips made when opening a Reader.
+const metadataTimeout = 10 * time.Second
+
+// DefaultGroupID is the group a Reader identifies as by default. It never
+// joins the group or commits its offsets.
+const DefaultGroupID = "replay"
+
+// Config says what a Reader reads. By default it reads every partition from
+// its start.
+type Config struct {
+	Brokers string
+	Topic   string
+	GroupID string
+	// Since starts each partition at its first message timestamped at or
+	// after it, skipping the partitions with none.
+	Since time.Time
+	// Offsets starts the partitions listed at their offset, before Since.
+	Offsets map[int32]int64
+	// Follow goes on reading after the end: ReadMessage returns io.EOF once
+	// on reaching it, then the messages written since, as state stores
+	// restoring from a changelog and then following it need. Every
+	// partition is read, those empty when opened included.
+	Follow bool
+}
+
+// Reader reads a topic up to the end it had when it was opened. It assigns
+// the partitions itself rather than joining a consumer group, so it never
+// moves a group's committed offsets. *Reader is a rideconsumer.Source that
+// ends with io.EOF.
+type Reader struct {
+	c     *kafka.Consumer
+	topic string
+	// last is the offset of the last message to read, per partition still
+	// being read.
+	last map[int32]int64
+	// follow is Config.Follow, and ended is set once io.EOF was returned.
+	follow, ended bool
+}
+
+// Open opens a Reader as cfg says.
+func Open(cfg Config) (*Reader, error) {
+	if cfg.GroupID == "" {
+		cfg.GroupID = DefaultGroupID
+	}
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  cfg.Brokers,
+		"group.id":           cfg.GroupID,
+		"enable.auto.commit": false,
+		// An offset before the start of a partition reads from its start
+		"auto.offset.reset": "earliest",
+		// Messages of aborted transactions, such as a processor's, are skipped
+		"isolation.level": "read_committed",
+	})
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{c: c, topic: cfg.Topic, last: map[int32]int64{}, follow: cfg.Follow}
+	if err := r.assign(cfg); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reader) assign(cfg Config) error {
+	topic := cfg.Topic
+	md, err := r.c.GetMetadata(&topic, false, int(metadataTimeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	for p := range cfg.Offsets {
+		if !hasPartition(md.Topics[topic].Partitions, p) {
+			return fmt.Errorf("%s has no partition %d", topic, p)
+		}
+	}
+
+	var parts, timed []kafka.TopicPartition
+	for _, p := range md.Topics[topic].Partitions {
+		low, high, err := r.c.QueryWatermarkOffsets(topic, p.ID, int(metadataTimeout.Milliseconds()))
+		if err != nil {
+			return fmt.Errorf("partition %d offsets: %w", p.ID, err)
+		}
+		tp := kafka.TopicPartition{Topic: &topic, Partition: p.ID, Offset: kafka.OffsetBeginning}
+		switch offset, ok := cfg.Offsets[p.ID]; {
+		case cfg.Follow && (high <= low || ok && offset >= high):
+			// Nothing to read up to the end, but what comes after
+			if ok {
+				tp.Offset = kafka.Offset(offset)
+			}
+			parts = append(parts, tp)
+			continue
+		case high <= low, ok && offset >= high:
+			continue
+		case ok:
+			tp.Offset = kafka.Offset(offset)
+		case !cfg.Since.IsZero():
+			tp.Offset = kafka.Offset(cfg.Since.UnixMilli())
+			timed = append(timed, tp)
+			r.last[p.ID] = high - 1
+			continue
+		}
+		r.last[p.ID] = high - 1
+		parts = append(parts, tp)
+	}
+	if len(timed) > 0 {
+		// Asked for a time, each partition gives the offset of its first
+		// message at or after it, or the end when it has none
+		if timed, err = r.c.OffsetsForTimes(timed, int(metadataTimeout.Milliseconds())); err != nil {
+			return err
+		}
+		for _, tp := range timed {
+			if tp.Offset < 0 {
+				delete(r.last, tp.Partition)
+				if !cfg.Follow {
+					continue
+				}
+				tp.Offset = kafka.OffsetEnd
+			}
+			parts = append(parts, tp)
+		}
+	}
+	return r.c.Assign(parts)
+}
+
+func hasPartition(parts []kafka.PartitionMetadata, id int32) bool {
+	for _, p := range parts {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next message, a kafka.ErrTimedOut error if none
+// arrives within timeout, or io.EOF once every partition has been read to its
+// end. With Config.Follow, io.EOF is returned once and reading goes on.
+func (r *Reader) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	if len(r.last) == 0 && (!r.follow || !r.ended) {
+		r.ended = true
+		return nil, io.EOF
+	}
+	msg, err := r.c.ReadMessage(timeout)
+	if err != nil {
+		if IsTimeout(err) && r.passedEnd() {
+			r.ended = true
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	p := msg.TopicPartition.Partition
+	if last, ok := r.last[p]; ok && int64(msg.TopicPartition.Offset) >= last {
+		delete(r.last, p)
+	}
+	return msg, nil
+}
+
+// passedEnd drops the partitions whose position is past their last offset
+// without its message having been read, as when a partition ends with the
+// marker of a transaction rather than a message, and reports whether none
+// is left.
+func (r *Reader) passedEnd() bool {
+	if len(r.last) == 0 {
+		return false
+	}
+	parts := make([]kafka.TopicPartition, 0, len(r.last))
+	for p := range r.last {
+		parts = append(parts, kafka.TopicPartition{Topic: &r.topic, Partition: p})
+	}
+	positions, err := r.c.Position(parts)
+	if err != nil {
+		return false
+	}
+	for _, tp := range positions {
+		if tp.Offset >= 0 && int64(tp.Offset) > r.last[tp.Partition] {
+			delete(r.last, tp.Partition)
+		}
+	}
+	return len(r.last) == 0
+}
+
+// IsTimeout reports whether err is the timeout ReadMessage returns when no
+// message arrived in time.
+func IsTimeout(err error) bool {
+	var kerr kafka.Error
+	return errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut
+}
+
+// Close closes the Reader's consumer.
+func (r *Reader) Close() error {
+	return r.c.Close()
+}
@@ -0,0 +1,160 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Destination is where the files of a report are delivered.
+type Destination interface {
+	// Name labels the destination in logs and metrics.
+	Name() string
+	Deliver(ctx context.Context, d Daily, files []File) error
+}
+
+// Dir writes the files into a directory, replacing those of an earlier run
+// for the same day.
+type Dir struct {
+	Path string
+}
+
+// Name returns "file".
+func (Dir) Name() string { return "file" }
+
+// Deliver writes each file to Path, through a temporary file so a reader
+// never sees one half written.
+func (dst Dir) Deliver(ctx context.Context, d Daily, files []File) error {
+	if err := os.MkdirAll(dst.Path, 0o755); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tmp, err := os.CreateTemp(dst.Path, "."+f.Name+".*")
+		if err != nil {
+			return err
+		}
+		_, err = tmp.Write(f.Data)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), filepath.Join(dst.Path, f.Name))
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return fmt.Errorf("write %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// Mail emails the report through the SMTP server at Addr: the HTML file, if
+// rendered, is the body, and the other files are attached.
+type Mail struct {
+	Addr string // host:port
+	From string
+	To   []string
+	// Auth authenticates with the server; nil sends without.
+	Auth smtp.Auth
+	// SendMail sends the message; nil uses smtp.SendMail. Tests replace it.
+	SendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Name returns "email".
+func (Mail) Name() string { return "email" }
+
+// Deliver mails the files. smtp.SendMail takes no context, so ctx only stops
+// a send that has not begun.
+func (m Mail) Deliver(ctx context.Context, d Daily, files []File) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := m.message(d, files)
+	if err != nil {
+		return err
+	}
+	send := m.SendMail
+	if send == nil {
+		send = smtp.SendMail
+	}
+	return send(m.Addr, m.Auth, m.From, m.To, msg)
+}
+
+// message builds the multipart/mixed message carrying files.
+func (m Mail) message(d Daily, files []File) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	id := make([]byte, 8)
+	rand.Read(id)
+	host := m.From[strings.LastIndex(m.From, "@")+1:]
+
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "Ride report for "+d.Day.Format(time.DateOnly)))
+	fmt.Fprintf(&buf, "Message-ID: <%s.%s@%s>\r\n", d.Name(), hex.EncodeToString(id), host)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mw.Boundary())
+
+	// The body goes first, so mail clients show it inline
+	body := slices.IndexFunc(files, func(f File) bool { return strings.HasPrefix(f.ContentType, "text/html") })
+	if body < 0 {
+		w, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(w, "The ride report for %s is attached.\r\n", d.Day.Format(time.DateOnly))
+	} else {
+		files = append([]File{files[body]}, slices.Delete(slices.Clone(files), body, body+1)...)
+	}
+	for i, f := range files {
+		h := textproto.MIMEHeader{
+			"Content-Type":              {f.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+		}
+		if body < 0 || i > 0 {
+			h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Name}))
+		}
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(w, f.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters, as
+// RFC 2045 asks.
+func writeBase64(w io.Writer, data []byte) error {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 0 {
+		n := min(76, len(enc))
+		if _, err := fmt.Fprintf(w, "%s\r\n", enc[:n]); err != nil {
+			return err
+		}
+		enc = enc[n:]
+	}
+	return nil
+}
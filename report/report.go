@@ -0,0 +1,235 @@
+// Package report builds the daily summary of the ride store: trips, revenue,
+// cancellation rate, and the busiest zones and drivers of one UTC day, as
+// HTML for reading and CSV for spreadsheets, and delivers it to a directory,
+// a mailbox, or an S3 bucket.
+package report
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// DefaultTop is how many zones and drivers a report lists unless told
+// otherwise.
+const DefaultTop = 5
+
+// Store is the part of rides_db.RideStore a report reads from: the trips
+// table and the windowed aggregates.
+type Store interface {
+	RevenueByDay(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.DailyRevenue, error)
+	CancellationRateByHour(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.HourlyCancellationRate, error)
+	AvgFareByZone(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.ZoneFare, error)
+	TopDriversByTrips(ctx context.Context, tr rides_db.TimeRange, limit int) ([]rides_db.DriverTrips, error)
+	ListEventWindows(ctx context.Context, tr rides_db.TimeRange) ([]aggregation.WindowResult, error)
+}
+
+// EventCount is how many events of one type the day's windows counted.
+type EventCount struct {
+	EventType events.RideEventType
+	Count     int64
+}
+
+// Daily is the summary of one UTC day.
+type Daily struct {
+	Day time.Time
+	// Trips and RevenueUSD count the trips completed during the day.
+	Trips      int64
+	RevenueUSD float64
+	// Requested and Cancelled count the trips requested during the day, and
+	// how many of those ended cancelled.
+	Requested        int64
+	Cancelled        int64
+	CancellationRate float64
+	// TopZones are the pickup zones with the most completed trips, and
+	// TopDrivers the drivers, most first.
+	TopZones   []rides_db.ZoneFare
+	TopDrivers []rides_db.DriverTrips
+	// Events totals the day's windowed aggregates by event type.
+	Events      []EventCount
+	GeneratedAt time.Time
+}
+
+// Build summarizes the UTC day of day from store, listing up to top zones
+// and drivers.
+func Build(ctx context.Context, store Store, day time.Time, top int) (Daily, error) {
+	if top <= 0 {
+		top = DefaultTop
+	}
+	day = day.UTC().Truncate(24 * time.Hour)
+	tr := rides_db.TimeRange{From: day, To: day.AddDate(0, 0, 1)}
+	d := Daily{Day: day, GeneratedAt: time.Now().UTC()}
+
+	revenue, err := store.RevenueByDay(ctx, tr)
+	if err != nil {
+		return Daily{}, fmt.Errorf("revenue: %w", err)
+	}
+	for _, r := range revenue {
+		d.Trips += r.Trips
+		d.RevenueUSD += r.RevenueUSD
+	}
+
+	hours, err := store.CancellationRateByHour(ctx, tr)
+	if err != nil {
+		return Daily{}, fmt.Errorf("cancellations: %w", err)
+	}
+	for _, h := range hours {
+		d.Requested += h.Requested
+		d.Cancelled += h.Cancelled
+	}
+	if d.Requested > 0 {
+		d.CancellationRate = float64(d.Cancelled) / float64(d.Requested)
+	}
+
+	zones, err := store.AvgFareByZone(ctx, tr)
+	if err != nil {
+		return Daily{}, fmt.Errorf("zones: %w", err)
+	}
+	// The store ranks zones by fare; the busiest are the ones worth listing
+	zones = slices.Clone(zones)
+	slices.SortStableFunc(zones, func(a, b rides_db.ZoneFare) int {
+		return cmp.Or(cmp.Compare(b.Trips, a.Trips), cmp.Compare(a.Zone, b.Zone))
+	})
+	d.TopZones = zones[:min(top, len(zones))]
+
+	if d.TopDrivers, err = store.TopDriversByTrips(ctx, tr, top); err != nil {
+		return Daily{}, fmt.Errorf("drivers: %w", err)
+	}
+
+	windows, err := store.ListEventWindows(ctx, tr)
+	if err != nil {
+		return Daily{}, fmt.Errorf("event windows: %w", err)
+	}
+	counts := make(map[events.RideEventType]int64)
+	for _, w := range windows {
+		counts[w.EventType] += w.Count
+	}
+	for typ, n := range counts {
+		d.Events = append(d.Events, EventCount{EventType: typ, Count: n})
+	}
+	slices.SortFunc(d.Events, func(a, b EventCount) int { return cmp.Compare(a.EventType, b.EventType) })
+	return d, nil
+}
+
+// Format is a file format a report is written in.
+type Format string
+
+const (
+	FormatHTML Format = "html"
+	FormatCSV  Format = "csv"
+)
+
+// File is a report written in one format.
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Render writes d in each of formats.
+func (d Daily) Render(formats []Format) ([]File, error) {
+	files := make([]File, 0, len(formats))
+	for _, f := range formats {
+		var buf bytes.Buffer
+		var contentType string
+		var err error
+		switch f {
+		case FormatHTML:
+			contentType, err = "text/html; charset=utf-8", d.WriteHTML(&buf)
+		case FormatCSV:
+			contentType, err = "text/csv; charset=utf-8", d.WriteCSV(&buf)
+		default:
+			return nil, fmt.Errorf("report: unknown format %q", f)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("report: write %s: %w", f, err)
+		}
+		files = append(files, File{Name: d.Name() + "." + string(f), ContentType: contentType, Data: buf.Bytes()})
+	}
+	return files, nil
+}
+
+// Name returns the base name of the report's files, as
+// "rides-2025-06-01".
+func (d Daily) Name() string { return "rides-" + d.Day.Format(time.DateOnly) }
+
+var htmlTemplate = template.Must(template.New("daily").Funcs(template.FuncMap{
+	"date":    func(t time.Time) string { return t.Format(time.DateOnly) },
+	"usd":     func(v float64) string { return fmt.Sprintf("$%.2f", v) },
+	"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Rides on {{date .Day}}</title></head>
+<body>
+<h1>Rides on {{date .Day}}</h1>
+<table>
+<tr><th>Completed trips</th><td>{{.Trips}}</td></tr>
+<tr><th>Revenue</th><td>{{usd .RevenueUSD}}</td></tr>
+<tr><th>Requested</th><td>{{.Requested}}</td></tr>
+<tr><th>Cancelled</th><td>{{.Cancelled}} ({{percent .CancellationRate}})</td></tr>
+</table>
+<h2>Top zones</h2>
+{{if .TopZones}}<table>
+<tr><th>Zone</th><th>Trips</th><th>Average fare</th></tr>
+{{range .TopZones}}<tr><td>{{.Zone}}</td><td>{{.Trips}}</td><td>{{usd .AvgFareUSD}}</td></tr>
+{{end}}</table>{{else}}<p>No completed trips.</p>{{end}}
+<h2>Top drivers</h2>
+{{if .TopDrivers}}<table>
+<tr><th>Driver</th><th>Trips</th><th>Revenue</th></tr>
+{{range .TopDrivers}}<tr><td>{{.DriverID}}</td><td>{{.Trips}}</td><td>{{usd .RevenueUSD}}</td></tr>
+{{end}}</table>{{else}}<p>No completed trips.</p>{{end}}
+{{if .Events}}<h2>Events</h2>
+<table>
+{{range .Events}}<tr><th>{{.EventType}}</th><td>{{.Count}}</td></tr>
+{{end}}</table>{{end}}
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}</p>
+</body>
+</html>
+`))
+
+// WriteHTML writes d to w as an HTML page.
+func (d Daily) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, d)
+}
+
+// WriteCSV writes d to w as CSV, a row per figure: the day's totals first,
+// then the top zones, the top drivers, and the event counts, each tagged
+// with its section. amount_usd is the revenue of the totals and drivers and
+// the average fare of the zones.
+func (d Daily) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	count := func(n int64) string { return strconv.FormatInt(n, 10) }
+	day := d.Day.Format(time.DateOnly)
+
+	rows := [][]string{
+		{"day", "section", "name", "trips", "amount_usd", "rate"},
+		{day, "total", "completed", count(d.Trips), num(d.RevenueUSD), ""},
+		{day, "total", "requested", count(d.Requested), "", ""},
+		{day, "total", "cancelled", count(d.Cancelled), "", num(d.CancellationRate)},
+	}
+	for _, z := range d.TopZones {
+		rows = append(rows, []string{day, "zone", z.Zone, count(z.Trips), num(z.AvgFareUSD), ""})
+	}
+	for _, dr := range d.TopDrivers {
+		rows = append(rows, []string{day, "driver", dr.DriverID, count(dr.Trips), num(dr.RevenueUSD), ""})
+	}
+	for _, e := range d.Events {
+		rows = append(rows, []string{day, "events", string(e.EventType), count(e.Count), "", ""})
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,157 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+var day = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// seed stores the trips and windows of day, and a few on either side of it
+// to be left out.
+func seed(t *testing.T) *ridetest.Store {
+	t.Helper()
+	ctx := context.Background()
+	store := ridetest.NewStore()
+	completed := func(id, driver, zone string, at time.Time, fare float64) {
+		t.Helper()
+		err := store.InsertTrip(ctx, aggregation.Trip{TripID: id, DriverID: driver, PickupLocation: zone,
+			FinalState: events.StateCompleted, RequestedAt: at.Add(-20 * time.Minute), CompletedAt: at, FareUSD: fare})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	completed("trip-1", "driver-1", "Midtown", day.Add(9*time.Hour), 20)
+	completed("trip-2", "driver-1", "Midtown", day.Add(10*time.Hour), 10)
+	completed("trip-3", "driver-2", "Harlem", day.Add(11*time.Hour), 30)
+	completed("trip-4", "driver-3", "<Downtown>", day.Add(12*time.Hour), 5.5)
+	completed("trip-yesterday", "driver-2", "Harlem", day.Add(-time.Hour), 100)
+	completed("trip-tomorrow", "driver-2", "Harlem", day.Add(25*time.Hour), 100)
+	if err := store.InsertTrip(ctx, aggregation.Trip{TripID: "trip-5", FinalState: events.StateCancelled,
+		RequestedAt: day.Add(13 * time.Hour), CancelledAt: day.Add(13*time.Hour + time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, w := range []aggregation.WindowResult{
+		{Start: day.Add(9 * time.Hour), EventType: events.EventRideRequested, Count: 3},
+		{Start: day.Add(10 * time.Hour), EventType: events.EventRideRequested, Count: 2},
+		{Start: day.Add(10 * time.Hour), EventType: events.EventTripCompleted, Count: 4},
+		{Start: day.Add(-time.Hour), EventType: events.EventTripCompleted, Count: 9},
+	} {
+		w.End = w.Start.Add(time.Hour)
+		if err := store.UpsertEventWindow(ctx, w); err != nil {
+			t.Fatalf("window %d: %v", i, err)
+		}
+	}
+	return store
+}
+
+func TestBuild(t *testing.T) {
+	d, err := Build(context.Background(), seed(t), day.Add(15*time.Hour), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Day.Equal(day) || d.Trips != 4 || d.RevenueUSD != 65.5 {
+		t.Errorf("day %s: %d trips for %v, want 4 for 65.5", d.Day, d.Trips, d.RevenueUSD)
+	}
+	if d.Requested != 5 || d.Cancelled != 1 || d.CancellationRate != 0.2 {
+		t.Errorf("cancelled %d of %d (%v), want 1 of 5", d.Cancelled, d.Requested, d.CancellationRate)
+	}
+	if len(d.TopZones) != 2 || d.TopZones[0].Zone != "Midtown" || d.TopZones[0].Trips != 2 || d.TopZones[1].Zone != "<Downtown>" {
+		t.Errorf("top zones = %+v, want Midtown by trips, then the rest by name", d.TopZones)
+	}
+	if len(d.TopDrivers) != 2 || d.TopDrivers[0].DriverID != "driver-1" || d.TopDrivers[1].DriverID != "driver-2" {
+		t.Errorf("top drivers = %+v", d.TopDrivers)
+	}
+	want := []EventCount{{events.EventTripCompleted, 4}, {events.EventRideRequested, 5}}
+	if fmt.Sprint(d.Events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", d.Events, want)
+	}
+}
+
+func TestBuild_StoreFails(t *testing.T) {
+	store := seed(t)
+	store.FailOn("AvgFareByZone", errors.New("connection refused"))
+	if _, err := Build(context.Background(), store, day, 0); err == nil || !strings.Contains(err.Error(), "zones") {
+		t.Errorf("err = %v, want the zones query's", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	d, err := Build(context.Background(), seed(t), day, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := d.Render([]Format{FormatCSV, FormatHTML})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Name != "rides-2025-06-01.csv" || files[1].Name != "rides-2025-06-01.html" {
+		t.Fatalf("files = %v", files)
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(files[0].Data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A header, 3 totals, 3 zones, 3 drivers, and 2 event types
+	if len(rows) != 12 {
+		t.Fatalf("%d rows, want 12:\n%s", len(rows), files[0].Data)
+	}
+	if got := strings.Join(rows[1], ","); got != "2025-06-01,total,completed,4,65.5," {
+		t.Errorf("totals row = %s", got)
+	}
+	if got := strings.Join(rows[3], ","); got != "2025-06-01,total,cancelled,1,,0.2" {
+		t.Errorf("cancelled row = %s", got)
+	}
+	if got := strings.Join(rows[4], ","); got != "2025-06-01,zone,Midtown,2,15," {
+		t.Errorf("first zone row = %s", got)
+	}
+
+	html := string(files[1].Data)
+	for _, want := range []string{"<title>Rides on 2025-06-01</title>", "$65.50", "1 (20.0%)", "&lt;Downtown&gt;", "driver-2"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML lacks %q:\n%s", want, html)
+		}
+	}
+
+	if _, err := d.Render([]Format{"pdf"}); err == nil {
+		t.Error("expected an unknown format to be refused")
+	}
+}
+
+// destination records what it is given, or fails with err.
+type destination struct {
+	name  string
+	err   error
+	files []File
+}
+
+func (d *destination) Name() string { return d.name }
+
+func (d *destination) Deliver(ctx context.Context, daily Daily, files []File) error {
+	d.files = files
+	return d.err
+}
+
+func TestGenerate_DeliversDespiteFailures(t *testing.T) {
+	failing := &destination{name: "s3", err: errors.New("access denied")}
+	ok := &destination{name: "file"}
+	err := Generate(context.Background(), seed(t), day, Config{Destinations: []Destination{failing, ok}})
+	if err == nil || !strings.Contains(err.Error(), "s3: access denied") {
+		t.Errorf("err = %v, want the s3 failure", err)
+	}
+	if len(ok.files) != 2 || ok.files[0].Name != "rides-2025-06-01.html" {
+		t.Errorf("delivered %v, want the HTML and CSV", ok.files)
+	}
+}
@@ -0,0 +1,93 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	reportsGenerated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reports_generated_total",
+		Help: "Number of daily reports generated, by outcome.",
+	}, []string{"outcome"})
+
+	deliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reports_deliveries_total",
+		Help: "Number of daily report deliveries, by destination and outcome.",
+	}, []string{"destination", "outcome"})
+)
+
+// Config configures Generate and Run.
+type Config struct {
+	// Formats are the formats written; nil writes HTML and CSV.
+	Formats []Format
+	// Top is how many zones and drivers are listed; 0 is DefaultTop.
+	Top          int
+	Destinations []Destination
+}
+
+// Generate builds the report of day and delivers it to every destination.
+// A destination that fails does not keep the report from the others; their
+// errors are returned together.
+func Generate(ctx context.Context, store Store, day time.Time, cfg Config) error {
+	formats := cfg.Formats
+	if formats == nil {
+		formats = []Format{FormatHTML, FormatCSV}
+	}
+	d, err := Build(ctx, store, day, cfg.Top)
+	if err == nil {
+		var files []File
+		if files, err = d.Render(formats); err == nil {
+			reportsGenerated.WithLabelValues("ok").Inc()
+			return deliver(ctx, d, files, cfg.Destinations)
+		}
+	}
+	reportsGenerated.WithLabelValues("failed").Inc()
+	return err
+}
+
+func deliver(ctx context.Context, d Daily, files []File, destinations []Destination) error {
+	var errs []error
+	for _, dst := range destinations {
+		if err := dst.Deliver(ctx, d, files); err != nil {
+			deliveries.WithLabelValues(dst.Name(), "failed").Inc()
+			errs = append(errs, fmt.Errorf("%s: %w", dst.Name(), err))
+			continue
+		}
+		deliveries.WithLabelValues(dst.Name(), "ok").Inc()
+		slog.Info("Delivered report", "day", d.Day.Format(time.DateOnly), "destination", dst.Name(), "files", len(files))
+	}
+	return errors.Join(errs...)
+}
+
+// Run generates the report of the day before each time schedule runs, in
+// UTC, until ctx is cancelled. Failures are logged and left for the next
+// run, or for `reports once`.
+func Run(ctx context.Context, store Store, schedule Schedule, cfg Config) {
+	for {
+		next := schedule.Next(time.Now().UTC())
+		if next.IsZero() {
+			slog.Error("Report schedule never runs")
+			return
+		}
+		slog.Info("Next report scheduled", "at", next)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		day := next.AddDate(0, 0, -1)
+		if err := Generate(ctx, store, day, cfg); err != nil {
+			slog.Error("Daily report failed", "day", day.Format(time.DateOnly), "error", err)
+		}
+	}
+}
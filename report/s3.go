@@ -0,0 +1,114 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Credentials sign requests to S3.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials.
+	SessionToken string
+}
+
+// S3 uploads the files to a bucket, under Prefix. It signs its requests with
+// AWS Signature Version 4, using the AWS SDK's signer, which S3-compatible
+// stores such as MinIO accept too.
+type S3 struct {
+	Bucket string
+	Region string
+	// Endpoint is the base URL of an S3-compatible store, addressed with
+	// the bucket in the path; empty uses AWS's virtual-hosted endpoint.
+	Endpoint    string
+	Prefix      string
+	Credentials Credentials
+	// Client sends the requests; nil uses http.DefaultClient.
+	Client *http.Client
+}
+
+// Name returns "s3".
+func (S3) Name() string { return "s3" }
+
+// Deliver puts each file at Prefix plus its name, replacing an earlier run's.
+func (dst S3) Deliver(ctx context.Context, d Daily, files []File) error {
+	client := dst.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for _, f := range files {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, dst.objectURL(dst.Prefix+f.Name), bytes.NewReader(f.Data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", f.ContentType)
+		sum := sha256.Sum256(f.Data)
+		if err := signV4(ctx, req, hex.EncodeToString(sum[:]), dst.Credentials, dst.Region, time.Now()); err != nil {
+			return fmt.Errorf("sign %s: %w", f.Name, err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("put %s: %w", f.Name, err)
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("put %s: %s: %s", f.Name, resp.Status, strings.TrimSpace(string(body)))
+		}
+	}
+	return nil
+}
+
+// objectURL returns the URL of the object at key.
+func (dst S3) objectURL(key string) string {
+	if dst.Endpoint != "" {
+		return strings.TrimSuffix(dst.Endpoint, "/") + "/" + dst.Bucket + "/" + escapePath(key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", dst.Bucket, dst.Region, escapePath(key))
+}
+
+// s3Signer signs with AWS Signature Version 4. S3 takes object keys escaped
+// once, so the signer must not escape the path again.
+var s3Signer = v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+
+// signV4 signs req for S3 in region at now, covering its host and every
+// header it carries, with payloadHash the hex SHA-256 of its body.
+func signV4(ctx context.Context, req *http.Request, payloadHash string, creds Credentials, region string, now time.Time) error {
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	return s3Signer.SignHTTP(ctx, aws.Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}, req, payloadHash, "s3", region, now)
+}
+
+// escapePath escapes an object key for a URL path, keeping its slashes.
+func escapePath(key string) string { return escape(key, true) }
+
+// escape percent-encodes all but the unreserved characters of RFC 3986, and
+// with path the slashes.
+func escape(s string, path bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || path && c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron schedule: minute, hour, day of month, month, and day of
+// week, each a *, a value, a range such as 1-5, a step such as */15 or
+// 0-30/10, or a comma-separated list of those. Days of the week run from 0,
+// Sunday, to 6, with 7 Sunday too. As in cron, when both day fields are
+// restricted, not starting with *, a day matching either of them is run.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit n is set when value n runs
+	domAny, dowAny                bool
+}
+
+// descriptors are the @ shorthands cron accepts.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule parses a five-field cron spec or one of the @yearly,
+// @monthly, @weekly, @daily, and @hourly shorthands.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := descriptors[spec]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("report: schedule %q: want 5 fields, got %d", spec, len(fields))
+	}
+	var s Schedule
+	var err error
+	for i, f := range []struct {
+		bits     *uint64
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	} {
+		if *f.bits, err = parseField(fields[i], f.min, f.max); err != nil {
+			return Schedule{}, fmt.Errorf("report: schedule %q: %w", spec, err)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny, s.dowAny = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseField returns the values field runs at as bits.
+func parseField(field string, first, last int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			step = n
+		}
+		lo, hi := first, last
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return 0, fmt.Errorf("bad value in %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return 0, fmt.Errorf("bad value in %q", part)
+				}
+			} else if hasStep {
+				// 5/15 is cron for 5-max/15
+				hi = last
+			}
+			if lo < first || hi > last || lo > hi {
+				return 0, fmt.Errorf("%q is outside %d-%d", part, first, last)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after after that s runs, in after's location,
+// or the zero time if it never runs, as for February 30.
+func (s Schedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// Every schedule that runs at all runs within eight years, leap days
+	// included
+	limit := t.AddDate(8, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<t.Month()) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<t.Weekday()) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
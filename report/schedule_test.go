@@ -0,0 +1,47 @@
+package report
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, 6, 4, 10, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"0 6 * * *", time.Date(2025, 6, 5, 6, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 6, 5, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 6, 4, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 6, 4, 10, 45, 0, 0, time.UTC)},
+		{"5/20 10 * * *", time.Date(2025, 6, 4, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2025, 6, 4, 13, 0, 0, 0, time.UTC)},
+		{"0 8 * * 1-5", time.Date(2025, 6, 5, 8, 0, 0, 0, time.UTC)},
+		{"0 8 * * 7", time.Date(2025, 6, 8, 8, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)},
+		// Either day field matching will do: the 20th, or the next Monday
+		{"0 0 20 * 1", time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		s, err := ParseSchedule(tc.spec)
+		if err != nil {
+			t.Errorf("%q: %v", tc.spec, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tc.want) {
+			t.Errorf("%q: next = %s, want %s", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *",
+		"0 0 * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@often"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%q parsed", spec)
+		}
+	}
+}
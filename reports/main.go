@@ -0,0 +1,127 @@
+// Command reports writes the daily summary of the report package, for the
+// day before each time REPORT_SCHEDULE runs, to REPORT_DIR, and to the
+// mailboxes of REPORT_EMAIL_TO and the bucket REPORT_S3_BUCKET when they are
+// set. `reports once` writes a single day's, the day before by default, for
+// running from cron or by hand.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/report"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+)
+
+const (
+	defaultSchedule = "0 6 * * *" // 06:00 UTC, once the day's late events are in
+	defaultDir      = "reports"
+	defaultFrom     = "reports@example.com"
+	defaultRegion   = "us-east-1"
+)
+
+func main() {
+	svc := runtime.Start("reports")
+	defer svc.Stop()
+	slog.Info("Starting reports")
+
+	cfg := configFromEnv()
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+
+	ctx := svc.Context()
+
+	// `reports once` reports a single day, for running from cron
+	if len(os.Args) > 1 && os.Args[1] == "once" {
+		fs := flag.NewFlagSet("once", flag.ExitOnError)
+		dayFlag := fs.String("day", "", "UTC day to report, as YYYY-MM-DD (default yesterday)")
+		fs.Parse(os.Args[2:])
+		day := time.Now().UTC().AddDate(0, 0, -1)
+		if *dayFlag != "" {
+			if day, err = time.Parse(time.DateOnly, *dayFlag); err != nil {
+				logger.Fatal("Invalid -day", "value", *dayFlag, "error", err)
+			}
+		}
+		if err := report.Generate(ctx, store, day, cfg); err != nil {
+			logger.Fatal("Daily report failed", "day", day.Format(time.DateOnly), "error", err)
+		}
+		return
+	}
+
+	spec := runtime.EnvString("REPORT_SCHEDULE", defaultSchedule)
+	schedule, err := report.ParseSchedule(spec)
+	if err != nil {
+		logger.Fatal("Invalid REPORT_SCHEDULE", "value", spec, "error", err)
+	}
+
+	svc.Serve(":2126")
+
+	report.Run(ctx, store, schedule, cfg)
+	slog.Info("Reports stopped")
+}
+
+// configFromEnv reads the formats in REPORT_FORMATS (default html,csv), the
+// number of zones and drivers listed in REPORT_TOP, and the destinations:
+// REPORT_DIR (default reports), email to the comma-separated REPORT_EMAIL_TO
+// through REPORT_SMTP_ADDR, and S3 to REPORT_S3_BUCKET with the AWS_
+// credentials. Invalid values are logged and left to the defaults, but a
+// destination missing a setting it needs is fatal.
+func configFromEnv() report.Config {
+	var cfg report.Config
+	for _, f := range runtime.EnvList("REPORT_FORMATS") {
+		switch format := report.Format(strings.ToLower(f)); format {
+		case report.FormatHTML, report.FormatCSV:
+			cfg.Formats = append(cfg.Formats, format)
+		default:
+			slog.Warn("Ignoring unknown report format", "value", f)
+		}
+	}
+	cfg.Top = runtime.EnvInt("REPORT_TOP", report.DefaultTop)
+
+	dir := runtime.EnvString("REPORT_DIR", defaultDir)
+	cfg.Destinations = append(cfg.Destinations, report.Dir{Path: dir})
+
+	if to := runtime.EnvList("REPORT_EMAIL_TO"); len(to) > 0 {
+		m := report.Mail{Addr: os.Getenv("REPORT_SMTP_ADDR"), From: runtime.EnvString("REPORT_SMTP_FROM", defaultFrom), To: to}
+		if m.Addr == "" {
+			logger.Fatal("REPORT_EMAIL_TO needs REPORT_SMTP_ADDR")
+		}
+		if user := os.Getenv("REPORT_SMTP_USERNAME"); user != "" {
+			host, _, _ := net.SplitHostPort(m.Addr)
+			m.Auth = smtp.PlainAuth("", user, os.Getenv("REPORT_SMTP_PASSWORD"), host)
+		}
+		cfg.Destinations = append(cfg.Destinations, m)
+	}
+
+	if bucket := os.Getenv("REPORT_S3_BUCKET"); bucket != "" {
+		s3 := report.S3{
+			Bucket:   bucket,
+			Region:   runtime.EnvString("REPORT_S3_REGION", defaultRegion),
+			Endpoint: os.Getenv("REPORT_S3_ENDPOINT"),
+			Prefix:   os.Getenv("REPORT_S3_PREFIX"),
+			Credentials: report.Credentials{
+				AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+				SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			},
+		}
+		if s3.Credentials.AccessKeyID == "" || s3.Credentials.SecretAccessKey == "" {
+			logger.Fatal("REPORT_S3_BUCKET needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+		cfg.Destinations = append(cfg.Destinations, s3)
+	}
+	return cfg
+}
@@ -0,0 +1,115 @@
+// Package retention moves old ride events out of the hot ride_events table
+// into ride_events_archive on a schedule.
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	eventsArchived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ride_janitor_events_archived_total",
+		Help: "Number of ride events moved to ride_events_archive.",
+	})
+
+	archiveFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ride_janitor_archive_failures_total",
+		Help: "Number of archive runs that stopped with an error.",
+	})
+
+	lastArchiveRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ride_janitor_last_success_timestamp_seconds",
+		Help: "Unix time of the last archive run that completed without error.",
+	})
+)
+
+// Store is the part of rides_db.RideStore the archiver needs.
+type Store interface {
+	ArchiveRideEvents(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// Config sets how old an event must be before it is archived and how many rows
+// each batch moves.
+type Config struct {
+	ArchiveAfter time.Duration
+	BatchSize    int
+}
+
+func (c *Config) setDefaults() {
+	if c.ArchiveAfter <= 0 {
+		c.ArchiveAfter = 90 * 24 * time.Hour
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 1000
+	}
+}
+
+// Archiver moves ride events older than Config.ArchiveAfter into the archive.
+type Archiver struct {
+	store Store
+	cfg   Config
+	// batchSize is cfg.BatchSize, as SetBatchSize last changed it
+	batchSize atomic.Int64
+}
+
+// NewArchiver returns an Archiver for store.
+func NewArchiver(store Store, cfg Config) *Archiver {
+	cfg.setDefaults()
+	a := &Archiver{store: store, cfg: cfg}
+	a.batchSize.Store(int64(cfg.BatchSize))
+	return a
+}
+
+// SetBatchSize changes how many rows each batch moves from the next batch on,
+// back to the default for n <= 0. It is safe to call while the archiver runs.
+func (a *Archiver) SetBatchSize(n int) {
+	c := Config{BatchSize: n}
+	c.setDefaults()
+	a.batchSize.Store(int64(c.BatchSize))
+}
+
+// RunOnce archives every event older than now minus ArchiveAfter in batches
+// and returns how many were moved.
+func (a *Archiver) RunOnce(ctx context.Context, now time.Time) (int64, error) {
+	cutoff := now.Add(-a.cfg.ArchiveAfter)
+	var total int64
+	for {
+		size := a.batchSize.Load()
+		n, err := a.store.ArchiveRideEvents(ctx, cutoff, int(size))
+		total += n
+		eventsArchived.Add(float64(n))
+		if err != nil {
+			archiveFailures.Inc()
+			return total, err
+		}
+		if n < size {
+			lastArchiveRun.SetToCurrentTime()
+			return total, nil
+		}
+	}
+}
+
+// Run calls RunOnce immediately and then every interval until ctx is cancelled.
+func (a *Archiver) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := a.RunOnce(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Archiving ride events failed", "archived", n, "error", err)
+		} else if n > 0 {
+			slog.Info("Archived ride events", "count", n, "older_than", a.cfg.ArchiveAfter)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+func TestArchiver_MovesOnlyOldEvents(t *testing.T) {
+	ctx := context.Background()
+	store, err := rides_db.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	defer store.Close()
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var evts []events.RideEvent
+	for i, age := range []time.Duration{200 * 24 * time.Hour, 120 * 24 * time.Hour, 100 * 24 * time.Hour, time.Hour} {
+		evts = append(evts, events.RideEvent{
+			ID:         string(rune('a'+i)) + "-event",
+			TripID:     string(rune('a'+i)) + "-trip",
+			Type:       events.EventRideAccepted,
+			State:      events.StateAccepted,
+			OccurredAt: now.Add(-age),
+			Payload:    events.RideAcceptedPayload{DriverID: "driver-1"},
+		})
+	}
+	if _, err := store.InsertRideEvents(ctx, evts); err != nil {
+		t.Fatalf("InsertRideEvents failed: %v", err)
+	}
+
+	archiver := NewArchiver(store, Config{ArchiveAfter: 90 * 24 * time.Hour, BatchSize: 2})
+	n, err := archiver.RunOnce(ctx, now)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 archived, got n=%d err=%v", n, err)
+	}
+
+	var remaining, archived int
+	store.DB().QueryRow(`SELECT COUNT(*) FROM ride_events`).Scan(&remaining)
+	store.DB().QueryRow(`SELECT COUNT(*) FROM ride_events_archive`).Scan(&archived)
+	if remaining != 1 || archived != 3 {
+		t.Errorf("expected 1 live and 3 archived events, got %d and %d", remaining, archived)
+	}
+}
+
+type failingStore struct{ calls int }
+
+func (s *failingStore) ArchiveRideEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	s.calls++
+	if s.calls == 1 {
+		return int64(limit), nil
+	}
+	return 0, errors.New("connection reset")
+}
+
+func TestArchiver_StopsOnError(t *testing.T) {
+	store := &failingStore{}
+	n, err := NewArchiver(store, Config{BatchSize: 10}).RunOnce(context.Background(), time.Now())
+	if err == nil || n != 10 {
+		t.Errorf("expected the first batch and an error, got n=%d err=%v", n, err)
+	}
+}
+
+// limitsStore records the batch sizes the archiver asks for, with nothing to
+// archive.
+type limitsStore struct{ limits []int }
+
+func (s *limitsStore) ArchiveRideEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	s.limits = append(s.limits, limit)
+	return 0, nil
+}
+
+func TestArchiver_SetBatchSize(t *testing.T) {
+	store := &limitsStore{}
+	archiver := NewArchiver(store, Config{BatchSize: 5})
+	run := func() {
+		t.Helper()
+		if _, err := archiver.RunOnce(context.Background(), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run()
+	archiver.SetBatchSize(50)
+	run()
+	archiver.SetBatchSize(0)
+	run()
+	if want := []int{5, 50, 1000}; !slices.Equal(store.limits, want) {
+		t.Errorf("batch sizes = %v, want %v", store.limits, want)
+	}
+}
@@ -0,0 +1,240 @@
+// Package rideconsumer is the runtime behind the ride consumer service. Custom
+// consumers register handlers per event type and call Run, reusing the schema
+// validation, retry tiers, dead-lettering, and metrics of the stock consumer.
+package rideconsumer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Config describes where a Consumer reads from and how it treats failures.
+// Zero values fall back to the defaults used by the ride consumer service.
+type Config struct {
+	Brokers    string
+	GroupID    string
+	Topic      string
+	DLQTopic   string
+	RetryTiers []RetryTier
+	LatencySLO time.Duration
+
+	// Registry supplies the handlers; nil uses the handlers added with RegisterHandler.
+	Registry *Registry
+
+	// Validator checks raw message values before decoding; nil uses the RideEvent
+	// JSON Schema. Consumers of custom event types the schema does not know about
+	// should provide their own or set DisableValidation.
+	Validator         func([]byte) error
+	DisableValidation bool
+
+	// StrictDecoding decodes with events.UnmarshalStrict, dead-lettering events
+	// with fields or types this build does not know about instead of dropping the
+	// fields or handling the event with a nil Payload.
+	StrictDecoding bool
+
+	// Unframe turns values in another encoding, such as the Avro events of
+	// a schemaregistry.RideEventDeserializer, into the JSON of the event
+	// before they are validated, returning JSON values as they are. Values
+	// it fails on are dead-lettered, unless its error has a Temporary method
+	// reporting true, as when a schema registry is unreachable, and the
+	// message is retried. nil reads every value as JSON.
+	Unframe func(ctx context.Context, value []byte) ([]byte, error)
+
+	// Source and Sink replace the Kafka consumer and producer, for example with a
+	// MemoryBroker. With a Source the retry tier topics are published to but not
+	// consumed, and Run returns once the Source returns io.EOF. A Source that can
+	// also StoreMessage and Seek, as a MemoryBroker's can, reads a message again
+	// when its retry cannot be scheduled; any other reads each message once.
+	Source Source
+	Sink   Sink
+}
+
+func (c *Config) setDefaults() {
+	if c.Brokers == "" {
+		c.Brokers = "redpanda:9092"
+	}
+	if c.GroupID == "" {
+		c.GroupID = "ride-consumer-group"
+	}
+	if c.Topic == "" {
+		c.Topic = "ride-events"
+	}
+	if c.DLQTopic == "" {
+		c.DLQTopic = c.Topic + "-dlq"
+	}
+	if c.RetryTiers == nil {
+		c.RetryTiers = DefaultRetryTiers
+	}
+	if c.LatencySLO == 0 {
+		c.LatencySLO = DefaultLatencySLO
+	}
+	if c.Registry == nil {
+		c.Registry = defaultRegistry
+	}
+}
+
+// Consumer reads ride events from Kafka, validates them, and dispatches them to
+// registered handlers, with delayed retries and dead-lettering for failures.
+type Consumer struct {
+	cfg      Config
+	producer *kafka.Producer // nil when Config.Sink is set
+	proc     *processor
+	retries  *retrier
+}
+
+// New builds a Consumer from cfg. Call Run to start consuming and Close when done.
+func New(cfg Config) (*Consumer, error) {
+	cfg.setDefaults()
+
+	validate := cfg.Validator
+	checkEvents := false
+	if cfg.DisableValidation {
+		validate = nil
+	} else if validate == nil {
+		checkEvents = true
+		validator, err := newSchemaValidator()
+		if err != nil {
+			return nil, err
+		}
+		validate = validator.Validate
+	}
+
+	// Shared producer for the retry tiers and the dead-letter topic
+	var producer *kafka.Producer
+	sink := cfg.Sink
+	if sink == nil {
+		var err error
+		producer, err = kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cfg.Brokers})
+		if err != nil {
+			return nil, err
+		}
+		sink = producer
+	}
+
+	dlq := newDeadLetterQueue(sink, cfg.DLQTopic)
+	return &Consumer{
+		cfg:      cfg,
+		producer: producer,
+		proc: &processor{
+			group:       cfg.GroupID,
+			validate:    validate,
+			checkEvents: checkEvents,
+			strict:      cfg.StrictDecoding,
+			unframe:     cfg.Unframe,
+			registry:    cfg.Registry,
+			dlq:         dlq,
+			slo:         cfg.LatencySLO,
+		},
+		retries: &retrier{producer: sink, tiers: cfg.RetryTiers, dlq: dlq},
+	}, nil
+}
+
+// Run consumes until ctx is cancelled. Each retry tier gets its own Kafka consumer
+// so a long delay never holds up a shorter one.
+func (c *Consumer) Run(ctx context.Context) error {
+	if c.cfg.Source != nil {
+		return c.consume(ctx, c.cfg.Source)
+	}
+
+	// The tier goroutines must be done with their consumers before those close
+	ctx, cancel := context.WithCancel(ctx)
+	var (
+		tiers         sync.WaitGroup
+		tierConsumers []*kafka.Consumer
+	)
+	defer func() {
+		cancel()
+		tiers.Wait()
+		for _, tc := range tierConsumers {
+			tc.Close()
+		}
+	}()
+	for _, tier := range c.cfg.RetryTiers {
+		retryConsumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+			"bootstrap.servers": c.cfg.Brokers,
+			"group.id":          c.cfg.GroupID + "-" + tier.Topic,
+			"auto.offset.reset": "earliest",
+			// consumeTier stores each offset once the message is handed on
+			"enable.auto.offset.store": false,
+		})
+		if err != nil {
+			return err
+		}
+		tierConsumers = append(tierConsumers, retryConsumer)
+		if err := retryConsumer.Subscribe(tier.Topic, nil); err != nil {
+			return err
+		}
+		tiers.Add(1)
+		go func() {
+			defer tiers.Done()
+			c.retries.consumeTier(ctx, retryConsumer, c.proc)
+		}()
+	}
+
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": c.cfg.Brokers,
+		"group.id":          c.cfg.GroupID,
+		"auto.offset.reset": "earliest",
+		// consume stores each offset once the message is handed on
+		"enable.auto.offset.store": false,
+	})
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	if err := consumer.Subscribe(c.cfg.Topic, nil); err != nil {
+		return err
+	}
+	return c.consume(ctx, consumer)
+}
+
+// consume processes messages from src until ctx is cancelled or src ends.
+func (c *Consumer) consume(ctx context.Context, src Source) error {
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Context cancelled. Exiting...")
+			return nil
+		default:
+		}
+
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			slog.Error("Consumer error", "error", err)
+			continue
+		}
+		// Transient failures are sent through the delayed retry tiers, one
+		// event of a batch at a time
+		err = c.retries.retry(ctx, msg, c.proc)
+		if store, ok := src.(offsetStore); ok {
+			if !settle(ctx, store, msg, err) {
+				return nil
+			}
+		} else if err != nil {
+			slog.Error("Failed to hand on message", "key", string(msg.Key), "offset", msg.TopicPartition.Offset, "error", err)
+		}
+	}
+}
+
+// Close flushes outstanding retry and dead-letter messages and releases the producer.
+func (c *Consumer) Close() {
+	if c.producer == nil {
+		return
+	}
+	c.producer.Flush(5000)
+	c.producer.Close()
+}
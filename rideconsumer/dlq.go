@@ -0,0 +1,70 @@
+package rideconsumer
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Header keys attached to dead-lettered messages so operators can see why
+// a message was rejected without reprocessing it.
+const (
+	headerDLQReason    = "dlq-reason"
+	headerDLQError     = "dlq-error"
+	headerDLQTopic     = "dlq-original-topic"
+	headerDLQPartition = "dlq-original-partition"
+	headerDLQOffset    = "dlq-original-offset"
+	headerDLQFailedAt  = "dlq-failed-at"
+)
+
+// Reasons recorded in the dlq-reason header.
+const (
+	reasonSchemaViolation = "schema_violation"
+	reasonUnmarshal       = "unmarshal_error"
+	reasonInvalidEvent    = "invalid_event"
+)
+
+// deadLetterQueue republishes messages that cannot be processed to a separate topic.
+type deadLetterQueue struct {
+	producer Sink
+	topic    string
+}
+
+// newDeadLetterQueue publishes dead-lettered messages to topic through producer.
+func newDeadLetterQueue(producer Sink, topic string) *deadLetterQueue {
+	return &deadLetterQueue{producer: producer, topic: topic}
+}
+
+// Send copies msg to the dead-letter topic with the failure reason and details as
+// headers, and waits for the broker to acknowledge it.
+func (d *deadLetterQueue) Send(msg *kafka.Message, reason string, details string) error {
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: headerDLQReason, Value: []byte(reason)},
+		kafka.Header{Key: headerDLQError, Value: []byte(details)},
+		kafka.Header{Key: headerDLQPartition, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		kafka.Header{Key: headerDLQOffset, Value: []byte(msg.TopicPartition.Offset.String())},
+		kafka.Header{Key: headerDLQFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339Nano))},
+	)
+	if msg.TopicPartition.Topic != nil {
+		headers = append(headers, kafka.Header{Key: headerDLQTopic, Value: []byte(*msg.TopicPartition.Topic)})
+	}
+
+	delivery := make(chan kafka.Event, 1)
+	err := d.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &d.topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+	}, delivery)
+	if err != nil {
+		return err
+	}
+	report := (<-delivery).(*kafka.Message)
+	if report.TopicPartition.Error != nil {
+		return fmt.Errorf("deliver to %s: %w", d.topic, report.TopicPartition.Error)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package rideconsumer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Source is where a Consumer reads messages from. *kafka.Consumer satisfies it.
+type Source interface {
+	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+	Close() error
+}
+
+// Sink is where a Consumer publishes retries and dead letters. *kafka.Producer
+// satisfies it; a delivery report is sent on deliveryChan when it is not nil.
+type Sink interface {
+	Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error
+}
+
+// MemoryBroker is an in-process stand-in for Kafka, so the consumer pipeline can
+// run in tests and local development without a broker. Every topic has a single
+// partition; messages are kept for inspection and never expire.
+type MemoryBroker struct {
+	mu      sync.Mutex
+	topics  map[string][]*kafka.Message
+	changed chan struct{}
+}
+
+// NewMemoryBroker returns an empty broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{topics: make(map[string][]*kafka.Message), changed: make(chan struct{})}
+}
+
+// Publish appends a message to topic.
+func (b *MemoryBroker) Publish(topic string, key, value []byte) {
+	b.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Key:            key,
+		Value:          value,
+	}, nil)
+}
+
+// Produce implements Sink.
+func (b *MemoryBroker) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	b.mu.Lock()
+	topic := *msg.TopicPartition.Topic
+	stored := *msg
+	stored.TopicPartition = kafka.TopicPartition{
+		Topic:     &topic,
+		Partition: 0,
+		Offset:    kafka.Offset(len(b.topics[topic])),
+	}
+	stored.Timestamp, stored.TimestampType = time.Now(), kafka.TimestampLogAppendTime
+	b.topics[topic] = append(b.topics[topic], &stored)
+	close(b.changed)
+	b.changed = make(chan struct{})
+	b.mu.Unlock()
+
+	if deliveryChan != nil {
+		report := stored
+		deliveryChan <- &report
+	}
+	return nil
+}
+
+// Messages returns a copy of everything published to topic so far.
+func (b *MemoryBroker) Messages(topic string) []*kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*kafka.Message(nil), b.topics[topic]...)
+}
+
+// Source returns a Source that reads topic from the beginning.
+func (b *MemoryBroker) Source(topic string) Source {
+	return &memorySource{broker: b, topic: topic}
+}
+
+type memorySource struct {
+	broker *MemoryBroker
+	topic  string
+	next   int
+}
+
+// ReadMessage returns the next message, or a kafka.ErrTimedOut error if none
+// arrives within timeout, like *kafka.Consumer.
+func (s *memorySource) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		s.broker.mu.Lock()
+		msgs := s.broker.topics[s.topic]
+		changed := s.broker.changed
+		s.broker.mu.Unlock()
+
+		if s.next < len(msgs) {
+			msg := msgs[s.next]
+			s.next++
+			return msg, nil
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return nil, kafka.NewError(kafka.ErrTimedOut, "timed out", false)
+		}
+	}
+}
+
+// Seek makes the next ReadMessage return the message at the offset of
+// partition.
+func (s *memorySource) Seek(partition kafka.TopicPartition, timeoutMs int) error {
+	s.next = int(partition.Offset)
+	return nil
+}
+
+// StoreMessage does nothing, as a memory source keeps no offsets.
+func (s *memorySource) StoreMessage(m *kafka.Message) ([]kafka.TopicPartition, error) {
+	return nil, nil
+}
+
+func (s *memorySource) Close() error { return nil }
@@ -0,0 +1,365 @@
+package rideconsumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/events/eventstest"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+func TestConsumer_MemoryBrokerEndToEnd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, err := rides_db.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	defer store.Close()
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	registry := NewRegistry()
+	registry.Register(AnyEvent, func(ctx context.Context, msg *Message) error {
+		return store.WithTx(ctx, func(tx rides_db.RideStore) error {
+			if _, err := tx.InsertRideEvent(ctx, msg.Event); err != nil {
+				return err
+			}
+			return tx.UpsertRideState(ctx, msg.Event)
+		})
+	})
+	var recordedAt time.Time
+	registry.Register(events.EventRideRequested, func(ctx context.Context, msg *Message) error {
+		recordedAt = msg.Event.RecordedAt
+		return nil
+	})
+
+	broker := NewMemoryBroker()
+	c, err := New(Config{
+		Topic:    "ride-events",
+		Registry: registry,
+		Source:   broker.Source("ride-events"),
+		Sink:     broker,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	event := events.RideEvent{
+		ID:          "6f1c2a56-2f4e-4d7a-9d2b-0c4b6f1f8a11",
+		TripID:      "trip-1",
+		Type:        events.EventRideRequested,
+		State:       events.StateRequested,
+		OccurredAt:  time.Now().UTC().Truncate(time.Millisecond),
+		PassengerID: "rider-1",
+		Payload:     events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St"},
+	}
+	value, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	broker.Publish("ride-events", []byte(event.TripID), value)
+	broker.Publish("ride-events", []byte("bad"), []byte(`{"event_type":`))
+	// Matches the schema, but a STARTED event cannot leave the ride REQUESTED
+	broker.Publish("ride-events", []byte("invalid"), []byte(`{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-2",
+		"event_type":"STARTED","event_time":"2025-06-01T12:00:00Z","ride_state":"REQUESTED","payload":{"start_time":"2025-06-01T12:00:00Z"}}`))
+
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(broker.Messages("ride-events-dlq")) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	ride, err := store.GetRide(context.Background(), "trip-1")
+	if err != nil {
+		t.Fatalf("GetRide failed: %v", err)
+	}
+	if ride.State != events.StateRequested || ride.PassengerID != "rider-1" {
+		t.Errorf("unexpected ride state: %+v", ride)
+	}
+	if published := broker.Messages("ride-events")[0].Timestamp; !recordedAt.Equal(published) {
+		t.Errorf("RecordedAt = %v, want the append time %v", recordedAt, published)
+	}
+	if dlq := broker.Messages("ride-events-dlq"); len(dlq) != 2 || string(dlq[0].Key) != "bad" || string(dlq[1].Key) != "invalid" {
+		t.Errorf("expected the malformed and invalid messages on the DLQ, got %d messages", len(dlq))
+	}
+}
+
+// TestConsumer_CanonicalEvents checks that every canonical event passes the
+// consumer's schema validation and reaches the handlers as it was encoded.
+func TestConsumer_CanonicalEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		handled []events.RideEvent
+	)
+	registry := NewRegistry()
+	registry.Register(AnyEvent, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, msg.Event)
+		return nil
+	})
+
+	broker := NewMemoryBroker()
+	c, err := New(Config{
+		Topic:    "ride-events",
+		Registry: registry,
+		Source:   broker.Source("ride-events"),
+		Sink:     broker,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	want := eventstest.Canonical()
+	for _, line := range bytes.Split(bytes.TrimSpace(eventstest.CanonicalNDJSON()), []byte("\n")) {
+		broker.Publish("ride-events", nil, line)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(handled)
+		mu.Unlock()
+		if n+len(broker.Messages("ride-events-dlq")) >= len(want) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if dlq := broker.Messages("ride-events-dlq"); len(dlq) != 0 {
+		t.Fatalf("expected no messages on the DLQ, got %d", len(dlq))
+	}
+	if len(handled) != len(want) {
+		t.Fatalf("expected %d events handled, got %d", len(want), len(handled))
+	}
+	for i, e := range handled {
+		e.RecordedAt = time.Time{} // Set from the append time
+		if !reflect.DeepEqual(e, want[i]) {
+			t.Errorf("event %d:\ngot  %+v\nwant %+v", i+1, e, want[i])
+		}
+	}
+}
+
+// TestConsumer_Batch checks that the events of a batch are handled one at a
+// time, and an invalid one is dead-lettered alone.
+func TestConsumer_Batch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		handled []*Message
+	)
+	registry := NewRegistry()
+	registry.Register(AnyEvent, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, msg)
+		return nil
+	})
+
+	broker := NewMemoryBroker()
+	c, err := New(Config{
+		Topic:    "ride-events",
+		Registry: registry,
+		Source:   broker.Source("ride-events"),
+		Sink:     broker,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	canonical := eventstest.Canonical()
+	invalid := canonical[1]
+	invalid.State = events.StateCompleted // Cannot follow its event type
+	batch, err := json.Marshal(events.RideEventBatch{Events: []events.RideEvent{canonical[0], invalid, canonical[2]}})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	topic := "ride-events"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}, Key: []byte("batch"), Value: batch}
+	for k, v := range events.BatchHeaders() {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	broker.Produce(msg, nil)
+	plain, _ := json.Marshal(canonical[3])
+	broker.Publish(topic, nil, plain)
+
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(handled)
+		mu.Unlock()
+		if n >= 3 && len(broker.Messages("ride-events-dlq")) >= 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(handled) != 3 {
+		t.Fatalf("expected 3 events handled, got %d", len(handled))
+	}
+	for i, want := range []struct {
+		id    string
+		index int
+	}{{canonical[0].ID, 0}, {canonical[2].ID, 2}, {canonical[3].ID, -1}} {
+		if m := handled[i]; m.Event.ID != want.id || m.BatchIndex != want.index {
+			t.Errorf("message %d: got event %s at batch index %d, want %s at %d", i, m.Event.ID, m.BatchIndex, want.id, want.index)
+		}
+	}
+	dlq := broker.Messages("ride-events-dlq")
+	if len(dlq) != 1 {
+		t.Fatalf("expected the invalid event on the DLQ, got %d messages", len(dlq))
+	}
+	var dead events.RideEvent
+	if err := json.Unmarshal(dlq[0].Value, &dead); err != nil || dead.ID != invalid.ID {
+		t.Errorf("expected the DLQ message to hold the invalid event alone, got %s", dlq[0].Value)
+	}
+}
+
+// endingSource yields the messages of a topic, then io.EOF.
+type endingSource struct {
+	Source
+	left int
+}
+
+func (s *endingSource) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	if s.left == 0 {
+		return nil, io.EOF
+	}
+	s.left--
+	return s.Source.ReadMessage(timeout)
+}
+
+// TestConsumer_SourceEnds checks that Run returns once its Source ends, having
+// handled every message before.
+func TestConsumer_SourceEnds(t *testing.T) {
+	handled := 0
+	registry := NewRegistry()
+	registry.Register(AnyEvent, func(context.Context, *Message) error {
+		handled++
+		return nil
+	})
+
+	broker := NewMemoryBroker()
+	want := eventstest.Canonical()
+	for _, line := range bytes.Split(bytes.TrimSpace(eventstest.CanonicalNDJSON()), []byte("\n")) {
+		broker.Publish("ride-events", nil, line)
+	}
+	c, err := New(Config{
+		Topic:    "ride-events",
+		Registry: registry,
+		Source:   &endingSource{Source: broker.Source("ride-events"), left: len(want)},
+		Sink:     broker,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if handled != len(want) {
+		t.Errorf("expected %d events handled, got %d", len(want), handled)
+	}
+}
+
+// temporaryError is an Unframe error worth retrying.
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "registry unreachable" }
+func (temporaryError) Temporary() bool { return true }
+
+// TestConsumer_Unframe checks that Unframe's JSON is what is validated and
+// handled, and that its failures are dead-lettered or, when temporary,
+// retried.
+func TestConsumer_Unframe(t *testing.T) {
+	var raws [][]byte
+	registry := NewRegistry()
+	registry.Register(AnyEvent, func(_ context.Context, m *Message) error {
+		raws = append(raws, m.Raw)
+		return nil
+	})
+
+	canonical := eventstest.Canonical()
+	plain, _ := json.Marshal(canonical[0])
+	framed, _ := json.Marshal(canonical[1])
+	broker := NewMemoryBroker()
+	broker.Publish("ride-events", nil, plain)
+	broker.Publish("ride-events", nil, append([]byte("framed:"), framed...))
+	broker.Publish("ride-events", nil, []byte("framed:garbage"))
+	broker.Publish("ride-events", nil, []byte("unreachable"))
+
+	c, err := New(Config{
+		Topic:    "ride-events",
+		Registry: registry,
+		Source:   &endingSource{Source: broker.Source("ride-events"), left: 4},
+		Sink:     broker,
+		Unframe: func(_ context.Context, value []byte) ([]byte, error) {
+			switch {
+			case bytes.Equal(value, []byte("framed:garbage")):
+				return nil, errors.New("undecodable")
+			case bytes.Equal(value, []byte("unreachable")):
+				return nil, temporaryError{}
+			}
+			return bytes.TrimPrefix(value, []byte("framed:")), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(raws) != 2 || !bytes.Equal(raws[1], framed) {
+		t.Errorf("handled %q, want the plain and unframed events", raws)
+	}
+	if n := len(broker.Messages("ride-events-dlq")); n != 1 {
+		t.Errorf("expected 1 dead-lettered message, got %d", n)
+	}
+	if n := len(broker.Messages(DefaultRetryTiers[0].Topic)); n != 1 {
+		t.Errorf("expected 1 message retried, got %d", n)
+	}
+}
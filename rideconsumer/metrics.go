@@ -0,0 +1,74 @@
+package rideconsumer
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// DefaultLatencySLO is the end-to-end freshness target for ride events when
+// LATENCY_SLO_SECONDS is not set.
+const DefaultLatencySLO = 5 * time.Second
+
+var (
+	eventsConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ride_consumer_events_consumed_total",
+		Help: "Number of ride events read from Kafka, by event type.",
+	}, []string{"event_type"})
+
+	eventsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ride_consumer_events_failed_total",
+		Help: "Number of ride events that could not be persisted, by event type and stage.",
+	}, []string{"event_type", "stage"})
+
+	eventLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ride_consumer_event_latency_seconds",
+		Help:    "End-to-end latency from event timestamp to successful handling, by event type.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+	}, []string{"event_type"})
+
+	recordLag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ride_consumer_event_record_lag_seconds",
+		Help:    "Time from event timestamp to the event being appended to Kafka, by event type.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60, 300, 3600},
+	}, []string{"event_type"})
+
+	sloBreaches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ride_consumer_latency_slo_breaches_total",
+		Help: "Number of ride events persisted later than the latency SLO, by event type.",
+	}, []string{"event_type"})
+)
+
+// LatencySLOFromEnv returns the freshness target configured through LATENCY_SLO_SECONDS.
+func LatencySLOFromEnv() time.Duration {
+	raw := os.Getenv("LATENCY_SLO_SECONDS")
+	if raw == "" {
+		return DefaultLatencySLO
+	}
+	secs, err := strconv.ParseFloat(raw, 64)
+	if err != nil || secs <= 0 {
+		slog.Warn("Invalid LATENCY_SLO_SECONDS, using default", "value", raw, "default", DefaultLatencySLO)
+		return DefaultLatencySLO
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// observeHandled records the end-to-end latency of a handled event, and its
+// record lag when known, and counts it against the SLO when it arrived later
+// than allowed.
+func observeHandled(event events.RideEvent, handledAt time.Time, slo time.Duration) {
+	if !event.RecordedAt.IsZero() {
+		recordLag.WithLabelValues(string(event.Type)).Observe(event.Lag().Seconds())
+	}
+	latency := handledAt.Sub(event.OccurredAt)
+	eventLatency.WithLabelValues(string(event.Type)).Observe(latency.Seconds())
+	if latency > slo {
+		sloBreaches.WithLabelValues(string(event.Type)).Inc()
+	}
+}
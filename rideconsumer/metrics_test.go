@@ -0,0 +1,60 @@
+package rideconsumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestLatencySLOFromEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want time.Duration
+	}{
+		{"unset", "", DefaultLatencySLO},
+		{"whole seconds", "2", 2 * time.Second},
+		{"fractional seconds", "0.25", 250 * time.Millisecond},
+		{"not a number", "soon", DefaultLatencySLO},
+		{"zero", "0", DefaultLatencySLO},
+		{"negative", "-3", DefaultLatencySLO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LATENCY_SLO_SECONDS", tt.raw)
+			if got := LatencySLOFromEnv(); got != tt.want {
+				t.Errorf("LatencySLOFromEnv() with %q = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObserveHandled_SLOBreaches(t *testing.T) {
+	occurred := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		latency  time.Duration
+		breaches float64
+	}{
+		{"within the SLO", time.Second, 0},
+		{"at the SLO", 5 * time.Second, 0},
+		{"past the SLO", 6 * time.Second, 1},
+		{"from the future", -time.Second, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := events.NewTripStarted("trip-1", events.WithTime(occurred))
+			breaches := sloBreaches.WithLabelValues(string(e.Type))
+			before := testutil.ToFloat64(breaches)
+
+			observeHandled(e, occurred.Add(tt.latency), 5*time.Second)
+
+			if got := testutil.ToFloat64(breaches) - before; got != tt.breaches {
+				t.Errorf("SLO breaches = %v, want %v", got, tt.breaches)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package rideconsumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/logger"
+)
+
+// processor validates and decodes ride event messages and dispatches them to the
+// registered handlers. It is shared by the main consumer loop and the retry tier
+// consumers so every path applies the same rules.
+type processor struct {
+	group    string
+	validate func([]byte) error
+	registry *Registry
+	dlq      *deadLetterQueue
+	slo      time.Duration
+
+	// checkEvents also applies RideEvent.Validate to decoded events; it is set
+	// when validate is the RideEvent JSON Schema.
+	checkEvents bool
+	// strict decodes with events.UnmarshalStrict.
+	strict bool
+	// unframe is Config.Unframe.
+	unframe func(ctx context.Context, value []byte) ([]byte, error)
+}
+
+// process handles a single message. Permanent failures (schema violations, bad JSON,
+// events that break the RideEvent contract) are dead-lettered here and return nil;
+// the returned error is a transient failure that is worth retrying later, or one
+// wrapping errDeadLetter when the message could not be dead-lettered and must be
+// read again.
+func (p *processor) process(ctx context.Context, msg *kafka.Message) error {
+	value := msg.Value
+	if p.unframe != nil {
+		var err error
+		if value, err = p.unframe(ctx, msg.Value); err != nil {
+			var temp interface{ Temporary() bool }
+			if errors.As(err, &temp) && temp.Temporary() {
+				return err
+			}
+			slog.Warn("Failed to unframe message, routing to DLQ", "offset", msg.TopicPartition.Offset, "key", string(msg.Key), "error", err)
+			eventsFailed.WithLabelValues("unknown", "unmarshal").Inc()
+			return p.deadLetter(msg, reasonUnmarshal, err.Error())
+		}
+	}
+	if p.validate != nil {
+		if err := p.validate(value); err != nil {
+			details := validationDetails(err)
+			slog.Warn("Schema validation failed, routing to DLQ", "offset", msg.TopicPartition.Offset, "key", string(msg.Key), "details", details)
+			eventsFailed.WithLabelValues("unknown", "validate").Inc()
+			return p.deadLetter(msg, reasonSchemaViolation, details)
+		}
+	}
+	var event events.RideEvent
+	if err := p.decode(value, &event); err != nil {
+		slog.Error("Failed to unmarshal message", "event_ID", event.ID, "event type", event.Type, "error", err)
+		eventsFailed.WithLabelValues(string(event.Type), "unmarshal").Inc()
+		return p.deadLetter(msg, reasonUnmarshal, err.Error())
+	}
+	if event.Meta.IsZero() {
+		event.Meta = events.MetaFromHeaders(headerMap(msg.Headers))
+	}
+	if event.RecordedAt.IsZero() && msg.TimestampType != kafka.TimestampNotAvailable {
+		// The append time on LogAppendTime topics, otherwise the time the
+		// producer sent the message
+		event.RecordedAt = msg.Timestamp
+	}
+	// Lines logged for the event from here on carry its trip, ID, and trace,
+	// and so do handlers' lines logged with ctx
+	ctx = logger.WithEvent(ctx, event)
+	if p.checkEvents {
+		if err := event.Validate(); err != nil {
+			logger.FromContext(ctx).Warn("Invalid event, routing to DLQ", "error", err)
+			eventsFailed.WithLabelValues(string(event.Type), "validate").Inc()
+			return p.deadLetter(msg, reasonInvalidEvent, err.Error())
+		}
+	}
+	eventsConsumed.WithLabelValues(string(event.Type)).Inc()
+
+	m := &Message{
+		Event:      event,
+		Raw:        value,
+		Key:        msg.Key,
+		Group:      p.group,
+		Partition:  msg.TopicPartition.Partition,
+		Offset:     int64(msg.TopicPartition.Offset),
+		BatchIndex: batchIndex(msg),
+	}
+	if msg.TopicPartition.Topic != nil {
+		m.Topic = *msg.TopicPartition.Topic
+	}
+	if err := p.registry.Dispatch(ctx, m); err != nil {
+		logger.FromContext(ctx).Error("Handler failed", logger.Err(err))
+		eventsFailed.WithLabelValues(string(event.Type), "handle").Inc()
+		return err
+	}
+	observeHandled(event, time.Now(), p.slo)
+
+	logger.FromContext(ctx).Info("Consumed message", "partition", msg.TopicPartition.Partition, "offset", msg.TopicPartition.Offset, "key", string(msg.Key))
+	return nil
+}
+
+// unbatch returns the messages to process for msg: msg itself, or one message
+// per event of a RideEventBatch, so each is validated, retried, and
+// dead-lettered on its own. The event messages keep the batch's key, position,
+// and headers, with HeaderBatch replaced by their index in HeaderBatchIndex.
+// Batches that cannot be split are dead-lettered, leaving nothing to process;
+// the error wraps errDeadLetter when that fails.
+func (p *processor) unbatch(msg *kafka.Message) ([]*kafka.Message, error) {
+	if !events.IsBatch(headerMap(msg.Headers)) {
+		return []*kafka.Message{msg}, nil
+	}
+	raws, err := events.SplitBatch(msg.Value)
+	if err != nil {
+		slog.Error("Failed to unpack batch", "offset", msg.TopicPartition.Offset, "key", string(msg.Key), "error", err)
+		eventsFailed.WithLabelValues("unknown", "unbatch").Inc()
+		return nil, p.deadLetter(msg, reasonUnmarshal, err.Error())
+	}
+	var headers []kafka.Header
+	for _, h := range msg.Headers {
+		if h.Key != events.HeaderBatch {
+			headers = append(headers, h)
+		}
+	}
+	out := make([]*kafka.Message, len(raws))
+	for i, raw := range raws {
+		m := *msg
+		m.Value = raw
+		m.Headers = setHeader(headers, events.HeaderBatchIndex, strconv.Itoa(i))
+		out[i] = &m
+	}
+	return out, nil
+}
+
+// batchIndex returns the index of msg's event in its batch, or -1 if it was
+// sent on its own.
+func batchIndex(msg *kafka.Message) int {
+	for _, h := range msg.Headers {
+		if h.Key == events.HeaderBatchIndex {
+			if i, err := strconv.Atoi(string(h.Value)); err == nil {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (p *processor) decode(data []byte, event *events.RideEvent) error {
+	if p.strict {
+		return events.UnmarshalStrict(data, event)
+	}
+	return event.UnmarshalJSON(data)
+}
+
+// headerMap returns headers as a map, keeping the last value of repeated keys.
+func headerMap(headers []kafka.Header) map[string]string {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[h.Key] = string(h.Value)
+	}
+	return m
+}
+
+// errDeadLetter is wrapped by the errors of messages that could not be
+// dead-lettered. Such a message was handed on nowhere, so it is read again
+// rather than scheduled for retry.
+var errDeadLetter = errors.New("dead-letter message")
+
+// deadLetter sends msg to the dead-letter topic, returning an error wrapping
+// errDeadLetter if it could not.
+func (p *processor) deadLetter(msg *kafka.Message, reason, details string) error {
+	if err := p.dlq.Send(msg, reason, details); err != nil {
+		slog.Error("Failed to dead-letter message", "offset", msg.TopicPartition.Offset, "reason", reason, "error", err)
+		return fmt.Errorf("%w: %w", errDeadLetter, err)
+	}
+	return nil
+}
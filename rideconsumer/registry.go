@@ -0,0 +1,86 @@
+package rideconsumer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// AnyEvent registers a handler that runs for every event type.
+const AnyEvent events.RideEventType = "*"
+
+// Message is a decoded ride event along with the Kafka metadata it was read with.
+// Raw holds the original message value so handlers for custom event types can
+// decode payloads the events package does not know about. Events unpacked from
+// an events.RideEventBatch share its offset, and Raw holds the event alone;
+// with Config.Unframe, Raw holds the JSON it returned.
+type Message struct {
+	Event     events.RideEvent
+	Raw       []byte
+	Key       []byte
+	Group     string
+	Topic     string
+	Partition int32
+	Offset    int64
+	// BatchIndex is the event's position in its batch, or -1 for an event sent
+	// on its own.
+	BatchIndex int
+	// Outcome is set by a handler that stores the event, to how it was stored,
+	// such as a rides_db.InsertOutcome, so that the handlers after it can skip
+	// an event stored before. It is empty until one does.
+	Outcome string
+}
+
+// Handler processes a single message. Returning an error marks the failure as
+// transient and sends the message through the retry tiers, where every handler
+// runs again, so handlers must be idempotent.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Registry maps event types to the handlers that process them.
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[events.RideEventType][]Handler
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[events.RideEventType][]Handler)}
+}
+
+// Register adds h for eventType. Use AnyEvent to receive every event.
+func (r *Registry) Register(eventType events.RideEventType, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[eventType] = append(r.handlers[eventType], h)
+}
+
+// Handlers returns the handlers for eventType: those registered for AnyEvent
+// first, then the type-specific ones, each in registration order.
+func (r *Registry) Handlers(eventType events.RideEventType) []Handler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := append([]Handler{}, r.handlers[AnyEvent]...)
+	if eventType != AnyEvent {
+		out = append(out, r.handlers[eventType]...)
+	}
+	return out
+}
+
+// Dispatch runs the handlers for msg's event type in order, stopping at the first error.
+func (r *Registry) Dispatch(ctx context.Context, msg *Message) error {
+	for _, h := range r.Handlers(msg.Event.Type) {
+		if err := h(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var defaultRegistry = NewRegistry()
+
+// RegisterHandler adds h for eventType to the default registry used by consumers
+// that are not given their own.
+func RegisterHandler(eventType events.RideEventType, h Handler) {
+	defaultRegistry.Register(eventType, h)
+}
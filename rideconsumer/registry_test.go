@@ -0,0 +1,75 @@
+package rideconsumer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestRegistry_DispatchOrder(t *testing.T) {
+	r := NewRegistry()
+	var calls []string
+	record := func(name string) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	r.Register(events.EventTripCompleted, record("completed"))
+	r.Register(AnyEvent, record("any-1"))
+	r.Register(AnyEvent, record("any-2"))
+	r.Register(events.EventRideRequested, record("requested"))
+
+	msg := &Message{Event: events.RideEvent{Type: events.EventTripCompleted}}
+	if err := r.Dispatch(context.Background(), msg); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	want := []string{"any-1", "any-2", "completed"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+}
+
+func TestRegistry_DispatchStopsOnError(t *testing.T) {
+	r := NewRegistry()
+	boom := errors.New("boom")
+	ranSecond := false
+
+	r.Register(AnyEvent, func(ctx context.Context, msg *Message) error { return boom })
+	r.Register(AnyEvent, func(ctx context.Context, msg *Message) error {
+		ranSecond = true
+		return nil
+	})
+
+	err := r.Dispatch(context.Background(), &Message{Event: events.RideEvent{Type: events.EventRideAccepted}})
+	if !errors.Is(err, boom) {
+		t.Errorf("expected boom error, got %v", err)
+	}
+	if ranSecond {
+		t.Error("handlers after a failure should not run")
+	}
+}
+
+func TestRegistry_CustomEventType(t *testing.T) {
+	r := NewRegistry()
+	custom := events.RideEventType("PAYMENT_CAPTURED")
+	handled := false
+	r.Register(custom, func(ctx context.Context, msg *Message) error {
+		handled = true
+		return nil
+	})
+
+	if err := r.Dispatch(context.Background(), &Message{Event: events.RideEvent{Type: custom}}); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	if !handled {
+		t.Error("expected handler for custom event type to run")
+	}
+	if len(r.Handlers(events.EventRideRequested)) != 0 {
+		t.Error("custom handler should not run for other event types")
+	}
+}
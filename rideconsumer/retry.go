@@ -0,0 +1,207 @@
+package rideconsumer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Header keys used to carry retry state between tiers.
+const (
+	headerRetryNotBefore = "retry-not-before"
+	headerRetryAttempt   = "retry-attempt"
+	headerRetryError     = "retry-error"
+)
+
+const reasonRetriesExhausted = "retries_exhausted"
+
+// RetryTier is a delayed-retry topic. Messages published to it are not processed
+// again until Delay has passed since they failed.
+type RetryTier struct {
+	Topic string
+	Delay time.Duration
+}
+
+// DefaultRetryTiers are tried in order after a transient failure; once all of them
+// have failed the message is dead-lettered.
+var DefaultRetryTiers = []RetryTier{
+	{Topic: "ride-events-retry-5s", Delay: 5 * time.Second},
+	{Topic: "ride-events-retry-1m", Delay: time.Minute},
+}
+
+// retrier republishes transiently failed messages to the next retry tier.
+type retrier struct {
+	producer Sink
+	tiers    []RetryTier
+	dlq      *deadLetterQueue
+}
+
+// Schedule publishes msg to the tier after the one it was last attempted on, or to
+// the dead-letter topic when every tier has been exhausted.
+func (r *retrier) Schedule(msg *kafka.Message, cause error) error {
+	attempt := retryAttempt(msg)
+	if attempt >= len(r.tiers) {
+		slog.Warn("Retries exhausted, routing to DLQ", "key", string(msg.Key), "attempts", attempt, "error", cause)
+		return r.dlq.Send(msg, reasonRetriesExhausted, cause.Error())
+	}
+
+	tier := r.tiers[attempt]
+	headers := setHeader(msg.Headers, headerRetryAttempt, strconv.Itoa(attempt+1))
+	headers = setHeader(headers, headerRetryNotBefore, time.Now().Add(tier.Delay).UTC().Format(time.RFC3339Nano))
+	headers = setHeader(headers, headerRetryError, cause.Error())
+
+	delivery := make(chan kafka.Event, 1)
+	err := r.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &tier.Topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+	}, delivery)
+	if err != nil {
+		return err
+	}
+	report := (<-delivery).(*kafka.Message)
+	if report.TopicPartition.Error != nil {
+		return report.TopicPartition.Error
+	}
+	slog.Info("Scheduled message for retry", "key", string(msg.Key), "topic", tier.Topic, "attempt", attempt+1)
+	return nil
+}
+
+// reseekPause is how long a consumer waits before reading a message again
+// that it could not hand on, so that a broker that is down is not hammered.
+var reseekPause = time.Second
+
+// offsetStore is the part of *kafka.Consumer that stores offsets by hand,
+// with enable.auto.offset.store off, and seeks back to read a message again.
+type offsetStore interface {
+	StoreMessage(m *kafka.Message) ([]kafka.TopicPartition, error)
+	Seek(partition kafka.TopicPartition, timeoutMs int) error
+}
+
+// tierConsumer is the part of *kafka.Consumer a retry tier reads with.
+type tierConsumer interface {
+	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+	offsetStore
+}
+
+// consumeTier reads one retry topic, waits until each message's not-before time,
+// and feeds it back through the processor. Every message on a tier has the same
+// delay, so waiting on the head of a partition never delays an earlier-due message.
+// A message's offset is stored only once it is processed or handed on to the
+// next tier, so one that is not, as on shutdown, is read again.
+func (r *retrier) consumeTier(ctx context.Context, consumer tierConsumer, p *processor) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		msg, err := consumer.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			slog.Error("Retry consumer error", "error", err)
+			continue
+		}
+
+		if wait := time.Until(notBefore(msg)); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if !settle(ctx, consumer, msg, r.retry(ctx, msg, p)) {
+			return
+		}
+	}
+}
+
+// retry processes the events of msg, scheduling those that fail again, and
+// returns the error of the first that could not be dead-lettered or scheduled.
+func (r *retrier) retry(ctx context.Context, msg *kafka.Message, p *processor) error {
+	msgs, err := p.unbatch(msg)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if err := p.process(ctx, msg); err != nil {
+			if errors.Is(err, errDeadLetter) {
+				return err
+			}
+			if err := r.Schedule(msg, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// settle stores the offset of msg once it has been handed on, or, when err
+// says it was not, seeks back to it and pauses before it is read again. It
+// returns false if ctx was cancelled during the pause.
+func settle(ctx context.Context, store offsetStore, msg *kafka.Message, err error) bool {
+	if err == nil {
+		if _, err := store.StoreMessage(msg); err != nil {
+			slog.Error("Failed to store offset", "key", string(msg.Key), "error", err)
+		}
+		return true
+	}
+	slog.Error("Failed to hand on message, reading it again", "key", string(msg.Key), "offset", msg.TopicPartition.Offset, "error", err)
+	if err := store.Seek(msg.TopicPartition, 1000); err != nil {
+		slog.Error("Failed to seek back to message", "key", string(msg.Key), "error", err)
+	}
+	select {
+	case <-time.After(reseekPause):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// retryAttempt returns how many retry tiers msg has already been through.
+func retryAttempt(msg *kafka.Message) int {
+	for _, h := range msg.Headers {
+		if h.Key == headerRetryAttempt {
+			n, err := strconv.Atoi(string(h.Value))
+			if err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// notBefore returns the earliest time msg may be reprocessed. Messages without a
+// valid header are due immediately.
+func notBefore(msg *kafka.Message) time.Time {
+	for _, h := range msg.Headers {
+		if h.Key == headerRetryNotBefore {
+			t, err := time.Parse(time.RFC3339Nano, string(h.Value))
+			if err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// setHeader returns a copy of headers with key set to value, replacing any existing entry.
+func setHeader(headers []kafka.Header, key, value string) []kafka.Header {
+	out := make([]kafka.Header, 0, len(headers)+1)
+	for _, h := range headers {
+		if h.Key != key {
+			out = append(out, h)
+		}
+	}
+	return append(out, kafka.Header{Key: key, Value: []byte(value)})
+}
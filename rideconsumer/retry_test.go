@@ -0,0 +1,269 @@
+package rideconsumer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events/eventstest"
+)
+
+// fakeTier is a retry topic of one partition, read as a tierConsumer.
+type fakeTier struct {
+	mu     sync.Mutex
+	msgs   []*kafka.Message
+	next   int
+	seeks  []kafka.Offset
+	stored []kafka.Offset
+}
+
+func newFakeTier(values ...[]byte) *fakeTier {
+	topic := "ride-events-retry-5s"
+	f := &fakeTier{}
+	for i, v := range values {
+		f.msgs = append(f.msgs, &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(i)},
+			Value:          v,
+		})
+	}
+	return f
+}
+
+func (f *fakeTier) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.next == len(f.msgs) {
+		time.Sleep(time.Millisecond)
+		return nil, kafka.NewError(kafka.ErrTimedOut, "timed out", false)
+	}
+	f.next++
+	return f.msgs[f.next-1], nil
+}
+
+func (f *fakeTier) StoreMessage(m *kafka.Message) ([]kafka.TopicPartition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stored = append(f.stored, m.TopicPartition.Offset)
+	return nil, nil
+}
+
+func (f *fakeTier) Seek(tp kafka.TopicPartition, timeoutMs int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seeks = append(f.seeks, tp.Offset)
+	f.next = int(tp.Offset)
+	return nil
+}
+
+func (f *fakeTier) offsets() (seeks, stored []kafka.Offset) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.seeks), slices.Clone(f.stored)
+}
+
+// downSink fails to produce until it is brought up.
+type downSink struct {
+	*MemoryBroker
+	mu   sync.Mutex
+	down bool
+}
+
+func (s *downSink) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	s.mu.Lock()
+	down := s.down
+	s.down = false
+	s.mu.Unlock()
+	if down {
+		return errors.New("broker down")
+	}
+	return s.MemoryBroker.Produce(msg, deliveryChan)
+}
+
+// TestRetrier_OffsetsStoredOnceHandedOn checks that a retry tier stores the
+// offset of a message only once it is processed or scheduled again, reading
+// one it could not schedule again.
+func TestRetrier_OffsetsStoredOnceHandedOn(t *testing.T) {
+	defer func(p time.Duration) { reseekPause = p }(reseekPause)
+	reseekPause = time.Millisecond
+
+	var mu sync.Mutex
+	calls := 0
+	registry := NewRegistry()
+	registry.Register(AnyEvent, func(context.Context, *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= 2 {
+			return errors.New("database down")
+		}
+		return nil
+	})
+	sink := &downSink{MemoryBroker: NewMemoryBroker(), down: true}
+	c, err := New(Config{Topic: "ride-events", Registry: registry, Source: sink.Source("ride-events"), Sink: sink})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	// The first attempt fails and cannot be scheduled, the second fails and is
+	// scheduled on the next tier, and the next message succeeds
+	lines := bytes.Split(bytes.TrimSpace(eventstest.CanonicalNDJSON()), []byte("\n"))
+	tier := newFakeTier(lines[0], lines[1])
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.retries.consumeTier(ctx, tier, c.proc)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, stored := tier.offsets(); len(stored) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the offsets to be stored")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	seeks, stored := tier.offsets()
+	if !slices.Equal(seeks, []kafka.Offset{0}) {
+		t.Errorf("seeks = %v, want back to 0 once", seeks)
+	}
+	if !slices.Equal(stored, []kafka.Offset{0, 1}) {
+		t.Errorf("stored offsets = %v, want 0 and 1 once each", stored)
+	}
+	if n := len(sink.Messages(DefaultRetryTiers[0].Topic)); n != 1 {
+		t.Errorf("%d messages scheduled again, want 1", n)
+	}
+}
+
+// TestRetrier_ShutdownDuringWait checks that a message not yet due when the
+// consumer stops keeps its offset unstored, to be read again.
+func TestRetrier_ShutdownDuringWait(t *testing.T) {
+	registry := NewRegistry()
+	broker := NewMemoryBroker()
+	c, err := New(Config{Topic: "ride-events", Registry: registry, Source: broker.Source("ride-events"), Sink: broker})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	tier := newFakeTier(bytes.Split(eventstest.CanonicalNDJSON(), []byte("\n"))[0])
+	tier.msgs[0].Headers = setHeader(nil, headerRetryNotBefore, time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c.retries.consumeTier(ctx, tier, c.proc)
+
+	if _, stored := tier.offsets(); len(stored) != 0 {
+		t.Errorf("stored offsets %v of a message never retried", stored)
+	}
+}
+
+// TestConsumer_RereadsWhenScheduleFails checks that the main consumer reads a
+// message again, rather than storing its offset, when its retry cannot be
+// scheduled.
+func TestConsumer_RereadsWhenScheduleFails(t *testing.T) {
+	defer func(p time.Duration) { reseekPause = p }(reseekPause)
+	reseekPause = time.Millisecond
+
+	var mu sync.Mutex
+	calls := 0
+	registry := NewRegistry()
+	registry.Register(AnyEvent, func(context.Context, *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return errors.New("database down")
+		}
+		return nil
+	})
+	sink := &downSink{MemoryBroker: NewMemoryBroker(), down: true}
+	sink.Publish("ride-events", nil, bytes.Split(eventstest.CanonicalNDJSON(), []byte("\n"))[0])
+	c, err := New(Config{Topic: "ride-events", Registry: registry, Source: sink.Source("ride-events"), Sink: sink})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := calls
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("handler called %d times, want the message read again", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if n := len(sink.Messages(DefaultRetryTiers[0].Topic)); n != 0 {
+		t.Errorf("%d messages scheduled for retry, want none", n)
+	}
+}
+
+// TestRetrier_RereadsWhenDeadLetterFails checks that a message that cannot be
+// processed is read again, rather than having its offset stored, when it
+// cannot be dead-lettered either.
+func TestRetrier_RereadsWhenDeadLetterFails(t *testing.T) {
+	defer func(p time.Duration) { reseekPause = p }(reseekPause)
+	reseekPause = time.Millisecond
+
+	sink := &downSink{MemoryBroker: NewMemoryBroker(), down: true}
+	c, err := New(Config{Topic: "ride-events", Registry: NewRegistry(), Source: sink.Source("ride-events"), Sink: sink})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	tier := newFakeTier([]byte("{not json"))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.retries.consumeTier(ctx, tier, c.proc)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, stored := tier.offsets(); len(stored) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the offset to be stored")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if seeks, _ := tier.offsets(); !slices.Equal(seeks, []kafka.Offset{0}) {
+		t.Errorf("seeks = %v, want back to 0 once", seeks)
+	}
+	if n := len(sink.Messages("ride-events-dlq")); n != 1 {
+		t.Errorf("%d messages dead-lettered, want 1 once the DLQ is back", n)
+	}
+	if n := len(sink.Messages(DefaultRetryTiers[0].Topic)); n != 0 {
+		t.Errorf("%d messages scheduled for retry, want none", n)
+	}
+}
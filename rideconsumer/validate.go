@@ -0,0 +1,71 @@
+package rideconsumer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+const rideEventSchemaURL = "ride_event.schema.json"
+
+// schemaValidator checks raw Kafka message values against the RideEvent JSON Schema
+// so malformed events never reach the database.
+type schemaValidator struct {
+	schema *jsonschema.Schema
+}
+
+// newSchemaValidator compiles the JSON Schema embedded in the events package.
+func newSchemaValidator() (*schemaValidator, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(rideEventSchemaURL, bytes.NewReader(events.RideEventJSONSchema)); err != nil {
+		return nil, fmt.Errorf("load ride event schema: %w", err)
+	}
+	schema, err := compiler.Compile(rideEventSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("compile ride event schema: %w", err)
+	}
+	return &schemaValidator{schema: schema}, nil
+}
+
+// Validate returns an error describing every schema violation found in raw.
+func (v *schemaValidator) Validate(raw []byte) error {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return v.schema.Validate(doc)
+}
+
+// validationDetails flattens the leaf causes of a schema validation error into a
+// single "location: message" list suitable for a Kafka header value.
+func validationDetails(err error) string {
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err.Error()
+	}
+	var details []string
+	var collect func(*jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			location := e.InstanceLocation
+			if location == "" {
+				location = "/"
+			}
+			details = append(details, location+": "+e.Message)
+			return
+		}
+		for _, cause := range e.Causes {
+			collect(cause)
+		}
+	}
+	collect(ve)
+	return strings.Join(details, "; ")
+}
@@ -0,0 +1,91 @@
+package rideconsumer
+
+import "testing"
+
+func TestSchemaValidator_SurgeAndRideEvents(t *testing.T) {
+	validator, err := newSchemaValidator()
+	if err != nil {
+		t.Fatalf("newSchemaValidator failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{
+			name: "surge update without trip",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","event_type":"SURGE_UPDATED","event_time":"2025-06-01T12:00:00Z",
+				"payload":{"zone_id":"midtown","multiplier":1.5}}`,
+		},
+		{
+			name: "surge update without multiplier",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","event_type":"SURGE_UPDATED","event_time":"2025-06-01T12:00:00Z",
+				"payload":{"zone_id":"midtown"}}`,
+			wantErr: true,
+		},
+		{
+			name: "ride event still needs its trip",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","event_type":"STARTED","event_time":"2025-06-01T12:00:00Z",
+				"payload":{"start_time":"2025-06-01T12:00:00Z"}}`,
+			wantErr: true,
+		},
+		{
+			name: "ride event in a city",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-1","event_type":"STARTED","event_time":"2025-06-01T12:00:00Z",
+				"ride_state":"IN_PROGRESS","city":"nyc","payload":{"start_time":"2025-06-01T12:00:00Z"}}`,
+		},
+		{
+			name: "city that is not a schema name",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-1","event_type":"STARTED","event_time":"2025-06-01T12:00:00Z",
+				"ride_state":"IN_PROGRESS","city":"New York","payload":{"start_time":"2025-06-01T12:00:00Z"}}`,
+			wantErr: true,
+		},
+		{
+			name: "version 1 event with rider_id",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-1","event_type":"STARTED","event_time":"2025-06-01T12:00:00Z",
+				"ride_state":"IN_PROGRESS","rider_id":"rider-1","payload":{"start_time":"2025-06-01T12:00:00Z"}}`,
+		},
+		{
+			name: "completed event with a fare",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-1","event_type":"COMPLETED","event_time":"2025-06-01T12:00:00Z",
+				"ride_state":"COMPLETED","schema_version":5,"payload":{"end_time":"2025-06-01T12:00:00Z","distance_km":3,"fare":{"amount_minor":950,"currency":"USD"}}}`,
+		},
+		{
+			name: "version 4 completed event with fare_usd",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-1","event_type":"COMPLETED","event_time":"2025-06-01T12:00:00Z",
+				"ride_state":"COMPLETED","schema_version":4,"payload":{"end_time":"2025-06-01T12:00:00Z","distance_km":3,"fare_usd":9.5}}`,
+		},
+		{
+			name: "fare in dollars rather than cents",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-1","event_type":"COMPLETED","event_time":"2025-06-01T12:00:00Z",
+				"ride_state":"COMPLETED","schema_version":5,"payload":{"end_time":"2025-06-01T12:00:00Z","distance_km":3,"fare":{"amount_minor":9.5,"currency":"USD"}}}`,
+			wantErr: true,
+		},
+		{
+			name: "schema version that is not a number",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-1","event_type":"STARTED","event_time":"2025-06-01T12:00:00Z",
+				"ride_state":"IN_PROGRESS","schema_version":"2","payload":{"start_time":"2025-06-01T12:00:00Z"}}`,
+			wantErr: true,
+		},
+		{
+			name: "payload checked when payload_type names the built-in struct",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-1","event_type":"STARTED","event_time":"2025-06-01T12:00:00Z",
+				"ride_state":"IN_PROGRESS","payload_type":"RideStartedPayload","payload":{}}`,
+			wantErr: true,
+		},
+		{
+			name: "payload version left to its own validator",
+			raw: `{"id":"0b5f8a4e-5d2c-4f43-9d5e-1c2b3a4d5e6f","trip_id":"trip-1","event_type":"STARTED","event_time":"2025-06-01T12:00:00Z",
+				"ride_state":"IN_PROGRESS","payload_type":"RideStartedPayloadV2","payload":{"started_at":"2025-06-01T12:00:00Z"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
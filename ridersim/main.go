@@ -0,0 +1,163 @@
+// Command ridersim simulates the riders of the ride platform. It requests
+// rides, follows the events the matcher and drivers send for them on the ride
+// topic, and reacts as riders would: it cancels requests nobody takes and
+// rides whose driver is too far away, and rates and tips completed rides. Run
+// it with the matcher in offer mode and driversim.
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+const (
+	defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID        = "ridersim"
+	// maxActive is how many rides may be open at once, as in the producer
+	maxActive = 100
+)
+
+// ridersFromEnv reads the simulation settings: RIDERS, the number of riders
+// (default 200); MAX_RIDES and TICK_INTERVAL, as the producer reads them, a
+// ride being requested every tick; and CITIES. Invalid values are logged and
+// left to the defaults. MAX_RIDES and TICK_INTERVAL are read again when the
+// config file changes them.
+func ridersFromEnv() (size, maxRides int, tick time.Duration, cities []string) {
+	size = 200
+	for name, set := range map[string]func(int){
+		"RIDERS":    func(n int) { size = n },
+		"MAX_RIDES": func(n int) { maxRides = n },
+	} {
+		raw := runtime.EnvString(name, "")
+		if raw == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(raw); err != nil || n < 0 || (n == 0 && name == "RIDERS") {
+			slog.Warn("Invalid setting, using default", "name", name, "value", raw)
+		} else {
+			set(n)
+		}
+	}
+	return size, maxRides, runtime.EnvDuration("TICK_INTERVAL", time.Second), runtime.EnvList("CITIES")
+}
+
+func main() {
+	svc := runtime.Start("ridersim")
+	defer svc.Stop()
+	slog.Info("Starting rider simulator")
+	instance, _ := os.Hostname()
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		logger.Fatal("Failed to create producer", "error", err)
+	}
+	logger.OnShutdown("kafka producer", func(context.Context) error {
+		if n := producer.Flush(5000); n > 0 {
+			slog.Warn("Ride events left undelivered", "count", n)
+		}
+		producer.Close()
+		return nil
+	})
+	logger.Go("delivery reports", func() {
+		for e := range producer.Events() {
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				slog.Error("Delivery failed", "key", string(m.Key), "error", m.TopicPartition.Error)
+			}
+		}
+	})
+
+	// Riders start fresh each run, so earlier rides are not theirs
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          groupID,
+		"auto.offset.reset": "latest",
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error { return consumer.Close() })
+	if err := consumer.Subscribe(topics.RideEvents, nil); err != nil {
+		logger.Fatal("Failed to subscribe", "error", err)
+	}
+
+	size, maxRides, tick, cities := ridersFromEnv()
+	sim := NewSim(size, cities, producer, instance, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	slog.Info("Riders ready", "riders", size, "cities", cities)
+
+	ctx, cancel := context.WithCancel(svc.Context())
+	defer cancel()
+
+	// Changes from the config file are applied between ticks
+	reload := make(chan struct{}, 1)
+	svc.OnChange(func() {
+		select {
+		case reload <- struct{}{}:
+		default:
+		}
+	}, "MAX_RIDES", "TICK_INTERVAL")
+
+	// Request a ride every tick until MAX_RIDES have been, then stop once
+	// they have all ended
+	logger.Go("requests", func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		requested := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reload:
+				_, maxRides, tick, _ = ridersFromEnv()
+				ticker.Reset(tick)
+				slog.Info("Applied new ride settings", "max_rides", maxRides, "tick_interval", tick.String())
+				continue
+			case <-ticker.C:
+			}
+			now := time.Now()
+			if maxRides > 0 && requested >= maxRides && sim.Active() == 0 {
+				slog.Info("Simulated all rides", "rides", requested)
+				cancel()
+				return
+			}
+			if sim.Active() < maxActive && (maxRides == 0 || requested < maxRides) {
+				ok, err := sim.Request(now)
+				if ok {
+					requested++
+				}
+				if err != nil {
+					slog.Error("Failed to request ride", "error", err)
+				}
+			}
+			if err := sim.Tick(now); err != nil {
+				slog.Error("Failed to send rider events", "error", err)
+			}
+		}
+	})
+
+	for ctx.Err() == nil {
+		msg, err := consumer.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if !errors.As(err, &kerr) || kerr.Code() != kafka.ErrTimedOut {
+				slog.Error("Consumer error", "error", err)
+			}
+			continue
+		}
+		if err := sim.Handle(msg, time.Now()); err != nil {
+			slog.Error("Failed to answer ride event", "key", string(msg.Key), "error", err)
+		}
+	}
+	slog.Info("Rider simulator stopped")
+}
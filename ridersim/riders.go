@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/google/uuid"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/ridesim"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+// Profile is how a simulated rider waits, cancels, rates, and tips.
+type Profile struct {
+	Name string
+	// Patience is how long the rider waits for a driver to accept before
+	// cancelling the request.
+	Patience time.Duration
+	// MaxWait is the longest pickup the rider puts up with. A driver whose
+	// location puts them further away than that, at their speed, is cancelled
+	// on for driver_too_far.
+	MaxWait time.Duration
+	// CancelRate is the chance the rider changes their plans and cancels a
+	// ride before pickup.
+	CancelRate float64
+	// TipRate is the chance the rider tips after a ride, and TipShare the
+	// share of the fare they tip.
+	TipRate  float64
+	TipShare float64
+}
+
+// profiles are given out to riders at random.
+var profiles = []Profile{
+	{Name: "relaxed", Patience: time.Minute, MaxWait: 15 * time.Minute, CancelRate: 0.02, TipRate: 0.6, TipShare: 0.2},
+	{Name: "regular", Patience: 30 * time.Second, MaxWait: 10 * time.Minute, CancelRate: 0.05, TipRate: 0.4, TipShare: 0.15},
+	{Name: "hurried", Patience: 15 * time.Second, MaxWait: 5 * time.Minute, CancelRate: 0.1, TipRate: 0.2, TipShare: 0.1},
+}
+
+// rider is a simulated passenger.
+type rider struct {
+	ID      string
+	Profile Profile
+	City    string
+	Riding  bool // whether the rider has a ride that has not ended
+}
+
+// ride is a ride a rider requested, until it ends.
+type ride struct {
+	TripID      string
+	Rider       *rider
+	DriverID    string
+	State       events.RideState
+	Route       events.Route
+	LastEventID string
+	UpdatedAt   time.Time
+	// CancelAt, when set, is when the rider cancels the ride before pickup.
+	CancelAt time.Time
+}
+
+// Sim is a population of simulated riders. It requests rides, follows the
+// events the matcher and drivers send for them, and answers those the way
+// riders would: it cancels requests nobody takes and rides whose driver is
+// too far away, sometimes cancels for no reason of the driver's, and rates
+// and tips completed rides. It is safe for concurrent use.
+type Sim struct {
+	sink     rideconsumer.Sink
+	instance string
+
+	mu     sync.Mutex
+	rand   *rand.Rand
+	riders []*rider
+	rides  map[string]*ride
+}
+
+// NewSim returns size riders, spread across cities when any are given, who
+// send their events to sink.
+func NewSim(size int, cities []string, sink rideconsumer.Sink, instance string, r *rand.Rand) *Sim {
+	s := &Sim{sink: sink, instance: instance, rand: r, rides: make(map[string]*ride)}
+	for range size {
+		rd := &rider{ID: uuid.NewString(), Profile: profiles[r.IntN(len(profiles))]}
+		if len(cities) > 0 {
+			rd.City = cities[r.IntN(len(cities))]
+		}
+		s.riders = append(s.riders, rd)
+	}
+	return s
+}
+
+// Active returns how many rides have not ended.
+func (s *Sim) Active() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.rides)
+}
+
+// Request has a rider without a ride request one, and reports whether any
+// rider was free to.
+func (s *Sim) Request(now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var free []*rider
+	for _, rd := range s.riders {
+		if !rd.Riding {
+			free = append(free, rd)
+		}
+	}
+	if len(free) == 0 {
+		return false, nil
+	}
+	rd := free[s.rand.IntN(len(free))]
+	rd.Riding = true
+	r := &ride{
+		TripID:    uuid.NewString(),
+		Rider:     rd,
+		State:     events.StateRequested,
+		Route:     events.Route{ridesim.RandomCoordinate(), ridesim.RandomCoordinate()},
+		UpdatedAt: now,
+	}
+	s.rides[r.TripID] = r
+	opts := append(s.options(r, now), events.WithCoordinates(&r.Route[0], &r.Route[1]))
+	return true, s.publish(r, events.NewRideRequested(r.TripID, rd.ID, gofakeit.Street(), gofakeit.Street(), opts...))
+}
+
+// Tick cancels the requests riders gave up waiting on, and the rides they
+// planned to cancel by now.
+func (s *Sim) Tick(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, r := range s.rides {
+		switch {
+		case r.State == events.StateRequested && now.Sub(r.UpdatedAt) >= r.Rider.Profile.Patience:
+			errs = append(errs, s.cancel(r, now, events.ReasonChangedPlans))
+		case r.State == events.StateAccepted && !r.CancelAt.IsZero() && !now.Before(r.CancelAt):
+			errs = append(errs, s.cancel(r, now, events.ReasonChangedPlans))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handle follows an event on the ride topic for one of the riders' rides.
+// Messages that cannot be decoded are logged and skipped; the error is from
+// sending the rider's answer.
+func (s *Sim) Handle(msg *kafka.Message, now time.Time) error {
+	if topic := *msg.TopicPartition.Topic; topic != topics.RideEvents {
+		return fmt.Errorf("ridersim: message from unexpected topic %q", topic)
+	}
+	var e events.RideEvent
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+		return nil
+	}
+	if e.Meta.ProducerInstance == s.instance {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rides[e.TripID]
+	if !ok {
+		return nil
+	}
+	switch e.Type {
+	case events.EventRideAccepted:
+		if r.State != events.StateRequested {
+			return nil
+		}
+		r.State, r.DriverID = events.StateAccepted, e.DriverID
+		if s.rand.Float64() < r.Rider.Profile.CancelRate {
+			r.CancelAt = now.Add(time.Duration(s.rand.Int64N(int64(r.Rider.Profile.Patience))))
+		}
+	case events.EventLocationUpdated:
+		p, ok := events.PayloadAs[events.LocationUpdatedPayload](e)
+		if !ok || r.State != events.StateAccepted || p.SpeedKPH <= 0 {
+			return nil
+		}
+		// The rider watches the driver come, and gives up on one too far away
+		eta := time.Duration(p.Location.DistanceKM(r.Route[0]) / p.SpeedKPH * float64(time.Hour))
+		if eta > r.Rider.Profile.MaxWait {
+			return s.cancel(r, now, events.ReasonDriverTooFar)
+		}
+		return nil
+	case events.EventTripStarted:
+		if r.State != events.StateAccepted {
+			return nil
+		}
+		r.State = events.StateInProgress
+	case events.EventTripCompleted:
+		s.end(r)
+		return s.rate(r, e, now)
+	case events.EventTripCancelled:
+		s.end(r)
+		return nil
+	default:
+		return nil
+	}
+	r.UpdatedAt = e.OccurredAt
+	r.LastEventID = e.ID
+	return nil
+}
+
+// cancel sends the rider's cancellation of r and ends it. s.mu is held.
+func (s *Sim) cancel(r *ride, now time.Time, reason events.CancelReason) error {
+	slog.Info("Rider cancelled", "tripID", r.TripID, "profile", r.Rider.Profile.Name, "reason", reason)
+	s.end(r)
+	return s.publish(r, events.NewTripCancelled(r.TripID, events.ActorPassenger, reason, s.options(r, now)...))
+}
+
+// rate sends the rider's rating of a completed ride, and sometimes a tip.
+// s.mu is held.
+func (s *Sim) rate(r *ride, completed events.RideEvent, now time.Time) error {
+	r.LastEventID = completed.ID
+	// Most rides get five stars, and one in five fewer
+	stars := 5
+	if s.rand.Float64() < 0.2 {
+		stars -= 1 + s.rand.IntN(4)
+	}
+	rating := events.NewRideRated(r.TripID, events.RatingPayload{RatedBy: string(events.ActorPassenger), Stars: stars},
+		s.options(r, now)...)
+	errs := []error{s.publish(r, rating)}
+
+	p, ok := events.PayloadAs[events.RideCompletedPayload](completed)
+	if ok && r.DriverID != "" && s.rand.Float64() < r.Rider.Profile.TipRate {
+		if tip := p.Fare.Mul(r.Rider.Profile.TipShare); tip.Amount > 0 {
+			errs = append(errs, s.publish(r, events.NewTipAdded(r.TripID, r.DriverID, tip, s.options(r, now)...)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// end forgets r and frees its rider. s.mu is held.
+func (s *Sim) end(r *ride) {
+	r.Rider.Riding = false
+	delete(s.rides, r.TripID)
+}
+
+// options returns the options of an event of r at now, caused by its last
+// event.
+func (s *Sim) options(r *ride, now time.Time) []events.Option {
+	return []events.Option{
+		events.WithTime(now),
+		events.WithDriver(r.DriverID),
+		events.WithPassenger(r.Rider.ID),
+		events.WithCity(r.Rider.City),
+		events.WithMeta(events.Meta{CorrelationID: r.TripID, CausationID: r.LastEventID, ProducerInstance: s.instance}),
+	}
+}
+
+// publish sends an event of r, which becomes the cause of the next.
+func (s *Sim) publish(r *ride, e events.RideEvent) error {
+	r.LastEventID = e.ID
+	return ridesim.PublishRide(s.sink, topics.RideEvents, e)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/ridesim"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newSim returns one rider with the given profile, and the ride they requested.
+func newSim(t *testing.T, p Profile) (*Sim, *ride, *ridetest.Broker) {
+	t.Helper()
+	broker := ridetest.NewBroker()
+	s := NewSim(1, nil, broker, "ridersim-1", rand.New(rand.NewPCG(1, 1)))
+	s.riders[0].Profile = p
+	if ok, err := s.Request(now); !ok || err != nil {
+		t.Fatalf("Request = %v, %v", ok, err)
+	}
+	if ok, _ := s.Request(now); ok {
+		t.Fatal("a rider requested a second ride")
+	}
+	for _, r := range s.rides {
+		return s, r, broker
+	}
+	t.Fatal("no ride open after the request")
+	return nil, nil, nil
+}
+
+// deliver hands s the event as if it were read from the ride topic.
+func deliver(t *testing.T, s *Sim, e events.RideEvent, at time.Time) {
+	t.Helper()
+	if err := s.Handle(ridetest.Message(t, topics.RideEvents, e), at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sent returns the types of the events the riders sent.
+func sent(t *testing.T, broker *ridetest.Broker) []events.RideEventType {
+	t.Helper()
+	evts, err := broker.Events(topics.RideEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []events.RideEventType
+	for i, e := range evts {
+		if err := e.Validate(); err != nil {
+			t.Errorf("event %d: %v", i, err)
+		}
+		if i > 0 && e.Meta.CausationID == "" {
+			t.Errorf("event %d has no cause", i)
+		}
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+var loyal = Profile{Name: "loyal", Patience: time.Minute, MaxWait: 10 * time.Minute, TipRate: 1, TipShare: 0.2}
+
+func TestSim_RatesAndTipsCompletedRide(t *testing.T) {
+	s, r, broker := newSim(t, loyal)
+	opts := []events.Option{events.WithTime(now), events.WithDriver("driver-1")}
+	deliver(t, s, events.NewRideAccepted(r.TripID, "driver-1", opts...), now)
+	deliver(t, s, events.NewTripStarted(r.TripID, opts...), now)
+	deliver(t, s, events.NewTripCompleted(r.TripID, 5, ridesim.Fare(5, 1), opts...), now)
+
+	got := sent(t, broker)
+	want := []events.RideEventType{events.EventRideRequested, events.EventRideRated, events.EventTipAdded}
+	if len(got) != len(want) || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("sent %v, want %v", got, want)
+	}
+	evts, _ := broker.Events(topics.RideEvents)
+	if p, _ := events.PayloadAs[events.TipPayload](evts[2]); p.DriverID != "driver-1" || p.Amount != events.NewMoney(150, events.USD) {
+		t.Errorf("tip: got %+v, want 20%% of $7.50", p)
+	}
+	if s.Active() != 0 || s.riders[0].Riding {
+		t.Error("the completed ride is still open")
+	}
+}
+
+func TestSim_Cancels(t *testing.T) {
+	// Nobody takes the request within the rider's patience
+	s, r, broker := newSim(t, loyal)
+	if err := s.Tick(now.Add(30 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if s.Active() != 1 {
+		t.Fatal("rider cancelled before running out of patience")
+	}
+	if err := s.Tick(now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent(t, broker); len(got) != 2 || got[1] != events.EventTripCancelled || s.Active() != 0 {
+		t.Fatalf("sent %v, want the request cancelled", got)
+	}
+
+	// The driver's location puts them too far away: 10km at 30 km/h is 20 minutes
+	s, r, broker = newSim(t, loyal)
+	deliver(t, s, events.NewRideAccepted(r.TripID, "driver-1", events.WithTime(now)), now)
+	near := events.Coordinate{Lat: r.Route[0].Lat + 0.01, Lng: r.Route[0].Lng}
+	deliver(t, s, events.NewLocationUpdated(r.TripID, events.StateAccepted, near, 0, 30, events.WithTime(now)), now)
+	if s.Active() != 1 {
+		t.Fatal("rider cancelled on a driver 1km away")
+	}
+	far := events.Coordinate{Lat: r.Route[0].Lat + 0.09, Lng: r.Route[0].Lng}
+	deliver(t, s, events.NewLocationUpdated(r.TripID, events.StateAccepted, far, 0, 30, events.WithTime(now)), now)
+	evts, _ := broker.Events(topics.RideEvents)
+	if last := evts[len(evts)-1]; last.Type != events.EventTripCancelled {
+		t.Fatalf("last sent %s, want a cancellation", last.Type)
+	} else if p, _ := events.PayloadAs[events.RideCancelledPayload](last); p.CancelledBy != events.ActorPassenger || p.Reason != events.ReasonDriverTooFar {
+		t.Errorf("cancellation: got %+v", p)
+	}
+
+	// A rider who changes their plans cancels before pickup
+	fickle := loyal
+	fickle.CancelRate = 1
+	s, r, broker = newSim(t, fickle)
+	deliver(t, s, events.NewRideAccepted(r.TripID, "driver-1", events.WithTime(now)), now)
+	if err := s.Tick(now.Add(fickle.Patience)); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent(t, broker); len(got) != 2 || got[1] != events.EventTripCancelled {
+		t.Errorf("sent %v, want the accepted ride cancelled", got)
+	}
+}
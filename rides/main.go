@@ -0,0 +1,232 @@
+// Command rides runs one-off operations against the rides database:
+//
+//	rides export -table trips|ride_events -from T -to T [-format csv|parquet] [-o FILE]
+//	rides rebuild [-tables rides,trips,ride_event_windows] [-from-kafka] [-brokers B] [-topic T]
+//	rides reconcile [-file FILE | -brokers B -topic T] [-from T] [-to T] [-max-issues N] [-json]
+//	rides schemas [-out DIR]
+//	rides registry list|versions|show|check|register|compatibility [-url U] ...
+//
+// Export, rebuild, and reconcile read the same environment as the services
+// (see rides_db.OpenFromEnv).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/export"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/rebuild"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+const usage = `usage: rides <command> [flags]
+
+commands:
+  export     write trips or ride_events for a time range to CSV or Parquet
+  rebuild    regenerate rides, trips, and window aggregates from ride_events
+  reconcile  compare the ride events on the topic, or in a file, with ride_events
+  schemas    write JSON Schema documents for ride events and their payloads
+  registry   list the Schema Registry's subjects, and check and register new versions
+`
+
+func main() {
+	// Logs go to stderr so that an export can be written to stdout
+	logger.Logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	slog.SetDefault(logger.Logger)
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := godotenv.Load(); err != nil {
+		slog.Debug("No .env file found, using the environment", "error", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(ctx, os.Args[2:])
+	case "rebuild":
+		err = runRebuild(ctx, os.Args[2:])
+	case "reconcile":
+		err = runReconcile(ctx, os.Args[2:])
+	case "schemas":
+		err = runSchemas(os.Args[2:])
+	case "registry":
+		err = runRegistry(ctx, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Fatal("rides "+os.Args[1]+" failed", "error", err)
+	}
+}
+
+// runExport streams a table to a file, or to stdout when -o is not given.
+func runExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	table := fs.String("table", string(export.TableTrips), "table to export: trips or ride_events")
+	format := fs.String("format", string(export.FormatCSV), "output format: csv or parquet")
+	from := fs.String("from", "", "start of the time range, inclusive (RFC 3339 or YYYY-MM-DD)")
+	to := fs.String("to", "", "end of the time range, exclusive (RFC 3339 or YYYY-MM-DD; default now)")
+	out := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+
+	if *from == "" {
+		return errors.New("-from is required")
+	}
+	tr, err := parseRange(*from, *to)
+	if err != nil {
+		return err
+	}
+	cols, err := export.Columns(export.Table(*table))
+	if err != nil {
+		return err
+	}
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var dst io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dst = f
+	}
+	w, err := export.NewWriter(dst, export.Format(*format), cols)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	n, err := export.Run(ctx, store, export.Table(*table), tr, w)
+	if err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	slog.Info("Export finished", "table", *table, "format", *format, "rows", n, "from", tr.From, "to", tr.To, "took", time.Since(start))
+	return nil
+}
+
+// runRebuild regenerates the derived tables from ride_events, first replaying
+// the ride events topic into ride_events when -from-kafka is set. Stop the
+// consumer while it runs.
+func runRebuild(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	tables := fs.String("tables", "", "comma-separated tables to rebuild: rides, trips, ride_event_windows (default all)")
+	fromKafka := fs.Bool("from-kafka", false, "replay the topic into ride_events before rebuilding")
+	brokers := fs.String("brokers", "redpanda:9092", "Kafka brokers to replay from")
+	topic := fs.String("topic", "ride-events", "topic to replay")
+	fs.Parse(args)
+
+	var cfg rebuild.Config
+	if *tables != "" {
+		for _, t := range strings.Split(*tables, ",") {
+			cfg.Tables = append(cfg.Tables, rides_db.DerivedTable(strings.TrimSpace(t)))
+		}
+	}
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if *fromKafka {
+		src, err := openKafkaReplay(*brokers, *topic, time.Time{})
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		report, err := rebuild.Backfill(ctx, store, src)
+		if err != nil {
+			return err
+		}
+		slog.Info("Replayed topic into ride_events", "topic", *topic, "read", report.Read,
+			"inserted", report.Inserted, "surges", report.Surges, "skipped", report.Skipped)
+	}
+
+	report, err := rebuild.Run(ctx, store, cfg)
+	if err != nil {
+		return err
+	}
+	slog.Info("Rebuild finished", "events", report.Events, "trips", report.Trips, "windows", report.Windows, "took", report.Took)
+	return nil
+}
+
+// runSchemas writes the JSON Schema documents generated from the events types,
+// replacing any already in the directory.
+func runSchemas(args []string) error {
+	fs := flag.NewFlagSet("schemas", flag.ExitOnError)
+	out := fs.String("out", "schemas", "directory to write the documents to")
+	fs.Parse(args)
+
+	docs, err := events.GenerateJSONSchemas()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	for name, doc := range docs {
+		if err := os.WriteFile(filepath.Join(*out, name), doc, 0o644); err != nil {
+			return err
+		}
+	}
+	slog.Info("Wrote JSON Schemas", "dir", *out, "documents", len(docs))
+	return nil
+}
+
+// parseRange parses the -from and -to flags; an empty to means now.
+func parseRange(from, to string) (rides_db.TimeRange, error) {
+	var (
+		tr  rides_db.TimeRange
+		err error
+	)
+	if tr.From, err = parseTime(from); err != nil {
+		return tr, fmt.Errorf("-from: %w", err)
+	}
+	tr.To = time.Now()
+	if to != "" {
+		if tr.To, err = parseTime(to); err != nil {
+			return tr, fmt.Errorf("-to: %w", err)
+		}
+	}
+	if !tr.To.After(tr.From) {
+		return tr, errors.New("-to must be after -from")
+	}
+	return tr, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
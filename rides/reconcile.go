@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/reconcile"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// runReconcile compares the ride events on the topic, or in -file, with
+// ride_events, prints the report to stdout, and fails if any event is missing
+// or was stored with other content.
+func runReconcile(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	file := fs.String("file", "", "read events from this file, one JSON event a line, instead of the topic")
+	brokers := fs.String("brokers", "redpanda:9092", "Kafka brokers to read from")
+	topic := fs.String("topic", "ride-events", "topic to read")
+	from := fs.String("from", "", "start of the event-time range, inclusive (RFC 3339 or YYYY-MM-DD; default the earliest read)")
+	to := fs.String("to", "", "end of the event-time range, exclusive (RFC 3339 or YYYY-MM-DD; default the latest read)")
+	maxIssues := fs.Int("max-issues", reconcile.DefaultMaxIssues, "issues of each kind to list")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	cfg := reconcile.Config{MaxIssues: *maxIssues}
+	var err error
+	if *from != "" {
+		if cfg.Range.From, err = parseTime(*from); err != nil {
+			return fmt.Errorf("-from: %w", err)
+		}
+	}
+	if *to != "" {
+		if cfg.Range.To, err = parseTime(*to); err != nil {
+			return fmt.Errorf("-to: %w", err)
+		}
+	}
+	if !cfg.Range.From.IsZero() && !cfg.Range.To.IsZero() && !cfg.Range.To.After(cfg.Range.From) {
+		return errors.New("-to must be after -from")
+	}
+
+	var src reconcile.Source
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = reconcile.NewFileSource(f, filepath.Base(*file))
+	} else {
+		// Events are produced after they occur, so reading from the first
+		// message produced at -from misses none within the range
+		replay, err := openKafkaReplay(*brokers, *topic, cfg.Range.From)
+		if err != nil {
+			return err
+		}
+		defer replay.Close()
+		src = kafkaRecords{replay}
+	}
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	start := time.Now()
+	report, err := reconcile.Run(ctx, store, src, cfg)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Reconciliation finished", "read", report.Read, "missing", report.Missing,
+		"duplicated", report.Duplicated, "mutated", report.Mutated, "took", time.Since(start))
+	if !report.OK() {
+		return fmt.Errorf("%d events missing and %d mutated", report.Missing, report.Mutated)
+	}
+	return nil
+}
+
+// kafkaRecords reads a replay as reconcile records, placed by partition and
+// offset.
+type kafkaRecords struct {
+	replay *kafkaReplay
+}
+
+func (r kafkaRecords) Next(ctx context.Context) (reconcile.Record, error) {
+	e, tp, err := r.replay.next(ctx)
+	if err != nil {
+		return reconcile.Record{}, err
+	}
+	return reconcile.Record{Event: e, Position: fmt.Sprintf("%s/%d@%d", *tp.Topic, tp.Partition, tp.Offset)}, nil
+}
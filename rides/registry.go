@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	sr "github.com/confluentinc/confluent-kafka-go/schemaregistry"
+
+	"github.com/pedeveaux/kafkarideshare/schemaregistry"
+)
+
+const registryUsage = `usage: rides registry <command> [flags] [args]
+
+commands:
+  list                            list the subjects with their latest version and compatibility level
+  versions SUBJECT                list the versions of a subject
+  show SUBJECT [VERSION]          print a version of a subject's schema (default the latest)
+  check -subject S [-file F]      check a schema against the subject's compatibility level
+  register -subject S [-file F]   register a schema as the subject's next version, if compatible
+  compatibility SUBJECT [LEVEL]   print or set a subject's compatibility level
+
+check and register take the ride event Avro schema unless -file names another.
+Every command takes -url, which defaults to SCHEMA_REGISTRY_URL.
+`
+
+// runRegistry lists and evolves the subjects of the Schema Registry.
+func runRegistry(_ context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, registryUsage)
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("registry "+args[0], flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, registryUsage) }
+	url := fs.String("url", "", "Schema Registry URL (default SCHEMA_REGISTRY_URL)")
+	subject := fs.String("subject", "", "subject to check or register under, such as ride-events-value")
+	file := fs.String("file", "", "file holding the schema (default the ride event Avro schema)")
+	typ := fs.String("type", "avro", "type of the schema in -file: avro, protobuf, or json")
+	level := fs.String("compatibility", "", "compatibility level to set on the subject before registering")
+	fs.Parse(args[1:])
+
+	cfg, err := schemaregistry.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	if *url != "" {
+		cfg.URL = *url
+	}
+	if *level != "" {
+		if cfg.Compatibility, err = schemaregistry.ParseCompatibility(*level); err != nil {
+			return err
+		}
+	}
+	client, err := schemaregistry.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		return listSubjects(client)
+	case "versions":
+		if fs.NArg() != 1 {
+			return errors.New("versions takes a subject")
+		}
+		versions, err := client.GetAllVersions(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		for _, v := range versions {
+			fmt.Println(v)
+		}
+		return nil
+	case "show":
+		if fs.NArg() < 1 || fs.NArg() > 2 {
+			return errors.New("show takes a subject and an optional version")
+		}
+		var s sr.SchemaMetadata
+		if fs.NArg() == 2 {
+			version, err := strconv.Atoi(fs.Arg(1))
+			if err != nil || version < 1 {
+				return fmt.Errorf("invalid version %q", fs.Arg(1))
+			}
+			s, err = client.GetSchemaMetadata(fs.Arg(0), version)
+		} else {
+			s, err = client.GetLatestSchemaMetadata(fs.Arg(0))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s version %d, ID %d, %s\n", fs.Arg(0), s.Version, s.ID, schemaType(s.SchemaInfo))
+		fmt.Println(s.Schema)
+		return nil
+	case "check", "register":
+		if *subject == "" {
+			return errors.New("-subject is required")
+		}
+		schema, err := readSchema(*file, *typ)
+		if err != nil {
+			return err
+		}
+		if args[0] == "register" {
+			id, err := client.RegisterCompatible(*subject, schema)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Registered %s with ID %d\n", *subject, id)
+			return nil
+		}
+		ok, err := client.CheckCompatibility(*subject, schema)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("the schema is not compatible with %s", *subject)
+		}
+		fmt.Printf("The schema is compatible with %s\n", *subject)
+		return nil
+	case "compatibility":
+		switch fs.NArg() {
+		case 1:
+			current, err := client.SubjectCompatibility(fs.Arg(0))
+			if err != nil {
+				return err
+			}
+			fmt.Println(current)
+			return nil
+		case 2:
+			next, err := schemaregistry.ParseCompatibility(fs.Arg(1))
+			if err != nil {
+				return err
+			}
+			_, err = client.UpdateCompatibility(fs.Arg(0), next)
+			return err
+		}
+		return errors.New("compatibility takes a subject and an optional level")
+	}
+	fmt.Fprint(os.Stderr, registryUsage)
+	os.Exit(2)
+	return nil
+}
+
+// listSubjects prints each subject with its latest version, that version's
+// ID and type, and the subject's compatibility level.
+func listSubjects(client *schemaregistry.Client) error {
+	subjects, err := client.GetAllSubjects()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SUBJECT\tVERSION\tID\tTYPE\tCOMPATIBILITY")
+	for _, subject := range subjects {
+		latest, err := client.GetLatestSchemaMetadata(subject)
+		if err != nil {
+			return fmt.Errorf("%s: %w", subject, err)
+		}
+		level, err := client.SubjectCompatibility(subject)
+		if err != nil {
+			return fmt.Errorf("%s: %w", subject, err)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", subject, latest.Version, latest.ID, schemaType(latest.SchemaInfo), level)
+	}
+	return w.Flush()
+}
+
+// readSchema reads the schema in file, of type typ, or returns the ride
+// event Avro schema when file is empty.
+func readSchema(file, typ string) (sr.SchemaInfo, error) {
+	if file == "" {
+		return schemaregistry.RideEventSchema, nil
+	}
+	t, err := parseSchemaType(typ)
+	if err != nil {
+		return sr.SchemaInfo{}, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return sr.SchemaInfo{}, err
+	}
+	return sr.SchemaInfo{SchemaType: t, Schema: string(data)}, nil
+}
+
+// parseSchemaType reads a schema type, ignoring case, as the registry names
+// it; "jsonschema" and "proto" are accepted too. Avro, the registry's
+// default, is named by leaving the type out.
+func parseSchemaType(s string) (string, error) {
+	switch strings.ToUpper(s) {
+	case "AVRO":
+		return "", nil
+	case "PROTOBUF", "PROTO":
+		return "PROTOBUF", nil
+	case "JSON", "JSONSCHEMA":
+		return "JSON", nil
+	}
+	return "", fmt.Errorf("unknown schema type %q", s)
+}
+
+// schemaType returns the type of a schema read from the registry, which
+// leaves out Avro's.
+func schemaType(info sr.SchemaInfo) string {
+	if info.SchemaType == "" {
+		return "AVRO"
+	}
+	return info.SchemaType
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/replay"
+)
+
+// kafkaReplay reads the ride events of a topic from the start of every
+// partition, or from the first message at or after a time, up to the end it
+// had when the replay was opened; see replay.Reader.
+type kafkaReplay struct {
+	r *replay.Reader
+}
+
+// openKafkaReplay opens a replay of topic from the messages timestamped at or
+// after since, or from the start when since is zero.
+func openKafkaReplay(brokers, topic string, since time.Time) (*kafkaReplay, error) {
+	r, err := replay.Open(replay.Config{Brokers: brokers, Topic: topic, GroupID: "rides-rebuild", Since: since})
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaReplay{r: r}, nil
+}
+
+// Next returns the next decodable event, or io.EOF once every partition has
+// been read to its end.
+func (r *kafkaReplay) Next(ctx context.Context) (events.RideEvent, error) {
+	e, _, err := r.next(ctx)
+	return e, err
+}
+
+// next is Next, also returning where the event was read.
+func (r *kafkaReplay) next(ctx context.Context) (events.RideEvent, kafka.TopicPartition, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return events.RideEvent{}, kafka.TopicPartition{}, err
+		}
+		msg, err := r.r.ReadMessage(time.Second)
+		if replay.IsTimeout(err) {
+			continue
+		}
+		if err != nil {
+			return events.RideEvent{}, kafka.TopicPartition{}, err
+		}
+		var e events.RideEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable message", "partition", msg.TopicPartition.Partition, "offset", msg.TopicPartition.Offset, "error", err)
+			continue
+		}
+		return e, msg.TopicPartition, nil
+	}
+}
+
+func (r *kafkaReplay) Close() error {
+	return r.r.Close()
+}
@@ -0,0 +1,154 @@
+package rides_db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// DailyRevenue is the fare total of trips completed on one UTC day.
+type DailyRevenue struct {
+	Day        time.Time
+	Trips      int64
+	RevenueUSD float64
+}
+
+// HourlyCancellationRate compares trips requested in one hour with how many of
+// them ended cancelled.
+type HourlyCancellationRate struct {
+	Hour      time.Time
+	Requested int64
+	Cancelled int64
+	Rate      float64
+}
+
+// ZoneFare is the average fare of completed trips picked up in one zone.
+type ZoneFare struct {
+	Zone       string
+	Trips      int64
+	AvgFareUSD float64
+}
+
+// DriverTrips is a driver's completed trip count and fare total.
+type DriverTrips struct {
+	DriverID   string
+	Trips      int64
+	RevenueUSD float64
+}
+
+// SurgeRevenue is the fare total of completed trips requested under one surge
+// multiplier. Trips whose pickup zone had no surge in effect count as 1.
+type SurgeRevenue struct {
+	Multiplier float64
+	Trips      int64
+	RevenueUSD float64
+}
+
+// withQueryTimeout bounds ctx by the store's query timeout, if one is set.
+func (s *Store) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.queryTimeout)
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// RevenueByDay sums completed-trip fares per day, bucketed by completion time.
+func (s *Store) RevenueByDay(ctx context.Context, tr TimeRange) ([]DailyRevenue, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.readQueries(ctx).RevenueByDay(ctx, sqlcdb.RevenueByDayParams{From: nullTime(tr.From), To: nullTime(tr.To)})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []DailyRevenue
+	for _, row := range rows {
+		out = append(out, DailyRevenue{Day: row.Day, Trips: row.Trips, RevenueUSD: row.RevenueUsd})
+	}
+	return out, nil
+}
+
+// CancellationRateByHour reports, per hour of request time, the share of trips
+// that were cancelled.
+func (s *Store) CancellationRateByHour(ctx context.Context, tr TimeRange) ([]HourlyCancellationRate, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.readQueries(ctx).CancellationRateByHour(ctx, sqlcdb.CancellationRateByHourParams{From: nullTime(tr.From), To: nullTime(tr.To)})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []HourlyCancellationRate
+	for _, row := range rows {
+		r := HourlyCancellationRate{Hour: row.Hour, Requested: row.Requested, Cancelled: row.Cancelled}
+		if r.Requested > 0 {
+			r.Rate = float64(r.Cancelled) / float64(r.Requested)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// AvgFareByZone averages completed-trip fares by pickup location, highest first.
+func (s *Store) AvgFareByZone(ctx context.Context, tr TimeRange) ([]ZoneFare, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.readQueries(ctx).AvgFareByZone(ctx, sqlcdb.AvgFareByZoneParams{From: nullTime(tr.From), To: nullTime(tr.To)})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ZoneFare
+	for _, row := range rows {
+		out = append(out, ZoneFare{Zone: row.PickupLocation.String, Trips: row.Trips, AvgFareUSD: row.AvgFareUsd})
+	}
+	return s.cipher.decryptZones(ctx, out)
+}
+
+// TopDriversByTrips ranks drivers by completed trips, breaking ties on revenue.
+func (s *Store) TopDriversByTrips(ctx context.Context, tr TimeRange, limit int) ([]DriverTrips, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.readQueries(ctx).TopDriversByTrips(ctx, sqlcdb.TopDriversByTripsParams{
+		From:  nullTime(tr.From),
+		To:    nullTime(tr.To),
+		Limit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []DriverTrips
+	for _, row := range rows {
+		out = append(out, DriverTrips{DriverID: row.DriverID.String, Trips: row.Trips, RevenueUSD: row.RevenueUsd})
+	}
+	return out, nil
+}
+
+// RevenueBySurge sums completed-trip fares by the surge multiplier in effect in
+// the pickup zone when each trip was requested, bucketed by completion time.
+// Pickup locations must be stored in plaintext to match their zones.
+func (s *Store) RevenueBySurge(ctx context.Context, tr TimeRange) ([]SurgeRevenue, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.readQueries(ctx).RevenueBySurge(ctx, sqlcdb.RevenueBySurgeParams{From: nullTime(tr.From), To: nullTime(tr.To)})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []SurgeRevenue
+	for _, row := range rows {
+		out = append(out, SurgeRevenue{Multiplier: row.Multiplier, Trips: row.Trips, RevenueUSD: row.RevenueUsd})
+	}
+	return out, nil
+}
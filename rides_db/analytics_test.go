@@ -0,0 +1,78 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCancellationRateByHour(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	hour := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT date_trunc\\('hour', requested_at\\)").
+		WithArgs(nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"hour", "count", "count"}).
+			AddRow(hour, 8, 2).
+			AddRow(hour.Add(time.Hour), 0, 0))
+
+	rates, err := store.CancellationRateByHour(context.Background(), TimeRange{})
+	if err != nil {
+		t.Fatalf("CancellationRateByHour failed: %v", err)
+	}
+	if len(rates) != 2 || rates[0].Rate != 0.25 || rates[1].Rate != 0 {
+		t.Errorf("unexpected rates: %+v", rates)
+	}
+}
+
+func TestTopDriversByTrips(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	mock.ExpectQuery("SELECT driver_id").
+		WithArgs(from, to, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"driver_id", "count", "revenue"}).
+			AddRow("driver-1", 12, 140.5).
+			AddRow("driver-2", 9, 98.0))
+
+	drivers, err := store.TopDriversByTrips(context.Background(), TimeRange{From: from, To: to}, 3)
+	if err != nil {
+		t.Fatalf("TopDriversByTrips failed: %v", err)
+	}
+	if len(drivers) != 2 || drivers[0].DriverID != "driver-1" || drivers[0].Trips != 12 {
+		t.Errorf("unexpected drivers: %+v", drivers)
+	}
+}
+
+func TestRevenueByDay_QueryTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db, WithQueryTimeout(10*time.Millisecond))
+
+	mock.ExpectQuery("SELECT date_trunc\\('day', completed_at\\)").
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"day", "count", "sum"}))
+
+	if _, err := store.RevenueByDay(context.Background(), TimeRange{}); err == nil {
+		t.Error("expected the query to be cancelled by the query timeout")
+	}
+}
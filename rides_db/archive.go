@@ -0,0 +1,91 @@
+package rides_db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// ArchiveRideEvents moves up to limit ride_events older than before into
+// ride_events_archive, oldest first, and returns how many rows were moved. The
+// copy and the delete happen in one statement, so a row is never lost or
+// duplicated; call it repeatedly until it returns fewer than limit.
+func (s *Store) ArchiveRideEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return s.queries().ArchiveRideEvents(ctx, sqlcdb.ArchiveRideEventsParams{Before: before, Limit: int32(limit)})
+}
+
+// archiveSQL holds the two statements backends without data-modifying CTEs
+// use to move a batch inside a transaction.
+type archiveSQL struct {
+	copy   string // before, limit
+	delete string // before
+}
+
+// archiveRideEvents copies a batch into the archive and then deletes the rows
+// that made it there; q must be a transaction.
+func archiveRideEvents(ctx context.Context, q querier, stmts archiveSQL, before time.Time, limit int) (int64, error) {
+	if _, err := q.ExecContext(ctx, stmts.copy, before, limit); err != nil {
+		return 0, err
+	}
+	res, err := q.ExecContext(ctx, stmts.delete, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+var sqliteArchiveSQL = archiveSQL{
+	copy: `
+		INSERT OR IGNORE INTO ride_events_archive (` + rideEventColumnList + `)
+		SELECT ` + rideEventColumnList + ` FROM ride_events
+		WHERE event_time < $1
+		ORDER BY event_time
+		LIMIT $2
+	`,
+	delete: `
+		DELETE FROM ride_events
+		WHERE event_time < $1
+		  AND EXISTS (SELECT 1 FROM ride_events_archive a WHERE a.id = ride_events.id)
+	`,
+}
+
+var mysqlArchiveSQL = archiveSQL{
+	copy: `
+		INSERT INTO ride_events_archive (` + rideEventColumnList + `)
+		SELECT ` + rideEventColumnList + ` FROM ride_events
+		WHERE event_time < ?
+		ORDER BY event_time
+		LIMIT ?
+		ON DUPLICATE KEY UPDATE id = ride_events_archive.id
+	`,
+	delete: `
+		DELETE FROM ride_events
+		WHERE event_time < ?
+		  AND EXISTS (SELECT 1 FROM ride_events_archive a WHERE a.id = ride_events.id)
+	`,
+}
+
+// ArchiveRideEvents moves a batch of old events to the archive; see Store.ArchiveRideEvents.
+func (s *SQLiteStore) ArchiveRideEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var n int64
+	err := s.withTx(ctx, func(tx *SQLiteStore) error {
+		var err error
+		n, err = archiveRideEvents(ctx, tx.q, sqliteArchiveSQL, before.UTC(), limit)
+		return err
+	})
+	return n, err
+}
+
+// ArchiveRideEvents moves a batch of old events to the archive; see Store.ArchiveRideEvents.
+func (s *MySQLStore) ArchiveRideEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var n int64
+	err := s.retry(ctx, func() error {
+		return s.withTx(ctx, func(tx *MySQLStore) error {
+			var err error
+			n, err = archiveRideEvents(ctx, tx.q, mysqlArchiveSQL, before, limit)
+			return err
+		})
+	})
+	return n, err
+}
@@ -0,0 +1,35 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestArchiveRideEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	cutoff := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(`WITH moved AS \(\s+DELETE FROM ride_events .* INSERT INTO ride_events_archive`).
+		WithArgs(cutoff, 500).
+		WillReturnResult(sqlmock.NewResult(0, 42))
+
+	n, err := store.ArchiveRideEvents(context.Background(), cutoff, 500)
+	if err != nil {
+		t.Fatalf("ArchiveRideEvents failed: %v", err)
+	}
+	if n != 42 {
+		t.Errorf("expected 42 archived rows, got %d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// AuditKind names what an audit_log row records.
+type AuditKind string
+
+const (
+	// AuditBatchInsert records a batch of events the consumer persisted from one
+	// topic partition.
+	AuditBatchInsert AuditKind = "batch_insert"
+	// AuditMigration records a schema migration being applied.
+	AuditMigration AuditKind = "migration"
+	// AuditRebuild records the derived tables being rebuilt from ride_events.
+	AuditRebuild AuditKind = "rebuild"
+)
+
+// AuditEntry is one row of the append-only audit_log. Only the fields of its
+// Kind are stored; the others are left NULL.
+type AuditEntry struct {
+	Kind       AuditKind
+	Actor      string        // consumer group or process that made the change; defaults to this process
+	RecordedAt time.Time     // defaults to now
+	Duration   time.Duration // time spent writing the batch, applying the migration, or rebuilding
+
+	// AuditBatchInsert
+	Topic       string
+	Partition   int32
+	FirstOffset int64
+	LastOffset  int64
+	Rows        int64 // also the events replayed by AuditRebuild
+
+	// AuditMigration
+	MigrationVersion int
+	MigrationName    string
+}
+
+// AppendAudit adds e to the audit log. The table rejects updates and deletes, so
+// entries cannot be rewritten once recorded.
+func (s *Store) AppendAudit(ctx context.Context, e AuditEntry) error {
+	return s.queries().AppendAudit(ctx, auditParams(e))
+}
+
+// AppendAudit adds e to the audit log; see Store.AppendAudit.
+func (s *SQLiteStore) AppendAudit(ctx context.Context, e AuditEntry) error {
+	_, err := s.q.ExecContext(ctx, auditInsertSQL, auditArgs(e)...)
+	return err
+}
+
+// AppendAudit adds e to the audit log; see Store.AppendAudit.
+func (s *MySQLStore) AppendAudit(ctx context.Context, e AuditEntry) error {
+	_, err := s.q.ExecContext(ctx, mysqlAuditInsertSQL, auditArgs(e)...)
+	return err
+}
+
+// auditInsertSQL is AppendAudit in queries/audit.sql, for SQLite and for
+// recording migrations on the connection that applied them.
+const auditInsertSQL = `
+	INSERT INTO audit_log
+	(kind, actor, recorded_at, topic, partition, first_offset, last_offset, row_count,
+	 duration_seconds, migration_version, migration_name)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+`
+
+const mysqlAuditInsertSQL = `
+	INSERT INTO audit_log
+	(kind, actor, recorded_at, topic, ` + mysqlPartition + `, first_offset, last_offset, row_count,
+	 duration_seconds, migration_version, migration_name)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+// auditParams returns the audit_log row for e.
+func auditParams(e AuditEntry) sqlcdb.AppendAuditParams {
+	if e.RecordedAt.IsZero() {
+		e.RecordedAt = time.Now()
+	}
+	if e.Actor == "" {
+		e.Actor = processActor()
+	}
+	p := sqlcdb.AppendAuditParams{
+		Kind:            string(e.Kind),
+		Actor:           e.Actor,
+		RecordedAt:      e.RecordedAt.UTC(),
+		DurationSeconds: sql.NullFloat64{Float64: e.Duration.Seconds(), Valid: true},
+	}
+	switch e.Kind {
+	case AuditBatchInsert:
+		p.Topic = nullString(e.Topic)
+		p.Partition = sql.NullInt32{Int32: e.Partition, Valid: true}
+		p.FirstOffset = sql.NullInt64{Int64: e.FirstOffset, Valid: true}
+		p.LastOffset = sql.NullInt64{Int64: e.LastOffset, Valid: true}
+		p.RowCount = sql.NullInt64{Int64: e.Rows, Valid: true}
+	case AuditRebuild:
+		p.RowCount = sql.NullInt64{Int64: e.Rows, Valid: true}
+	case AuditMigration:
+		p.MigrationVersion = sql.NullInt32{Int32: int32(e.MigrationVersion), Valid: true}
+		p.MigrationName = nullString(e.MigrationName)
+	}
+	return p
+}
+
+// auditArgs returns the audit_log column values for e in insert order,
+// for the backends that do not use the generated queries.
+func auditArgs(e AuditEntry) []any {
+	p := auditParams(e)
+	return []any{
+		p.Kind, p.Actor, p.RecordedAt, p.Topic, p.Partition, p.FirstOffset, p.LastOffset, p.RowCount,
+		p.DurationSeconds, p.MigrationVersion, p.MigrationName,
+	}
+}
+
+// processActor names this process in audit entries it writes on its own
+// behalf, such as applied migrations.
+func processActor() string {
+	actor := filepath.Base(os.Args[0])
+	if host, err := os.Hostname(); err == nil {
+		actor += "@" + host
+	}
+	return actor
+}
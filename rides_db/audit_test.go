@@ -0,0 +1,73 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAppendAudit_BatchInsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("batch_insert", "ride-consumer-group", sqlmock.AnyArg(), "ride-events", int32(3), int64(100), int64(149),
+			int64(50), 1.5, nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = store.AppendAudit(context.Background(), AuditEntry{
+		Kind:        AuditBatchInsert,
+		Actor:       "ride-consumer-group",
+		Topic:       "ride-events",
+		Partition:   3,
+		FirstOffset: 100,
+		LastOffset:  149,
+		Rows:        50,
+		Duration:    1500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Errorf("AppendAudit failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLiteStore_AuditLog(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+
+	// Every migration applied by openTestSQLite is recorded
+	migrations, err := loadMigrations("migrations_sqlite")
+	if err != nil {
+		t.Fatalf("loadMigrations failed: %v", err)
+	}
+	var audited int
+	store.DB().QueryRow(`SELECT COUNT(*) FROM audit_log WHERE kind = 'migration'`).Scan(&audited)
+	if audited != len(migrations) {
+		t.Errorf("expected %d migration entries, got %d", len(migrations), audited)
+	}
+
+	err = store.AppendAudit(ctx, AuditEntry{Kind: AuditBatchInsert, Actor: "ride-consumer-group", Topic: "ride-events", FirstOffset: 7, LastOffset: 9, Rows: 3})
+	if err != nil {
+		t.Fatalf("AppendAudit failed: %v", err)
+	}
+	var rows, migrationVersion any
+	store.DB().QueryRow(`SELECT row_count, migration_version FROM audit_log WHERE kind = 'batch_insert'`).Scan(&rows, &migrationVersion)
+	if rows != int64(3) || migrationVersion != nil {
+		t.Errorf("unexpected batch entry: row_count=%v migration_version=%v", rows, migrationVersion)
+	}
+
+	for _, stmt := range []string{`UPDATE audit_log SET actor = 'someone-else'`, `DELETE FROM audit_log`} {
+		if _, err := store.DB().Exec(stmt); err == nil {
+			t.Errorf("expected %q to be rejected", stmt)
+		}
+	}
+}
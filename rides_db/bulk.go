@@ -0,0 +1,244 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// copyThreshold is the batch size from which InsertRideEvents switches from a
+// pipelined pgx.Batch to COPY into a staging table.
+const copyThreshold = 500
+
+var rideEventColumns = []string{
+	"id", "trip_id", "event_type", "event_state", "event_time", "driver_id", "passenger_id", "payload",
+	"distance_km", "fare_total", "cancelled_by", "pickup_location", "dropoff_location",
+}
+
+var rideEventColumnList = strings.Join(rideEventColumns, ", ")
+
+var errNotPgx = errors.New("rides_db: connection is not a pgx connection")
+
+// InsertRideEvents stores evts in one round trip and returns how many rows were
+// inserted; duplicates and conflicts are skipped exactly as in InsertRideEvent. Small batches are
+// pipelined with pgx.Batch and large ones are loaded with COPY through a staging table.
+func (s *Store) InsertRideEvents(ctx context.Context, evts []events.RideEvent) (inserted int64, err error) {
+	if len(evts) == 0 {
+		return 0, nil
+	}
+	defer func(start time.Time) { observeBatch(start, len(evts), inserted, err) }(time.Now())
+	evts, err = s.cipher.encryptEvents(ctx, evts)
+	if err != nil {
+		return 0, err
+	}
+	params := make([]sqlcdb.InsertRideEventParams, len(evts))
+	rows := make([][]any, len(evts))
+	for i, e := range evts {
+		if params[i], err = rideEventParams(e); err != nil {
+			return 0, err
+		}
+		rows[i] = rideEventParamArgs(params[i])
+	}
+
+	if s.tx != nil {
+		// Inside WithTx the rows must go through the open transaction
+		return execRideEvents(ctx, s.queries(), params)
+	}
+
+	// Retrying is safe because rows that made it in on an earlier attempt are
+	// skipped as duplicates; inserted then counts only the final attempt
+	err = s.retry(ctx, func() error {
+		return s.withPgxConn(ctx, func(conn *pgx.Conn) error {
+			var err error
+			if len(rows) >= copyThreshold {
+				inserted, err = copyRideEvents(ctx, conn, rows)
+			} else {
+				inserted, err = batchRideEvents(ctx, conn, rows)
+			}
+			return err
+		})
+	})
+	if errors.Is(err, errNotPgx) {
+		// Other database/sql drivers fall back to a plain transaction
+		return s.insertRideEventsTx(ctx, params)
+	}
+	return inserted, err
+}
+
+// rideEventParams returns the ride_events row for e. The typed columns are
+// copied out of the payload; the payload itself is stored whole.
+func rideEventParams(e events.RideEvent) (sqlcdb.InsertRideEventParams, error) {
+	payloadBytes, err := json.Marshal(e.Payload)
+	if err != nil {
+		return sqlcdb.InsertRideEventParams{}, err
+	}
+
+	var (
+		distance, fare  sql.NullFloat64
+		cancelledBy     sql.NullString
+		pickup, dropoff sql.NullString
+	)
+	switch p := e.Payload.(type) {
+	case events.RideCompletedPayload:
+		distance = sql.NullFloat64{Float64: p.DistanceKM, Valid: true}
+		fare = sql.NullFloat64{Float64: p.Fare.Float64(), Valid: true}
+	case events.RideCancelledPayload:
+		cancelledBy = nullString(string(p.CancelledBy))
+	case events.RideRequestedPayload:
+		pickup = nullString(p.PickupLocation)
+		dropoff = nullString(p.DropoffLocation)
+	case sealedRequest:
+		pickup = nullString(p.PickupLocation)
+		dropoff = nullString(p.DropoffLocation)
+	}
+
+	return sqlcdb.InsertRideEventParams{
+		ID:              e.ID,
+		TripID:          e.TripID,
+		EventType:       string(e.Type),
+		EventState:      string(e.State),
+		EventTime:       e.OccurredAt,
+		DriverID:        sql.NullString{String: e.DriverID, Valid: true},
+		PassengerID:     sql.NullString{String: e.PassengerID, Valid: true},
+		Payload:         payloadBytes,
+		DistanceKm:      distance,
+		FareTotal:       fare,
+		CancelledBy:     cancelledBy,
+		PickupLocation:  pickup,
+		DropoffLocation: dropoff,
+	}, nil
+}
+
+// rideEventArgs returns the column values of e in rideEventColumns order, for
+// COPY and the backends that do not use the generated queries.
+func rideEventArgs(e events.RideEvent) ([]any, error) {
+	p, err := rideEventParams(e)
+	if err != nil {
+		return nil, err
+	}
+	return rideEventParamArgs(p), nil
+}
+
+func rideEventParamArgs(p sqlcdb.InsertRideEventParams) []any {
+	return []any{
+		p.ID, p.TripID, p.EventType, p.EventState, p.EventTime, p.DriverID, p.PassengerID, []byte(p.Payload),
+		p.DistanceKm, p.FareTotal, p.CancelledBy, p.PickupLocation, p.DropoffLocation,
+	}
+}
+
+// withPgxConn runs fn on the native pgx connection behind a pooled database/sql connection.
+func (s *Store) withPgxConn(ctx context.Context, fn func(*pgx.Conn) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errNotPgx
+		}
+		return fn(c.Conn())
+	})
+}
+
+// batchRideEvents pipelines InsertRideEvent for each of rows and counts the
+// events it inserted.
+func batchRideEvents(ctx context.Context, conn *pgx.Conn, rows [][]any) (int64, error) {
+	batch := &pgx.Batch{}
+	for _, args := range rows {
+		batch.Queue(sqlcdb.InsertRideEventSQL, args...)
+	}
+
+	results := conn.SendBatch(ctx, batch)
+	defer results.Close()
+
+	var inserted int64
+	for range rows {
+		var outcome string
+		if err := results.QueryRow().Scan(&outcome); err != nil {
+			return inserted, err
+		}
+		if InsertOutcome(outcome) == OutcomeInserted {
+			inserted++
+		}
+	}
+	return inserted, results.Close()
+}
+
+// copyRideEvents loads rows into a transaction-scoped staging table with COPY and
+// moves the ones whose IDs it claims in the ledger into ride_events, since COPY
+// alone cannot skip duplicates or conflicting rows. Its SQL is written here
+// rather than in queries/ because the staging table is not in the schema sqlc
+// checks against.
+func copyRideEvents(ctx context.Context, conn *pgx.Conn, rows [][]any) (int64, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE ride_events_staging
+		(LIKE ride_events INCLUDING DEFAULTS) ON COMMIT DROP
+	`); err != nil {
+		return 0, err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"ride_events_staging"}, rideEventColumns, pgx.CopyFromRows(rows)); err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(ctx, `
+		WITH claimed AS (
+			INSERT INTO ride_event_ledger (event_id, trip_id, event_type, event_time)
+			SELECT id, trip_id, event_type, event_time FROM ride_events_staging
+			ON CONFLICT (event_id) DO NOTHING
+			RETURNING event_id
+		)
+		INSERT INTO ride_events (`+rideEventColumnList+`)
+		SELECT DISTINCT ON (id) `+rideEventColumnList+` FROM ride_events_staging
+		WHERE id IN (SELECT event_id FROM claimed)
+		ORDER BY id
+		ON CONFLICT (trip_id, event_type, event_time) DO NOTHING
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
+
+func (s *Store) insertRideEventsTx(ctx context.Context, params []sqlcdb.InsertRideEventParams) (int64, error) {
+	var inserted int64
+	err := s.retry(ctx, func() error {
+		return s.withTx(ctx, func(tx *Store) error {
+			var err error
+			inserted, err = execRideEvents(ctx, tx.queries(), params)
+			return err
+		})
+	})
+	return inserted, err
+}
+
+// execRideEvents inserts params one statement at a time on q.
+func execRideEvents(ctx context.Context, q *sqlcdb.Queries, params []sqlcdb.InsertRideEventParams) (int64, error) {
+	var inserted int64
+	for _, p := range params {
+		outcome, err := q.InsertRideEvent(ctx, p)
+		if err != nil {
+			return inserted, err
+		}
+		if InsertOutcome(outcome) == OutcomeInserted {
+			inserted++
+		}
+	}
+	return inserted, nil
+}
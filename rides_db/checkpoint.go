@@ -0,0 +1,49 @@
+package rides_db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// Checkpoint records the last Kafka offset a consumer group has persisted for a partition.
+type Checkpoint struct {
+	Group     string
+	Topic     string
+	Partition int32
+	Offset    int64
+	UpdatedAt time.Time
+}
+
+// UpdateCheckpoint upserts the processing position for a partition. Offsets only move
+// forward, so a redelivered older message never rewinds the checkpoint.
+func (s *Store) UpdateCheckpoint(ctx context.Context, cp Checkpoint) error {
+	return s.queries().UpsertCheckpoint(ctx, sqlcdb.UpsertCheckpointParams{
+		ConsumerGroup: cp.Group,
+		Topic:         cp.Topic,
+		Partition:     cp.Partition,
+		LastOffset:    cp.Offset,
+		UpdatedAt:     cp.UpdatedAt,
+	})
+}
+
+// GetCheckpoints returns every checkpoint recorded for a consumer group.
+func (s *Store) GetCheckpoints(ctx context.Context, group string) ([]Checkpoint, error) {
+	rows, err := s.queries().GetCheckpoints(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []Checkpoint
+	for _, row := range rows {
+		checkpoints = append(checkpoints, Checkpoint{
+			Group:     row.ConsumerGroup,
+			Topic:     row.Topic,
+			Partition: row.Partition,
+			Offset:    row.LastOffset,
+			UpdatedAt: row.UpdatedAt,
+		})
+	}
+	return checkpoints, nil
+}
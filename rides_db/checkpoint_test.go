@@ -0,0 +1,58 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpdateCheckpoint_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	cp := Checkpoint{Group: "ride-consumer-group", Topic: "ride-events", Partition: 2, Offset: 42, UpdatedAt: time.Now()}
+
+	mock.ExpectExec("INSERT INTO consumer_checkpoints").
+		WithArgs("ride-consumer-group", "ride-events", int32(2), int64(42), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.UpdateCheckpoint(context.Background(), cp); err != nil {
+		t.Errorf("UpdateCheckpoint failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetCheckpoints_ScansRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"consumer_group", "topic", "partition", "last_offset", "updated_at"}).
+		AddRow("ride-consumer-group", "ride-events", 0, 10, now).
+		AddRow("ride-consumer-group", "ride-events", 1, 7, now)
+	mock.ExpectQuery("SELECT (.+) FROM consumer_checkpoints").
+		WithArgs("ride-consumer-group").
+		WillReturnRows(rows)
+
+	got, err := store.GetCheckpoints(context.Background(), "ride-consumer-group")
+	if err != nil {
+		t.Fatalf("GetCheckpoints failed: %v", err)
+	}
+	if len(got) != 2 || got[1].Partition != 1 || got[1].Offset != 7 {
+		t.Errorf("unexpected checkpoints: %+v", got)
+	}
+}
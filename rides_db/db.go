@@ -1,24 +1,161 @@
 package rides_db
 
 import (
-    "database/sql"
-    _ "github.com/lib/pq"
-    "log"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
 )
 
-var DB *sql.DB
+// ErrNotFound is returned by lookups that match no rows.
+var ErrNotFound = errors.New("rides_db: not found")
+
+// RideStore is the persistence API used by the consumer and other services.
+type RideStore interface {
+	InsertRideEvent(ctx context.Context, e events.RideEvent) (InsertOutcome, error)
+	InsertRideEvents(ctx context.Context, evts []events.RideEvent) (int64, error)
+	UpsertRideState(ctx context.Context, e events.RideEvent) error
+	InsertTrip(ctx context.Context, t aggregation.Trip) error
+	RefreshTrip(ctx context.Context, tripID string) error
+	GetTrip(ctx context.Context, tripID string) (aggregation.Trip, error)
+	ListTrips(ctx context.Context, f TripFilter) ([]aggregation.Trip, error)
+	ExportTrips(ctx context.Context, tr TimeRange, fn func(aggregation.Trip) error) error
+	GetTripEvents(ctx context.Context, tripID string) ([]events.RideEvent, error)
+	ExportRideEvents(ctx context.Context, tr TimeRange, fn func(events.RideEvent) error) error
+	GetRide(ctx context.Context, tripID string) (Ride, error)
+	ListActiveRides(ctx context.Context, limit, offset int) ([]Ride, error)
+	ListRidesByDriver(ctx context.Context, driverID string, tr TimeRange) ([]Ride, error)
+	RidesWithinRadius(ctx context.Context, lat, lng, meters float64) ([]Ride, error)
+	RevenueByDay(ctx context.Context, tr TimeRange) ([]DailyRevenue, error)
+	CancellationRateByHour(ctx context.Context, tr TimeRange) ([]HourlyCancellationRate, error)
+	AvgFareByZone(ctx context.Context, tr TimeRange) ([]ZoneFare, error)
+	TopDriversByTrips(ctx context.Context, tr TimeRange, limit int) ([]DriverTrips, error)
+	RevenueBySurge(ctx context.Context, tr TimeRange) ([]SurgeRevenue, error)
+	UpsertEventWindow(ctx context.Context, w aggregation.WindowResult) error
+	ListEventWindows(ctx context.Context, tr TimeRange) ([]aggregation.WindowResult, error)
+	UpsertZone(ctx context.Context, z Zone) error
+	UpsertSurgeMultiplier(ctx context.Context, m SurgeMultiplier) error
+	UpdateCheckpoint(ctx context.Context, cp Checkpoint) error
+	GetCheckpoints(ctx context.Context, group string) ([]Checkpoint, error)
+	EnqueueOutbox(ctx context.Context, m OutboxMessage) error
+	ProcessOutbox(ctx context.Context, limit int, publish func(OutboxMessage) error) (int, error)
+	ArchiveRideEvents(ctx context.Context, before time.Time, limit int) (int64, error)
+	ResetDerived(ctx context.Context, tables ...DerivedTable) error
+	AppendAudit(ctx context.Context, e AuditEntry) error
+	InsertFraudAlert(ctx context.Context, a fraud.Alert) error
+	ListFraudAlerts(ctx context.Context, limit int) ([]fraud.Alert, error)
+	UpsertHeatmapCell(ctx context.Context, c HeatmapCell) error
+	ListHeatmapCells(ctx context.Context, tr TimeRange) ([]HeatmapCell, error)
+	UpsertNotificationDelivery(ctx context.Context, d NotificationDelivery) error
+	ListNotificationDeliveries(ctx context.Context, tripID string) ([]NotificationDelivery, error)
+	WithTx(ctx context.Context, fn func(tx RideStore) error) error
+	Migrate(ctx context.Context) error
+	Health(ctx context.Context) error
+	Close() error
+}
+
+// Store is the PostgreSQL implementation of RideStore.
+type Store struct {
+	db           *sql.DB
+	q            querier
+	tx           *sql.Tx // set on stores handed out by WithTx
+	queryTimeout time.Duration
+	retryPolicy  retryPolicy
+	cipher       *fieldCipher // nil unless WithFieldEncryption was given
+	slowQuery    time.Duration
+	replicas     *replicaSet // nil unless WithReplicas was given
+	schema       string      // empty unless WithCity or WithSchema was given
+}
+
+var _ RideStore = (*Store)(nil)
+
+// New wraps an existing database handle, applying any pool options given.
+func New(db *sql.DB, opts ...Option) *Store {
+	o := buildOptions(opts)
+	if len(opts) > 0 {
+		o.apply(db)
+	}
+	return newStore(db, o)
+}
+
+// Open connects to PostgreSQL using connStr, configures the pool, and verifies
+// the connection within the connect timeout.
+func Open(connStr string, opts ...Option) (*Store, error) {
+	o := buildOptions(opts)
+	schema, err := storeSchema(o)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openPostgres(connStr, schema)
+	if err != nil {
+		return nil, err
+	}
+	o.apply(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.ConnectTimeout)
+	defer cancel()
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Println("✅ Connected to PostgreSQL")
+	s := newStore(db, o)
+	s.schema = schema
+
+	// Replicas are not pinged: one that is down is skipped until it recovers
+	var replicas []*sql.DB
+	for _, dsn := range o.ReplicaDSNs {
+		replica, err := openPostgres(dsn, schema)
+		if err != nil {
+			db.Close()
+			for _, r := range replicas {
+				r.Close()
+			}
+			return nil, err
+		}
+		o.apply(replica)
+		replicas = append(replicas, replica)
+	}
+	s.replicas = newReplicaSet(replicas, o)
+	return s, nil
+}
+
+func newStore(db *sql.DB, o Options) *Store {
+	policy := o.retryPolicy()
+	return &Store{
+		db:           db,
+		q:            withSlowQueryLog(retryQuerier{db: db, policy: policy}, o.SlowQueryThreshold),
+		queryTimeout: o.QueryTimeout,
+		retryPolicy:  policy,
+		cipher:       newFieldCipher(o.FieldKeys),
+		slowQuery:    o.SlowQueryThreshold,
+	}
+}
 
-func Init(connStr string) error {
-    var err error
-    DB, err = sql.Open("postgres", connStr)
-    if err != nil {
-        return err
-    }
+// queries returns the sqlc-generated queries, running on the store's querier so
+// retries, slow query logging, and an open transaction all apply.
+func (s *Store) queries() *sqlcdb.Queries {
+	return sqlcdb.New(s.q)
+}
 
-    if err = DB.Ping(); err != nil {
-        return err
-    }
+// DB returns the underlying database handle.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
 
-    log.Println("✅ Connected to PostgreSQL")
-    return nil
-}
\ No newline at end of file
+// Close closes the underlying database handles, waiting for in-flight queries to finish.
+func (s *Store) Close() error {
+	if s.replicas != nil {
+		s.replicas.close()
+	}
+	return s.db.Close()
+}
@@ -1,10 +1,45 @@
 package rides_db
 
-import "testing"
+import (
+	"testing"
+	"time"
 
-func TestInit_BadConnectionString(t *testing.T) {
-	err := Init("host=invalidhost user=bad password=bad dbname=none sslmode=disable")
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOpen_BadConnectionString(t *testing.T) {
+	_, err := Open("host=invalidhost user=bad password=bad dbname=none sslmode=disable")
 	if err == nil {
-		t.Error("Expected error from Init with bad connection string, got nil")
+		t.Error("Expected error from Open with bad connection string, got nil")
+	}
+}
+
+func TestNew_AppliesPoolOptions(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db, WithMaxOpenConns(3), WithConnMaxLifetime(time.Minute))
+	if got := store.DB().Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("expected max open connections 3, got %d", got)
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "25")
+	t.Setenv("DB_CONNECT_TIMEOUT", "2s")
+	t.Setenv("DB_MAX_IDLE_CONNS", "not-a-number")
+
+	o := buildOptions(OptionsFromEnv())
+	if o.MaxOpenConns != 25 {
+		t.Errorf("expected MaxOpenConns 25, got %d", o.MaxOpenConns)
+	}
+	if o.ConnectTimeout != 2*time.Second {
+		t.Errorf("expected ConnectTimeout 2s, got %v", o.ConnectTimeout)
+	}
+	if o.MaxIdleConns != DefaultOptions().MaxIdleConns {
+		t.Errorf("invalid value should keep default, got %d", o.MaxIdleConns)
 	}
 }
@@ -0,0 +1,60 @@
+package rides_db
+
+import (
+	"context"
+	"fmt"
+)
+
+// DerivedTable names a table whose rows are computed from ride_events and so
+// can be rebuilt from it.
+type DerivedTable string
+
+const (
+	DerivedRides   DerivedTable = "rides"
+	DerivedTrips   DerivedTable = "trips"
+	DerivedWindows DerivedTable = "ride_event_windows"
+)
+
+// DerivedTables lists every derived table.
+var DerivedTables = []DerivedTable{DerivedRides, DerivedTrips, DerivedWindows}
+
+// resetDerivedSQL deletes the rows of each derived table that ride_events can
+// reproduce. Rows built from events that have since been archived stay.
+var resetDerivedSQL = map[DerivedTable]string{
+	DerivedRides:   `DELETE FROM rides WHERE trip_id IN (SELECT trip_id FROM ride_events)`,
+	DerivedTrips:   `DELETE FROM trips WHERE trip_id IN (SELECT trip_id FROM ride_events)`,
+	DerivedWindows: `DELETE FROM ride_event_windows WHERE window_start >= (SELECT MIN(event_time) FROM ride_events)`,
+}
+
+// ResetDerived deletes the rows of tables that can be rebuilt from ride_events:
+// the rides and trips rows of every trip with stored events, and the windows
+// starting at or after the oldest stored event. Rows that only archived events
+// could reproduce are kept.
+func (s *Store) ResetDerived(ctx context.Context, tables ...DerivedTable) error {
+	return resetDerived(ctx, s.q, tables)
+}
+
+// ResetDerived deletes the rows of tables that can be rebuilt from ride_events;
+// see Store.ResetDerived.
+func (s *SQLiteStore) ResetDerived(ctx context.Context, tables ...DerivedTable) error {
+	return resetDerived(ctx, s.q, tables)
+}
+
+// ResetDerived deletes the rows of tables that can be rebuilt from ride_events;
+// see Store.ResetDerived.
+func (s *MySQLStore) ResetDerived(ctx context.Context, tables ...DerivedTable) error {
+	return resetDerived(ctx, s.q, tables)
+}
+
+func resetDerived(ctx context.Context, q querier, tables []DerivedTable) error {
+	for _, t := range tables {
+		query, ok := resetDerivedSQL[t]
+		if !ok {
+			return fmt.Errorf("rides_db: %q is not a derived table", t)
+		}
+		if _, err := q.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("reset %s: %w", t, err)
+		}
+	}
+	return nil
+}
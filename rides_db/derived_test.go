@@ -0,0 +1,66 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestSQLiteStore_ResetDerived(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	base := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	// trip-old's events were archived, trip-new's are still stored
+	archived := aggregation.Trip{TripID: "trip-old", FinalState: events.StateCompleted,
+		RequestedAt: base.Add(-time.Hour), CompletedAt: base.Add(-50 * time.Minute)}
+	if err := store.InsertTrip(ctx, archived); err != nil {
+		t.Fatalf("InsertTrip failed: %v", err)
+	}
+	for _, e := range completedTrip("trip-new", base) {
+		if _, err := store.InsertRideEvent(ctx, e); err != nil {
+			t.Fatalf("InsertRideEvent failed: %v", err)
+		}
+		if err := store.UpsertRideState(ctx, e); err != nil {
+			t.Fatalf("UpsertRideState failed: %v", err)
+		}
+	}
+	if err := store.InsertTrip(ctx, aggregation.Trip{TripID: "trip-new", FinalState: events.StateCompleted, RequestedAt: base}); err != nil {
+		t.Fatalf("InsertTrip failed: %v", err)
+	}
+	for _, start := range []time.Time{base.Add(-time.Hour), base} {
+		w := aggregation.WindowResult{Start: start, End: start.Add(time.Minute), EventType: events.EventRideRequested, Count: 1}
+		if err := store.UpsertEventWindow(ctx, w); err != nil {
+			t.Fatalf("UpsertEventWindow failed: %v", err)
+		}
+	}
+
+	if err := store.ResetDerived(ctx, DerivedTables...); err != nil {
+		t.Fatalf("ResetDerived failed: %v", err)
+	}
+
+	if _, err := store.GetTrip(ctx, "trip-old"); err != nil {
+		t.Errorf("expected the archived trip to be kept, got %v", err)
+	}
+	if _, err := store.GetTrip(ctx, "trip-new"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected trip-new's trip to be deleted, got %v", err)
+	}
+	if _, err := store.GetRide(ctx, "trip-new"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected trip-new's ride to be deleted, got %v", err)
+	}
+	var windows int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM ride_event_windows`).Scan(&windows); err != nil {
+		t.Fatalf("counting windows failed: %v", err)
+	}
+	if windows != 1 {
+		t.Errorf("expected only the window before the oldest event to be kept, got %d windows", windows)
+	}
+
+	if err := store.ResetDerived(ctx, "ride_events"); err == nil {
+		t.Error("expected an error resetting a table that is not derived")
+	}
+}
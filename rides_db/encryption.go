@@ -0,0 +1,403 @@
+package rides_db
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// encryptedPrefix marks an encrypted field value: enc:v1:<key id>:<base64 nonce+ciphertext>.
+const encryptedPrefix = "enc:v1:"
+
+// KeyProvider supplies AES-256 keys for field encryption. CurrentKey encrypts
+// new values; Key looks up the key a stored value was encrypted with, so keys
+// can be rotated without rewriting old rows. Implementations may call out to a KMS.
+type KeyProvider interface {
+	CurrentKey(ctx context.Context) (id string, key []byte, err error)
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider holding its keys in memory.
+type StaticKeys struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// CurrentKey returns the key new values are encrypted with.
+func (k *StaticKeys) CurrentKey(ctx context.Context) (string, []byte, error) {
+	key, err := k.Key(ctx, k.Current)
+	return k.Current, key, err
+}
+
+// Key returns the key with the given id.
+func (k *StaticKeys) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("rides_db: unknown encryption key %q", id)
+	}
+	return key, nil
+}
+
+// KeysFromEnv reads FIELD_ENCRYPTION_KEYS, a comma-separated list of
+// id:base64key pairs whose first entry encrypts new values. Each key must
+// decode to 32 bytes. It returns nil when the variable is unset.
+func KeysFromEnv() (*StaticKeys, error) {
+	raw := os.Getenv("FIELD_ENCRYPTION_KEYS")
+	if raw == "" {
+		return nil, nil
+	}
+	keys := &StaticKeys{Keys: make(map[string][]byte)}
+	for _, entry := range strings.Split(raw, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, errors.New("FIELD_ENCRYPTION_KEYS: entries must look like id:base64key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("FIELD_ENCRYPTION_KEYS: key %q: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("FIELD_ENCRYPTION_KEYS: key %q must be 32 bytes, got %d", id, len(key))
+		}
+		if keys.Current == "" {
+			keys.Current = id
+		}
+		keys.Keys[id] = key
+	}
+	return keys, nil
+}
+
+// failingKeys is installed when the configured keys are unusable, so writes
+// fail instead of silently storing plaintext.
+type failingKeys struct{ err error }
+
+func (k failingKeys) CurrentKey(context.Context) (string, []byte, error) { return "", nil, k.err }
+func (k failingKeys) Key(context.Context, string) ([]byte, error)        { return nil, k.err }
+
+// fieldCipher encrypts the sensitive fields of events and trips with AES-GCM.
+// A nil *fieldCipher leaves every value as it is.
+type fieldCipher struct {
+	keys KeyProvider
+}
+
+func newFieldCipher(keys KeyProvider) *fieldCipher {
+	if keys == nil {
+		return nil
+	}
+	return &fieldCipher{keys: keys}
+}
+
+// encrypt seals plaintext, binding it to field so a value cannot be moved to
+// another column. Empty values stay empty.
+func (c *fieldCipher) encrypt(ctx context.Context, field, plaintext string) (string, error) {
+	if c == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	id, key, err := c.keys.CurrentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(field))
+	return encryptedPrefix + id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value produced by encrypt. Values without the encrypted
+// prefix, such as rows written before encryption was enabled, are returned as is.
+func (c *fieldCipher) decrypt(ctx context.Context, field, value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, encryptedPrefix)
+	if c == nil || !ok {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("decrypt %s: malformed value", field)
+	}
+	key, err := c.keys.Key(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s: %w", field, err)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("decrypt %s: value too short", field)
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s: %w", field, err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sensitiveFields are the payload and trip fields encrypted at rest.
+const (
+	fieldPassenger       = "passenger"
+	fieldPickupLocation  = "pickup_location"
+	fieldDropoffLocation = "dropoff_location"
+	fieldPickup          = "pickup"
+	fieldDropoff         = "dropoff"
+	fieldPickupZone      = "pickup_zone"
+)
+
+// sealedRequest is the stored form of an encrypted requested event with
+// coordinates. Its pickup and dropoff are moved out of the payload's numeric
+// fields into encrypted JSON strings.
+type sealedRequest struct {
+	events.RideRequestedPayload
+	SealedPickup  string `json:"sealed_pickup,omitempty"`
+	SealedDropoff string `json:"sealed_dropoff,omitempty"`
+}
+
+// decodeStoredPayload is events.DecodePayload for a stored payload, returning
+// a sealedRequest when a requested event's coordinates were encrypted so that
+// decryptEvent can open them.
+func decodeStoredPayload(eventType events.RideEventType, raw []byte) (events.RideEventPayload, error) {
+	payload, err := events.DecodePayload(eventType, raw)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := payload.(events.RideRequestedPayload)
+	if !ok || !bytes.Contains(raw, []byte(`"sealed_`)) {
+		return payload, nil
+	}
+	sealed := sealedRequest{RideRequestedPayload: p}
+	if err := json.Unmarshal(raw, &sealed); err != nil {
+		return nil, fmt.Errorf("events: %s payload: %w", eventType, err)
+	}
+	sealed.RideRequestedPayload = p
+	return sealed, nil
+}
+
+// transformEvent applies fn to the passenger name and locations of a
+// requested event; other events have no sensitive fields.
+func transformEvent(e events.RideEvent, fn func(field, value string) (string, error)) (events.RideEvent, error) {
+	p, ok := events.PayloadAs[events.RideRequestedPayload](e)
+	if !ok {
+		return e, nil
+	}
+	var err error
+	if p.Passenger, err = fn(fieldPassenger, p.Passenger); err != nil {
+		return e, err
+	}
+	if p.PickupLocation, err = fn(fieldPickupLocation, p.PickupLocation); err != nil {
+		return e, err
+	}
+	if p.DropoffLocation, err = fn(fieldDropoffLocation, p.DropoffLocation); err != nil {
+		return e, err
+	}
+	e.Payload = p
+	return e, nil
+}
+
+// encryptEvent encrypts the sensitive fields of e. The coordinates of a
+// requested event are sealed into a sealedRequest, as numbers cannot hold a
+// ciphertext.
+func (c *fieldCipher) encryptEvent(ctx context.Context, e events.RideEvent) (events.RideEvent, error) {
+	if c == nil {
+		return e, nil
+	}
+	e, err := transformEvent(e, func(field, v string) (string, error) { return c.encrypt(ctx, field, v) })
+	if err != nil {
+		return e, err
+	}
+	p, ok := events.PayloadAs[events.RideRequestedPayload](e)
+	if !ok || (p.Pickup == nil && p.Dropoff == nil) {
+		return e, nil
+	}
+	sealed := sealedRequest{RideRequestedPayload: p}
+	if sealed.SealedPickup, err = c.encryptCoordinate(ctx, fieldPickup, p.Pickup); err != nil {
+		return e, err
+	}
+	if sealed.SealedDropoff, err = c.encryptCoordinate(ctx, fieldDropoff, p.Dropoff); err != nil {
+		return e, err
+	}
+	sealed.Pickup, sealed.Dropoff = nil, nil
+	e.Payload = sealed
+	return e, nil
+}
+
+func (c *fieldCipher) decryptEvent(ctx context.Context, e events.RideEvent) (events.RideEvent, error) {
+	if sealed, ok := e.Payload.(sealedRequest); ok {
+		p := sealed.RideRequestedPayload
+		var err error
+		if p.Pickup, err = c.decryptCoordinate(ctx, fieldPickup, sealed.SealedPickup); err != nil {
+			return e, err
+		}
+		if p.Dropoff, err = c.decryptCoordinate(ctx, fieldDropoff, sealed.SealedDropoff); err != nil {
+			return e, err
+		}
+		e.Payload = p
+	}
+	if c == nil {
+		return e, nil
+	}
+	return transformEvent(e, func(field, v string) (string, error) { return c.decrypt(ctx, field, v) })
+}
+
+func (c *fieldCipher) encryptCoordinate(ctx context.Context, field string, coord *events.Coordinate) (string, error) {
+	if coord == nil {
+		return "", nil
+	}
+	plaintext, err := json.Marshal(coord)
+	if err != nil {
+		return "", err
+	}
+	return c.encrypt(ctx, field, string(plaintext))
+}
+
+// decryptCoordinate opens a coordinate sealed by encryptCoordinate. Without a
+// cipher the coordinate cannot be read and is left out.
+func (c *fieldCipher) decryptCoordinate(ctx context.Context, field, value string) (*events.Coordinate, error) {
+	if c == nil || value == "" {
+		return nil, nil
+	}
+	plaintext, err := c.decrypt(ctx, field, value)
+	if err != nil {
+		return nil, err
+	}
+	var coord events.Coordinate
+	if err := json.Unmarshal([]byte(plaintext), &coord); err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", field, err)
+	}
+	return &coord, nil
+}
+
+// withoutCoordinates clears the coordinates of a requested event when fields
+// are encrypted, so they are not written to the numeric rides columns.
+func (c *fieldCipher) withoutCoordinates(e events.RideEvent) events.RideEvent {
+	if c == nil {
+		return e
+	}
+	if p, ok := events.PayloadAs[events.RideRequestedPayload](e); ok {
+		p.Pickup, p.Dropoff = nil, nil
+		e.Payload = p
+	}
+	return e
+}
+
+func (c *fieldCipher) encryptEvents(ctx context.Context, evts []events.RideEvent) ([]events.RideEvent, error) {
+	if c == nil {
+		return evts, nil
+	}
+	out := make([]events.RideEvent, len(evts))
+	for i, e := range evts {
+		var err error
+		if out[i], err = c.encryptEvent(ctx, e); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// encryptTrip encrypts the trip's locations and its pickup zone. A zone that
+// is the pickup location of a request without coordinates shares the location's
+// ciphertext; a grid cell is encrypted on its own, as it narrows down the pickup.
+func (c *fieldCipher) encryptTrip(ctx context.Context, t aggregation.Trip) (aggregation.Trip, error) {
+	zoneIsLocation := string(t.PickupZone) == t.PickupLocation
+	var err error
+	if t.PickupLocation, err = c.encrypt(ctx, fieldPickupLocation, t.PickupLocation); err != nil {
+		return t, err
+	}
+	zone := t.PickupLocation
+	if !zoneIsLocation {
+		if zone, err = c.encrypt(ctx, fieldPickupZone, string(t.PickupZone)); err != nil {
+			return t, err
+		}
+	}
+	t.PickupZone = events.ZoneID(zone)
+	t.DropoffLocation, err = c.encrypt(ctx, fieldDropoffLocation, t.DropoffLocation)
+	return t, err
+}
+
+func (c *fieldCipher) decryptTrip(ctx context.Context, t aggregation.Trip) (aggregation.Trip, error) {
+	zoneIsLocation := string(t.PickupZone) == t.PickupLocation
+	var err error
+	if t.PickupLocation, err = c.decrypt(ctx, fieldPickupLocation, t.PickupLocation); err != nil {
+		return t, err
+	}
+	zone := t.PickupLocation
+	if !zoneIsLocation {
+		if zone, err = c.decrypt(ctx, fieldPickupZone, string(t.PickupZone)); err != nil {
+			return t, err
+		}
+	}
+	t.PickupZone = events.ZoneID(zone)
+	t.DropoffLocation, err = c.decrypt(ctx, fieldDropoffLocation, t.DropoffLocation)
+	return t, err
+}
+
+func (c *fieldCipher) decryptTrips(ctx context.Context, trips []aggregation.Trip) ([]aggregation.Trip, error) {
+	for i := range trips {
+		var err error
+		if trips[i], err = c.decryptTrip(ctx, trips[i]); err != nil {
+			return nil, err
+		}
+	}
+	return trips, nil
+}
+
+// decryptZones decrypts the pickup locations of AvgFareByZone rows. Every
+// encrypted value is unique, so the database groups each trip on its own; the
+// rows are merged again here by plaintext zone.
+func (c *fieldCipher) decryptZones(ctx context.Context, zones []ZoneFare) ([]ZoneFare, error) {
+	if c == nil {
+		return zones, nil
+	}
+	merged := make(map[string]*ZoneFare)
+	var order []string
+	for _, z := range zones {
+		zone, err := c.decrypt(ctx, fieldPickupLocation, z.Zone)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := merged[zone]
+		if !ok {
+			m = &ZoneFare{Zone: zone}
+			merged[zone] = m
+			order = append(order, zone)
+		}
+		total := m.AvgFareUSD*float64(m.Trips) + z.AvgFareUSD*float64(z.Trips)
+		m.Trips += z.Trips
+		m.AvgFareUSD = total / float64(m.Trips)
+	}
+
+	out := make([]ZoneFare, 0, len(order))
+	for _, zone := range order {
+		out = append(out, *merged[zone])
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].AvgFareUSD > out[j].AvgFareUSD })
+	return out, nil
+}
@@ -0,0 +1,194 @@
+package rides_db
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func testKeys(ids ...string) *StaticKeys {
+	keys := &StaticKeys{Current: ids[0], Keys: make(map[string][]byte)}
+	for _, id := range ids {
+		keys.Keys[id] = bytes.Repeat([]byte(id), 32)[:32]
+	}
+	return keys
+}
+
+func TestFieldCipher_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c := newFieldCipher(testKeys("k1"))
+
+	sealed, err := c.encrypt(ctx, fieldPickupLocation, "Main St")
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v1:k1:") || strings.Contains(sealed, "Main St") {
+		t.Fatalf("unexpected ciphertext %q", sealed)
+	}
+	again, _ := c.encrypt(ctx, fieldPickupLocation, "Main St")
+	if again == sealed {
+		t.Error("expected a fresh nonce per value")
+	}
+
+	got, err := c.decrypt(ctx, fieldPickupLocation, sealed)
+	if err != nil || got != "Main St" {
+		t.Errorf("decrypt = %q, %v", got, err)
+	}
+	// Values are bound to their field
+	if _, err := c.decrypt(ctx, fieldDropoffLocation, sealed); err == nil {
+		t.Error("expected decrypting under another field to fail")
+	}
+	// Rows written before encryption was enabled are read as they are
+	if got, err := c.decrypt(ctx, fieldPickupLocation, "Elm St"); err != nil || got != "Elm St" {
+		t.Errorf("plaintext passthrough = %q, %v", got, err)
+	}
+	if got, _ := c.encrypt(ctx, fieldPassenger, ""); got != "" {
+		t.Errorf("expected empty values to stay empty, got %q", got)
+	}
+}
+
+func TestFieldCipher_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	old := newFieldCipher(testKeys("k1"))
+	sealed, err := old.encrypt(ctx, fieldPassenger, "Ada")
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	rotated := newFieldCipher(testKeys("k2", "k1"))
+	if got, err := rotated.decrypt(ctx, fieldPassenger, sealed); err != nil || got != "Ada" {
+		t.Errorf("decrypt with rotated keys = %q, %v", got, err)
+	}
+	if fresh, _ := rotated.encrypt(ctx, fieldPassenger, "Ada"); !strings.HasPrefix(fresh, "enc:v1:k2:") {
+		t.Errorf("expected new values under k2, got %q", fresh)
+	}
+	if _, err := newFieldCipher(testKeys("k3")).decrypt(ctx, fieldPassenger, sealed); err == nil {
+		t.Error("expected an unknown key to fail")
+	}
+}
+
+func TestKeysFromEnv(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	short := base64.StdEncoding.EncodeToString([]byte("short"))
+
+	tests := []struct {
+		name    string
+		value   string
+		current string
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "first key is current", value: "new:" + key + ", old:" + key, current: "new"},
+		{name: "missing id", value: key, wantErr: true},
+		{name: "bad base64", value: "k1:***", wantErr: true},
+		{name: "wrong length", value: "k1:" + short, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FIELD_ENCRYPTION_KEYS", tt.value)
+			keys, err := KeysFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.current != "" && (keys == nil || keys.Current != tt.current || len(keys.Keys) != 2) {
+				t.Errorf("unexpected keys %+v", keys)
+			}
+			if tt.value == "" && keys != nil {
+				t.Errorf("expected nil keys when unset, got %+v", keys)
+			}
+		})
+	}
+}
+
+func TestSQLiteStore_FieldEncryption(t *testing.T) {
+	store, err := OpenSQLite(":memory:", WithFieldEncryption(testKeys("k1")))
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	for _, tripID := range []string{"trip-1", "trip-2"} {
+		err := store.WithTx(ctx, func(tx RideStore) error {
+			evts := completedTrip(tripID, base)
+			if _, err := tx.InsertRideEvents(ctx, evts); err != nil {
+				return err
+			}
+			for _, e := range evts {
+				if err := tx.UpsertRideState(ctx, e); err != nil {
+					return err
+				}
+			}
+			return tx.RefreshTrip(ctx, tripID)
+		})
+		if err != nil {
+			t.Fatalf("persisting %s failed: %v", tripID, err)
+		}
+	}
+
+	var payload, pickup string
+	if err := store.DB().QueryRow(`SELECT payload, pickup_location FROM ride_events WHERE id = 'trip-1-1'`).Scan(&payload, &pickup); err != nil {
+		t.Fatalf("reading raw event failed: %v", err)
+	}
+	if strings.Contains(payload, "Main St") || strings.Contains(payload, `"passenger":"rider-1"`) || !strings.HasPrefix(pickup, "enc:v1:") {
+		t.Errorf("expected sensitive fields encrypted at rest, got payload=%s pickup=%s", payload, pickup)
+	}
+	if strings.Contains(payload, "40.75") || strings.Contains(payload, "73.98") || !strings.Contains(payload, `"sealed_pickup":"enc:v1:`) {
+		t.Errorf("expected coordinates sealed at rest, got payload=%s", payload)
+	}
+	var plaintextCoordinates int
+	if err := store.DB().QueryRow(`SELECT COUNT(*) FROM rides
+		WHERE pickup_lat IS NOT NULL OR pickup_lng IS NOT NULL OR dropoff_lat IS NOT NULL OR dropoff_lng IS NOT NULL`).Scan(&plaintextCoordinates); err != nil {
+		t.Fatalf("reading raw rides failed: %v", err)
+	}
+	if plaintextCoordinates != 0 {
+		t.Errorf("expected no coordinates in rides, got %d rows with them", plaintextCoordinates)
+	}
+
+	evts, err := store.GetTripEvents(ctx, "trip-1")
+	if err != nil {
+		t.Fatalf("GetTripEvents failed: %v", err)
+	}
+	if p := evts[0].Payload.(events.RideRequestedPayload); p.Passenger != "rider-1" || p.PickupLocation != "Main St" || p.DropoffLocation != "Elm St" ||
+		p.Pickup == nil || *p.Pickup != (events.Coordinate{Lat: 40.75, Lng: -73.98}) || p.Dropoff != nil {
+		t.Errorf("expected decrypted payload, got %+v", p)
+	}
+
+	trip, err := store.GetTrip(ctx, "trip-1")
+	if err != nil || trip.PickupLocation != "Main St" || trip.DropoffLocation != "Elm St" {
+		t.Errorf("GetTrip = %+v, %v", trip, err)
+	}
+
+	// A grid cell narrows down the pickup, so it is encrypted too
+	if err := store.InsertTrip(ctx, aggregation.Trip{TripID: "trip-3", PickupLocation: "Main St", PickupZone: "g:40.75:-73.98"}); err != nil {
+		t.Fatalf("InsertTrip failed: %v", err)
+	}
+	var zone string
+	if err := store.DB().QueryRow(`SELECT pickup_zone FROM trips WHERE trip_id = 'trip-3'`).Scan(&zone); err != nil {
+		t.Fatalf("reading raw trip failed: %v", err)
+	}
+	if !strings.HasPrefix(zone, "enc:v1:") {
+		t.Errorf("expected pickup zone encrypted at rest, got %q", zone)
+	}
+	if trip, err := store.GetTrip(ctx, "trip-3"); err != nil || trip.PickupZone != "g:40.75:-73.98" {
+		t.Errorf("GetTrip = %+v, %v", trip, err)
+	}
+
+	zones, err := store.AvgFareByZone(ctx, TimeRange{})
+	if err != nil {
+		t.Fatalf("AvgFareByZone failed: %v", err)
+	}
+	if len(zones) != 1 || zones[0].Zone != "Main St" || zones[0].Trips != 2 || zones[0].AvgFareUSD != 8.5 {
+		t.Errorf("expected one merged zone, got %+v", zones)
+	}
+}
@@ -0,0 +1,91 @@
+package rides_db
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// OpenFromEnv opens the store selected by DB_DRIVER: "postgres" (the default,
+// configured by POSTGRES_HOST, POSTGRES_PORT, POSTGRES_USER, POSTGRES_PASSWORD,
+// and POSTGRES_DB), "mysql" (MYSQL_DSN), or "sqlite" (SQLITE_PATH, default
+// rides.db). Pool and retry settings come from OptionsFromEnv, and the pool
+// statistics are registered as Prometheus metrics labelled with the driver.
+func OpenFromEnv() (RideStore, error) {
+	opts := OptionsFromEnv()
+	var (
+		store interface {
+			RideStore
+			DB() *sql.DB
+		}
+		err error
+	)
+	driver := os.Getenv("DB_DRIVER")
+	switch driver {
+	case "", "postgres":
+		driver = "postgres"
+		store, err = Open(postgresConnString(), opts...)
+	case "mysql":
+		store, err = OpenMySQL(os.Getenv("MYSQL_DSN"), opts...)
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "rides.db"
+		}
+		store, err = OpenSQLite(path, opts...)
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := RegisterStatsMetrics(store.DB(), driver); err != nil {
+		slog.Warn("Failed to register database pool metrics", "error", err)
+	}
+	return store, nil
+}
+
+// OpenPostgresFromEnv opens a PostgreSQL store configured as OpenFromEnv
+// configures it, with opts applied after the settings from the environment,
+// such as WithSchema for a copy of the tables. It fails if DB_DRIVER selects
+// another database.
+func OpenPostgresFromEnv(opts ...Option) (*Store, error) {
+	if driver := os.Getenv("DB_DRIVER"); driver != "" && driver != "postgres" {
+		return nil, fmt.Errorf("need DB_DRIVER=postgres, got %q", driver)
+	}
+	return Open(postgresConnString(), append(OptionsFromEnv(), opts...)...)
+}
+
+// CityStoresFromEnv returns the per-city stores when DB_SCHEMA_PER_CITY is true,
+// or nil when it is unset or false. def is the store OpenFromEnv returned and
+// serves events without a city; schemas per city need the PostgreSQL driver.
+func CityStoresFromEnv(def RideStore) (*CityStores, error) {
+	perCity, _ := strconv.ParseBool(os.Getenv("DB_SCHEMA_PER_CITY"))
+	if !perCity {
+		return nil, nil
+	}
+	pg, ok := def.(*Store)
+	if !ok {
+		return nil, fmt.Errorf("DB_SCHEMA_PER_CITY requires DB_DRIVER=postgres, got %q", os.Getenv("DB_DRIVER"))
+	}
+	return NewCityStores(pg, postgresConnString(), OptionsFromEnv()...), nil
+}
+
+// postgresConnString builds the PostgreSQL connection string from
+// POSTGRES_HOST, POSTGRES_USER, POSTGRES_PASSWORD, and POSTGRES_DB, and
+// POSTGRES_PORT when set.
+func postgresConnString() string {
+	conn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("POSTGRES_HOST"),
+		os.Getenv("POSTGRES_USER"),
+		os.Getenv("POSTGRES_PASSWORD"),
+		os.Getenv("POSTGRES_DB"),
+	)
+	if port := os.Getenv("POSTGRES_PORT"); port != "" {
+		conn += " port=" + port
+	}
+	return conn
+}
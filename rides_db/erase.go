@@ -0,0 +1,117 @@
+package rides_db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// Erasure subjects recorded in pii_erasures.
+const (
+	SubjectPassenger = "passenger"
+	SubjectDriver    = "driver"
+)
+
+// Erasure reports what an ErasePassenger or EraseDriver call changed.
+type Erasure struct {
+	SubjectType  string
+	Pseudonym    string
+	RowsAffected int64
+}
+
+// ErasePassenger replaces passengerID with a random pseudonym in every table,
+// notification recipients included, and scrubs the name, pickup and dropoff
+// locations, and coordinates of the passenger's trips, then records the
+// erasure in pii_erasures, all in one transaction. A trip's pricing zone goes
+// too when it is the pickup location, but a grid cell is kept. Aggregates such
+// as fares and durations are kept.
+func (s *Store) ErasePassenger(ctx context.Context, passengerID string) (Erasure, error) {
+	// Requested events carry the passenger's name and locations, so those go
+	// along with the ID. Events are matched through rides too, so they must be
+	// scrubbed first.
+	return s.erase(ctx, SubjectPassenger, passengerID, func(q *sqlcdb.Queries, pseudonym string) (int64, error) {
+		events := sqlcdb.ErasePassengerEventsParams{PassengerID: passengerID, Pseudonym: pseudonym}
+		rides := sqlcdb.ErasePassengerRidesParams{Pseudonym: pseudonym, PassengerID: passengerID}
+		return sumRows(
+			func() (int64, error) { return q.ErasePassengerEvents(ctx, events) },
+			func() (int64, error) {
+				return q.ErasePassengerArchivedEvents(ctx, sqlcdb.ErasePassengerArchivedEventsParams(events))
+			},
+			func() (int64, error) { return q.ErasePassengerRides(ctx, rides) },
+			func() (int64, error) { return q.ErasePassengerTrips(ctx, sqlcdb.ErasePassengerTripsParams(rides)) },
+			func() (int64, error) {
+				return q.ErasePassengerNotifications(ctx, sqlcdb.ErasePassengerNotificationsParams(rides))
+			},
+		)
+	})
+}
+
+// EraseDriver replaces driverID with a random pseudonym in every table,
+// including accepted-event payloads, notification recipients, and fraud
+// alerts and their details, and records the erasure in pii_erasures.
+func (s *Store) EraseDriver(ctx context.Context, driverID string) (Erasure, error) {
+	return s.erase(ctx, SubjectDriver, driverID, func(q *sqlcdb.Queries, pseudonym string) (int64, error) {
+		events := sqlcdb.EraseDriverEventsParams{DriverID: driverID, Pseudonym: pseudonym}
+		rides := sqlcdb.EraseDriverRidesParams{Pseudonym: pseudonym, DriverID: driverID}
+		return sumRows(
+			func() (int64, error) { return q.EraseDriverEvents(ctx, events) },
+			func() (int64, error) {
+				return q.EraseDriverArchivedEvents(ctx, sqlcdb.EraseDriverArchivedEventsParams(events))
+			},
+			func() (int64, error) { return q.EraseDriverRides(ctx, rides) },
+			func() (int64, error) { return q.EraseDriverTrips(ctx, sqlcdb.EraseDriverTripsParams(rides)) },
+			func() (int64, error) {
+				return q.EraseDriverNotifications(ctx, sqlcdb.EraseDriverNotificationsParams(rides))
+			},
+			func() (int64, error) {
+				return q.EraseDriverFraudAlerts(ctx, sqlcdb.EraseDriverFraudAlertsParams(events))
+			},
+		)
+	})
+}
+
+func (s *Store) erase(ctx context.Context, subjectType, subjectID string, scrub func(q *sqlcdb.Queries, pseudonym string) (int64, error)) (Erasure, error) {
+	if subjectID == "" {
+		return Erasure{}, fmt.Errorf("erase %s: empty ID", subjectType)
+	}
+	e := Erasure{SubjectType: subjectType, Pseudonym: "erased-" + uuid.NewString()}
+	sum := sha256.Sum256([]byte(subjectID))
+	err := s.retry(ctx, func() error {
+		return s.withTx(ctx, func(tx *Store) error {
+			q := tx.queries()
+			n, err := scrub(q, e.Pseudonym)
+			if err != nil {
+				return err
+			}
+			e.RowsAffected = n
+			return q.RecordErasure(ctx, sqlcdb.RecordErasureParams{
+				SubjectType:  subjectType,
+				SubjectHash:  hex.EncodeToString(sum[:]),
+				Pseudonym:    e.Pseudonym,
+				RowsAffected: n,
+			})
+		})
+	})
+	if err != nil {
+		return Erasure{}, fmt.Errorf("erase %s: %w", subjectType, err)
+	}
+	return e, nil
+}
+
+// sumRows runs stmts in order and returns the rows they changed in total.
+func sumRows(stmts ...func() (int64, error)) (int64, error) {
+	var total int64
+	for _, stmt := range stmts {
+		n, err := stmt()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,129 @@
+package rides_db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestErasePassenger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	sum := sha256.Sum256([]byte("rider-1"))
+
+	mock.ExpectBegin()
+	for _, table := range []string{"ride_events", "ride_events_archive"} {
+		mock.ExpectExec(`UPDATE `+table+`\s+SET passenger_id = CASE .* THEN \(payload - 'pickup' - 'dropoff'\)`).
+			WithArgs("rider-1", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 4))
+	}
+	mock.ExpectExec(`UPDATE rides\s+SET passenger_id = \$1::text, pickup_lat = NULL`).
+		WithArgs(sqlmock.AnyArg(), "rider-1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE trips\s+SET passenger_id = \$1::text, pickup_location = NULL, dropoff_location = NULL,\s+pickup_zone = CASE WHEN pickup_zone = pickup_location THEN NULL`).
+		WithArgs(sqlmock.AnyArg(), "rider-1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE notification_deliveries SET recipient = \$1::text WHERE recipient = \$2::text`).
+		WithArgs(sqlmock.AnyArg(), "rider-1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`INSERT INTO pii_erasures`).
+		WithArgs(SubjectPassenger, hex.EncodeToString(sum[:]), sqlmock.AnyArg(), int64(15)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	e, err := store.ErasePassenger(context.Background(), "rider-1")
+	if err != nil {
+		t.Fatalf("ErasePassenger failed: %v", err)
+	}
+	if e.RowsAffected != 15 || !strings.HasPrefix(e.Pseudonym, "erased-") {
+		t.Errorf("unexpected erasure: %+v", e)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestEraseDriver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	sum := sha256.Sum256([]byte("driver-1"))
+
+	mock.ExpectBegin()
+	for _, table := range []string{"ride_events", "ride_events_archive"} {
+		mock.ExpectExec(`UPDATE `+table+`\s+SET driver_id = CASE`).
+			WithArgs("driver-1", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+	}
+	for _, table := range []string{"rides", "trips"} {
+		mock.ExpectExec(`UPDATE `+table+` SET driver_id = \$1::text WHERE driver_id = \$2::text`).
+			WithArgs(sqlmock.AnyArg(), "driver-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(`UPDATE notification_deliveries SET recipient = \$1::text WHERE recipient = \$2::text`).
+		WithArgs(sqlmock.AnyArg(), "driver-1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE fraud_alerts\s+SET driver_id = CASE .*\s+detail = replace\(detail, \$1::text, \$2::text\)`).
+		WithArgs("driver-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO pii_erasures`).
+		WithArgs(SubjectDriver, hex.EncodeToString(sum[:]), sqlmock.AnyArg(), int64(12)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	e, err := store.EraseDriver(context.Background(), "driver-1")
+	if err != nil {
+		t.Fatalf("EraseDriver failed: %v", err)
+	}
+	if e.RowsAffected != 12 || e.SubjectType != SubjectDriver {
+		t.Errorf("unexpected erasure: %+v", e)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestEraseDriver_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db, WithRetry(1, 0, 0))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE ride_events\s+SET driver_id = CASE`).
+		WithArgs("driver-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE ride_events_archive\s+SET driver_id = CASE`).
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	if _, err := store.EraseDriver(context.Background(), "driver-1"); err == nil {
+		t.Error("expected an error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestErase_RejectsEmptyID(t *testing.T) {
+	store := New(nil)
+	if _, err := store.EraseDriver(context.Background(), ""); err == nil {
+		t.Error("expected an error for an empty driver ID")
+	}
+}
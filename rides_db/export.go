@@ -0,0 +1,231 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// exportPageSize is how many rows the export methods read per query. Each page
+// resumes after the last row of the one before, so an export holds neither a
+// long-running transaction nor the whole range in memory.
+const exportPageSize = 1000
+
+// ErrExportRange is returned by the export methods when the time range is open
+// at either end.
+var ErrExportRange = errors.New("rides_db: export needs a time range with both From and To")
+
+// firstEventID sorts before every ride_events ID, so the first export page
+// starts at the beginning of the range.
+const firstEventID = "00000000-0000-0000-0000-000000000000"
+
+// exportTripsSQL and exportRideEventsSQL are the keyset pages of ExportTrips and
+// ExportRideEvents in queries/, for the backends that do not use the generated
+// queries: sqlc.yaml generates code for the Postgres schema alone, so the
+// SQLite and MySQL statements stay written by hand. The arguments are the range, the sort key of the last row seen, and
+// the page size. MySQL has its own placeholders.
+const (
+	exportTripsSQL = `
+		SELECT ` + tripColumns + ` FROM trips
+		WHERE requested_at >= $1 AND requested_at < $2
+		  AND (requested_at, trip_id) > ($3, $4)
+		ORDER BY requested_at, trip_id
+		LIMIT $5
+	`
+	exportRideEventsSQL = `
+		SELECT id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload
+		FROM ride_events
+		WHERE event_time >= $1 AND event_time < $2
+		  AND (event_time, id) > ($3, $4)
+		ORDER BY event_time, id
+		LIMIT $5
+	`
+	mysqlExportTripsSQL = `
+		SELECT ` + tripColumns + ` FROM trips
+		WHERE requested_at >= ? AND requested_at < ?
+		  AND (requested_at, trip_id) > (?, ?)
+		ORDER BY requested_at, trip_id
+		LIMIT ?
+	`
+	mysqlExportRideEventsSQL = `
+		SELECT id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload
+		FROM ride_events
+		WHERE event_time >= ? AND event_time < ?
+		  AND (event_time, id) > (?, ?)
+		ORDER BY event_time, id
+		LIMIT ?
+	`
+)
+
+// ExportTrips calls fn with every finished trip requested within tr, in request
+// order, stopping at the first error fn returns. Reads go to a replica when one
+// is configured.
+func (s *Store) ExportTrips(ctx context.Context, tr TimeRange, fn func(aggregation.Trip) error) error {
+	if tr.From.IsZero() || tr.To.IsZero() {
+		return ErrExportRange
+	}
+	p := sqlcdb.ExportTripsParams{From: tr.From, To: tr.To, AfterRequestedAt: tr.From, Limit: exportPageSize}
+	for {
+		rows, err := s.readQueries(ctx).ExportTrips(ctx, p)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			t, err := s.cipher.decryptTrip(ctx, tripFromRow(row))
+			if err != nil {
+				return err
+			}
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+		if len(rows) < exportPageSize {
+			return nil
+		}
+		last := rows[len(rows)-1]
+		p.AfterRequestedAt, p.AfterTripID = last.RequestedAt.Time, last.TripID
+	}
+}
+
+// ExportRideEvents calls fn with every stored event within tr, in event-time
+// order, stopping at the first error fn returns. Reads go to a replica when one
+// is configured.
+func (s *Store) ExportRideEvents(ctx context.Context, tr TimeRange, fn func(events.RideEvent) error) error {
+	if tr.From.IsZero() || tr.To.IsZero() {
+		return ErrExportRange
+	}
+	p := sqlcdb.ExportRideEventsParams{From: tr.From, To: tr.To, AfterEventTime: tr.From, AfterID: firstEventID, Limit: exportPageSize}
+	for {
+		rows, err := s.readQueries(ctx).ExportRideEvents(ctx, p)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			e, err := rideEventFromRow(sqlcdb.GetTripEventsRow(row))
+			if err != nil {
+				return err
+			}
+			if e, err = s.cipher.decryptEvent(ctx, e); err != nil {
+				return err
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		if len(rows) < exportPageSize {
+			return nil
+		}
+		last := rows[len(rows)-1]
+		p.AfterEventTime, p.AfterID = last.EventTime, last.ID
+	}
+}
+
+// ExportTrips calls fn with every finished trip requested within tr, in request
+// order, stopping at the first error fn returns.
+func (s *SQLiteStore) ExportTrips(ctx context.Context, tr TimeRange, fn func(aggregation.Trip) error) error {
+	return exportTrips(ctx, s.q, exportTripsSQL, s.cipher, tr, fn)
+}
+
+// ExportRideEvents calls fn with every stored event within tr, in event-time
+// order, stopping at the first error fn returns.
+func (s *SQLiteStore) ExportRideEvents(ctx context.Context, tr TimeRange, fn func(events.RideEvent) error) error {
+	return exportRideEvents(ctx, s.q, exportRideEventsSQL, s.cipher, tr, fn)
+}
+
+// ExportTrips calls fn with every finished trip requested within tr, in request
+// order, stopping at the first error fn returns.
+func (s *MySQLStore) ExportTrips(ctx context.Context, tr TimeRange, fn func(aggregation.Trip) error) error {
+	return exportTrips(ctx, s.q, mysqlExportTripsSQL, s.cipher, tr, fn)
+}
+
+// ExportRideEvents calls fn with every stored event within tr, in event-time
+// order, stopping at the first error fn returns.
+func (s *MySQLStore) ExportRideEvents(ctx context.Context, tr TimeRange, fn func(events.RideEvent) error) error {
+	return exportRideEvents(ctx, s.q, mysqlExportRideEventsSQL, s.cipher, tr, fn)
+}
+
+// exportTrips pages through the trips in tr with query (see exportTripsSQL).
+func exportTrips(ctx context.Context, q querier, query string, c *fieldCipher, tr TimeRange, fn func(aggregation.Trip) error) error {
+	if tr.From.IsZero() || tr.To.IsZero() {
+		return ErrExportRange
+	}
+	afterAt, afterID := tr.From, ""
+	for {
+		var page []aggregation.Trip
+		rows, err := q.QueryContext(ctx, query, utcArgs([]any{tr.From, tr.To, afterAt, afterID, exportPageSize})...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			t, err := scanTrip(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			page = append(page, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, t := range page {
+			if t, err = c.decryptTrip(ctx, t); err != nil {
+				return err
+			}
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		last := page[len(page)-1]
+		afterAt, afterID = last.RequestedAt, last.TripID
+	}
+}
+
+// exportRideEvents pages through the ride events in tr with query (see
+// exportRideEventsSQL).
+func exportRideEvents(ctx context.Context, q querier, query string, c *fieldCipher, tr TimeRange, fn func(events.RideEvent) error) error {
+	if tr.From.IsZero() || tr.To.IsZero() {
+		return ErrExportRange
+	}
+	afterAt, afterID := tr.From, ""
+	for {
+		var page []events.RideEvent
+		rows, err := q.QueryContext(ctx, query, utcArgs([]any{tr.From, tr.To, afterAt, afterID, exportPageSize})...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			e, err := scanRideEvent(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			page = append(page, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, e := range page {
+			if e, err = c.decryptEvent(ctx, e); err != nil {
+				return err
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		last := page[len(page)-1]
+		afterAt, afterID = last.OccurredAt, last.ID
+	}
+}
@@ -0,0 +1,120 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestExportRideEvents_PagesByEventTimeAndID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	columns := []string{"id", "trip_id", "event_type", "event_state", "event_time", "driver_id", "passenger_id", "payload"}
+
+	first := sqlmock.NewRows(columns)
+	for i := 0; i < exportPageSize; i++ {
+		first.AddRow(fmt.Sprintf("e%04d", i), "trip-1", "STARTED", "IN_PROGRESS", from.Add(time.Duration(i)*time.Second), nil, nil, []byte(`{}`))
+	}
+	last := from.Add(time.Duration(exportPageSize-1) * time.Second)
+	mock.ExpectQuery("SELECT (.+) FROM ride_events").
+		WithArgs(from, to, from, firstEventID, exportPageSize).
+		WillReturnRows(first)
+	mock.ExpectQuery("SELECT (.+) FROM ride_events").
+		WithArgs(from, to, last, fmt.Sprintf("e%04d", exportPageSize-1), exportPageSize).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("e9999", "trip-2", "COMPLETED", "COMPLETED", last.Add(time.Second), "driver-1", nil,
+			[]byte(`{"distance_km":3,"fare_usd":5.5}`)))
+
+	var got []events.RideEvent
+	err = store.ExportRideEvents(context.Background(), TimeRange{From: from, To: to}, func(e events.RideEvent) error {
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportRideEvents failed: %v", err)
+	}
+	if len(got) != exportPageSize+1 {
+		t.Fatalf("expected %d events, got %d", exportPageSize+1, len(got))
+	}
+	if p, ok := got[exportPageSize].Payload.(events.RideCompletedPayload); !ok || p.Fare != events.NewMoney(550, events.USD) {
+		t.Errorf("unexpected completed payload: %#v", got[exportPageSize].Payload)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLiteStore_Export(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	base := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	// One more trip than fits on a page, so the export has to resume after it
+	for i := 0; i <= exportPageSize; i++ {
+		requested := base.Add(time.Duration(i/2) * time.Minute) // pairs share a request time
+		trip := aggregation.Trip{
+			TripID: fmt.Sprintf("trip-%04d", i), FinalState: events.StateCompleted,
+			RequestedAt: requested, CompletedAt: requested.Add(10 * time.Minute), FareUSD: 5,
+		}
+		if err := store.InsertTrip(ctx, trip); err != nil {
+			t.Fatalf("InsertTrip failed: %v", err)
+		}
+	}
+	for _, e := range completedTrip("trip-0000", base) {
+		if _, err := store.InsertRideEvent(ctx, e); err != nil {
+			t.Fatalf("InsertRideEvent failed: %v", err)
+		}
+	}
+
+	var trips []string
+	err := store.ExportTrips(ctx, TimeRange{From: base, To: base.Add(24 * time.Hour)}, func(tr aggregation.Trip) error {
+		trips = append(trips, tr.TripID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportTrips failed: %v", err)
+	}
+	if len(trips) != exportPageSize+1 {
+		t.Fatalf("expected %d trips, got %d", exportPageSize+1, len(trips))
+	}
+	for i, id := range trips {
+		if want := fmt.Sprintf("trip-%04d", i); id != want {
+			t.Fatalf("trip %d is %s, want %s", i, id, want)
+		}
+	}
+
+	var evts []events.RideEvent
+	err = store.ExportRideEvents(ctx, TimeRange{From: base.Add(time.Minute), To: base.Add(20 * time.Minute)}, func(e events.RideEvent) error {
+		evts = append(evts, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportRideEvents failed: %v", err)
+	}
+	// The range is half-open, so the completion at exactly 20 minutes is left out
+	if len(evts) != 2 || evts[0].Type != events.EventRideAccepted || evts[1].Type != events.EventTripStarted {
+		t.Errorf("unexpected events in range: %+v", evts)
+	}
+
+	stop := errors.New("stop")
+	err = store.ExportTrips(ctx, TimeRange{From: base, To: base.Add(time.Hour)}, func(aggregation.Trip) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("expected the callback's error, got %v", err)
+	}
+	if err := store.ExportTrips(ctx, TimeRange{From: base}, func(aggregation.Trip) error { return nil }); !errors.Is(err, ErrExportRange) {
+		t.Errorf("expected ErrExportRange for an open range, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// InsertFraudAlert stores an alert in fraud_alerts. An alert already stored,
+// by its ID, is left as it is, so redelivered alerts are stored once.
+func (s *Store) InsertFraudAlert(ctx context.Context, a fraud.Alert) error {
+	defer observeWrite("fraud_alerts", time.Now())
+	return s.queries().InsertFraudAlert(ctx, fraudAlertParams(a))
+}
+
+// InsertFraudAlert stores an alert; see Store.InsertFraudAlert.
+func (s *SQLiteStore) InsertFraudAlert(ctx context.Context, a fraud.Alert) error {
+	defer observeWrite("fraud_alerts", time.Now())
+	_, err := s.q.ExecContext(ctx, fraudAlertInsertSQL, fraudAlertArgs(a)...)
+	return err
+}
+
+// InsertFraudAlert stores an alert; see Store.InsertFraudAlert.
+func (s *MySQLStore) InsertFraudAlert(ctx context.Context, a fraud.Alert) error {
+	defer observeWrite("fraud_alerts", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT IGNORE INTO fraud_alerts
+		(alert_id, kind, trip_id, driver_id, city, detected_at, detail, fare_usd, expected_fare_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, fraudAlertArgs(a)...)
+	return err
+}
+
+// fraudAlertInsertSQL is InsertFraudAlert in queries/fraud_alerts.sql, for
+// SQLite.
+const fraudAlertInsertSQL = `
+	INSERT INTO fraud_alerts
+	(alert_id, kind, trip_id, driver_id, city, detected_at, detail, fare_usd, expected_fare_usd)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (alert_id) DO NOTHING
+`
+
+// fraudAlertParams returns the fraud_alerts row for a.
+func fraudAlertParams(a fraud.Alert) sqlcdb.InsertFraudAlertParams {
+	usd := func(m *events.Money) sql.NullFloat64 {
+		if m == nil {
+			return sql.NullFloat64{}
+		}
+		return sql.NullFloat64{Float64: m.Float64(), Valid: true}
+	}
+	return sqlcdb.InsertFraudAlertParams{
+		AlertID:         a.ID,
+		Kind:            string(a.Kind),
+		TripID:          a.TripID,
+		DriverID:        nullString(a.DriverID),
+		City:            nullString(a.City),
+		DetectedAt:      a.DetectedAt.UTC(),
+		Detail:          a.Detail,
+		FareUsd:         usd(a.Fare),
+		ExpectedFareUsd: usd(a.Expected),
+	}
+}
+
+// fraudAlertArgs returns the fraud_alerts column values for a in insert
+// order, for the backends that do not use the generated queries.
+func fraudAlertArgs(a fraud.Alert) []any {
+	p := fraudAlertParams(a)
+	return []any{p.AlertID, p.Kind, p.TripID, p.DriverID, p.City, p.DetectedAt, p.Detail, p.FareUsd, p.ExpectedFareUsd}
+}
+
+// ListFraudAlerts returns the limit most recently detected alerts, newest
+// first.
+func (s *Store) ListFraudAlerts(ctx context.Context, limit int) ([]fraud.Alert, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.readQueries(ctx).ListFraudAlerts(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]fraud.Alert, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, fraudAlertFromRow(row))
+	}
+	return out, nil
+}
+
+// ListFraudAlerts returns recent alerts; see Store.ListFraudAlerts.
+func (s *SQLiteStore) ListFraudAlerts(ctx context.Context, limit int) ([]fraud.Alert, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, fraudAlertListSQL+` LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanFraudAlerts(rows)
+}
+
+// ListFraudAlerts returns recent alerts; see Store.ListFraudAlerts.
+func (s *MySQLStore) ListFraudAlerts(ctx context.Context, limit int) ([]fraud.Alert, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, fraudAlertListSQL+` LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanFraudAlerts(rows)
+}
+
+// fraudAlertListSQL is ListFraudAlerts in queries/fraud_alerts.sql without
+// its limit, whose placeholder differs between backends.
+const fraudAlertListSQL = `
+	SELECT alert_id, kind, trip_id, driver_id, city, detected_at, detail, fare_usd, expected_fare_usd
+	FROM fraud_alerts
+	ORDER BY detected_at DESC, alert_id
+`
+
+// scanFraudAlerts reads the rows of ListFraudAlerts, for the backends that do
+// not use the generated queries.
+func scanFraudAlerts(rows *sql.Rows) ([]fraud.Alert, error) {
+	defer rows.Close()
+	var out []fraud.Alert
+	for rows.Next() {
+		var row sqlcdb.FraudAlert
+		if err := rows.Scan(&row.AlertID, &row.Kind, &row.TripID, &row.DriverID, &row.City,
+			&row.DetectedAt, &row.Detail, &row.FareUsd, &row.ExpectedFareUsd); err != nil {
+			return nil, err
+		}
+		out = append(out, fraudAlertFromRow(row))
+	}
+	return out, rows.Err()
+}
+
+// fraudAlertFromRow returns the alert of a fraud_alerts row. Its type is
+// EventAlert and its fares are in USD; the meta is not stored.
+func fraudAlertFromRow(row sqlcdb.FraudAlert) fraud.Alert {
+	usd := func(f sql.NullFloat64) *events.Money {
+		if !f.Valid {
+			return nil
+		}
+		m := events.MoneyFromFloat(f.Float64, events.USD)
+		return &m
+	}
+	return fraud.Alert{
+		ID:         row.AlertID,
+		Type:       fraud.EventAlert,
+		Kind:       fraud.AlertKind(row.Kind),
+		TripID:     row.TripID,
+		DriverID:   row.DriverID.String,
+		City:       row.City.String,
+		DetectedAt: row.DetectedAt.UTC(),
+		Detail:     row.Detail,
+		Fare:       usd(row.FareUsd),
+		Expected:   usd(row.ExpectedFareUsd),
+	}
+}
@@ -0,0 +1,92 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+)
+
+func fareAlert(at time.Time) fraud.Alert {
+	a := fraud.NewAlert(fraud.KindFareMismatch, "trip-1", at, "charged 20.00 USD, want 10.00 USD")
+	fare, expected := events.NewMoney(2000, events.USD), events.NewMoney(1000, events.USD)
+	a.DriverID, a.Fare, a.Expected = "driver-1", &fare, &expected
+	return a
+}
+
+func TestInsertFraudAlert_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	at := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	a := fareAlert(at)
+
+	mock.ExpectExec("INSERT INTO fraud_alerts").
+		WithArgs(a.ID, "fare_mismatch", "trip-1", "driver-1", nil, at, a.Detail, 20.0, 10.0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.InsertFraudAlert(context.Background(), a); err != nil {
+		t.Errorf("InsertFraudAlert failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLiteStore_FraudAlerts(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	at := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	fare := fareAlert(at)
+	speed := fraud.NewAlert(fraud.KindImpossibleSpeed, "trip-2", at, "moved 30 km in a minute")
+	// A redelivered alert is stored once
+	for _, a := range []fraud.Alert{fare, speed, fare} {
+		if err := store.InsertFraudAlert(ctx, a); err != nil {
+			t.Fatalf("InsertFraudAlert failed: %v", err)
+		}
+	}
+
+	var n int
+	store.DB().QueryRow(`SELECT COUNT(*) FROM fraud_alerts`).Scan(&n)
+	if n != 2 {
+		t.Errorf("expected 2 alerts, got %d", n)
+	}
+	var fareUSD, expectedUSD sql.NullFloat64
+	store.DB().QueryRow(`SELECT fare_usd, expected_fare_usd FROM fraud_alerts WHERE kind = 'impossible_speed'`).Scan(&fareUSD, &expectedUSD)
+	if fareUSD.Valid || expectedUSD.Valid {
+		t.Errorf("expected no fares on a speed alert, got %v and %v", fareUSD, expectedUSD)
+	}
+	store.DB().QueryRow(`SELECT fare_usd, expected_fare_usd FROM fraud_alerts WHERE alert_id = $1`, fare.ID).Scan(&fareUSD, &expectedUSD)
+	if fareUSD.Float64 != 20 || expectedUSD.Float64 != 10 {
+		t.Errorf("unexpected fares: %v and %v", fareUSD, expectedUSD)
+	}
+
+	// The latest alert comes first
+	late := fraud.NewAlert(fraud.KindDuplicateTrip, "trip-3", at.Add(time.Minute), "accepted while on trip-2")
+	if err := store.InsertFraudAlert(ctx, late); err != nil {
+		t.Fatalf("InsertFraudAlert failed: %v", err)
+	}
+	alerts, err := store.ListFraudAlerts(ctx, 2)
+	if err != nil {
+		t.Fatalf("ListFraudAlerts failed: %v", err)
+	}
+	if len(alerts) != 2 || alerts[0].ID != late.ID || !alerts[0].DetectedAt.Equal(late.DetectedAt) || alerts[0].Type != fraud.EventAlert {
+		t.Fatalf("unexpected alerts: %+v", alerts)
+	}
+	all, _ := store.ListFraudAlerts(ctx, 10)
+	for _, a := range all {
+		if a.ID == fare.ID && (a.DriverID != "driver-1" || *a.Fare != *fare.Fare || *a.Expected != *fare.Expected) {
+			t.Errorf("fare alert read back as %+v", a)
+		}
+	}
+}
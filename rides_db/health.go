@@ -0,0 +1,111 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// healthTimeout bounds Health when the caller's context has no deadline.
+const healthTimeout = 2 * time.Second
+
+var slowQueries = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "rides_db_slow_queries_total",
+	Help: "Number of database statements that took longer than the slow query threshold.",
+})
+
+// health pings db and runs a trivial query, so a pool that still has
+// connections but cannot reach the server is reported as unhealthy.
+func health(ctx context.Context, db *sql.DB) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, healthTimeout)
+		defer cancel()
+	}
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+	var one int
+	return db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
+// Health reports whether the database answers queries.
+func (s *Store) Health(ctx context.Context) error { return health(ctx, s.db) }
+
+// Health reports whether the database answers queries.
+func (s *SQLiteStore) Health(ctx context.Context) error { return health(ctx, s.db) }
+
+// Health reports whether the database answers queries.
+func (s *MySQLStore) Health(ctx context.Context) error { return health(ctx, s.db) }
+
+// RegisterStatsMetrics exposes the connection pool statistics of db (open,
+// in-use, and idle connections, waits, and closes) as Prometheus metrics
+// labelled with name. Registering the same name twice is not an error.
+func RegisterStatsMetrics(db *sql.DB, name string) error {
+	err := prometheus.Register(collectors.NewDBStatsCollector(db, name))
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
+
+// slowQueryLogger logs statements on q that take at least threshold. Only the
+// SQL text is logged; arguments may hold personal data.
+type slowQueryLogger struct {
+	q         querier
+	threshold time.Duration
+}
+
+// withSlowQueryLog wraps q in a slowQueryLogger; a threshold <= 0 returns q unchanged.
+func withSlowQueryLog(q querier, threshold time.Duration) querier {
+	if threshold <= 0 {
+		return q
+	}
+	return slowQueryLogger{q: q, threshold: threshold}
+}
+
+func (l slowQueryLogger) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer l.observe(query, time.Now())
+	return l.q.ExecContext(ctx, query, args...)
+}
+
+func (l slowQueryLogger) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	defer l.observe(query, time.Now())
+	return l.q.QueryContext(ctx, query, args...)
+}
+
+func (l slowQueryLogger) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return l.q.PrepareContext(ctx, query)
+}
+
+func (l slowQueryLogger) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	defer l.observe(query, time.Now())
+	return l.q.QueryRowContext(ctx, query, args...)
+}
+
+func (l slowQueryLogger) observe(query string, start time.Time) {
+	d := time.Since(start)
+	if d < l.threshold {
+		return
+	}
+	slowQueries.Inc()
+	slog.Warn("Slow database query", "duration", d, "threshold", l.threshold, "query", compactSQL(query))
+}
+
+// compactSQL collapses whitespace and truncates query for logging.
+func compactSQL(query string) string {
+	const maxLen = 300
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLen {
+		query = query[:maxLen] + "…"
+	}
+	return query
+}
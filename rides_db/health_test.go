@@ -0,0 +1,69 @@
+package rides_db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHealth(t *testing.T) {
+	store := openTestSQLite(t)
+	if err := store.Health(context.Background()); err != nil {
+		t.Errorf("expected a healthy store, got %v", err)
+	}
+	store.Close()
+	if err := store.Health(context.Background()); err == nil {
+		t.Error("expected a closed store to be unhealthy")
+	}
+}
+
+func TestRegisterStatsMetrics_Twice(t *testing.T) {
+	store := openTestSQLite(t)
+	for range 2 {
+		if err := RegisterStatsMetrics(store.DB(), "health_test"); err != nil {
+			t.Fatalf("RegisterStatsMetrics failed: %v", err)
+		}
+	}
+}
+
+func TestSlowQueryLogger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE rides").WillDelayFor(20 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE trips").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	q := withSlowQueryLog(db, 10*time.Millisecond)
+	before := testutil.ToFloat64(slowQueries)
+	ctx := context.Background()
+	if _, err := q.ExecContext(ctx, "UPDATE rides SET state = $1", "COMPLETED"); err != nil {
+		t.Fatalf("ExecContext failed: %v", err)
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE trips SET fare_usd = $1", 1); err != nil {
+		t.Fatalf("ExecContext failed: %v", err)
+	}
+	if got := testutil.ToFloat64(slowQueries) - before; got != 1 {
+		t.Errorf("expected one slow query, got %v", got)
+	}
+
+	if withSlowQueryLog(db, 0) != querier(db) {
+		t.Error("expected a zero threshold to leave the querier unwrapped")
+	}
+}
+
+func TestCompactSQL(t *testing.T) {
+	got := compactSQL("SELECT a,\n\t\tb\n  FROM t")
+	if got != "SELECT a, b FROM t" {
+		t.Errorf("compactSQL = %q", got)
+	}
+	if long := compactSQL(strings.Repeat("x ", 500)); len(long) > 310 || !strings.HasSuffix(long, "…") {
+		t.Errorf("expected long statements to be truncated, got %d bytes", len(long))
+	}
+}
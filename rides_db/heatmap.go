@@ -0,0 +1,140 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// HeatmapCell is the demand and supply of one pricing zone in one time bucket,
+// as the heatmap builder counts them.
+type HeatmapCell struct {
+	ZoneID string
+	Start  time.Time
+	End    time.Time
+	// Pickups counts the rides requested in the bucket with a pickup in the
+	// zone, and Drivers the drivers that reported from it free.
+	Pickups int64
+	Drivers int64
+}
+
+// UpsertHeatmapCell stores a heatmap cell. Counts only grow, so a builder that
+// restarts mid-bucket and writes the lower counts it has since seen leaves the
+// cell as it was.
+func (s *Store) UpsertHeatmapCell(ctx context.Context, c HeatmapCell) error {
+	defer observeWrite("heatmap_cells", time.Now())
+	return s.queries().UpsertHeatmapCell(ctx, sqlcdb.UpsertHeatmapCellParams{
+		ZoneID:      c.ZoneID,
+		BucketStart: c.Start.UTC(),
+		BucketEnd:   c.End.UTC(),
+		Pickups:     c.Pickups,
+		Drivers:     c.Drivers,
+	})
+}
+
+// UpsertHeatmapCell stores a heatmap cell; see Store.UpsertHeatmapCell.
+func (s *SQLiteStore) UpsertHeatmapCell(ctx context.Context, c HeatmapCell) error {
+	defer observeWrite("heatmap_cells", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO heatmap_cells
+		(zone_id, bucket_start, bucket_end, pickups, drivers, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (bucket_start, zone_id) DO UPDATE
+		SET pickups = max(heatmap_cells.pickups, excluded.pickups),
+		    drivers = max(heatmap_cells.drivers, excluded.drivers),
+		    updated_at = excluded.updated_at
+	`, utcArgs([]any{c.ZoneID, c.Start, c.End, c.Pickups, c.Drivers, time.Now()})...)
+	return err
+}
+
+// UpsertHeatmapCell stores a heatmap cell; see Store.UpsertHeatmapCell.
+func (s *MySQLStore) UpsertHeatmapCell(ctx context.Context, c HeatmapCell) error {
+	defer observeWrite("heatmap_cells", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO heatmap_cells
+		(zone_id, bucket_start, bucket_end, pickups, drivers, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			pickups = GREATEST(pickups, VALUES(pickups)),
+			drivers = GREATEST(drivers, VALUES(drivers)),
+			updated_at = VALUES(updated_at)
+	`, c.ZoneID, c.Start.UTC(), c.End.UTC(), c.Pickups, c.Drivers, time.Now())
+	return err
+}
+
+// ListHeatmapCells returns the heatmap cells whose bucket starts within tr, by
+// start and zone.
+func (s *Store) ListHeatmapCells(ctx context.Context, tr TimeRange) ([]HeatmapCell, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.readQueries(ctx).ListHeatmapCells(ctx, sqlcdb.ListHeatmapCellsParams{From: nullTime(tr.From), To: nullTime(tr.To)})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]HeatmapCell, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, HeatmapCell{
+			ZoneID:  row.ZoneID,
+			Start:   row.BucketStart.UTC(),
+			End:     row.BucketEnd.UTC(),
+			Pickups: row.Pickups,
+			Drivers: row.Drivers,
+		})
+	}
+	return out, nil
+}
+
+// ListHeatmapCells returns heatmap cells; see Store.ListHeatmapCells.
+func (s *SQLiteStore) ListHeatmapCells(ctx context.Context, tr TimeRange) ([]HeatmapCell, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT zone_id, bucket_start, bucket_end, pickups, drivers
+		FROM heatmap_cells
+		WHERE ($1 IS NULL OR bucket_start >= $1)
+		  AND ($2 IS NULL OR bucket_start < $2)
+		ORDER BY bucket_start, zone_id
+	`, utcArgs([]any{nullTime(tr.From), nullTime(tr.To)})...)
+	if err != nil {
+		return nil, err
+	}
+	return scanHeatmapCells(rows)
+}
+
+// ListHeatmapCells returns heatmap cells; see Store.ListHeatmapCells.
+func (s *MySQLStore) ListHeatmapCells(ctx context.Context, tr TimeRange) ([]HeatmapCell, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT zone_id, bucket_start, bucket_end, pickups, drivers
+		FROM heatmap_cells
+		WHERE (? IS NULL OR bucket_start >= ?)
+		  AND (? IS NULL OR bucket_start < ?)
+		ORDER BY bucket_start, zone_id
+	`, nullTime(tr.From), nullTime(tr.From), nullTime(tr.To), nullTime(tr.To))
+	if err != nil {
+		return nil, err
+	}
+	return scanHeatmapCells(rows)
+}
+
+// scanHeatmapCells reads the rows of ListHeatmapCells, for the backends that
+// do not use the generated queries.
+func scanHeatmapCells(rows *sql.Rows) ([]HeatmapCell, error) {
+	defer rows.Close()
+	var out []HeatmapCell
+	for rows.Next() {
+		var c HeatmapCell
+		if err := rows.Scan(&c.ZoneID, &c.Start, &c.End, &c.Pickups, &c.Drivers); err != nil {
+			return nil, err
+		}
+		c.Start, c.End = c.Start.UTC(), c.End.UTC()
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,37 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStore_HeatmapCells(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	cell := func(zone string, bucket, pickups, drivers int) HeatmapCell {
+		at := start.Add(time.Duration(bucket) * 5 * time.Minute)
+		return HeatmapCell{ZoneID: zone, Start: at, End: at.Add(5 * time.Minute), Pickups: int64(pickups), Drivers: int64(drivers)}
+	}
+	midtown := cell("40.74,-73.98", 0, 4, 1)
+	// A builder that restarted mid-bucket writes lower counts, which are kept
+	// only where they are higher
+	for _, c := range []HeatmapCell{midtown, cell("40.74,-73.98", 0, 2, 3), cell("40.74,-73.98", 1, 1, 0)} {
+		if err := store.UpsertHeatmapCell(ctx, c); err != nil {
+			t.Fatalf("UpsertHeatmapCell failed: %v", err)
+		}
+	}
+
+	cells, err := store.ListHeatmapCells(ctx, TimeRange{From: start, To: start.Add(5 * time.Minute)})
+	if err != nil {
+		t.Fatalf("ListHeatmapCells failed: %v", err)
+	}
+	if len(cells) != 1 || cells[0].ZoneID != midtown.ZoneID || !cells[0].End.Equal(midtown.End) || cells[0].Pickups != 4 || cells[0].Drivers != 3 {
+		t.Fatalf("cells in the first bucket: %+v", cells)
+	}
+	all, _ := store.ListHeatmapCells(ctx, TimeRange{})
+	if len(all) != 2 || !all[1].Start.Equal(start.Add(5*time.Minute)) {
+		t.Errorf("cells: %+v", all)
+	}
+}
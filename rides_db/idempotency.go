@@ -0,0 +1,36 @@
+package rides_db
+
+import (
+	"context"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// InsertOutcome reports what InsertRideEvent did with an event.
+type InsertOutcome string
+
+const (
+	// OutcomeInserted means the event was stored.
+	OutcomeInserted InsertOutcome = "inserted"
+	// OutcomeDuplicate means an event with the same ID, trip, and type was
+	// stored before, so this one is a redelivery and nothing changed.
+	OutcomeDuplicate InsertOutcome = "duplicate"
+	// OutcomeConflict means the event was not stored because it clashes with
+	// what is already there: its ID was used for a different event, or the trip
+	// already has an event of its type at the same time under another ID.
+	OutcomeConflict InsertOutcome = "conflict"
+)
+
+// skippedEventOutcome tells a duplicate from a conflict after an insert of e
+// changed no rows, on backends whose ride_events is unique on id. query takes
+// the event's id, trip_id, and event_type and counts matching rows.
+func skippedEventOutcome(ctx context.Context, q querier, query string, e events.RideEvent) (InsertOutcome, error) {
+	var n int
+	if err := q.QueryRowContext(ctx, query, e.ID, e.TripID, string(e.Type)).Scan(&n); err != nil {
+		return "", err
+	}
+	if n > 0 {
+		return OutcomeDuplicate, nil
+	}
+	return OutcomeConflict, nil
+}
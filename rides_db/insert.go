@@ -1,23 +1,31 @@
 package rides_db
 
 import (
-    "context"
-    "encoding/json"
+	"context"
+	"time"
+
 	"github.com/pedeveaux/kafkarideshare/events"
 )
 
-func InsertRideEvent(ctx context.Context, e events.RideEvent) error {
-    payloadBytes, err := json.Marshal(e.Payload)
-    if err != nil {
-        return err
-    }
-
-    _, err = DB.ExecContext(ctx, `
-        INSERT INTO ride_events 
-        (id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-        ON CONFLICT (trip_id, event_type) DO NOTHING
-    `, e.ID, e.TripID, e.Type, e.State, e.Timestamp, e.DriverID, e.PassengerID, payloadBytes)
+// InsertRideEvent stores e and reports whether it was inserted, skipped as a
+// redelivery of an event ID already in the ride_event_ledger, or skipped as a
+// conflict with a stored event. Only an error means the event may not be stored.
+func (s *Store) InsertRideEvent(ctx context.Context, e events.RideEvent) (outcome InsertOutcome, err error) {
+	defer func(start time.Time) { observeInsert(start, outcome, err) }(time.Now())
+	e, err = s.cipher.encryptEvent(ctx, e)
+	if err != nil {
+		return "", err
+	}
+	params, err := rideEventParams(e)
+	if err != nil {
+		return "", err
+	}
 
-    return err
-}
\ No newline at end of file
+	var result string
+	err = s.retry(ctx, func() error {
+		var err error
+		result, err = s.queries().InsertRideEvent(ctx, params)
+		return err
+	})
+	return InsertOutcome(result), err
+}
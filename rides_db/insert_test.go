@@ -2,6 +2,7 @@ package rides_db
 
 import (
 	"context"
+	"database/sql/driver"
 	"testing"
 	"time"
 
@@ -17,30 +18,120 @@ func TestInsertRideEvent_Success(t *testing.T) {
 	}
 	defer db.Close()
 
-	DB = db // override global for test
+	store := New(db)
 
 	evt := events.RideEvent{
 		ID:          uuid.New().String(),
 		TripID:      "trip-123",
 		Type:        "trip_started",
 		State:       "in_progress",
-		Timestamp:   time.Now(),
+		OccurredAt:  time.Now(),
 		DriverID:    "driver-1",
 		PassengerID: "rider-1",
-		Payload: events.RideStartedPayload{StartTime: time.Now(),
-		},
+		Payload:     events.RideStartedPayload{StartTime: time.Now()},
 	}
 
-	mock.ExpectExec("INSERT INTO ride_events").
-		WithArgs(sqlmock.AnyArg(), "trip-123", "trip_started", "in_progress", sqlmock.AnyArg(), "driver-1", "rider-1", sqlmock.AnyArg()).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO ride_event_ledger (.+) INSERT INTO ride_events").
+		WithArgs(sqlmock.AnyArg(), "trip-123", "trip_started", "in_progress", sqlmock.AnyArg(), "driver-1", "rider-1", sqlmock.AnyArg(),
+			nil, nil, nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"outcome"}).AddRow("inserted"))
 
 	ctx := context.Background()
-	if err := InsertRideEvent(ctx, evt); err != nil {
+	outcome, err := store.InsertRideEvent(ctx, evt)
+	if err != nil {
 		t.Errorf("InsertRideEvent failed: %v", err)
 	}
+	if outcome != OutcomeInserted {
+		t.Errorf("expected outcome %q, got %q", OutcomeInserted, outcome)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
 }
+
+func TestInsertRideEvents_FallbackTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	evts := []events.RideEvent{
+		{ID: uuid.NewString(), TripID: "trip-1", Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: time.Now(),
+			Payload: events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "A", DropoffLocation: "B"}},
+		{ID: uuid.NewString(), TripID: "trip-1", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: time.Now(),
+			Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO ride_events").
+		WithArgs(evts[0].ID, "trip-1", "REQUESTED", "REQUESTED", sqlmock.AnyArg(), "", "", sqlmock.AnyArg(),
+			nil, nil, nil, "A", "B").
+		WillReturnRows(sqlmock.NewRows([]string{"outcome"}).AddRow("inserted"))
+	mock.ExpectQuery("INSERT INTO ride_events").
+		WithArgs(evts[1].ID, "trip-1", "ACCEPTED", "ACCEPTED", sqlmock.AnyArg(), "", "", sqlmock.AnyArg(),
+			nil, nil, nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"outcome"}).AddRow("duplicate"))
+	mock.ExpectCommit()
+
+	inserted, err := store.InsertRideEvents(context.Background(), evts)
+	if err != nil {
+		t.Fatalf("InsertRideEvents failed: %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("expected 1 inserted row, got %d", inserted)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestInsertRideEvents_Empty(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	if n, err := New(db).InsertRideEvents(context.Background(), nil); err != nil || n != 0 {
+		t.Errorf("expected no-op for empty batch, got n=%d err=%v", n, err)
+	}
+}
+
+func TestInsertRideEvent_TypedColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	tests := []struct {
+		name    string
+		payload events.RideEventPayload
+		typed   []driver.Value
+	}{
+		{"completed", events.RideCompletedPayload{DistanceKM: 8.2, Fare: events.NewMoney(1070, events.USD)}, []driver.Value{8.2, 10.7, nil, nil, nil}},
+		{"cancelled", events.RideCancelledPayload{CancelledBy: "driver"}, []driver.Value{nil, nil, "driver", nil, nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]driver.Value{sqlmock.AnyArg(), "trip-1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "", "", sqlmock.AnyArg()}, tt.typed...)
+			mock.ExpectQuery("INSERT INTO ride_events").
+				WithArgs(args...).
+				WillReturnRows(sqlmock.NewRows([]string{"outcome"}).AddRow("inserted"))
+
+			if _, err := store.InsertRideEvent(context.Background(), events.RideEvent{TripID: "trip-1", Payload: tt.payload}); err != nil {
+				t.Errorf("InsertRideEvent failed: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
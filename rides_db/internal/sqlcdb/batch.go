@@ -0,0 +1,9 @@
+package sqlcdb
+
+// This file is not generated. sqlc's database/sql queries run one statement
+// per round trip, so the SQL text of those that rides_db pipelines on a
+// pgx.Batch is exported here; sqlc still checks it against the schema.
+
+// InsertRideEventSQL is the text of InsertRideEvent, taking its arguments in
+// the order of InsertRideEventParams.
+const InsertRideEventSQL = insertRideEvent
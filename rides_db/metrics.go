@@ -0,0 +1,58 @@
+package rides_db
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// outcomeSkipped labels the events of an InsertRideEvents batch that were not
+// inserted. A batch reports only how many rows it inserted, so it cannot tell
+// duplicates from conflicts.
+const outcomeSkipped = "skipped"
+
+var (
+	rideEventsWritten = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rides_db_ride_events_written_total",
+		Help: "Ride events passed to the store, by outcome: inserted, duplicate, conflict, or skipped (a batched duplicate or conflict).",
+	}, []string{"outcome"})
+	rideEventBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "rides_db_ride_event_batch_size",
+		Help:    "Number of events in each InsertRideEvents batch.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 7),
+	})
+	writeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rides_db_write_duration_seconds",
+		Help:    "Time taken by store writes, by table, including retries.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"table"})
+)
+
+// observeWrite records the latency of a write to table that began at start:
+//
+//	defer observeWrite("trips", time.Now())
+func observeWrite(table string, start time.Time) {
+	writeDuration.WithLabelValues(table).Observe(time.Since(start).Seconds())
+}
+
+// observeInsert records an InsertRideEvent that began at start. Failed inserts
+// count towards the latency but not the outcomes.
+func observeInsert(start time.Time, outcome InsertOutcome, err error) {
+	observeWrite("ride_events", start)
+	if err == nil {
+		rideEventsWritten.WithLabelValues(string(outcome)).Inc()
+	}
+}
+
+// observeBatch records an InsertRideEvents of size events that began at start
+// and inserted inserted of them.
+func observeBatch(start time.Time, size int, inserted int64, err error) {
+	observeWrite("ride_events", start)
+	if err != nil || size == 0 {
+		return
+	}
+	rideEventBatchSize.Observe(float64(size))
+	rideEventsWritten.WithLabelValues(string(OutcomeInserted)).Add(float64(inserted))
+	rideEventsWritten.WithLabelValues(outcomeSkipped).Add(float64(int64(size) - inserted))
+}
@@ -0,0 +1,59 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSQLiteStore_WriteMetrics(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	base := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	written := func(outcome string) float64 {
+		return testutil.ToFloat64(rideEventsWritten.WithLabelValues(outcome))
+	}
+	before := map[string]float64{}
+	for _, o := range []string{string(OutcomeInserted), string(OutcomeDuplicate), string(OutcomeConflict), outcomeSkipped} {
+		before[o] = written(o)
+	}
+
+	trip := completedTrip("trip-1", base)
+	if _, err := store.InsertRideEvent(ctx, trip[0]); err != nil {
+		t.Fatalf("InsertRideEvent failed: %v", err)
+	}
+	if _, err := store.InsertRideEvent(ctx, trip[0]); err != nil {
+		t.Fatalf("InsertRideEvent failed: %v", err)
+	}
+	// Same trip, type, and time as the first event but another ID
+	conflicting := trip[0]
+	conflicting.ID = "other"
+	if _, err := store.InsertRideEvent(ctx, conflicting); err != nil {
+		t.Fatalf("InsertRideEvent failed: %v", err)
+	}
+	if n, err := store.InsertRideEvents(ctx, trip); err != nil || n != 3 {
+		t.Fatalf("InsertRideEvents = %d, %v; want 3 inserted", n, err)
+	}
+	if err := store.UpsertRideState(ctx, trip[0]); err != nil {
+		t.Fatalf("UpsertRideState failed: %v", err)
+	}
+
+	want := map[string]float64{
+		string(OutcomeInserted):  4,
+		string(OutcomeDuplicate): 1,
+		string(OutcomeConflict):  1,
+		outcomeSkipped:           1,
+	}
+	for outcome, n := range want {
+		if got := written(outcome) - before[outcome]; got != n {
+			t.Errorf("%s events = %v, want %v", outcome, got, n)
+		}
+	}
+	// At least the ride_events and rides latency series
+	if got := testutil.CollectAndCount(writeDuration); got < 2 {
+		t.Errorf("expected write latencies for ride_events and rides, got %d series", got)
+	}
+}
@@ -0,0 +1,186 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed migrations/*.sql migrations_sqlite/*.sql migrations_mysql/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the Postgres advisory lock key held while migrating, so
+// several consumers starting at once don't apply the same migration twice.
+const migrationLockID = 72461001
+
+// Migration is one embedded schema change. Files are named NNNN_description.sql
+// and applied in version order.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the embedded PostgreSQL migrations sorted by version.
+func Migrations() ([]Migration, error) {
+	return loadMigrations("migrations")
+}
+
+func loadMigrations(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, desc, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_description.sql", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		body, err := migrationFiles.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: desc, SQL: string(body)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies every embedded migration that has not been recorded in
+// schema_migrations yet. Each migration runs in its own transaction. A store
+// opened WithCity creates its city's schema first and migrates that schema.
+func (s *Store) Migrate(ctx context.Context) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if s.schema != "" {
+		if _, err := conn.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS `+pgx.Identifier{s.schema}.Sanitize()); err != nil {
+			return fmt.Errorf("create schema %s: %w", s.schema, err)
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT now()
+		)
+	`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	return applyPending(ctx, conn, migrations, recordMigrationSQL, auditInsertSQL)
+}
+
+// recordMigrationSQL marks a migration applied; MySQL uses its own placeholders.
+const recordMigrationSQL = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+
+// applyPending applies the migrations not yet recorded in schema_migrations,
+// recording each with the record statement. Once all of them are in, and so
+// audit_log is sure to exist, each is also appended to the audit log with the
+// audit statement.
+func applyPending(ctx context.Context, conn *sql.Conn, migrations []Migration, record, audit string) error {
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	var entries []AuditEntry
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		start := time.Now()
+		if err := applyMigration(ctx, conn, m, record); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		entries = append(entries, AuditEntry{
+			Kind:             AuditMigration,
+			Actor:            processActor(),
+			RecordedAt:       start,
+			Duration:         time.Since(start),
+			MigrationVersion: m.Version,
+			MigrationName:    m.Name,
+		})
+	}
+
+	// The migrations are committed, so a failed audit write is reported but not fatal
+	for _, e := range entries {
+		if _, err := conn.ExecContext(ctx, audit, auditArgs(e)...); err != nil {
+			slog.Error("Failed to audit migration", "version", e.MigrationVersion, "name", e.MigrationName, "error", err)
+		}
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, m Migration, record string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, m.Version, m.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
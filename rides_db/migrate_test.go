@@ -0,0 +1,71 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMigrations_SortedAndUnique(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations failed: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.SQL == "" {
+			t.Errorf("migration %d has no SQL", m.Version)
+		}
+		if i > 0 && migrations[i-1].Version >= m.Version {
+			t.Errorf("migrations out of order: %d before %d", migrations[i-1].Version, m.Version)
+		}
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_ride_events" {
+		t.Errorf("unexpected first migration: %d %s", migrations[0].Version, migrations[0].Name)
+	}
+}
+
+func TestMigrate_AppliesOnlyPendingMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations failed: %v", err)
+	}
+
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+	for _, m := range migrations[1:] {
+		mock.ExpectBegin()
+		mock.ExpectExec(".+").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(m.Version, m.Name).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	for _, m := range migrations[1:] {
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(string(AuditMigration), sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, nil, nil, nil,
+				sqlmock.AnyArg(), int32(m.Version), m.Name).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS consumer_checkpoints (
+    consumer_group TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    partition INTEGER NOT NULL,
+    last_offset BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (consumer_group, topic, partition)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS ride_event_windows (
+    window_start TIMESTAMP NOT NULL,
+    window_end TIMESTAMP NOT NULL,
+    event_type VARCHAR(10) NOT NULL,
+    event_count BIGINT NOT NULL,
+    fare_total NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    is_correction BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (window_start, event_type)
+);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS trips (
+    trip_id TEXT PRIMARY KEY,
+    passenger_id TEXT,
+    driver_id TEXT,
+    final_state VARCHAR(12) NOT NULL,
+    pickup_location TEXT,
+    dropoff_location TEXT,
+    requested_at TIMESTAMP,
+    accepted_at TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    pickup_wait_seconds DOUBLE PRECISION,
+    duration_seconds DOUBLE PRECISION,
+    distance_km DOUBLE PRECISION,
+    fare_usd NUMERIC(10, 2),
+    cancelled_by TEXT,
+    cancel_reason TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_trips_requested_at ON trips (requested_at);
+CREATE INDEX IF NOT EXISTS idx_trips_driver_id ON trips (driver_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS rides (
+    trip_id TEXT PRIMARY KEY,
+    state VARCHAR(12) NOT NULL,
+    last_event_type VARCHAR(10) NOT NULL,
+    last_event_at TIMESTAMP NOT NULL,
+    driver_id TEXT,
+    passenger_id TEXT,
+    requested_at TIMESTAMP,
+    accepted_at TIMESTAMP,
+    started_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    fare_usd NUMERIC(10, 2)
+);
+CREATE INDEX IF NOT EXISTS idx_rides_active ON rides (last_event_at)
+    WHERE state IN ('REQUESTED', 'ACCEPTED', 'IN_PROGRESS');
+CREATE INDEX IF NOT EXISTS idx_rides_driver_id ON rides (driver_id);
@@ -0,0 +1,5 @@
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS accept_latency_seconds DOUBLE PRECISION;
+UPDATE trips
+SET accept_latency_seconds = EXTRACT(EPOCH FROM accepted_at - requested_at)
+WHERE accept_latency_seconds IS NULL AND accepted_at IS NOT NULL AND requested_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_trips_final_state ON trips (final_state);
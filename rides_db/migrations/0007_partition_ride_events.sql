@@ -0,0 +1,31 @@
+-- Partitioned tables need the partition key in every unique constraint, so
+-- duplicates are now detected on (trip_id, event_type, event_time). A redelivered
+-- event carries the same event_time and is still skipped.
+CREATE TABLE ride_events_partitioned (
+    id UUID NOT NULL,
+    trip_id TEXT NOT NULL,
+    event_type VARCHAR(10) NOT NULL,
+    event_state VARCHAR(12) NOT NULL,
+    event_time TIMESTAMP NOT NULL,
+    driver_id TEXT,
+    passenger_id TEXT,
+    payload JSONB,
+    PRIMARY KEY (id, event_time),
+    UNIQUE (trip_id, event_type, event_time)
+) PARTITION BY RANGE (event_time);
+
+-- Catches events outside every dated partition (backfills, clock skew) so
+-- inserts never fail; MaintainPartitions creates dated partitions ahead of time.
+CREATE TABLE ride_events_default PARTITION OF ride_events_partitioned DEFAULT;
+
+INSERT INTO ride_events_partitioned
+    (id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload)
+SELECT id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload
+FROM ride_events;
+
+DROP TABLE ride_events;
+ALTER TABLE ride_events_partitioned RENAME TO ride_events;
+
+CREATE INDEX IF NOT EXISTS idx_trip_events ON ride_events (trip_id, event_time);
+CREATE INDEX IF NOT EXISTS idx_event_type ON ride_events (event_type);
+CREATE INDEX IF NOT EXISTS idx_passenger_id ON ride_events (passenger_id);
@@ -0,0 +1,25 @@
+-- Frequently queried payload fields as real columns; payload keeps the full event.
+ALTER TABLE ride_events
+    ADD COLUMN IF NOT EXISTS distance_km DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS fare_total NUMERIC(10, 2),
+    ADD COLUMN IF NOT EXISTS cancelled_by TEXT,
+    ADD COLUMN IF NOT EXISTS pickup_location TEXT,
+    ADD COLUMN IF NOT EXISTS dropoff_location TEXT;
+
+UPDATE ride_events SET
+    distance_km = (payload->>'distance_km')::DOUBLE PRECISION,
+    fare_total = (payload->>'fare_usd')::NUMERIC
+WHERE event_type = 'COMPLETED' AND distance_km IS NULL;
+
+UPDATE ride_events SET cancelled_by = payload->>'cancelled_by'
+WHERE event_type = 'CANCELLED' AND cancelled_by IS NULL;
+
+UPDATE ride_events SET
+    pickup_location = payload->>'pickup_location',
+    dropoff_location = payload->>'dropoff_location'
+WHERE event_type = 'REQUESTED' AND pickup_location IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_ride_events_fare_total ON ride_events (fare_total) WHERE fare_total IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_ride_events_cancelled_by ON ride_events (cancelled_by) WHERE cancelled_by IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_ride_events_pickup_location ON ride_events (pickup_location) WHERE pickup_location IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_ride_events_dropoff_location ON ride_events (dropoff_location) WHERE dropoff_location IS NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE rides
+    ADD COLUMN IF NOT EXISTS pickup_lat DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS pickup_lng DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS dropoff_lat DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS dropoff_lng DOUBLE PRECISION;
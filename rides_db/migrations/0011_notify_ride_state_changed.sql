@@ -0,0 +1,22 @@
+-- Announce every change of a ride's state on the ride_state_changed channel.
+-- Notifications are sent when the writing transaction commits.
+CREATE OR REPLACE FUNCTION notify_ride_state_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.state IS DISTINCT FROM OLD.state THEN
+        PERFORM pg_notify('ride_state_changed', json_build_object(
+            'trip_id', NEW.trip_id,
+            'state', NEW.state,
+            'previous_state', CASE WHEN TG_OP = 'UPDATE' THEN OLD.state END,
+            'event_type', NEW.last_event_type,
+            'event_time', NEW.last_event_at AT TIME ZONE 'UTC',
+            'driver_id', NEW.driver_id
+        )::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS rides_state_changed ON rides;
+CREATE TRIGGER rides_state_changed
+    AFTER INSERT OR UPDATE ON rides
+    FOR EACH ROW EXECUTE FUNCTION notify_ride_state_changed();
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS ride_events_archive (
+    id UUID PRIMARY KEY,
+    trip_id TEXT NOT NULL,
+    event_type VARCHAR(10) NOT NULL,
+    event_state VARCHAR(12) NOT NULL,
+    event_time TIMESTAMP NOT NULL,
+    driver_id TEXT,
+    passenger_id TEXT,
+    payload JSONB,
+    distance_km DOUBLE PRECISION,
+    fare_total NUMERIC(10, 2),
+    cancelled_by TEXT,
+    pickup_location TEXT,
+    dropoff_location TEXT,
+    archived_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_ride_events_archive_trip ON ride_events_archive (trip_id, event_time);
@@ -0,0 +1,11 @@
+-- One row per erasure request. The erased ID itself is not kept; its SHA-256
+-- lets a repeated request be recognised.
+CREATE TABLE IF NOT EXISTS pii_erasures (
+    id BIGSERIAL PRIMARY KEY,
+    subject_type TEXT NOT NULL,
+    subject_hash TEXT NOT NULL,
+    pseudonym TEXT NOT NULL,
+    rows_affected BIGINT NOT NULL,
+    erased_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_pii_erasures_subject ON pii_erasures (subject_type, subject_hash);
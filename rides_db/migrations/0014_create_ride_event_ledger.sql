@@ -0,0 +1,18 @@
+-- ride_events is partitioned by event_time, so it cannot carry a unique index on
+-- id alone. The ledger holds every event ID ever stored, including archived
+-- ones, so a redelivery is recognised by its ID rather than its contents.
+CREATE TABLE IF NOT EXISTS ride_event_ledger (
+    event_id UUID PRIMARY KEY,
+    trip_id TEXT NOT NULL,
+    event_type VARCHAR(10) NOT NULL,
+    event_time TIMESTAMP NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+INSERT INTO ride_event_ledger (event_id, trip_id, event_type, event_time)
+SELECT id, trip_id, event_type, event_time FROM ride_events
+ON CONFLICT (event_id) DO NOTHING;
+
+INSERT INTO ride_event_ledger (event_id, trip_id, event_type, event_time)
+SELECT id, trip_id, event_type, event_time FROM ride_events_archive
+ON CONFLICT (event_id) DO NOTHING;
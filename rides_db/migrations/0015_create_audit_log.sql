@@ -0,0 +1,34 @@
+-- Append-only record of consumer write batches and applied migrations, so data
+-- lineage can be answered from the database. Rows for a kind leave the columns
+-- of the other kind NULL.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT now(),
+    topic TEXT,
+    partition INTEGER,
+    first_offset BIGINT,
+    last_offset BIGINT,
+    row_count BIGINT,
+    duration_seconds DOUBLE PRECISION,
+    migration_version INTEGER,
+    migration_name TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_kind ON audit_log (kind, recorded_at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
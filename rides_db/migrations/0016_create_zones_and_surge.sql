@@ -0,0 +1,15 @@
+-- Reference data maintained from SURGE_UPDATED events. Zone IDs are the pickup
+-- locations trips record, so a trip is priced under the latest multiplier of
+-- its pickup zone that took effect before it was requested.
+CREATE TABLE IF NOT EXISTS zones (
+    zone_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS surge_multipliers (
+    zone_id TEXT NOT NULL REFERENCES zones (zone_id),
+    effective_from TIMESTAMP NOT NULL,
+    multiplier NUMERIC(6, 2) NOT NULL CHECK (multiplier > 0),
+    PRIMARY KEY (zone_id, effective_from)
+);
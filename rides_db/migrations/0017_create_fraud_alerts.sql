@@ -0,0 +1,16 @@
+-- Alerts about rides that look wrong, from the fraud-alerts topic. Alert IDs
+-- are unique, so a redelivered alert is stored once. Fares are in dollars, as
+-- in rides and trips, and only set on fare alerts.
+CREATE TABLE IF NOT EXISTS fraud_alerts (
+    alert_id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    trip_id TEXT NOT NULL,
+    driver_id TEXT,
+    city TEXT,
+    detected_at TIMESTAMP NOT NULL,
+    detail TEXT NOT NULL,
+    fare_usd NUMERIC(10, 2),
+    expected_fare_usd NUMERIC(10, 2)
+);
+CREATE INDEX IF NOT EXISTS idx_fraud_alerts_detected_at ON fraud_alerts (detected_at);
+CREATE INDEX IF NOT EXISTS idx_fraud_alerts_driver ON fraud_alerts (driver_id, detected_at);
@@ -0,0 +1,12 @@
+-- Demand and supply per pricing zone and time bucket, from the heatmap
+-- builder. Counts only grow, so a builder that restarts mid-bucket and counts
+-- it again from where it resumed does not lower them.
+CREATE TABLE IF NOT EXISTS heatmap_cells (
+    zone_id TEXT NOT NULL,
+    bucket_start TIMESTAMP NOT NULL,
+    bucket_end TIMESTAMP NOT NULL,
+    pickups BIGINT NOT NULL,
+    drivers BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (bucket_start, zone_id)
+);
@@ -0,0 +1,18 @@
+-- The notifier's deliveries: one row per notification and channel, with its
+-- latest status. A sent delivery is final, so a redelivered event retried on
+-- a channel that already sent it does not turn it back to failed.
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    notification_id TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    trip_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (notification_id, channel)
+);
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_trip ON notification_deliveries (trip_id);
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_status ON notification_deliveries (status, updated_at);
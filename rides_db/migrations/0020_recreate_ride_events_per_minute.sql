@@ -0,0 +1,5 @@
+-- ride_events_per_minute summed payload->>'fare_usd', which payloads stopped
+-- carrying when fares became Money objects. CREATE MATERIALIZED VIEW IF NOT
+-- EXISTS never replaces it, so drop it here and let SetupTimescale recreate it
+-- over the fare_total column.
+DROP MATERIALIZED VIEW IF EXISTS ride_events_per_minute;
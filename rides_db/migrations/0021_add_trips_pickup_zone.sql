@@ -0,0 +1,17 @@
+-- Surge multipliers are keyed by pricing zone (pricing.RequestZone): the grid
+-- cell of the request's pickup coordinate, or its pickup location when it has
+-- none. Trips record that zone so that RevenueBySurge joins them on it.
+-- Existing trips take it from their REQUESTED event, with the cell computed as
+-- pricing.ZoneOf does.
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS pickup_zone TEXT;
+
+UPDATE trips t SET pickup_zone = COALESCE((
+    SELECT ROUND(FLOOR((e.payload->'pickup'->>'lat')::NUMERIC / 0.02 + 0.000000001) * 0.02, 2)::TEXT
+        || ',' || ROUND(FLOOR((e.payload->'pickup'->>'lng')::NUMERIC / 0.02 + 0.000000001) * 0.02, 2)::TEXT
+    FROM ride_events e
+    WHERE e.trip_id = t.trip_id AND e.event_type = 'REQUESTED'
+    LIMIT 1
+), t.pickup_location)
+WHERE pickup_zone IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_trips_pickup_zone ON trips (pickup_zone);
@@ -0,0 +1,19 @@
+-- Event types and ride states were sized for the original five, and cannot
+-- hold DRIVER_ARRIVED (14 characters) or PICKED_UP. Widening a VARCHAR does
+-- not rewrite the table or its indexes. ride_events_per_minute groups by
+-- event_type, which blocks the change, so drop it and let SetupTimescale
+-- recreate it. Compressed TimescaleDB chunks must be decompressed first.
+DROP MATERIALIZED VIEW IF EXISTS ride_events_per_minute;
+
+ALTER TABLE ride_events
+    ALTER COLUMN event_type TYPE VARCHAR(32),
+    ALTER COLUMN event_state TYPE VARCHAR(32);
+ALTER TABLE ride_events_archive
+    ALTER COLUMN event_type TYPE VARCHAR(32),
+    ALTER COLUMN event_state TYPE VARCHAR(32);
+ALTER TABLE ride_event_ledger ALTER COLUMN event_type TYPE VARCHAR(32);
+ALTER TABLE ride_event_windows ALTER COLUMN event_type TYPE VARCHAR(32);
+ALTER TABLE rides
+    ALTER COLUMN state TYPE VARCHAR(32),
+    ALTER COLUMN last_event_type TYPE VARCHAR(32);
+ALTER TABLE trips ALTER COLUMN final_state TYPE VARCHAR(32);
@@ -0,0 +1,86 @@
+-- MySQL/MariaDB counterpart of the PostgreSQL migrations up to 0009. Timestamps
+-- are stored in UTC with microsecond precision.
+CREATE TABLE IF NOT EXISTS ride_events (
+    id CHAR(36) PRIMARY KEY,
+    trip_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    event_state VARCHAR(32) NOT NULL,
+    event_time DATETIME(6) NOT NULL,
+    driver_id VARCHAR(64),
+    passenger_id VARCHAR(64),
+    payload JSON,
+    distance_km DOUBLE,
+    fare_total DOUBLE,
+    cancelled_by VARCHAR(32),
+    pickup_location VARCHAR(255),
+    dropoff_location VARCHAR(255),
+    UNIQUE KEY uq_ride_events_trip_type_time (trip_id, event_type, event_time),
+    KEY idx_trip_events (trip_id, event_time),
+    KEY idx_event_type (event_type),
+    KEY idx_passenger_id (passenger_id)
+);
+
+CREATE TABLE IF NOT EXISTS consumer_checkpoints (
+    consumer_group VARCHAR(255) NOT NULL,
+    topic VARCHAR(255) NOT NULL,
+    `partition` INT NOT NULL,
+    last_offset BIGINT NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (consumer_group, topic, `partition`)
+);
+
+CREATE TABLE IF NOT EXISTS ride_event_windows (
+    window_start DATETIME(6) NOT NULL,
+    window_end DATETIME(6) NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    event_count BIGINT NOT NULL,
+    fare_total DOUBLE NOT NULL DEFAULT 0,
+    is_correction BOOLEAN NOT NULL DEFAULT false,
+    updated_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (window_start, event_type)
+);
+
+CREATE TABLE IF NOT EXISTS trips (
+    trip_id VARCHAR(64) PRIMARY KEY,
+    passenger_id VARCHAR(64),
+    driver_id VARCHAR(64),
+    final_state VARCHAR(32) NOT NULL,
+    pickup_location VARCHAR(255),
+    dropoff_location VARCHAR(255),
+    requested_at DATETIME(6),
+    accepted_at DATETIME(6),
+    started_at DATETIME(6),
+    completed_at DATETIME(6),
+    cancelled_at DATETIME(6),
+    pickup_wait_seconds DOUBLE,
+    duration_seconds DOUBLE,
+    distance_km DOUBLE,
+    fare_usd DOUBLE,
+    cancelled_by VARCHAR(32),
+    cancel_reason TEXT,
+    accept_latency_seconds DOUBLE,
+    KEY idx_trips_requested_at (requested_at),
+    KEY idx_trips_driver_id (driver_id),
+    KEY idx_trips_final_state (final_state)
+);
+
+CREATE TABLE IF NOT EXISTS rides (
+    trip_id VARCHAR(64) PRIMARY KEY,
+    state VARCHAR(32) NOT NULL,
+    last_event_type VARCHAR(32) NOT NULL,
+    last_event_at DATETIME(6) NOT NULL,
+    driver_id VARCHAR(64),
+    passenger_id VARCHAR(64),
+    requested_at DATETIME(6),
+    accepted_at DATETIME(6),
+    started_at DATETIME(6),
+    ended_at DATETIME(6),
+    fare_usd DOUBLE,
+    pickup_lat DOUBLE,
+    pickup_lng DOUBLE,
+    dropoff_lat DOUBLE,
+    dropoff_lng DOUBLE,
+    KEY idx_rides_active (state, last_event_at),
+    KEY idx_rides_driver_id (driver_id),
+    KEY idx_rides_pickup (pickup_lat, pickup_lng)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS ride_outbox (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    message_key VARCHAR(255),
+    payload LONGBLOB NOT NULL,
+    headers JSON,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    published_at DATETIME(6),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    KEY idx_ride_outbox_pending (published_at, id)
+);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS ride_events_archive (
+    id CHAR(36) PRIMARY KEY,
+    trip_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    event_state VARCHAR(32) NOT NULL,
+    event_time DATETIME(6) NOT NULL,
+    driver_id VARCHAR(64),
+    passenger_id VARCHAR(64),
+    payload JSON,
+    distance_km DOUBLE,
+    fare_total DOUBLE,
+    cancelled_by VARCHAR(32),
+    pickup_location VARCHAR(255),
+    dropoff_location VARCHAR(255),
+    archived_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    KEY idx_ride_events_archive_trip (trip_id, event_time)
+);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    recorded_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    topic VARCHAR(255),
+    `partition` INT,
+    first_offset BIGINT,
+    last_offset BIGINT,
+    row_count BIGINT,
+    duration_seconds DOUBLE,
+    migration_version INT,
+    migration_name VARCHAR(255),
+    KEY idx_audit_log_kind (kind, recorded_at)
+);
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
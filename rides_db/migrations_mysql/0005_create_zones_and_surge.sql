@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS zones (
+    zone_id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    updated_at DATETIME(6) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS surge_multipliers (
+    zone_id VARCHAR(255) NOT NULL,
+    effective_from DATETIME(6) NOT NULL,
+    multiplier DECIMAL(6, 2) NOT NULL,
+    PRIMARY KEY (zone_id, effective_from),
+    CONSTRAINT fk_surge_multipliers_zone FOREIGN KEY (zone_id) REFERENCES zones (zone_id),
+    CONSTRAINT chk_surge_multipliers_positive CHECK (multiplier > 0)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS fraud_alerts (
+    alert_id VARCHAR(64) PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    trip_id VARCHAR(64) NOT NULL,
+    driver_id VARCHAR(64),
+    city VARCHAR(64),
+    detected_at DATETIME(6) NOT NULL,
+    detail TEXT NOT NULL,
+    fare_usd DECIMAL(10, 2),
+    expected_fare_usd DECIMAL(10, 2),
+    KEY idx_fraud_alerts_detected_at (detected_at),
+    KEY idx_fraud_alerts_driver (driver_id, detected_at)
+);
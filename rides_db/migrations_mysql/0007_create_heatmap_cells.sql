@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS heatmap_cells (
+    zone_id VARCHAR(64) NOT NULL,
+    bucket_start DATETIME(6) NOT NULL,
+    bucket_end DATETIME(6) NOT NULL,
+    pickups BIGINT NOT NULL,
+    drivers BIGINT NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (bucket_start, zone_id)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    notification_id VARCHAR(128) NOT NULL,
+    channel VARCHAR(32) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    trip_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT,
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (notification_id, channel),
+    KEY idx_notification_deliveries_trip (trip_id)
+);
@@ -0,0 +1,87 @@
+-- SQLite counterpart of the PostgreSQL migrations up to 0009, for local
+-- development and tests. Timestamps are stored in UTC.
+CREATE TABLE IF NOT EXISTS ride_events (
+    id TEXT PRIMARY KEY,
+    trip_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    event_state TEXT NOT NULL,
+    event_time TIMESTAMP NOT NULL,
+    driver_id TEXT,
+    passenger_id TEXT,
+    payload TEXT,
+    distance_km REAL,
+    fare_total REAL,
+    cancelled_by TEXT,
+    pickup_location TEXT,
+    dropoff_location TEXT,
+    UNIQUE (trip_id, event_type, event_time)
+);
+CREATE INDEX IF NOT EXISTS idx_trip_events ON ride_events (trip_id, event_time);
+CREATE INDEX IF NOT EXISTS idx_event_type ON ride_events (event_type);
+CREATE INDEX IF NOT EXISTS idx_passenger_id ON ride_events (passenger_id);
+
+CREATE TABLE IF NOT EXISTS consumer_checkpoints (
+    consumer_group TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    partition INTEGER NOT NULL,
+    last_offset INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (consumer_group, topic, partition)
+);
+
+CREATE TABLE IF NOT EXISTS ride_event_windows (
+    window_start TIMESTAMP NOT NULL,
+    window_end TIMESTAMP NOT NULL,
+    event_type TEXT NOT NULL,
+    event_count INTEGER NOT NULL,
+    fare_total REAL NOT NULL DEFAULT 0,
+    is_correction BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (window_start, event_type)
+);
+
+CREATE TABLE IF NOT EXISTS trips (
+    trip_id TEXT PRIMARY KEY,
+    passenger_id TEXT,
+    driver_id TEXT,
+    final_state TEXT NOT NULL,
+    pickup_location TEXT,
+    dropoff_location TEXT,
+    requested_at TIMESTAMP,
+    accepted_at TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    pickup_wait_seconds REAL,
+    duration_seconds REAL,
+    distance_km REAL,
+    fare_usd REAL,
+    cancelled_by TEXT,
+    cancel_reason TEXT,
+    accept_latency_seconds REAL
+);
+CREATE INDEX IF NOT EXISTS idx_trips_requested_at ON trips (requested_at);
+CREATE INDEX IF NOT EXISTS idx_trips_driver_id ON trips (driver_id);
+CREATE INDEX IF NOT EXISTS idx_trips_final_state ON trips (final_state);
+
+CREATE TABLE IF NOT EXISTS rides (
+    trip_id TEXT PRIMARY KEY,
+    state TEXT NOT NULL,
+    last_event_type TEXT NOT NULL,
+    last_event_at TIMESTAMP NOT NULL,
+    driver_id TEXT,
+    passenger_id TEXT,
+    requested_at TIMESTAMP,
+    accepted_at TIMESTAMP,
+    started_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    fare_usd REAL,
+    pickup_lat REAL,
+    pickup_lng REAL,
+    dropoff_lat REAL,
+    dropoff_lng REAL
+);
+CREATE INDEX IF NOT EXISTS idx_rides_active ON rides (last_event_at)
+    WHERE state IN ('REQUESTED', 'ACCEPTED', 'IN_PROGRESS');
+CREATE INDEX IF NOT EXISTS idx_rides_driver_id ON rides (driver_id);
+CREATE INDEX IF NOT EXISTS idx_rides_pickup ON rides (pickup_lat, pickup_lng);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS ride_outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    topic TEXT NOT NULL,
+    message_key TEXT,
+    payload BLOB NOT NULL,
+    headers TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_ride_outbox_pending ON ride_outbox (id) WHERE published_at IS NULL;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS ride_events_archive (
+    id TEXT PRIMARY KEY,
+    trip_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    event_state TEXT NOT NULL,
+    event_time TIMESTAMP NOT NULL,
+    driver_id TEXT,
+    passenger_id TEXT,
+    payload TEXT,
+    distance_km REAL,
+    fare_total REAL,
+    cancelled_by TEXT,
+    pickup_location TEXT,
+    dropoff_location TEXT,
+    archived_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_ride_events_archive_trip ON ride_events_archive (trip_id, event_time);
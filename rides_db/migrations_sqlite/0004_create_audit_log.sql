@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    topic TEXT,
+    partition INTEGER,
+    first_offset INTEGER,
+    last_offset INTEGER,
+    row_count INTEGER,
+    duration_seconds REAL,
+    migration_version INTEGER,
+    migration_name TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_kind ON audit_log (kind, recorded_at);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS zones (
+    zone_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS surge_multipliers (
+    zone_id TEXT NOT NULL REFERENCES zones (zone_id),
+    effective_from TIMESTAMP NOT NULL,
+    multiplier REAL NOT NULL CHECK (multiplier > 0),
+    PRIMARY KEY (zone_id, effective_from)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS fraud_alerts (
+    alert_id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    trip_id TEXT NOT NULL,
+    driver_id TEXT,
+    city TEXT,
+    detected_at TIMESTAMP NOT NULL,
+    detail TEXT NOT NULL,
+    fare_usd REAL,
+    expected_fare_usd REAL
+);
+CREATE INDEX IF NOT EXISTS idx_fraud_alerts_detected_at ON fraud_alerts (detected_at);
+CREATE INDEX IF NOT EXISTS idx_fraud_alerts_driver ON fraud_alerts (driver_id, detected_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS heatmap_cells (
+    zone_id TEXT NOT NULL,
+    bucket_start TIMESTAMP NOT NULL,
+    bucket_end TIMESTAMP NOT NULL,
+    pickups INTEGER NOT NULL,
+    drivers INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (bucket_start, zone_id)
+);
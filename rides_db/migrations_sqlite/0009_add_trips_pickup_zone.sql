@@ -0,0 +1,6 @@
+-- The pricing zone of the trip's request, which surge multipliers are keyed by
+-- (see pricing.RequestZone). Existing trips start from their pickup location;
+-- `rides rebuild` gives those requested with coordinates their grid cell.
+ALTER TABLE trips ADD COLUMN pickup_zone TEXT;
+UPDATE trips SET pickup_zone = pickup_location WHERE pickup_zone IS NULL;
+CREATE INDEX IF NOT EXISTS idx_trips_pickup_zone ON trips (pickup_zone);
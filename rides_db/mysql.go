@@ -0,0 +1,748 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// MySQLStore is a RideStore backed by MySQL 8 or MariaDB 10.5+. It keeps the
+// same schema and upsert rules as the PostgreSQL Store; partitioning,
+// TimescaleDB, and PostGIS are not available, and RidesWithinRadius filters in Go.
+type MySQLStore struct {
+	db           *sql.DB
+	q            querier
+	tx           *sql.Tx
+	queryTimeout time.Duration
+	retryPolicy  retryPolicy
+	cipher       *fieldCipher
+	slowQuery    time.Duration
+}
+
+var _ RideStore = (*MySQLStore)(nil)
+
+// mysqlMigrationLock names the GET_LOCK held while migrating.
+const mysqlMigrationLock = "rides_db_migrate"
+
+// mysqlPartition quotes the checkpoint column named after a reserved word.
+const mysqlPartition = "`partition`"
+
+// NewMySQL wraps an existing MySQL database handle, applying any pool options
+// given. The handle must have been opened with parseTime=true and loc=UTC.
+func NewMySQL(db *sql.DB, opts ...Option) *MySQLStore {
+	o := buildOptions(opts)
+	if len(opts) > 0 {
+		o.apply(db)
+	}
+	return newMySQLStore(db, o)
+}
+
+// OpenMySQL connects to MySQL using a go-sql-driver DSN such as
+// "user:password@tcp(mysql:3306)/rides", configures the pool, and verifies the
+// connection within the connect timeout. Times are always read and written in UTC.
+func OpenMySQL(dsn string, opts ...Option) (*MySQLStore, error) {
+	o := buildOptions(opts)
+
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	cfg.MultiStatements = true // migrations are multi-statement scripts
+	cfg.Timeout = o.ConnectTimeout
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+	o.apply(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.ConnectTimeout)
+	defer cancel()
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Println("✅ Connected to MySQL")
+	return newMySQLStore(db, o), nil
+}
+
+func newMySQLStore(db *sql.DB, o Options) *MySQLStore {
+	policy := o.retryPolicy()
+	return &MySQLStore{
+		db:           db,
+		q:            withSlowQueryLog(retryQuerier{db: db, policy: policy}, o.SlowQueryThreshold),
+		queryTimeout: o.QueryTimeout,
+		retryPolicy:  policy,
+		cipher:       newFieldCipher(o.FieldKeys),
+		slowQuery:    o.SlowQueryThreshold,
+	}
+}
+
+// DB returns the underlying database handle.
+func (s *MySQLStore) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the underlying database handle.
+func (s *MySQLStore) Close() error {
+	return s.db.Close()
+}
+
+// Migrate applies the embedded MySQL migrations, holding a named lock so
+// several consumers starting at once don't apply the same migration twice.
+// MySQL commits DDL implicitly, so a failed migration may be partly applied.
+func (s *MySQLStore) Migrate(ctx context.Context) error {
+	migrations, err := loadMigrations("migrations_mysql")
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, -1)`, mysqlMigrationLock).Scan(&locked); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	if locked.Int64 != 1 {
+		return errors.New("acquire migration lock: not granted")
+	}
+	defer conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, mysqlMigrationLock)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return applyPending(ctx, conn, migrations, `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, mysqlAuditInsertSQL)
+}
+
+// WithTx runs fn inside one transaction; see Store.WithTx.
+func (s *MySQLStore) WithTx(ctx context.Context, fn func(tx RideStore) error) error {
+	return s.retry(ctx, func() error {
+		return s.withTx(ctx, func(tx *MySQLStore) error { return fn(tx) })
+	})
+}
+
+func (s *MySQLStore) withTx(ctx context.Context, fn func(tx *MySQLStore) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txStore := &MySQLStore{
+		db: s.db, q: withSlowQueryLog(tx, s.slowQuery), tx: tx,
+		queryTimeout: s.queryTimeout, retryPolicy: s.retryPolicy, cipher: s.cipher, slowQuery: s.slowQuery,
+	}
+	if err := fn(txStore); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// retry runs fn with the store's retry policy, once inside a transaction.
+func (s *MySQLStore) retry(ctx context.Context, fn func() error) error {
+	if s.tx != nil {
+		return fn()
+	}
+	return s.retryPolicy.do(ctx, fn)
+}
+
+// mysqlInsertRideEventSQL skips redeliveries with a no-op update rather than
+// INSERT IGNORE, which would also swallow data errors.
+const mysqlInsertRideEventSQL = `
+	INSERT INTO ride_events
+	(id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload,
+	 distance_km, fare_total, cancelled_by, pickup_location, dropoff_location)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE id = id
+`
+
+// mysqlRideEventArgs binds the payload as text, since MySQL rejects binary
+// strings for JSON columns.
+func mysqlRideEventArgs(e events.RideEvent) ([]any, error) {
+	args, err := rideEventArgs(e)
+	if err != nil {
+		return nil, err
+	}
+	args[7] = string(args[7].([]byte))
+	return args, nil
+}
+
+// mysqlSeenEventSQL counts stored events matching an event's ID, trip, and type.
+const mysqlSeenEventSQL = `SELECT COUNT(*) FROM ride_events WHERE id = ? AND trip_id = ? AND event_type = ?`
+
+// InsertRideEvent stores e and reports what happened to it; see Store.InsertRideEvent.
+func (s *MySQLStore) InsertRideEvent(ctx context.Context, e events.RideEvent) (outcome InsertOutcome, err error) {
+	defer func(start time.Time) { observeInsert(start, outcome, err) }(time.Now())
+	e, err = s.cipher.encryptEvent(ctx, e)
+	if err != nil {
+		return "", err
+	}
+	args, err := mysqlRideEventArgs(e)
+	if err != nil {
+		return "", err
+	}
+	res, err := s.q.ExecContext(ctx, mysqlInsertRideEventSQL, args...)
+	if err != nil {
+		return "", err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return OutcomeInserted, err
+	}
+	return skippedEventOutcome(ctx, s.q, mysqlSeenEventSQL, e)
+}
+
+// InsertRideEvents stores evts in one transaction and returns how many rows were inserted.
+func (s *MySQLStore) InsertRideEvents(ctx context.Context, evts []events.RideEvent) (inserted int64, err error) {
+	defer func(start time.Time) { observeBatch(start, len(evts), inserted, err) }(time.Now())
+	evts, err = s.cipher.encryptEvents(ctx, evts)
+	if err != nil {
+		return 0, err
+	}
+	err = s.retry(ctx, func() error {
+		inserted = 0
+		return s.withTx(ctx, func(tx *MySQLStore) error {
+			for _, e := range evts {
+				args, err := mysqlRideEventArgs(e)
+				if err != nil {
+					return err
+				}
+				res, err := tx.q.ExecContext(ctx, mysqlInsertRideEventSQL, args...)
+				if err != nil {
+					return err
+				}
+				n, err := res.RowsAffected()
+				if err != nil {
+					return err
+				}
+				inserted += n
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// UpsertRideState folds e into the rides table; see Store.UpsertRideState.
+// MySQL applies the assignments left to right, so state and last_event_type
+// are compared against last_event_at before it is moved forward.
+func (s *MySQLStore) UpsertRideState(ctx context.Context, e events.RideEvent) error {
+	defer observeWrite("rides", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO rides
+		(trip_id, state, last_event_type, last_event_at, driver_id, passenger_id,
+		 requested_at, accepted_at, started_at, ended_at, fare_usd,
+		 pickup_lat, pickup_lng, dropoff_lat, dropoff_lng)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			state = IF(VALUES(last_event_at) >= last_event_at, VALUES(state), state),
+			last_event_type = IF(VALUES(last_event_at) >= last_event_at, VALUES(last_event_type), last_event_type),
+			last_event_at = GREATEST(last_event_at, VALUES(last_event_at)),
+			driver_id = COALESCE(VALUES(driver_id), driver_id),
+			passenger_id = COALESCE(VALUES(passenger_id), passenger_id),
+			requested_at = COALESCE(requested_at, VALUES(requested_at)),
+			accepted_at = COALESCE(accepted_at, VALUES(accepted_at)),
+			started_at = COALESCE(started_at, VALUES(started_at)),
+			ended_at = COALESCE(ended_at, VALUES(ended_at)),
+			fare_usd = COALESCE(VALUES(fare_usd), fare_usd),
+			pickup_lat = COALESCE(pickup_lat, VALUES(pickup_lat)),
+			pickup_lng = COALESCE(pickup_lng, VALUES(pickup_lng)),
+			dropoff_lat = COALESCE(dropoff_lat, VALUES(dropoff_lat)),
+			dropoff_lng = COALESCE(dropoff_lng, VALUES(dropoff_lng))
+	`, rideStateArgs(s.cipher.withoutCoordinates(e))...)
+	return err
+}
+
+// InsertTrip writes the assembled summary of a finished trip, replacing any earlier row.
+func (s *MySQLStore) InsertTrip(ctx context.Context, t aggregation.Trip) error {
+	defer observeWrite("trips", time.Now())
+	t, err := s.cipher.encryptTrip(ctx, t)
+	if err != nil {
+		return err
+	}
+	_, err = s.q.ExecContext(ctx, `
+		REPLACE INTO trips
+		(trip_id, passenger_id, driver_id, final_state, pickup_location, dropoff_location,
+		 requested_at, accepted_at, started_at, completed_at, cancelled_at,
+		 pickup_wait_seconds, duration_seconds, distance_km, fare_usd, cancelled_by, cancel_reason,
+		 accept_latency_seconds, pickup_zone)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, tripArgs(t)...)
+	return err
+}
+
+// RefreshTrip rebuilds the trips row for tripID by replaying its stored events
+// through a TripAssembler. Trips without a terminal event are left alone.
+func (s *MySQLStore) RefreshTrip(ctx context.Context, tripID string) error {
+	evts, err := s.GetTripEvents(ctx, tripID)
+	if err != nil {
+		return err
+	}
+	assembler := aggregation.NewTripAssembler()
+	for _, e := range evts {
+		if trip, done := assembler.Add(e); done {
+			return s.InsertTrip(ctx, trip)
+		}
+	}
+	return nil
+}
+
+// GetTrip returns the assembled summary of a finished trip, or ErrNotFound.
+func (s *MySQLStore) GetTrip(ctx context.Context, tripID string) (aggregation.Trip, error) {
+	var t aggregation.Trip
+	err := s.retry(ctx, func() error {
+		var err error
+		t, err = scanTrip(s.q.QueryRowContext(ctx, `SELECT `+tripColumns+` FROM trips WHERE trip_id = ?`, tripID))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return aggregation.Trip{}, ErrNotFound
+	}
+	if err != nil {
+		return aggregation.Trip{}, err
+	}
+	return s.cipher.decryptTrip(ctx, t)
+}
+
+// ListTrips returns finished trips matching f, newest request first.
+func (s *MySQLStore) ListTrips(ctx context.Context, f TripFilter) ([]aggregation.Trip, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if !f.From.IsZero() {
+		where, args = append(where, "requested_at >= ?"), append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		where, args = append(where, "requested_at < ?"), append(args, f.To)
+	}
+	if f.State != "" {
+		where, args = append(where, "final_state = ?"), append(args, f.State)
+	}
+	if f.DriverID != "" {
+		where, args = append(where, "driver_id = ?"), append(args, f.DriverID)
+	}
+
+	query := `SELECT ` + tripColumns + ` FROM trips`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	query += ` ORDER BY requested_at IS NULL, requested_at DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, f.Offset)
+
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trips []aggregation.Trip
+	for rows.Next() {
+		t, err := scanTrip(rows)
+		if err != nil {
+			return nil, err
+		}
+		trips = append(trips, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return s.cipher.decryptTrips(ctx, trips)
+}
+
+// GetTripEvents returns every stored event of a trip in event-time order.
+func (s *MySQLStore) GetTripEvents(ctx context.Context, tripID string) ([]events.RideEvent, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload
+		FROM ride_events
+		WHERE trip_id = ?
+		ORDER BY event_time, id
+	`, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var evts []events.RideEvent
+	for rows.Next() {
+		e, err := scanRideEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		if e, err = s.cipher.decryptEvent(ctx, e); err != nil {
+			return nil, err
+		}
+		evts = append(evts, e)
+	}
+	return evts, rows.Err()
+}
+
+// GetRide returns the current state of a trip, or ErrNotFound.
+func (s *MySQLStore) GetRide(ctx context.Context, tripID string) (Ride, error) {
+	var r Ride
+	err := s.retry(ctx, func() error {
+		var err error
+		r, err = scanRide(s.q.QueryRowContext(ctx, `SELECT `+rideColumns+` FROM rides WHERE trip_id = ?`, tripID))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Ride{}, ErrNotFound
+	}
+	return r, err
+}
+
+// ListActiveRides returns rides that have not completed or been cancelled,
+// most recently updated first.
+func (s *MySQLStore) ListActiveRides(ctx context.Context, limit, offset int) ([]Ride, error) {
+	return s.queryRides(ctx, `
+		SELECT `+rideColumns+`
+		FROM rides
+		WHERE state IN ('REQUESTED', 'ACCEPTED', 'IN_PROGRESS')
+		ORDER BY last_event_at DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+}
+
+// ListRidesByDriver returns the rides a driver accepted that were requested within tr.
+func (s *MySQLStore) ListRidesByDriver(ctx context.Context, driverID string, tr TimeRange) ([]Ride, error) {
+	from, to := nullTime(tr.From), nullTime(tr.To)
+	return s.queryRides(ctx, `
+		SELECT `+rideColumns+`
+		FROM rides
+		WHERE driver_id = ?
+		  AND (? IS NULL OR requested_at >= ?)
+		  AND (? IS NULL OR requested_at < ?)
+		ORDER BY requested_at DESC
+	`, driverID, from, from, to, to)
+}
+
+// RidesWithinRadius returns rides picked up within meters of (lat, lng),
+// nearest first, narrowing by bounding box in SQL and by distance in Go.
+func (s *MySQLStore) RidesWithinRadius(ctx context.Context, lat, lng, meters float64) ([]Ride, error) {
+	minLat, maxLat, minLng, maxLng := boundingBox(lat, lng, meters)
+	candidates, err := s.queryRides(ctx, `
+		SELECT `+rideColumns+`
+		FROM rides
+		WHERE pickup_lat BETWEEN ? AND ? AND pickup_lng BETWEEN ? AND ?
+	`, minLat, maxLat, minLng, maxLng)
+	if err != nil {
+		return nil, err
+	}
+	return nearestWithin(candidates, events.Coordinate{Lat: lat, Lng: lng}, meters), nil
+}
+
+func (s *MySQLStore) queryRides(ctx context.Context, query string, args ...any) ([]Ride, error) {
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rides []Ride
+	for rows.Next() {
+		r, err := scanRide(rows)
+		if err != nil {
+			return nil, err
+		}
+		rides = append(rides, r)
+	}
+	return rides, rows.Err()
+}
+
+// RevenueByDay sums completed-trip fares per UTC day of completion.
+func (s *MySQLStore) RevenueByDay(ctx context.Context, tr TimeRange) ([]DailyRevenue, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	from, to := nullTime(tr.From), nullTime(tr.To)
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT CAST(DATE(completed_at) AS DATETIME) AS day, COUNT(*), COALESCE(SUM(fare_usd), 0)
+		FROM trips
+		WHERE final_state = 'COMPLETED'
+		  AND (? IS NULL OR completed_at >= ?)
+		  AND (? IS NULL OR completed_at < ?)
+		GROUP BY day
+		ORDER BY day
+	`, from, from, to, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DailyRevenue
+	for rows.Next() {
+		var r DailyRevenue
+		if err := rows.Scan(&r.Day, &r.Trips, &r.RevenueUSD); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// CancellationRateByHour reports, per UTC hour of request time, the share of
+// trips that were cancelled.
+func (s *MySQLStore) CancellationRateByHour(ctx context.Context, tr TimeRange) ([]HourlyCancellationRate, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	from, to := nullTime(tr.From), nullTime(tr.To)
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT CAST(DATE_FORMAT(requested_at, '%Y-%m-%d %H:00:00') AS DATETIME) AS hour,
+		       COUNT(*),
+		       SUM(final_state = 'CANCELLED')
+		FROM trips
+		WHERE requested_at IS NOT NULL
+		  AND (? IS NULL OR requested_at >= ?)
+		  AND (? IS NULL OR requested_at < ?)
+		GROUP BY hour
+		ORDER BY hour
+	`, from, from, to, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []HourlyCancellationRate
+	for rows.Next() {
+		var r HourlyCancellationRate
+		if err := rows.Scan(&r.Hour, &r.Requested, &r.Cancelled); err != nil {
+			return nil, err
+		}
+		if r.Requested > 0 {
+			r.Rate = float64(r.Cancelled) / float64(r.Requested)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// AvgFareByZone averages completed-trip fares by pickup location, highest first.
+func (s *MySQLStore) AvgFareByZone(ctx context.Context, tr TimeRange) ([]ZoneFare, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	from, to := nullTime(tr.From), nullTime(tr.To)
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT pickup_location, COUNT(*), AVG(fare_usd) AS avg_fare
+		FROM trips
+		WHERE final_state = 'COMPLETED'
+		  AND pickup_location IS NOT NULL
+		  AND (? IS NULL OR completed_at >= ?)
+		  AND (? IS NULL OR completed_at < ?)
+		GROUP BY pickup_location
+		ORDER BY avg_fare DESC
+	`, from, from, to, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ZoneFare
+	for rows.Next() {
+		var z ZoneFare
+		if err := rows.Scan(&z.Zone, &z.Trips, &z.AvgFareUSD); err != nil {
+			return nil, err
+		}
+		out = append(out, z)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return s.cipher.decryptZones(ctx, out)
+}
+
+// TopDriversByTrips ranks drivers by completed trips, breaking ties on revenue.
+func (s *MySQLStore) TopDriversByTrips(ctx context.Context, tr TimeRange, limit int) ([]DriverTrips, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	from, to := nullTime(tr.From), nullTime(tr.To)
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT driver_id, COUNT(*) AS trip_count, COALESCE(SUM(fare_usd), 0) AS revenue
+		FROM trips
+		WHERE final_state = 'COMPLETED'
+		  AND driver_id IS NOT NULL
+		  AND (? IS NULL OR completed_at >= ?)
+		  AND (? IS NULL OR completed_at < ?)
+		GROUP BY driver_id
+		ORDER BY trip_count DESC, revenue DESC
+		LIMIT ?
+	`, from, from, to, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DriverTrips
+	for rows.Next() {
+		var d DriverTrips
+		if err := rows.Scan(&d.DriverID, &d.Trips, &d.RevenueUSD); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RevenueBySurge sums completed-trip fares by surge multiplier; see Store.RevenueBySurge.
+func (s *MySQLStore) RevenueBySurge(ctx context.Context, tr TimeRange) ([]SurgeRevenue, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	from, to := nullTime(tr.From), nullTime(tr.To)
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT multiplier, COUNT(*), COALESCE(SUM(fare_usd), 0)
+		FROM (
+			SELECT t.fare_usd,
+			       COALESCE((
+			           SELECT s.multiplier FROM surge_multipliers s
+			           WHERE s.zone_id = t.pickup_zone
+			             AND s.effective_from <= t.requested_at
+			           ORDER BY s.effective_from DESC
+			           LIMIT 1
+			       ), 1) AS multiplier
+			FROM trips t
+			WHERE t.final_state = 'COMPLETED'
+			  AND (? IS NULL OR t.completed_at >= ?)
+			  AND (? IS NULL OR t.completed_at < ?)
+		) surged
+		GROUP BY multiplier
+		ORDER BY multiplier
+	`, from, from, to, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SurgeRevenue
+	for rows.Next() {
+		var r SurgeRevenue
+		if err := rows.Scan(&r.Multiplier, &r.Trips, &r.RevenueUSD); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// UpsertEventWindow stores a windowed aggregate; see Store.UpsertEventWindow.
+func (s *MySQLStore) UpsertEventWindow(ctx context.Context, w aggregation.WindowResult) error {
+	defer observeWrite("ride_event_windows", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO ride_event_windows
+		(window_start, window_end, event_type, event_count, fare_total, is_correction, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			event_count = VALUES(event_count),
+			fare_total = VALUES(fare_total),
+			is_correction = is_correction OR VALUES(is_correction),
+			updated_at = VALUES(updated_at)
+	`, w.Start, w.End, w.EventType, w.Count, w.FareTotal.Float64(), w.IsCorrection, time.Now())
+	return err
+}
+
+// UpdateCheckpoint upserts the processing position for a partition; offsets
+// only move forward, so updated_at is compared before last_offset changes.
+func (s *MySQLStore) UpdateCheckpoint(ctx context.Context, cp Checkpoint) error {
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO consumer_checkpoints
+		(consumer_group, topic, `+mysqlPartition+`, last_offset, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			updated_at = IF(VALUES(last_offset) > last_offset, VALUES(updated_at), updated_at),
+			last_offset = GREATEST(last_offset, VALUES(last_offset))
+	`, cp.Group, cp.Topic, cp.Partition, cp.Offset, cp.UpdatedAt)
+	return err
+}
+
+// GetCheckpoints returns every checkpoint recorded for a consumer group.
+func (s *MySQLStore) GetCheckpoints(ctx context.Context, group string) ([]Checkpoint, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT consumer_group, topic, `+mysqlPartition+`, last_offset, updated_at
+		FROM consumer_checkpoints
+		WHERE consumer_group = ?
+		ORDER BY topic, `+mysqlPartition+`
+	`, group)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkpoints []Checkpoint
+	for rows.Next() {
+		var cp Checkpoint
+		if err := rows.Scan(&cp.Group, &cp.Topic, &cp.Partition, &cp.Offset, &cp.UpdatedAt); err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
+
+// SKIP LOCKED needs MySQL 8 or MariaDB 10.6.
+var mysqlOutboxSQL = outboxSQL{
+	enqueue: `INSERT INTO ride_outbox (topic, message_key, payload, headers) VALUES (?, ?, ?, ?)`,
+	pending: `
+		SELECT id, topic, message_key, payload, headers, created_at, attempts
+		FROM ride_outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`,
+	published: `UPDATE ride_outbox SET published_at = ? WHERE id = ?`,
+	failed:    `UPDATE ride_outbox SET attempts = attempts + 1, last_error = ? WHERE id = ?`,
+}
+
+// EnqueueOutbox writes m to the outbox; see Store.EnqueueOutbox.
+func (s *MySQLStore) EnqueueOutbox(ctx context.Context, m OutboxMessage) error {
+	return enqueueOutbox(ctx, s.q, mysqlOutboxSQL, m, true)
+}
+
+// ProcessOutbox publishes a batch of outbox messages; see Store.ProcessOutbox.
+func (s *MySQLStore) ProcessOutbox(ctx context.Context, limit int, publish func(OutboxMessage) error) (int, error) {
+	var batch outboxBatch
+	err := s.withTx(ctx, func(tx *MySQLStore) error {
+		var err error
+		batch, err = processOutbox(ctx, sqlOutbox{tx.q, mysqlOutboxSQL}, limit, publish)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return batch.published, batch.publishErr
+}
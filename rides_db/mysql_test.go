@@ -0,0 +1,101 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestMySQLStore_InsertRideEventsCountsNewRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := NewMySQL(db)
+	now := time.Now()
+	evts := []events.RideEvent{
+		{ID: "e1", TripID: "trip-1", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: now,
+			Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+		{ID: "e2", TripID: "trip-1", Type: events.EventTripStarted, State: events.StateInProgress, OccurredAt: now,
+			Payload: events.RideStartedPayload{StartTime: now}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO ride_events .* ON DUPLICATE KEY UPDATE id = id`).WillReturnResult(sqlmock.NewResult(0, 1))
+	// A redelivered event leaves the row untouched
+	mock.ExpectExec(`INSERT INTO ride_events .* ON DUPLICATE KEY UPDATE id = id`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	n, err := store.InsertRideEvents(context.Background(), evts)
+	if err != nil {
+		t.Fatalf("InsertRideEvents failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 inserted row, got %d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMySQLStore_UpsertRideState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := NewMySQL(db)
+	now := time.Now()
+	evt := events.RideEvent{
+		TripID:     "trip-1",
+		Type:       events.EventRideAccepted,
+		State:      events.StateAccepted,
+		OccurredAt: now,
+		DriverID:   "driver-1",
+		Payload:    events.RideAcceptedPayload{DriverID: "driver-1"},
+	}
+
+	mock.ExpectExec(`INSERT INTO rides .* ON DUPLICATE KEY UPDATE\s+state = IF`).
+		WithArgs("trip-1", events.StateAccepted, events.EventRideAccepted, now, "driver-1", nil,
+			nil, now, nil, nil, nil, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.UpsertRideState(context.Background(), evt); err != nil {
+		t.Errorf("UpsertRideState failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMySQLStore_GetCheckpointsQuotesPartition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := NewMySQL(db)
+	now := time.Now()
+	mock.ExpectQuery("SELECT consumer_group, topic, `partition`, last_offset").
+		WithArgs("ride-consumer-group").
+		WillReturnRows(sqlmock.NewRows([]string{"consumer_group", "topic", "partition", "last_offset", "updated_at"}).
+			AddRow("ride-consumer-group", "ride-events", 0, 42, now))
+
+	cps, err := store.GetCheckpoints(context.Background(), "ride-consumer-group")
+	if err != nil {
+		t.Fatalf("GetCheckpoints failed: %v", err)
+	}
+	if len(cps) != 1 || cps[0].Offset != 42 {
+		t.Errorf("unexpected checkpoints: %+v", cps)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// DeliveryStatus is where a notification stands on one channel.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending is a notification still being tried.
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySent is a notification the channel took. It is final.
+	DeliverySent DeliveryStatus = "sent"
+	// DeliveryFailed is a notification the channel refused on every attempt.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// NotificationDelivery is the delivery of one notification on one channel, as
+// the notifier tracks it.
+type NotificationDelivery struct {
+	NotificationID string
+	Channel        string
+	Recipient      string
+	TripID         string
+	EventType      string
+	Status         DeliveryStatus
+	Attempts       int
+	LastError      string // of the latest failed attempt, if any
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// UpsertNotificationDelivery stores the latest status of a delivery. A delivery
+// already sent stays sent, so retrying a redelivered event's notification on a
+// channel that took it does not mark it failed. CreatedAt and UpdatedAt are set
+// by the store.
+func (s *Store) UpsertNotificationDelivery(ctx context.Context, d NotificationDelivery) error {
+	defer observeWrite("notification_deliveries", time.Now())
+	return s.queries().UpsertNotificationDelivery(ctx, sqlcdb.UpsertNotificationDeliveryParams{
+		NotificationID: d.NotificationID,
+		Channel:        d.Channel,
+		Recipient:      d.Recipient,
+		TripID:         d.TripID,
+		EventType:      d.EventType,
+		Status:         string(d.Status),
+		Attempts:       int32(d.Attempts),
+		LastError:      nullString(d.LastError),
+	})
+}
+
+// UpsertNotificationDelivery stores a delivery; see
+// Store.UpsertNotificationDelivery.
+func (s *SQLiteStore) UpsertNotificationDelivery(ctx context.Context, d NotificationDelivery) error {
+	defer observeWrite("notification_deliveries", time.Now())
+	now := time.Now()
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO notification_deliveries
+		(notification_id, channel, recipient, trip_id, event_type, status, attempts, last_error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (notification_id, channel) DO UPDATE
+		SET status = excluded.status,
+		    attempts = excluded.attempts,
+		    last_error = excluded.last_error,
+		    updated_at = excluded.updated_at
+		WHERE notification_deliveries.status <> 'sent'
+	`, utcArgs([]any{d.NotificationID, d.Channel, d.Recipient, d.TripID, d.EventType,
+		string(d.Status), d.Attempts, nullString(d.LastError), now, now})...)
+	return err
+}
+
+// UpsertNotificationDelivery stores a delivery; see
+// Store.UpsertNotificationDelivery.
+func (s *MySQLStore) UpsertNotificationDelivery(ctx context.Context, d NotificationDelivery) error {
+	defer observeWrite("notification_deliveries", time.Now())
+	now := time.Now().UTC()
+	// MySQL assigns left to right, so status goes last for the others to see
+	// the stored one
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO notification_deliveries
+		(notification_id, channel, recipient, trip_id, event_type, status, attempts, last_error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			attempts = IF(status = 'sent', attempts, VALUES(attempts)),
+			last_error = IF(status = 'sent', last_error, VALUES(last_error)),
+			updated_at = IF(status = 'sent', updated_at, VALUES(updated_at)),
+			status = IF(status = 'sent', status, VALUES(status))
+	`, d.NotificationID, d.Channel, d.Recipient, d.TripID, d.EventType,
+		string(d.Status), d.Attempts, nullString(d.LastError), now, now)
+	return err
+}
+
+// ListNotificationDeliveries returns the deliveries of the notifications about
+// trip tripID, oldest first.
+func (s *Store) ListNotificationDeliveries(ctx context.Context, tripID string) ([]NotificationDelivery, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.readQueries(ctx).ListNotificationDeliveries(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]NotificationDelivery, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, NotificationDelivery{
+			NotificationID: row.NotificationID,
+			Channel:        row.Channel,
+			Recipient:      row.Recipient,
+			TripID:         row.TripID,
+			EventType:      row.EventType,
+			Status:         DeliveryStatus(row.Status),
+			Attempts:       int(row.Attempts),
+			LastError:      row.LastError.String,
+			CreatedAt:      row.CreatedAt.UTC(),
+			UpdatedAt:      row.UpdatedAt.UTC(),
+		})
+	}
+	return out, nil
+}
+
+// ListNotificationDeliveries returns a trip's deliveries; see
+// Store.ListNotificationDeliveries.
+func (s *SQLiteStore) ListNotificationDeliveries(ctx context.Context, tripID string) ([]NotificationDelivery, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, notificationDeliveriesSQL("$1"), tripID)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotificationDeliveries(rows)
+}
+
+// ListNotificationDeliveries returns a trip's deliveries; see
+// Store.ListNotificationDeliveries.
+func (s *MySQLStore) ListNotificationDeliveries(ctx context.Context, tripID string) ([]NotificationDelivery, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, notificationDeliveriesSQL("?"), tripID)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotificationDeliveries(rows)
+}
+
+// notificationDeliveriesSQL is ListNotificationDeliveries in
+// queries/notifications.sql, with the trip ID as placeholder.
+func notificationDeliveriesSQL(placeholder string) string {
+	return `
+		SELECT notification_id, channel, recipient, trip_id, event_type, status, attempts, last_error, created_at, updated_at
+		FROM notification_deliveries
+		WHERE trip_id = ` + placeholder + `
+		ORDER BY created_at, notification_id, channel
+	`
+}
+
+// scanNotificationDeliveries reads the rows of ListNotificationDeliveries, for
+// the backends that do not use the generated queries.
+func scanNotificationDeliveries(rows *sql.Rows) ([]NotificationDelivery, error) {
+	defer rows.Close()
+	var out []NotificationDelivery
+	for rows.Next() {
+		var (
+			d         NotificationDelivery
+			lastError sql.NullString
+		)
+		if err := rows.Scan(&d.NotificationID, &d.Channel, &d.Recipient, &d.TripID, &d.EventType,
+			&d.Status, &d.Attempts, &lastError, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		d.LastError = lastError.String
+		d.CreatedAt, d.UpdatedAt = d.CreatedAt.UTC(), d.UpdatedAt.UTC()
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,42 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSQLiteStore_NotificationDeliveries(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	delivery := func(id, channel string, status DeliveryStatus, attempts int, lastError string) NotificationDelivery {
+		return NotificationDelivery{NotificationID: id, Channel: channel, Recipient: "rider-1", TripID: "trip-1",
+			EventType: "ACCEPTED", Status: status, Attempts: attempts, LastError: lastError}
+	}
+	for _, d := range []NotificationDelivery{
+		delivery("evt-1-rider", "webhook", DeliveryPending, 1, "503 Service Unavailable"),
+		delivery("evt-1-rider", "webhook", DeliverySent, 2, ""),
+		// A redelivered event's retry fails, but the delivery was already sent
+		delivery("evt-1-rider", "webhook", DeliveryFailed, 3, "timeout"),
+		delivery("evt-1-rider", "log", DeliverySent, 1, ""),
+		{NotificationID: "evt-2-rider", Channel: "log", TripID: "trip-2", Status: DeliverySent, Attempts: 1},
+	} {
+		if err := store.UpsertNotificationDelivery(ctx, d); err != nil {
+			t.Fatalf("UpsertNotificationDelivery failed: %v", err)
+		}
+	}
+
+	got, err := store.ListNotificationDeliveries(ctx, "trip-1")
+	if err != nil {
+		t.Fatalf("ListNotificationDeliveries failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("deliveries: %+v", got)
+	}
+	byChannel := map[string]NotificationDelivery{got[0].Channel: got[0], got[1].Channel: got[1]}
+	if d := byChannel["webhook"]; d.Status != DeliverySent || d.Attempts != 2 || d.LastError != "" || d.CreatedAt.IsZero() {
+		t.Errorf("webhook delivery %+v, want sent on the second attempt", d)
+	}
+	if d := byChannel["log"]; d.Status != DeliverySent || d.Recipient != "rider-1" {
+		t.Errorf("log delivery %+v", d)
+	}
+}
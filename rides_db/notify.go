@@ -0,0 +1,74 @@
+package rides_db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// RideStateChannel is the Postgres NOTIFY channel a trigger on the rides table
+// publishes RideStateChange payloads to whenever a ride is created or changes state.
+const RideStateChannel = "ride_state_changed"
+
+// RideStateChange is the payload of a ride_state_changed notification.
+// PreviousState is empty for a newly seen ride.
+type RideStateChange struct {
+	TripID        string               `json:"trip_id"`
+	State         events.RideState     `json:"state"`
+	PreviousState events.RideState     `json:"previous_state,omitempty"`
+	EventType     events.RideEventType `json:"event_type"`
+	EventTime     time.Time            `json:"event_time"`
+	DriverID      string               `json:"driver_id,omitempty"`
+}
+
+// ListenRideStateChanges holds one pooled connection listening on
+// RideStateChannel and calls fn for every notification until ctx is cancelled,
+// returning nil, or the connection fails, returning the error. It is meant for
+// lightweight in-process subscribers; notifications sent while nobody is
+// listening are lost, so anything that must see every change should consume Kafka.
+func (s *Store) ListenRideStateChanges(ctx context.Context, fn func(RideStateChange)) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn any) error {
+		pgConn := driverConn.(*stdlib.Conn).Conn()
+		if _, err := pgConn.Exec(ctx, "LISTEN "+RideStateChannel); err != nil {
+			return err
+		}
+		defer pgConn.Exec(context.Background(), "UNLISTEN "+RideStateChannel)
+
+		for {
+			n, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			change, err := decodeRideStateChange(n.Payload)
+			if err != nil {
+				slog.Warn("Ignoring malformed ride state notification", "payload", n.Payload, "error", err)
+				continue
+			}
+			fn(change)
+		}
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func decodeRideStateChange(payload string) (RideStateChange, error) {
+	var change RideStateChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return change, fmt.Errorf("decode %s payload: %w", RideStateChannel, err)
+	}
+	return change, nil
+}
@@ -0,0 +1,47 @@
+package rides_db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestDecodeRideStateChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    RideStateChange
+		wantErr bool
+	}{
+		{
+			name:    "new ride",
+			payload: `{"trip_id":"trip-1","state":"REQUESTED","previous_state":null,"event_type":"REQUESTED","event_time":"2025-01-01T08:00:00+00:00","driver_id":null}`,
+			want: RideStateChange{TripID: "trip-1", State: events.StateRequested, EventType: events.EventRideRequested,
+				EventTime: time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "state change",
+			payload: `{"trip_id":"trip-1","state":"ACCEPTED","previous_state":"REQUESTED","event_type":"ACCEPTED","event_time":"2025-01-01T08:01:00.5+00:00","driver_id":"driver-1"}`,
+			want: RideStateChange{TripID: "trip-1", State: events.StateAccepted, PreviousState: events.StateRequested,
+				EventType: events.EventRideAccepted, EventTime: time.Date(2025, 1, 1, 8, 1, 0, 5e8, time.UTC), DriverID: "driver-1"},
+		},
+		{name: "malformed", payload: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeRideStateChange(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeRideStateChange error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.TripID != tt.want.TripID || got.State != tt.want.State || got.PreviousState != tt.want.PreviousState ||
+				got.EventType != tt.want.EventType || !got.EventTime.Equal(tt.want.EventTime) || got.DriverID != tt.want.DriverID {
+				t.Errorf("decodeRideStateChange = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
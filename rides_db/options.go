@@ -0,0 +1,222 @@
+package rides_db
+
+import (
+	"database/sql"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options tune the connection pool, how long Open waits for the database, how
+// long analytics queries may run, which statements are logged as slow, how
+// transient errors are retried, which keys encrypt sensitive fields, which
+// read replicas serve queries, and which schema the store works in: a city's,
+// or one named for an isolated copy such as a replay.
+type Options struct {
+	MaxOpenConns       int
+	MaxIdleConns       int
+	ConnMaxLifetime    time.Duration
+	ConnMaxIdleTime    time.Duration
+	ConnectTimeout     time.Duration
+	QueryTimeout       time.Duration
+	SlowQueryThreshold time.Duration
+	RetryAttempts      int
+	RetryBackoff       time.Duration
+	RetryMaxBackoff    time.Duration
+	FieldKeys          KeyProvider
+	ReplicaDSNs        []string
+	ReplicaMaxLag      time.Duration
+	City               string
+	Schema             string
+}
+
+// Option changes a single pool setting.
+type Option func(*Options)
+
+// DefaultOptions suit a single consumer instance writing one event at a time.
+func DefaultOptions() Options {
+	return Options{
+		MaxOpenConns:       10,
+		MaxIdleConns:       5,
+		ConnMaxLifetime:    30 * time.Minute,
+		ConnMaxIdleTime:    5 * time.Minute,
+		ConnectTimeout:     5 * time.Second,
+		QueryTimeout:       30 * time.Second,
+		SlowQueryThreshold: time.Second,
+		RetryAttempts:      3,
+		RetryBackoff:       50 * time.Millisecond,
+		RetryMaxBackoff:    2 * time.Second,
+		ReplicaMaxLag:      5 * time.Second,
+	}
+}
+
+// WithMaxOpenConns caps the number of open connections; 0 means unlimited.
+func WithMaxOpenConns(n int) Option { return func(o *Options) { o.MaxOpenConns = n } }
+
+// WithMaxIdleConns sets how many idle connections are kept for reuse.
+func WithMaxIdleConns(n int) Option { return func(o *Options) { o.MaxIdleConns = n } }
+
+// WithConnMaxLifetime recycles connections older than d.
+func WithConnMaxLifetime(d time.Duration) Option { return func(o *Options) { o.ConnMaxLifetime = d } }
+
+// WithConnMaxIdleTime closes connections that have been idle longer than d.
+func WithConnMaxIdleTime(d time.Duration) Option { return func(o *Options) { o.ConnMaxIdleTime = d } }
+
+// WithConnectTimeout bounds how long Open waits for the first successful ping.
+func WithConnectTimeout(d time.Duration) Option { return func(o *Options) { o.ConnectTimeout = d } }
+
+// WithQueryTimeout bounds each analytics query; 0 leaves only the caller's deadline.
+func WithQueryTimeout(d time.Duration) Option { return func(o *Options) { o.QueryTimeout = d } }
+
+// WithSlowQueryThreshold logs every statement taking at least d as slow; 0
+// disables the log.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(o *Options) { o.SlowQueryThreshold = d }
+}
+
+// WithRetry sets how many times an operation is attempted when it fails with a
+// transient error (see IsTransient) and the initial and maximum backoff between
+// attempts. attempts <= 1 disables retries.
+func WithRetry(attempts int, backoff, maxBackoff time.Duration) Option {
+	return func(o *Options) {
+		o.RetryAttempts = attempts
+		o.RetryBackoff = backoff
+		o.RetryMaxBackoff = maxBackoff
+	}
+}
+
+// WithFieldEncryption encrypts passenger names, pickup and dropoff locations
+// and coordinates, and pickup zones with AES-GCM before they are stored, using
+// keys from kp, and decrypts them in the query methods. The rides table then
+// holds no coordinates, so radius queries and the dashboard map find no rides.
+func WithFieldEncryption(kp KeyProvider) Option { return func(o *Options) { o.FieldKeys = kp } }
+
+// WithReplicas has Open connect to the PostgreSQL read replicas at dsns, each
+// with the same pool settings as the primary. Query API reads (trips, rides,
+// trip events, and analytics) are spread across the replicas that are within
+// the tolerated lag and fall back to the primary; writes, transactions, and the
+// consumer's reads always use the primary.
+func WithReplicas(dsns ...string) Option { return func(o *Options) { o.ReplicaDSNs = dsns } }
+
+// WithReplicaMaxLag sets how far behind the primary a replica may be and still
+// serve reads. WithMaxStaleness and ReadFromPrimary override it per call.
+func WithReplicaMaxLag(d time.Duration) Option { return func(o *Options) { o.ReplicaMaxLag = d } }
+
+// WithCity has Open put the store in the PostgreSQL schema of city (see
+// CitySchema), creating the schema on Migrate. Every table the store touches,
+// including schema_migrations, then lives in that schema.
+func WithCity(city string) Option { return func(o *Options) { o.City = city } }
+
+// WithSchema has Open put the store in the PostgreSQL schema named schema,
+// which must be a lower-case identifier other than public, creating it on
+// Migrate. It keeps a full copy of the tables apart from the live ones, as a
+// replay does; it cannot be combined with WithCity.
+func WithSchema(schema string) Option { return func(o *Options) { o.Schema = schema } }
+
+// OptionsFromEnv reads settings from DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS,
+// DB_CONN_MAX_LIFETIME, DB_CONN_MAX_IDLE_TIME, DB_CONNECT_TIMEOUT,
+// DB_QUERY_TIMEOUT, DB_SLOW_QUERY_THRESHOLD, DB_RETRY_ATTEMPTS, DB_RETRY_BACKOFF, DB_RETRY_MAX_BACKOFF,
+// DB_REPLICA_DSNS (comma-separated), DB_REPLICA_MAX_LAG, and
+// FIELD_ENCRYPTION_KEYS (see KeysFromEnv). Durations use Go syntax such as
+// "30m". Unset or invalid values keep the defaults, except that invalid
+// encryption keys make every write fail rather than store plaintext.
+func OptionsFromEnv() []Option {
+	var opts []Option
+	if n, ok := envInt("DB_MAX_OPEN_CONNS"); ok {
+		opts = append(opts, WithMaxOpenConns(n))
+	}
+	if n, ok := envInt("DB_MAX_IDLE_CONNS"); ok {
+		opts = append(opts, WithMaxIdleConns(n))
+	}
+	if d, ok := envDuration("DB_CONN_MAX_LIFETIME"); ok {
+		opts = append(opts, WithConnMaxLifetime(d))
+	}
+	if d, ok := envDuration("DB_CONN_MAX_IDLE_TIME"); ok {
+		opts = append(opts, WithConnMaxIdleTime(d))
+	}
+	if d, ok := envDuration("DB_CONNECT_TIMEOUT"); ok {
+		opts = append(opts, WithConnectTimeout(d))
+	}
+	if d, ok := envDuration("DB_QUERY_TIMEOUT"); ok {
+		opts = append(opts, WithQueryTimeout(d))
+	}
+	if d, ok := envDuration("DB_SLOW_QUERY_THRESHOLD"); ok {
+		opts = append(opts, WithSlowQueryThreshold(d))
+	}
+	if n, ok := envInt("DB_RETRY_ATTEMPTS"); ok {
+		opts = append(opts, func(o *Options) { o.RetryAttempts = n })
+	}
+	if d, ok := envDuration("DB_RETRY_BACKOFF"); ok {
+		opts = append(opts, func(o *Options) { o.RetryBackoff = d })
+	}
+	if d, ok := envDuration("DB_RETRY_MAX_BACKOFF"); ok {
+		opts = append(opts, func(o *Options) { o.RetryMaxBackoff = d })
+	}
+	if raw := os.Getenv("DB_REPLICA_DSNS"); raw != "" {
+		var dsns []string
+		for _, dsn := range strings.Split(raw, ",") {
+			if dsn = strings.TrimSpace(dsn); dsn != "" {
+				dsns = append(dsns, dsn)
+			}
+		}
+		opts = append(opts, WithReplicas(dsns...))
+	}
+	if d, ok := envDuration("DB_REPLICA_MAX_LAG"); ok {
+		opts = append(opts, WithReplicaMaxLag(d))
+	}
+	keys, err := KeysFromEnv()
+	if err != nil {
+		slog.Error("Invalid field encryption keys", "error", err)
+		opts = append(opts, WithFieldEncryption(failingKeys{err}))
+	} else if keys != nil {
+		opts = append(opts, WithFieldEncryption(keys))
+	}
+	return opts
+}
+
+func (o Options) apply(db *sql.DB) {
+	db.SetMaxOpenConns(o.MaxOpenConns)
+	db.SetMaxIdleConns(o.MaxIdleConns)
+	db.SetConnMaxLifetime(o.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+}
+
+func (o Options) retryPolicy() retryPolicy {
+	return retryPolicy{attempts: o.RetryAttempts, backoff: o.RetryBackoff, maxBackoff: o.RetryMaxBackoff}
+}
+
+func buildOptions(opts []Option) Options {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func envInt(key string) (int, bool) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		slog.Warn("Ignoring invalid integer setting", "key", key, "value", raw)
+		return 0, false
+	}
+	return n, true
+}
+
+func envDuration(key string) (time.Duration, bool) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		slog.Warn("Ignoring invalid duration setting", "key", key, "value", raw)
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,206 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// OutboxMessage is a Kafka message written to ride_outbox in the same
+// transaction as the change it announces, and published later by a relay.
+type OutboxMessage struct {
+	ID        int64
+	Topic     string
+	Key       string
+	Payload   []byte
+	Headers   map[string]string
+	CreatedAt time.Time
+	Attempts  int
+}
+
+// outboxSQL holds the statements SQLite and MySQL use for the outbox; the
+// Store runs the generated queries in queries/outbox.sql.
+type outboxSQL struct {
+	enqueue   string // topic, key, payload, headers
+	pending   string // limit; must lock the rows it returns where the backend can
+	published string // published_at, id
+	failed    string // last_error, id
+}
+
+// EnqueueOutbox writes m to the outbox. Call it on the store passed to WithTx so
+// the message is only published if the surrounding writes commit.
+func (s *Store) EnqueueOutbox(ctx context.Context, m OutboxMessage) error {
+	headers, err := outboxHeaders(m)
+	if err != nil {
+		return err
+	}
+	return s.queries().EnqueueOutbox(ctx, sqlcdb.EnqueueOutboxParams{
+		Topic:      m.Topic,
+		MessageKey: nullString(m.Key),
+		Payload:    m.Payload,
+		Headers:    headers,
+	})
+}
+
+// ProcessOutbox publishes up to limit unpublished messages in insertion order
+// and marks them published, returning how many were. Rows are locked with SKIP
+// LOCKED, so several relays can run at once without publishing a message
+// twice. The first failed publish is recorded on its row and returned, and
+// the messages after it wait for the next call so their order is kept.
+func (s *Store) ProcessOutbox(ctx context.Context, limit int, publish func(OutboxMessage) error) (int, error) {
+	var batch outboxBatch
+	err := s.withTx(ctx, func(tx *Store) error {
+		var err error
+		batch, err = processOutbox(ctx, pgOutbox{tx.queries()}, limit, publish)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return batch.published, batch.publishErr
+}
+
+// enqueueOutbox inserts m; textHeaders binds the headers JSON as a string for
+// backends that reject bytes in JSON columns.
+func enqueueOutbox(ctx context.Context, q querier, stmts outboxSQL, m OutboxMessage, textHeaders bool) error {
+	raw, err := outboxHeaders(m)
+	if err != nil {
+		return err
+	}
+	var headers any
+	if raw != nil {
+		headers = []byte(raw)
+		if textHeaders {
+			headers = string(raw)
+		}
+	}
+	_, err = q.ExecContext(ctx, stmts.enqueue, m.Topic, nullString(m.Key), m.Payload, headers)
+	return err
+}
+
+// outboxHeaders returns the headers of m as JSON, or nil without any.
+func outboxHeaders(m OutboxMessage) (json.RawMessage, error) {
+	if len(m.Headers) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(m.Headers)
+}
+
+// outboxBatch is the outcome of one relay batch. A failed publish still
+// commits, so the attempt is recorded alongside the messages sent before it.
+type outboxBatch struct {
+	published  int
+	publishErr error
+}
+
+// outboxQueries runs the statements of a relay batch on one backend.
+type outboxQueries interface {
+	pending(ctx context.Context, limit int) ([]OutboxMessage, error)
+	published(ctx context.Context, id int64) error
+	failed(ctx context.Context, id int64, lastError string) error
+}
+
+// processOutbox runs one relay batch on q, which must be in a transaction.
+func processOutbox(ctx context.Context, q outboxQueries, limit int, publish func(OutboxMessage) error) (outboxBatch, error) {
+	var batch outboxBatch
+	pending, err := q.pending(ctx, limit)
+	if err != nil {
+		return batch, err
+	}
+
+	for _, m := range pending {
+		if err := publish(m); err != nil {
+			batch.publishErr = err
+			return batch, q.failed(ctx, m.ID, err.Error())
+		}
+		if err := q.published(ctx, m.ID); err != nil {
+			return batch, err
+		}
+		batch.published++
+	}
+	return batch, nil
+}
+
+// pgOutbox runs a relay batch with the generated queries.
+type pgOutbox struct {
+	q *sqlcdb.Queries
+}
+
+func (o pgOutbox) pending(ctx context.Context, limit int) ([]OutboxMessage, error) {
+	rows, err := o.q.ListPendingOutbox(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]OutboxMessage, 0, len(rows))
+	for _, row := range rows {
+		m := OutboxMessage{
+			ID:        row.ID,
+			Topic:     row.Topic,
+			Key:       row.MessageKey.String,
+			Payload:   row.Payload,
+			CreatedAt: row.CreatedAt,
+			Attempts:  int(row.Attempts),
+		}
+		if err := json.Unmarshal(row.Headers, &m.Headers); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+func (o pgOutbox) published(ctx context.Context, id int64) error {
+	return o.q.MarkOutboxPublished(ctx, sqlcdb.MarkOutboxPublishedParams{PublishedAt: time.Now().UTC(), ID: id})
+}
+
+func (o pgOutbox) failed(ctx context.Context, id int64, lastError string) error {
+	return o.q.MarkOutboxFailed(ctx, sqlcdb.MarkOutboxFailedParams{LastError: lastError, ID: id})
+}
+
+// sqlOutbox runs a relay batch with the statements of a backend sqlc does not
+// generate queries for.
+type sqlOutbox struct {
+	q     querier
+	stmts outboxSQL
+}
+
+func (o sqlOutbox) pending(ctx context.Context, limit int) ([]OutboxMessage, error) {
+	rows, err := o.q.QueryContext(ctx, o.stmts.pending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []OutboxMessage
+	for rows.Next() {
+		var (
+			m       OutboxMessage
+			key     sql.NullString
+			headers []byte
+		)
+		if err := rows.Scan(&m.ID, &m.Topic, &key, &m.Payload, &headers, &m.CreatedAt, &m.Attempts); err != nil {
+			return nil, err
+		}
+		m.Key = key.String
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &m.Headers); err != nil {
+				return nil, err
+			}
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+func (o sqlOutbox) published(ctx context.Context, id int64) error {
+	_, err := o.q.ExecContext(ctx, o.stmts.published, time.Now().UTC(), id)
+	return err
+}
+
+func (o sqlOutbox) failed(ctx context.Context, id int64, lastError string) error {
+	_, err := o.q.ExecContext(ctx, o.stmts.failed, lastError, id)
+	return err
+}
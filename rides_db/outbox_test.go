@@ -0,0 +1,51 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestProcessOutbox_RecordsFailureAndCommits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	now := time.Now()
+	down := errors.New("broker down")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM ride_outbox .* FOR UPDATE SKIP LOCKED`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "message_key", "payload", "headers", "created_at", "attempts"}).
+			AddRow(1, "ride-events", "trip-1", []byte("{}"), []byte("null"), now, 0).
+			AddRow(2, "ride-events", "trip-2", []byte("{}"), []byte(`{"source":"api"}`), now, 0).
+			AddRow(3, "ride-events", "trip-3", []byte("{}"), []byte("null"), now, 0))
+	mock.ExpectExec(`UPDATE ride_outbox SET published_at`).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE ride_outbox SET attempts = attempts \+ 1`).WithArgs("broker down", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var keys []string
+	n, err := store.ProcessOutbox(context.Background(), 10, func(m OutboxMessage) error {
+		keys = append(keys, m.Key)
+		if m.Headers["source"] == "api" {
+			return down
+		}
+		return nil
+	})
+	if !errors.Is(err, down) || n != 1 {
+		t.Errorf("expected 1 published and the publish error, got n=%d err=%v", n, err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("messages after a failure should wait for the next batch, published %v", keys)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
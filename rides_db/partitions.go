@@ -0,0 +1,230 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// PartitionInterval is the span of event_time covered by one ride_events partition.
+type PartitionInterval string
+
+const (
+	PartitionDaily   PartitionInterval = "daily"
+	PartitionMonthly PartitionInterval = "monthly"
+)
+
+const partitionPrefix = "ride_events_p"
+
+// PartitionPolicy controls which dated ride_events partitions MaintainPartitions
+// keeps. A zero Retention keeps every partition.
+type PartitionPolicy struct {
+	Interval  PartitionInterval
+	Premake   int
+	Retention time.Duration
+}
+
+// DefaultPartitionPolicy creates daily partitions a week ahead and never drops data.
+func DefaultPartitionPolicy() PartitionPolicy {
+	return PartitionPolicy{Interval: PartitionDaily, Premake: 7}
+}
+
+// PartitionPolicyFromEnv reads RIDE_EVENTS_PARTITION_INTERVAL ("daily" or
+// "monthly"), RIDE_EVENTS_PARTITION_PREMAKE, and RIDE_EVENTS_RETENTION_DAYS.
+// Unset or invalid values keep the defaults.
+func PartitionPolicyFromEnv() PartitionPolicy {
+	p := DefaultPartitionPolicy()
+	switch iv := PartitionInterval(os.Getenv("RIDE_EVENTS_PARTITION_INTERVAL")); iv {
+	case "":
+	case PartitionDaily, PartitionMonthly:
+		p.Interval = iv
+	default:
+		slog.Warn("Ignoring invalid partition interval", "value", iv)
+	}
+	if n, ok := envInt("RIDE_EVENTS_PARTITION_PREMAKE"); ok {
+		p.Premake = n
+	}
+	if n, ok := envInt("RIDE_EVENTS_RETENTION_DAYS"); ok {
+		p.Retention = time.Duration(n) * 24 * time.Hour
+	}
+	return p
+}
+
+// Partition is one dated ride_events partition covering [From, To).
+type Partition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// PartitionReport lists the partitions a MaintainPartitions run changed.
+type PartitionReport struct {
+	Created []string
+	Dropped []string
+}
+
+// partitionFor returns the partition of interval iv that contains t.
+func partitionFor(iv PartitionInterval, t time.Time) Partition {
+	t = t.UTC()
+	if iv == PartitionMonthly {
+		from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return Partition{Name: partitionPrefix + from.Format("200601"), From: from, To: from.AddDate(0, 1, 0)}
+	}
+	from := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return Partition{Name: partitionPrefix + from.Format("20060102"), From: from, To: from.AddDate(0, 0, 1)}
+}
+
+// parsePartition recovers the bounds of a partition from its name, accepting
+// both daily and monthly names so a change of interval leaves old partitions
+// eligible for retention.
+func parsePartition(name string) (Partition, bool) {
+	suffix, ok := strings.CutPrefix(name, partitionPrefix)
+	if !ok {
+		return Partition{}, false
+	}
+	switch len(suffix) {
+	case len("20060102"):
+		from, err := time.Parse("20060102", suffix)
+		if err != nil {
+			return Partition{}, false
+		}
+		return Partition{Name: name, From: from, To: from.AddDate(0, 0, 1)}, true
+	case len("200601"):
+		from, err := time.Parse("200601", suffix)
+		if err != nil {
+			return Partition{}, false
+		}
+		return Partition{Name: name, From: from, To: from.AddDate(0, 1, 0)}, true
+	}
+	return Partition{}, false
+}
+
+// ListPartitions returns the dated partitions currently attached to ride_events.
+// The default partition is not included.
+func (s *Store) ListPartitions(ctx context.Context) ([]Partition, error) {
+	names, err := sqlcdb.New(s.db).ListPartitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var parts []Partition
+	for _, name := range names {
+		if p, ok := parsePartition(name); ok {
+			parts = append(parts, p)
+		}
+	}
+	return parts, nil
+}
+
+// MaintainPartitions creates the partitions for the current period and the next
+// Premake periods, then drops partitions whose whole range is older than the
+// retention window. It is safe to run repeatedly and from several instances.
+//
+// Periods already covered by a partition of another interval, as after the
+// interval changes, are skipped, and a period partly covered by daily
+// partitions gets daily partitions for the rest. A failure to create a
+// partition does not stop the drops; the errors are returned together.
+//
+// The DDL is built here rather than in queries/: partition names and bounds
+// cannot be bound as parameters, and sqlc does not generate DDL.
+func (s *Store) MaintainPartitions(ctx context.Context, p PartitionPolicy, now time.Time) (PartitionReport, error) {
+	var report PartitionReport
+
+	existing, err := s.ListPartitions(ctx)
+	if err != nil {
+		return report, fmt.Errorf("list partitions: %w", err)
+	}
+
+	var errs []error
+	next := partitionFor(p.Interval, now)
+	for i := 0; i <= p.Premake; i++ {
+		for _, part := range uncovered(next, existing) {
+			if err := s.createPartition(ctx, part); err != nil {
+				errs = append(errs, fmt.Errorf("create partition %s: %w", part.Name, err))
+				continue
+			}
+			report.Created = append(report.Created, part.Name)
+		}
+		next = partitionFor(p.Interval, next.To)
+	}
+
+	if p.Retention > 0 {
+		cutoff := now.UTC().Add(-p.Retention)
+		for _, part := range existing {
+			if part.To.After(cutoff) {
+				continue
+			}
+			if _, err := s.db.ExecContext(ctx, `DROP TABLE IF EXISTS `+part.Name); err != nil {
+				errs = append(errs, fmt.Errorf("drop partition %s: %w", part.Name, err))
+				continue
+			}
+			report.Dropped = append(report.Dropped, part.Name)
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// uncovered returns the partitions to create for period: period itself when no
+// existing partition overlaps it, or else the days of it none does. Partitions
+// are whole days or months, so a day is covered entirely or not at all.
+func uncovered(period Partition, existing []Partition) []Partition {
+	overlaps := func(p Partition) bool {
+		return slices.ContainsFunc(existing, func(e Partition) bool {
+			return e.From.Before(p.To) && p.From.Before(e.To)
+		})
+	}
+	if !overlaps(period) {
+		return []Partition{period}
+	}
+	var days []Partition
+	for day := partitionFor(PartitionDaily, period.From); day.From.Before(period.To); day = partitionFor(PartitionDaily, day.To) {
+		if !overlaps(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// createPartition creates part as a partition of ride_events. Rows of its range
+// in the default partition would make that fail, so when there are any, part is
+// created on its own, the rows are moved into it, and it is attached, all in
+// one transaction.
+func (s *Store) createPartition(ctx context.Context, part Partition) error {
+	from, to := part.From.Format(time.DateOnly), part.To.Format(time.DateOnly)
+	return s.withTx(ctx, func(tx *Store) error {
+		var stray bool
+		if err := tx.q.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM ride_events_default WHERE event_time >= $1 AND event_time < $2)`,
+			part.From, part.To,
+		).Scan(&stray); err != nil {
+			return err
+		}
+		if !stray {
+			_, err := tx.q.ExecContext(ctx, fmt.Sprintf(
+				`CREATE TABLE IF NOT EXISTS %s PARTITION OF ride_events FOR VALUES FROM ('%s') TO ('%s')`,
+				part.Name, from, to,
+			))
+			return err
+		}
+
+		slog.Info("Moving rows from the default partition", "partition", part.Name)
+		for _, stmt := range []string{
+			fmt.Sprintf(`CREATE TABLE %s (LIKE ride_events INCLUDING DEFAULTS)`, part.Name),
+			fmt.Sprintf(`WITH moved AS (
+				DELETE FROM ride_events_default WHERE event_time >= '%s' AND event_time < '%s' RETURNING *
+			) INSERT INTO %s SELECT * FROM moved`, from, to, part.Name),
+			fmt.Sprintf(`ALTER TABLE ride_events ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`, part.Name, from, to),
+		} {
+			if _, err := tx.q.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
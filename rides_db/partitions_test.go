@@ -0,0 +1,183 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPartitionFor(t *testing.T) {
+	at := time.Date(2025, 1, 31, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		interval PartitionInterval
+		want     Partition
+	}{
+		{PartitionDaily, Partition{
+			Name: "ride_events_p20250131",
+			From: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{PartitionMonthly, Partition{
+			Name: "ride_events_p202501",
+			From: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	for _, tt := range tests {
+		got := partitionFor(tt.interval, at)
+		if got != tt.want {
+			t.Errorf("partitionFor(%s) = %+v, want %+v", tt.interval, got, tt.want)
+		}
+		parsed, ok := parsePartition(got.Name)
+		if !ok || parsed != got {
+			t.Errorf("parsePartition(%q) = %+v, %v", got.Name, parsed, ok)
+		}
+	}
+
+	if _, ok := parsePartition("ride_events_default"); ok {
+		t.Error("default partition should not parse as a dated partition")
+	}
+}
+
+func TestMaintainPartitions_CreatesAndDrops(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT c.relname FROM pg_inherits").
+		WillReturnRows(sqlmock.NewRows([]string{"relname"}).
+			AddRow("ride_events_default").
+			AddRow("ride_events_p20250101").
+			AddRow("ride_events_p20250310"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM ride_events_default").
+		WithArgs(time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS ride_events_p20250311 PARTITION OF ride_events FOR VALUES FROM \('2025-03-11'\) TO \('2025-03-12'\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectExec("DROP TABLE IF EXISTS ride_events_p20250101").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	report, err := store.MaintainPartitions(context.Background(), PartitionPolicy{
+		Interval:  PartitionDaily,
+		Premake:   1,
+		Retention: 30 * 24 * time.Hour,
+	}, now)
+	if err != nil {
+		t.Fatalf("MaintainPartitions failed: %v", err)
+	}
+
+	want := PartitionReport{Created: []string{"ride_events_p20250311"}, Dropped: []string{"ride_events_p20250101"}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUncovered(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+	march := partitionFor(PartitionMonthly, day(1))
+
+	tests := []struct {
+		name     string
+		period   Partition
+		existing []Partition
+		want     []string
+	}{
+		{"nothing there", partitionFor(PartitionDaily, day(10)), nil, []string{"ride_events_p20250310"}},
+		{"already there", partitionFor(PartitionDaily, day(10)),
+			[]Partition{partitionFor(PartitionDaily, day(10))}, nil},
+		{"monthly to daily", partitionFor(PartitionDaily, day(10)), []Partition{march}, nil},
+		{"daily to monthly", march, []Partition{
+			partitionFor(PartitionDaily, day(1)),
+			partitionFor(PartitionDaily, day(2)),
+			partitionFor(PartitionDaily, day(30)),
+		}, nil},
+	}
+	// The month is filled in with the days its daily partitions leave out
+	for d := 3; d <= 31; d++ {
+		if d != 30 {
+			tests[3].want = append(tests[3].want, partitionFor(PartitionDaily, day(d)).Name)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range uncovered(tt.period, tt.existing) {
+				got = append(got, p.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("uncovered = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintainPartitions_MovesDefaultRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT c.relname FROM pg_inherits").
+		WillReturnRows(sqlmock.NewRows([]string{"relname"}).
+			AddRow("ride_events_default").
+			AddRow("ride_events_p20250101"))
+	// Events of the 10th already landed in the default partition
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM ride_events_default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec(`CREATE TABLE ride_events_p20250310 \(LIKE ride_events INCLUDING DEFAULTS\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM ride_events_default WHERE event_time >= '2025-03-10' AND event_time < '2025-03-11'(.+)INSERT INTO ride_events_p20250310`).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(`ALTER TABLE ride_events ATTACH PARTITION ride_events_p20250310 FOR VALUES FROM \('2025-03-10'\) TO \('2025-03-11'\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	// A failure to create the next one does not stop the drops
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM ride_events_default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS ride_events_p20250311").
+		WillReturnError(errors.New("out of disk"))
+	mock.ExpectRollback()
+	mock.ExpectExec("DROP TABLE IF EXISTS ride_events_p20250101").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	report, err := store.MaintainPartitions(context.Background(), PartitionPolicy{
+		Interval:  PartitionDaily,
+		Premake:   1,
+		Retention: 30 * 24 * time.Hour,
+	}, now)
+	if err == nil || !strings.Contains(err.Error(), "ride_events_p20250311") {
+		t.Errorf("MaintainPartitions error = %v, want the failed partition", err)
+	}
+
+	want := PartitionReport{Created: []string{"ride_events_p20250310"}, Dropped: []string{"ride_events_p20250101"}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package rides_db
+
+import (
+	"context"
+	"fmt"
+)
+
+// EnablePostGIS installs the postgis extension and adds geography columns for
+// the pickup and dropoff points of every ride, generated from the plain
+// latitude/longitude columns so writers don't need to know about PostGIS. It
+// is optional and safe to run on every start; RidesWithinRadius requires it.
+func (s *Store) EnablePostGIS(ctx context.Context) error {
+	stmts := []string{
+		`CREATE EXTENSION IF NOT EXISTS postgis`,
+		`ALTER TABLE rides ADD COLUMN IF NOT EXISTS pickup_geog GEOGRAPHY(Point, 4326)
+			GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(pickup_lng, pickup_lat), 4326)::geography) STORED`,
+		`ALTER TABLE rides ADD COLUMN IF NOT EXISTS dropoff_geog GEOGRAPHY(Point, 4326)
+			GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(dropoff_lng, dropoff_lat), 4326)::geography) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_rides_pickup_geog ON rides USING GIST (pickup_geog)`,
+		`CREATE INDEX IF NOT EXISTS idx_rides_dropoff_geog ON rides USING GIST (dropoff_geog)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("enable postgis: %w", err)
+		}
+	}
+	return nil
+}
+
+// RidesWithinRadius returns rides picked up within meters of (lat, lng),
+// nearest first. It needs EnablePostGIS to have run.
+func (s *Store) RidesWithinRadius(ctx context.Context, lat, lng, meters float64) ([]Ride, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.queryRides(ctx, `
+		WITH origin AS (
+			SELECT ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography AS point
+		)
+		SELECT `+rideColumns+`
+		FROM rides, origin
+		WHERE ST_DWithin(rides.pickup_geog, origin.point, $3)
+		ORDER BY ST_Distance(rides.pickup_geog, origin.point)
+	`, lat, lng, meters)
+}
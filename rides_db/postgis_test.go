@@ -0,0 +1,33 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRidesWithinRadius(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	mock.ExpectQuery("ST_DWithin\\(rides.pickup_geog, origin.point, \\$3\\)").
+		WithArgs(40.75, -73.98, 500.0).
+		WillReturnRows(sqlmock.NewRows(rideRowColumns))
+
+	rides, err := store.RidesWithinRadius(context.Background(), 40.75, -73.98, 500)
+	if err != nil {
+		t.Fatalf("RidesWithinRadius failed: %v", err)
+	}
+	if len(rides) != 0 {
+		t.Errorf("expected no rides, got %d", len(rides))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// Ride is a row of the rides table: the current state of one trip.
+type Ride struct {
+	TripID        string
+	State         events.RideState
+	LastEventType events.RideEventType
+	LastEventAt   time.Time
+	DriverID      string
+	PassengerID   string
+	RequestedAt   time.Time
+	AcceptedAt    time.Time
+	StartedAt     time.Time
+	EndedAt       time.Time
+	FareUSD       float64
+	Pickup        *events.Coordinate
+	Dropoff       *events.Coordinate
+}
+
+// TimeRange is a half-open interval [From, To). A zero bound is unbounded.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+const rideColumns = `trip_id, state, last_event_type, last_event_at, driver_id, passenger_id,
+	requested_at, accepted_at, started_at, ended_at, fare_usd,
+	pickup_lat, pickup_lng, dropoff_lat, dropoff_lng`
+
+// GetTripEvents returns every stored event of a trip in event-time order, with
+// payloads decoded into their typed structs.
+func (s *Store) GetTripEvents(ctx context.Context, tripID string) ([]events.RideEvent, error) {
+	rows, err := s.readQueries(ctx).GetTripEvents(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	var evts []events.RideEvent
+	for _, row := range rows {
+		e, err := rideEventFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		if e, err = s.cipher.decryptEvent(ctx, e); err != nil {
+			return nil, err
+		}
+		evts = append(evts, e)
+	}
+	return evts, nil
+}
+
+// rideEventFromRow converts a generated ride_events row, decoding its payload
+// into the typed struct for its event type.
+func rideEventFromRow(row sqlcdb.GetTripEventsRow) (events.RideEvent, error) {
+	e := events.RideEvent{
+		ID:          row.ID,
+		TripID:      row.TripID,
+		Type:        events.RideEventType(row.EventType),
+		State:       events.RideState(row.EventState),
+		OccurredAt:  row.EventTime,
+		DriverID:    row.DriverID.String,
+		PassengerID: row.PassengerID.String,
+	}
+	var err error
+	if e.Payload, err = decodeStoredPayload(e.Type, row.Payload); err != nil {
+		return events.RideEvent{}, err
+	}
+	return e, nil
+}
+
+// scanRideEvent reads a row of id, trip_id, event_type, event_state, event_time,
+// driver_id, passenger_id, and payload, for the backends that do not use the
+// generated queries.
+func scanRideEvent(row rowScanner) (events.RideEvent, error) {
+	var (
+		e                     events.RideEvent
+		driverID, passengerID sql.NullString
+		payload               []byte
+	)
+	if err := row.Scan(&e.ID, &e.TripID, &e.Type, &e.State, &e.OccurredAt, &driverID, &passengerID, &payload); err != nil {
+		return events.RideEvent{}, err
+	}
+	e.DriverID = driverID.String
+	e.PassengerID = passengerID.String
+	var err error
+	if e.Payload, err = decodeStoredPayload(e.Type, payload); err != nil {
+		return events.RideEvent{}, err
+	}
+	return e, nil
+}
+
+// GetRide returns the current state of a trip, or ErrNotFound.
+func (s *Store) GetRide(ctx context.Context, tripID string) (Ride, error) {
+	var row sqlcdb.Ride
+	err := s.retry(ctx, func() error {
+		var err error
+		row, err = s.readQueries(ctx).GetRide(ctx, tripID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Ride{}, ErrNotFound
+	}
+	if err != nil {
+		return Ride{}, err
+	}
+	return rideFromRow(row), nil
+}
+
+// ListActiveRides returns rides that have not completed or been cancelled,
+// most recently updated first.
+func (s *Store) ListActiveRides(ctx context.Context, limit, offset int) ([]Ride, error) {
+	rows, err := s.readQueries(ctx).ListActiveRides(ctx, sqlcdb.ListActiveRidesParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	return ridesFromRows(rows), err
+}
+
+// ListRidesByDriver returns the rides a driver accepted that were requested within tr.
+func (s *Store) ListRidesByDriver(ctx context.Context, driverID string, tr TimeRange) ([]Ride, error) {
+	rows, err := s.readQueries(ctx).ListRidesByDriver(ctx, sqlcdb.ListRidesByDriverParams{
+		DriverID: driverID,
+		From:     nullTime(tr.From),
+		To:       nullTime(tr.To),
+	})
+	return ridesFromRows(rows), err
+}
+
+// rideFromRow converts a generated rides row.
+func rideFromRow(row sqlcdb.Ride) Ride {
+	return Ride{
+		TripID:        row.TripID,
+		State:         events.RideState(row.State),
+		LastEventType: events.RideEventType(row.LastEventType),
+		LastEventAt:   row.LastEventAt,
+		DriverID:      row.DriverID.String,
+		PassengerID:   row.PassengerID.String,
+		RequestedAt:   row.RequestedAt.Time,
+		AcceptedAt:    row.AcceptedAt.Time,
+		StartedAt:     row.StartedAt.Time,
+		EndedAt:       row.EndedAt.Time,
+		FareUSD:       row.FareUsd.Float64,
+		Pickup:        scannedCoordinate(row.PickupLat, row.PickupLng),
+		Dropoff:       scannedCoordinate(row.DropoffLat, row.DropoffLng),
+	}
+}
+
+func ridesFromRows(rows []sqlcdb.Ride) []Ride {
+	var rides []Ride
+	for _, row := range rows {
+		rides = append(rides, rideFromRow(row))
+	}
+	return rides
+}
+
+// queryRides runs query, which selects rideColumns. Only RidesWithinRadius
+// needs it: the geography columns it filters on are added by EnablePostGIS
+// rather than a migration, so sqlc cannot check a query that uses them.
+func (s *Store) queryRides(ctx context.Context, query string, args ...any) ([]Ride, error) {
+	rows, err := s.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rides []Ride
+	for rows.Next() {
+		r, err := scanRide(rows)
+		if err != nil {
+			return nil, err
+		}
+		rides = append(rides, r)
+	}
+	return rides, rows.Err()
+}
+
+func scanRide(row rowScanner) (Ride, error) {
+	var (
+		r                                 Ride
+		driverID, passengerID             sql.NullString
+		requested, accepted, started, end sql.NullTime
+		fare                              sql.NullFloat64
+		pickupLat, pickupLng              sql.NullFloat64
+		dropoffLat, dropoffLng            sql.NullFloat64
+	)
+	err := row.Scan(
+		&r.TripID, &r.State, &r.LastEventType, &r.LastEventAt, &driverID, &passengerID,
+		&requested, &accepted, &started, &end, &fare,
+		&pickupLat, &pickupLng, &dropoffLat, &dropoffLng,
+	)
+	if err != nil {
+		return Ride{}, err
+	}
+	r.DriverID = driverID.String
+	r.PassengerID = passengerID.String
+	r.RequestedAt = requested.Time
+	r.AcceptedAt = accepted.Time
+	r.StartedAt = started.Time
+	r.EndedAt = end.Time
+	r.FareUSD = fare.Float64
+	r.Pickup = scannedCoordinate(pickupLat, pickupLng)
+	r.Dropoff = scannedCoordinate(dropoffLat, dropoffLng)
+	return r, nil
+}
+
+func scannedCoordinate(lat, lng sql.NullFloat64) *events.Coordinate {
+	if !lat.Valid || !lng.Valid {
+		return nil
+	}
+	return &events.Coordinate{Lat: lat.Float64, Lng: lng.Float64}
+}
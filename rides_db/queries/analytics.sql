@@ -0,0 +1,61 @@
+-- name: RevenueByDay :many
+SELECT date_trunc('day', completed_at)::timestamp AS day,
+       COUNT(*) AS trips,
+       COALESCE(SUM(fare_usd), 0)::DOUBLE PRECISION AS revenue_usd
+FROM trips
+WHERE final_state = 'COMPLETED'
+  AND (sqlc.narg('from')::timestamp IS NULL OR completed_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamp IS NULL OR completed_at < sqlc.narg('to'))
+GROUP BY day
+ORDER BY day;
+
+-- name: CancellationRateByHour :many
+SELECT date_trunc('hour', requested_at)::timestamp AS hour,
+       COUNT(*) AS requested,
+       COUNT(*) FILTER (WHERE final_state = 'CANCELLED') AS cancelled
+FROM trips
+WHERE requested_at IS NOT NULL
+  AND (sqlc.narg('from')::timestamp IS NULL OR requested_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamp IS NULL OR requested_at < sqlc.narg('to'))
+GROUP BY hour
+ORDER BY hour;
+
+-- name: AvgFareByZone :many
+SELECT pickup_location, COUNT(*) AS trips, AVG(fare_usd)::DOUBLE PRECISION AS avg_fare_usd
+FROM trips
+WHERE final_state = 'COMPLETED'
+  AND pickup_location IS NOT NULL
+  AND (sqlc.narg('from')::timestamp IS NULL OR completed_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamp IS NULL OR completed_at < sqlc.narg('to'))
+GROUP BY pickup_location
+ORDER BY 3 DESC;
+
+-- name: TopDriversByTrips :many
+SELECT driver_id, COUNT(*) AS trips, COALESCE(SUM(fare_usd), 0)::DOUBLE PRECISION AS revenue_usd
+FROM trips
+WHERE final_state = 'COMPLETED'
+  AND driver_id IS NOT NULL
+  AND (sqlc.narg('from')::timestamp IS NULL OR completed_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamp IS NULL OR completed_at < sqlc.narg('to'))
+GROUP BY driver_id
+ORDER BY 2 DESC, revenue_usd DESC
+LIMIT sqlc.arg('limit');
+
+-- name: RevenueBySurge :many
+SELECT multiplier, COUNT(*) AS trips, COALESCE(SUM(fare_usd), 0)::DOUBLE PRECISION AS revenue_usd
+FROM (
+    SELECT t.fare_usd,
+           COALESCE((
+               SELECT s.multiplier FROM surge_multipliers s
+               WHERE s.zone_id = t.pickup_zone
+                 AND s.effective_from <= t.requested_at
+               ORDER BY s.effective_from DESC
+               LIMIT 1
+           ), 1)::DOUBLE PRECISION AS multiplier
+    FROM trips t
+    WHERE t.final_state = 'COMPLETED'
+      AND (sqlc.narg('from')::timestamp IS NULL OR t.completed_at >= sqlc.narg('from'))
+      AND (sqlc.narg('to')::timestamp IS NULL OR t.completed_at < sqlc.narg('to'))
+) surged
+GROUP BY multiplier
+ORDER BY multiplier;
@@ -0,0 +1,5 @@
+-- name: AppendAudit :exec
+INSERT INTO audit_log
+(kind, actor, recorded_at, topic, partition, first_offset, last_offset, row_count,
+ duration_seconds, migration_version, migration_name)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
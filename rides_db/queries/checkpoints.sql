@@ -0,0 +1,12 @@
+-- name: UpsertCheckpoint :exec
+INSERT INTO consumer_checkpoints
+(consumer_group, topic, partition, last_offset, updated_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (consumer_group, topic, partition) DO UPDATE
+SET last_offset = EXCLUDED.last_offset, updated_at = EXCLUDED.updated_at
+WHERE consumer_checkpoints.last_offset < EXCLUDED.last_offset;
+
+-- name: GetCheckpoints :many
+SELECT * FROM consumer_checkpoints
+WHERE consumer_group = $1
+ORDER BY topic, partition;
@@ -0,0 +1,70 @@
+-- name: ErasePassengerEvents :execrows
+UPDATE ride_events
+SET passenger_id = CASE WHEN passenger_id = sqlc.arg('passenger_id')::text THEN sqlc.arg('pseudonym')::text ELSE passenger_id END,
+    payload = CASE WHEN event_type = 'REQUESTED'
+        THEN (payload - 'pickup' - 'dropoff')
+            || jsonb_build_object('passenger', sqlc.arg('pseudonym')::text, 'pickup_location', '', 'dropoff_location', '')
+        ELSE payload END,
+    pickup_location = CASE WHEN event_type = 'REQUESTED' THEN NULL ELSE pickup_location END,
+    dropoff_location = CASE WHEN event_type = 'REQUESTED' THEN NULL ELSE dropoff_location END
+WHERE passenger_id = sqlc.arg('passenger_id')::text
+   OR trip_id IN (SELECT trip_id FROM rides WHERE passenger_id = sqlc.arg('passenger_id')::text);
+
+-- name: ErasePassengerArchivedEvents :execrows
+UPDATE ride_events_archive
+SET passenger_id = CASE WHEN passenger_id = sqlc.arg('passenger_id')::text THEN sqlc.arg('pseudonym')::text ELSE passenger_id END,
+    payload = CASE WHEN event_type = 'REQUESTED'
+        THEN (payload - 'pickup' - 'dropoff')
+            || jsonb_build_object('passenger', sqlc.arg('pseudonym')::text, 'pickup_location', '', 'dropoff_location', '')
+        ELSE payload END,
+    pickup_location = CASE WHEN event_type = 'REQUESTED' THEN NULL ELSE pickup_location END,
+    dropoff_location = CASE WHEN event_type = 'REQUESTED' THEN NULL ELSE dropoff_location END
+WHERE passenger_id = sqlc.arg('passenger_id')::text
+   OR trip_id IN (SELECT trip_id FROM rides WHERE passenger_id = sqlc.arg('passenger_id')::text);
+
+-- name: ErasePassengerRides :execrows
+UPDATE rides
+SET passenger_id = sqlc.arg('pseudonym')::text, pickup_lat = NULL, pickup_lng = NULL, dropoff_lat = NULL, dropoff_lng = NULL
+WHERE passenger_id = sqlc.arg('passenger_id')::text;
+
+-- name: ErasePassengerTrips :execrows
+UPDATE trips
+SET passenger_id = sqlc.arg('pseudonym')::text, pickup_location = NULL, dropoff_location = NULL,
+    pickup_zone = CASE WHEN pickup_zone = pickup_location THEN NULL ELSE pickup_zone END
+WHERE passenger_id = sqlc.arg('passenger_id')::text;
+
+-- name: ErasePassengerNotifications :execrows
+UPDATE notification_deliveries SET recipient = sqlc.arg('pseudonym')::text WHERE recipient = sqlc.arg('passenger_id')::text;
+
+-- name: EraseDriverEvents :execrows
+UPDATE ride_events
+SET driver_id = CASE WHEN driver_id = sqlc.arg('driver_id')::text THEN sqlc.arg('pseudonym')::text ELSE driver_id END,
+    payload = CASE WHEN payload->>'driver_id' = sqlc.arg('driver_id')::text
+        THEN jsonb_set(payload, '{driver_id}', to_jsonb(sqlc.arg('pseudonym')::text)) ELSE payload END
+WHERE driver_id = sqlc.arg('driver_id')::text OR payload->>'driver_id' = sqlc.arg('driver_id')::text;
+
+-- name: EraseDriverArchivedEvents :execrows
+UPDATE ride_events_archive
+SET driver_id = CASE WHEN driver_id = sqlc.arg('driver_id')::text THEN sqlc.arg('pseudonym')::text ELSE driver_id END,
+    payload = CASE WHEN payload->>'driver_id' = sqlc.arg('driver_id')::text
+        THEN jsonb_set(payload, '{driver_id}', to_jsonb(sqlc.arg('pseudonym')::text)) ELSE payload END
+WHERE driver_id = sqlc.arg('driver_id')::text OR payload->>'driver_id' = sqlc.arg('driver_id')::text;
+
+-- name: EraseDriverRides :execrows
+UPDATE rides SET driver_id = sqlc.arg('pseudonym')::text WHERE driver_id = sqlc.arg('driver_id')::text;
+
+-- name: EraseDriverTrips :execrows
+UPDATE trips SET driver_id = sqlc.arg('pseudonym')::text WHERE driver_id = sqlc.arg('driver_id')::text;
+
+-- name: EraseDriverNotifications :execrows
+UPDATE notification_deliveries SET recipient = sqlc.arg('pseudonym')::text WHERE recipient = sqlc.arg('driver_id')::text;
+
+-- name: EraseDriverFraudAlerts :execrows
+UPDATE fraud_alerts
+SET driver_id = CASE WHEN driver_id = sqlc.arg('driver_id')::text THEN sqlc.arg('pseudonym')::text ELSE driver_id END,
+    detail = replace(detail, sqlc.arg('driver_id')::text, sqlc.arg('pseudonym')::text)
+WHERE driver_id = sqlc.arg('driver_id')::text OR strpos(detail, sqlc.arg('driver_id')::text) > 0;
+
+-- name: RecordErasure :exec
+INSERT INTO pii_erasures (subject_type, subject_hash, pseudonym, rows_affected)
+VALUES ($1, $2, $3, $4);
@@ -0,0 +1,11 @@
+-- name: InsertFraudAlert :exec
+INSERT INTO fraud_alerts
+(alert_id, kind, trip_id, driver_id, city, detected_at, detail, fare_usd, expected_fare_usd)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (alert_id) DO NOTHING;
+
+-- name: ListFraudAlerts :many
+SELECT alert_id, kind, trip_id, driver_id, city, detected_at, detail, fare_usd, expected_fare_usd
+FROM fraud_alerts
+ORDER BY detected_at DESC, alert_id
+LIMIT $1;
@@ -0,0 +1,15 @@
+-- name: UpsertHeatmapCell :exec
+INSERT INTO heatmap_cells
+(zone_id, bucket_start, bucket_end, pickups, drivers, updated_at)
+VALUES ($1, $2, $3, $4, $5, now())
+ON CONFLICT (bucket_start, zone_id) DO UPDATE
+SET pickups = GREATEST(heatmap_cells.pickups, EXCLUDED.pickups),
+    drivers = GREATEST(heatmap_cells.drivers, EXCLUDED.drivers),
+    updated_at = EXCLUDED.updated_at;
+
+-- name: ListHeatmapCells :many
+SELECT zone_id, bucket_start, bucket_end, pickups, drivers, updated_at
+FROM heatmap_cells
+WHERE (sqlc.narg('from')::timestamp IS NULL OR bucket_start >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamp IS NULL OR bucket_start < sqlc.narg('to'))
+ORDER BY bucket_start, zone_id;
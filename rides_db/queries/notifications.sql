@@ -0,0 +1,16 @@
+-- name: UpsertNotificationDelivery :exec
+INSERT INTO notification_deliveries
+(notification_id, channel, recipient, trip_id, event_type, status, attempts, last_error, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now(), now())
+ON CONFLICT (notification_id, channel) DO UPDATE
+SET status = EXCLUDED.status,
+    attempts = EXCLUDED.attempts,
+    last_error = EXCLUDED.last_error,
+    updated_at = EXCLUDED.updated_at
+WHERE notification_deliveries.status <> 'sent';
+
+-- name: ListNotificationDeliveries :many
+SELECT notification_id, channel, recipient, trip_id, event_type, status, attempts, last_error, created_at, updated_at
+FROM notification_deliveries
+WHERE trip_id = $1
+ORDER BY created_at, notification_id, channel;
@@ -0,0 +1,16 @@
+-- name: EnqueueOutbox :exec
+INSERT INTO ride_outbox (topic, message_key, payload, headers) VALUES ($1, $2, $3, $4);
+
+-- name: ListPendingOutbox :many
+SELECT id, topic, message_key, payload, COALESCE(headers, 'null'::jsonb) AS headers, created_at, attempts
+FROM ride_outbox
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxPublished :exec
+UPDATE ride_outbox SET published_at = sqlc.arg('published_at')::timestamp WHERE id = sqlc.arg('id');
+
+-- name: MarkOutboxFailed :exec
+UPDATE ride_outbox SET attempts = attempts + 1, last_error = sqlc.arg('last_error')::text WHERE id = sqlc.arg('id');
@@ -0,0 +1,6 @@
+-- name: ListPartitions :many
+SELECT c.relname
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'ride_events'::regclass
+ORDER BY c.relname;
@@ -0,0 +1,75 @@
+-- name: InsertRideEvent :one
+WITH event AS (
+    SELECT sqlc.arg('id')::uuid AS id,
+        sqlc.arg('trip_id')::text AS trip_id,
+        sqlc.arg('event_type')::varchar AS event_type,
+        sqlc.arg('event_state')::varchar AS event_state,
+        sqlc.arg('event_time')::timestamp AS event_time,
+        sqlc.narg('driver_id')::text AS driver_id,
+        sqlc.narg('passenger_id')::text AS passenger_id,
+        sqlc.narg('payload')::jsonb AS payload,
+        sqlc.narg('distance_km')::double precision AS distance_km,
+        sqlc.narg('fare_total')::numeric AS fare_total,
+        sqlc.narg('cancelled_by')::text AS cancelled_by,
+        sqlc.narg('pickup_location')::text AS pickup_location,
+        sqlc.narg('dropoff_location')::text AS dropoff_location
+), claimed AS (
+    INSERT INTO ride_event_ledger (event_id, trip_id, event_type, event_time)
+    SELECT id, trip_id, event_type, event_time FROM event
+    ON CONFLICT (event_id) DO NOTHING
+    RETURNING event_id
+), inserted AS (
+    INSERT INTO ride_events
+    (id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload,
+     distance_km, fare_total, cancelled_by, pickup_location, dropoff_location)
+    SELECT id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload,
+        distance_km, fare_total, cancelled_by, pickup_location, dropoff_location
+    FROM event
+    WHERE EXISTS (SELECT 1 FROM claimed)
+    ON CONFLICT (trip_id, event_type, event_time) DO NOTHING
+    RETURNING id
+)
+SELECT (CASE
+    WHEN EXISTS (SELECT 1 FROM inserted) THEN 'inserted'
+    WHEN NOT EXISTS (SELECT 1 FROM claimed) AND EXISTS (
+        SELECT 1 FROM ride_event_ledger l JOIN event e
+            ON l.event_id = e.id AND l.trip_id = e.trip_id AND l.event_type = e.event_type
+    ) THEN 'duplicate'
+    ELSE 'conflict'
+END)::text AS outcome;
+
+-- name: GetTripEvents :many
+SELECT id, trip_id, event_type, event_state, event_time, driver_id, passenger_id,
+    COALESCE(payload, 'null'::jsonb) AS payload
+FROM ride_events
+WHERE trip_id = $1
+ORDER BY event_time, id;
+
+-- name: ExportRideEvents :many
+SELECT id, trip_id, event_type, event_state, event_time, driver_id, passenger_id,
+    COALESCE(payload, 'null'::jsonb) AS payload
+FROM ride_events
+WHERE event_time >= sqlc.arg('from') AND event_time < sqlc.arg('to')
+  AND (event_time, id) > (sqlc.arg('after_event_time')::timestamp, sqlc.arg('after_id')::uuid)
+ORDER BY event_time, id
+LIMIT sqlc.arg('limit');
+
+-- name: ArchiveRideEvents :execrows
+WITH moved AS (
+    DELETE FROM ride_events
+    WHERE (id, event_time) IN (
+        SELECT id, event_time FROM ride_events
+        WHERE event_time < sqlc.arg('before')
+        ORDER BY event_time
+        LIMIT sqlc.arg('limit')
+    )
+    RETURNING id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload,
+        distance_km, fare_total, cancelled_by, pickup_location, dropoff_location
+)
+INSERT INTO ride_events_archive
+(id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload,
+ distance_km, fare_total, cancelled_by, pickup_location, dropoff_location)
+SELECT id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload,
+    distance_km, fare_total, cancelled_by, pickup_location, dropoff_location
+FROM moved
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,40 @@
+-- name: UpsertRideState :exec
+INSERT INTO rides
+(trip_id, state, last_event_type, last_event_at, driver_id, passenger_id,
+ requested_at, accepted_at, started_at, ended_at, fare_usd,
+ pickup_lat, pickup_lng, dropoff_lat, dropoff_lng)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+ON CONFLICT (trip_id) DO UPDATE SET
+    state = CASE WHEN EXCLUDED.last_event_at >= rides.last_event_at
+        THEN EXCLUDED.state ELSE rides.state END,
+    last_event_type = CASE WHEN EXCLUDED.last_event_at >= rides.last_event_at
+        THEN EXCLUDED.last_event_type ELSE rides.last_event_type END,
+    last_event_at = GREATEST(rides.last_event_at, EXCLUDED.last_event_at),
+    driver_id = COALESCE(EXCLUDED.driver_id, rides.driver_id),
+    passenger_id = COALESCE(EXCLUDED.passenger_id, rides.passenger_id),
+    requested_at = COALESCE(rides.requested_at, EXCLUDED.requested_at),
+    accepted_at = COALESCE(rides.accepted_at, EXCLUDED.accepted_at),
+    started_at = COALESCE(rides.started_at, EXCLUDED.started_at),
+    ended_at = COALESCE(rides.ended_at, EXCLUDED.ended_at),
+    fare_usd = COALESCE(EXCLUDED.fare_usd, rides.fare_usd),
+    pickup_lat = COALESCE(rides.pickup_lat, EXCLUDED.pickup_lat),
+    pickup_lng = COALESCE(rides.pickup_lng, EXCLUDED.pickup_lng),
+    dropoff_lat = COALESCE(rides.dropoff_lat, EXCLUDED.dropoff_lat),
+    dropoff_lng = COALESCE(rides.dropoff_lng, EXCLUDED.dropoff_lng);
+
+-- name: GetRide :one
+SELECT * FROM rides
+WHERE trip_id = $1;
+
+-- name: ListActiveRides :many
+SELECT * FROM rides
+WHERE state IN ('REQUESTED', 'ACCEPTED', 'IN_PROGRESS')
+ORDER BY last_event_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ListRidesByDriver :many
+SELECT * FROM rides
+WHERE driver_id = sqlc.arg('driver_id')
+  AND (sqlc.narg('from')::timestamp IS NULL OR requested_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamp IS NULL OR requested_at < sqlc.narg('to'))
+ORDER BY requested_at DESC;
@@ -0,0 +1,104 @@
+-- name: UpsertTrip :exec
+INSERT INTO trips
+(trip_id, passenger_id, driver_id, final_state, pickup_location, dropoff_location,
+ requested_at, accepted_at, started_at, completed_at, cancelled_at,
+ pickup_wait_seconds, duration_seconds, distance_km, fare_usd, cancelled_by, cancel_reason,
+ accept_latency_seconds, pickup_zone)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+ON CONFLICT (trip_id) DO UPDATE SET
+    passenger_id = EXCLUDED.passenger_id,
+    driver_id = EXCLUDED.driver_id,
+    final_state = EXCLUDED.final_state,
+    pickup_location = EXCLUDED.pickup_location,
+    dropoff_location = EXCLUDED.dropoff_location,
+    requested_at = EXCLUDED.requested_at,
+    accepted_at = EXCLUDED.accepted_at,
+    started_at = EXCLUDED.started_at,
+    completed_at = EXCLUDED.completed_at,
+    cancelled_at = EXCLUDED.cancelled_at,
+    pickup_wait_seconds = EXCLUDED.pickup_wait_seconds,
+    duration_seconds = EXCLUDED.duration_seconds,
+    distance_km = EXCLUDED.distance_km,
+    fare_usd = EXCLUDED.fare_usd,
+    cancelled_by = EXCLUDED.cancelled_by,
+    cancel_reason = EXCLUDED.cancel_reason,
+    accept_latency_seconds = EXCLUDED.accept_latency_seconds,
+    pickup_zone = EXCLUDED.pickup_zone;
+
+-- name: RefreshTrip :exec
+WITH e AS (
+    SELECT
+        trip_id,
+        MAX(NULLIF(passenger_id, '')) AS passenger_id,
+        MAX(NULLIF(driver_id, '')) AS driver_id,
+        (ARRAY_AGG(event_state ORDER BY event_time DESC))[1] AS final_state,
+        MAX(payload->>'pickup_location') FILTER (WHERE event_type = 'REQUESTED') AS pickup_location,
+        MAX(payload->>'dropoff_location') FILTER (WHERE event_type = 'REQUESTED') AS dropoff_location,
+        MAX(COALESCE(
+            ROUND(FLOOR((payload->'pickup'->>'lat')::NUMERIC / 0.02 + 0.000000001) * 0.02, 2)::TEXT
+                || ',' || ROUND(FLOOR((payload->'pickup'->>'lng')::NUMERIC / 0.02 + 0.000000001) * 0.02, 2)::TEXT,
+            payload->>'pickup_location')) FILTER (WHERE event_type = 'REQUESTED') AS pickup_zone,
+        MIN(event_time) FILTER (WHERE event_type = 'REQUESTED') AS requested_at,
+        MIN(event_time) FILTER (WHERE event_type = 'ACCEPTED') AS accepted_at,
+        MIN(event_time) FILTER (WHERE event_type = 'STARTED') AS started_at,
+        MIN(event_time) FILTER (WHERE event_type = 'COMPLETED') AS completed_at,
+        MIN(event_time) FILTER (WHERE event_type = 'CANCELLED') AS cancelled_at,
+        MAX((payload->>'distance_km')::DOUBLE PRECISION) FILTER (WHERE event_type = 'COMPLETED') AS distance_km,
+        MAX(fare_total) FILTER (WHERE event_type = 'COMPLETED') AS fare_usd,
+        MAX(payload->>'cancelled_by') FILTER (WHERE event_type = 'CANCELLED') AS cancelled_by,
+        MAX(payload->>'reason') FILTER (WHERE event_type = 'CANCELLED') AS cancel_reason
+    FROM ride_events
+    WHERE trip_id = $1
+    GROUP BY trip_id
+)
+INSERT INTO trips
+(trip_id, passenger_id, driver_id, final_state, pickup_location, dropoff_location,
+ requested_at, accepted_at, started_at, completed_at, cancelled_at,
+ pickup_wait_seconds, duration_seconds, distance_km, fare_usd, cancelled_by, cancel_reason,
+ accept_latency_seconds, pickup_zone)
+SELECT trip_id, passenger_id, driver_id, final_state, pickup_location, dropoff_location,
+    requested_at, accepted_at, started_at, completed_at, cancelled_at,
+    EXTRACT(EPOCH FROM started_at - requested_at),
+    EXTRACT(EPOCH FROM completed_at - started_at),
+    COALESCE(distance_km, 0), COALESCE(fare_usd, 0), cancelled_by, cancel_reason,
+    EXTRACT(EPOCH FROM accepted_at - requested_at), pickup_zone
+FROM e
+ON CONFLICT (trip_id) DO UPDATE SET
+    passenger_id = EXCLUDED.passenger_id,
+    driver_id = EXCLUDED.driver_id,
+    final_state = EXCLUDED.final_state,
+    pickup_location = EXCLUDED.pickup_location,
+    dropoff_location = EXCLUDED.dropoff_location,
+    requested_at = EXCLUDED.requested_at,
+    accepted_at = EXCLUDED.accepted_at,
+    started_at = EXCLUDED.started_at,
+    completed_at = EXCLUDED.completed_at,
+    cancelled_at = EXCLUDED.cancelled_at,
+    pickup_wait_seconds = EXCLUDED.pickup_wait_seconds,
+    duration_seconds = EXCLUDED.duration_seconds,
+    distance_km = EXCLUDED.distance_km,
+    fare_usd = EXCLUDED.fare_usd,
+    cancelled_by = EXCLUDED.cancelled_by,
+    cancel_reason = EXCLUDED.cancel_reason,
+    accept_latency_seconds = EXCLUDED.accept_latency_seconds,
+    pickup_zone = EXCLUDED.pickup_zone;
+
+-- name: GetTrip :one
+SELECT * FROM trips
+WHERE trip_id = $1;
+
+-- name: ListTrips :many
+SELECT * FROM trips
+WHERE (sqlc.narg('from')::timestamp IS NULL OR requested_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamp IS NULL OR requested_at < sqlc.narg('to'))
+  AND (sqlc.narg('final_state')::varchar IS NULL OR final_state = sqlc.narg('final_state'))
+  AND (sqlc.narg('driver_id')::text IS NULL OR driver_id = sqlc.narg('driver_id'))
+ORDER BY requested_at DESC NULLS LAST
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ExportTrips :many
+SELECT * FROM trips
+WHERE requested_at >= sqlc.arg('from') AND requested_at < sqlc.arg('to')
+  AND (requested_at, trip_id) > (sqlc.arg('after_requested_at')::timestamp, sqlc.arg('after_trip_id')::text)
+ORDER BY requested_at, trip_id
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,16 @@
+-- name: UpsertEventWindow :exec
+INSERT INTO ride_event_windows
+(window_start, window_end, event_type, event_count, fare_total, is_correction, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, now())
+ON CONFLICT (window_start, event_type) DO UPDATE
+SET event_count = EXCLUDED.event_count,
+    fare_total = EXCLUDED.fare_total,
+    is_correction = ride_event_windows.is_correction OR EXCLUDED.is_correction,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: ListEventWindows :many
+SELECT window_start, window_end, event_type, event_count, fare_total, is_correction, updated_at
+FROM ride_event_windows
+WHERE (sqlc.narg('from')::timestamp IS NULL OR window_start >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamp IS NULL OR window_start < sqlc.narg('to'))
+ORDER BY window_start, event_type;
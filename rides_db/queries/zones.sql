@@ -0,0 +1,12 @@
+-- name: UpsertZone :exec
+INSERT INTO zones (zone_id, name, updated_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (zone_id) DO UPDATE
+SET name = EXCLUDED.name, updated_at = EXCLUDED.updated_at
+WHERE zones.updated_at < EXCLUDED.updated_at;
+
+-- name: UpsertSurgeMultiplier :exec
+INSERT INTO surge_multipliers (zone_id, effective_from, multiplier)
+VALUES ($1, $2, $3)
+ON CONFLICT (zone_id, effective_from) DO UPDATE
+SET multiplier = EXCLUDED.multiplier;
@@ -0,0 +1,114 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+var rideRowColumns = []string{
+	"trip_id", "state", "last_event_type", "last_event_at", "driver_id", "passenger_id",
+	"requested_at", "accepted_at", "started_at", "ended_at", "fare_usd",
+	"pickup_lat", "pickup_lng", "dropoff_lat", "dropoff_lng",
+}
+
+func TestGetTripEvents_DecodesPayloads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "trip_id", "event_type", "event_state", "event_time", "driver_id", "passenger_id", "payload"}).
+		AddRow("e1", "trip-1", "REQUESTED", "REQUESTED", now, nil, "rider-1", []byte(`{"passenger":"rider-1","pickup_location":"A","dropoff_location":"B"}`)).
+		AddRow("e2", "trip-1", "COMPLETED", "COMPLETED", now.Add(time.Minute), "driver-1", "rider-1", []byte(`{"distance_km":3,"fare_usd":5.5}`))
+	mock.ExpectQuery("SELECT (.+) FROM ride_events").WithArgs("trip-1").WillReturnRows(rows)
+
+	evts, err := store.GetTripEvents(context.Background(), "trip-1")
+	if err != nil {
+		t.Fatalf("GetTripEvents failed: %v", err)
+	}
+	if len(evts) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(evts))
+	}
+	if p, ok := evts[0].Payload.(events.RideRequestedPayload); !ok || p.PickupLocation != "A" {
+		t.Errorf("unexpected requested payload: %#v", evts[0].Payload)
+	}
+	if p, ok := evts[1].Payload.(events.RideCompletedPayload); !ok || p.Fare != events.NewMoney(550, events.USD) {
+		t.Errorf("unexpected completed payload: %#v", evts[1].Payload)
+	}
+	if evts[0].DriverID != "" || evts[1].DriverID != "driver-1" {
+		t.Errorf("unexpected driver IDs: %q %q", evts[0].DriverID, evts[1].DriverID)
+	}
+}
+
+func TestListActiveRides(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT (.+) FROM rides WHERE state IN").
+		WithArgs(50, 0).
+		WillReturnRows(sqlmock.NewRows(rideRowColumns).
+			AddRow("trip-1", "ACCEPTED", "ACCEPTED", now, "driver-1", "rider-1", now, now, nil, nil, nil, 40.7, -73.9, nil, nil))
+
+	rides, err := store.ListActiveRides(context.Background(), 50, 0)
+	if err != nil {
+		t.Fatalf("ListActiveRides failed: %v", err)
+	}
+	if len(rides) != 1 || rides[0].State != events.StateAccepted || !rides[0].StartedAt.IsZero() {
+		t.Fatalf("unexpected rides: %+v", rides)
+	}
+	if rides[0].Pickup == nil || *rides[0].Pickup != (events.Coordinate{Lat: 40.7, Lng: -73.9}) || rides[0].Dropoff != nil {
+		t.Errorf("unexpected coordinates: pickup=%v dropoff=%v", rides[0].Pickup, rides[0].Dropoff)
+	}
+}
+
+func TestListRidesByDriver_OpenEndedRange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) FROM rides WHERE driver_id").
+		WithArgs("driver-1", from, nil).
+		WillReturnRows(sqlmock.NewRows(rideRowColumns))
+
+	rides, err := store.ListRidesByDriver(context.Background(), "driver-1", TimeRange{From: from})
+	if err != nil {
+		t.Fatalf("ListRidesByDriver failed: %v", err)
+	}
+	if len(rides) != 0 {
+		t.Errorf("expected no rides, got %d", len(rides))
+	}
+}
+
+func TestGetRide_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM rides").WithArgs("nope").WillReturnRows(sqlmock.NewRows(rideRowColumns))
+
+	if _, err := New(db).GetRide(context.Background(), "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// replicaLagCheckInterval is how long a replica's measured lag is trusted before
+// it is measured again.
+const replicaLagCheckInterval = time.Second
+
+// replicaLagSQL measures how far a standby is behind its primary. A standby that
+// has replayed everything it received counts as current even if the primary has
+// been idle, and a server that is not a standby reports no lag.
+const replicaLagSQL = `
+	SELECT COALESCE(CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+	END, 0)::double precision
+`
+
+// replica is a read-only standby the store may send reads to.
+type replica struct {
+	db *sql.DB
+	q  querier
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	lag       time.Duration
+	err       error
+}
+
+// replicaSet routes reads round-robin across the replicas that are within the
+// tolerated lag.
+type replicaSet struct {
+	replicas []*replica
+	maxLag   time.Duration
+	next     atomic.Uint64
+}
+
+func newReplicaSet(dbs []*sql.DB, o Options) *replicaSet {
+	if len(dbs) == 0 {
+		return nil
+	}
+	set := &replicaSet{maxLag: o.ReplicaMaxLag}
+	for _, db := range dbs {
+		set.replicas = append(set.replicas, &replica{
+			db: db,
+			q:  withSlowQueryLog(retryQuerier{db: db, policy: o.retryPolicy()}, o.SlowQueryThreshold),
+		})
+	}
+	return set
+}
+
+// pick returns the querier of the next replica no further behind than maxLag,
+// or nil if none is.
+func (rs *replicaSet) pick(ctx context.Context, maxLag time.Duration) querier {
+	start := rs.next.Add(1)
+	for i := range rs.replicas {
+		r := rs.replicas[(start+uint64(i))%uint64(len(rs.replicas))]
+		if lag, err := r.currentLag(ctx); err == nil && lag <= maxLag {
+			return r.q
+		}
+	}
+	return nil
+}
+
+// currentLag returns the replica's lag, measuring it at most once per
+// replicaLagCheckInterval. An unreachable replica reports the error until the
+// next check.
+func (r *replica) currentLag(ctx context.Context) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) < replicaLagCheckInterval {
+		return r.lag, r.err
+	}
+
+	var seconds float64
+	err := r.db.QueryRowContext(ctx, replicaLagSQL).Scan(&seconds)
+	if err != nil && r.err == nil {
+		slog.Warn("Read replica unavailable, reading from the primary", "error", err)
+	}
+	r.checkedAt, r.lag, r.err = time.Now(), time.Duration(seconds*float64(time.Second)), err
+	return r.lag, r.err
+}
+
+func (rs *replicaSet) close() error {
+	var firstErr error
+	for _, r := range rs.replicas {
+		if err := r.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+type readPreferenceKey struct{}
+
+type readPreference struct {
+	primary bool
+	maxLag  time.Duration
+}
+
+// ReadFromPrimary returns a context whose reads skip the replicas, for callers
+// that must see their own writes.
+func ReadFromPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPreferenceKey{}, readPreference{primary: true})
+}
+
+// WithMaxStaleness returns a context whose reads may use a replica at most d
+// behind the primary, instead of the store's configured tolerance.
+func WithMaxStaleness(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, readPreferenceKey{}, readPreference{maxLag: d})
+}
+
+// reader returns the querier for a read that tolerates replication lag: a
+// replica within the tolerated lag if there is one, otherwise the primary.
+// Reads inside a transaction always use it.
+func (s *Store) reader(ctx context.Context) querier {
+	if s.tx != nil || s.replicas == nil {
+		return s.q
+	}
+	maxLag := s.replicas.maxLag
+	if pref, ok := ctx.Value(readPreferenceKey{}).(readPreference); ok {
+		if pref.primary {
+			return s.q
+		}
+		maxLag = pref.maxLag
+	}
+	if q := s.replicas.pick(ctx, maxLag); q != nil {
+		return q
+	}
+	return s.q
+}
+
+// readQueries returns the sqlc-generated queries on reader.
+func (s *Store) readQueries(ctx context.Context) *sqlcdb.Queries {
+	return sqlcdb.New(s.reader(ctx))
+}
@@ -0,0 +1,129 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// newReplicatedMocks returns a store with one replica, both backed by sqlmock.
+func newReplicatedMocks(t *testing.T) (*Store, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	t.Helper()
+	primary, primaryMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { primary.Close() })
+	replica, replicaMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { replica.Close() })
+
+	store := New(primary)
+	store.replicas = newReplicaSet([]*sql.DB{replica}, DefaultOptions())
+	return store, primaryMock, replicaMock
+}
+
+func TestStore_RoutesReadsToReplicas(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func(context.Context) context.Context
+		lagSeconds  float64
+		checksLag   bool
+		wantReplica bool
+	}{
+		{name: "current replica", lagSeconds: 0, checksLag: true, wantReplica: true},
+		{name: "replica within tolerance", lagSeconds: 2, checksLag: true, wantReplica: true},
+		{name: "replica too far behind", lagSeconds: 10, checksLag: true, wantReplica: false},
+		{name: "caller tolerates more lag", lagSeconds: 10, checksLag: true, wantReplica: true,
+			ctx: func(ctx context.Context) context.Context { return WithMaxStaleness(ctx, time.Minute) }},
+		{name: "caller needs the primary", ctx: ReadFromPrimary, checksLag: false, wantReplica: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, primaryMock, replicaMock := newReplicatedMocks(t)
+
+			ctx := context.Background()
+			if tt.ctx != nil {
+				ctx = tt.ctx(ctx)
+			}
+			if tt.checksLag {
+				replicaMock.ExpectQuery("pg_last_wal_replay_lsn").
+					WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(tt.lagSeconds))
+			}
+			reads := primaryMock
+			if tt.wantReplica {
+				reads = replicaMock
+			}
+			reads.ExpectQuery("SELECT (.+) FROM rides").WithArgs("trip-1").WillReturnRows(sqlmock.NewRows(rideRowColumns))
+
+			if _, err := store.GetRide(ctx, "trip-1"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+			if err := primaryMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("primary: %v", err)
+			}
+			if err := replicaMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("replica: %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_UnreachableReplicaFallsBackToPrimary(t *testing.T) {
+	store, primaryMock, replicaMock := newReplicatedMocks(t)
+
+	// The failed lag check is remembered, so the second read does not retry it
+	replicaMock.ExpectQuery("pg_last_wal_replay_lsn").WillReturnError(errors.New("connection refused"))
+	for range 2 {
+		primaryMock.ExpectQuery("SELECT (.+) FROM rides").WithArgs("trip-1").WillReturnRows(sqlmock.NewRows(rideRowColumns))
+	}
+
+	for range 2 {
+		if _, err := store.GetRide(context.Background(), "trip-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("primary: %v", err)
+	}
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("replica: %v", err)
+	}
+}
+
+func TestStore_TransactionsReadFromPrimary(t *testing.T) {
+	store, primaryMock, replicaMock := newReplicatedMocks(t)
+	evt := events.RideEvent{ID: "e1", TripID: "trip-1", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: time.Now()}
+
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectExec("INSERT INTO rides").WillReturnResult(sqlmock.NewResult(0, 1))
+	primaryMock.ExpectQuery("SELECT (.+) FROM rides").WithArgs("trip-1").WillReturnRows(sqlmock.NewRows(rideRowColumns))
+	primaryMock.ExpectCommit()
+
+	err := store.WithTx(context.Background(), func(tx RideStore) error {
+		if err := tx.UpsertRideState(context.Background(), evt); err != nil {
+			return err
+		}
+		if _, err := tx.GetRide(context.Background(), "trip-1"); !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("primary: %v", err)
+	}
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("replica: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsTransient reports whether err is worth retrying: serialization failures,
+// deadlocks, lock timeouts, server shutdowns, connection limits, and dropped or
+// refused connections. Context cancellation is never transient.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", // serialization_failure
+			"40P01", // deadlock_detected
+			"55P03", // lock_not_available
+			"53300", // too_many_connections
+			"57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		// Class 08: connection exception
+		return strings.HasPrefix(pgErr.Code, "08")
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1040, // ER_CON_COUNT_ERROR
+			1053, // ER_SERVER_SHUTDOWN
+			1205, // ER_LOCK_WAIT_TIMEOUT
+			1213: // ER_LOCK_DEADLOCK
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryPolicy bounds how often and how quickly an operation is retried.
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// do runs fn until it succeeds, fails with a permanent error, or runs out of
+// attempts, sleeping with exponential backoff and full jitter in between. Every
+// operation passed to do must be idempotent.
+func (p retryPolicy) do(ctx context.Context, fn func() error) error {
+	err := fn()
+	for attempt := 1; attempt < p.attempts && IsTransient(err); attempt++ {
+		delay := p.backoff << (attempt - 1)
+		if delay <= 0 || delay > p.maxBackoff {
+			delay = p.maxBackoff
+		}
+		if delay > 0 {
+			delay = rand.N(delay) + 1
+		}
+		slog.Warn("Retrying database operation after transient error", "attempt", attempt, "delay", delay, "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		err = fn()
+	}
+	return err
+}
+
+// retryQuerier retries statements that fail with a transient error before
+// returning any rows. It is only used outside transactions, where a statement
+// can be repeated on its own; every statement the store issues is idempotent.
+type retryQuerier struct {
+	db     *sql.DB
+	policy retryPolicy
+}
+
+func (r retryQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var res sql.Result
+	err := r.policy.do(ctx, func() error {
+		var err error
+		res, err = r.db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func (r retryQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := r.policy.do(ctx, func() error {
+		var err error
+		rows, err = r.db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// PrepareContext does not retry; the prepared statement's own calls go straight to the database.
+func (r retryQuerier) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.db.PrepareContext(ctx, query)
+}
+
+// QueryRowContext cannot retry because *sql.Row defers its error to Scan;
+// callers wrap the whole lookup in Store.retry instead.
+func (r retryQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.db.QueryRowContext(ctx, query, args...)
+}
+
+// retry runs fn with the store's retry policy. Inside a transaction fn runs
+// once, since only the whole transaction can be retried.
+func (s *Store) retry(ctx context.Context, fn func() error) error {
+	if s.tx != nil {
+		return fn()
+	}
+	return s.retryPolicy.do(ctx, fn)
+}
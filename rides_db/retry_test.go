@@ -0,0 +1,82 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", fmt.Errorf("upsert: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"bad conn", driver.ErrBadConn, true},
+		{"mysql deadlock", &mysql.MySQLError{Number: 1213}, true},
+		{"mysql duplicate entry", &mysql.MySQLError{Number: 1062}, false},
+		{"context canceled", context.Canceled, false},
+		{"plain error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertEventWindow_RetriesTransientError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db, WithRetry(3, time.Millisecond, time.Millisecond))
+
+	mock.ExpectExec("INSERT INTO ride_event_windows").WillReturnError(&pgconn.PgError{Code: "40P01"})
+	mock.ExpectExec("INSERT INTO ride_event_windows").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.UpsertEventWindow(context.Background(), aggregation.WindowResult{Start: time.Now()}); err != nil {
+		t.Errorf("expected retry to succeed, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpsertEventWindow_PermanentErrorNotRetried(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db, WithRetry(3, time.Millisecond, time.Millisecond))
+	permanent := &pgconn.PgError{Code: "23502"}
+
+	mock.ExpectExec("INSERT INTO ride_event_windows").WillReturnError(permanent)
+
+	if err := store.UpsertEventWindow(context.Background(), aggregation.WindowResult{Start: time.Now()}); !errors.Is(err, permanent) {
+		t.Errorf("expected permanent error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// UpsertRideState folds e into the rides table, which holds the current state of
+// every trip. An event older than the one already applied still fills in missing
+// details but never moves the state backwards. With field encryption the
+// pickup and dropoff coordinates are left NULL.
+func (s *Store) UpsertRideState(ctx context.Context, e events.RideEvent) error {
+	defer observeWrite("rides", time.Now())
+	return s.queries().UpsertRideState(ctx, rideStateParams(s.cipher.withoutCoordinates(e)))
+}
+
+// rideStateParams returns the rides row e contributes, leaving the timestamps
+// and details of other event types NULL.
+func rideStateParams(e events.RideEvent) sqlcdb.UpsertRideStateParams {
+	p := sqlcdb.UpsertRideStateParams{
+		TripID:        e.TripID,
+		State:         string(e.State),
+		LastEventType: string(e.Type),
+		LastEventAt:   e.OccurredAt,
+		DriverID:      nullString(e.DriverID),
+		PassengerID:   nullString(e.PassengerID),
+	}
+	switch e.Type {
+	case events.EventRideRequested:
+		p.RequestedAt = nullTime(e.OccurredAt)
+		if payload, ok := events.PayloadAs[events.RideRequestedPayload](e); ok {
+			p.PickupLat, p.PickupLng = nullCoordinate(payload.Pickup)
+			p.DropoffLat, p.DropoffLng = nullCoordinate(payload.Dropoff)
+		}
+	case events.EventRideAccepted:
+		p.AcceptedAt = nullTime(e.OccurredAt)
+	case events.EventTripStarted:
+		p.StartedAt = nullTime(e.OccurredAt)
+	case events.EventTripCompleted:
+		p.EndedAt = nullTime(e.OccurredAt)
+		if payload, ok := events.PayloadAs[events.RideCompletedPayload](e); ok {
+			p.FareUsd = sql.NullFloat64{Float64: payload.Fare.Float64(), Valid: true}
+		}
+	case events.EventTripCancelled:
+		p.EndedAt = nullTime(e.OccurredAt)
+	}
+	return p
+}
+
+// rideStateArgs returns the rides column values e contributes in insert order,
+// for the backends that do not use the generated queries.
+func rideStateArgs(e events.RideEvent) []any {
+	p := rideStateParams(e)
+	return []any{
+		p.TripID, p.State, p.LastEventType, p.LastEventAt, p.DriverID, p.PassengerID,
+		p.RequestedAt, p.AcceptedAt, p.StartedAt, p.EndedAt, p.FareUsd,
+		p.PickupLat, p.PickupLng, p.DropoffLat, p.DropoffLng,
+	}
+}
+
+func nullCoordinate(c *events.Coordinate) (lat, lng sql.NullFloat64) {
+	if c == nil {
+		return lat, lng
+	}
+	return sql.NullFloat64{Float64: c.Lat, Valid: true}, sql.NullFloat64{Float64: c.Lng, Valid: true}
+}
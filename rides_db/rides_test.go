@@ -0,0 +1,43 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestUpsertRideState_Completed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	now := time.Now()
+	evt := events.RideEvent{
+		TripID:      "trip-1",
+		Type:        events.EventTripCompleted,
+		State:       events.StateCompleted,
+		OccurredAt:  now,
+		DriverID:    "driver-1",
+		PassengerID: "rider-1",
+		Payload:     events.RideCompletedPayload{EndTime: now, DistanceKM: 5, Fare: events.NewMoney(750, events.USD)},
+	}
+
+	mock.ExpectExec("INSERT INTO rides").
+		WithArgs("trip-1", events.StateCompleted, events.EventTripCompleted, now, "driver-1", "rider-1",
+			nil, nil, nil, now, 7.5, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.UpsertRideState(context.Background(), evt); err != nil {
+		t.Errorf("UpsertRideState failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
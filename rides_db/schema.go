@@ -0,0 +1,222 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// schemaPattern matches the names WithSchema accepts: valid unquoted
+// identifiers.
+var schemaPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// storeSchema returns the schema a store opened with o works in, or "" for
+// the default.
+func storeSchema(o Options) (string, error) {
+	if o.Schema == "" {
+		return citySchema(o.City)
+	}
+	if o.City != "" {
+		return "", errors.New("rides_db: WithSchema and WithCity cannot be combined")
+	}
+	if !schemaPattern.MatchString(o.Schema) || o.Schema == "public" || strings.HasPrefix(o.Schema, "pg_") {
+		return "", fmt.Errorf("rides_db: invalid schema %q", o.Schema)
+	}
+	return o.Schema, nil
+}
+
+// Schema returns the schema the store works in, or "" for the default.
+func (s *Store) Schema() string { return s.schema }
+
+// DropSchema drops the store's schema with every table in it, as before a
+// fresh replay into it. It refuses to drop the default schema.
+func (s *Store) DropSchema(ctx context.Context) error {
+	if s.schema == "" {
+		return errors.New("rides_db: the store has no schema of its own to drop")
+	}
+	if _, err := s.db.ExecContext(ctx, `DROP SCHEMA IF EXISTS `+pgx.Identifier{s.schema}.Sanitize()+` CASCADE`); err != nil {
+		return fmt.Errorf("drop schema %s: %w", s.schema, err)
+	}
+	return nil
+}
+
+// SilenceRideStateChanges disables the trigger that announces the store's
+// ride state changes on RideStateChannel, so that listeners do not take a
+// copy's writes for live ones. It needs the migrations applied.
+func (s *Store) SilenceRideStateChanges(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `ALTER TABLE rides DISABLE TRIGGER rides_state_changed`); err != nil {
+		return fmt.Errorf("silence ride state changes: %w", err)
+	}
+	return nil
+}
+
+// TableDiff compares a table's rows in the store's schema with those in
+// another, within the scope DiffSchema gives the table.
+type TableDiff struct {
+	Table    string   `json:"table"`
+	Compared int64    `json:"compared"` // rows in either schema
+	Missing  int64    `json:"missing"`  // rows only in the other schema
+	Extra    int64    `json:"extra"`    // rows only in the store's schema
+	Changed  int64    `json:"changed"`  // rows in both with other values
+	Keys     []string `json:"keys,omitempty"`
+}
+
+// Same reports whether the table's rows agree.
+func (d TableDiff) Same() bool {
+	return d.Missing == 0 && d.Extra == 0 && d.Changed == 0
+}
+
+// diffTable says how DiffSchema compares a table.
+type diffTable struct {
+	name    string
+	key     []string
+	columns []string
+	// encrypted columns are left out with field encryption, as each write
+	// encrypts them anew
+	encrypted []string
+	// scope limits the rows compared in either schema, as a condition on the
+	// row x in which {here} is the store's schema
+	scope string
+}
+
+// tripScope limits a table to the trips the store's schema has events of.
+const tripScope = `x.trip_id IN (SELECT trip_id FROM {here}.ride_events)`
+
+// diffTables are the tables the consumer writes, but for its checkpoints,
+// audit log, and zones, whose rows record when they were written.
+var diffTables = []diffTable{
+	{
+		name: "ride_events",
+		key:  []string{"id"},
+		columns: []string{"trip_id", "event_type", "event_state", "event_time", "driver_id", "passenger_id", "payload",
+			"distance_km", "fare_total", "cancelled_by", "pickup_location", "dropoff_location"},
+		encrypted: []string{"payload", "pickup_location", "dropoff_location"},
+		scope:     tripScope,
+	},
+	{
+		name: "rides",
+		key:  []string{"trip_id"},
+		columns: []string{"state", "last_event_type", "last_event_at", "driver_id", "passenger_id", "requested_at",
+			"accepted_at", "started_at", "ended_at", "fare_usd", "pickup_lat", "pickup_lng", "dropoff_lat", "dropoff_lng"},
+		scope: tripScope,
+	},
+	{
+		name: "trips",
+		key:  []string{"trip_id"},
+		columns: []string{"passenger_id", "driver_id", "final_state", "pickup_location", "dropoff_location",
+			"requested_at", "accepted_at", "started_at", "completed_at", "cancelled_at", "pickup_wait_seconds",
+			"duration_seconds", "accept_latency_seconds", "distance_km", "fare_usd", "cancelled_by", "cancel_reason",
+			"pickup_zone"},
+		encrypted: []string{"pickup_location", "dropoff_location", "pickup_zone"},
+		scope:     tripScope,
+	},
+	{
+		name:    "ride_event_windows",
+		key:     []string{"window_start", "event_type"},
+		columns: []string{"window_end", "event_count", "fare_total"},
+		scope: `x.window_start BETWEEN (SELECT min(window_start) FROM {here}.ride_event_windows)
+			AND (SELECT max(window_start) FROM {here}.ride_event_windows)`,
+	},
+	{
+		name:    "surge_multipliers",
+		key:     []string{"zone_id", "effective_from"},
+		columns: []string{"multiplier"},
+		scope: `x.effective_from BETWEEN (SELECT min(effective_from) FROM {here}.surge_multipliers)
+			AND (SELECT max(effective_from) FROM {here}.surge_multipliers)`,
+	},
+}
+
+// DiffSchema compares the tables the consumer writes in the store's schema
+// with the same tables in other, such as a replay's with the live ones, and
+// lists the keys of up to limit differing rows of each. Each table is
+// compared only where the store's schema has rows: the events, rides, and
+// trips of the trips it has events of, and the windows and surge multipliers
+// within the times it has.
+func (s *Store) DiffSchema(ctx context.Context, other string, limit int) ([]TableDiff, error) {
+	if s.schema == "" {
+		return nil, errors.New("rides_db: the store has no schema of its own to compare")
+	}
+	here, there := pgx.Identifier{s.schema}.Sanitize(), pgx.Identifier{other}.Sanitize()
+	out := make([]TableDiff, 0, len(diffTables))
+	for _, t := range diffTables {
+		d, err := s.diffTable(ctx, t, here, there, limit)
+		if err != nil {
+			return out, fmt.Errorf("diff %s: %w", t.name, err)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (s *Store) diffTable(ctx context.Context, t diffTable, here, there string, limit int) (TableDiff, error) {
+	columns := t.columns
+	if s.cipher != nil {
+		columns = nil
+		for _, c := range t.columns {
+			if !slices.Contains(t.encrypted, c) {
+				columns = append(columns, c)
+			}
+		}
+	}
+	var (
+		selected = strings.Join(append(append([]string(nil), t.key...), columns...), ", ")
+		scope    = strings.ReplaceAll(t.scope, "{here}", here)
+		on       []string
+		key      []string
+		h, o     []string
+	)
+	for _, k := range t.key {
+		on = append(on, "h."+k+" = o."+k)
+		key = append(key, "COALESCE(h."+k+", o."+k+")")
+	}
+	for _, c := range columns {
+		h = append(h, "h."+c)
+		o = append(o, "o."+c)
+	}
+	// A single column is compared as itself, as ROW needs two
+	changed := fmt.Sprintf("(%s) IS DISTINCT FROM (%s)", strings.Join(h, ", "), strings.Join(o, ", "))
+	if len(columns) > 1 {
+		changed = fmt.Sprintf("ROW(%s) IS DISTINCT FROM ROW(%s)", strings.Join(h, ", "), strings.Join(o, ", "))
+	}
+	from := fmt.Sprintf(`
+		WITH h AS (SELECT %[1]s FROM %[2]s.%[4]s x WHERE %[5]s),
+			o AS (SELECT %[1]s FROM %[3]s.%[4]s x WHERE %[5]s)
+		SELECT %[6]s AS k, h.%[7]s IS NULL AS missing, o.%[7]s IS NULL AS extra, %[8]s AS changed
+		FROM h FULL JOIN o ON %[9]s`,
+		selected, here, there, t.name, scope, "concat_ws('/', "+strings.Join(key, ", ")+")", t.key[0],
+		changed, strings.Join(on, " AND "))
+
+	d := TableDiff{Table: t.name}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT count(*),
+			count(*) FILTER (WHERE missing),
+			count(*) FILTER (WHERE extra),
+			count(*) FILTER (WHERE NOT missing AND NOT extra AND changed)
+		FROM (`+from+`) d`).Scan(&d.Compared, &d.Missing, &d.Extra, &d.Changed)
+	if err != nil || d.Same() || limit <= 0 {
+		return d, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT k FROM (`+from+`) d
+		WHERE missing OR extra OR changed
+		ORDER BY k
+		LIMIT $1`, limit)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return d, err
+		}
+		d.Keys = append(d.Keys, k)
+	}
+	return d, rows.Err()
+}
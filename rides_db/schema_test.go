@@ -0,0 +1,109 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStoreSchema(t *testing.T) {
+	tests := []struct {
+		opts    Options
+		want    string
+		wantErr bool
+	}{
+		{Options{}, "", false},
+		{Options{City: "austin"}, "city_austin", false},
+		{Options{Schema: "replay"}, "replay", false},
+		{Options{Schema: "replay", City: "austin"}, "", true},
+		{Options{Schema: "public"}, "", true},
+		{Options{Schema: "pg_catalog"}, "", true},
+		{Options{Schema: `replay"; DROP`}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := storeSchema(tt.opts)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("storeSchema(%+v) = %q, %v; want %q, error %v", tt.opts, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestDropSchema_RefusesDefault(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	if err := New(db).DropSchema(context.Background()); err == nil {
+		t.Error("DropSchema on the default schema succeeded, want an error")
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	store.schema = "replay"
+	counts := []string{"count", "missing", "extra", "changed"}
+	for _, table := range diffTables {
+		row := []driver.Value{int64(10), int64(0), int64(0), int64(0)}
+		if table.name == "trips" {
+			row = []driver.Value{int64(10), int64(1), int64(0), int64(2)}
+		}
+		mock.ExpectQuery(`FROM "replay".` + table.name + ` x .* FROM "public".` + table.name + ` x`).
+			WillReturnRows(sqlmock.NewRows(counts).AddRow(row...))
+		if table.name == "trips" {
+			mock.ExpectQuery(`SELECT k FROM .* LIMIT \$1`).WithArgs(5).
+				WillReturnRows(sqlmock.NewRows([]string{"k"}).AddRow("trip-1").AddRow("trip-2").AddRow("trip-3"))
+		}
+	}
+
+	diffs, err := store.DiffSchema(context.Background(), "public", 5)
+	if err != nil {
+		t.Fatalf("DiffSchema failed: %v", err)
+	}
+	if len(diffs) != len(diffTables) {
+		t.Fatalf("got %d tables, want %d", len(diffs), len(diffTables))
+	}
+	want := TableDiff{Table: "trips", Compared: 10, Missing: 1, Changed: 2, Keys: []string{"trip-1", "trip-2", "trip-3"}}
+	for _, d := range diffs {
+		if d.Table == "trips" && !reflect.DeepEqual(d, want) {
+			t.Errorf("trips = %+v, want %+v", d, want)
+		}
+		if d.Table != "trips" && !d.Same() {
+			t.Errorf("%s differs: %+v", d.Table, d)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDiffTable_LeavesOutEncryptedColumns(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(_, sql string) error {
+		if strings.Contains(sql, "pickup_location") {
+			t.Errorf("query compares an encrypted column:\n%s", sql)
+		}
+		return nil
+	})))
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db, WithFieldEncryption(testKeys("k1")))
+	store.schema = "replay"
+	mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"count", "missing", "extra", "changed"}).AddRow(0, 0, 0, 0))
+	if _, err := store.diffTable(context.Background(), diffTables[2], `"replay"`, `"public"`, 5); err != nil {
+		t.Fatalf("diffTable failed: %v", err)
+	}
+}
@@ -0,0 +1,738 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// SQLiteStore is a RideStore backed by an SQLite file, for running the pipeline
+// locally without PostgreSQL and for store tests against a real database. It
+// mirrors the PostgreSQL schema and upsert rules; partitioning, TimescaleDB,
+// and PostGIS are not available, and RidesWithinRadius filters in Go.
+type SQLiteStore struct {
+	db           *sql.DB
+	q            querier
+	tx           *sql.Tx
+	queryTimeout time.Duration
+	cipher       *fieldCipher
+	slowQuery    time.Duration
+}
+
+var _ RideStore = (*SQLiteStore)(nil)
+
+// OpenSQLite opens (creating if needed) the SQLite database at path; ":memory:"
+// gives a private in-memory database. Only QueryTimeout, SlowQueryThreshold,
+// and FieldKeys are taken from opts, as SQLite is limited to a single
+// connection so writers never see "database is locked".
+func OpenSQLite(path string, opts ...Option) (*SQLiteStore, error) {
+	o := buildOptions(opts)
+
+	dsn := "file:" + path + "?_busy_timeout=5000&_foreign_keys=on"
+	if path != ":memory:" {
+		dsn += "&_journal_mode=WAL"
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.ConnectTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{
+		db: db, q: withSlowQueryLog(db, o.SlowQueryThreshold),
+		queryTimeout: o.QueryTimeout, cipher: newFieldCipher(o.FieldKeys), slowQuery: o.SlowQueryThreshold,
+	}, nil
+}
+
+// DB returns the underlying database handle.
+func (s *SQLiteStore) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Migrate applies the embedded SQLite migrations.
+func (s *SQLiteStore) Migrate(ctx context.Context) error {
+	migrations, err := loadMigrations("migrations_sqlite")
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return applyPending(ctx, conn, migrations, recordMigrationSQL, auditInsertSQL)
+}
+
+// WithTx runs fn inside one transaction; see Store.WithTx.
+func (s *SQLiteStore) WithTx(ctx context.Context, fn func(tx RideStore) error) error {
+	return s.withTx(ctx, func(tx *SQLiteStore) error { return fn(tx) })
+}
+
+func (s *SQLiteStore) withTx(ctx context.Context, fn func(tx *SQLiteStore) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&SQLiteStore{
+		db: s.db, q: withSlowQueryLog(tx, s.slowQuery), tx: tx,
+		queryTimeout: s.queryTimeout, cipher: s.cipher, slowQuery: s.slowQuery,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const sqliteInsertRideEventSQL = `
+	INSERT INTO ride_events
+	(id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload,
+	 distance_km, fare_total, cancelled_by, pickup_location, dropoff_location)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	ON CONFLICT DO NOTHING
+`
+
+// sqliteRideEventArgs adapts rideEventArgs to SQLite: the payload is bound as
+// text so the JSON functions read it, and times are normalised to UTC.
+func sqliteRideEventArgs(e events.RideEvent) ([]any, error) {
+	args, err := rideEventArgs(e)
+	if err != nil {
+		return nil, err
+	}
+	args[7] = string(args[7].([]byte))
+	return utcArgs(args), nil
+}
+
+// sqliteSeenEventSQL counts stored events matching an event's ID, trip, and type.
+const sqliteSeenEventSQL = `SELECT COUNT(*) FROM ride_events WHERE id = $1 AND trip_id = $2 AND event_type = $3`
+
+// InsertRideEvent stores e and reports what happened to it; see Store.InsertRideEvent.
+func (s *SQLiteStore) InsertRideEvent(ctx context.Context, e events.RideEvent) (outcome InsertOutcome, err error) {
+	defer func(start time.Time) { observeInsert(start, outcome, err) }(time.Now())
+	e, err = s.cipher.encryptEvent(ctx, e)
+	if err != nil {
+		return "", err
+	}
+	args, err := sqliteRideEventArgs(e)
+	if err != nil {
+		return "", err
+	}
+	res, err := s.q.ExecContext(ctx, sqliteInsertRideEventSQL, args...)
+	if err != nil {
+		return "", err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return OutcomeInserted, err
+	}
+	return skippedEventOutcome(ctx, s.q, sqliteSeenEventSQL, e)
+}
+
+// InsertRideEvents stores evts in one transaction and returns how many rows were inserted.
+func (s *SQLiteStore) InsertRideEvents(ctx context.Context, evts []events.RideEvent) (inserted int64, err error) {
+	defer func(start time.Time) { observeBatch(start, len(evts), inserted, err) }(time.Now())
+	evts, err = s.cipher.encryptEvents(ctx, evts)
+	if err != nil {
+		return 0, err
+	}
+	err = s.withTx(ctx, func(tx *SQLiteStore) error {
+		for _, e := range evts {
+			args, err := sqliteRideEventArgs(e)
+			if err != nil {
+				return err
+			}
+			res, err := tx.q.ExecContext(ctx, sqliteInsertRideEventSQL, args...)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			inserted += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// UpsertRideState folds e into the rides table; see Store.UpsertRideState.
+func (s *SQLiteStore) UpsertRideState(ctx context.Context, e events.RideEvent) error {
+	defer observeWrite("rides", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO rides
+		(trip_id, state, last_event_type, last_event_at, driver_id, passenger_id,
+		 requested_at, accepted_at, started_at, ended_at, fare_usd,
+		 pickup_lat, pickup_lng, dropoff_lat, dropoff_lng)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (trip_id) DO UPDATE SET
+			state = CASE WHEN excluded.last_event_at >= rides.last_event_at
+				THEN excluded.state ELSE rides.state END,
+			last_event_type = CASE WHEN excluded.last_event_at >= rides.last_event_at
+				THEN excluded.last_event_type ELSE rides.last_event_type END,
+			last_event_at = MAX(rides.last_event_at, excluded.last_event_at),
+			driver_id = COALESCE(excluded.driver_id, rides.driver_id),
+			passenger_id = COALESCE(excluded.passenger_id, rides.passenger_id),
+			requested_at = COALESCE(rides.requested_at, excluded.requested_at),
+			accepted_at = COALESCE(rides.accepted_at, excluded.accepted_at),
+			started_at = COALESCE(rides.started_at, excluded.started_at),
+			ended_at = COALESCE(rides.ended_at, excluded.ended_at),
+			fare_usd = COALESCE(excluded.fare_usd, rides.fare_usd),
+			pickup_lat = COALESCE(rides.pickup_lat, excluded.pickup_lat),
+			pickup_lng = COALESCE(rides.pickup_lng, excluded.pickup_lng),
+			dropoff_lat = COALESCE(rides.dropoff_lat, excluded.dropoff_lat),
+			dropoff_lng = COALESCE(rides.dropoff_lng, excluded.dropoff_lng)
+	`, utcArgs(rideStateArgs(s.cipher.withoutCoordinates(e)))...)
+	return err
+}
+
+// InsertTrip writes the assembled summary of a finished trip, replacing any earlier row.
+func (s *SQLiteStore) InsertTrip(ctx context.Context, t aggregation.Trip) error {
+	defer observeWrite("trips", time.Now())
+	t, err := s.cipher.encryptTrip(ctx, t)
+	if err != nil {
+		return err
+	}
+	_, err = s.q.ExecContext(ctx, `
+		INSERT OR REPLACE INTO trips
+		(trip_id, passenger_id, driver_id, final_state, pickup_location, dropoff_location,
+		 requested_at, accepted_at, started_at, completed_at, cancelled_at,
+		 pickup_wait_seconds, duration_seconds, distance_km, fare_usd, cancelled_by, cancel_reason,
+		 accept_latency_seconds, pickup_zone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+	`, utcArgs(tripArgs(t))...)
+	return err
+}
+
+// RefreshTrip rebuilds the trips row for tripID by replaying its stored events
+// through a TripAssembler. Trips without a terminal event are left alone.
+func (s *SQLiteStore) RefreshTrip(ctx context.Context, tripID string) error {
+	evts, err := s.GetTripEvents(ctx, tripID)
+	if err != nil {
+		return err
+	}
+	assembler := aggregation.NewTripAssembler()
+	for _, e := range evts {
+		if trip, done := assembler.Add(e); done {
+			return s.InsertTrip(ctx, trip)
+		}
+	}
+	return nil
+}
+
+// GetTrip returns the assembled summary of a finished trip, or ErrNotFound.
+func (s *SQLiteStore) GetTrip(ctx context.Context, tripID string) (aggregation.Trip, error) {
+	row := s.q.QueryRowContext(ctx, `SELECT `+tripColumns+` FROM trips WHERE trip_id = $1`, tripID)
+	t, err := scanTrip(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return aggregation.Trip{}, ErrNotFound
+	}
+	if err != nil {
+		return aggregation.Trip{}, err
+	}
+	return s.cipher.decryptTrip(ctx, t)
+}
+
+// ListTrips returns finished trips matching f, newest request first.
+func (s *SQLiteStore) ListTrips(ctx context.Context, f TripFilter) ([]aggregation.Trip, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if !f.From.IsZero() {
+		add("requested_at >= $%d", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		add("requested_at < $%d", f.To.UTC())
+	}
+	if f.State != "" {
+		add("final_state = $%d", f.State)
+	}
+	if f.DriverID != "" {
+		add("driver_id = $%d", f.DriverID)
+	}
+
+	query := `SELECT ` + tripColumns + ` FROM trips`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit, f.Offset)
+	query += fmt.Sprintf(` ORDER BY requested_at IS NULL, requested_at DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trips []aggregation.Trip
+	for rows.Next() {
+		t, err := scanTrip(rows)
+		if err != nil {
+			return nil, err
+		}
+		trips = append(trips, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return s.cipher.decryptTrips(ctx, trips)
+}
+
+// GetTripEvents returns every stored event of a trip in event-time order.
+func (s *SQLiteStore) GetTripEvents(ctx context.Context, tripID string) ([]events.RideEvent, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT id, trip_id, event_type, event_state, event_time, driver_id, passenger_id, payload
+		FROM ride_events
+		WHERE trip_id = $1
+		ORDER BY event_time, id
+	`, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var evts []events.RideEvent
+	for rows.Next() {
+		e, err := scanRideEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		if e, err = s.cipher.decryptEvent(ctx, e); err != nil {
+			return nil, err
+		}
+		evts = append(evts, e)
+	}
+	return evts, rows.Err()
+}
+
+// GetRide returns the current state of a trip, or ErrNotFound.
+func (s *SQLiteStore) GetRide(ctx context.Context, tripID string) (Ride, error) {
+	row := s.q.QueryRowContext(ctx, `SELECT `+rideColumns+` FROM rides WHERE trip_id = $1`, tripID)
+	r, err := scanRide(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Ride{}, ErrNotFound
+	}
+	return r, err
+}
+
+// ListActiveRides returns rides that have not completed or been cancelled,
+// most recently updated first.
+func (s *SQLiteStore) ListActiveRides(ctx context.Context, limit, offset int) ([]Ride, error) {
+	return s.queryRides(ctx, `
+		SELECT `+rideColumns+`
+		FROM rides
+		WHERE state IN ('REQUESTED', 'ACCEPTED', 'IN_PROGRESS')
+		ORDER BY last_event_at DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+}
+
+// ListRidesByDriver returns the rides a driver accepted that were requested within tr.
+func (s *SQLiteStore) ListRidesByDriver(ctx context.Context, driverID string, tr TimeRange) ([]Ride, error) {
+	return s.queryRides(ctx, `
+		SELECT `+rideColumns+`
+		FROM rides
+		WHERE driver_id = $1
+		  AND ($2 IS NULL OR requested_at >= $2)
+		  AND ($3 IS NULL OR requested_at < $3)
+		ORDER BY requested_at DESC
+	`, utcArgs([]any{driverID, nullTime(tr.From), nullTime(tr.To)})...)
+}
+
+// RidesWithinRadius returns rides picked up within meters of (lat, lng), nearest
+// first. Without PostGIS the candidates are narrowed by a bounding box in SQL
+// and filtered by great-circle distance in Go.
+func (s *SQLiteStore) RidesWithinRadius(ctx context.Context, lat, lng, meters float64) ([]Ride, error) {
+	minLat, maxLat, minLng, maxLng := boundingBox(lat, lng, meters)
+	candidates, err := s.queryRides(ctx, `
+		SELECT `+rideColumns+`
+		FROM rides
+		WHERE pickup_lat BETWEEN $1 AND $2 AND pickup_lng BETWEEN $3 AND $4
+	`, minLat, maxLat, minLng, maxLng)
+	if err != nil {
+		return nil, err
+	}
+	return nearestWithin(candidates, events.Coordinate{Lat: lat, Lng: lng}, meters), nil
+}
+
+// boundingBox returns a latitude/longitude box containing every point within
+// meters of (lat, lng), for narrowing radius searches with plain indexes.
+func boundingBox(lat, lng, meters float64) (minLat, maxLat, minLng, maxLng float64) {
+	const metersPerDegree = 111_320.0
+	dLat := meters / metersPerDegree
+	dLng := meters / (metersPerDegree * math.Max(math.Cos(lat*math.Pi/180), 1e-6))
+	return lat - dLat, lat + dLat, lng - dLng, lng + dLng
+}
+
+// nearestWithin keeps the candidates picked up within meters of origin, nearest first.
+func nearestWithin(candidates []Ride, origin events.Coordinate, meters float64) []Ride {
+	dist := make(map[string]float64, len(candidates))
+	var rides []Ride
+	for _, r := range candidates {
+		if r.Pickup == nil {
+			continue
+		}
+		d := origin.DistanceM(*r.Pickup)
+		if d <= meters {
+			dist[r.TripID] = d
+			rides = append(rides, r)
+		}
+	}
+	sort.Slice(rides, func(i, j int) bool { return dist[rides[i].TripID] < dist[rides[j].TripID] })
+	return rides
+}
+
+func (s *SQLiteStore) queryRides(ctx context.Context, query string, args ...any) ([]Ride, error) {
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rides []Ride
+	for rows.Next() {
+		r, err := scanRide(rows)
+		if err != nil {
+			return nil, err
+		}
+		rides = append(rides, r)
+	}
+	return rides, rows.Err()
+}
+
+// RevenueByDay sums completed-trip fares per UTC day of completion.
+func (s *SQLiteStore) RevenueByDay(ctx context.Context, tr TimeRange) ([]DailyRevenue, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT strftime('%Y-%m-%d', completed_at) AS day, COUNT(*), COALESCE(SUM(fare_usd), 0)
+		FROM trips
+		WHERE final_state = 'COMPLETED'
+		  AND ($1 IS NULL OR completed_at >= $1)
+		  AND ($2 IS NULL OR completed_at < $2)
+		GROUP BY day
+		ORDER BY day
+	`, utcArgs([]any{nullTime(tr.From), nullTime(tr.To)})...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DailyRevenue
+	for rows.Next() {
+		var (
+			r   DailyRevenue
+			day string
+		)
+		if err := rows.Scan(&day, &r.Trips, &r.RevenueUSD); err != nil {
+			return nil, err
+		}
+		if r.Day, err = time.Parse(time.DateOnly, day); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// CancellationRateByHour reports, per UTC hour of request time, the share of
+// trips that were cancelled.
+func (s *SQLiteStore) CancellationRateByHour(ctx context.Context, tr TimeRange) ([]HourlyCancellationRate, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT strftime('%Y-%m-%d %H:00:00', requested_at) AS hour,
+		       COUNT(*),
+		       SUM(CASE WHEN final_state = 'CANCELLED' THEN 1 ELSE 0 END)
+		FROM trips
+		WHERE requested_at IS NOT NULL
+		  AND ($1 IS NULL OR requested_at >= $1)
+		  AND ($2 IS NULL OR requested_at < $2)
+		GROUP BY hour
+		ORDER BY hour
+	`, utcArgs([]any{nullTime(tr.From), nullTime(tr.To)})...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []HourlyCancellationRate
+	for rows.Next() {
+		var (
+			r    HourlyCancellationRate
+			hour string
+		)
+		if err := rows.Scan(&hour, &r.Requested, &r.Cancelled); err != nil {
+			return nil, err
+		}
+		if r.Hour, err = time.Parse(time.DateTime, hour); err != nil {
+			return nil, err
+		}
+		if r.Requested > 0 {
+			r.Rate = float64(r.Cancelled) / float64(r.Requested)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// AvgFareByZone averages completed-trip fares by pickup location, highest first.
+func (s *SQLiteStore) AvgFareByZone(ctx context.Context, tr TimeRange) ([]ZoneFare, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT pickup_location, COUNT(*), AVG(fare_usd)
+		FROM trips
+		WHERE final_state = 'COMPLETED'
+		  AND pickup_location IS NOT NULL
+		  AND ($1 IS NULL OR completed_at >= $1)
+		  AND ($2 IS NULL OR completed_at < $2)
+		GROUP BY pickup_location
+		ORDER BY 3 DESC
+	`, utcArgs([]any{nullTime(tr.From), nullTime(tr.To)})...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ZoneFare
+	for rows.Next() {
+		var z ZoneFare
+		if err := rows.Scan(&z.Zone, &z.Trips, &z.AvgFareUSD); err != nil {
+			return nil, err
+		}
+		out = append(out, z)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return s.cipher.decryptZones(ctx, out)
+}
+
+// TopDriversByTrips ranks drivers by completed trips, breaking ties on revenue.
+func (s *SQLiteStore) TopDriversByTrips(ctx context.Context, tr TimeRange, limit int) ([]DriverTrips, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT driver_id, COUNT(*), COALESCE(SUM(fare_usd), 0) AS revenue
+		FROM trips
+		WHERE final_state = 'COMPLETED'
+		  AND driver_id IS NOT NULL
+		  AND ($1 IS NULL OR completed_at >= $1)
+		  AND ($2 IS NULL OR completed_at < $2)
+		GROUP BY driver_id
+		ORDER BY 2 DESC, revenue DESC
+		LIMIT $3
+	`, utcArgs([]any{nullTime(tr.From), nullTime(tr.To), limit})...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DriverTrips
+	for rows.Next() {
+		var d DriverTrips
+		if err := rows.Scan(&d.DriverID, &d.Trips, &d.RevenueUSD); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RevenueBySurge sums completed-trip fares by surge multiplier; see Store.RevenueBySurge.
+func (s *SQLiteStore) RevenueBySurge(ctx context.Context, tr TimeRange) ([]SurgeRevenue, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT multiplier, COUNT(*), COALESCE(SUM(fare_usd), 0)
+		FROM (
+			SELECT t.fare_usd,
+			       COALESCE((
+			           SELECT s.multiplier FROM surge_multipliers s
+			           WHERE s.zone_id = t.pickup_zone
+			             AND s.effective_from <= t.requested_at
+			           ORDER BY s.effective_from DESC
+			           LIMIT 1
+			       ), 1.0) AS multiplier
+			FROM trips t
+			WHERE t.final_state = 'COMPLETED'
+			  AND ($1 IS NULL OR t.completed_at >= $1)
+			  AND ($2 IS NULL OR t.completed_at < $2)
+		)
+		GROUP BY multiplier
+		ORDER BY multiplier
+	`, utcArgs([]any{nullTime(tr.From), nullTime(tr.To)})...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SurgeRevenue
+	for rows.Next() {
+		var r SurgeRevenue
+		if err := rows.Scan(&r.Multiplier, &r.Trips, &r.RevenueUSD); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// UpsertEventWindow stores a windowed aggregate; see Store.UpsertEventWindow.
+func (s *SQLiteStore) UpsertEventWindow(ctx context.Context, w aggregation.WindowResult) error {
+	defer observeWrite("ride_event_windows", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO ride_event_windows
+		(window_start, window_end, event_type, event_count, fare_total, is_correction, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (window_start, event_type) DO UPDATE
+		SET event_count = excluded.event_count,
+		    fare_total = excluded.fare_total,
+		    is_correction = ride_event_windows.is_correction OR excluded.is_correction,
+		    updated_at = excluded.updated_at
+	`, utcArgs([]any{w.Start, w.End, w.EventType, w.Count, w.FareTotal.Float64(), w.IsCorrection, time.Now()})...)
+	return err
+}
+
+// UpdateCheckpoint upserts the processing position for a partition; offsets only move forward.
+func (s *SQLiteStore) UpdateCheckpoint(ctx context.Context, cp Checkpoint) error {
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO consumer_checkpoints
+		(consumer_group, topic, partition, last_offset, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (consumer_group, topic, partition) DO UPDATE
+		SET last_offset = excluded.last_offset, updated_at = excluded.updated_at
+		WHERE consumer_checkpoints.last_offset < excluded.last_offset
+	`, cp.Group, cp.Topic, cp.Partition, cp.Offset, cp.UpdatedAt.UTC())
+	return err
+}
+
+// GetCheckpoints returns every checkpoint recorded for a consumer group.
+func (s *SQLiteStore) GetCheckpoints(ctx context.Context, group string) ([]Checkpoint, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT consumer_group, topic, partition, last_offset, updated_at
+		FROM consumer_checkpoints
+		WHERE consumer_group = $1
+		ORDER BY topic, partition
+	`, group)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkpoints []Checkpoint
+	for rows.Next() {
+		var cp Checkpoint
+		if err := rows.Scan(&cp.Group, &cp.Topic, &cp.Partition, &cp.Offset, &cp.UpdatedAt); err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
+
+// utcArgs converts time arguments to UTC in place. SQLite stores times as text,
+// so mixing zones would break comparisons and ordering.
+func utcArgs(args []any) []any {
+	for i, a := range args {
+		switch v := a.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case sql.NullTime:
+			if v.Valid {
+				args[i] = v.Time.UTC()
+			} else {
+				args[i] = nil
+			}
+		}
+	}
+	return args
+}
+
+// SQLite has a single writer, so pending rows need no locking.
+var sqliteOutboxSQL = outboxSQL{
+	enqueue: `INSERT INTO ride_outbox (topic, message_key, payload, headers) VALUES ($1, $2, $3, $4)`,
+	pending: `
+		SELECT id, topic, message_key, payload, headers, created_at, attempts
+		FROM ride_outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`,
+	published: `UPDATE ride_outbox SET published_at = $1 WHERE id = $2`,
+	failed:    `UPDATE ride_outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2`,
+}
+
+// EnqueueOutbox writes m to the outbox; see Store.EnqueueOutbox.
+func (s *SQLiteStore) EnqueueOutbox(ctx context.Context, m OutboxMessage) error {
+	return enqueueOutbox(ctx, s.q, sqliteOutboxSQL, m, true)
+}
+
+// ProcessOutbox publishes a batch of outbox messages; see Store.ProcessOutbox.
+func (s *SQLiteStore) ProcessOutbox(ctx context.Context, limit int, publish func(OutboxMessage) error) (int, error) {
+	var batch outboxBatch
+	err := s.withTx(ctx, func(tx *SQLiteStore) error {
+		var err error
+		batch, err = processOutbox(ctx, sqlOutbox{tx.q, sqliteOutboxSQL}, limit, publish)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return batch.published, batch.publishErr
+}
@@ -0,0 +1,224 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/events/eventstest"
+)
+
+func openTestSQLite(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	return store
+}
+
+func completedTrip(tripID string, base time.Time) []events.RideEvent {
+	return []events.RideEvent{
+		{ID: tripID + "-1", TripID: tripID, Type: events.EventRideRequested, State: events.StateRequested, OccurredAt: base, PassengerID: "rider-1",
+			Payload: events.RideRequestedPayload{Passenger: "rider-1", PickupLocation: "Main St", DropoffLocation: "Elm St",
+				Pickup: &events.Coordinate{Lat: 40.7500, Lng: -73.9800}}},
+		{ID: tripID + "-2", TripID: tripID, Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: base.Add(time.Minute), DriverID: "driver-1",
+			Payload: events.RideAcceptedPayload{DriverID: "driver-1"}},
+		{ID: tripID + "-3", TripID: tripID, Type: events.EventTripStarted, State: events.StateInProgress, OccurredAt: base.Add(5 * time.Minute),
+			Payload: events.RideStartedPayload{StartTime: base.Add(5 * time.Minute)}},
+		{ID: tripID + "-4", TripID: tripID, Type: events.EventTripCompleted, State: events.StateCompleted, OccurredAt: base.Add(20 * time.Minute),
+			Payload: events.RideCompletedPayload{EndTime: base.Add(20 * time.Minute), DistanceKM: 6, Fare: events.NewMoney(850, events.USD)}},
+	}
+}
+
+func TestSQLiteStore_TripLifecycle(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	for _, e := range completedTrip("trip-1", base) {
+		err := store.WithTx(ctx, func(tx RideStore) error {
+			if _, err := tx.InsertRideEvent(ctx, e); err != nil {
+				return err
+			}
+			return tx.UpsertRideState(ctx, e)
+		})
+		if err != nil {
+			t.Fatalf("persisting %s failed: %v", e.Type, err)
+		}
+	}
+
+	// A redelivered event is ignored
+	inserted, err := store.InsertRideEvents(ctx, completedTrip("trip-1", base)[:1])
+	if err != nil || inserted != 0 {
+		t.Errorf("expected duplicate to be skipped, got n=%d err=%v", inserted, err)
+	}
+
+	ride, err := store.GetRide(ctx, "trip-1")
+	if err != nil {
+		t.Fatalf("GetRide failed: %v", err)
+	}
+	if ride.State != events.StateCompleted || ride.DriverID != "driver-1" || ride.FareUSD != 8.5 || !ride.RequestedAt.Equal(base) {
+		t.Errorf("unexpected ride: %+v", ride)
+	}
+
+	if err := store.RefreshTrip(ctx, "trip-1"); err != nil {
+		t.Fatalf("RefreshTrip failed: %v", err)
+	}
+	trip, err := store.GetTrip(ctx, "trip-1")
+	if err != nil {
+		t.Fatalf("GetTrip failed: %v", err)
+	}
+	if wait, ok := trip.PickupWait(); !ok || wait != 5*time.Minute || trip.FareUSD != 8.5 {
+		t.Errorf("unexpected trip: %+v", trip)
+	}
+
+	revenue, err := store.RevenueByDay(ctx, TimeRange{})
+	if err != nil {
+		t.Fatalf("RevenueByDay failed: %v", err)
+	}
+	if len(revenue) != 1 || !revenue[0].Day.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || revenue[0].RevenueUSD != 8.5 {
+		t.Errorf("unexpected revenue: %+v", revenue)
+	}
+
+	nearby, err := store.RidesWithinRadius(ctx, 40.7505, -73.9805, 200)
+	if err != nil {
+		t.Fatalf("RidesWithinRadius failed: %v", err)
+	}
+	if len(nearby) != 1 {
+		t.Errorf("expected the ride within 200m, got %d", len(nearby))
+	}
+	if far, _ := store.RidesWithinRadius(ctx, 40.80, -73.90, 200); len(far) != 0 {
+		t.Errorf("expected no rides far away, got %d", len(far))
+	}
+}
+
+// TestSQLiteStore_CanonicalEvents checks that the canonical lifecycle events
+// read back from the store as they were written.
+func TestSQLiteStore_CanonicalEvents(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+
+	byTrip := map[string][]events.RideEvent{}
+	for _, e := range eventstest.Canonical() {
+		if !e.Type.IsLifecycle() {
+			continue
+		}
+		if _, err := store.InsertRideEvent(ctx, e); err != nil {
+			t.Fatalf("InsertRideEvent %s failed: %v", e.Type, err)
+		}
+		if err := store.UpsertRideState(ctx, e); err != nil {
+			t.Fatalf("UpsertRideState %s failed: %v", e.Type, err)
+		}
+		byTrip[e.TripID] = append(byTrip[e.TripID], e)
+	}
+
+	for tripID, want := range byTrip {
+		got, err := store.GetTripEvents(ctx, tripID)
+		if err != nil {
+			t.Fatalf("GetTripEvents failed: %v", err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("trip %s: expected %d events, got %d", tripID, len(want), len(got))
+		}
+		for i, e := range got {
+			w := want[i]
+			if d := events.Diff(w, e); len(d) != 0 || e.ID != w.ID || !e.OccurredAt.Equal(w.OccurredAt) {
+				t.Errorf("trip %s event %d: differs in %v\ngot  %+v\nwant %+v", tripID, i+1, d, e, w)
+			}
+		}
+		ride, err := store.GetRide(ctx, tripID)
+		if err != nil {
+			t.Fatalf("GetRide failed: %v", err)
+		}
+		if last := want[len(want)-1]; ride.State != last.State {
+			t.Errorf("trip %s: ride state %s, want %s", tripID, ride.State, last.State)
+		}
+	}
+}
+
+func TestSQLiteStore_RideStateNeverMovesBackwards(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	evts := completedTrip("trip-2", time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC))
+
+	// Deliver the completion before the acceptance
+	for _, e := range []events.RideEvent{evts[3], evts[1]} {
+		if err := store.UpsertRideState(ctx, e); err != nil {
+			t.Fatalf("UpsertRideState failed: %v", err)
+		}
+	}
+
+	ride, err := store.GetRide(ctx, "trip-2")
+	if err != nil {
+		t.Fatalf("GetRide failed: %v", err)
+	}
+	if ride.State != events.StateCompleted || ride.AcceptedAt.IsZero() {
+		t.Errorf("unexpected ride after out-of-order events: %+v", ride)
+	}
+	if _, err := store.GetRide(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSQLiteStore_CheckpointsOnlyMoveForward(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+
+	for _, offset := range []int64{10, 4} {
+		if err := store.UpdateCheckpoint(ctx, Checkpoint{Group: "g", Topic: "ride-events", Partition: 0, Offset: offset, UpdatedAt: time.Now()}); err != nil {
+			t.Fatalf("UpdateCheckpoint failed: %v", err)
+		}
+	}
+
+	cps, err := store.GetCheckpoints(ctx, "g")
+	if err != nil {
+		t.Fatalf("GetCheckpoints failed: %v", err)
+	}
+	if len(cps) != 1 || cps[0].Offset != 10 {
+		t.Errorf("unexpected checkpoints: %+v", cps)
+	}
+}
+
+func TestSQLiteStore_InsertRideEventOutcomes(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	requested := completedTrip("trip-1", base)[0]
+
+	if outcome, err := store.InsertRideEvent(ctx, requested); err != nil || outcome != OutcomeInserted {
+		t.Fatalf("first insert: outcome=%q err=%v", outcome, err)
+	}
+
+	reusedID := requested
+	reusedID.TripID = "trip-2"
+	sameKey := requested
+	sameKey.ID = "other-id"
+
+	tests := []struct {
+		name string
+		e    events.RideEvent
+		want InsertOutcome
+	}{
+		{"redelivery", requested, OutcomeDuplicate},
+		{"ID reused for another trip", reusedID, OutcomeConflict},
+		{"same trip, type, and time under a new ID", sameKey, OutcomeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, err := store.InsertRideEvent(ctx, tt.e)
+			if err != nil {
+				t.Fatalf("InsertRideEvent failed: %v", err)
+			}
+			if outcome != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, outcome)
+			}
+		})
+	}
+}
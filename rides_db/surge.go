@@ -0,0 +1,104 @@
+package rides_db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// Zone is a pricing zone. Its ID matches the pickup location trips record, so
+// trips can be joined to the zone's surge history.
+type Zone struct {
+	ID        string
+	Name      string
+	UpdatedAt time.Time
+}
+
+// SurgeMultiplier is the fare multiplier of a zone from EffectiveFrom until the
+// zone's next update.
+type SurgeMultiplier struct {
+	ZoneID        string
+	EffectiveFrom time.Time
+	Multiplier    float64
+}
+
+// UpsertZone creates or renames a zone. Updates only move forward in time, so a
+// redelivered older event never undoes a newer name.
+func (s *Store) UpsertZone(ctx context.Context, z Zone) error {
+	defer observeWrite("zones", time.Now())
+	return s.queries().UpsertZone(ctx, sqlcdb.UpsertZoneParams{
+		ZoneID:    z.ID,
+		Name:      zoneName(z),
+		UpdatedAt: z.UpdatedAt,
+	})
+}
+
+// UpsertSurgeMultiplier records a zone's multiplier from m.EffectiveFrom on. The
+// zone must exist. Recording the same zone and time again replaces the multiplier.
+func (s *Store) UpsertSurgeMultiplier(ctx context.Context, m SurgeMultiplier) error {
+	defer observeWrite("surge_multipliers", time.Now())
+	return s.queries().UpsertSurgeMultiplier(ctx, sqlcdb.UpsertSurgeMultiplierParams{
+		ZoneID:        m.ZoneID,
+		EffectiveFrom: m.EffectiveFrom,
+		Multiplier:    m.Multiplier,
+	})
+}
+
+// UpsertZone creates or renames a zone; see Store.UpsertZone.
+func (s *SQLiteStore) UpsertZone(ctx context.Context, z Zone) error {
+	defer observeWrite("zones", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO zones (zone_id, name, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (zone_id) DO UPDATE
+		SET name = excluded.name, updated_at = excluded.updated_at
+		WHERE zones.updated_at < excluded.updated_at
+	`, z.ID, zoneName(z), z.UpdatedAt.UTC())
+	return err
+}
+
+// UpsertSurgeMultiplier records a zone's multiplier; see Store.UpsertSurgeMultiplier.
+func (s *SQLiteStore) UpsertSurgeMultiplier(ctx context.Context, m SurgeMultiplier) error {
+	defer observeWrite("surge_multipliers", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO surge_multipliers (zone_id, effective_from, multiplier)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (zone_id, effective_from) DO UPDATE
+		SET multiplier = excluded.multiplier
+	`, m.ZoneID, m.EffectiveFrom.UTC(), m.Multiplier)
+	return err
+}
+
+// UpsertZone creates or renames a zone; see Store.UpsertZone. name is assigned
+// before updated_at so it is compared against the old timestamp.
+func (s *MySQLStore) UpsertZone(ctx context.Context, z Zone) error {
+	defer observeWrite("zones", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO zones (zone_id, name, updated_at)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			name = IF(VALUES(updated_at) > updated_at, VALUES(name), name),
+			updated_at = GREATEST(updated_at, VALUES(updated_at))
+	`, z.ID, zoneName(z), z.UpdatedAt)
+	return err
+}
+
+// UpsertSurgeMultiplier records a zone's multiplier; see Store.UpsertSurgeMultiplier.
+func (s *MySQLStore) UpsertSurgeMultiplier(ctx context.Context, m SurgeMultiplier) error {
+	defer observeWrite("surge_multipliers", time.Now())
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO surge_multipliers (zone_id, effective_from, multiplier)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE multiplier = VALUES(multiplier)
+	`, m.ZoneID, m.EffectiveFrom, m.Multiplier)
+	return err
+}
+
+// zoneName falls back to the zone's ID when it has no display name.
+func zoneName(z Zone) string {
+	if z.Name == "" {
+		return z.ID
+	}
+	return z.Name
+}
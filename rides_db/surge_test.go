@@ -0,0 +1,107 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestUpsertSurgeMultiplier_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	at := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO zones").
+		WithArgs("midtown", "midtown", at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO surge_multipliers").
+		WithArgs("midtown", at, 1.8).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.UpsertZone(context.Background(), Zone{ID: "midtown", UpdatedAt: at}); err != nil {
+		t.Errorf("UpsertZone failed: %v", err)
+	}
+	if err := store.UpsertSurgeMultiplier(context.Background(), SurgeMultiplier{ZoneID: "midtown", EffectiveFrom: at, Multiplier: 1.8}); err != nil {
+		t.Errorf("UpsertSurgeMultiplier failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLiteStore_RevenueBySurge(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	// The trips are requested with coordinates, so they are in the grid cell
+	// of their pickup, as surges are keyed, rather than at Main St. The cell
+	// surges to 2x an hour before trip-1 and drops to 1.25x before trip-2;
+	// trip-3 is requested before any surge was recorded
+	zone := string(events.Coordinate{Lat: 40.7500, Lng: -73.9800}.Zone())
+	surges := []SurgeMultiplier{
+		{ZoneID: zone, EffectiveFrom: base.Add(-time.Hour), Multiplier: 2},
+		{ZoneID: zone, EffectiveFrom: base.Add(30 * time.Minute), Multiplier: 1.25},
+		{ZoneID: "Main St", EffectiveFrom: base.Add(-2 * time.Hour), Multiplier: 3},
+	}
+	for _, m := range surges {
+		if err := store.UpsertZone(ctx, Zone{ID: m.ZoneID, UpdatedAt: m.EffectiveFrom}); err != nil {
+			t.Fatalf("UpsertZone failed: %v", err)
+		}
+		if err := store.UpsertSurgeMultiplier(ctx, m); err != nil {
+			t.Fatalf("UpsertSurgeMultiplier failed: %v", err)
+		}
+	}
+	for tripID, requested := range map[string]time.Time{
+		"trip-1": base,
+		"trip-2": base.Add(time.Hour),
+		"trip-3": base.Add(-2 * time.Hour),
+	} {
+		if _, err := store.InsertRideEvents(ctx, completedTrip(tripID, requested)); err != nil {
+			t.Fatalf("InsertRideEvents failed: %v", err)
+		}
+		if err := store.RefreshTrip(ctx, tripID); err != nil {
+			t.Fatalf("RefreshTrip failed: %v", err)
+		}
+	}
+
+	got, err := store.RevenueBySurge(ctx, TimeRange{})
+	if err != nil {
+		t.Fatalf("RevenueBySurge failed: %v", err)
+	}
+	want := []SurgeRevenue{
+		{Multiplier: 1, Trips: 1, RevenueUSD: 8.5},
+		{Multiplier: 1.25, Trips: 1, RevenueUSD: 8.5},
+		{Multiplier: 2, Trips: 1, RevenueUSD: 8.5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d buckets, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("bucket %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	// An older rename arriving late does not overwrite the newer name
+	if err := store.UpsertZone(ctx, Zone{ID: "Main St", Name: "Main Street", UpdatedAt: base.Add(time.Hour)}); err != nil {
+		t.Fatalf("UpsertZone failed: %v", err)
+	}
+	if err := store.UpsertZone(ctx, Zone{ID: "Main St", Name: "Old Main", UpdatedAt: base}); err != nil {
+		t.Fatalf("UpsertZone failed: %v", err)
+	}
+	var name string
+	store.DB().QueryRow(`SELECT name FROM zones WHERE zone_id = 'Main St'`).Scan(&name)
+	if name != "Main Street" {
+		t.Errorf("expected zone name Main Street, got %q", name)
+	}
+}
@@ -0,0 +1,125 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// cityPattern matches the city names events may carry; it keeps schema names
+// valid unquoted identifiers.
+var cityPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// CitySchema returns the PostgreSQL schema holding city's tables.
+func CitySchema(city string) (string, error) {
+	if !cityPattern.MatchString(city) {
+		return "", fmt.Errorf("rides_db: invalid city %q", city)
+	}
+	return "city_" + city, nil
+}
+
+// citySchema is CitySchema, except that no city means the default schema.
+func citySchema(city string) (string, error) {
+	if city == "" {
+		return "", nil
+	}
+	return CitySchema(city)
+}
+
+// openPostgres opens a pgx handle on connStr. With a schema, every connection
+// resolves unqualified names in that schema first and then in public, where
+// extensions such as PostGIS live.
+func openPostgres(connStr, schema string) (*sql.DB, error) {
+	if schema == "" {
+		return sql.Open("pgx", connStr)
+	}
+	cfg, err := pgx.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RuntimeParams["search_path"] = pgx.Identifier{schema}.Sanitize() + ", public"
+	return stdlib.OpenDB(*cfg), nil
+}
+
+// CityStores keeps one Store per city in a shared PostgreSQL database, each in
+// its own schema. A city's store is opened and migrated the first time it is
+// asked for; events without a city go to the default store.
+type CityStores struct {
+	connStr string
+	opts    []Option
+	def     *Store
+
+	mu     sync.Mutex
+	stores map[string]*Store
+}
+
+// NewCityStores returns a CityStores connecting to connStr with opts. def
+// serves events without a city and is not closed by Close.
+func NewCityStores(def *Store, connStr string, opts ...Option) *CityStores {
+	return &CityStores{
+		connStr: connStr,
+		opts:    opts,
+		def:     def,
+		stores:  make(map[string]*Store),
+	}
+}
+
+// ForCity returns the store for city, opening and migrating its schema if this
+// is the first time the city is seen.
+func (c *CityStores) ForCity(ctx context.Context, city string) (*Store, error) {
+	if city == "" {
+		return c.def, nil
+	}
+	if _, err := CitySchema(city); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.stores[city]; ok {
+		return s, nil
+	}
+
+	s, err := Open(c.connStr, append(c.opts[:len(c.opts):len(c.opts)], WithCity(city))...)
+	if err != nil {
+		return nil, fmt.Errorf("open city %s: %w", city, err)
+	}
+	if err := s.Migrate(ctx); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("migrate city %s: %w", city, err)
+	}
+	c.stores[city] = s
+	return s, nil
+}
+
+// Cities returns the cities whose stores are open, sorted.
+func (c *CityStores) Cities() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cities := make([]string, 0, len(c.stores))
+	for city := range c.stores {
+		cities = append(cities, city)
+	}
+	sort.Strings(cities)
+	return cities
+}
+
+// Close closes every city store that was opened.
+func (c *CityStores) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var firstErr error
+	for city, s := range c.stores {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(c.stores, city)
+	}
+	return firstErr
+}
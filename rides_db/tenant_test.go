@@ -0,0 +1,95 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCitySchema(t *testing.T) {
+	tests := []struct {
+		city    string
+		want    string
+		wantErr bool
+	}{
+		{city: "nyc", want: "city_nyc"},
+		{city: "san_francisco", want: "city_san_francisco"},
+		{city: "la2", want: "city_la2"},
+		{city: "", wantErr: true},
+		{city: "NYC", wantErr: true},
+		{city: "2nyc", wantErr: true},
+		{city: "nyc; DROP SCHEMA public", wantErr: true},
+		{city: "a2345678901234567890123456789012345678901", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.city, func(t *testing.T) {
+			got, err := CitySchema(tt.city)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CitySchema(%q) error = %v, wantErr %v", tt.city, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CitySchema(%q) = %q, want %q", tt.city, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrate_CreatesCitySchema(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	store.schema = "city_nyc"
+
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations failed: %v", err)
+	}
+	applied := sqlmock.NewRows([]string{"version"})
+	for _, m := range migrations {
+		applied.AddRow(m.Version)
+	}
+
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE SCHEMA IF NOT EXISTS "city_nyc"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(applied)
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCityStores_ForCity(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	def := New(db)
+	cities := NewCityStores(def, "host=unused")
+
+	got, err := cities.ForCity(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ForCity without a city failed: %v", err)
+	}
+	if got != def {
+		t.Error("expected events without a city to use the default store")
+	}
+
+	if _, err := cities.ForCity(context.Background(), "Not A City"); err == nil {
+		t.Error("expected an invalid city to be rejected")
+	}
+	if n := len(cities.Cities()); n != 0 {
+		t.Errorf("expected no city stores to be opened, got %d", n)
+	}
+}
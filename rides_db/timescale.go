@@ -0,0 +1,222 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// EventStorage selects how ride_events is laid out on disk.
+type EventStorage string
+
+const (
+	// StoragePartitioned uses native declarative partitions kept up by MaintainPartitions.
+	StoragePartitioned EventStorage = "partitioned"
+	// StorageTimescale converts ride_events into a TimescaleDB hypertable.
+	StorageTimescale EventStorage = "timescale"
+	// StorageAuto uses TimescaleDB when the extension is installed on the server.
+	StorageAuto EventStorage = "auto"
+)
+
+// TimescaleConfig tunes the hypertable created for ride_events. A zero
+// CompressAfter or Retention leaves that policy off.
+type TimescaleConfig struct {
+	ChunkInterval time.Duration
+	CompressAfter time.Duration
+	Retention     time.Duration
+}
+
+// DefaultTimescaleConfig uses daily chunks compressed after a week and keeps all data.
+func DefaultTimescaleConfig() TimescaleConfig {
+	return TimescaleConfig{
+		ChunkInterval: 24 * time.Hour,
+		CompressAfter: 7 * 24 * time.Hour,
+	}
+}
+
+// EventStorageFromEnv reads RIDE_EVENTS_STORAGE ("partitioned", "timescale", or
+// "auto") along with TIMESCALE_CHUNK_INTERVAL and TIMESCALE_COMPRESS_AFTER.
+// Retention is shared with partitioned storage via RIDE_EVENTS_RETENTION_DAYS.
+// Unset or invalid values keep the defaults.
+func EventStorageFromEnv() (EventStorage, TimescaleConfig) {
+	mode := StoragePartitioned
+	switch m := EventStorage(os.Getenv("RIDE_EVENTS_STORAGE")); m {
+	case "":
+	case StoragePartitioned, StorageTimescale, StorageAuto:
+		mode = m
+	default:
+		slog.Warn("Ignoring invalid event storage", "value", m)
+	}
+
+	cfg := DefaultTimescaleConfig()
+	if d, ok := envDuration("TIMESCALE_CHUNK_INTERVAL"); ok {
+		cfg.ChunkInterval = d
+	}
+	if d, ok := envDuration("TIMESCALE_COMPRESS_AFTER"); ok {
+		cfg.CompressAfter = d
+	}
+	cfg.Retention = PartitionPolicyFromEnv().Retention
+	return mode, cfg
+}
+
+// ConfigureEventStorage applies mode to ride_events and returns the storage
+// actually in use. StorageAuto resolves to StorageTimescale when the extension
+// is available and to StoragePartitioned otherwise. It must run after Migrate.
+func (s *Store) ConfigureEventStorage(ctx context.Context, mode EventStorage, cfg TimescaleConfig) (EventStorage, error) {
+	if mode == StorageAuto {
+		ok, err := s.TimescaleAvailable(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return StoragePartitioned, nil
+		}
+		mode = StorageTimescale
+	}
+	if mode != StorageTimescale {
+		return StoragePartitioned, nil
+	}
+	if err := s.SetupTimescale(ctx, cfg); err != nil {
+		return "", err
+	}
+	return StorageTimescale, nil
+}
+
+// TimescaleAvailable reports whether the timescaledb extension can be installed.
+func (s *Store) TimescaleAvailable(ctx context.Context) (bool, error) {
+	var ok bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'timescaledb')
+	`).Scan(&ok)
+	return ok, err
+}
+
+// SetupTimescale turns ride_events into a hypertable, copying over any rows in
+// the partitioned table, and (re)applies the compression and retention policies
+// and the per-minute continuous aggregate ride_events_per_minute. It is safe to
+// run on every start.
+func (s *Store) SetupTimescale(ctx context.Context, cfg TimescaleConfig) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, `CREATE EXTENSION IF NOT EXISTS timescaledb`); err != nil {
+		return fmt.Errorf("create timescaledb extension: %w", err)
+	}
+
+	var isHypertable bool
+	if err := conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM timescaledb_information.hypertables WHERE hypertable_name = 'ride_events'
+		)
+	`).Scan(&isHypertable); err != nil {
+		return err
+	}
+	if !isHypertable {
+		if err := convertToHypertable(ctx, conn, cfg.ChunkInterval); err != nil {
+			return fmt.Errorf("convert ride_events to hypertable: %w", err)
+		}
+		slog.Info("Converted ride_events to a TimescaleDB hypertable")
+	}
+
+	if cfg.CompressAfter > 0 {
+		if _, err := conn.ExecContext(ctx, `
+			ALTER TABLE ride_events SET (
+				timescaledb.compress,
+				timescaledb.compress_segmentby = 'event_type',
+				timescaledb.compress_orderby = 'event_time DESC'
+			)
+		`); err != nil {
+			return fmt.Errorf("enable compression: %w", err)
+		}
+		if _, err := conn.ExecContext(ctx,
+			`SELECT add_compression_policy('ride_events', $1::INTERVAL, if_not_exists => true)`,
+			pgInterval(cfg.CompressAfter),
+		); err != nil {
+			return fmt.Errorf("add compression policy: %w", err)
+		}
+	}
+	if cfg.Retention > 0 {
+		if _, err := conn.ExecContext(ctx,
+			`SELECT add_retention_policy('ride_events', $1::INTERVAL, if_not_exists => true)`,
+			pgInterval(cfg.Retention),
+		); err != nil {
+			return fmt.Errorf("add retention policy: %w", err)
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE MATERIALIZED VIEW IF NOT EXISTS ride_events_per_minute
+		WITH (timescaledb.continuous) AS
+		SELECT
+			time_bucket(INTERVAL '1 minute', event_time) AS bucket,
+			event_type,
+			COUNT(*) AS event_count,
+			COALESCE(SUM(fare_total), 0) AS fare_total
+		FROM ride_events
+		GROUP BY bucket, event_type
+		WITH NO DATA
+	`); err != nil {
+		return fmt.Errorf("create continuous aggregate: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, `
+		SELECT add_continuous_aggregate_policy('ride_events_per_minute',
+			start_offset => INTERVAL '1 hour',
+			end_offset => INTERVAL '1 minute',
+			schedule_interval => INTERVAL '1 minute',
+			if_not_exists => true)
+	`); err != nil {
+		return fmt.Errorf("add continuous aggregate policy: %w", err)
+	}
+	return nil
+}
+
+// convertToHypertable replaces the partitioned ride_events with a plain table of
+// the same shape and hands it to create_hypertable, which cannot adopt a
+// declaratively partitioned table.
+func convertToHypertable(ctx context.Context, conn *sql.Conn, chunk time.Duration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`CREATE TABLE ride_events_hyper (LIKE ride_events INCLUDING DEFAULTS)`,
+		`INSERT INTO ride_events_hyper SELECT * FROM ride_events`,
+		`DROP TABLE ride_events`,
+		`ALTER TABLE ride_events_hyper RENAME TO ride_events`,
+		`ALTER TABLE ride_events ADD PRIMARY KEY (id, event_time)`,
+		`ALTER TABLE ride_events ADD UNIQUE (trip_id, event_type, event_time)`,
+		`CREATE INDEX IF NOT EXISTS idx_trip_events ON ride_events (trip_id, event_time)`,
+		`CREATE INDEX IF NOT EXISTS idx_event_type ON ride_events (event_type)`,
+		`CREATE INDEX IF NOT EXISTS idx_passenger_id ON ride_events (passenger_id)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`SELECT create_hypertable('ride_events', 'event_time', chunk_time_interval => $1::INTERVAL, migrate_data => true)`,
+		pgInterval(chunk),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// pgInterval formats d as a PostgreSQL interval literal.
+func pgInterval(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int64(d/time.Second))
+}
@@ -0,0 +1,63 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestConfigureEventStorage_AutoWithoutExtension(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM pg_available_extensions").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	got, err := store.ConfigureEventStorage(context.Background(), StorageAuto, DefaultTimescaleConfig())
+	if err != nil {
+		t.Fatalf("ConfigureEventStorage failed: %v", err)
+	}
+	if got != StoragePartitioned {
+		t.Errorf("expected partitioned storage, got %q", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSetupTimescale_ExistingHypertable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE EXTENSION IF NOT EXISTS timescaledb").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("timescaledb_information.hypertables").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("ALTER TABLE ride_events SET").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("add_compression_policy").WithArgs("604800 seconds").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("add_retention_policy").WithArgs("2592000 seconds").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE MATERIALIZED VIEW IF NOT EXISTS ride_events_per_minute (.+)SUM\\(fare_total\\)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("add_continuous_aggregate_policy").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	cfg := DefaultTimescaleConfig()
+	cfg.Retention = 30 * 24 * time.Hour
+	if err := store.SetupTimescale(context.Background(), cfg); err != nil {
+		t.Fatalf("SetupTimescale failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
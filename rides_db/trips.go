@@ -0,0 +1,202 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+const tripColumns = `trip_id, passenger_id, driver_id, final_state, pickup_location, dropoff_location,
+	requested_at, accepted_at, started_at, completed_at, cancelled_at,
+	distance_km, fare_usd, cancelled_by, cancel_reason, pickup_zone`
+
+// InsertTrip writes the assembled summary of a finished trip. A trip that is
+// assembled again (for example after a redelivery) replaces the earlier row.
+func (s *Store) InsertTrip(ctx context.Context, t aggregation.Trip) error {
+	defer observeWrite("trips", time.Now())
+	t, err := s.cipher.encryptTrip(ctx, t)
+	if err != nil {
+		return err
+	}
+	return s.queries().UpsertTrip(ctx, tripParams(t))
+}
+
+// tripParams returns the trips row for t.
+func tripParams(t aggregation.Trip) sqlcdb.UpsertTripParams {
+	pickupWait, pickupWaitOK := t.PickupWait()
+	duration, durationOK := t.Duration()
+	acceptLatency, acceptLatencyOK := t.AcceptLatency()
+	return sqlcdb.UpsertTripParams{
+		TripID:               t.TripID,
+		PassengerID:          nullString(t.PassengerID),
+		DriverID:             nullString(t.DriverID),
+		FinalState:           string(t.FinalState),
+		PickupLocation:       nullString(t.PickupLocation),
+		DropoffLocation:      nullString(t.DropoffLocation),
+		RequestedAt:          nullTime(t.RequestedAt),
+		AcceptedAt:           nullTime(t.AcceptedAt),
+		StartedAt:            nullTime(t.StartedAt),
+		CompletedAt:          nullTime(t.CompletedAt),
+		CancelledAt:          nullTime(t.CancelledAt),
+		PickupWaitSeconds:    nullSeconds(pickupWait, pickupWaitOK),
+		DurationSeconds:      nullSeconds(duration, durationOK),
+		DistanceKm:           sql.NullFloat64{Float64: t.DistanceKM, Valid: true},
+		FareUsd:              sql.NullFloat64{Float64: t.FareUSD, Valid: true},
+		CancelledBy:          nullString(t.CancelledBy),
+		CancelReason:         nullString(t.CancelReason),
+		AcceptLatencySeconds: nullSeconds(acceptLatency, acceptLatencyOK),
+		PickupZone:           nullString(string(t.PickupZone)),
+	}
+}
+
+// tripArgs returns the trips column values for t in insert order, for the
+// backends that do not use the generated queries.
+func tripArgs(t aggregation.Trip) []any {
+	p := tripParams(t)
+	return []any{
+		p.TripID, p.PassengerID, p.DriverID, p.FinalState, p.PickupLocation, p.DropoffLocation,
+		p.RequestedAt, p.AcceptedAt, p.StartedAt, p.CompletedAt, p.CancelledAt,
+		p.PickupWaitSeconds, p.DurationSeconds, p.DistanceKm, p.FareUsd, p.CancelledBy, p.CancelReason,
+		p.AcceptLatencySeconds, p.PickupZone,
+	}
+}
+
+// RefreshTrip rebuilds the trips row for tripID from its stored ride_events. Unlike
+// InsertTrip it does not depend on in-memory state, so it still produces a complete
+// row when the consumer restarted in the middle of a trip.
+func (s *Store) RefreshTrip(ctx context.Context, tripID string) error {
+	defer observeWrite("trips", time.Now())
+	return s.queries().RefreshTrip(ctx, tripID)
+}
+
+// GetTrip returns the assembled summary of a finished trip, or ErrNotFound.
+func (s *Store) GetTrip(ctx context.Context, tripID string) (aggregation.Trip, error) {
+	var row sqlcdb.Trip
+	err := s.retry(ctx, func() error {
+		var err error
+		row, err = s.readQueries(ctx).GetTrip(ctx, tripID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return aggregation.Trip{}, ErrNotFound
+	}
+	if err != nil {
+		return aggregation.Trip{}, err
+	}
+	return s.cipher.decryptTrip(ctx, tripFromRow(row))
+}
+
+// TripFilter narrows ListTrips. Zero fields are not filtered on; From and To bound
+// requested_at as a half-open range [From, To).
+type TripFilter struct {
+	From     time.Time
+	To       time.Time
+	State    events.RideState
+	DriverID string
+	Limit    int
+	Offset   int
+}
+
+// ListTrips returns finished trips matching f, newest request first.
+func (s *Store) ListTrips(ctx context.Context, f TripFilter) ([]aggregation.Trip, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.readQueries(ctx).ListTrips(ctx, sqlcdb.ListTripsParams{
+		From:       nullTime(f.From),
+		To:         nullTime(f.To),
+		FinalState: nullString(string(f.State)),
+		DriverID:   nullString(f.DriverID),
+		Limit:      int32(limit),
+		Offset:     int32(f.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var trips []aggregation.Trip
+	for _, row := range rows {
+		trips = append(trips, tripFromRow(row))
+	}
+	return s.cipher.decryptTrips(ctx, trips)
+}
+
+// tripFromRow converts a generated trips row.
+func tripFromRow(row sqlcdb.Trip) aggregation.Trip {
+	return aggregation.Trip{
+		TripID:          row.TripID,
+		PassengerID:     row.PassengerID.String,
+		DriverID:        row.DriverID.String,
+		FinalState:      events.RideState(row.FinalState),
+		PickupLocation:  row.PickupLocation.String,
+		DropoffLocation: row.DropoffLocation.String,
+		RequestedAt:     row.RequestedAt.Time,
+		AcceptedAt:      row.AcceptedAt.Time,
+		StartedAt:       row.StartedAt.Time,
+		CompletedAt:     row.CompletedAt.Time,
+		CancelledAt:     row.CancelledAt.Time,
+		DistanceKM:      row.DistanceKm.Float64,
+		FareUSD:         row.FareUsd.Float64,
+		CancelledBy:     row.CancelledBy.String,
+		CancelReason:    row.CancelReason.String,
+		PickupZone:      events.ZoneID(row.PickupZone.String),
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTrip(row rowScanner) (aggregation.Trip, error) {
+	var (
+		t                            aggregation.Trip
+		passengerID, driverID        sql.NullString
+		pickup, dropoff, zone        sql.NullString
+		cancelledBy, cancelReason    sql.NullString
+		requested, accepted, started sql.NullTime
+		completed, cancelled         sql.NullTime
+		distance, fare               sql.NullFloat64
+	)
+	err := row.Scan(
+		&t.TripID, &passengerID, &driverID, &t.FinalState, &pickup, &dropoff,
+		&requested, &accepted, &started, &completed, &cancelled,
+		&distance, &fare, &cancelledBy, &cancelReason, &zone,
+	)
+	if err != nil {
+		return aggregation.Trip{}, err
+	}
+
+	t.PassengerID = passengerID.String
+	t.DriverID = driverID.String
+	t.PickupLocation = pickup.String
+	t.DropoffLocation = dropoff.String
+	t.RequestedAt = requested.Time
+	t.AcceptedAt = accepted.Time
+	t.StartedAt = started.Time
+	t.CompletedAt = completed.Time
+	t.CancelledAt = cancelled.Time
+	t.DistanceKM = distance.Float64
+	t.FareUSD = fare.Float64
+	t.CancelledBy = cancelledBy.String
+	t.CancelReason = cancelReason.String
+	t.PickupZone = events.ZoneID(zone.String)
+	return t, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullSeconds(d time.Duration, ok bool) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: d.Seconds(), Valid: ok}
+}
@@ -0,0 +1,146 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+var tripRowColumns = []string{
+	"trip_id", "passenger_id", "driver_id", "final_state", "pickup_location", "dropoff_location",
+	"requested_at", "accepted_at", "started_at", "completed_at", "cancelled_at",
+	"pickup_wait_seconds", "duration_seconds", "distance_km", "fare_usd", "cancelled_by", "cancel_reason",
+	"accept_latency_seconds", "pickup_zone",
+}
+
+func TestInsertTrip_Completed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	trip := aggregation.Trip{
+		TripID:      "trip-1",
+		PassengerID: "rider-1",
+		DriverID:    "driver-1",
+		FinalState:  events.StateCompleted,
+		RequestedAt: base,
+		StartedAt:   base.Add(4 * time.Minute),
+		CompletedAt: base.Add(14 * time.Minute),
+		DistanceKM:  8.2,
+		FareUSD:     10.7,
+	}
+
+	mock.ExpectExec("INSERT INTO trips").
+		WithArgs("trip-1", "rider-1", "driver-1", events.StateCompleted, nil, nil,
+			base, nil, base.Add(4*time.Minute), base.Add(14*time.Minute), nil,
+			240.0, 600.0, 8.2, 10.7, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.InsertTrip(context.Background(), trip); err != nil {
+		t.Errorf("InsertTrip failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetTrip_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	mock.ExpectQuery("SELECT (.+) FROM trips").
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"trip_id"}))
+
+	if _, err := store.GetTrip(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGetTrip_ScansNullableColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	requested := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows(tripRowColumns).AddRow("trip-1", "rider-1", nil, "CANCELLED", "A", "B",
+		requested, nil, nil, nil, requested.Add(time.Minute),
+		nil, nil, nil, nil, "passenger", "no_show", nil, "A")
+	mock.ExpectQuery("SELECT (.+) FROM trips").WithArgs("trip-1").WillReturnRows(rows)
+
+	trip, err := store.GetTrip(context.Background(), "trip-1")
+	if err != nil {
+		t.Fatalf("GetTrip failed: %v", err)
+	}
+	if trip.FinalState != events.StateCancelled || trip.DriverID != "" || trip.CancelledBy != "passenger" {
+		t.Errorf("unexpected trip: %+v", trip)
+	}
+	if !trip.RequestedAt.Equal(requested) || !trip.StartedAt.IsZero() {
+		t.Errorf("unexpected timestamps: requested=%v started=%v", trip.RequestedAt, trip.StartedAt)
+	}
+}
+
+func TestRefreshTrip_RebuildsFromEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	mock.ExpectExec("WITH e AS (.+) MAX\\(fare_total\\) (.+) FROM ride_events (.+) INSERT INTO trips").
+		WithArgs("trip-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.RefreshTrip(context.Background(), "trip-1"); err != nil {
+		t.Errorf("RefreshTrip failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListTrips_BindsFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	mock.ExpectQuery(`FROM trips WHERE .* ORDER BY requested_at DESC NULLS LAST LIMIT \$5 OFFSET \$6`).
+		WithArgs(from, to, nil, "driver-1", 100, 0).
+		WillReturnRows(sqlmock.NewRows(tripRowColumns).AddRow("trip-1", "rider-1", "driver-1", "COMPLETED", "A", "B",
+			from, from, from, from, nil, 0.0, 600.0, 3.2, 5.7, nil, nil, 0.0, "40.74,-73.98"))
+
+	trips, err := store.ListTrips(context.Background(), TripFilter{From: from, To: to, DriverID: "driver-1"})
+	if err != nil {
+		t.Fatalf("ListTrips failed: %v", err)
+	}
+	if len(trips) != 1 || trips[0].FareUSD != 5.7 || trips[0].PickupZone != "40.74,-73.98" {
+		t.Errorf("unexpected trips: %+v", trips)
+	}
+}
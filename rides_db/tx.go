@@ -0,0 +1,49 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// querier is the subset of *sql.DB and *sql.Tx the store's queries use, so the
+// same methods run either directly or inside a transaction. It matches the DBTX
+// interface of the sqlc-generated queries.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// WithTx runs fn with a RideStore whose writes and reads all go through one
+// transaction, committing if fn returns nil and rolling back otherwise. A
+// transaction that fails with a transient error is retried from the start, so fn
+// must be safe to run more than once. Calling WithTx on a store that is already
+// in a transaction reuses it. fn must not call Migrate or Close on the store it
+// is given.
+func (s *Store) WithTx(ctx context.Context, fn func(tx RideStore) error) error {
+	return s.retry(ctx, func() error {
+		return s.withTx(ctx, func(tx *Store) error { return fn(tx) })
+	})
+}
+
+func (s *Store) withTx(ctx context.Context, fn func(tx *Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txStore := &Store{
+		db: s.db, q: withSlowQueryLog(tx, s.slowQuery), tx: tx,
+		queryTimeout: s.queryTimeout, retryPolicy: s.retryPolicy, cipher: s.cipher, slowQuery: s.slowQuery,
+	}
+	if err := fn(txStore); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,74 @@
+package rides_db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestWithTx_CommitsAllWrites(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	evt := events.RideEvent{ID: "e1", TripID: "trip-1", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO ride_events").WillReturnRows(sqlmock.NewRows([]string{"outcome"}).AddRow("inserted"))
+	mock.ExpectExec("INSERT INTO rides").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO consumer_checkpoints").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = store.WithTx(context.Background(), func(tx RideStore) error {
+		if _, err := tx.InsertRideEvent(context.Background(), evt); err != nil {
+			return err
+		}
+		if err := tx.UpsertRideState(context.Background(), evt); err != nil {
+			return err
+		}
+		return tx.UpdateCheckpoint(context.Background(), Checkpoint{Group: "g", Topic: "t", Offset: 1, UpdatedAt: time.Now()})
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestWithTx_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+	evt := events.RideEvent{ID: "e1", TripID: "trip-1", Type: events.EventRideAccepted, State: events.StateAccepted, OccurredAt: time.Now()}
+	upsertErr := errors.New("upsert failed")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO ride_events").WillReturnRows(sqlmock.NewRows([]string{"outcome"}).AddRow("inserted"))
+	mock.ExpectExec("INSERT INTO rides").WillReturnError(upsertErr)
+	mock.ExpectRollback()
+
+	err = store.WithTx(context.Background(), func(tx RideStore) error {
+		if _, err := tx.InsertRideEvent(context.Background(), evt); err != nil {
+			return err
+		}
+		return tx.UpsertRideState(context.Background(), evt)
+	})
+	if !errors.Is(err, upsertErr) {
+		t.Errorf("expected upsert error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package rides_db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rides_db/internal/sqlcdb"
+)
+
+// UpsertEventWindow stores a windowed aggregate. Corrections for late events
+// overwrite the earlier row for the same window and keep is_correction set.
+func (s *Store) UpsertEventWindow(ctx context.Context, w aggregation.WindowResult) error {
+	defer observeWrite("ride_event_windows", time.Now())
+	return s.queries().UpsertEventWindow(ctx, sqlcdb.UpsertEventWindowParams{
+		WindowStart:  w.Start,
+		WindowEnd:    w.End,
+		EventType:    string(w.EventType),
+		EventCount:   w.Count,
+		FareTotal:    w.FareTotal.Float64(),
+		IsCorrection: w.IsCorrection,
+	})
+}
+
+// ListEventWindows returns the windowed aggregates that start within tr, by
+// start and event type. The table keeps no currency, so fare totals are read
+// back in USD.
+func (s *Store) ListEventWindows(ctx context.Context, tr TimeRange) ([]aggregation.WindowResult, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.readQueries(ctx).ListEventWindows(ctx, sqlcdb.ListEventWindowsParams{From: nullTime(tr.From), To: nullTime(tr.To)})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []aggregation.WindowResult
+	for _, row := range rows {
+		out = append(out, aggregation.WindowResult{
+			Start:        row.WindowStart,
+			End:          row.WindowEnd,
+			EventType:    events.RideEventType(row.EventType),
+			Count:        row.EventCount,
+			FareTotal:    events.MoneyFromFloat(row.FareTotal, events.USD),
+			IsCorrection: row.IsCorrection,
+		})
+	}
+	return out, nil
+}
+
+// ListEventWindows returns windowed aggregates; see Store.ListEventWindows.
+func (s *SQLiteStore) ListEventWindows(ctx context.Context, tr TimeRange) ([]aggregation.WindowResult, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT window_start, window_end, event_type, event_count, fare_total, is_correction
+		FROM ride_event_windows
+		WHERE ($1 IS NULL OR window_start >= $1)
+		  AND ($2 IS NULL OR window_start < $2)
+		ORDER BY window_start, event_type
+	`, utcArgs([]any{nullTime(tr.From), nullTime(tr.To)})...)
+	if err != nil {
+		return nil, err
+	}
+	return scanEventWindows(rows)
+}
+
+// ListEventWindows returns windowed aggregates; see Store.ListEventWindows.
+func (s *MySQLStore) ListEventWindows(ctx context.Context, tr TimeRange) ([]aggregation.WindowResult, error) {
+	ctx, cancel := withTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT window_start, window_end, event_type, event_count, fare_total, is_correction
+		FROM ride_event_windows
+		WHERE (? IS NULL OR window_start >= ?)
+		  AND (? IS NULL OR window_start < ?)
+		ORDER BY window_start, event_type
+	`, nullTime(tr.From), nullTime(tr.From), nullTime(tr.To), nullTime(tr.To))
+	if err != nil {
+		return nil, err
+	}
+	return scanEventWindows(rows)
+}
+
+// scanEventWindows reads the rows of ListEventWindows, for the backends that
+// do not use the generated queries.
+func scanEventWindows(rows *sql.Rows) ([]aggregation.WindowResult, error) {
+	defer rows.Close()
+	var out []aggregation.WindowResult
+	for rows.Next() {
+		var (
+			w         aggregation.WindowResult
+			eventType string
+			fareTotal float64
+		)
+		if err := rows.Scan(&w.Start, &w.End, &eventType, &w.Count, &fareTotal, &w.IsCorrection); err != nil {
+			return nil, err
+		}
+		w.EventType, w.FareTotal = events.RideEventType(eventType), events.MoneyFromFloat(fareTotal, events.USD)
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,72 @@
+package rides_db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestUpsertEventWindow_Correction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	store := New(db)
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	w := aggregation.WindowResult{
+		Start:        start,
+		End:          start.Add(time.Minute),
+		EventType:    events.EventTripCompleted,
+		Count:        3,
+		FareTotal:    events.NewMoney(4250, events.USD),
+		IsCorrection: true,
+	}
+
+	mock.ExpectExec("INSERT INTO ride_event_windows").
+		WithArgs(start, start.Add(time.Minute), events.EventTripCompleted, int64(3), 42.5, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.UpsertEventWindow(context.Background(), w); err != nil {
+		t.Errorf("UpsertEventWindow failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLiteStore_ListEventWindows(t *testing.T) {
+	store := openTestSQLite(t)
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, typ := range []events.RideEventType{events.EventTripCompleted, events.EventRideRequested, events.EventTripCompleted} {
+		w := aggregation.WindowResult{
+			Start:     start.Add(time.Duration(i/2) * time.Minute),
+			End:       start.Add(time.Duration(i/2+1) * time.Minute),
+			EventType: typ,
+			Count:     int64(i + 1),
+			FareTotal: events.NewMoney(int64(1000*i), events.USD),
+		}
+		if err := store.UpsertEventWindow(ctx, w); err != nil {
+			t.Fatalf("UpsertEventWindow failed: %v", err)
+		}
+	}
+
+	windows, err := store.ListEventWindows(ctx, TimeRange{From: start, To: start.Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("ListEventWindows failed: %v", err)
+	}
+	if len(windows) != 2 || windows[0].EventType != events.EventTripCompleted || windows[1].Count != 2 {
+		t.Fatalf("unexpected windows in the first minute: %+v", windows)
+	}
+	all, _ := store.ListEventWindows(ctx, TimeRange{})
+	if len(all) != 3 || !all[2].Start.Equal(start.Add(time.Minute)) || all[2].FareTotal != events.NewMoney(2000, events.USD) {
+		t.Errorf("unexpected windows: %+v", all)
+	}
+}
@@ -0,0 +1,67 @@
+// Command rideshare runs any of the main ride services from one binary, so a
+// deployment can ship a single image and pick the service by its command:
+//
+//	rideshare produce|consume|match|price|api|dashboard [flags]
+//	rideshare migrate
+//	rideshare admin [-brokers B] [-dry-run] [-add-partitions] [-list]
+//
+// Each command runs the service as its own binary does, with the same
+// settings, metrics port, and shutdown; see the services packages.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pedeveaux/kafkarideshare/services/api"
+	"github.com/pedeveaux/kafkarideshare/services/consumer"
+	"github.com/pedeveaux/kafkarideshare/services/dashboard"
+	"github.com/pedeveaux/kafkarideshare/services/kafkaadmin"
+	"github.com/pedeveaux/kafkarideshare/services/matcher"
+	"github.com/pedeveaux/kafkarideshare/services/pricer"
+	"github.com/pedeveaux/kafkarideshare/services/producer"
+)
+
+const usage = `usage: rideshare <command> [flags]
+
+commands:
+  produce    simulate rides and send their events to ride-events
+  consume    store ride events in the database (consume replay replays the topic)
+  match      answer ride requests with the nearest free driver
+  price      quote ride requests and check completed fares
+  api        serve the rides database over REST, GraphQL, and gRPC
+  dashboard  serve the live operations page
+  migrate    apply the database migrations and exit
+  admin      create the topics and bring their configs in line, then exit
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "produce":
+		producer.Run(args)
+	case "consume":
+		consumer.Run(args)
+	case "match":
+		matcher.Run(args)
+	case "price":
+		pricer.Run(args)
+	case "api":
+		api.Run(args)
+	case "dashboard":
+		dashboard.Run(args)
+	case "migrate":
+		consumer.Run([]string{"migrate"})
+	case "admin":
+		kafkaadmin.Run(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
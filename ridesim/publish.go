@@ -0,0 +1,45 @@
+package ridesim
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+// PublishRide validates e and produces it to topic, keyed by its trip, with
+// its meta as headers. Invalid events are not sent.
+func PublishRide(sink rideconsumer.Sink, topic string, e events.RideEvent) error {
+	if err := e.Validate(); err != nil {
+		return fmt.Errorf("ridesim: refusing to send invalid %s event: %w", e.Type, err)
+	}
+	return produce(sink, topic, e.TripID, e, e.Meta)
+}
+
+// PublishDriver validates e and produces it to topic, keyed by its driver, as
+// PublishRide does for ride events.
+func PublishDriver(sink rideconsumer.Sink, topic string, e events.DriverEvent) error {
+	if err := e.Validate(); err != nil {
+		return fmt.Errorf("ridesim: refusing to send invalid %s event: %w", e.Type, err)
+	}
+	return produce(sink, topic, e.DriverID, e, e.Meta)
+}
+
+func produce(sink rideconsumer.Sink, topic, key string, v any, meta events.Meta) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(key),
+		Value:          value,
+	}
+	for k, v := range meta.Headers() {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return sink.Produce(msg, nil)
+}
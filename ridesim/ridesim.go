@@ -0,0 +1,65 @@
+// Package ridesim holds what the ride simulators share: where simulated rides
+// happen, how far they drive, what they cost, and how simulated vehicles move.
+package ridesim
+
+import (
+	"math"
+
+	"github.com/brianvoe/gofakeit/v6"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+)
+
+// RandomCoordinate returns a point inside a box roughly covering New York
+// City, so simulated trips cluster the way real ones would.
+func RandomCoordinate() events.Coordinate {
+	lat, _ := gofakeit.LatitudeInRange(40.60, 40.85)
+	lng, _ := gofakeit.LongitudeInRange(-74.05, -73.75)
+	return events.Coordinate{Lat: lat, Lng: lng}
+}
+
+// DetourFactor is how much longer a trip on the road is than the straight line
+// between its pickup and dropoff.
+const DetourFactor = 1.3
+
+// TripDistance returns the simulated distance driven along route, in
+// kilometers rounded to two decimal places.
+func TripDistance(route events.Route) float64 {
+	return math.Round(route.DistanceKM()*DetourFactor*100) / 100
+}
+
+// Fare returns the fare of a trip of distance kilometers at
+// pricing.DefaultRates and the surge multiplier of its pickup zone when it was
+// requested: a base fare of $2.50 and $1.00 per kilometer, times the
+// multiplier, rounded to the cent. A multiplier below 1 is taken as 1.
+func Fare(distance, multiplier float64) events.Money {
+	fare, _ := pricing.DefaultRates.Fare(distance, 0, max(multiplier, 1)) // same currency
+	return fare
+}
+
+// Toward returns where a vehicle at from ends up after driving meters in a
+// straight line to to, and whether it got there.
+func Toward(from, to events.Coordinate, meters float64) (events.Coordinate, bool) {
+	left := from.DistanceM(to)
+	if left <= meters {
+		return to, true
+	}
+	f := meters / left
+	return events.Coordinate{
+		Lat: from.Lat + (to.Lat-from.Lat)*f,
+		Lng: from.Lng + (to.Lng-from.Lng)*f,
+	}, false
+}
+
+// Heading returns the initial bearing from from to to, in degrees clockwise
+// from true north in [0, 360).
+func Heading(from, to events.Coordinate) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	lat1, lat2 := toRad(from.Lat), toRad(to.Lat)
+	dLng := toRad(to.Lng - from.Lng)
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	deg := math.Atan2(y, x) * 180 / math.Pi
+	return math.Mod(deg+360, 360)
+}
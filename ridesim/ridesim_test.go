@@ -0,0 +1,54 @@
+package ridesim
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+func TestToward(t *testing.T) {
+	from := events.Coordinate{Lat: 40.70, Lng: -74.00}
+	to := events.Coordinate{Lat: 40.71, Lng: -74.00} // about 1.1km north
+
+	at, arrived := Toward(from, to, 500)
+	if arrived {
+		t.Fatal("arrived after 500m of a 1.1km drive")
+	}
+	if d := from.DistanceM(at); math.Abs(d-500) > 1 {
+		t.Errorf("drove %.1fm, want 500m", d)
+	}
+	if at, arrived = Toward(at, to, 1000); !arrived || at != to {
+		t.Errorf("got %+v, %v; want to arrive", at, arrived)
+	}
+}
+
+func TestHeading(t *testing.T) {
+	from := events.Coordinate{Lat: 40.70, Lng: -74.00}
+	for _, tt := range []struct {
+		to   events.Coordinate
+		want float64
+	}{
+		{events.Coordinate{Lat: 40.71, Lng: -74.00}, 0},
+		{events.Coordinate{Lat: 40.70, Lng: -73.99}, 90},
+		{events.Coordinate{Lat: 40.69, Lng: -74.00}, 180},
+		{events.Coordinate{Lat: 40.70, Lng: -74.01}, 270},
+	} {
+		if got := Heading(from, tt.to); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("Heading to %+v = %.2f, want %.0f", tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestFare(t *testing.T) {
+	distance := TripDistance(events.Route{{Lat: 40.70, Lng: -74.00}, {Lat: 40.71, Lng: -74.00}})
+	if got, want := Fare(distance, 1), events.NewMoney(395, events.USD); got != want {
+		t.Errorf("Fare = %v, want %v", got, want)
+	}
+	if got, want := Fare(distance, 1.5), events.NewMoney(593, events.USD); got != want {
+		t.Errorf("Fare at 1.5x = %v, want %v", got, want)
+	}
+	if got, want := Fare(distance, 0), events.NewMoney(395, events.USD); got != want {
+		t.Errorf("Fare at 0x = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+// Package ridetest provides in-memory fakes of the broker and the store, so
+// the path from generating ride events through consuming them to persisting
+// them can be tested without Kafka, a database, or Docker. Broker stands in
+// for both the producer's Kafka producer and the consumer's Source and Sink,
+// and Store implements rides_db.RideStore.
+package ridetest
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+// Broker is a rideconsumer.MemoryBroker whose Produce can be made to fail, for
+// testing how producers handle a full queue or a broker that is down. Every
+// topic has a single partition, and consumers read it with Source.
+type Broker struct {
+	*rideconsumer.MemoryBroker
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewBroker returns an empty broker.
+func NewBroker() *Broker {
+	return &Broker{MemoryBroker: rideconsumer.NewMemoryBroker()}
+}
+
+// FailProduce makes the next len(errs) calls to Produce return errs in turn
+// without storing their messages.
+func (b *Broker) FailProduce(errs ...error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errs = append(b.errs, errs...)
+}
+
+// Produce stores msg on its topic and sends its delivery report on
+// deliveryChan when that is not nil, unless FailProduce queued an error.
+func (b *Broker) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	b.mu.Lock()
+	if len(b.errs) > 0 {
+		err := b.errs[0]
+		b.errs = b.errs[1:]
+		b.mu.Unlock()
+		return err
+	}
+	b.mu.Unlock()
+	return b.MemoryBroker.Produce(msg, deliveryChan)
+}
+
+// Events decodes the ride events published to topic so far, in order.
+func (b *Broker) Events(topic string) ([]events.RideEvent, error) {
+	msgs := b.Messages(topic)
+	evts := make([]events.RideEvent, 0, len(msgs))
+	for _, msg := range msgs {
+		var e events.RideEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			return nil, err
+		}
+		evts = append(evts, e)
+	}
+	return evts, nil
+}
+
+// Message encodes v as JSON in a message read from topic, for passing to a
+// service's Handle.
+func Message(t testing.TB, topic string, v any) *kafka.Message {
+	t.Helper()
+	value, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}, Value: value}
+}
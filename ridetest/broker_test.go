@@ -0,0 +1,41 @@
+package ridetest
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/events/eventstest"
+)
+
+func TestBroker_FailProduce(t *testing.T) {
+	broker := NewBroker()
+	full := errors.New("queue full")
+	broker.FailProduce(full)
+
+	e := eventstest.RandomRideEvent(rand.New(rand.NewPCG(5, 5)), events.EventRideRequested)
+	value, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic := "ride-events"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}, Key: []byte(e.TripID), Value: value}
+	if err := broker.Produce(msg, nil); !errors.Is(err, full) {
+		t.Errorf("first Produce: got %v, want %v", err, full)
+	}
+	if err := broker.Produce(msg, nil); err != nil {
+		t.Errorf("second Produce: %v", err)
+	}
+
+	evts, err := broker.Events(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evts) != 1 || evts[0].ID != e.ID {
+		t.Errorf("got %d events, want the one produced after the failure", len(evts))
+	}
+}
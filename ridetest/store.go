@@ -0,0 +1,1065 @@
+package ridetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// ErrClosed is returned by a Store's methods after Close.
+var ErrClosed = errors.New("ridetest: store is closed")
+
+// Store is an in-memory rides_db.RideStore. It keeps the semantics the
+// consumer and the other services rely on: duplicate and conflicting events
+// are told apart, an older event never moves a ride backwards, checkpoints
+// only move forward, and WithTx either applies every write or none. Queries
+// are answered from the same data in Go, so results match the SQL backends
+// for the rows a test stores.
+//
+// Transactions run one at a time and hold the store until they finish, so
+// calling the Store itself rather than the tx from inside WithTx deadlocks.
+type Store struct {
+	mu     *sync.Mutex
+	data   *storeData
+	faults *faults
+	inTx   bool
+}
+
+var _ rides_db.RideStore = (*Store)(nil)
+
+// storeData holds the rows of every table. WithTx works on a copy and swaps
+// it in on commit.
+type storeData struct {
+	events      map[string]events.RideEvent // by event ID
+	archived    map[string]events.RideEvent
+	rides       map[string]rides_db.Ride
+	trips       map[string]aggregation.Trip
+	windows     map[windowKey]aggregation.WindowResult
+	zones       map[string]rides_db.Zone
+	surge       map[surgeKey]rides_db.SurgeMultiplier
+	checkpoints map[checkpointKey]rides_db.Checkpoint
+	outbox      []outboxRow
+	outboxID    int64
+	audit       []rides_db.AuditEntry
+	fraudAlerts map[string]fraud.Alert // by alert ID
+	heatmap     map[heatmapKey]rides_db.HeatmapCell
+	deliveries  map[deliveryKey]rides_db.NotificationDelivery
+	closed      bool
+}
+
+type windowKey struct {
+	start     time.Time
+	eventType events.RideEventType
+}
+
+type heatmapKey struct {
+	start  time.Time
+	zoneID string
+}
+
+type deliveryKey struct {
+	notificationID string
+	channel        string
+}
+
+type surgeKey struct {
+	zoneID        string
+	effectiveFrom time.Time
+}
+
+type checkpointKey struct {
+	group     string
+	topic     string
+	partition int32
+}
+
+type outboxRow struct {
+	rides_db.OutboxMessage
+	published bool
+}
+
+// faults holds the errors set with FailOn, shared by a Store and its transactions.
+type faults struct {
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{
+		mu: &sync.Mutex{},
+		data: &storeData{
+			events:      make(map[string]events.RideEvent),
+			archived:    make(map[string]events.RideEvent),
+			rides:       make(map[string]rides_db.Ride),
+			trips:       make(map[string]aggregation.Trip),
+			windows:     make(map[windowKey]aggregation.WindowResult),
+			zones:       make(map[string]rides_db.Zone),
+			surge:       make(map[surgeKey]rides_db.SurgeMultiplier),
+			checkpoints: make(map[checkpointKey]rides_db.Checkpoint),
+			fraudAlerts: make(map[string]fraud.Alert),
+			heatmap:     make(map[heatmapKey]rides_db.HeatmapCell),
+			deliveries:  make(map[deliveryKey]rides_db.NotificationDelivery),
+		},
+		faults: &faults{errs: make(map[string]error)},
+	}
+}
+
+// FailOn makes every call to the RideStore method named method, such as
+// "InsertRideEvent", return err without changing anything, until FailOn is
+// called again for it with a nil err. It applies inside transactions too, and
+// a failure there rolls the transaction back like any other error.
+func (s *Store) FailOn(method string, err error) {
+	s.faults.mu.Lock()
+	defer s.faults.mu.Unlock()
+	if err == nil {
+		delete(s.faults.errs, method)
+		return
+	}
+	s.faults.errs[method] = err
+}
+
+// begin locks the store for a call to method and returns the error it should
+// fail with, if any. The caller must call s.mu.Unlock.
+func (s *Store) begin(method string) error {
+	s.mu.Lock()
+	if s.data.closed {
+		return ErrClosed
+	}
+	s.faults.mu.Lock()
+	defer s.faults.mu.Unlock()
+	return s.faults.errs[method]
+}
+
+func (d *storeData) clone() *storeData {
+	c := *d
+	c.events = maps.Clone(d.events)
+	c.archived = maps.Clone(d.archived)
+	c.rides = maps.Clone(d.rides)
+	c.trips = maps.Clone(d.trips)
+	c.windows = maps.Clone(d.windows)
+	c.zones = maps.Clone(d.zones)
+	c.surge = maps.Clone(d.surge)
+	c.checkpoints = maps.Clone(d.checkpoints)
+	c.outbox = slices.Clone(d.outbox)
+	c.audit = slices.Clone(d.audit)
+	c.fraudAlerts = maps.Clone(d.fraudAlerts)
+	c.heatmap = maps.Clone(d.heatmap)
+	c.deliveries = maps.Clone(d.deliveries)
+	return &c
+}
+
+// WithTx runs fn on a transaction over a copy of the store's data, which
+// replaces the data if fn returns nil and is dropped otherwise. Calls on a
+// store handed to fn join its transaction.
+func (s *Store) WithTx(ctx context.Context, fn func(tx rides_db.RideStore) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	if err := s.begin("WithTx"); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	defer s.mu.Unlock()
+
+	tx := &Store{mu: &sync.Mutex{}, data: s.data.clone(), faults: s.faults, inTx: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.data = tx.data
+	return nil
+}
+
+// Events returns every stored event that has not been archived, in event-time order.
+func (s *Store) Events() []events.RideEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedEvents(s.data.events, func(events.RideEvent) bool { return true })
+}
+
+// Windows returns the stored aggregation windows, by start and event type.
+func (s *Store) Windows() []aggregation.WindowResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := slices.Collect(maps.Values(s.data.windows))
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return out[i].EventType < out[j].EventType
+	})
+	return out
+}
+
+// Audit returns the audit log in the order it was appended.
+func (s *Store) Audit() []rides_db.AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.data.audit)
+}
+
+// FraudAlerts returns the stored fraud alerts, by detection time.
+func (s *Store) FraudAlerts() []fraud.Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := slices.Collect(maps.Values(s.data.fraudAlerts))
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].DetectedAt.Equal(out[j].DetectedAt) {
+			return out[i].DetectedAt.Before(out[j].DetectedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// InsertRideEvent stores e unless an event with its ID is already stored, or
+// the trip already has an event of its type at the same time, and reports
+// which happened.
+func (s *Store) InsertRideEvent(ctx context.Context, e events.RideEvent) (rides_db.InsertOutcome, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("InsertRideEvent"); err != nil {
+		return "", err
+	}
+	return s.data.insertEvent(e), nil
+}
+
+func (d *storeData) insertEvent(e events.RideEvent) rides_db.InsertOutcome {
+	if stored, ok := d.events[e.ID]; ok {
+		if stored.TripID == e.TripID && stored.Type == e.Type {
+			return rides_db.OutcomeDuplicate
+		}
+		return rides_db.OutcomeConflict
+	}
+	for _, stored := range d.events {
+		if stored.TripID == e.TripID && stored.Type == e.Type && stored.OccurredAt.Equal(e.OccurredAt) {
+			return rides_db.OutcomeConflict
+		}
+	}
+	d.events[e.ID] = e
+	return rides_db.OutcomeInserted
+}
+
+// InsertRideEvents stores evts and returns how many were inserted; events
+// InsertRideEvent would skip are skipped.
+func (s *Store) InsertRideEvents(ctx context.Context, evts []events.RideEvent) (int64, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("InsertRideEvents"); err != nil {
+		return 0, err
+	}
+	var inserted int64
+	for _, e := range evts {
+		if s.data.insertEvent(e) == rides_db.OutcomeInserted {
+			inserted++
+		}
+	}
+	return inserted, nil
+}
+
+// UpsertRideState folds e into the trip's ride. An event older than the one
+// already applied still fills in missing details but never moves the state
+// backwards.
+func (s *Store) UpsertRideState(ctx context.Context, e events.RideEvent) error {
+	defer s.mu.Unlock()
+	if err := s.begin("UpsertRideState"); err != nil {
+		return err
+	}
+	r, ok := s.data.rides[e.TripID]
+	if !ok {
+		r = rides_db.Ride{TripID: e.TripID, LastEventAt: e.OccurredAt}
+	}
+	if !e.OccurredAt.Before(r.LastEventAt) {
+		r.State, r.LastEventType, r.LastEventAt = e.State, e.Type, e.OccurredAt
+	}
+	if e.DriverID != "" {
+		r.DriverID = e.DriverID
+	}
+	if e.PassengerID != "" {
+		r.PassengerID = e.PassengerID
+	}
+	setOnce := func(t *time.Time) {
+		if t.IsZero() {
+			*t = e.OccurredAt
+		}
+	}
+	switch e.Type {
+	case events.EventRideRequested:
+		setOnce(&r.RequestedAt)
+		if p, ok := events.PayloadAs[events.RideRequestedPayload](e); ok {
+			if r.Pickup == nil {
+				r.Pickup = p.Pickup
+			}
+			if r.Dropoff == nil {
+				r.Dropoff = p.Dropoff
+			}
+		}
+	case events.EventRideAccepted:
+		setOnce(&r.AcceptedAt)
+	case events.EventTripStarted:
+		setOnce(&r.StartedAt)
+	case events.EventTripCompleted:
+		setOnce(&r.EndedAt)
+		if p, ok := events.PayloadAs[events.RideCompletedPayload](e); ok {
+			r.FareUSD = p.Fare.Float64()
+		}
+	case events.EventTripCancelled:
+		setOnce(&r.EndedAt)
+	}
+	s.data.rides[e.TripID] = r
+	return nil
+}
+
+// InsertTrip writes the summary of a finished trip, replacing any earlier one.
+func (s *Store) InsertTrip(ctx context.Context, t aggregation.Trip) error {
+	defer s.mu.Unlock()
+	if err := s.begin("InsertTrip"); err != nil {
+		return err
+	}
+	s.data.trips[t.TripID] = t
+	return nil
+}
+
+// RefreshTrip rebuilds the trip's summary by replaying its stored events
+// through a TripAssembler. Trips without a terminal event are left alone.
+func (s *Store) RefreshTrip(ctx context.Context, tripID string) error {
+	defer s.mu.Unlock()
+	if err := s.begin("RefreshTrip"); err != nil {
+		return err
+	}
+	assembler := aggregation.NewTripAssembler()
+	for _, e := range s.data.tripEvents(tripID) {
+		if trip, done := assembler.Add(e); done {
+			s.data.trips[tripID] = trip
+			return nil
+		}
+	}
+	return nil
+}
+
+// GetTrip returns the summary of a finished trip, or rides_db.ErrNotFound.
+func (s *Store) GetTrip(ctx context.Context, tripID string) (aggregation.Trip, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("GetTrip"); err != nil {
+		return aggregation.Trip{}, err
+	}
+	t, ok := s.data.trips[tripID]
+	if !ok {
+		return aggregation.Trip{}, rides_db.ErrNotFound
+	}
+	return t, nil
+}
+
+// ListTrips returns finished trips matching f, newest request first.
+func (s *Store) ListTrips(ctx context.Context, f rides_db.TripFilter) ([]aggregation.Trip, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("ListTrips"); err != nil {
+		return nil, err
+	}
+	var trips []aggregation.Trip
+	for _, t := range s.data.trips {
+		if !f.From.IsZero() && (t.RequestedAt.IsZero() || t.RequestedAt.Before(f.From)) {
+			continue
+		}
+		if !f.To.IsZero() && (t.RequestedAt.IsZero() || !t.RequestedAt.Before(f.To)) {
+			continue
+		}
+		if (f.State != "" && t.FinalState != f.State) || (f.DriverID != "" && t.DriverID != f.DriverID) {
+			continue
+		}
+		trips = append(trips, t)
+	}
+	sort.Slice(trips, func(i, j int) bool {
+		a, b := trips[i].RequestedAt, trips[j].RequestedAt
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return trips[i].TripID < trips[j].TripID
+	})
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	return page(trips, limit, f.Offset), nil
+}
+
+// ExportTrips calls fn with every finished trip requested within tr, in request
+// order, stopping at the first error fn returns. fn runs with the store locked.
+func (s *Store) ExportTrips(ctx context.Context, tr rides_db.TimeRange, fn func(aggregation.Trip) error) error {
+	defer s.mu.Unlock()
+	if err := s.begin("ExportTrips"); err != nil {
+		return err
+	}
+	if tr.From.IsZero() || tr.To.IsZero() {
+		return rides_db.ErrExportRange
+	}
+	var trips []aggregation.Trip
+	for _, t := range s.data.trips {
+		if within(t.RequestedAt, tr) {
+			trips = append(trips, t)
+		}
+	}
+	sort.Slice(trips, func(i, j int) bool {
+		if !trips[i].RequestedAt.Equal(trips[j].RequestedAt) {
+			return trips[i].RequestedAt.Before(trips[j].RequestedAt)
+		}
+		return trips[i].TripID < trips[j].TripID
+	})
+	for _, t := range trips {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTripEvents returns every stored event of a trip in event-time order.
+func (s *Store) GetTripEvents(ctx context.Context, tripID string) ([]events.RideEvent, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("GetTripEvents"); err != nil {
+		return nil, err
+	}
+	return s.data.tripEvents(tripID), nil
+}
+
+func (d *storeData) tripEvents(tripID string) []events.RideEvent {
+	return sortedEvents(d.events, func(e events.RideEvent) bool { return e.TripID == tripID })
+}
+
+// ExportRideEvents calls fn with every stored event within tr, in event-time
+// order, stopping at the first error fn returns. fn runs with the store locked.
+func (s *Store) ExportRideEvents(ctx context.Context, tr rides_db.TimeRange, fn func(events.RideEvent) error) error {
+	defer s.mu.Unlock()
+	if err := s.begin("ExportRideEvents"); err != nil {
+		return err
+	}
+	if tr.From.IsZero() || tr.To.IsZero() {
+		return rides_db.ErrExportRange
+	}
+	for _, e := range sortedEvents(s.data.events, func(e events.RideEvent) bool { return within(e.OccurredAt, tr) }) {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedEvents returns the events keep accepts, by event time and then ID.
+func sortedEvents(evts map[string]events.RideEvent, keep func(events.RideEvent) bool) []events.RideEvent {
+	var out []events.RideEvent
+	for _, e := range evts {
+		if keep(e) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].OccurredAt.Equal(out[j].OccurredAt) {
+			return out[i].OccurredAt.Before(out[j].OccurredAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// GetRide returns the current state of a trip, or rides_db.ErrNotFound.
+func (s *Store) GetRide(ctx context.Context, tripID string) (rides_db.Ride, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("GetRide"); err != nil {
+		return rides_db.Ride{}, err
+	}
+	r, ok := s.data.rides[tripID]
+	if !ok {
+		return rides_db.Ride{}, rides_db.ErrNotFound
+	}
+	return r, nil
+}
+
+// ListActiveRides returns rides that have not completed or been cancelled,
+// most recently updated first.
+func (s *Store) ListActiveRides(ctx context.Context, limit, offset int) ([]rides_db.Ride, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("ListActiveRides"); err != nil {
+		return nil, err
+	}
+	var rides []rides_db.Ride
+	for _, r := range s.data.rides {
+		switch r.State {
+		case events.StateRequested, events.StateAccepted, events.StateInProgress:
+			rides = append(rides, r)
+		}
+	}
+	sort.Slice(rides, func(i, j int) bool {
+		if !rides[i].LastEventAt.Equal(rides[j].LastEventAt) {
+			return rides[i].LastEventAt.After(rides[j].LastEventAt)
+		}
+		return rides[i].TripID < rides[j].TripID
+	})
+	return page(rides, limit, offset), nil
+}
+
+// ListRidesByDriver returns the rides a driver accepted that were requested within tr.
+func (s *Store) ListRidesByDriver(ctx context.Context, driverID string, tr rides_db.TimeRange) ([]rides_db.Ride, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("ListRidesByDriver"); err != nil {
+		return nil, err
+	}
+	var rides []rides_db.Ride
+	for _, r := range s.data.rides {
+		if r.DriverID != driverID {
+			continue
+		}
+		if (!tr.From.IsZero() || !tr.To.IsZero()) && (r.RequestedAt.IsZero() || !within(r.RequestedAt, tr)) {
+			continue
+		}
+		rides = append(rides, r)
+	}
+	sort.Slice(rides, func(i, j int) bool { return rides[i].RequestedAt.After(rides[j].RequestedAt) })
+	return rides, nil
+}
+
+// RidesWithinRadius returns rides picked up within meters of (lat, lng), nearest first.
+func (s *Store) RidesWithinRadius(ctx context.Context, lat, lng, meters float64) ([]rides_db.Ride, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("RidesWithinRadius"); err != nil {
+		return nil, err
+	}
+	origin := events.Coordinate{Lat: lat, Lng: lng}
+	dist := make(map[string]float64)
+	var rides []rides_db.Ride
+	for _, r := range s.data.rides {
+		if r.Pickup == nil {
+			continue
+		}
+		if d := origin.DistanceM(*r.Pickup); d <= meters {
+			dist[r.TripID] = d
+			rides = append(rides, r)
+		}
+	}
+	sort.Slice(rides, func(i, j int) bool { return dist[rides[i].TripID] < dist[rides[j].TripID] })
+	return rides, nil
+}
+
+// completedTrips returns the completed trips that finished within tr.
+func (d *storeData) completedTrips(tr rides_db.TimeRange) []aggregation.Trip {
+	var trips []aggregation.Trip
+	for _, t := range d.trips {
+		if t.FinalState == events.StateCompleted && within(t.CompletedAt, tr) {
+			trips = append(trips, t)
+		}
+	}
+	return trips
+}
+
+// RevenueByDay sums completed-trip fares per UTC day of completion.
+func (s *Store) RevenueByDay(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.DailyRevenue, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("RevenueByDay"); err != nil {
+		return nil, err
+	}
+	days := make(map[time.Time]*rides_db.DailyRevenue)
+	for _, t := range s.data.completedTrips(tr) {
+		c := t.CompletedAt.UTC()
+		day := time.Date(c.Year(), c.Month(), c.Day(), 0, 0, 0, 0, time.UTC)
+		r, ok := days[day]
+		if !ok {
+			r = &rides_db.DailyRevenue{Day: day}
+			days[day] = r
+		}
+		r.Trips++
+		r.RevenueUSD += t.FareUSD
+	}
+	out := derefAll(days)
+	sort.Slice(out, func(i, j int) bool { return out[i].Day.Before(out[j].Day) })
+	return out, nil
+}
+
+// CancellationRateByHour reports, per UTC hour of request time, the share of
+// trips that were cancelled.
+func (s *Store) CancellationRateByHour(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.HourlyCancellationRate, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("CancellationRateByHour"); err != nil {
+		return nil, err
+	}
+	hours := make(map[time.Time]*rides_db.HourlyCancellationRate)
+	for _, t := range s.data.trips {
+		if t.RequestedAt.IsZero() || !within(t.RequestedAt, tr) {
+			continue
+		}
+		hour := t.RequestedAt.UTC().Truncate(time.Hour)
+		r, ok := hours[hour]
+		if !ok {
+			r = &rides_db.HourlyCancellationRate{Hour: hour}
+			hours[hour] = r
+		}
+		r.Requested++
+		if t.FinalState == events.StateCancelled {
+			r.Cancelled++
+		}
+		r.Rate = float64(r.Cancelled) / float64(r.Requested)
+	}
+	out := derefAll(hours)
+	sort.Slice(out, func(i, j int) bool { return out[i].Hour.Before(out[j].Hour) })
+	return out, nil
+}
+
+// AvgFareByZone averages completed-trip fares by pickup location, highest first.
+func (s *Store) AvgFareByZone(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.ZoneFare, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("AvgFareByZone"); err != nil {
+		return nil, err
+	}
+	zones := make(map[string]*rides_db.ZoneFare)
+	totals := make(map[string]float64)
+	for _, t := range s.data.completedTrips(tr) {
+		if t.PickupLocation == "" {
+			continue
+		}
+		z, ok := zones[t.PickupLocation]
+		if !ok {
+			z = &rides_db.ZoneFare{Zone: t.PickupLocation}
+			zones[t.PickupLocation] = z
+		}
+		z.Trips++
+		totals[z.Zone] += t.FareUSD
+		z.AvgFareUSD = totals[z.Zone] / float64(z.Trips)
+	}
+	out := derefAll(zones)
+	sort.Slice(out, func(i, j int) bool { return out[i].AvgFareUSD > out[j].AvgFareUSD })
+	return out, nil
+}
+
+// TopDriversByTrips ranks drivers by completed trips, breaking ties on revenue.
+func (s *Store) TopDriversByTrips(ctx context.Context, tr rides_db.TimeRange, limit int) ([]rides_db.DriverTrips, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("TopDriversByTrips"); err != nil {
+		return nil, err
+	}
+	drivers := make(map[string]*rides_db.DriverTrips)
+	for _, t := range s.data.completedTrips(tr) {
+		if t.DriverID == "" {
+			continue
+		}
+		d, ok := drivers[t.DriverID]
+		if !ok {
+			d = &rides_db.DriverTrips{DriverID: t.DriverID}
+			drivers[t.DriverID] = d
+		}
+		d.Trips++
+		d.RevenueUSD += t.FareUSD
+	}
+	out := derefAll(drivers)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Trips != out[j].Trips {
+			return out[i].Trips > out[j].Trips
+		}
+		return out[i].RevenueUSD > out[j].RevenueUSD
+	})
+	return page(out, limit, 0), nil
+}
+
+// RevenueBySurge sums completed-trip fares by the surge multiplier of their
+// pickup zone when they were requested, counting trips without one as 1.
+func (s *Store) RevenueBySurge(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.SurgeRevenue, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("RevenueBySurge"); err != nil {
+		return nil, err
+	}
+	multipliers := make(map[float64]*rides_db.SurgeRevenue)
+	for _, t := range s.data.completedTrips(tr) {
+		m := 1.0
+		var from time.Time
+		for k, sm := range s.data.surge {
+			if k.zoneID == string(t.PickupZone) && !k.effectiveFrom.After(t.RequestedAt) && !k.effectiveFrom.Before(from) {
+				m, from = sm.Multiplier, k.effectiveFrom
+			}
+		}
+		r, ok := multipliers[m]
+		if !ok {
+			r = &rides_db.SurgeRevenue{Multiplier: m}
+			multipliers[m] = r
+		}
+		r.Trips++
+		r.RevenueUSD += t.FareUSD
+	}
+	out := derefAll(multipliers)
+	sort.Slice(out, func(i, j int) bool { return out[i].Multiplier < out[j].Multiplier })
+	return out, nil
+}
+
+// UpsertEventWindow stores a windowed aggregate, replacing the window's counts.
+// A window once marked as a correction stays marked.
+func (s *Store) UpsertEventWindow(ctx context.Context, w aggregation.WindowResult) error {
+	defer s.mu.Unlock()
+	if err := s.begin("UpsertEventWindow"); err != nil {
+		return err
+	}
+	key := windowKey{start: w.Start, eventType: w.EventType}
+	if stored, ok := s.data.windows[key]; ok && stored.IsCorrection {
+		w.IsCorrection = true
+	}
+	s.data.windows[key] = w
+	return nil
+}
+
+// ListEventWindows returns the windowed aggregates that start within tr, by
+// start and event type.
+func (s *Store) ListEventWindows(ctx context.Context, tr rides_db.TimeRange) ([]aggregation.WindowResult, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("ListEventWindows"); err != nil {
+		return nil, err
+	}
+	var out []aggregation.WindowResult
+	for _, w := range s.data.windows {
+		if within(w.Start, tr) {
+			out = append(out, w)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return out[i].EventType < out[j].EventType
+	})
+	return out, nil
+}
+
+// UpsertZone creates or renames a zone. Updates only move forward in time.
+func (s *Store) UpsertZone(ctx context.Context, z rides_db.Zone) error {
+	defer s.mu.Unlock()
+	if err := s.begin("UpsertZone"); err != nil {
+		return err
+	}
+	if stored, ok := s.data.zones[z.ID]; ok && z.UpdatedAt.Before(stored.UpdatedAt) {
+		return nil
+	}
+	if z.Name == "" {
+		z.Name = z.ID
+	}
+	s.data.zones[z.ID] = z
+	return nil
+}
+
+// UpsertSurgeMultiplier records a zone's multiplier from m.EffectiveFrom on. The
+// zone must exist.
+func (s *Store) UpsertSurgeMultiplier(ctx context.Context, m rides_db.SurgeMultiplier) error {
+	defer s.mu.Unlock()
+	if err := s.begin("UpsertSurgeMultiplier"); err != nil {
+		return err
+	}
+	if _, ok := s.data.zones[m.ZoneID]; !ok {
+		return fmt.Errorf("ridetest: surge multiplier for unknown zone %q", m.ZoneID)
+	}
+	s.data.surge[surgeKey{zoneID: m.ZoneID, effectiveFrom: m.EffectiveFrom}] = m
+	return nil
+}
+
+// UpdateCheckpoint upserts the processing position for a partition; offsets only move forward.
+func (s *Store) UpdateCheckpoint(ctx context.Context, cp rides_db.Checkpoint) error {
+	defer s.mu.Unlock()
+	if err := s.begin("UpdateCheckpoint"); err != nil {
+		return err
+	}
+	key := checkpointKey{group: cp.Group, topic: cp.Topic, partition: cp.Partition}
+	if stored, ok := s.data.checkpoints[key]; ok && stored.Offset >= cp.Offset {
+		return nil
+	}
+	s.data.checkpoints[key] = cp
+	return nil
+}
+
+// GetCheckpoints returns every checkpoint recorded for a consumer group.
+func (s *Store) GetCheckpoints(ctx context.Context, group string) ([]rides_db.Checkpoint, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("GetCheckpoints"); err != nil {
+		return nil, err
+	}
+	var out []rides_db.Checkpoint
+	for k, cp := range s.data.checkpoints {
+		if k.group == group {
+			out = append(out, cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Topic != out[j].Topic {
+			return out[i].Topic < out[j].Topic
+		}
+		return out[i].Partition < out[j].Partition
+	})
+	return out, nil
+}
+
+// EnqueueOutbox adds m to the outbox with the next ID.
+func (s *Store) EnqueueOutbox(ctx context.Context, m rides_db.OutboxMessage) error {
+	defer s.mu.Unlock()
+	if err := s.begin("EnqueueOutbox"); err != nil {
+		return err
+	}
+	s.data.outboxID++
+	m.ID, m.CreatedAt, m.Attempts = s.data.outboxID, time.Now(), 0
+	s.data.outbox = append(s.data.outbox, outboxRow{OutboxMessage: m})
+	return nil
+}
+
+// ProcessOutbox publishes up to limit unpublished messages in insertion order
+// and marks them published, returning how many were. The first failed publish
+// is counted as an attempt and returned, and the messages after it wait for
+// the next call.
+func (s *Store) ProcessOutbox(ctx context.Context, limit int, publish func(rides_db.OutboxMessage) error) (int, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("ProcessOutbox"); err != nil {
+		return 0, err
+	}
+	published := 0
+	for i := range s.data.outbox {
+		row := &s.data.outbox[i]
+		if row.published {
+			continue
+		}
+		if published == limit {
+			break
+		}
+		if err := publish(row.OutboxMessage); err != nil {
+			row.Attempts++
+			return published, err
+		}
+		row.published = true
+		published++
+	}
+	return published, nil
+}
+
+// ArchiveRideEvents moves up to limit events older than before out of the
+// store, oldest first, and returns how many were moved.
+func (s *Store) ArchiveRideEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("ArchiveRideEvents"); err != nil {
+		return 0, err
+	}
+	old := sortedEvents(s.data.events, func(e events.RideEvent) bool { return e.OccurredAt.Before(before) })
+	old = page(old, limit, 0)
+	for _, e := range old {
+		s.data.archived[e.ID] = e
+		delete(s.data.events, e.ID)
+	}
+	return int64(len(old)), nil
+}
+
+// ResetDerived deletes the rides and trips of every trip with stored events,
+// and the windows starting at or after the oldest stored event.
+func (s *Store) ResetDerived(ctx context.Context, tables ...rides_db.DerivedTable) error {
+	defer s.mu.Unlock()
+	if err := s.begin("ResetDerived"); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if !slices.Contains(rides_db.DerivedTables, t) {
+			return fmt.Errorf("ridetest: %q is not a derived table", t)
+		}
+	}
+	var oldest time.Time
+	for _, e := range s.data.events {
+		if oldest.IsZero() || e.OccurredAt.Before(oldest) {
+			oldest = e.OccurredAt
+		}
+	}
+	for _, t := range tables {
+		switch t {
+		case rides_db.DerivedRides, rides_db.DerivedTrips:
+			for _, e := range s.data.events {
+				if t == rides_db.DerivedRides {
+					delete(s.data.rides, e.TripID)
+				} else {
+					delete(s.data.trips, e.TripID)
+				}
+			}
+		case rides_db.DerivedWindows:
+			if oldest.IsZero() {
+				continue
+			}
+			for k := range s.data.windows {
+				if !k.start.Before(oldest) {
+					delete(s.data.windows, k)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// AppendAudit adds e to the audit log, recorded now unless it says otherwise.
+func (s *Store) AppendAudit(ctx context.Context, e rides_db.AuditEntry) error {
+	defer s.mu.Unlock()
+	if err := s.begin("AppendAudit"); err != nil {
+		return err
+	}
+	if e.RecordedAt.IsZero() {
+		e.RecordedAt = time.Now()
+	}
+	s.data.audit = append(s.data.audit, e)
+	return nil
+}
+
+// InsertFraudAlert stores a unless an alert with its ID is already stored.
+func (s *Store) InsertFraudAlert(ctx context.Context, a fraud.Alert) error {
+	defer s.mu.Unlock()
+	if err := s.begin("InsertFraudAlert"); err != nil {
+		return err
+	}
+	if _, ok := s.data.fraudAlerts[a.ID]; !ok {
+		s.data.fraudAlerts[a.ID] = a
+	}
+	return nil
+}
+
+// ListFraudAlerts returns the limit most recently detected alerts, newest
+// first.
+func (s *Store) ListFraudAlerts(ctx context.Context, limit int) ([]fraud.Alert, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("ListFraudAlerts"); err != nil {
+		return nil, err
+	}
+	out := slices.Collect(maps.Values(s.data.fraudAlerts))
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].DetectedAt.Equal(out[j].DetectedAt) {
+			return out[i].DetectedAt.After(out[j].DetectedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return page(out, limit, 0), nil
+}
+
+// UpsertHeatmapCell stores c, keeping the higher of each count where the cell
+// is already stored.
+func (s *Store) UpsertHeatmapCell(ctx context.Context, c rides_db.HeatmapCell) error {
+	defer s.mu.Unlock()
+	if err := s.begin("UpsertHeatmapCell"); err != nil {
+		return err
+	}
+	key := heatmapKey{c.Start.UTC(), c.ZoneID}
+	if old, ok := s.data.heatmap[key]; ok {
+		c.Pickups, c.Drivers = max(c.Pickups, old.Pickups), max(c.Drivers, old.Drivers)
+	}
+	c.Start, c.End = c.Start.UTC(), c.End.UTC()
+	s.data.heatmap[key] = c
+	return nil
+}
+
+// ListHeatmapCells returns the cells whose bucket starts within tr, by start
+// and zone.
+func (s *Store) ListHeatmapCells(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.HeatmapCell, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("ListHeatmapCells"); err != nil {
+		return nil, err
+	}
+	var out []rides_db.HeatmapCell
+	for _, c := range s.data.heatmap {
+		if within(c.Start, tr) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return out[i].ZoneID < out[j].ZoneID
+	})
+	return out, nil
+}
+
+// UpsertNotificationDelivery stores d, unless its delivery is already sent.
+// CreatedAt is set when it is first stored, and UpdatedAt on every change.
+func (s *Store) UpsertNotificationDelivery(ctx context.Context, d rides_db.NotificationDelivery) error {
+	defer s.mu.Unlock()
+	if err := s.begin("UpsertNotificationDelivery"); err != nil {
+		return err
+	}
+	key := deliveryKey{d.NotificationID, d.Channel}
+	now := time.Now().UTC()
+	d.CreatedAt, d.UpdatedAt = now, now
+	if old, ok := s.data.deliveries[key]; ok {
+		if old.Status == rides_db.DeliverySent {
+			return nil
+		}
+		d.CreatedAt = old.CreatedAt
+	}
+	s.data.deliveries[key] = d
+	return nil
+}
+
+// ListNotificationDeliveries returns the deliveries of trip tripID, oldest
+// first.
+func (s *Store) ListNotificationDeliveries(ctx context.Context, tripID string) ([]rides_db.NotificationDelivery, error) {
+	defer s.mu.Unlock()
+	if err := s.begin("ListNotificationDeliveries"); err != nil {
+		return nil, err
+	}
+	var out []rides_db.NotificationDelivery
+	for _, d := range s.data.deliveries {
+		if d.TripID == tripID {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		if out[i].NotificationID != out[j].NotificationID {
+			return out[i].NotificationID < out[j].NotificationID
+		}
+		return out[i].Channel < out[j].Channel
+	})
+	return out, nil
+}
+
+// Migrate does nothing; the store has no schema.
+func (s *Store) Migrate(ctx context.Context) error {
+	defer s.mu.Unlock()
+	return s.begin("Migrate")
+}
+
+// Health reports ErrClosed once the store is closed.
+func (s *Store) Health(ctx context.Context) error {
+	defer s.mu.Unlock()
+	return s.begin("Health")
+}
+
+// Close makes later calls fail with ErrClosed. The data stays readable with
+// Events, Windows, and Audit.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.closed = true
+	return nil
+}
+
+// within reports whether t falls in tr, whose zero bounds are open.
+func within(t time.Time, tr rides_db.TimeRange) bool {
+	return (tr.From.IsZero() || !t.Before(tr.From)) && (tr.To.IsZero() || t.Before(tr.To))
+}
+
+// page returns up to limit items of s from offset on, as LIMIT and OFFSET would.
+func page[T any](s []T, limit, offset int) []T {
+	if offset >= len(s) || limit <= 0 {
+		return nil
+	}
+	return s[offset:min(offset+limit, len(s))]
+}
+
+func derefAll[K comparable, V any](m map[K]*V) []V {
+	out := make([]V, 0, len(m))
+	for _, v := range m {
+		out = append(out, *v)
+	}
+	return out
+}
@@ -0,0 +1,197 @@
+package ridetest
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/events/eventstest"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+func TestStore_InsertRideEventOutcomes(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	e := eventstest.RandomRideEvent(rand.New(rand.NewPCG(1, 1)), events.EventRideRequested)
+
+	for _, want := range []rides_db.InsertOutcome{rides_db.OutcomeInserted, rides_db.OutcomeDuplicate} {
+		if got, err := store.InsertRideEvent(ctx, e); err != nil || got != want {
+			t.Errorf("InsertRideEvent: got %q, %v; want %q", got, err, want)
+		}
+	}
+
+	reused := e
+	reused.TripID = "another-trip"
+	sameTime := e
+	sameTime.ID = "7d3c9f2a-1b4e-4c5d-8e6f-a7b8c9d0e1f2"
+	for _, clash := range []events.RideEvent{reused, sameTime} {
+		if got, _ := store.InsertRideEvent(ctx, clash); got != rides_db.OutcomeConflict {
+			t.Errorf("InsertRideEvent(%s): got %q, want conflict", clash.ID, got)
+		}
+	}
+	if n := len(store.Events()); n != 1 {
+		t.Errorf("stored %d events, want 1", n)
+	}
+}
+
+func TestStore_UpsertRideStateOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	trip := eventstest.RandomTrip(rand.New(rand.NewPCG(2, 2)),
+		events.EventRideRequested, events.EventRideAccepted, events.EventTripStarted, events.EventTripCompleted)
+
+	// The completion arrives first; the earlier events fill in details only
+	for _, i := range []int{3, 0, 2, 1} {
+		if err := store.UpsertRideState(ctx, trip[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ride, err := store.GetRide(ctx, trip[0].TripID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ride.State != events.StateCompleted || ride.LastEventType != events.EventTripCompleted {
+		t.Errorf("state moved backwards: %s after %s", ride.State, ride.LastEventType)
+	}
+	if ride.RequestedAt.IsZero() || ride.AcceptedAt.IsZero() || ride.Pickup == nil || ride.FareUSD == 0 || ride.DriverID == "" {
+		t.Errorf("details missing: %+v", ride)
+	}
+	if _, err := store.GetRide(ctx, "no-such-trip"); !errors.Is(err, rides_db.ErrNotFound) {
+		t.Errorf("GetRide of a missing trip: got %v", err)
+	}
+}
+
+func TestStore_WithTxRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	e := eventstest.RandomRideEvent(rand.New(rand.NewPCG(3, 3)), events.EventRideRequested)
+	down := errors.New("database down")
+	store.FailOn("UpdateCheckpoint", down)
+
+	err := store.WithTx(ctx, func(tx rides_db.RideStore) error {
+		if _, err := tx.InsertRideEvent(ctx, e); err != nil {
+			return err
+		}
+		if err := tx.UpsertRideState(ctx, e); err != nil {
+			return err
+		}
+		return tx.UpdateCheckpoint(ctx, rides_db.Checkpoint{Group: "g", Topic: "t", Offset: 1})
+	})
+	if !errors.Is(err, down) {
+		t.Fatalf("WithTx: got %v, want %v", err, down)
+	}
+	if n := len(store.Events()); n != 0 {
+		t.Errorf("rolled back transaction left %d events", n)
+	}
+	if _, err := store.GetRide(ctx, e.TripID); !errors.Is(err, rides_db.ErrNotFound) {
+		t.Errorf("rolled back transaction left the ride: %v", err)
+	}
+
+	store.FailOn("UpdateCheckpoint", nil)
+	for _, offset := range []int64{5, 3} {
+		err = store.WithTx(ctx, func(tx rides_db.RideStore) error {
+			return tx.UpdateCheckpoint(ctx, rides_db.Checkpoint{Group: "g", Topic: "t", Offset: offset})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cps, _ := store.GetCheckpoints(ctx, "g"); len(cps) != 1 || cps[0].Offset != 5 {
+		t.Errorf("checkpoint moved backwards: %+v", cps)
+	}
+
+	store.Close()
+	if _, err := store.InsertRideEvent(ctx, e); !errors.Is(err, ErrClosed) {
+		t.Errorf("after Close: got %v, want ErrClosed", err)
+	}
+}
+
+func TestStore_ProcessOutboxStopsAtFailure(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	for _, key := range []string{"a", "b", "c"} {
+		if err := store.EnqueueOutbox(ctx, rides_db.OutboxMessage{Topic: "t", Key: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var sent []string
+	refused := errors.New("broker refused")
+	n, err := store.ProcessOutbox(ctx, 10, func(m rides_db.OutboxMessage) error {
+		if m.Key == "b" {
+			return refused
+		}
+		sent = append(sent, m.Key)
+		return nil
+	})
+	if n != 1 || !errors.Is(err, refused) {
+		t.Errorf("ProcessOutbox: got %d, %v; want 1, %v", n, err, refused)
+	}
+
+	n, err = store.ProcessOutbox(ctx, 10, func(m rides_db.OutboxMessage) error {
+		if m.Key == "b" && m.Attempts != 1 {
+			t.Errorf("attempts = %d, want 1", m.Attempts)
+		}
+		sent = append(sent, m.Key)
+		return nil
+	})
+	if n != 2 || err != nil {
+		t.Errorf("ProcessOutbox: got %d, %v; want 2, nil", n, err)
+	}
+	if got := len(sent); got != 3 || sent[0] != "a" || sent[1] != "b" || sent[2] != "c" {
+		t.Errorf("published %v, want [a b c]", sent)
+	}
+}
+
+func TestStore_Analytics(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	r := rand.New(rand.NewPCG(4, 4))
+	for i := 0; i < 3; i++ {
+		trip := eventstest.RandomTrip(r, events.EventRideRequested, events.EventRideAccepted, events.EventTripStarted, events.EventTripCompleted)
+		for _, e := range trip {
+			if _, err := store.InsertRideEvent(ctx, e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := store.RefreshTrip(ctx, trip[0].TripID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancelled := eventstest.RandomTrip(r, events.EventRideRequested, events.EventTripCancelled)
+	store.InsertRideEvents(ctx, cancelled)
+	store.RefreshTrip(ctx, cancelled[0].TripID)
+
+	day := rides_db.TimeRange{From: eventstest.Epoch, To: eventstest.Epoch.Add(48 * time.Hour)}
+	revenue, err := store.RevenueByDay(ctx, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trips int64
+	for _, d := range revenue {
+		trips += d.Trips
+	}
+	if trips != 3 {
+		t.Errorf("RevenueByDay counted %d trips, want 3: %+v", trips, revenue)
+	}
+
+	rates, _ := store.CancellationRateByHour(ctx, day)
+	var requested, cancelledTrips int64
+	for _, h := range rates {
+		requested += h.Requested
+		cancelledTrips += h.Cancelled
+	}
+	if requested != 4 || cancelledTrips != 1 {
+		t.Errorf("CancellationRateByHour: %d requested, %d cancelled; want 4 and 1", requested, cancelledTrips)
+	}
+
+	if top, _ := store.TopDriversByTrips(ctx, day, 2); len(top) != 2 {
+		t.Errorf("TopDriversByTrips returned %d drivers, want the limit of 2", len(top))
+	}
+	if surge, _ := store.RevenueBySurge(ctx, day); len(surge) != 1 || surge[0].Multiplier != 1 || surge[0].Trips != 3 {
+		t.Errorf("RevenueBySurge without surge: %+v", surge)
+	}
+}
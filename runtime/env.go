@@ -0,0 +1,67 @@
+package runtime
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvString returns the value of key, or def when it is unset or empty. Like
+// the other Env helpers, it reads the environment and then the config file
+// Start loaded from CONFIG_FILE, reloaded since.
+func EnvString(key, def string) string {
+	if v := lookup(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// EnvInt returns the value of key as a positive integer, or def when it is
+// unset. Invalid values are logged and def returned.
+func EnvInt(key string, def int) int {
+	return env(key, def, strconv.Atoi, func(n int) bool { return n > 0 })
+}
+
+// EnvFloat returns the value of key as a positive number, or def when it is
+// unset. Invalid values are logged and def returned.
+func EnvFloat(key string, def float64) float64 {
+	return env(key, def, func(raw string) (float64, error) { return strconv.ParseFloat(raw, 64) },
+		func(f float64) bool { return f > 0 })
+}
+
+// EnvDuration returns the value of key as a positive duration, such as 30s, or
+// def when it is unset. Invalid values are logged and def returned.
+func EnvDuration(key string, def time.Duration) time.Duration {
+	return env(key, def, time.ParseDuration, func(d time.Duration) bool { return d > 0 })
+}
+
+// EnvBool returns the value of key as a boolean, or def when it is unset.
+// Invalid values are logged and def returned.
+func EnvBool(key string, def bool) bool {
+	return env(key, def, strconv.ParseBool, func(bool) bool { return true })
+}
+
+// EnvList returns the comma-separated values of key, dropping blanks.
+func EnvList(key string) []string {
+	var out []string
+	for _, s := range strings.Split(lookup(key), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func env[T any](key string, def T, parse func(string) (T, error), valid func(T) bool) T {
+	raw := lookup(key)
+	if raw == "" {
+		return def
+	}
+	v, err := parse(raw)
+	if err != nil || !valid(v) {
+		slog.Warn("Invalid "+key+", using default", "value", raw, "default", def)
+		return def
+	}
+	return v
+}
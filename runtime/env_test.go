@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/config"
+)
+
+func TestEnv(t *testing.T) {
+	t.Setenv("TEST_INT", "7")
+	t.Setenv("TEST_BAD_INT", "-3")
+	t.Setenv("TEST_FLOAT", "1.5")
+	t.Setenv("TEST_DURATION", "30s")
+	t.Setenv("TEST_BAD_DURATION", "soon")
+	t.Setenv("TEST_BOOL", "false")
+	t.Setenv("TEST_LIST", " a, ,b,")
+
+	if got := EnvString("TEST_UNSET", "def"); got != "def" {
+		t.Errorf("EnvString unset = %q", got)
+	}
+	if got := EnvInt("TEST_INT", 1); got != 7 {
+		t.Errorf("EnvInt = %d, want 7", got)
+	}
+	if got := EnvInt("TEST_BAD_INT", 1); got != 1 {
+		t.Errorf("EnvInt of a negative = %d, want the default", got)
+	}
+	if got := EnvFloat("TEST_FLOAT", 2); got != 1.5 {
+		t.Errorf("EnvFloat = %v, want 1.5", got)
+	}
+	if got := EnvDuration("TEST_DURATION", time.Second); got != 30*time.Second {
+		t.Errorf("EnvDuration = %s, want 30s", got)
+	}
+	if got := EnvDuration("TEST_BAD_DURATION", time.Second); got != time.Second {
+		t.Errorf("EnvDuration of %q = %s, want the default", "soon", got)
+	}
+	if got := EnvBool("TEST_BOOL", true); got {
+		t.Error("EnvBool = true, want false")
+	}
+	if got := EnvBool("TEST_UNSET", true); !got {
+		t.Error("EnvBool unset = false, want the default")
+	}
+	if got := EnvList("TEST_LIST"); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("EnvList = %q", got)
+	}
+}
+
+func TestEnv_ConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("tick_interval: 250ms\nmax_rides: 5\nbrokers: [a, b]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.Store(c)
+	t.Cleanup(func() { settings.Store(nil) })
+	t.Setenv("MAX_RIDES", "9")
+
+	if got := EnvDuration("TICK_INTERVAL", time.Second); got != 250*time.Millisecond {
+		t.Errorf("EnvDuration = %s, want the file's 250ms", got)
+	}
+	if got := EnvInt("MAX_RIDES", 1); got != 9 {
+		t.Errorf("EnvInt = %d, want the environment's 9", got)
+	}
+	if got := EnvList("BROKERS"); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("EnvList = %q, want the file's", got)
+	}
+}
@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckTimeout bounds how long each readiness check may take.
+const CheckTimeout = 2 * time.Second
+
+type check struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// Health is the liveness and readiness of a service. A service is live for as
+// long as it answers, and ready when it is not shutting down and all its
+// checks pass.
+type Health struct {
+	draining atomic.Bool
+
+	mu     sync.Mutex
+	checks []check
+}
+
+// NewHealth returns a Health with no checks, ready until Drain.
+func NewHealth() *Health { return &Health{} }
+
+// Check adds a readiness check, named name in the answers, such as a store's
+// Health. It is run on each readiness request, within CheckTimeout.
+func (h *Health) Check(name string, fn func(context.Context) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check{name: name, fn: fn})
+}
+
+// Drain makes the service unready for good, so that it is taken out of load
+// balancing while it shuts down.
+func (h *Health) Drain() { h.draining.Store(true) }
+
+// Ready runs the checks and returns whether they all passed and the service
+// is not draining, with each check's result: "ok" or its error.
+func (h *Health) Ready(ctx context.Context) (bool, map[string]string) {
+	h.mu.Lock()
+	checks := h.checks
+	h.mu.Unlock()
+
+	ready := !h.draining.Load()
+	results := make(map[string]string, len(checks))
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+		err := c.fn(checkCtx)
+		cancel()
+		if err != nil {
+			ready = false
+			results[c.name] = err.Error()
+			continue
+		}
+		results[c.name] = "ok"
+	}
+	return ready, results
+}
+
+// Handler serves the health as JSON:
+//
+//	GET /healthz  200 while the process answers
+//	GET /readyz   200 when ready, 503 with the failing checks otherwise
+func (h *Health) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+			return
+		}
+		ready, results := h.Ready(r.Context())
+		body := map[string]any{"status": "ok", "checks": results}
+		status := http.StatusOK
+		if !ready {
+			body["status"] = "unavailable"
+			if h.draining.Load() {
+				body["status"] = "draining"
+			}
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, body)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write health response", "error", err)
+	}
+}
@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func get(t *testing.T, h http.Handler, path string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s: bad body %q: %v", path, rec.Body, err)
+	}
+	return rec.Code, body
+}
+
+func TestHealth(t *testing.T) {
+	h := NewHealth()
+	var dbErr error
+	h.Check("database", func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the check to have a deadline")
+		}
+		return dbErr
+	})
+	handler := h.Handler()
+
+	if code, body := get(t, handler, "/readyz"); code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("ready: %d %v", code, body)
+	}
+
+	dbErr = errors.New("connection refused")
+	code, body := get(t, handler, "/readyz")
+	if code != http.StatusServiceUnavailable || body["status"] != "unavailable" {
+		t.Errorf("failing check: %d %v", code, body)
+	}
+	if checks, _ := body["checks"].(map[string]any); checks["database"] != "connection refused" {
+		t.Errorf("checks = %v, want the error", body["checks"])
+	}
+	// Liveness does not depend on the checks
+	if code, _ := get(t, handler, "/healthz"); code != http.StatusOK {
+		t.Errorf("healthz with a failing check = %d", code)
+	}
+
+	dbErr = nil
+	h.Drain()
+	if code, body := get(t, handler, "/readyz"); code != http.StatusServiceUnavailable || body["status"] != "draining" {
+		t.Errorf("draining: %d %v", code, body)
+	}
+	if code, _ := get(t, handler, "/healthz"); code != http.StatusOK {
+		t.Errorf("healthz while draining = %d", code)
+	}
+}
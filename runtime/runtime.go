@@ -0,0 +1,144 @@
+// Package runtime is what every service's main does before and after its own
+// work: it loads .env and sets up the logger, cancels the service's context on
+// SIGINT or SIGTERM, serves metrics, log levels, and health checks, and shuts
+// the service down in order.
+//
+//	svc := runtime.Start("janitor")
+//	defer svc.Stop()
+//	svc.Serve(":2114")
+//
+// Settings come from the environment and then from the YAML file CONFIG_FILE
+// names, if any, which is reloaded whenever it changes (see package config).
+// The log settings of the file apply as it changes; a service follows the
+// others it can change while it runs with OnChange.
+//
+// Resources are released by logger.OnShutdown hooks, which run the last
+// registered first, and the server Serve starts is closed after all of them,
+// so the service's metrics can be scraped until it is gone.
+package runtime
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/pedeveaux/kafkarideshare/config"
+	"github.com/pedeveaux/kafkarideshare/logger"
+)
+
+// Service is a running service. Its methods are safe for concurrent use.
+type Service struct {
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	health *Health
+	// settings is the config file, or the environment alone without one
+	settings *config.Config
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+// Start loads .env, sets up the logger as the component name (see logger.Init
+// and logger.SetComponent), loads the config file, and returns the Service, whose context is
+// cancelled on the first SIGINT or SIGTERM. A second signal kills the process
+// without waiting for the shutdown. Defer Stop right after calling it.
+func Start(name string) *Service {
+	// Load .env first so that it can set the log levels
+	envErr := godotenv.Load()
+	logger.Init(slog.LevelInfo, "json")
+	logger.SetComponent(name)
+	if envErr != nil {
+		slog.Debug("No .env file found, using the environment", "error", envErr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Service{name: name, ctx: ctx, cancel: cancel, health: NewHealth()}
+	// Registered first, so it runs after every hook the service registers
+	logger.OnShutdown("health server", s.closeServer)
+	s.loadSettings()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigs:
+			slog.Info("Received shutdown signal", "signal", sig.String())
+			s.health.Drain()
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+	return s
+}
+
+// Name returns the name the service was started as.
+func (s *Service) Name() string { return s.name }
+
+// Context returns the context of the service, cancelled on a shutdown signal
+// or by Stop.
+func (s *Service) Context() context.Context { return s.ctx }
+
+// Health returns the health checks Serve answers for.
+func (s *Service) Health() *Health { return s.health }
+
+// Check adds a readiness check to the service's health; see Health.Check.
+func (s *Service) Check(name string, fn func(context.Context) error) {
+	s.health.Check(name, fn)
+}
+
+// Serve serves, on METRICS_ADDR or defaultAddr when it is unset, the
+// Prometheus registry at /metrics, the log levels at /loglevel (see
+// logger.LevelHandler), and the service's health at /healthz and /readyz (see
+// Health.Handler), until the service stops.
+func (s *Service) Serve(defaultAddr string) {
+	addr := EnvString("METRICS_ADDR", defaultAddr)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/loglevel", logger.LevelHandler())
+	health := s.health.Handler()
+	mux.Handle("/healthz", health)
+	mux.Handle("/readyz", health)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	s.mu.Lock()
+	s.server = srv
+	s.mu.Unlock()
+	go func() {
+		slog.Info("Serving metrics and health", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics server stopped", "error", err)
+		}
+	}()
+}
+
+// Stop shuts the service down: it fails its readiness checks, cancels its
+// context, and runs the shutdown hooks (see logger.Shutdown), closing the
+// server of Serve last. Defer it in main, where it replaces the deferred
+// logger.Shutdown: the hooks then run whether main returns or logger.Fatal
+// ends the process, which runs them itself as it skips main's defers.
+func (s *Service) Stop() {
+	s.health.Drain()
+	s.cancel()
+	logger.Shutdown()
+}
+
+func (s *Service) closeServer(ctx context.Context) error {
+	s.mu.Lock()
+	srv := s.server
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+)
+
+// freeAddr returns a local address nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func status(url string) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestService_StopOrder(t *testing.T) {
+	addr := freeAddr(t)
+	t.Setenv("METRICS_ADDR", addr)
+	svc := Start("test")
+	svc.Serve(":0")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for status("http://"+addr+"/readyz") != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("server did not come up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A hook of the service sees it draining but still served
+	var cancelled bool
+	var readyz, metrics int
+	logger.OnShutdown("store", func(context.Context) error {
+		cancelled = svc.Context().Err() != nil
+		readyz = status("http://" + addr + "/readyz")
+		metrics = status("http://" + addr + "/metrics")
+		return nil
+	})
+	svc.Stop()
+
+	if !cancelled {
+		t.Error("context not cancelled before the hooks ran")
+	}
+	if readyz != http.StatusServiceUnavailable {
+		t.Errorf("readyz during shutdown = %d, want 503", readyz)
+	}
+	if metrics != http.StatusOK {
+		t.Errorf("metrics during shutdown = %d, want 200", metrics)
+	}
+	if code := status("http://" + addr + "/healthz"); code != 0 {
+		t.Errorf("server still answering after Stop: %d", code)
+	}
+}
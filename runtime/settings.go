@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/pedeveaux/kafkarideshare/config"
+	"github.com/pedeveaux/kafkarideshare/logger"
+)
+
+// settings is the config file of the service Start loaded, which the Env
+// helpers look settings up in when the environment does not set them.
+var settings atomic.Pointer[config.Config]
+
+// logSettings are the settings the logger reads from the environment, and
+// Start from the config file as well.
+var logSettings = []string{"LOG_LEVEL", "LOG_LEVELS", "LOG_SAMPLE", "LOG_RATE_LIMIT"}
+
+// lookup returns the setting key, from the environment or the config file.
+func lookup(key string) string {
+	if c := settings.Load(); c != nil {
+		return c.Get(key)
+	}
+	return os.Getenv(key)
+}
+
+// loadSettings loads the config file named by CONFIG_FILE, if any, applies its
+// log settings, and reloads it on every change until the service stops.
+func (s *Service) loadSettings() {
+	c, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		logger.Fatal("Failed to load the config file", "error", err)
+	}
+	settings.Store(c)
+	s.settings = c
+	if c.Path() == "" {
+		return
+	}
+	applyLogSettings(c)
+	c.OnChange(func() { applyLogSettings(c) }, logSettings...)
+	slog.Info("Loaded config file", "path", c.Path())
+	go func() {
+		if err := c.Watch(s.ctx); err != nil {
+			slog.Warn("Failed to watch the config file, it will not be reloaded", "path", c.Path(), "error", err)
+		}
+	}()
+}
+
+// OnChange calls fn whenever the config file is reloaded with a new value for
+// any of keys; see config.Config.OnChange. Without a config file it never
+// does.
+func (s *Service) OnChange(fn func(), keys ...string) {
+	s.settings.OnChange(fn, keys...)
+}
+
+// applyLogSettings sets the log levels, sample rates, and rate limit of c, as
+// the logger does from the environment. A setting removed from the file keeps
+// the value it set.
+func applyLogSettings(c *config.Config) {
+	if raw := c.Get("LOG_LEVEL"); raw != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(raw)); err != nil {
+			slog.Warn("Ignoring invalid LOG_LEVEL", "value", raw, "error", err)
+		} else {
+			logger.SetLevel("", l)
+		}
+	}
+	if raw, ok := c.Lookup("LOG_LEVELS"); ok {
+		if err := logger.SetLevels(raw); err != nil {
+			slog.Warn("Ignoring invalid LOG_LEVELS", "value", raw, "error", err)
+		}
+	}
+	if raw, ok := c.Lookup("LOG_SAMPLE"); ok {
+		if err := logger.SetSampleRates(raw); err != nil {
+			slog.Warn("Ignoring invalid LOG_SAMPLE", "value", raw, "error", err)
+		}
+	}
+	if raw := c.Get("LOG_RATE_LIMIT"); raw != "" {
+		if n, err := strconv.Atoi(raw); err != nil || n < 0 {
+			slog.Warn("Ignoring invalid LOG_RATE_LIMIT", "value", raw)
+		} else {
+			logger.SetRateLimit(n)
+		}
+	}
+}
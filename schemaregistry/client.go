@@ -0,0 +1,234 @@
+// Package schemaregistry wraps confluent-kafka-go's Schema Registry client,
+// as served by Redpanda and Confluent. It enforces a subject's compatibility
+// level before registering a new version, so that a producer built from an
+// incompatible schema change fails at startup rather than writing events its
+// consumers cannot read, and tells failures worth retrying from lasting ones;
+// see Error.Temporary.
+package schemaregistry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/schemaregistry"
+	"github.com/confluentinc/confluent-kafka-go/schemaregistry/serde"
+)
+
+// DefaultTimeout bounds each request to the registry.
+const DefaultTimeout = 10 * time.Second
+
+// ParseCompatibility reads a compatibility level, ignoring case.
+func ParseCompatibility(s string) (schemaregistry.Compatibility, error) {
+	var level schemaregistry.Compatibility
+	if s == "" || level.ParseString(strings.ToUpper(s)) != nil {
+		return 0, fmt.Errorf("schemaregistry: unknown compatibility level %q", s)
+	}
+	return level, nil
+}
+
+// Subject returns the subject of a topic's values under the registry's
+// default naming, the topic name strategy.
+func Subject(topic string) string {
+	subject, _ := serde.TopicNameStrategy(topic, serde.ValueSerde, schemaregistry.SchemaInfo{})
+	return subject
+}
+
+var (
+	// ErrNotFound is matched by errors for subjects, versions, and schemas
+	// the registry does not have.
+	ErrNotFound = errors.New("schemaregistry: not found")
+	// ErrIncompatible is matched by errors for schemas that break their
+	// subject's compatibility level.
+	ErrIncompatible = errors.New("schemaregistry: incompatible schema")
+)
+
+// Error is a failed request to the registry: an error answer, which
+// confluent-kafka-go returns as a *schemaregistry.RestError, or a request that
+// got none.
+type Error struct {
+	Err error
+}
+
+// wrap returns err, if any, as an *Error.
+func wrap(err error) error {
+	var rerr *Error
+	if err == nil || errors.As(err, &rerr) {
+		return err
+	}
+	return &Error{Err: err}
+}
+
+func (e *Error) Error() string { return "schemaregistry: " + e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// status returns the HTTP status of an error answer, which the registry's
+// error codes start with, such as 404 for 40401, or 0 for other errors.
+func (e *Error) status() int {
+	var rest *schemaregistry.RestError
+	if !errors.As(e.Err, &rest) {
+		return 0
+	}
+	code := rest.Code
+	for code >= 1000 {
+		code /= 10
+	}
+	return code
+}
+
+// Is matches ErrNotFound for 404 answers and ErrIncompatible for 409.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.status() == 404
+	case ErrIncompatible:
+		return e.status() == 409
+	}
+	return false
+}
+
+// Temporary reports whether the request may succeed if tried again: the
+// registry could not be reached, was overloaded, or failed. An answer that is
+// not JSON, as a proxy in front of a registry that is down gives, counts as
+// temporary too.
+func (e *Error) Temporary() bool {
+	if status := e.status(); status != 0 {
+		return status == 429 || status >= 500
+	}
+	var urlErr *url.Error
+	var syntaxErr *json.SyntaxError
+	return errors.As(e.Err, &urlErr) || errors.As(e.Err, &syntaxErr)
+}
+
+// IncompatibleError says a schema breaks its subject's compatibility level.
+type IncompatibleError struct {
+	Subject string
+	Level   schemaregistry.Compatibility
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("schemaregistry: schema is not %s compatible with %s", e.Level, e.Subject)
+}
+
+func (e *IncompatibleError) Is(target error) bool { return target == ErrIncompatible }
+
+// Config says which registry a Client talks to.
+type Config struct {
+	URL      string
+	Username string
+	Password string
+	// Compatibility is the level RegisterCompatible sets on subjects before
+	// checking new versions against it; zero leaves the registry's level.
+	Compatibility schemaregistry.Compatibility
+}
+
+// ConfigFromEnv reads SCHEMA_REGISTRY_URL, SCHEMA_REGISTRY_USERNAME and
+// SCHEMA_REGISTRY_PASSWORD, and SCHEMA_REGISTRY_COMPATIBILITY. The URL is
+// empty when the registry is not used.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		URL:      os.Getenv("SCHEMA_REGISTRY_URL"),
+		Username: os.Getenv("SCHEMA_REGISTRY_USERNAME"),
+		Password: os.Getenv("SCHEMA_REGISTRY_PASSWORD"),
+	}
+	if raw := os.Getenv("SCHEMA_REGISTRY_COMPATIBILITY"); raw != "" {
+		level, err := ParseCompatibility(raw)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Compatibility = level
+	}
+	return cfg, nil
+}
+
+// Client is confluent-kafka-go's Schema Registry client, which caches
+// schemas by ID and the IDs of registered schemas, with compatibility checks
+// on top. It is safe for concurrent use.
+type Client struct {
+	schemaregistry.Client
+	compatibility schemaregistry.Compatibility
+}
+
+// New returns a client of the registry cfg names.
+func New(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("schemaregistry: no registry URL")
+	}
+	conf := schemaregistry.NewConfig(cfg.URL)
+	if cfg.Username != "" {
+		conf = schemaregistry.NewConfigWithAuthentication(cfg.URL, cfg.Username, cfg.Password)
+	}
+	conf.RequestTimeoutMs = int(DefaultTimeout / time.Millisecond)
+	client, err := schemaregistry.NewClient(conf)
+	if err != nil {
+		return nil, fmt.Errorf("schemaregistry: %w", err)
+	}
+	return &Client{Client: client, compatibility: cfg.Compatibility}, nil
+}
+
+// SubjectCompatibility returns subject's compatibility level, or the
+// registry's when the subject has none of its own.
+func (c *Client) SubjectCompatibility(subject string) (schemaregistry.Compatibility, error) {
+	level, err := c.GetCompatibility(subject)
+	if errors.Is(wrap(err), ErrNotFound) {
+		level, err = c.GetDefaultCompatibility()
+	}
+	return level, wrap(err)
+}
+
+// CheckCompatibility reports whether schema could be registered as the next
+// version of subject under its compatibility level. A subject with no
+// versions accepts any schema.
+func (c *Client) CheckCompatibility(subject string, schema schemaregistry.SchemaInfo) (bool, error) {
+	latest, err := c.GetLatestSchemaMetadata(subject)
+	if errors.Is(wrap(err), ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, wrap(err)
+	}
+	ok, err := c.TestCompatibility(subject, latest.Version, schema)
+	return ok, wrap(err)
+}
+
+// RegisterCompatible registers schema under subject and returns its ID,
+// enforcing the subject's compatibility level. A schema already registered
+// under subject returns its ID as it is. Otherwise the subject is first set
+// to the Config's Compatibility, if given, and a schema that breaks the level
+// is refused with an *IncompatibleError.
+func (c *Client) RegisterCompatible(subject string, schema schemaregistry.SchemaInfo) (int, error) {
+	// An earlier version is returned as it is even if it breaks the level
+	// against the latest, as the registry does, so a rollback can start
+	id, err := c.GetID(subject, schema, false)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(wrap(err), ErrNotFound) {
+		return 0, wrap(err)
+	}
+
+	level, err := c.SubjectCompatibility(subject)
+	if err != nil {
+		return 0, err
+	}
+	if c.compatibility != 0 && c.compatibility != level {
+		if _, err := c.UpdateCompatibility(subject, c.compatibility); err != nil {
+			return 0, wrap(err)
+		}
+		level = c.compatibility
+	}
+	ok, err := c.CheckCompatibility(subject, schema)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, &IncompatibleError{Subject: subject, Level: level}
+	}
+	id, err = c.Register(subject, schema, false)
+	return id, wrap(err)
+}
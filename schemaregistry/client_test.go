@@ -0,0 +1,272 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/schemaregistry"
+)
+
+// storedSchema is a schema as the fake registry holds it.
+type storedSchema struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+// fakeRegistry serves the part of the Schema Registry API the client uses.
+// A schema is incompatible with its subject when it contains "breaking".
+type fakeRegistry struct {
+	mu       sync.Mutex
+	url      string
+	schemas  []storedSchema // by ID less one
+	subjects map[string][]int
+	levels   map[string]string
+	requests []string
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *Client) {
+	t.Helper()
+	f := &fakeRegistry{subjects: map[string][]int{}, levels: map[string]string{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	f.url = srv.URL + "/"
+	return f, f.client(t, Config{})
+}
+
+// client returns a new client of f, as another process would have.
+func (f *fakeRegistry) client(t *testing.T, cfg Config) *Client {
+	t.Helper()
+	cfg.URL = f.url
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return c
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	raw, _ := io.ReadAll(r.Body)
+	var body storedSchema
+	json.Unmarshal(raw, &body)
+	fail := func(status, code int) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"error_code": code, "message": "failed"})
+	}
+	reply := func(v any) { json.NewEncoder(w).Encode(v) }
+	version := func(subject string, v int) map[string]any {
+		s := f.schemas[f.subjects[subject][v-1]-1]
+		return map[string]any{"id": f.subjects[subject][v-1], "subject": subject, "version": v,
+			"schema": s.Schema, "schemaType": s.SchemaType}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "subjects":
+		names := []string{}
+		for s := range f.subjects {
+			names = append(names, s)
+		}
+		reply(names)
+	case parts[0] == "subjects" && len(parts) == 2 && r.Method == http.MethodPost:
+		for i, id := range f.subjects[parts[1]] {
+			if f.schemas[id-1] == body {
+				reply(version(parts[1], i+1))
+				return
+			}
+		}
+		fail(http.StatusNotFound, 40403)
+	case parts[0] == "subjects" && len(parts) == 3 && r.Method == http.MethodGet:
+		versions, ok := f.subjects[parts[1]]
+		if !ok {
+			fail(http.StatusNotFound, 40401)
+			return
+		}
+		out := []int{}
+		for i := range versions {
+			out = append(out, i+1)
+		}
+		reply(out)
+	case parts[0] == "subjects" && len(parts) == 4 && r.Method == http.MethodGet:
+		versions := f.subjects[parts[1]]
+		v := len(versions)
+		if parts[3] != "latest" {
+			v, _ = strconv.Atoi(parts[3])
+		}
+		if v < 1 || v > len(versions) {
+			fail(http.StatusNotFound, 40401)
+			return
+		}
+		reply(version(parts[1], v))
+	case parts[0] == "subjects" && len(parts) == 3 && r.Method == http.MethodPost:
+		if strings.Contains(body.Schema, "breaking") && len(f.subjects[parts[1]]) > 0 {
+			fail(http.StatusConflict, 409)
+			return
+		}
+		f.schemas = append(f.schemas, body)
+		f.subjects[parts[1]] = append(f.subjects[parts[1]], len(f.schemas))
+		reply(map[string]int{"id": len(f.schemas)})
+	case parts[0] == "schemas" && len(parts) == 3:
+		id, _ := strconv.Atoi(parts[2])
+		if id < 1 || id > len(f.schemas) {
+			fail(http.StatusNotFound, 40403)
+			return
+		}
+		reply(f.schemas[id-1])
+	case parts[0] == "compatibility":
+		reply(map[string]any{"is_compatible": !strings.Contains(body.Schema, "breaking")})
+	case parts[0] == "config" && len(parts) == 1 && r.Method == http.MethodGet:
+		reply(map[string]string{"compatibilityLevel": "BACKWARD"})
+	case parts[0] == "config" && r.Method == http.MethodGet:
+		level, ok := f.levels[parts[1]]
+		if !ok {
+			fail(http.StatusNotFound, 40408)
+			return
+		}
+		reply(map[string]string{"compatibilityLevel": level})
+	case parts[0] == "config" && r.Method == http.MethodPut:
+		var req map[string]string
+		json.Unmarshal(raw, &req)
+		f.levels[parts[1]] = req["compatibility"]
+		reply(req)
+	default:
+		fail(http.StatusInternalServerError, 50001)
+	}
+}
+
+func (f *fakeRegistry) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+func TestRegisterCompatible(t *testing.T) {
+	f, c := newFakeRegistry(t)
+
+	v1 := schemaregistry.SchemaInfo{Schema: `{"type":"string"}`}
+	id, err := c.RegisterCompatible("rides-value", v1)
+	if err != nil || id != 1 {
+		t.Fatalf("RegisterCompatible = %d, %v; want 1", id, err)
+	}
+	v2 := schemaregistry.SchemaInfo{Schema: `{"type":"long"}`}
+	if id, err := c.RegisterCompatible("rides-value", v2); err != nil || id != 2 {
+		t.Fatalf("RegisterCompatible of a second version = %d, %v; want 2", id, err)
+	}
+
+	// Registered schemas are remembered, and earlier versions are returned
+	// as they are
+	before := f.count()
+	if id, err := c.RegisterCompatible("rides-value", v2); err != nil || id != 2 || f.count() != before {
+		t.Errorf("RegisterCompatible again = %d, %v after %d requests; want 2 from the cache", id, err, f.count()-before)
+	}
+	if id, err := f.client(t, Config{}).RegisterCompatible("rides-value", v1); err != nil || id != 1 {
+		t.Errorf("RegisterCompatible of the first version = %d, %v; want 1", id, err)
+	}
+
+	versions, err := c.GetAllVersions("rides-value")
+	if err != nil || !reflect.DeepEqual(versions, []int{1, 2}) {
+		t.Errorf("GetAllVersions = %v, %v", versions, err)
+	}
+	latest, err := c.GetLatestSchemaMetadata("rides-value")
+	if err != nil || latest.Version != 2 || latest.ID != 2 || latest.Schema != v2.Schema {
+		t.Errorf("latest version = %+v, %v", latest, err)
+	}
+}
+
+func TestRegisterCompatible_Incompatible(t *testing.T) {
+	f, c := newFakeRegistry(t)
+	if _, err := c.RegisterCompatible("rides-value", schemaregistry.SchemaInfo{Schema: `{"type":"string"}`}); err != nil {
+		t.Fatalf("RegisterCompatible failed: %v", err)
+	}
+
+	_, err := c.RegisterCompatible("rides-value", schemaregistry.SchemaInfo{Schema: `{"type":"breaking"}`})
+	var incompatible *IncompatibleError
+	if !errors.As(err, &incompatible) || !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("RegisterCompatible of a breaking schema = %v, want an IncompatibleError", err)
+	}
+	if incompatible.Level != schemaregistry.Backward {
+		t.Errorf("error = %+v, want the registry's BACKWARD level", incompatible)
+	}
+	if n := len(f.subjects["rides-value"]); n != 1 {
+		t.Errorf("subject has %d versions, want the breaking one refused", n)
+	}
+}
+
+func TestRegisterCompatible_SetsCompatibility(t *testing.T) {
+	f, _ := newFakeRegistry(t)
+	c := f.client(t, Config{Compatibility: schemaregistry.Full})
+	if _, err := c.RegisterCompatible("rides-value", schemaregistry.SchemaInfo{Schema: `{"type":"string"}`}); err != nil {
+		t.Fatalf("RegisterCompatible failed: %v", err)
+	}
+	if f.levels["rides-value"] != "FULL" {
+		t.Errorf("subject level = %q, want FULL", f.levels["rides-value"])
+	}
+	if level, err := c.SubjectCompatibility("rides-value"); err != nil || level != schemaregistry.Full {
+		t.Errorf("SubjectCompatibility = %v, %v; want FULL", level, err)
+	}
+}
+
+func TestError(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	c, err := New(Config{URL: unreachable.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, unreachableErr := c.GetBySubjectAndID("", 1)
+
+	tests := []struct {
+		name      string
+		err       error
+		notFound  bool
+		temporary bool
+	}{
+		{"unknown schema", &schemaregistry.RestError{Code: 40403}, true, false},
+		{"plain not found", &schemaregistry.RestError{Code: 404}, true, false},
+		{"invalid schema", &schemaregistry.RestError{Code: 42201}, false, false},
+		{"backend error", &schemaregistry.RestError{Code: 50001}, false, true},
+		{"rate limited", &schemaregistry.RestError{Code: 429}, false, true},
+		{"unreachable", unreachableErr, false, true},
+		{"not JSON", &json.SyntaxError{}, false, true},
+		{"bad URL", errors.New("parse failed"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrap(tt.err)
+			var rerr *Error
+			if !errors.As(err, &rerr) {
+				t.Fatalf("wrap = %v, want an *Error", err)
+			}
+			if got := errors.Is(err, ErrNotFound); got != tt.notFound {
+				t.Errorf("errors.Is(ErrNotFound) = %v, want %v", got, tt.notFound)
+			}
+			if got := rerr.Temporary(); got != tt.temporary {
+				t.Errorf("Temporary = %v, want %v", got, tt.temporary)
+			}
+		})
+	}
+	var urlErr *url.Error
+	if !errors.As(unreachableErr, &urlErr) {
+		t.Errorf("unreachable registry error = %T, want a *url.Error", unreachableErr)
+	}
+}
+
+func TestParseCompatibility(t *testing.T) {
+	if c, err := ParseCompatibility("backward_transitive"); err != nil || c != schemaregistry.BackwardTransitive {
+		t.Errorf("ParseCompatibility = %v, %v", c, err)
+	}
+	if _, err := ParseCompatibility("sideways"); err == nil {
+		t.Error("expected an unknown level to fail")
+	}
+}
@@ -0,0 +1,139 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/schemaregistry"
+	"github.com/confluentinc/confluent-kafka-go/schemaregistry/serde"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+)
+
+// headerSize is the size of the wire format's header: a zero byte, then the
+// ID of the schema the value was written with.
+const headerSize = 5
+
+// ErrNotFramed is returned for values that do not start with the wire
+// format's header, such as plain JSON events.
+var ErrNotFramed = errors.New("schemaregistry: value is not framed with a schema ID")
+
+// IsFramed reports whether value starts with the wire format's header. JSON
+// values never do, as they cannot start with a zero byte.
+func IsFramed(value []byte) bool {
+	return len(value) >= headerSize && value[0] == 0
+}
+
+// RideEventSchema is events.RideEventAvroSchema as registered. Avro is the
+// registry's default type and is left out, as older registries expect.
+var RideEventSchema = schemaregistry.SchemaInfo{Schema: string(events.RideEventAvroSchema)}
+
+// RideEventSerializer writes ride events as Avro framed with the ID their
+// schema is registered with.
+type RideEventSerializer struct {
+	base serde.BaseSerializer
+	id   int
+}
+
+// NewRideEventSerializer registers RideEventSchema as the value schema of
+// topic, enforcing the subject's compatibility level, and returns a
+// serializer writing with it. It fails with an error matching
+// ErrIncompatible if the schema breaks the level.
+func NewRideEventSerializer(c *Client, topic string) (*RideEventSerializer, error) {
+	id, err := c.RegisterCompatible(Subject(topic), RideEventSchema)
+	if err != nil {
+		return nil, err
+	}
+	s := &RideEventSerializer{id: id}
+	if err := s.base.ConfigureSerializer(c.Client, serde.ValueSerde, serde.NewSerializerConfig()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ID returns the ID of the schema events are written with.
+func (s *RideEventSerializer) ID() int {
+	return s.id
+}
+
+// Marshal encodes e in the registry's wire format.
+func (s *RideEventSerializer) Marshal(e events.RideEvent) ([]byte, error) {
+	data, err := events.MarshalAvro(e)
+	if err != nil {
+		return nil, err
+	}
+	return s.base.WriteBytes(s.id, data)
+}
+
+// RideEventDeserializer reads the ride events of a topic framed with the ID
+// of the Avro schema they were written with, whatever its version, by
+// fetching the schema from the registry the first time it is seen.
+type RideEventDeserializer struct {
+	base  serde.BaseDeserializer
+	topic string
+
+	mu sync.Mutex
+	// readers are by schema text, as the client caches schemas by ID.
+	readers map[string]*events.AvroReader
+}
+
+// NewRideEventDeserializer returns a deserializer of topic's values,
+// fetching schemas from c.
+func NewRideEventDeserializer(c *Client, topic string) *RideEventDeserializer {
+	d := &RideEventDeserializer{topic: topic, readers: map[string]*events.AvroReader{}}
+	// Only fails without a client
+	_ = d.base.ConfigureDeserializer(c.Client, serde.ValueSerde, serde.NewDeserializerConfig())
+	return d
+}
+
+// Unmarshal decodes a framed ride event. Errors fetching its schema are
+// *Error; see Error.Temporary. The client's requests cannot be cancelled, so
+// ctx is not used.
+func (d *RideEventDeserializer) Unmarshal(_ context.Context, value []byte) (events.RideEvent, error) {
+	if !IsFramed(value) {
+		return events.RideEvent{}, ErrNotFramed
+	}
+	info, err := d.base.GetSchema(d.topic, value)
+	if err != nil {
+		return events.RideEvent{}, wrap(err)
+	}
+	r, err := d.reader(info)
+	if err != nil {
+		return events.RideEvent{}, err
+	}
+	return r.Unmarshal(value[headerSize:])
+}
+
+// ToJSON returns the JSON encoding of a framed ride event, and returns
+// values that are not framed, such as JSON events, as they are. It suits
+// rideconsumer.Config.Unframe, letting a consumer read both encodings.
+func (d *RideEventDeserializer) ToJSON(ctx context.Context, value []byte) ([]byte, error) {
+	if !IsFramed(value) {
+		return value, nil
+	}
+	e, err := d.Unmarshal(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+func (d *RideEventDeserializer) reader(info schemaregistry.SchemaInfo) (*events.AvroReader, error) {
+	if info.SchemaType != "" && info.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("schemaregistry: schema is %s, not a ride event Avro schema", info.SchemaType)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if r, ok := d.readers[info.Schema]; ok {
+		return r, nil
+	}
+	r, err := events.NewAvroReader([]byte(info.Schema))
+	if err != nil {
+		return nil, fmt.Errorf("schemaregistry: %w", err)
+	}
+	d.readers[info.Schema] = r
+	return r, nil
+}
@@ -0,0 +1,64 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/events/eventstest"
+)
+
+func TestRideEventSerde(t *testing.T) {
+	f, c := newFakeRegistry(t)
+	ctx := context.Background()
+
+	s, err := NewRideEventSerializer(c, "ride-events")
+	if err != nil {
+		t.Fatalf("NewRideEventSerializer failed: %v", err)
+	}
+	if ids := f.subjects["ride-events-value"]; len(ids) != 1 || ids[0] != s.ID() {
+		t.Errorf("ride-events-value has %v, want the serializer's schema %d", ids, s.ID())
+	}
+
+	want := eventstest.Canonical()[0]
+	want.SchemaVersion = events.SchemaVersion
+	value, err := s.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !IsFramed(value) || !bytes.Equal(value[:headerSize], []byte{0, 0, 0, 0, byte(s.ID())}) {
+		t.Errorf("header = %v, want a zero byte and schema ID %d", value[:headerSize], s.ID())
+	}
+	// A new client, as in another process, fetches the schema by its ID
+	d := NewRideEventDeserializer(f.client(t, Config{}), "ride-events")
+	got, err := d.Unmarshal(ctx, value)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+
+	data, err := d.ToJSON(ctx, value)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	var decoded events.RideEvent
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID != want.ID {
+		t.Errorf("ToJSON = %s, %v", data, err)
+	}
+	plain, _ := json.Marshal(want)
+	if data, err := d.ToJSON(ctx, plain); err != nil || !bytes.Equal(data, plain) {
+		t.Errorf("ToJSON of a JSON event = %s, %v; want it as it is", data, err)
+	}
+	unknown := append([]byte{0, 0, 0, 0, 99}, value[headerSize:]...)
+	_, err = d.ToJSON(ctx, unknown)
+	var rerr *Error
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &rerr) || rerr.Temporary() {
+		t.Errorf("ToJSON of an event framed with an unknown schema = %v, want a lasting ErrNotFound", err)
+	}
+}
@@ -0,0 +1,90 @@
+// Package api is the read API service, which serves the rides database over
+// REST, GraphQL, and gRPC; see the httpapi, graphqlapi, and grpcapi packages.
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/pedeveaux/kafkarideshare/graphqlapi"
+	"github.com/pedeveaux/kafkarideshare/grpcapi"
+	"github.com/pedeveaux/kafkarideshare/httpapi"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+)
+
+// Run runs the read API with args, the command line after the command's name, until
+// it is stopped by a signal.
+func Run(args []string) {
+	svc := runtime.Start("api")
+	defer svc.Stop()
+	slog.Info("Starting ride read API...")
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+	ctx := svc.Context()
+
+	svc.Serve(":2115")
+
+	addr := runtime.EnvString("API_ADDR", ":8080")
+	mux := http.NewServeMux()
+	mux.Handle("POST /graphql", graphqlapi.NewHandler(store))
+	mux.Handle("/", httpapi.NewHandler(store))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	grpcAddr := runtime.EnvString("GRPC_ADDR", ":9090")
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		logger.Fatal("Failed to listen for gRPC", "addr", grpcAddr, "error", err)
+	}
+	grpcSrv := grpc.NewServer()
+	grpcapi.NewServer(store, grpcapi.Config{}).Register(grpcSrv)
+	go func() {
+		slog.Info("Serving gRPC query API", "addr", grpcAddr)
+		if err := grpcSrv.Serve(lis); err != nil {
+			slog.Error("gRPC server stopped", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("API shutdown failed", "error", err)
+		}
+		// WatchTrip streams on unfinished trips never end by themselves, so
+		// they are cut off once the shutdown timeout expires
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcSrv.Stop()
+		}
+	}()
+
+	slog.Info("Serving read API", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal("Read API stopped", "error", err)
+	}
+	slog.Info("Read API stopped")
+}
@@ -0,0 +1,96 @@
+package consumer
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+// auditBatcher accumulates persisted messages per topic partition into
+// batch_insert audit entries, so the audit log gets one row per batch rather
+// than one per event.
+type auditBatcher struct {
+	store      rides_db.RideStore
+	actor      string
+	maxOffsets int64
+
+	mu      sync.Mutex
+	batches map[auditPartition]*rides_db.AuditEntry
+}
+
+type auditPartition struct {
+	topic     string
+	partition int32
+}
+
+func newAuditBatcher(store rides_db.RideStore, actor string, maxOffsets int64) *auditBatcher {
+	return &auditBatcher{
+		store:      store,
+		actor:      actor,
+		maxOffsets: maxOffsets,
+		batches:    make(map[auditPartition]*rides_db.AuditEntry),
+	}
+}
+
+// Record adds a persisted message to its partition's batch. The offset range
+// covers every message persisted; inserted says whether it added a row rather
+// than being skipped as a duplicate or conflict. A batch whose range reaches
+// maxOffsets is appended to the audit log straight away.
+func (a *auditBatcher) Record(ctx context.Context, msg *rideconsumer.Message, inserted bool, took time.Duration) {
+	key := auditPartition{topic: msg.Topic, partition: msg.Partition}
+
+	a.mu.Lock()
+	b, ok := a.batches[key]
+	if !ok {
+		b = &rides_db.AuditEntry{
+			Kind:        rides_db.AuditBatchInsert,
+			Actor:       a.actor,
+			RecordedAt:  time.Now(),
+			Topic:       msg.Topic,
+			Partition:   msg.Partition,
+			FirstOffset: msg.Offset,
+			LastOffset:  msg.Offset,
+		}
+		a.batches[key] = b
+	}
+	b.FirstOffset = min(b.FirstOffset, msg.Offset)
+	b.LastOffset = max(b.LastOffset, msg.Offset)
+	if inserted {
+		b.Rows++
+	}
+	b.Duration += took
+	full := b.LastOffset-b.FirstOffset+1 >= a.maxOffsets
+	if full {
+		delete(a.batches, key)
+	}
+	a.mu.Unlock()
+
+	if full {
+		a.append(ctx, *b)
+	}
+}
+
+// Flush appends every open batch to the audit log.
+func (a *auditBatcher) Flush(ctx context.Context) {
+	a.mu.Lock()
+	batches := a.batches
+	a.batches = make(map[auditPartition]*rides_db.AuditEntry)
+	a.mu.Unlock()
+
+	for _, b := range batches {
+		a.append(ctx, *b)
+	}
+}
+
+// append writes one entry; the events are already stored, so a failure is
+// logged rather than retried.
+func (a *auditBatcher) append(ctx context.Context, e rides_db.AuditEntry) {
+	if err := a.store.AppendAudit(ctx, e); err != nil {
+		slog.Error("Failed to append audit entry", "topic", e.Topic, "partition", e.Partition,
+			"first_offset", e.FirstOffset, "last_offset", e.LastOffset, "error", err)
+	}
+}
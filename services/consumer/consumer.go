@@ -0,0 +1,243 @@
+// Package consumer is the consumer service, which stores the events of
+// ride-events in the database, with the rides, trips, and window aggregates
+// derived from them.
+package consumer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/schemaregistry"
+)
+
+const (
+	defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID        = "ride-consumer-group"
+	topic          = "ride-events"
+	dlqTopic       = "ride-events-dlq"
+)
+
+// Trips that see no events for tripIdleTimeout are assumed to have lost their
+// terminal event and are dropped from the assembler.
+const tripIdleTimeout = time.Hour
+
+// partitionMaintenanceInterval is how often upcoming ride_events partitions are
+// created and expired ones dropped.
+const partitionMaintenanceInterval = time.Hour
+
+// Persisted messages are written to the audit log as one batch_insert entry per
+// partition every auditFlushInterval, or sooner once a batch spans auditBatchOffsets.
+const (
+	auditFlushInterval = time.Minute
+	auditBatchOffsets  = 10000
+)
+
+// unframeFromEnv returns the rideconsumer.Config.Unframe reading the Avro
+// events of a producer with EVENT_ENCODING=avro through the Schema Registry
+// at SCHEMA_REGISTRY_URL, alongside JSON events. Without a registry it
+// returns nil, and every event is read as JSON.
+func unframeFromEnv() (func(context.Context, []byte) ([]byte, error), error) {
+	cfg, err := schemaregistry.ConfigFromEnv()
+	if err != nil || cfg.URL == "" {
+		return nil, err
+	}
+	client, err := schemaregistry.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return schemaregistry.NewRideEventDeserializer(client, topic).ToJSON, nil
+}
+
+// Run runs the consumer with args, the command line after the command's name,
+// until it is stopped by a signal. `migrate` applies the database migrations
+// and returns, and `replay` replays the topic into a schema of its own (see
+// runReplay).
+func Run(args []string) {
+	svc := runtime.Start("consumer")
+	defer svc.Stop()
+	slog.Info("Starting ride consumer service...")
+	ctx := svc.Context()
+
+	// `consumer replay` replays the topic into a schema of its own and exits
+	if len(args) > 0 && args[0] == "replay" {
+		if err := runReplay(ctx, args[1:]); err != nil {
+			logger.Fatal("Replay failed", "error", err)
+		}
+		return
+	}
+
+	// Initialize the database connection; DB_DRIVER selects Postgres, MySQL, or SQLite
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+
+	// Bring the schema up to date; `consumer migrate` stops after this step
+	if err := store.Migrate(context.Background()); err != nil {
+		logger.Fatal("Failed to apply database migrations", "error", err)
+	}
+	if len(args) > 0 && args[0] == "migrate" {
+		slog.Info("Migrations applied")
+		return
+	}
+
+	// DB_SCHEMA_PER_CITY gives each city its own schema, created on first use
+	cities, err := rides_db.CityStoresFromEnv(store)
+	if err != nil {
+		logger.Fatal("Failed to set up per-city schemas", "error", err)
+	}
+	if cities != nil {
+		logger.OnShutdown("city databases", func(context.Context) error { return cities.Close() })
+	}
+
+	// Partitioning, TimescaleDB and PostGIS only apply to Postgres
+	var storage rides_db.EventStorage
+	pgStore, isPostgres := store.(*rides_db.Store)
+	if isPostgres {
+		// Lay out ride_events as native partitions or a TimescaleDB hypertable
+		storageMode, timescaleConfig := rides_db.EventStorageFromEnv()
+		storage, err = pgStore.ConfigureEventStorage(context.Background(), storageMode, timescaleConfig)
+		if err != nil {
+			logger.Fatal("Failed to configure ride_events storage", "mode", storageMode, "error", err)
+		}
+		slog.Info("Configured ride_events storage", "storage", storage)
+
+		// Geospatial columns need the postgis extension, so they are opt-in
+		if runtime.EnvBool("POSTGIS_ENABLED", false) {
+			if err := pgStore.EnablePostGIS(context.Background()); err != nil {
+				logger.Fatal("Failed to enable PostGIS", "error", err)
+			}
+		}
+	}
+
+	// Expose per-event-type metrics for dashboards and SLO alerting
+	svc.Serve(":2112")
+
+	handlers := &eventHandlers{
+		store:   store,
+		windows: newCityWindows(aggregation.DefaultConfig),
+		trips:   aggregation.NewTripAssembler(),
+		audit:   newAuditBatcher(store, groupID, auditBatchOffsets),
+	}
+	if cities != nil {
+		handlers.forCity = func(ctx context.Context, city string) (rides_db.RideStore, error) {
+			return cities.ForCity(ctx, city)
+		}
+	}
+
+	handlers.register(rideconsumer.RegisterHandler)
+
+	// Persist partially filled windows and audit batches on the way out
+	logger.OnShutdown("windows and audit batches", func(ctx context.Context) error {
+		handlers.flush(ctx)
+		return nil
+	})
+
+	// Close out audit batches regularly so the log stays current on a quiet topic
+	logger.Go("audit flush", func() {
+		ticker := time.NewTicker(auditFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				handlers.audit.Flush(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+
+	// Keep dated ride_events partitions ahead of the clock and prune expired ones;
+	// TimescaleDB manages its own chunks and retention
+	if storage == rides_db.StoragePartitioned {
+		partitionPolicy := rides_db.PartitionPolicyFromEnv()
+		maintainPartitions := func() {
+			report, err := pgStore.MaintainPartitions(ctx, partitionPolicy, time.Now())
+			// A report comes with the error of a partial run
+			if err != nil {
+				slog.Error("Partition maintenance failed", "error", err)
+			}
+			if len(report.Created) > 0 || len(report.Dropped) > 0 {
+				slog.Info("Maintained ride_events partitions", "created", report.Created, "dropped", report.Dropped)
+			}
+			if cities == nil {
+				return
+			}
+			// City schemas are partitioned by their migrations, so they need the same upkeep
+			for _, city := range cities.Cities() {
+				cityStore, err := cities.ForCity(ctx, city)
+				if err != nil {
+					slog.Error("Partition maintenance failed", "city", city, "error", err)
+					continue
+				}
+				if _, err := cityStore.MaintainPartitions(ctx, partitionPolicy, time.Now()); err != nil {
+					slog.Error("Partition maintenance failed", "city", city, "error", err)
+				}
+			}
+		}
+		maintainPartitions()
+		logger.Go("partition maintenance", func() {
+			ticker := time.NewTicker(partitionMaintenanceInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					maintainPartitions()
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+
+	// Periodically release trips that never reached a terminal state
+	logger.Go("trip eviction", func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if n := handlers.trips.Evict(time.Now().Add(-tripIdleTimeout)); n > 0 {
+					slog.Warn("Evicted idle trips from assembler", "count", n)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+
+	// Initialize the Kafka consumer runtime
+	unframe, err := unframeFromEnv()
+	if err != nil {
+		logger.Fatal("Invalid schema registry settings", "error", err)
+	}
+	consumer, err := rideconsumer.New(rideconsumer.Config{
+		Brokers:    runtime.EnvString("KAFKA_BROKERS", defaultBrokers),
+		GroupID:    groupID,
+		Topic:      topic,
+		DLQTopic:   dlqTopic,
+		LatencySLO: rideconsumer.LatencySLOFromEnv(),
+
+		StrictDecoding: runtime.EnvBool("STRICT_DECODING", false),
+		Unframe:        unframe,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error {
+		consumer.Close()
+		return nil
+	})
+
+	if err := consumer.Run(ctx); err != nil {
+		logger.Fatal("Consumer stopped", "error", err)
+	}
+}
@@ -0,0 +1,326 @@
+package consumer
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+var (
+	lateEventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ride_consumer_late_events_dropped_total",
+		Help: "Number of events that arrived after their aggregation window's grace period, by event type.",
+	}, []string{"event_type"})
+
+	eventsSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ride_consumer_events_skipped_total",
+		Help: "Number of ride events not stored because they were redelivered duplicates, conflicted with a stored event, or are of a type ride_events does not take, by event type and outcome.",
+	}, []string{"event_type", "outcome"})
+
+	windowCorrections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ride_consumer_window_corrections_total",
+		Help: "Number of aggregation windows re-emitted because of late events, by event type.",
+	}, []string{"event_type"})
+)
+
+// outcomeNotStored is the eventsSkipped outcome of events of a type
+// ride_events does not take, such as DRIVER_ARRIVED.
+const outcomeNotStored = "not_stored"
+
+// eventHandlers are the built-in consumer handlers, sharing one store and the
+// in-memory aggregation state. With forCity set, each city's rides, trips,
+// windows, and surge data go to that city's schema instead.
+type eventHandlers struct {
+	store rides_db.RideStore
+	// forCity returns a city's store; nil unless DB_SCHEMA_PER_CITY is set
+	forCity func(ctx context.Context, city string) (rides_db.RideStore, error)
+	windows *cityWindows
+	trips   *aggregation.TripAssembler
+	audit   *auditBatcher
+}
+
+// cityWindows keeps an aggregator per city, since each city's windows are
+// stored in its own schema. Events without a city, and every event when
+// schemas are not split by city, share the aggregator of "".
+type cityWindows struct {
+	cfg aggregation.Config
+
+	mu     sync.Mutex
+	byCity map[string]*aggregation.Aggregator
+}
+
+func newCityWindows(cfg aggregation.Config) *cityWindows {
+	return &cityWindows{cfg: cfg, byCity: make(map[string]*aggregation.Aggregator)}
+}
+
+// forCity returns city's aggregator, creating it the first time city is seen.
+func (w *cityWindows) forCity(city string) *aggregation.Aggregator {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	a, ok := w.byCity[city]
+	if !ok {
+		a = aggregation.NewAggregator(w.cfg)
+		w.byCity[city] = a
+	}
+	return a
+}
+
+// cities returns the cities that have an aggregator.
+func (w *cityWindows) cities() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Collect(maps.Keys(w.byCity))
+}
+
+// register adds the built-in handlers with add: persist first so a failed
+// insert stops the others and is retried, and so that only the events it
+// inserts are counted and assembled, once each.
+func (h *eventHandlers) register(add func(events.RideEventType, rideconsumer.Handler)) {
+	add(rideconsumer.AnyEvent, h.persist)
+	add(rideconsumer.AnyEvent, lifecycleOnly(insertedOnly(h.aggregate)))
+	add(rideconsumer.AnyEvent, lifecycleOnly(insertedOnly(h.assembleTrip)))
+	add(events.EventSurgeUpdated, h.updateSurge)
+}
+
+// persist inserts the event, moves the trip's row in the rides table, and records
+// the partition checkpoint in one transaction, so a crash never leaves one of
+// them applied without the others. A failure is returned so the message goes
+// through the retry tiers. The outcome of the insert is set on msg. Events
+// other than the ride lifecycle ones are not stored, and are counted as
+// not_stored unless another handler stores them, as updateSurge does.
+func (h *eventHandlers) persist(ctx context.Context, msg *rideconsumer.Message) error {
+	if t := msg.Event.Type; !t.IsLifecycle() {
+		if t != events.EventSurgeUpdated {
+			eventsSkipped.WithLabelValues(string(t), outcomeNotStored).Inc()
+		}
+		return nil
+	}
+	store, err := h.storeFor(ctx, msg.Event)
+	if err != nil {
+		return err
+	}
+	var outcome rides_db.InsertOutcome
+	start := time.Now()
+	err = store.WithTx(ctx, func(tx rides_db.RideStore) error {
+		var err error
+		if outcome, err = tx.InsertRideEvent(ctx, msg.Event); err != nil {
+			return err
+		}
+		if err := tx.UpsertRideState(ctx, msg.Event); err != nil {
+			return err
+		}
+		// Record progress so it can be inspected from SQL
+		return tx.UpdateCheckpoint(ctx, rides_db.Checkpoint{
+			Group:     msg.Group,
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+			UpdatedAt: time.Now(),
+		})
+	})
+	if err != nil {
+		// The processor logs the stack with "Handler failed"
+		return logger.WithStack(err)
+	}
+	msg.Outcome = string(outcome)
+	h.audit.Record(ctx, msg, outcome == rides_db.OutcomeInserted, time.Since(start))
+
+	// Counted only once the transaction commits, since it may be retried
+	switch outcome {
+	case rides_db.OutcomeDuplicate:
+		eventsSkipped.WithLabelValues(string(msg.Event.Type), string(outcome)).Inc()
+	case rides_db.OutcomeConflict:
+		eventsSkipped.WithLabelValues(string(msg.Event.Type), string(outcome)).Inc()
+		slog.WarnContext(ctx, "Event conflicts with a stored event, not stored")
+	}
+	return nil
+}
+
+// updateSurge records a SURGE_UPDATED event's zone and multiplier, with the
+// partition checkpoint, in one transaction.
+func (h *eventHandlers) updateSurge(ctx context.Context, msg *rideconsumer.Message) error {
+	p, ok := events.PayloadAs[events.SurgeUpdatedPayload](msg.Event)
+	if !ok {
+		slog.WarnContext(ctx, "Surge update without a payload, skipped")
+		return nil
+	}
+	store, err := h.storeFor(ctx, msg.Event)
+	if err != nil {
+		return err
+	}
+	return store.WithTx(ctx, func(tx rides_db.RideStore) error {
+		err := tx.UpsertZone(ctx, rides_db.Zone{ID: string(p.ZoneID), Name: p.ZoneName, UpdatedAt: msg.Event.OccurredAt})
+		if err != nil {
+			return err
+		}
+		err = tx.UpsertSurgeMultiplier(ctx, rides_db.SurgeMultiplier{
+			ZoneID:        string(p.ZoneID),
+			EffectiveFrom: msg.Event.OccurredAt,
+			Multiplier:    p.Multiplier,
+		})
+		if err != nil {
+			return err
+		}
+		return tx.UpdateCheckpoint(ctx, rides_db.Checkpoint{
+			Group:     msg.Group,
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+			UpdatedAt: time.Now(),
+		})
+	})
+}
+
+// storeFor returns the store an event is written to: its city's store when
+// schemas are split by city, and the shared store otherwise.
+func (h *eventHandlers) storeFor(ctx context.Context, e events.RideEvent) (rides_db.RideStore, error) {
+	return h.cityStore(ctx, e.City)
+}
+
+// cityStore returns city's store when schemas are split by city, and the
+// shared store otherwise.
+func (h *eventHandlers) cityStore(ctx context.Context, city string) (rides_db.RideStore, error) {
+	if h.forCity == nil || city == "" {
+		return h.store, nil
+	}
+	return h.forCity(ctx, city)
+}
+
+// lifecycleOnly runs h only for ride lifecycle events, so reference data such
+// as surge updates never reaches ride_events, the windows, or the trips.
+func lifecycleOnly(h rideconsumer.Handler) rideconsumer.Handler {
+	return func(ctx context.Context, msg *rideconsumer.Message) error {
+		if !msg.Event.Type.IsLifecycle() {
+			return nil
+		}
+		return h(ctx, msg)
+	}
+}
+
+// insertedOnly runs h only for events persist inserted, so that redelivered
+// and conflicting events are not counted or assembled again.
+func insertedOnly(h rideconsumer.Handler) rideconsumer.Handler {
+	return func(ctx context.Context, msg *rideconsumer.Message) error {
+		if msg.Outcome != string(rides_db.OutcomeInserted) {
+			return nil
+		}
+		return h(ctx, msg)
+	}
+}
+
+// aggregate folds persisted events into the per-minute windows of their city
+// and stores any windows that closed or were corrected by a late event, in the
+// city's store.
+func (h *eventHandlers) aggregate(ctx context.Context, msg *rideconsumer.Message) error {
+	event := msg.Event
+	store, err := h.storeFor(ctx, event)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to open the event's city store", "city", event.City, "error", err)
+		return nil
+	}
+	windows := h.windowsFor(event.City)
+	restoreWindows(ctx, store, windows, event)
+	results, ok := windows.Add(event)
+	if !ok {
+		slog.WarnContext(ctx, "Event arrived after window grace period, not aggregated", "event_time", event.OccurredAt)
+		lateEventsDropped.WithLabelValues(string(event.Type)).Inc()
+		return nil
+	}
+	storeWindows(ctx, store, results)
+	return nil
+}
+
+// windowsFor returns the aggregator of city's events: the city's own when
+// schemas are split by city, and the shared one otherwise.
+func (h *eventHandlers) windowsFor(city string) *aggregation.Aggregator {
+	if h.forCity == nil {
+		city = ""
+	}
+	return h.windows.forCity(city)
+}
+
+// restoreWindows restores the windows stored in store starting with event's
+// when windows does not hold event's window, as after a restart: a window
+// flushed at shutdown then goes on from its stored counts instead of being
+// overwritten with only the events since.
+func restoreWindows(ctx context.Context, store rides_db.RideStore, windows *aggregation.Aggregator, event events.RideEvent) {
+	start, end, held := windows.Window(event)
+	if held {
+		return
+	}
+	// A replica may not have the windows flushed just before a restart yet
+	stored, err := store.ListEventWindows(rides_db.ReadFromPrimary(ctx), rides_db.TimeRange{From: start, To: end})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load stored event windows", "window_start", start, "error", err)
+		return
+	}
+	windows.Restore(stored...)
+}
+
+// flush stores the windows still open and the audit batch in progress, as the
+// consumer does on shutdown. It takes the windows when it runs, so that a
+// shutdown hook calling it stores those open at shutdown.
+func (h *eventHandlers) flush(ctx context.Context) {
+	for _, city := range h.windows.cities() {
+		store, err := h.cityStore(ctx, city)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to open the city store for its windows", "city", city, "error", err)
+			continue
+		}
+		storeWindows(ctx, store, h.windows.forCity(city).Flush())
+	}
+	h.audit.Flush(ctx)
+}
+
+// storeWindows upserts window aggregates into store, counting corrections
+// separately.
+func storeWindows(ctx context.Context, store rides_db.RideStore, results []aggregation.WindowResult) {
+	for _, w := range results {
+		if w.IsCorrection {
+			windowCorrections.WithLabelValues(string(w.EventType)).Inc()
+		}
+		if err := store.UpsertEventWindow(ctx, w); err != nil {
+			slog.Error("Failed to store event window", "window_start", w.Start, "type", w.EventType, "error", err)
+		}
+	}
+}
+
+// assembleTrip collects events into their trip and writes the trips row once the
+// trip reaches a terminal state.
+func (h *eventHandlers) assembleTrip(ctx context.Context, msg *rideconsumer.Message) error {
+	trip, done := h.trips.Add(msg.Event)
+	if !done {
+		return nil
+	}
+	store, err := h.storeFor(ctx, msg.Event)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to open the trip's city store", "city", msg.Event.City, "error", err)
+		return nil
+	}
+	if trip.RequestedAt.IsZero() {
+		// The assembler missed the start of this trip (e.g. after a restart), so
+		// rebuild the row from the events already stored instead
+		if err := store.RefreshTrip(ctx, trip.TripID); err != nil {
+			slog.ErrorContext(ctx, "Failed to rebuild trip from stored events", "error", err)
+		}
+		return nil
+	}
+	if err := store.InsertTrip(ctx, trip); err != nil {
+		slog.ErrorContext(ctx, "Failed to store assembled trip", "error", err)
+		return nil
+	}
+	slog.InfoContext(ctx, "Assembled trip", "state", trip.FinalState)
+	return nil
+}
@@ -0,0 +1,271 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/events/eventstest"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+// runPipeline publishes trips to an in-memory broker and runs the built-in
+// handlers on them against store until every message has been handled once.
+func runPipeline(t *testing.T, store *ridetest.Store, trips [][]events.RideEvent) *ridetest.Broker {
+	t.Helper()
+	broker := ridetest.NewBroker()
+	for _, trip := range trips {
+		for _, e := range trip {
+			value, err := json.Marshal(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			broker.Publish(topic, []byte(e.TripID), value)
+		}
+	}
+	want := len(broker.Messages(topic))
+
+	handlers := &eventHandlers{
+		store:   store,
+		windows: newCityWindows(aggregation.DefaultConfig),
+		trips:   aggregation.NewTripAssembler(),
+		audit:   newAuditBatcher(store, groupID, auditBatchOffsets),
+	}
+	registry := rideconsumer.NewRegistry()
+	handlers.register(registry.Register)
+	handled := make(chan struct{}, want)
+	registry.Register(rideconsumer.AnyEvent, func(context.Context, *rideconsumer.Message) error {
+		handled <- struct{}{}
+		return nil
+	})
+
+	c, err := rideconsumer.New(rideconsumer.Config{
+		GroupID:  groupID,
+		Topic:    topic,
+		DLQTopic: dlqTopic,
+		Registry: registry,
+		Source:   broker.Source(topic),
+		Sink:     broker,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	// Messages a handler fails on leave for the retry tiers instead
+	deadline := time.After(5 * time.Second)
+	for seen := 0; seen+len(broker.Messages(rideconsumer.DefaultRetryTiers[0].Topic)) < want; {
+		select {
+		case <-handled:
+			seen++
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("handled %d of %d messages", seen, want)
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// As the consumer does on shutdown
+	handlers.flush(context.Background())
+	return broker
+}
+
+func TestHandlers_PersistTrips(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	completed := eventstest.RandomTrip(r, events.EventRideRequested, events.EventRideAccepted,
+		events.EventDriverArrived, events.EventPickedUp, events.EventTripStarted, events.EventTripCompleted)
+	cancelled := eventstest.RandomTrip(r, events.EventRideRequested, events.EventTripCancelled)
+	// Right after the completed trip, so that its windows are still open
+	shift := completed[len(completed)-1].OccurredAt.Sub(cancelled[0].OccurredAt) + time.Second
+	for i := range cancelled {
+		cancelled[i].OccurredAt = cancelled[i].OccurredAt.Add(shift)
+	}
+	// A redelivery is stored, counted, and assembled once
+	redelivered := []events.RideEvent{cancelled[0]}
+
+	// DRIVER_ARRIVED and PICKED_UP are not stored yet, and are counted
+	notStored := eventsSkipped.WithLabelValues(string(events.EventDriverArrived), outcomeNotStored)
+	before := testutil.ToFloat64(notStored)
+
+	store := ridetest.NewStore()
+	broker := runPipeline(t, store, [][]events.RideEvent{completed, cancelled, redelivered})
+	ctx := context.Background()
+
+	stored := len(completed) - 2 + len(cancelled)
+	if n := len(store.Events()); n != stored {
+		t.Errorf("stored %d events, want %d", n, stored)
+	}
+	if n := testutil.ToFloat64(notStored) - before; n != 1 {
+		t.Errorf("counted %v DRIVER_ARRIVED events not stored, want 1", n)
+	}
+	for _, trip := range [][]events.RideEvent{completed, cancelled} {
+		last := trip[len(trip)-1]
+		ride, err := store.GetRide(ctx, last.TripID)
+		if err != nil || ride.State != last.State {
+			t.Errorf("ride %s: got %+v, %v; want state %s", last.TripID, ride, err, last.State)
+		}
+		summary, err := store.GetTrip(ctx, last.TripID)
+		if err != nil || summary.FinalState != last.State {
+			t.Errorf("trip %s: got %+v, %v; want final state %s", last.TripID, summary, err, last.State)
+		}
+	}
+	var requested int64
+	for _, w := range store.Windows() {
+		if w.EventType == events.EventRideRequested {
+			requested += w.Count
+		}
+	}
+	if requested != 2 {
+		t.Errorf("REQUESTED windows count %d events, want 2: the redelivery counted once", requested)
+	}
+	if summary, _ := store.GetTrip(ctx, completed[0].TripID); summary.FareUSD == 0 {
+		t.Errorf("completed trip has no fare: %+v", summary)
+	}
+
+	last := int64(len(broker.Messages(topic)) - 1)
+	if cps, _ := store.GetCheckpoints(ctx, groupID); len(cps) != 1 || cps[0].Offset != last {
+		t.Errorf("checkpoints: got %+v, want offset %d", cps, last)
+	}
+	audit := store.Audit()
+	if len(audit) != 1 || audit[0].Rows != int64(stored) || audit[0].LastOffset != last {
+		t.Errorf("audit: got %+v", audit)
+	}
+	if dlq := broker.Messages(dlqTopic); len(dlq) != 0 {
+		t.Errorf("%d messages dead-lettered", len(dlq))
+	}
+}
+
+// TestHandlers_FlushStoresOpenWindows checks that windows still open when the
+// consumer stops are stored by flush, its shutdown hook.
+func TestHandlers_FlushStoresOpenWindows(t *testing.T) {
+	ctx := context.Background()
+	store := ridetest.NewStore()
+	handlers := &eventHandlers{
+		store:   store,
+		windows: newCityWindows(aggregation.DefaultConfig),
+		trips:   aggregation.NewTripAssembler(),
+		audit:   newAuditBatcher(store, groupID, auditBatchOffsets),
+	}
+	trip := eventstest.RandomTrip(rand.New(rand.NewPCG(3, 3)), events.EventRideRequested)
+	if err := handlers.aggregate(ctx, &rideconsumer.Message{Event: trip[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(store.Windows()); n != 0 {
+		t.Fatalf("%d windows stored before the flush, want the window still open", n)
+	}
+
+	handlers.flush(ctx)
+	windows := store.Windows()
+	if len(windows) != 1 || windows[0].EventType != events.EventRideRequested || windows[0].Count != 1 {
+		t.Errorf("windows after the flush = %+v, want the open REQUESTED window", windows)
+	}
+}
+
+// TestHandlers_RestartMidWindow checks that a window flushed at shutdown keeps
+// its stored counts when the next consumer adds to it.
+func TestHandlers_RestartMidWindow(t *testing.T) {
+	ctx := context.Background()
+	store := ridetest.NewStore()
+	newHandlers := func() *eventHandlers {
+		return &eventHandlers{
+			store:   store,
+			windows: newCityWindows(aggregation.DefaultConfig),
+			trips:   aggregation.NewTripAssembler(),
+			audit:   newAuditBatcher(store, groupID, auditBatchOffsets),
+		}
+	}
+	r := rand.New(rand.NewPCG(4, 4))
+	first := eventstest.RandomTrip(r, events.EventRideRequested)[0]
+	second := eventstest.RandomTrip(r, events.EventRideRequested)[0]
+	first.OccurredAt = first.OccurredAt.Truncate(time.Minute)
+	second.OccurredAt = first.OccurredAt.Add(10 * time.Second)
+
+	before := newHandlers()
+	if err := before.aggregate(ctx, &rideconsumer.Message{Event: first}); err != nil {
+		t.Fatal(err)
+	}
+	before.flush(ctx)
+
+	after := newHandlers()
+	if err := after.aggregate(ctx, &rideconsumer.Message{Event: second}); err != nil {
+		t.Fatal(err)
+	}
+	after.flush(ctx)
+
+	windows := store.Windows()
+	if len(windows) != 1 || windows[0].Count != 2 {
+		t.Errorf("windows after the restart = %+v, want one REQUESTED window counting both events", windows)
+	}
+}
+
+// TestHandlers_WindowsPerCity checks that with schemas split by city, each
+// city's windows are counted apart and stored in the city's store, and events
+// without a city in the shared store.
+func TestHandlers_WindowsPerCity(t *testing.T) {
+	ctx := context.Background()
+	store := ridetest.NewStore()
+	cities := map[string]*ridetest.Store{"nyc": ridetest.NewStore(), "sf": ridetest.NewStore()}
+	handlers := &eventHandlers{
+		store: store,
+		forCity: func(ctx context.Context, city string) (rides_db.RideStore, error) {
+			return cities[city], nil
+		},
+		windows: newCityWindows(aggregation.DefaultConfig),
+		trips:   aggregation.NewTripAssembler(),
+		audit:   newAuditBatcher(store, groupID, auditBatchOffsets),
+	}
+	r := rand.New(rand.NewPCG(5, 5))
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, city := range []string{"nyc", "nyc", "sf", ""} {
+		e := eventstest.RandomTrip(r, events.EventRideRequested)[0]
+		e.City, e.OccurredAt = city, at
+		if err := handlers.aggregate(ctx, &rideconsumer.Message{Event: e}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handlers.flush(ctx)
+
+	for name, tt := range map[string]struct {
+		store *ridetest.Store
+		count int64
+	}{
+		"nyc":    {cities["nyc"], 2},
+		"sf":     {cities["sf"], 1},
+		"shared": {store, 1},
+	} {
+		windows := tt.store.Windows()
+		if len(windows) != 1 || windows[0].Count != tt.count {
+			t.Errorf("%s windows = %+v, want one REQUESTED window counting %d events", name, windows, tt.count)
+		}
+	}
+}
+
+func TestHandlers_PersistFailureRetries(t *testing.T) {
+	trip := eventstest.RandomTrip(rand.New(rand.NewPCG(2, 2)), events.EventRideRequested, events.EventRideAccepted)
+	store := ridetest.NewStore()
+	store.FailOn("UpdateCheckpoint", errors.New("database down"))
+
+	broker := runPipeline(t, store, [][]events.RideEvent{trip})
+
+	if n := len(store.Events()); n != 0 {
+		t.Errorf("failed transactions left %d events", n)
+	}
+	if retries := broker.Messages(rideconsumer.DefaultRetryTiers[0].Topic); len(retries) != len(trip) {
+		t.Errorf("%d messages sent for retry, want %d", len(retries), len(trip))
+	}
+}
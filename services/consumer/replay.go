@@ -0,0 +1,202 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/replay"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+)
+
+// replayGroupID is the group replayed events are handled as, as recorded in
+// the replay's checkpoints and audit log.
+const replayGroupID = "replay"
+
+// replayReport is what a replay read, and how its tables compare with the
+// live ones.
+type replayReport struct {
+	Schema       string               `json:"schema"`
+	Against      string               `json:"against"`
+	Retried      int                  `json:"retried"`       // events that failed and were left for a retry tier
+	DeadLettered int                  `json:"dead_lettered"` // events the consumer rejected
+	Tables       []rides_db.TableDiff `json:"tables"`
+}
+
+// same reports whether the replay's tables agree with the live ones.
+func (r replayReport) same() bool {
+	for _, t := range r.Tables {
+		if !t.Same() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r replayReport) writeText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Replayed into %s, compared with %s\n", r.Schema, r.Against)
+	fmt.Fprintf(&b, "Retried: %d, dead-lettered: %d\n", r.Retried, r.DeadLettered)
+	fmt.Fprintf(&b, "%-20s %10s %10s %10s %10s\n", "TABLE", "COMPARED", "MISSING", "EXTRA", "CHANGED")
+	for _, t := range r.Tables {
+		fmt.Fprintf(&b, "%-20s %10d %10d %10d %10d\n", t.Table, t.Compared, t.Missing, t.Extra, t.Changed)
+	}
+	for _, t := range r.Tables {
+		for _, k := range t.Keys {
+			fmt.Fprintf(&b, "differs: %s %s\n", t.Table, k)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// runReplay handles `consumer replay`: it reads the topic again from a chosen
+// point into a schema of its own with the consumer's handlers, then compares
+// the tables they wrote with the live schema's, so a change to the handlers
+// can be checked against what the running consumer stored. It fails if any
+// table differs.
+func runReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	schema := fs.String("schema", "replay", "schema to replay into")
+	against := fs.String("against", "public", "schema to compare the replay with")
+	keep := fs.Bool("keep", false, "replay on top of the schema's tables rather than dropping them first")
+	from := fs.String("from", "", "start each partition at its first message at or after this time (RFC 3339 or YYYY-MM-DD; default the start)")
+	offsets := fs.String("offsets", "", "start the partitions listed at these offsets, as partition:offset,...")
+	brokers := fs.String("brokers", "", "Kafka brokers to read from (default KAFKA_BROKERS)")
+	maxKeys := fs.Int("max-keys", 20, "keys of differing rows to list per table")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	cfg := replay.Config{Brokers: *brokers, Topic: topic, GroupID: replayGroupID}
+	if cfg.Brokers == "" {
+		cfg.Brokers = runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+	}
+	var err error
+	if *from != "" {
+		if cfg.Since, err = time.Parse(time.RFC3339, *from); err != nil {
+			if cfg.Since, err = time.Parse(time.DateOnly, *from); err != nil {
+				return fmt.Errorf("-from: %w", err)
+			}
+		}
+	}
+	if cfg.Offsets, err = parseOffsets(*offsets); err != nil {
+		return fmt.Errorf("-offsets: %w", err)
+	}
+
+	store, err := rides_db.OpenPostgresFromEnv(rides_db.WithSchema(*schema))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if !*keep {
+		if err := store.DropSchema(ctx); err != nil {
+			return err
+		}
+	}
+	if err := store.Migrate(ctx); err != nil {
+		return err
+	}
+	if err := store.SilenceRideStateChanges(ctx); err != nil {
+		return err
+	}
+
+	src, err := replay.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// The handlers are the consumer's own, less the per-city stores; retries
+	// and dead letters go to a broker in memory rather than the live topics
+	handlers := &eventHandlers{
+		store:   store,
+		windows: newCityWindows(aggregation.DefaultConfig),
+		trips:   aggregation.NewTripAssembler(),
+		audit:   newAuditBatcher(store, replayGroupID, auditBatchOffsets),
+	}
+	registry := rideconsumer.NewRegistry()
+	handlers.register(registry.Register)
+	strictDecoding, _ := strconv.ParseBool(os.Getenv("STRICT_DECODING"))
+	unframe, err := unframeFromEnv()
+	if err != nil {
+		return err
+	}
+	sink := rideconsumer.NewMemoryBroker()
+	c, err := rideconsumer.New(rideconsumer.Config{
+		GroupID:    replayGroupID,
+		Topic:      topic,
+		DLQTopic:   dlqTopic,
+		LatencySLO: rideconsumer.LatencySLOFromEnv(),
+		Registry:   registry,
+		Source:     src,
+		Sink:       sink,
+
+		StrictDecoding: strictDecoding,
+		Unframe:        unframe,
+	})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	start := time.Now()
+	if err := c.Run(ctx); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	handlers.flush(ctx)
+	slog.Info("Replay finished", "schema", *schema, "took", time.Since(start))
+
+	report := replayReport{Schema: *schema, Against: *against, DeadLettered: len(sink.Messages(dlqTopic))}
+	for _, tier := range rideconsumer.DefaultRetryTiers {
+		report.Retried += len(sink.Messages(tier.Topic))
+	}
+	if report.Tables, err = store.DiffSchema(ctx, *against, *maxKeys); err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.writeText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if !report.same() {
+		return errors.New("the replay differs from " + *against)
+	}
+	return nil
+}
+
+// parseOffsets parses partition:offset pairs separated by commas.
+func parseOffsets(raw string) (map[int32]int64, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	out := make(map[int32]int64)
+	for _, pair := range strings.Split(raw, ",") {
+		p, o, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		partition, perr := strconv.ParseInt(p, 10, 32)
+		offset, oerr := strconv.ParseInt(o, 10, 64)
+		if !ok || perr != nil || oerr != nil || partition < 0 || offset < 0 {
+			return nil, fmt.Errorf("%q is not partition:offset", pair)
+		}
+		out[int32(partition)] = offset
+	}
+	return out, nil
+}
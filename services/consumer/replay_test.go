@@ -0,0 +1,21 @@
+package consumer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOffsets(t *testing.T) {
+	got, err := parseOffsets("0:120, 2:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int32]int64{0: 120, 2: 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseOffsets = %v, want %v", got, want)
+	}
+	for _, bad := range []string{"0", "0:x", "-1:5", "1:-5", "0:1,"} {
+		if _, err := parseOffsets(bad); err == nil {
+			t.Errorf("parseOffsets(%q) succeeded, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package dashboard
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+)
+
+var (
+	refreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dashboard_refreshes_total",
+		Help: "Number of dashboard snapshots taken, by outcome: ok, or partial when a source failed.",
+	}, []string{"outcome"})
+
+	clients = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dashboard_clients",
+		Help: "Number of browsers following the dashboard's event stream.",
+	})
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// Store is the part of rides_db.RideStore the dashboard reads from.
+type Store interface {
+	ListActiveRides(ctx context.Context, limit, offset int) ([]rides_db.Ride, error)
+	ListEventWindows(ctx context.Context, tr rides_db.TimeRange) ([]aggregation.WindowResult, error)
+	CancellationRateByHour(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.HourlyCancellationRate, error)
+	GetCheckpoints(ctx context.Context, group string) ([]rides_db.Checkpoint, error)
+	ListFraudAlerts(ctx context.Context, limit int) ([]fraud.Alert, error)
+	ListHeatmapCells(ctx context.Context, tr rides_db.TimeRange) ([]rides_db.HeatmapCell, error)
+}
+
+// Watermarks reports the offsets of a partition's first and next message.
+// *kafka.Consumer satisfies it.
+type Watermarks interface {
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+}
+
+// Config configures a Dashboard. Zero values take the defaults.
+type Config struct {
+	// Group is the consumer group whose lag is shown, from its checkpoints.
+	Group string
+	// Refresh is how often a snapshot is taken and sent to browsers.
+	Refresh time.Duration
+	// Throughput is how far back events per minute are shown.
+	Throughput time.Duration
+	// Heatmap is how far back the latest heatmap bucket is looked for.
+	Heatmap time.Duration
+	// MaxRides and MaxAlerts cap the rides on the map and the alerts listed.
+	MaxRides  int
+	MaxAlerts int
+	// Now returns the current time; nil uses time.Now.
+	Now func() time.Time
+}
+
+// Defaults of Config.
+const (
+	defaultGroup      = "ride-consumer-group"
+	defaultRefresh    = 2 * time.Second
+	defaultThroughput = 30 * time.Minute
+	defaultHeatmap    = 15 * time.Minute
+	defaultMaxRides   = 500
+	defaultMaxAlerts  = 20
+)
+
+func (c *Config) setDefaults() {
+	if c.Group == "" {
+		c.Group = defaultGroup
+	}
+	if c.Refresh <= 0 {
+		c.Refresh = defaultRefresh
+	}
+	if c.Throughput <= 0 {
+		c.Throughput = defaultThroughput
+	}
+	if c.Heatmap <= 0 {
+		c.Heatmap = defaultHeatmap
+	}
+	if c.MaxRides <= 0 {
+		c.MaxRides = defaultMaxRides
+	}
+	if c.MaxAlerts <= 0 {
+		c.MaxAlerts = defaultMaxAlerts
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+// Snapshot is what the dashboard shows at one time.
+type Snapshot struct {
+	At          time.Time `json:"at"`
+	ActiveRides []RidePin `json:"active_rides"`
+	// Throughput is the events stored per minute, oldest first, from the
+	// closed aggregation windows.
+	Throughput []Throughput `json:"throughput"`
+	// Requested and Cancelled count the trips requested in the last hour
+	// that have ended, and how many of them were cancelled.
+	Requested        int64          `json:"requested"`
+	Cancelled        int64          `json:"cancelled"`
+	CancellationRate float64        `json:"cancellation_rate"`
+	Lag              []PartitionLag `json:"lag"`
+	Alerts           []fraud.Alert  `json:"alerts"`
+	// Heatmap holds the cells of the latest heatmap bucket.
+	Heatmap []HeatCell `json:"heatmap"`
+}
+
+// RidePin is an active ride on the map, at its pickup.
+type RidePin struct {
+	TripID   string           `json:"trip_id"`
+	State    events.RideState `json:"state"`
+	DriverID string           `json:"driver_id,omitempty"`
+	Lat      float64          `json:"lat"`
+	Lng      float64          `json:"lng"`
+}
+
+// HeatCell is the demand and supply of one zone on the map, within its
+// bounds.
+type HeatCell struct {
+	Zone    string  `json:"zone"`
+	South   float64 `json:"south"`
+	West    float64 `json:"west"`
+	North   float64 `json:"north"`
+	East    float64 `json:"east"`
+	Pickups int64   `json:"pickups"`
+	Drivers int64   `json:"drivers"`
+}
+
+// Throughput is how many events were stored in one minute.
+type Throughput struct {
+	Minute time.Time `json:"minute"`
+	Events int64     `json:"events"`
+}
+
+// PartitionLag is how far the consumer group is behind on one partition.
+type PartitionLag struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"` // of the last message the group stored
+	High      int64  `json:"high"`   // of the next message to arrive
+	Lag       int64  `json:"lag"`
+}
+
+// Dashboard takes snapshots of the read model every Refresh and serves the
+// latest to browsers. It is safe for concurrent use.
+type Dashboard struct {
+	cfg        Config
+	store      Store
+	watermarks Watermarks
+
+	mu     sync.Mutex
+	latest Snapshot
+	// changed is closed and replaced when a new snapshot is taken
+	changed chan struct{}
+}
+
+// New returns a Dashboard reading from store, and the high watermarks of the
+// consumer group's partitions from watermarks.
+func New(cfg Config, store Store, watermarks Watermarks) *Dashboard {
+	cfg.setDefaults()
+	return &Dashboard{cfg: cfg, store: store, watermarks: watermarks, changed: make(chan struct{})}
+}
+
+// Run takes a snapshot every Refresh until ctx is cancelled.
+func (d *Dashboard) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Refresh)
+	defer ticker.Stop()
+	for {
+		d.Refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh takes a snapshot and sends it to the browsers following the stream.
+// A source that fails is logged and left empty in the snapshot.
+func (d *Dashboard) Refresh(ctx context.Context) {
+	s, err := d.Snapshot(ctx)
+	if err != nil {
+		refreshes.WithLabelValues("partial").Inc()
+		slog.Warn("Dashboard snapshot incomplete", "error", err)
+	} else {
+		refreshes.WithLabelValues("ok").Inc()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.latest = s
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// Snapshot reads what the dashboard shows now. The error joins those of the
+// sources that failed; the snapshot holds the rest.
+func (d *Dashboard) Snapshot(ctx context.Context) (Snapshot, error) {
+	now := d.cfg.Now()
+	s := Snapshot{At: now}
+	var errs []error
+
+	rides, err := d.store.ListActiveRides(ctx, d.cfg.MaxRides, 0)
+	errs = append(errs, err)
+	for _, r := range rides {
+		if r.Pickup != nil {
+			s.ActiveRides = append(s.ActiveRides, RidePin{TripID: r.TripID, State: r.State, DriverID: r.DriverID, Lat: r.Pickup.Lat, Lng: r.Pickup.Lng})
+		}
+	}
+
+	windows, err := d.store.ListEventWindows(ctx, rides_db.TimeRange{From: now.Add(-d.cfg.Throughput), To: now})
+	errs = append(errs, err)
+	for _, w := range windows {
+		if n := len(s.Throughput); n > 0 && s.Throughput[n-1].Minute.Equal(w.Start) {
+			s.Throughput[n-1].Events += w.Count
+		} else {
+			s.Throughput = append(s.Throughput, Throughput{Minute: w.Start, Events: w.Count})
+		}
+	}
+
+	rates, err := d.store.CancellationRateByHour(ctx, rides_db.TimeRange{From: now.Add(-time.Hour), To: now})
+	errs = append(errs, err)
+	for _, r := range rates {
+		s.Requested += r.Requested
+		s.Cancelled += r.Cancelled
+	}
+	if s.Requested > 0 {
+		s.CancellationRate = float64(s.Cancelled) / float64(s.Requested)
+	}
+
+	s.Lag, err = d.lag(ctx)
+	errs = append(errs, err)
+
+	s.Alerts, err = d.store.ListFraudAlerts(ctx, d.cfg.MaxAlerts)
+	errs = append(errs, err)
+
+	s.Heatmap, err = d.heatmap(ctx, now)
+	errs = append(errs, err)
+	return s, errors.Join(errs...)
+}
+
+// heatmap returns the cells of the latest bucket that started within the
+// Heatmap duration before now. Cells of zones off the grid are left out.
+func (d *Dashboard) heatmap(ctx context.Context, now time.Time) ([]HeatCell, error) {
+	cells, err := d.store.ListHeatmapCells(ctx, rides_db.TimeRange{From: now.Add(-d.cfg.Heatmap), To: now})
+	if err != nil || len(cells) == 0 {
+		return nil, err
+	}
+	latest := cells[len(cells)-1].Start
+	var out []HeatCell
+	for _, c := range cells {
+		sw, ne, err := pricing.ZoneBounds(events.ZoneID(c.ZoneID))
+		if err != nil || !c.Start.Equal(latest) {
+			continue
+		}
+		out = append(out, HeatCell{Zone: c.ZoneID, South: sw.Lat, West: sw.Lng, North: ne.Lat, East: ne.Lng,
+			Pickups: c.Pickups, Drivers: c.Drivers})
+	}
+	return out, nil
+}
+
+// lag compares the consumer group's checkpoints with the partitions' high
+// watermarks.
+func (d *Dashboard) lag(ctx context.Context) ([]PartitionLag, error) {
+	checkpoints, err := d.store.GetCheckpoints(ctx, d.cfg.Group)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out  []PartitionLag
+		errs []error
+	)
+	for _, cp := range checkpoints {
+		_, high, err := d.watermarks.QueryWatermarkOffsets(cp.Topic, cp.Partition, 1000)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("watermarks of %s/%d: %w", cp.Topic, cp.Partition, err))
+			continue
+		}
+		out = append(out, PartitionLag{Topic: cp.Topic, Partition: cp.Partition, Offset: cp.Offset, High: high, Lag: max(high-cp.Offset-1, 0)})
+	}
+	return out, errors.Join(errs...)
+}
+
+// latestSnapshot returns the latest snapshot and a channel closed when the
+// next is taken.
+func (d *Dashboard) latestSnapshot() (Snapshot, <-chan struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.latest, d.changed
+}
+
+// Handler returns the dashboard's routes:
+//
+//	GET /           the dashboard page
+//	GET /snapshot   the latest snapshot as JSON
+//	GET /events     the snapshots as server-sent events, one each Refresh
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexHTML)
+	})
+	mux.HandleFunc("GET /snapshot", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := d.latestSnapshot()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+	})
+	mux.HandleFunc("GET /events", d.stream)
+	return mux
+}
+
+// stream sends each snapshot to the browser as a server-sent event named
+// snapshot, starting with the latest, until the browser goes away.
+func (d *Dashboard) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	clients.Inc()
+	defer clients.Dec()
+
+	for {
+		s, changed := d.latestSnapshot()
+		data, err := json.Marshal(s)
+		if err != nil {
+			slog.Error("Failed to encode snapshot", "error", err)
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/aggregation"
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/fraud"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// watermarks holds the high watermark of each partition of ride-events.
+type watermarks map[int32]int64
+
+func (w watermarks) QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (int64, int64, error) {
+	high, ok := w[partition]
+	if !ok {
+		return 0, 0, errors.New("unknown partition")
+	}
+	return 0, high, nil
+}
+
+// newStore returns a store with two active rides, one completed, events in
+// two minutes, a cancelled and a completed trip, the ride consumer's
+// checkpoints on two partitions, an alert, and two heatmap buckets.
+func newStore(t *testing.T) *ridetest.Store {
+	t.Helper()
+	ctx := context.Background()
+	store := ridetest.NewStore()
+	pickup := events.Coordinate{Lat: 40.75, Lng: -73.98}
+	dropoff := events.Coordinate{Lat: 40.76, Lng: -73.97}
+	at := func(min int) events.Option { return events.WithTime(now.Add(time.Duration(min) * time.Minute)) }
+	for _, e := range []events.RideEvent{
+		events.NewRideRequested("trip-1", "rider-1", "A St", "B St", at(-10), events.WithCoordinates(&pickup, &dropoff)),
+		events.NewRideRequested("trip-2", "rider-2", "A St", "B St", at(-9), events.WithCoordinates(&pickup, &dropoff)),
+		events.NewRideAccepted("trip-2", "driver-1", at(-8)),
+		events.NewRideRequested("trip-3", "rider-3", "A St", "B St", at(-9), events.WithCoordinates(&pickup, &dropoff)),
+		events.NewTripCompleted("trip-3", 2, events.NewMoney(900, events.USD), at(-2)),
+	} {
+		if err := store.UpsertRideState(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, w := range []aggregation.WindowResult{
+		{Start: now.Add(-3 * time.Minute), End: now.Add(-2 * time.Minute), EventType: events.EventRideRequested, Count: 4},
+		{Start: now.Add(-3 * time.Minute), End: now.Add(-2 * time.Minute), EventType: events.EventRideAccepted, Count: 3},
+		{Start: now.Add(-2 * time.Minute), End: now.Add(-time.Minute), EventType: events.EventRideRequested, Count: 5},
+		{Start: now.Add(-2 * time.Hour), End: now.Add(-2*time.Hour + time.Minute), EventType: events.EventRideRequested, Count: 9},
+	} {
+		if err := store.UpsertEventWindow(ctx, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, trip := range []aggregation.Trip{
+		{TripID: "trip-3", FinalState: events.StateCompleted, RequestedAt: now.Add(-9 * time.Minute)},
+		{TripID: "trip-4", FinalState: events.StateCancelled, RequestedAt: now.Add(-30 * time.Minute)},
+		{TripID: "trip-5", FinalState: events.StateCancelled, RequestedAt: now.Add(-3 * time.Hour)},
+	} {
+		if err := store.InsertTrip(ctx, trip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, cp := range []rides_db.Checkpoint{
+		{Group: defaultGroup, Topic: "ride-events", Partition: 0, Offset: 41},
+		{Group: defaultGroup, Topic: "ride-events", Partition: 1, Offset: 9},
+		{Group: "other", Topic: "ride-events", Partition: 0, Offset: 1},
+	} {
+		if err := store.UpdateCheckpoint(ctx, cp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.InsertFraudAlert(ctx, fraud.NewAlert(fraud.KindFareMismatch, "trip-3", now, "charged too much")); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []rides_db.HeatmapCell{
+		{ZoneID: "40.74,-74.00", Start: now.Add(-10 * time.Minute), End: now.Add(-5 * time.Minute), Pickups: 9},
+		{ZoneID: "40.74,-74.00", Start: now.Add(-5 * time.Minute), End: now, Pickups: 3, Drivers: 1},
+		{ZoneID: "Main St", Start: now.Add(-5 * time.Minute), End: now, Pickups: 2},
+	} {
+		if err := store.UpsertHeatmapCell(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestDashboard_Snapshot(t *testing.T) {
+	d := New(Config{Now: func() time.Time { return now }}, newStore(t), watermarks{0: 50, 1: 10})
+	s, err := d.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.ActiveRides) != 2 || s.ActiveRides[0].TripID != "trip-2" || s.ActiveRides[0].DriverID != "driver-1" || s.ActiveRides[0].Lat != 40.75 {
+		t.Errorf("active rides %+v, want trip-2 and trip-1 at their pickup", s.ActiveRides)
+	}
+	want := []Throughput{{Minute: now.Add(-3 * time.Minute), Events: 7}, {Minute: now.Add(-2 * time.Minute), Events: 5}}
+	if len(s.Throughput) != len(want) || !s.Throughput[0].Minute.Equal(want[0].Minute) || s.Throughput[0].Events != 7 || s.Throughput[1].Events != 5 {
+		t.Errorf("throughput %+v, want %+v", s.Throughput, want)
+	}
+	if s.Requested != 2 || s.Cancelled != 1 || s.CancellationRate != 0.5 {
+		t.Errorf("cancelled %d of %d (%v), want 1 of 2", s.Cancelled, s.Requested, s.CancellationRate)
+	}
+	if len(s.Lag) != 2 || s.Lag[0].Lag != 8 || s.Lag[1].Lag != 0 {
+		t.Errorf("lag %+v, want 8 on partition 0 and none on 1", s.Lag)
+	}
+	if len(s.Alerts) != 1 || s.Alerts[0].TripID != "trip-3" {
+		t.Errorf("alerts %+v", s.Alerts)
+	}
+	// Only the latest bucket is shown, without the zone off the grid
+	if len(s.Heatmap) != 1 || s.Heatmap[0].Zone != "40.74,-74.00" || s.Heatmap[0].Pickups != 3 || s.Heatmap[0].Drivers != 1 ||
+		s.Heatmap[0].South != 40.74 || s.Heatmap[0].West != -74 || s.Heatmap[0].North <= s.Heatmap[0].South {
+		t.Errorf("heatmap %+v, want the latest bucket's grid cell", s.Heatmap)
+	}
+}
+
+func TestDashboard_SnapshotKeepsWhatItCouldRead(t *testing.T) {
+	store := newStore(t)
+	store.FailOn("ListFraudAlerts", errors.New("boom"))
+	// Partition 1's watermarks are unknown
+	d := New(Config{Now: func() time.Time { return now }}, store, watermarks{0: 50})
+	s, err := d.Snapshot(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "boom") || !strings.Contains(err.Error(), "ride-events/1") {
+		t.Errorf("error %v, want the alerts' and partition 1's", err)
+	}
+	if len(s.ActiveRides) != 2 || len(s.Lag) != 1 || s.Alerts != nil {
+		t.Errorf("snapshot %+v, want the rides and partition 0's lag", s)
+	}
+}
+
+func TestDashboard_StreamsSnapshots(t *testing.T) {
+	d := New(Config{Now: func() time.Time { return now }}, newStore(t), watermarks{0: 50, 1: 10})
+	d.Refresh(context.Background())
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("page: %s %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+	lines := bufio.NewScanner(resp.Body)
+	next := func() Snapshot {
+		t.Helper()
+		for lines.Scan() {
+			if data, ok := strings.CutPrefix(lines.Text(), "data: "); ok {
+				var s Snapshot
+				if err := json.Unmarshal([]byte(data), &s); err != nil {
+					t.Fatal(err)
+				}
+				return s
+			}
+		}
+		t.Fatalf("stream ended: %v", lines.Err())
+		return Snapshot{}
+	}
+
+	// The latest snapshot comes first, then each new one
+	if s := next(); len(s.ActiveRides) != 2 {
+		t.Errorf("first snapshot has %d active rides, want 2", len(s.ActiveRides))
+	}
+	d.cfg.Now = func() time.Time { return now.Add(time.Minute) }
+	d.Refresh(context.Background())
+	if s := next(); !s.At.Equal(now.Add(time.Minute)) {
+		t.Errorf("second snapshot at %v, want a minute later", s.At)
+	}
+}
@@ -0,0 +1,96 @@
+// Package dashboard is the dashboard service, a live operations page: the
+// active rides on a map, events stored per minute, the last hour's
+// cancellation rate, the consumer group's lag, and the latest fraud alerts.
+// It reads them from the database the consumer keeps, every
+// DASHBOARD_REFRESH, and pushes them to the page over server-sent events.
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/rides_db"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+)
+
+const (
+	defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID        = "dashboard"
+)
+
+// configFromEnv reads the dashboard settings: DASHBOARD_REFRESH, how often
+// the page is updated (default 2s), and DASHBOARD_GROUP, the consumer group
+// whose lag is shown (default ride-consumer-group). Invalid values are logged
+// and left to the defaults.
+func configFromEnv() Config {
+	return Config{
+		Group:   os.Getenv("DASHBOARD_GROUP"),
+		Refresh: runtime.EnvDuration("DASHBOARD_REFRESH", defaultRefresh),
+	}
+}
+
+// Run runs the dashboard with args, the command line after the command's name, until
+// it is stopped by a signal.
+func Run(args []string) {
+	svc := runtime.Start("dashboard")
+	defer svc.Stop()
+	slog.Info("Starting dashboard")
+
+	store, err := rides_db.OpenFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	logger.OnShutdown("database", func(context.Context) error { return store.Close() })
+	svc.Check("database", store.Health)
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+	// The consumer subscribes to nothing; it only asks the brokers for the
+	// partitions' high watermarks
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          groupID,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error { return consumer.Close() })
+
+	ctx := svc.Context()
+
+	svc.Serve(":2120")
+
+	d := New(configFromEnv(), store, consumer)
+	go d.Run(ctx)
+
+	addr := runtime.EnvString("DASHBOARD_ADDR", ":8088")
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           d.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		// Event streams never end by themselves, so requests share ctx and
+		// the streams stop on the way out
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Dashboard shutdown failed", "error", err)
+		}
+	}()
+
+	slog.Info("Serving dashboard", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal("Dashboard stopped", "error", err)
+	}
+	slog.Info("Dashboard stopped")
+}
@@ -0,0 +1,70 @@
+// Package kafkaadmin is the kafka-admin command, which creates the Kafka
+// topics the ride services use, with the partitions, replication, retention,
+// and compaction of topics.SpecsFromEnv, and brings the configs of existing
+// topics in line. It runs once before the services start, so they do not
+// depend on the broker's auto-create defaults.
+package kafkaadmin
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+// Run sets up the topics as the flags in args say:
+//
+//	[-brokers B] [-dry-run] [-add-partitions] [-list]
+func Run(args []string) {
+	svc := runtime.Start("kafka-admin")
+	defer svc.Stop()
+
+	fs := flag.NewFlagSet("kafka-admin", flag.ExitOnError)
+	brokers := fs.String("brokers", runtime.EnvString("KAFKA_BROKERS", "redpanda:9092"), "Kafka brokers (default KAFKA_BROKERS, or redpanda:9092)")
+	dryRun := fs.Bool("dry-run", false, "report the changes without making them")
+	addPartitions := fs.Bool("add-partitions", false, "add partitions to topics with fewer than configured, which moves keys between partitions")
+	list := fs.Bool("list", false, "print the configured topics and exit")
+	fs.Parse(args)
+
+	specs, err := topics.SpecsFromEnv()
+	if err != nil {
+		logger.Fatal("Invalid topic settings", "error", err)
+	}
+	if *list {
+		for _, s := range specs {
+			fmt.Printf("%s\t%d partitions\treplication factor %d\t%v\n", s.Name, s.Partitions, s.ReplicationFactor, s.Config)
+		}
+		return
+	}
+
+	admin, err := kafka.NewAdminClient(&kafka.ConfigMap{"bootstrap.servers": *brokers})
+	if err != nil {
+		logger.Fatal("Failed to create admin client", "error", err)
+	}
+	logger.OnShutdown("kafka admin client", func(context.Context) error {
+		admin.Close()
+		return nil
+	})
+
+	changes, err := topics.Ensure(svc.Context(), admin, specs, topics.Options{DryRun: *dryRun, AddPartitions: *addPartitions})
+	for _, c := range changes {
+		switch {
+		case c.Kind == topics.ChangeMismatch:
+			slog.Warn("Topic differs from its settings and was left as is", "topic", c.Topic, "detail", c.Detail)
+		case c.Applied:
+			slog.Info("Changed topic", "topic", c.Topic, "change", c.Kind, "detail", c.Detail)
+		default:
+			slog.Info("Topic needs changing", "topic", c.Topic, "change", c.Kind, "detail", c.Detail, "dry_run", *dryRun)
+		}
+	}
+	if err != nil {
+		logger.Fatal("Failed to set up topics", "error", err)
+	}
+	slog.Info("Topics are set up", "topics", len(specs), "changes", len(changes))
+}
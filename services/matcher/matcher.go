@@ -0,0 +1,106 @@
+// Package matcher is the matcher service, which dispatches ride requests. It reads driver events to learn
+// where drivers are, and answers each REQUESTED ride event with an ACCEPTED
+// event for the nearest free driver, or a system cancellation when none is
+// within reach; see the matching package.
+package matcher
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/matching"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+const (
+	defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID        = "matcher"
+)
+
+// configFromEnv reads the matcher settings: MATCH_MAX_PICKUP_METERS, how far a
+// driver may be from the pickup; MATCH_DRIVER_STALE_AFTER, how long a driver
+// is matched after their last heartbeat; MATCH_OFFERS, whether requests are
+// offered to drivers on topics.DispatchOffers rather than accepted for them;
+// MATCH_OFFER_TIMEOUT, how long a driver has to answer an offer; and
+// MATCH_TRIP_IDLE_TIMEOUT, how long a trip is kept without an event. Invalid
+// values are logged and left to the defaults.
+func configFromEnv() matching.Config {
+	cfg := matching.Config{
+		RideTopic:         topics.RideEvents,
+		DriverTopic:       topics.DriverEvents,
+		MaxPickupDistance: runtime.EnvFloat("MATCH_MAX_PICKUP_METERS", matching.DefaultMaxPickupDistance),
+		StaleAfter:        runtime.EnvDuration("MATCH_DRIVER_STALE_AFTER", matching.DefaultStaleAfter),
+		OfferTimeout:      runtime.EnvDuration("MATCH_OFFER_TIMEOUT", matching.DefaultOfferTimeout),
+		TripIdleTimeout:   runtime.EnvDuration("MATCH_TRIP_IDLE_TIMEOUT", matching.DefaultTripIdleTimeout),
+	}
+	if runtime.EnvBool("MATCH_OFFERS", false) {
+		cfg.OfferTopic = topics.DispatchOffers
+	}
+	cfg.Instance, _ = os.Hostname()
+	return cfg
+}
+
+// Run runs the matcher with args, the command line after the command's name, until
+// it is stopped by a signal.
+func Run(args []string) {
+	svc := runtime.Start("matcher")
+	defer svc.Stop()
+	slog.Info("Starting matcher")
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+
+	// A new group starts from the latest requests; answering ones made before
+	// it first ran would cancel rides that have long since ended
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          groupID,
+		"auto.offset.reset": "latest",
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error { return consumer.Close() })
+	cfg := configFromEnv()
+	subscribed := []string{topics.RideEvents, topics.DriverEvents}
+	if cfg.OfferTopic != "" {
+		slog.Info("Offering rides to drivers", "topic", cfg.OfferTopic)
+		subscribed = append(subscribed, cfg.OfferTopic)
+	}
+	if err := consumer.SubscribeTopics(subscribed, nil); err != nil {
+		logger.Fatal("Failed to subscribe", "error", err)
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		logger.Fatal("Failed to create producer", "error", err)
+	}
+	logger.OnShutdown("kafka producer", func(context.Context) error {
+		if n := producer.Flush(5000); n > 0 {
+			slog.Warn("Answers left undelivered", "count", n)
+		}
+		producer.Close()
+		return nil
+	})
+	logger.Go("delivery reports", func() {
+		for e := range producer.Events() {
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				slog.Error("Delivery failed", "key", string(m.Key), "error", m.TopicPartition.Error)
+			}
+		}
+	})
+
+	svc.Serve(":2116")
+
+	ctx := svc.Context()
+
+	m := matching.New(cfg, matching.NewPool(), producer)
+	if err := m.Run(ctx, consumer); err != nil {
+		logger.Fatal("Matcher stopped", "error", err)
+	}
+	slog.Info("Matcher stopped")
+}
@@ -0,0 +1,87 @@
+// Package pricer is the pricer service, which prices rides. It answers each REQUESTED ride event with a
+// PRICE_QUOTE event at the surge of the pickup zone, and sends a fraud alert
+// for each completed ride charged a fare other than its distance and duration
+// come to; see the pricing package.
+package pricer
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+const (
+	defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID        = "pricer"
+)
+
+// configFromEnv reads the pricer settings: PRICING_FARE_TOLERANCE, the share
+// of the expected fare a charged fare may be off by before it is flagged.
+// Invalid values are logged and left to the defaults.
+func configFromEnv() pricing.Config {
+	cfg := pricing.Config{RideTopic: topics.RideEvents, FraudTopic: topics.FraudAlerts}
+	cfg.Tolerance = runtime.EnvFloat("PRICING_FARE_TOLERANCE", pricing.DefaultTolerance)
+	cfg.Instance, _ = os.Hostname()
+	return cfg
+}
+
+// Run runs the pricer with args, the command line after the command's name, until
+// it is stopped by a signal.
+func Run(args []string) {
+	svc := runtime.Start("pricer")
+	defer svc.Stop()
+	slog.Info("Starting pricer")
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+
+	// A new group starts from the latest requests, as quoting ones made before
+	// it first ran would be of no use; until the next surge updates, it prices
+	// every zone without surge
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          groupID,
+		"auto.offset.reset": "latest",
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error { return consumer.Close() })
+	if err := consumer.Subscribe(topics.RideEvents, nil); err != nil {
+		logger.Fatal("Failed to subscribe", "error", err)
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		logger.Fatal("Failed to create producer", "error", err)
+	}
+	logger.OnShutdown("kafka producer", func(context.Context) error {
+		if n := producer.Flush(5000); n > 0 {
+			slog.Warn("Quotes and alerts left undelivered", "count", n)
+		}
+		producer.Close()
+		return nil
+	})
+	logger.Go("delivery reports", func() {
+		for e := range producer.Events() {
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				slog.Error("Delivery failed", "key", string(m.Key), "error", m.TopicPartition.Error)
+			}
+		}
+	})
+
+	svc.Serve(":2117")
+
+	ctx := svc.Context()
+
+	if err := pricing.New(configFromEnv(), producer).Run(ctx, consumer); err != nil {
+		logger.Fatal("Pricer stopped", "error", err)
+	}
+	slog.Info("Pricer stopped")
+}
@@ -0,0 +1,456 @@
+// Package producer is the producer service, which simulates rides from
+// request to completion or cancellation and sends their events to
+// ride-events.
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/google/uuid"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+	"github.com/pedeveaux/kafkarideshare/ridesim"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/schemaregistry"
+)
+
+// transitions defines the state transitions for the ride lifecycle.
+// It maps the current state to a map of valid events and their resulting states.
+// The keys of the outer map are the current states, and the values are maps
+// where the keys are the events and the values are the resulting states.
+var transitions = map[events.RideState]map[events.RideEventType]events.RideState{
+	events.StateRequested: {
+		events.EventRideAccepted:  events.StateAccepted,
+		events.EventTripCancelled: events.StateCancelled,
+	},
+	events.StateAccepted: {
+		events.EventTripStarted:   events.StateInProgress,
+		events.EventTripCancelled: events.StateCancelled,
+	},
+	events.StateInProgress: {
+		events.EventTripCancelled: events.StateCancelled,
+		events.EventTripCompleted: events.StateCompleted,
+	},
+}
+
+// FSM represents a finite state machine for the ride lifecycle.
+// It manages the current state and applies events to transition between states.
+// It also provides a method to check if the current state is terminal.
+// The FSM is initialized with a starting state and can transition to other states
+// based on the defined transitions.
+type FSM struct {
+	State events.RideState
+}
+
+// Apply applies an event to the FSM and transitions to the new state.
+// It checks if the event is valid for the current state and updates the state accordingly.
+// If the event is not valid, it returns an error.
+func (f *FSM) Apply(event events.RideEventType) error {
+	valid, ok := transitions[f.State]
+	if !ok {
+		return fmt.Errorf("no transitions defined for state %s", f.State)
+	}
+	newState, ok := valid[event]
+	if !ok {
+		return fmt.Errorf("event %s not valid from state %s", event, f.State)
+	}
+	f.State = newState
+	return nil
+}
+
+// IsTerminal checks if the current state is a terminal state.
+// Terminal states are those where no further transitions are possible.
+// In this case, the terminal states are StateCompleted and StateCancelled.
+// The method returns true if the current state is terminal, and false otherwise.
+func (f *FSM) IsTerminal() bool {
+	return f.State == events.StateCompleted || f.State == events.StateCancelled
+}
+
+// IsCancelable checks if the current state allows for cancellation.
+// A ride can be cancelled if it is in the Requested or Accepted state.
+func (f *FSM) IsCancelable() bool {
+	return f.State == events.StateRequested || f.State == events.StateAccepted
+}
+
+// Ride represents a ride in the rideshare application.
+// It contains the trip ID, driver ID, rider ID, the city it takes place in (empty
+// outside multi-city mode), and the FSM for managing the ride's state.
+// The ride also has an updated timestamp to track the last time it was modified,
+// the ID of its last event, which caused the next, its route from pickup to
+// dropoff, and the surge multiplier of its pickup zone when it was requested,
+// which its fare is charged at.
+type Ride struct {
+	TripID      string
+	DriverID    string
+	PassengerID string
+	City        string
+	FSM         FSM
+	UpdatedAt   time.Time
+	LastEventID string
+	Route       events.Route
+	Multiplier  float64
+}
+
+// instance names this producer in the meta of its events.
+var instance string
+
+// eventOptions returns the options that give an event of the ride its driver,
+// passenger, city, and time, and meta correlating it with the ride's other events.
+func (r *Ride) eventOptions(now time.Time) []events.Option {
+	return []events.Option{
+		events.WithTime(now),
+		events.WithDriver(r.DriverID),
+		events.WithPassenger(r.PassengerID),
+		events.WithCity(r.City),
+		events.WithMeta(events.Meta{CorrelationID: r.TripID, CausationID: r.LastEventID, ProducerInstance: instance}),
+	}
+}
+
+// getNextEvent generates the next event for a given ride.
+// It simulates the ride lifecycle by applying the next event based on the current state.
+// The method also handles the case where a ride is cancelled with a 10% chance.
+// If the ride is cancelled, it creates a cancellation event and updates the ride's state.
+// The method returns the generated event and any error encountered during the process.
+// The event contains the trip ID, driver ID, rider ID, event type, state, timestamp,
+// and any additional payload data specific to the event type.
+// The payload can be of different types depending on the event type.
+// The method uses a random number generator to simulate the cancellation event.
+// The ride's updated timestamp is also set to the current time.
+func getNextEvent(ride *Ride) (events.RideEvent, error) {
+	now := time.Now()
+
+	// Simulate cancellation with 10% chance when not terminal
+	if !ride.FSM.IsTerminal() && rand.Float64() < 0.1 && ride.FSM.IsCancelable() {
+		err := ride.FSM.Apply(events.EventTripCancelled)
+		if err != nil {
+			return events.RideEvent{}, err
+		}
+		evt := events.NewTripCancelled(ride.TripID, events.ActorPassenger, events.ReasonPassengerNoShow, ride.eventOptions(now)...)
+		ride.UpdatedAt = now
+		ride.LastEventID = evt.ID
+		return evt, nil
+	}
+
+	var next events.RideEventType
+	// Determine the next event based on the current state
+	// and the defined transitions
+	switch ride.FSM.State {
+	case events.StateRequested:
+		next = events.EventRideAccepted
+	case events.StateAccepted:
+		next = events.EventTripStarted
+	case events.StateInProgress:
+		next = events.EventTripCompleted
+	default:
+		return events.RideEvent{}, nil // terminal or unknown state
+	}
+
+	err := ride.FSM.Apply(next)
+	if err != nil {
+		return events.RideEvent{}, err
+	}
+
+	// Build the event of that type, with its payload
+	var evt events.RideEvent
+	opts := ride.eventOptions(now)
+	switch next {
+	case events.EventRideAccepted:
+		evt = events.NewRideAccepted(ride.TripID, ride.DriverID, opts...)
+	case events.EventTripStarted:
+		evt = events.NewTripStarted(ride.TripID, opts...)
+	case events.EventTripCompleted:
+		distance := ridesim.TripDistance(ride.Route)
+		evt = events.NewTripCompleted(ride.TripID, distance, ridesim.Fare(distance, ride.Multiplier), opts...)
+	}
+
+	ride.UpdatedAt = now
+	ride.LastEventID = evt.ID
+	return evt, nil
+}
+
+// MessageSink is where the producer sends ride events. *kafka.Producer
+// satisfies it, and so does ridetest.Broker in tests.
+type MessageSink interface {
+	Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error
+}
+
+// marshalEvent encodes the events publish sends; see encodingFromEnv.
+var marshalEvent = func(e events.RideEvent) ([]byte, error) { return json.Marshal(e) }
+
+// encodingFromEnv sets how events are encoded from EVENT_ENCODING: json, the
+// default, or avro, framed with the ID events.RideEventAvroSchema has in the
+// Schema Registry at SCHEMA_REGISTRY_URL. With a registry, the schema is
+// registered under the topic's subject whatever the encoding, so a producer
+// built with a schema that breaks the subject's compatibility level fails to
+// start rather than sending events consumers cannot read.
+func encodingFromEnv(ctx context.Context, topic string) error {
+	encoding := strings.ToLower(os.Getenv("EVENT_ENCODING"))
+	if encoding != "" && encoding != "json" && encoding != "avro" {
+		return fmt.Errorf("EVENT_ENCODING must be json or avro, not %q", encoding)
+	}
+	cfg, err := schemaregistry.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	if cfg.URL == "" {
+		if encoding == "avro" {
+			return errors.New("EVENT_ENCODING=avro needs SCHEMA_REGISTRY_URL")
+		}
+		return nil
+	}
+	client, err := schemaregistry.New(cfg)
+	if err != nil {
+		return err
+	}
+	serializer, err := schemaregistry.NewRideEventSerializer(client, topic)
+	if err != nil {
+		return err
+	}
+	slog.Info("Registered the ride event schema", "subject", schemaregistry.Subject(topic), "id", serializer.ID())
+	if encoding == "avro" {
+		marshalEvent = serializer.Marshal
+	}
+	return nil
+}
+
+// publish validates evt and produces it to topic, keyed by its trip. Events
+// that fail validation are logged and dropped rather than sent.
+func publish(producer MessageSink, topic string, evt events.RideEvent) {
+	if err := evt.Validate(); err != nil {
+		slog.Error("Refusing to send invalid event", "error", err, "tripID", evt.TripID, "type", evt.Type)
+		return
+	}
+	bytes, err := marshalEvent(evt)
+	if err != nil {
+		slog.Error("Failed to marshal event", "error", err, "tripID", evt.TripID)
+		return
+	}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(evt.TripID),
+		Value:          bytes,
+	}
+	for k, v := range evt.Meta.Headers() {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	if err := producer.Produce(msg, nil); err != nil {
+		slog.Error("Failed to produce event", "error", err, "tripID", evt.TripID, "type", evt.Type)
+	}
+}
+
+// followSurges applies the SURGE_UPDATED events read from src to surges until
+// ctx is cancelled. Other events, the producer's own among them, are skipped.
+func followSurges(ctx context.Context, src rideconsumer.Source, surges *pricing.Surges) {
+	for ctx.Err() == nil {
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if !errors.As(err, &kerr) || kerr.Code() != kafka.ErrTimedOut {
+				slog.Error("Consumer error", "error", err)
+			}
+			continue
+		}
+		if schemaregistry.IsFramed(msg.Value) {
+			// Surge updates are JSON, so Avro events are the producer's own
+			continue
+		}
+		var e events.RideEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+			continue
+		}
+		surges.Apply(e)
+	}
+}
+
+// runFromEnv returns how many rides to simulate, from MAX_RIDES, and how often
+// rides advance, from TICK_INTERVAL. With a limit, the producer stops once
+// that many rides have ended, as tests and demos need; 0, the default, runs
+// until it is stopped. Both are read again when the config file changes them.
+func runFromEnv() (maxRides int, tick time.Duration) {
+	if raw := runtime.EnvString("MAX_RIDES", ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			slog.Warn("Invalid MAX_RIDES, running until stopped", "value", raw)
+		} else {
+			maxRides = n
+		}
+	}
+	return maxRides, runtime.EnvDuration("TICK_INTERVAL", time.Second)
+}
+
+// citiesFromEnv returns the cities listed in CITIES (comma-separated). When
+// any are given the producer runs in multi-city mode and spreads rides across them.
+func citiesFromEnv() []string {
+	return runtime.EnvList("CITIES")
+}
+
+// Run runs the producer with args, the command line after the command's name, until
+// it is stopped by a signal.
+func Run(args []string) {
+	// Delivery reports come once per message; LOG_SAMPLE overrides this
+	logger.SetSampleRate("Delivery successful", 100)
+	svc := runtime.Start("producer")
+	defer svc.Stop()
+	slog.Info("Starting ride producer")
+	instance, _ = os.Hostname()
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", "redpanda:9092")
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		logger.Fatal("Failed to create producer", "error", err)
+	}
+	logger.OnShutdown("kafka producer", func(context.Context) error {
+		if n := producer.Flush(5000); n > 0 {
+			slog.Warn("Ride events left undelivered", "count", n)
+		}
+		producer.Close()
+		return nil
+	})
+
+	// A panic here would stop delivery reports being drained, and with them
+	// the producer, so it is recovered and the loop restarted
+	logger.Go("delivery reports", func() {
+		for e := range producer.Events() {
+			switch ev := e.(type) {
+			case *kafka.Message:
+				if ev.TopicPartition.Error != nil {
+					slog.Error("Delivery failed", "key", ev.Key, "topic partition", ev.TopicPartition.Partition, "error", ev.TopicPartition.Error)
+				} else {
+					slog.Info("Delivery successful", "key", ev.Key, "topic partition", ev.TopicPartition.Partition)
+				}
+			}
+		}
+	})
+	// Initialize the ride events topic and active rides map
+	// and start the ticker for generating ride events.
+	topic := "ride-events"
+	registerCtx, cancelRegister := context.WithTimeout(context.Background(), 30*time.Second)
+	err = encodingFromEnv(registerCtx, topic)
+	cancelRegister()
+	if err != nil {
+		logger.Fatal("Failed to set up the event encoding", "error", err)
+	}
+	activeRides := make(map[string]*Ride)
+	cities := citiesFromEnv()
+	if len(cities) > 0 {
+		slog.Info("Simulating multiple cities", "cities", cities)
+	}
+	maxRides, tick := runFromEnv()
+	requested := 0
+	ticker := time.NewTicker(tick)
+	// Changes from the config file are applied between ticks, by the loop
+	reload := make(chan struct{}, 1)
+	svc.OnChange(func() {
+		select {
+		case reload <- struct{}{}:
+		default:
+		}
+	}, "MAX_RIDES", "TICK_INTERVAL")
+
+	// The context is cancelled on SIGINT or SIGTERM, which stops the ticker
+	// and flushes the producer on the way out
+	ctx := svc.Context()
+	svc.Serve(":2127")
+
+	// Rides are charged at the surge of their pickup zone when requested, as
+	// the surge updater sets it on the ride topic. A new group starts from the
+	// latest updates, and until a zone's next one its rides are charged at 1x
+	surges := pricing.NewSurges()
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          "producer",
+		"auto.offset.reset": "latest",
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	if err := consumer.Subscribe(topic, nil); err != nil {
+		logger.Fatal("Failed to subscribe", "error", err)
+	}
+	surgesDone := make(chan struct{})
+	go func() {
+		defer close(surgesDone)
+		followSurges(ctx, consumer, surges)
+	}()
+	logger.OnShutdown("kafka consumer", func(context.Context) error {
+		<-surgesDone
+		return consumer.Close()
+	})
+
+loop:
+	for {
+		select {
+		// Generate a new ride request every tick if there are fewer than 100 active
+		// rides, until MAX_RIDES have been requested.
+		case <-ticker.C:
+			if maxRides > 0 && requested >= maxRides && len(activeRides) == 0 {
+				slog.Info("Simulated all rides", "rides", requested)
+				break loop
+			}
+			if len(activeRides) < 100 && (maxRides == 0 || requested < maxRides) {
+				requested++
+				tripID := uuid.NewString()
+				var city string
+				if len(cities) > 0 {
+					city = cities[rand.Intn(len(cities))]
+				}
+				ride := &Ride{
+					TripID:      tripID,
+					DriverID:    uuid.NewString(),
+					PassengerID: uuid.NewString(),
+					City:        city,
+					FSM:         FSM{State: events.StateRequested},
+					UpdatedAt:   time.Now(),
+					Route:       events.Route{ridesim.RandomCoordinate(), ridesim.RandomCoordinate()},
+				}
+				ride.Multiplier = surges.Multiplier(pricing.ZoneOf(ride.Route[0]))
+				activeRides[tripID] = ride
+				opts := append(ride.eventOptions(ride.UpdatedAt), events.WithCoordinates(&ride.Route[0], &ride.Route[1]))
+				evt := events.NewRideRequested(ride.TripID, ride.PassengerID, gofakeit.Street(), gofakeit.Street(), opts...)
+				ride.LastEventID = evt.ID
+				publish(producer, topic, evt)
+			}
+			// Process each active ride to generate the next event.
+			for tripID, ride := range activeRides {
+				event, err := getNextEvent(ride)
+				if err != nil {
+					slog.Error("Ride Error", "error", err, "tripID", tripID)
+					delete(activeRides, tripID)
+					continue
+				}
+				if event.Type == "" || event.TripID == "" {
+					slog.Warn("Skipping empty event", "tripID", tripID, "eventType", event.Type)
+					continue
+				}
+				publish(producer, topic, event)
+
+				if ride.FSM.IsTerminal() {
+					delete(activeRides, tripID)
+				}
+			}
+		case <-reload:
+			maxRides, tick = runFromEnv()
+			ticker.Reset(tick)
+			slog.Info("Applied new ride settings", "max_rides", maxRides, "tick_interval", tick.String())
+		// Handle OS signals for graceful shutdown.
+		case <-ctx.Done():
+			slog.Info("Shutting down via context cancel")
+			break loop
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/ridesim"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+func TestPublish_SimulatedRide(t *testing.T) {
+	broker := ridetest.NewBroker()
+	ride := &Ride{
+		TripID:      "3f0c5a9e-8d1b-4e6a-b2c7-9a0d1e2f3b4c",
+		DriverID:    "driver-1",
+		PassengerID: "rider-1",
+		FSM:         FSM{State: events.StateRequested},
+		UpdatedAt:   time.Now(),
+		Route:       events.Route{ridesim.RandomCoordinate(), ridesim.RandomCoordinate()},
+	}
+	requested := events.NewRideRequested(ride.TripID, ride.PassengerID, "Main St", "Elm St",
+		append(ride.eventOptions(ride.UpdatedAt), events.WithCoordinates(&ride.Route[0], &ride.Route[1]))...)
+	ride.LastEventID = requested.ID
+	publish(broker, "ride-events", requested)
+	for !ride.FSM.IsTerminal() {
+		evt, err := getNextEvent(ride)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publish(broker, "ride-events", evt)
+	}
+
+	// A refused message is logged and dropped
+	broker.FailProduce(errors.New("queue full"))
+	publish(broker, "ride-events", requested)
+
+	evts, err := broker.Events("ride-events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evts) < 2 || evts[0].Type != events.EventRideRequested {
+		t.Fatalf("got %d events, want a request and its outcome", len(evts))
+	}
+	for i, e := range evts {
+		if err := e.Validate(); err != nil {
+			t.Errorf("event %d: %v", i, err)
+		}
+		if i > 0 && e.Meta.CausationID != evts[i-1].ID {
+			t.Errorf("event %d is not caused by the one before it", i)
+		}
+	}
+	if final := evts[len(evts)-1].State; final != ride.FSM.State {
+		t.Errorf("last event in state %s, want %s", final, ride.FSM.State)
+	}
+}
+
+func TestFollowSurges(t *testing.T) {
+	broker := ridetest.NewBroker()
+	surges := pricing.NewSurges()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		followSurges(ctx, broker.Source("ride-events"), surges)
+	}()
+
+	pickup := events.Coordinate{Lat: 40.75, Lng: -73.99}
+	zone := pricing.ZoneOf(pickup)
+	publish(broker, "ride-events", events.NewRideRequested("trip-1", "rider-1", "Main St", "Elm St"))
+	surge, _ := json.Marshal(events.NewSurgeUpdated(zone, 2))
+	broker.Publish("ride-events", []byte(zone), surge)
+	for deadline := time.Now().Add(5 * time.Second); surges.Multiplier(zone) != 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if m := surges.Multiplier(zone); m != 2 {
+		t.Fatalf("multiplier = %v, want 2", m)
+	}
+
+	// A ride requested in the zone is charged at its surge; rides in progress
+	// are never cancelled, so the next event completes it
+	ride := &Ride{TripID: "trip-2", DriverID: "driver-1", PassengerID: "rider-1", FSM: FSM{State: events.StateInProgress},
+		Route: events.Route{pickup, {Lat: 40.76, Lng: -73.99}}, Multiplier: surges.Multiplier(zone)}
+	evt, err := getNextEvent(ride)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := events.PayloadAs[events.RideCompletedPayload](evt)
+	if want := ridesim.Fare(p.DistanceKM, 2); p.Fare != want {
+		t.Errorf("fare = %v, want %v", p.Fare, want)
+	}
+}
+
+func TestEncodingFromEnv(t *testing.T) {
+	t.Setenv("SCHEMA_REGISTRY_URL", "")
+	for _, tt := range []struct {
+		encoding string
+		wantErr  bool
+	}{
+		{"", false},
+		{"json", false},
+		{"avro", true}, // without a registry
+		{"xml", true},
+	} {
+		t.Setenv("EVENT_ENCODING", tt.encoding)
+		if err := encodingFromEnv(context.Background(), "ride-events"); (err != nil) != tt.wantErr {
+			t.Errorf("EVENT_ENCODING=%s: error %v, want error %v", tt.encoding, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Command state-processor keeps the ride state and driver stats tables of the
+// processors package, restoring them from their compacted changelogs rather
+// than from Postgres, and serves their rows over HTTP on STATE_ADDR:
+// GET /rides/{id} and GET /drivers/{id}. Its processors run in Kafka
+// transactions unless PROCESSOR_TRANSACTIONS is false, so the tables and the
+// trip metrics of ride-aggregates count each event exactly once.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/processors"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+const defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+
+// transactionsFromEnv reads PROCESSOR_TRANSACTIONS, whether the processors
+// run in transactions (default true), and PROCESSOR_COMMIT_INTERVAL, how
+// long each transaction collects input. Invalid values are logged and left
+// to the defaults.
+func transactionsFromEnv() (bool, time.Duration) {
+	return runtime.EnvBool("PROCESSOR_TRANSACTIONS", true),
+		runtime.EnvDuration("PROCESSOR_COMMIT_INTERVAL", processors.DefaultCommitInterval)
+}
+
+func main() {
+	svc := runtime.Start("state-processor")
+	defer svc.Stop()
+	slog.Info("Starting state processor")
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+
+	transactional, commitInterval := transactionsFromEnv()
+	slog.Info("Configured processors", "transactional", transactional, "commit_interval", commitInterval)
+
+	rides := processors.NewRideStateTable()
+	rideState, err := processors.New(processors.Config{
+		Name:           processors.RideStateName,
+		Brokers:        brokers,
+		Input:          []string{topics.RideEvents},
+		Tables:         []processors.StateTable{rides},
+		Handler:        processors.RideStateHandler(rides),
+		Transactional:  transactional,
+		CommitInterval: commitInterval,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create ride state processor", "error", err)
+	}
+	logger.OnShutdown("ride state processor", func(context.Context) error { rideState.Close(); return nil })
+
+	drivers := processors.NewDriverStatsTable()
+	driverStats, err := processors.New(processors.Config{
+		Name:           processors.DriverStatsName,
+		Brokers:        brokers,
+		Input:          []string{topics.RideEventsByDriver},
+		Tables:         []processors.StateTable{drivers},
+		Handler:        processors.DriverStatsHandler(drivers),
+		Transactional:  transactional,
+		CommitInterval: commitInterval,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create driver stats processor", "error", err)
+	}
+	logger.OnShutdown("driver stats processor", func(context.Context) error { driverStats.Close(); return nil })
+
+	svc.Serve(":2125")
+
+	ctx := svc.Context()
+
+	addr := runtime.EnvString("STATE_ADDR", ":8091")
+	mux := http.NewServeMux()
+	mux.Handle("GET /rides/{id}", rowHandler(rides))
+	mux.Handle("GET /drivers/{id}", rowHandler(drivers))
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("State API shutdown failed", "error", err)
+		}
+	}()
+	go func() {
+		slog.Info("Serving state tables", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("State API stopped", "error", err)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, p := range []*processors.Processor{rideState, driverStats} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Run(ctx); err != nil {
+				logger.Fatal("Processor stopped", "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+	slog.Info("State processor stopped")
+}
+
+// rowHandler serves the row of the path's id as JSON, or 404 when the table
+// has none.
+func rowHandler[V any](t *processors.Table[V]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, ok := t.Get(r.PathValue("id"))
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			slog.Error("Failed to write row", "table", t.Topic(), "error", err)
+		}
+	})
+}
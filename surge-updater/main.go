@@ -0,0 +1,98 @@
+// Command surge-updater sets surge pricing from live supply and demand. It
+// counts ride requests and free drivers in each pricing zone over a sliding
+// window, and sends a SURGE_UPDATED event to the ride topic whenever a zone's
+// multiplier changes, for the pricer, producer, and driversim to charge; see
+// the surge package.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/pedeveaux/kafkarideshare/logger"
+	"github.com/pedeveaux/kafkarideshare/runtime"
+	"github.com/pedeveaux/kafkarideshare/surge"
+	"github.com/pedeveaux/kafkarideshare/topics"
+)
+
+const (
+	defaultBrokers = "redpanda:9092" // unless KAFKA_BROKERS is set
+	groupID        = "surge-updater"
+)
+
+// configFromEnv reads the updater settings: SURGE_WINDOW, how far back
+// requests and driver reports count; SURGE_INTERVAL, how often multipliers
+// are recomputed; and SURGE_MAX_MULTIPLIER, the highest multiplier. Invalid
+// values are logged and left to the defaults.
+func configFromEnv() surge.Config {
+	cfg := surge.Config{
+		RideTopic:   topics.RideEvents,
+		DriverTopic: topics.DriverEvents,
+		Window:      runtime.EnvDuration("SURGE_WINDOW", surge.DefaultWindow),
+		Interval:    runtime.EnvDuration("SURGE_INTERVAL", surge.DefaultInterval),
+	}
+	if raw := os.Getenv("SURGE_MAX_MULTIPLIER"); raw != "" {
+		if f, err := strconv.ParseFloat(raw, 64); err != nil || f <= 1 {
+			slog.Warn("Invalid SURGE_MAX_MULTIPLIER, using default", "value", raw, "default", surge.DefaultMaxMultiplier)
+		} else {
+			cfg.MaxMultiplier = f
+		}
+	}
+	cfg.Instance, _ = os.Hostname()
+	return cfg
+}
+
+func main() {
+	svc := runtime.Start("surge-updater")
+	defer svc.Stop()
+	slog.Info("Starting surge updater")
+
+	brokers := runtime.EnvString("KAFKA_BROKERS", defaultBrokers)
+
+	// A new group starts from the latest events; its window fills within
+	// SURGE_WINDOW, and until then zones surge less than they should
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": brokers,
+		"group.id":          groupID,
+		"auto.offset.reset": "latest",
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consumer", "error", err)
+	}
+	logger.OnShutdown("kafka consumer", func(context.Context) error { return consumer.Close() })
+	if err := consumer.SubscribeTopics([]string{topics.RideEvents, topics.DriverEvents}, nil); err != nil {
+		logger.Fatal("Failed to subscribe", "error", err)
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		logger.Fatal("Failed to create producer", "error", err)
+	}
+	logger.OnShutdown("kafka producer", func(context.Context) error {
+		if n := producer.Flush(5000); n > 0 {
+			slog.Warn("Surge updates left undelivered", "count", n)
+		}
+		producer.Close()
+		return nil
+	})
+	logger.Go("delivery reports", func() {
+		for e := range producer.Events() {
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				slog.Error("Delivery failed", "key", string(m.Key), "error", m.TopicPartition.Error)
+			}
+		}
+	})
+
+	svc.Serve(":2119")
+
+	ctx := svc.Context()
+
+	if err := surge.New(configFromEnv(), producer).Run(ctx, consumer); err != nil {
+		logger.Fatal("Surge updater stopped", "error", err)
+	}
+	slog.Info("Surge updater stopped")
+}
@@ -0,0 +1,348 @@
+// Package surge sets the surge multiplier of each pricing zone from live
+// supply and demand. An Updater counts the ride requests picked up in each
+// zone and the free drivers in it over a sliding window, and publishes a
+// SURGE_UPDATED event whenever a zone's multiplier changes. Zones are those
+// of pricing.ZoneOf, so the pricer, producer, and driversim charge the
+// multipliers it sets.
+package surge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+var (
+	updatesSent = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "surge_updates_total",
+		Help: "Number of SURGE_UPDATED events sent.",
+	})
+
+	zonesSurging = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "surge_zones_surging",
+		Help: "Number of zones with a multiplier above 1, as of the last update.",
+	})
+)
+
+// Defaults of Config.
+const (
+	DefaultRideTopic     = "ride-events"
+	DefaultDriverTopic   = "driver-events"
+	DefaultWindow        = 5 * time.Minute
+	DefaultInterval      = 30 * time.Second
+	DefaultMaxMultiplier = 3.0
+)
+
+// Config configures an Updater. Zero values take the defaults.
+type Config struct {
+	RideTopic   string
+	DriverTopic string
+	// Window is how far back requests are counted as demand, and how recently
+	// a free driver must have reported to count as supply.
+	Window time.Duration
+	// Interval is how often multipliers are recomputed and changes sent.
+	Interval time.Duration
+	// MaxMultiplier caps the multiplier, and is that of a zone with demand
+	// and no supply.
+	MaxMultiplier float64
+	// Instance names this updater in the meta of the events it sends.
+	Instance string
+	// Now returns the current time; nil uses time.Now.
+	Now func() time.Time
+}
+
+func (c *Config) setDefaults() {
+	if c.RideTopic == "" {
+		c.RideTopic = DefaultRideTopic
+	}
+	if c.DriverTopic == "" {
+		c.DriverTopic = DefaultDriverTopic
+	}
+	if c.Window <= 0 {
+		c.Window = DefaultWindow
+	}
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.MaxMultiplier <= 1 {
+		c.MaxMultiplier = DefaultMaxMultiplier
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+// Multiplier returns the multiplier of a zone with demand requests and supply
+// free drivers: demand over supply, rounded down to a tenth, at least 1 and
+// at most maxMultiplier.
+func Multiplier(demand, supply int, maxMultiplier float64) float64 {
+	switch {
+	case demand == 0:
+		return 1
+	case supply == 0:
+		return maxMultiplier
+	}
+	m := math.Floor(float64(demand)/float64(supply)*10+1e-9) / 10
+	return min(max(m, 1), maxMultiplier)
+}
+
+// Updater reads ride requests from the ride topic and driver events from the
+// driver topic, and every Interval sends a SURGE_UPDATED event to the ride
+// topic for each zone whose multiplier changed, including zones whose surge
+// ended, at 1.
+type Updater struct {
+	cfg  Config
+	sink rideconsumer.Sink
+
+	mu sync.Mutex
+	// requests holds the time of each request in the window, by pickup zone
+	// and trip, so a redelivered request counts once
+	requests map[events.ZoneID]map[string]time.Time
+	drivers  map[string]*driver
+	// sent holds the multiplier last sent for each zone above 1
+	sent map[events.ZoneID]float64
+	next time.Time // when multipliers are next recomputed
+}
+
+// driver is where a driver last reported being, and whether they were free.
+type driver struct {
+	zone      events.ZoneID
+	available bool
+	at        time.Time
+}
+
+// New returns an Updater that sends its SURGE_UPDATED events to sink.
+func New(cfg Config, sink rideconsumer.Sink) *Updater {
+	cfg.setDefaults()
+	return &Updater{cfg: cfg, sink: sink, requests: make(map[events.ZoneID]map[string]time.Time),
+		drivers: make(map[string]*driver), sent: make(map[events.ZoneID]float64)}
+}
+
+// Run handles messages from src, subscribed to the ride and the driver topic,
+// and sends the multipliers that changed every Interval, until ctx is
+// cancelled.
+func (u *Updater) Run(ctx context.Context, src rideconsumer.Source) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if now := u.cfg.Now(); !now.Before(u.next) {
+			u.next = now.Add(u.cfg.Interval)
+			if err := u.Update(); err != nil {
+				slog.Error("Failed to send surge updates", "error", err)
+			}
+		}
+
+		msg, err := src.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			slog.Error("Consumer error", "error", err)
+			continue
+		}
+		if err := u.Handle(msg); err != nil {
+			slog.Error("Failed to handle message", "topic", *msg.TopicPartition.Topic,
+				"key", string(msg.Key), "offset", msg.TopicPartition.Offset, "error", err)
+		}
+	}
+}
+
+// Handle processes one message from the ride or the driver topic. Messages
+// that cannot be decoded are logged and skipped.
+func (u *Updater) Handle(msg *kafka.Message) error {
+	switch topic := *msg.TopicPartition.Topic; topic {
+	case u.cfg.DriverTopic:
+		var e events.DriverEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable driver event", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		u.observeDriver(e)
+		return nil
+	case u.cfg.RideTopic:
+		var e events.RideEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			slog.Warn("Skipping undecodable ride event", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		u.observeRide(e)
+		return nil
+	default:
+		return fmt.Errorf("surge: message from unexpected topic %q", topic)
+	}
+}
+
+// observeRide counts a request as demand in its pickup zone. Requests without
+// a pickup coordinate have no zone, and other events are ignored.
+func (u *Updater) observeRide(e events.RideEvent) {
+	p, ok := events.PayloadAs[events.RideRequestedPayload](e)
+	if !ok || p.Pickup == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	zone := pricing.ZoneOf(*p.Pickup)
+	trips, ok := u.requests[zone]
+	if !ok {
+		trips = make(map[string]time.Time)
+		u.requests[zone] = trips
+	}
+	if _, ok := trips[e.TripID]; !ok {
+		trips[e.TripID] = e.OccurredAt
+	}
+}
+
+// observeDriver moves a driver to the zone they reported from. Events older
+// than the driver's last report are ignored.
+func (u *Updater) observeDriver(e events.DriverEvent) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	d, ok := u.drivers[e.DriverID]
+	if ok && e.OccurredAt.Before(d.at) {
+		return
+	}
+	switch p := e.Payload.(type) {
+	case events.ShiftStartedPayload:
+		u.drivers[e.DriverID] = &driver{zone: pricing.ZoneOf(p.Location), available: true, at: e.OccurredAt}
+	case events.HeartbeatPayload:
+		u.drivers[e.DriverID] = &driver{zone: pricing.ZoneOf(p.Location), available: p.Available, at: e.OccurredAt}
+	case events.RelocatedPayload:
+		if ok {
+			d.zone, d.at = pricing.ZoneOf(p.To), e.OccurredAt
+		}
+	case events.ShiftEndedPayload:
+		delete(u.drivers, e.DriverID)
+	}
+}
+
+// Multipliers returns the multiplier of every zone with demand or supply in
+// the window ending now, and forgets requests and drivers older than it.
+func (u *Updater) Multipliers() map[events.ZoneID]float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.multipliers()
+}
+
+// multipliers is Multipliers with u.mu held.
+func (u *Updater) multipliers() map[events.ZoneID]float64 {
+	since := u.cfg.Now().Add(-u.cfg.Window)
+	demand := make(map[events.ZoneID]int)
+	for zone, trips := range u.requests {
+		for tripID, at := range trips {
+			if at.Before(since) {
+				delete(trips, tripID)
+			}
+		}
+		if len(trips) == 0 {
+			delete(u.requests, zone)
+			continue
+		}
+		demand[zone] = len(trips)
+	}
+	supply := make(map[events.ZoneID]int)
+	for id, d := range u.drivers {
+		switch {
+		case d.at.Before(since):
+			delete(u.drivers, id) // stopped reporting
+		case d.available:
+			supply[d.zone]++
+		}
+	}
+
+	out := make(map[events.ZoneID]float64, len(demand)+len(supply))
+	for zone, n := range demand {
+		out[zone] = Multiplier(n, supply[zone], u.cfg.MaxMultiplier)
+	}
+	for zone := range supply {
+		if _, ok := out[zone]; !ok {
+			out[zone] = 1
+		}
+	}
+	return out
+}
+
+// Update recomputes the multipliers, and sends a SURGE_UPDATED event for each
+// zone whose multiplier differs from the one last sent for it.
+func (u *Updater) Update() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	current := u.multipliers()
+	changed := make(map[events.ZoneID]float64)
+	for zone, m := range current {
+		if m != max(u.sent[zone], 1) {
+			changed[zone] = m
+		}
+	}
+	for zone := range u.sent {
+		if _, ok := current[zone]; !ok {
+			changed[zone] = 1 // no demand or supply left
+		}
+	}
+
+	zones := make([]events.ZoneID, 0, len(changed))
+	for zone := range changed {
+		zones = append(zones, zone)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i] < zones[j] })
+	var errs []error
+	for _, zone := range zones {
+		m := changed[zone]
+		if err := u.publish(zone, m); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if m > 1 {
+			u.sent[zone] = m
+		} else {
+			delete(u.sent, zone)
+		}
+	}
+	zonesSurging.Set(float64(len(u.sent)))
+	return errors.Join(errs...)
+}
+
+// publish sends the SURGE_UPDATED event of zone to the ride topic, keyed by
+// the zone so its updates stay in order.
+func (u *Updater) publish(zone events.ZoneID, multiplier float64) error {
+	e := events.NewSurgeUpdated(zone, multiplier, events.WithTime(u.cfg.Now()),
+		events.WithMeta(events.Meta{ProducerInstance: u.cfg.Instance}))
+	if err := e.Validate(); err != nil {
+		return fmt.Errorf("surge: refusing to send invalid event: %w", err)
+	}
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &u.cfg.RideTopic, Partition: kafka.PartitionAny},
+		Key:            []byte(zone),
+		Value:          value,
+	}
+	for k, v := range e.Meta.Headers() {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	if err := u.sink.Produce(msg, nil); err != nil {
+		return err
+	}
+	updatesSent.Inc()
+	slog.Info("Surge updated", "zone", zone, "multiplier", multiplier)
+	return nil
+}
@@ -0,0 +1,110 @@
+package surge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/events"
+	"github.com/pedeveaux/kafkarideshare/pricing"
+	"github.com/pedeveaux/kafkarideshare/ridetest"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestMultiplier(t *testing.T) {
+	tests := []struct {
+		demand, supply int
+		want           float64
+	}{
+		{0, 0, 1},
+		{0, 5, 1},
+		{3, 5, 1},
+		{6, 5, 1.2},
+		{7, 3, 2.3},
+		{20, 2, 3},
+		{1, 0, 3},
+	}
+	for _, tt := range tests {
+		if got := Multiplier(tt.demand, tt.supply, 3); got != tt.want {
+			t.Errorf("Multiplier(%d, %d) = %v, want %v", tt.demand, tt.supply, got, tt.want)
+		}
+	}
+}
+
+func TestUpdater_SendsChangedMultipliers(t *testing.T) {
+	clock := now
+	broker := ridetest.NewBroker()
+	u := New(Config{Window: 5 * time.Minute, Instance: "surge-1", Now: func() time.Time { return clock }}, broker)
+	midtown := events.Coordinate{Lat: 40.755, Lng: -73.985}
+	downtown := events.Coordinate{Lat: 40.705, Lng: -74.005}
+	handle := func(topic string, v any) {
+		t.Helper()
+		if err := u.Handle(ridetest.Message(t, topic, v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	request := func(tripID string, at events.Coordinate) {
+		t.Helper()
+		handle(DefaultRideTopic, events.NewRideRequested(tripID, "rider-1", "A St", "B St",
+			events.WithTime(clock), events.WithCoordinates(&at, &downtown)))
+	}
+	heartbeat := func(driverID string, at events.Coordinate, available bool) {
+		t.Helper()
+		handle(DefaultDriverTopic, events.DriverEvent{ID: driverID + "-hb", DriverID: driverID, Type: events.EventHeartbeat,
+			OccurredAt: clock, Payload: events.HeartbeatPayload{Location: at, Available: available}})
+	}
+	update := func() map[events.ZoneID]float64 {
+		t.Helper()
+		n := len(broker.Messages(DefaultRideTopic))
+		if err := u.Update(); err != nil {
+			t.Fatal(err)
+		}
+		evts, err := broker.Events(DefaultRideTopic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent := make(map[events.ZoneID]float64)
+		for _, e := range evts[n:] {
+			p, ok := events.PayloadAs[events.SurgeUpdatedPayload](e)
+			if err := e.Validate(); err != nil || !ok || e.Meta.ProducerInstance != "surge-1" {
+				t.Fatalf("sent %+v: %v", e, err)
+			}
+			sent[p.ZoneID] = p.Multiplier
+		}
+		return sent
+	}
+
+	// Five requests in midtown, one of them redelivered, for two free drivers
+	// and one busy one; downtown has a driver and no requests
+	for _, id := range []string{"trip-1", "trip-2", "trip-3", "trip-4", "trip-4", "trip-5"} {
+		request(id, midtown)
+	}
+	heartbeat("driver-1", midtown, true)
+	heartbeat("driver-2", midtown, true)
+	heartbeat("driver-3", midtown, false)
+	heartbeat("driver-4", downtown, true)
+	sent := update()
+	if len(sent) != 1 || sent[pricing.ZoneOf(midtown)] != 2.5 {
+		t.Errorf("sent %v, want midtown at 2.5", sent)
+	}
+	if sent := update(); len(sent) != 0 {
+		t.Errorf("sent %v again with nothing changed", sent)
+	}
+
+	// Another driver frees up, so midtown drops to 5 requests for 3 drivers
+	clock = now.Add(time.Minute)
+	heartbeat("driver-3", midtown, true)
+	if sent := update(); sent[pricing.ZoneOf(midtown)] != 1.6 {
+		t.Errorf("sent %v, want midtown at 1.6", sent)
+	}
+
+	// Once the requests fall out of the window the surge ends
+	clock = now.Add(7 * time.Minute)
+	heartbeat("driver-1", midtown, true)
+	if sent := update(); len(sent) != 1 || sent[pricing.ZoneOf(midtown)] != 1 {
+		t.Errorf("sent %v, want midtown back at 1", sent)
+	}
+	if m := u.Multipliers(); len(m) != 1 || m[pricing.ZoneOf(midtown)] != 1 {
+		t.Errorf("multipliers %v, want only midtown's reporting driver", m)
+	}
+}
@@ -0,0 +1,220 @@
+package topics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Admin is the part of *kafka.AdminClient that Ensure uses.
+type Admin interface {
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+	CreateTopics(ctx context.Context, topics []kafka.TopicSpecification, options ...kafka.CreateTopicsAdminOption) ([]kafka.TopicResult, error)
+	CreatePartitions(ctx context.Context, partitions []kafka.PartitionsSpecification, options ...kafka.CreatePartitionsAdminOption) ([]kafka.TopicResult, error)
+	DescribeConfigs(ctx context.Context, resources []kafka.ConfigResource, options ...kafka.DescribeConfigsAdminOption) ([]kafka.ConfigResourceResult, error)
+	AlterConfigs(ctx context.Context, resources []kafka.ConfigResource, options ...kafka.AlterConfigsAdminOption) ([]kafka.ConfigResourceResult, error)
+}
+
+// Kinds of Change.
+const (
+	ChangeCreate     = "create"     // the topic was missing
+	ChangePartitions = "partitions" // the topic has fewer partitions than its Spec
+	ChangeConfig     = "config"     // settings of the topic differ from its Spec
+	ChangeMismatch   = "mismatch"   // the topic differs in a way Ensure cannot change
+)
+
+// Change is a difference Ensure found between a topic and its Spec, and
+// applied unless it is a ChangeMismatch or the Options say otherwise.
+type Change struct {
+	Topic   string
+	Kind    string
+	Detail  string
+	Applied bool
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s: %s", c.Kind, c.Topic, c.Detail)
+}
+
+// Options control what Ensure changes.
+type Options struct {
+	// DryRun reports the changes without applying any.
+	DryRun bool
+	// AddPartitions grows topics with fewer partitions than their Spec.
+	// Adding partitions moves keys to other partitions, which breaks the
+	// per-trip ordering of messages already in flight, so it is opt-in;
+	// partitions can never be removed.
+	AddPartitions bool
+	// Timeout bounds the metadata request; default 10s.
+	Timeout time.Duration
+}
+
+// Ensure makes the topics of specs exist with their settings: it creates the
+// missing ones and sets the configs of existing ones that differ, keeping the
+// other settings already made on them. Differences it cannot make, such as in
+// replication factor or fewer partitions, are returned as ChangeMismatch.
+func Ensure(ctx context.Context, admin Admin, specs []Spec, opts Options) ([]Change, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	md, err := admin.GetMetadata(nil, true, int(opts.Timeout.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("topics: get metadata: %w", err)
+	}
+
+	var changes []Change
+	var create []kafka.TopicSpecification
+	var grow []kafka.PartitionsSpecification
+	var existing []Spec
+	for _, s := range specs {
+		tm, ok := md.Topics[s.Name]
+		if !ok || tm.Error.Code() == kafka.ErrUnknownTopicOrPart {
+			changes = append(changes, Change{Topic: s.Name, Kind: ChangeCreate, Detail: describe(s)})
+			create = append(create, kafka.TopicSpecification{
+				Topic:             s.Name,
+				NumPartitions:     s.Partitions,
+				ReplicationFactor: s.ReplicationFactor,
+				Config:            s.Config,
+			})
+			continue
+		}
+		existing = append(existing, s)
+		switch n := len(tm.Partitions); {
+		case n < s.Partitions && opts.AddPartitions:
+			changes = append(changes, Change{Topic: s.Name, Kind: ChangePartitions, Detail: fmt.Sprintf("%d to %d partitions", n, s.Partitions)})
+			grow = append(grow, kafka.PartitionsSpecification{Topic: s.Name, IncreaseTo: s.Partitions})
+		case n != s.Partitions:
+			changes = append(changes, Change{Topic: s.Name, Kind: ChangeMismatch, Detail: fmt.Sprintf("has %d partitions, want %d", n, s.Partitions)})
+		}
+		if n := len(tm.Partitions); n > 0 && len(tm.Partitions[0].Replicas) != s.ReplicationFactor {
+			changes = append(changes, Change{Topic: s.Name, Kind: ChangeMismatch, Detail: fmt.Sprintf("has replication factor %d, want %d", len(tm.Partitions[0].Replicas), s.ReplicationFactor)})
+		}
+	}
+
+	alter, configChanges, err := configChanges(ctx, admin, existing)
+	if err != nil {
+		return changes, err
+	}
+	changes = append(changes, configChanges...)
+	if opts.DryRun {
+		return changes, nil
+	}
+
+	var errs []error
+	failed := map[string]bool{}
+	fail := func(topic string, err error) {
+		failed[topic] = true
+		errs = append(errs, err)
+	}
+	if len(create) > 0 {
+		results, err := admin.CreateTopics(ctx, create)
+		for _, t := range create {
+			checkResult(results, err, t.Topic, "create", fail)
+		}
+	}
+	if len(grow) > 0 {
+		results, err := admin.CreatePartitions(ctx, grow)
+		for _, p := range grow {
+			checkResult(results, err, p.Topic, "add partitions to", fail)
+		}
+	}
+	if len(alter) > 0 {
+		results, err := admin.AlterConfigs(ctx, alter)
+		for _, r := range alter {
+			switch i := slices.IndexFunc(results, func(res kafka.ConfigResourceResult) bool { return res.Name == r.Name }); {
+			case err != nil:
+				fail(r.Name, fmt.Errorf("topics: alter config of %s: %w", r.Name, err))
+			case i >= 0 && results[i].Error.Code() != kafka.ErrNoError:
+				fail(r.Name, fmt.Errorf("topics: alter config of %s: %w", r.Name, results[i].Error))
+			}
+		}
+	}
+	for i, c := range changes {
+		changes[i].Applied = c.Kind != ChangeMismatch && !failed[c.Topic]
+	}
+	return changes, errors.Join(errs...)
+}
+
+// configChanges compares the configs of specs, all existing topics, with
+// theirs. It returns the resources to alter, each with the topic's own
+// settings and those of its Spec, as AlterConfigs resets any it is not given.
+func configChanges(ctx context.Context, admin Admin, specs []Spec) ([]kafka.ConfigResource, []Change, error) {
+	if len(specs) == 0 {
+		return nil, nil, nil
+	}
+	resources := make([]kafka.ConfigResource, len(specs))
+	for i, s := range specs {
+		resources[i] = kafka.ConfigResource{Type: kafka.ResourceTopic, Name: s.Name}
+	}
+	results, err := admin.DescribeConfigs(ctx, resources)
+	if err != nil {
+		return nil, nil, fmt.Errorf("topics: describe configs: %w", err)
+	}
+	byName := make(map[string]kafka.ConfigResourceResult, len(results))
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+
+	var alter []kafka.ConfigResource
+	var changes []Change
+	for _, s := range specs {
+		r, ok := byName[s.Name]
+		if !ok || r.Error.Code() != kafka.ErrNoError {
+			return nil, nil, fmt.Errorf("topics: describe config of %s: %v", s.Name, r.Error)
+		}
+		var diffs []string
+		for _, key := range slices.Sorted(maps.Keys(s.Config)) {
+			if have := r.Config[key].Value; have != s.Config[key] {
+				diffs = append(diffs, fmt.Sprintf("%s %q to %q", key, have, s.Config[key]))
+			}
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+		changes = append(changes, Change{Topic: s.Name, Kind: ChangeConfig, Detail: strings.Join(diffs, ", ")})
+		set := map[string]string{}
+		for name, entry := range r.Config {
+			if entry.Source == kafka.ConfigSourceDynamicTopic {
+				set[name] = entry.Value
+			}
+		}
+		maps.Copy(set, s.Config)
+		alter = append(alter, kafka.ConfigResource{
+			Type:   kafka.ResourceTopic,
+			Name:   s.Name,
+			Config: kafka.StringMapToConfigEntries(set, kafka.AlterOperationSet),
+		})
+	}
+	return alter, changes, nil
+}
+
+// checkResult reports to fail whether the request for topic failed, as a
+// whole with err or in its result, treating a topic created meanwhile as a
+// success.
+func checkResult(results []kafka.TopicResult, err error, topic, action string, fail func(string, error)) {
+	if err != nil {
+		fail(topic, fmt.Errorf("topics: %s %s: %w", action, topic, err))
+		return
+	}
+	for _, r := range results {
+		if code := r.Error.Code(); r.Topic == topic && code != kafka.ErrNoError && code != kafka.ErrTopicAlreadyExists {
+			fail(topic, fmt.Errorf("topics: %s %s: %w", action, topic, r.Error))
+		}
+	}
+}
+
+// describe returns the settings of s, such as
+// "3 partitions, replication factor 1, cleanup.policy=compact".
+func describe(s Spec) string {
+	parts := []string{fmt.Sprintf("%d partitions, replication factor %d", s.Partitions, s.ReplicationFactor)}
+	for _, key := range slices.Sorted(maps.Keys(s.Config)) {
+		parts = append(parts, key+"="+s.Config[key])
+	}
+	return strings.Join(parts, ", ")
+}
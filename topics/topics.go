@@ -0,0 +1,198 @@
+// Package topics declares the Kafka topics the ride services use and creates
+// them, with their partitions, replication, retention, and compaction, rather
+// than leaving them to the broker's auto-create defaults.
+package topics
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedeveaux/kafkarideshare/rideconsumer"
+)
+
+// Names of the topics the services use, beyond the retry tiers of
+// rideconsumer.DefaultRetryTiers.
+const (
+	RideEvents    = "ride-events"
+	DriverEvents  = "driver-events"
+	PaymentEvents = "payment-events"
+	RideEventsDLQ = "ride-events-dlq"
+	// DispatchOffers carries the matcher's ride offers to drivers, and the
+	// offers drivers decline back to the matcher.
+	DispatchOffers = "dispatch-offers"
+	// FraudAlerts carries the fraud.Alert of rides that look wrong.
+	FraudAlerts = "fraud-alerts"
+	// RideState is compacted: it keeps the latest message of each key, the
+	// current state of each trip, rather than expiring messages by age.
+	RideState = "ride-state"
+	// RideEventsByDriver carries the ride events of assigned rides keyed by
+	// their driver, for the processors that keep per-driver state.
+	RideEventsByDriver = "ride-events-by-driver"
+	// DriverStats is compacted like RideState, keeping each driver's stats.
+	DriverStats = "driver-stats"
+	// RideAggregates carries the metrics of each finished trip, written
+	// once by a transactional processor.
+	RideAggregates = "ride-aggregates"
+)
+
+// Defaults of the topic settings, which TOPIC_PARTITIONS,
+// TOPIC_REPLICATION_FACTOR, and TOPIC_RETENTION_HOURS override.
+const (
+	DefaultPartitions        = 3
+	DefaultReplicationFactor = 1
+	DefaultRetention         = 7 * 24 * time.Hour
+)
+
+// The dead-letter topic and fraud alerts keep messages longer than the event
+// topics, so there is time to investigate them, and retry tiers shorter, as
+// their messages are consumed within their tier's delay.
+const (
+	dlqRetention   = 30 * 24 * time.Hour
+	retryRetention = 24 * time.Hour
+	offerRetention = 24 * time.Hour
+	// repartitionRetention keeps re-keyed events long enough for their
+	// processor to catch up after a day down.
+	repartitionRetention = 24 * time.Hour
+)
+
+// Spec is a topic as it should be. Config holds topic-level settings, such as
+// retention.ms and cleanup.policy.
+type Spec struct {
+	Name              string
+	Partitions        int
+	ReplicationFactor int
+	Config            map[string]string
+}
+
+// Settings are the defaults Specs builds topics from.
+type Settings struct {
+	Partitions        int
+	ReplicationFactor int
+	Retention         time.Duration
+}
+
+// DefaultSettings are the Settings without any environment overrides.
+var DefaultSettings = Settings{
+	Partitions:        DefaultPartitions,
+	ReplicationFactor: DefaultReplicationFactor,
+	Retention:         DefaultRetention,
+}
+
+// Specs returns the topics the services need: the ride, driver, and payment
+// event topics, kept for s.Retention; the dead-letter topic, with a single
+// partition; the retry tiers; the dispatch offers, kept for a day; the fraud
+// alerts, kept as long as the dead letters; the ride events by driver, kept
+// for a day; the ride aggregates, kept like the events; and the compacted
+// RideState and DriverStats.
+func Specs(s Settings) []Spec {
+	deleteAfter := func(d time.Duration) map[string]string {
+		return map[string]string{
+			"cleanup.policy": "delete",
+			"retention.ms":   strconv.FormatInt(d.Milliseconds(), 10),
+		}
+	}
+	specs := []Spec{
+		{Name: RideEvents, Partitions: s.Partitions, ReplicationFactor: s.ReplicationFactor, Config: deleteAfter(s.Retention)},
+		{Name: DriverEvents, Partitions: s.Partitions, ReplicationFactor: s.ReplicationFactor, Config: deleteAfter(s.Retention)},
+		{Name: PaymentEvents, Partitions: s.Partitions, ReplicationFactor: s.ReplicationFactor, Config: deleteAfter(s.Retention)},
+		{Name: RideEventsDLQ, Partitions: 1, ReplicationFactor: s.ReplicationFactor, Config: deleteAfter(dlqRetention)},
+	}
+	for _, tier := range rideconsumer.DefaultRetryTiers {
+		specs = append(specs, Spec{Name: tier.Topic, Partitions: s.Partitions, ReplicationFactor: s.ReplicationFactor, Config: deleteAfter(retryRetention)})
+	}
+	specs = append(specs,
+		Spec{Name: DispatchOffers, Partitions: s.Partitions, ReplicationFactor: s.ReplicationFactor, Config: deleteAfter(offerRetention)},
+		Spec{Name: FraudAlerts, Partitions: s.Partitions, ReplicationFactor: s.ReplicationFactor, Config: deleteAfter(dlqRetention)},
+		Spec{Name: RideEventsByDriver, Partitions: s.Partitions, ReplicationFactor: s.ReplicationFactor, Config: deleteAfter(repartitionRetention)},
+		Spec{Name: RideAggregates, Partitions: s.Partitions, ReplicationFactor: s.ReplicationFactor, Config: deleteAfter(s.Retention)},
+	)
+	for _, name := range []string{RideState, DriverStats} {
+		specs = append(specs, Spec{
+			Name:              name,
+			Partitions:        s.Partitions,
+			ReplicationFactor: s.ReplicationFactor,
+			Config:            map[string]string{"cleanup.policy": "compact"},
+		})
+	}
+	return specs
+}
+
+// SpecsFromEnv returns Specs with the settings of TOPIC_PARTITIONS,
+// TOPIC_REPLICATION_FACTOR, and TOPIC_RETENTION_HOURS, then the per-topic
+// overrides of TOPIC_OVERRIDES (see Override).
+func SpecsFromEnv() ([]Spec, error) {
+	s := DefaultSettings
+	for key, set := range map[string]func(int){
+		"TOPIC_PARTITIONS":         func(n int) { s.Partitions = n },
+		"TOPIC_REPLICATION_FACTOR": func(n int) { s.ReplicationFactor = n },
+		"TOPIC_RETENTION_HOURS":    func(n int) { s.Retention = time.Duration(n) * time.Hour },
+	} {
+		if raw := os.Getenv(key); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid %s %q", key, raw)
+			}
+			set(n)
+		}
+	}
+	return Override(Specs(s), os.Getenv("TOPIC_OVERRIDES"))
+}
+
+// Override returns specs with the settings of spec applied. spec is a
+// semicolon-separated list of topic:key=value,... entries, such as
+// "ride-events:partitions=12,retention.ms=86400000;ride-state:min.compaction.lag.ms=60000".
+// The keys partitions and replication.factor set those of the topic; any other
+// key is a topic config.
+func Override(specs []Spec, spec string) ([]Spec, error) {
+	out := make([]Spec, len(specs))
+	for i, s := range specs {
+		s.Config = maps.Clone(s.Config)
+		out[i] = s
+	}
+	for _, entry := range strings.Split(spec, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, settings, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		i := indexOf(out, name)
+		if !ok || i < 0 {
+			return nil, fmt.Errorf("topics: %q does not override a known topic", entry)
+		}
+		for _, pair := range strings.Split(settings, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if !ok || key == "" || value == "" {
+				return nil, fmt.Errorf("topics: %q is not key=value", pair)
+			}
+			switch key {
+			case "partitions", "replication.factor":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("topics: invalid %s %q for %s", key, value, name)
+				}
+				if key == "partitions" {
+					out[i].Partitions = n
+				} else {
+					out[i].ReplicationFactor = n
+				}
+			default:
+				out[i].Config[key] = value
+			}
+		}
+	}
+	return out, nil
+}
+
+func indexOf(specs []Spec, name string) int {
+	for i, s := range specs {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,258 @@
+package topics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+func TestSpecs(t *testing.T) {
+	specs := Specs(DefaultSettings)
+	var names []string
+	for _, s := range specs {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "ride-events,driver-events,payment-events,ride-events-dlq,ride-events-retry-5s,ride-events-retry-1m,dispatch-offers,fraud-alerts,ride-events-by-driver,ride-aggregates,ride-state,driver-stats" {
+		t.Errorf("topics = %s", got)
+	}
+	if s := specs[0]; s.Partitions != 3 || s.ReplicationFactor != 1 || s.Config["retention.ms"] != "604800000" || s.Config["cleanup.policy"] != "delete" {
+		t.Errorf("ride-events = %+v", s)
+	}
+	if s := specs[3]; s.Partitions != 1 || s.Config["retention.ms"] != "2592000000" {
+		t.Errorf("dlq = %+v", s)
+	}
+	for _, s := range specs[len(specs)-2:] {
+		if s.Config["cleanup.policy"] != "compact" || s.Config["retention.ms"] != "" {
+			t.Errorf("%s = %+v", s.Name, s)
+		}
+	}
+}
+
+func TestSpecsFromEnv(t *testing.T) {
+	t.Setenv("TOPIC_PARTITIONS", "12")
+	t.Setenv("TOPIC_REPLICATION_FACTOR", "3")
+	t.Setenv("TOPIC_RETENTION_HOURS", "24")
+	t.Setenv("TOPIC_OVERRIDES", " ride-state:min.compaction.lag.ms=60000 ; payment-events:partitions=6, retention.ms=1000;")
+	specs, err := SpecsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]Spec{}
+	for _, s := range specs {
+		byName[s.Name] = s
+	}
+	if s := byName[RideEvents]; s.Partitions != 12 || s.ReplicationFactor != 3 || s.Config["retention.ms"] != "86400000" {
+		t.Errorf("ride-events = %+v", s)
+	}
+	if s := byName[PaymentEvents]; s.Partitions != 6 || s.Config["retention.ms"] != "1000" {
+		t.Errorf("payment-events = %+v", s)
+	}
+	if s := byName[RideState]; s.Config["min.compaction.lag.ms"] != "60000" || s.Config["cleanup.policy"] != "compact" {
+		t.Errorf("ride-state = %+v", s)
+	}
+
+	for _, bad := range []string{"rides:partitions=2", "ride-events", "ride-events:partitions", "ride-events:partitions=0", "ride-events:replication.factor=x"} {
+		if _, err := Override(Specs(DefaultSettings), bad); err == nil {
+			t.Errorf("Override(%q): expected an error", bad)
+		}
+	}
+	t.Setenv("TOPIC_OVERRIDES", "")
+	t.Setenv("TOPIC_PARTITIONS", "0")
+	if _, err := SpecsFromEnv(); err == nil {
+		t.Error("expected an error for 0 partitions")
+	}
+}
+
+func TestOverride_DoesNotShareConfig(t *testing.T) {
+	specs := Specs(DefaultSettings)
+	if _, err := Override(specs, "ride-events:retention.ms=1"); err != nil {
+		t.Fatal(err)
+	}
+	if specs[0].Config["retention.ms"] != "604800000" {
+		t.Error("expected Override to leave its input alone")
+	}
+}
+
+// fakeAdmin is a cluster of topics with their partition counts, replication
+// factors, and configs.
+type fakeAdmin struct {
+	partitions map[string]int
+	replicas   map[string]int
+	configs    map[string]map[string]kafka.ConfigEntryResult
+	failCreate string
+
+	created []kafka.TopicSpecification
+	grown   []kafka.PartitionsSpecification
+	altered []kafka.ConfigResource
+}
+
+func (f *fakeAdmin) GetMetadata(*string, bool, int) (*kafka.Metadata, error) {
+	md := &kafka.Metadata{Topics: map[string]kafka.TopicMetadata{}}
+	for name, n := range f.partitions {
+		tm := kafka.TopicMetadata{Topic: name}
+		for i := range n {
+			tm.Partitions = append(tm.Partitions, kafka.PartitionMetadata{ID: int32(i), Replicas: make([]int32, f.replicas[name])})
+		}
+		md.Topics[name] = tm
+	}
+	return md, nil
+}
+
+func (f *fakeAdmin) CreateTopics(_ context.Context, topics []kafka.TopicSpecification, _ ...kafka.CreateTopicsAdminOption) ([]kafka.TopicResult, error) {
+	f.created = append(f.created, topics...)
+	var results []kafka.TopicResult
+	for _, t := range topics {
+		r := kafka.TopicResult{Topic: t.Topic}
+		if t.Topic == f.failCreate {
+			r.Error = kafka.NewError(kafka.ErrInvalidReplicationFactor, "replication factor larger than available brokers", false)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (f *fakeAdmin) CreatePartitions(_ context.Context, ps []kafka.PartitionsSpecification, _ ...kafka.CreatePartitionsAdminOption) ([]kafka.TopicResult, error) {
+	f.grown = append(f.grown, ps...)
+	return nil, nil
+}
+
+func (f *fakeAdmin) DescribeConfigs(_ context.Context, rs []kafka.ConfigResource, _ ...kafka.DescribeConfigsAdminOption) ([]kafka.ConfigResourceResult, error) {
+	var results []kafka.ConfigResourceResult
+	for _, r := range rs {
+		results = append(results, kafka.ConfigResourceResult{Type: r.Type, Name: r.Name, Config: f.configs[r.Name]})
+	}
+	return results, nil
+}
+
+func (f *fakeAdmin) AlterConfigs(_ context.Context, rs []kafka.ConfigResource, _ ...kafka.AlterConfigsAdminOption) ([]kafka.ConfigResourceResult, error) {
+	f.altered = append(f.altered, rs...)
+	return nil, nil
+}
+
+func entry(value string, source kafka.ConfigSource) kafka.ConfigEntryResult {
+	return kafka.ConfigEntryResult{Value: value, Source: source}
+}
+
+// cluster returns a fake with ride-events in place but with the wrong
+// retention and fewer partitions, and ride-state as specified.
+func cluster() *fakeAdmin {
+	return &fakeAdmin{
+		partitions: map[string]int{RideEvents: 1, RideState: 3},
+		replicas:   map[string]int{RideEvents: 1, RideState: 1},
+		configs: map[string]map[string]kafka.ConfigEntryResult{
+			RideEvents: {
+				"cleanup.policy":         entry("delete", kafka.ConfigSourceDefault),
+				"retention.ms":           entry("3600000", kafka.ConfigSourceDynamicTopic),
+				"max.message.bytes":      entry("2000000", kafka.ConfigSourceDynamicTopic),
+				"segment.ms":             entry("604800000", kafka.ConfigSourceDefault),
+				"message.timestamp.type": entry("LogAppendTime", kafka.ConfigSourceDynamicTopic),
+			},
+			RideState: {"cleanup.policy": entry("compact", kafka.ConfigSourceDynamicTopic)},
+		},
+	}
+}
+
+func specsFor(names ...string) []Spec {
+	var out []Spec
+	for _, s := range Specs(DefaultSettings) {
+		for _, n := range names {
+			if s.Name == n {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func TestEnsure(t *testing.T) {
+	admin := cluster()
+	changes, err := Ensure(context.Background(), admin, specsFor(RideEvents, DriverEvents, RideState), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range changes {
+		if !c.Applied && c.Kind != ChangeMismatch {
+			t.Errorf("expected %s to be applied", c)
+		}
+		got = append(got, c.String())
+	}
+	want := []string{
+		"mismatch ride-events: has 1 partitions, want 3",
+		"create driver-events: 3 partitions, replication factor 1, cleanup.policy=delete, retention.ms=604800000",
+		`config ride-events: retention.ms "3600000" to "604800000"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if len(admin.created) != 1 || admin.created[0].Topic != DriverEvents || admin.created[0].NumPartitions != 3 {
+		t.Errorf("created %+v", admin.created)
+	}
+	if len(admin.grown) != 0 {
+		t.Errorf("expected partitions not to be added without AddPartitions, got %+v", admin.grown)
+	}
+	if len(admin.altered) != 1 {
+		t.Fatalf("altered %+v", admin.altered)
+	}
+	set := map[string]string{}
+	for _, e := range admin.altered[0].Config {
+		set[e.Name] = e.Value
+	}
+	// The topic's own settings are kept, as AlterConfigs resets the rest
+	if len(set) != 4 || set["retention.ms"] != "604800000" || set["cleanup.policy"] != "delete" ||
+		set["max.message.bytes"] != "2000000" || set["message.timestamp.type"] != "LogAppendTime" {
+		t.Errorf("altered config = %v", set)
+	}
+}
+
+func TestEnsure_AddPartitions(t *testing.T) {
+	admin := cluster()
+	changes, err := Ensure(context.Background(), admin, specsFor(RideEvents), Options{AddPartitions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(admin.grown) != 1 || admin.grown[0].IncreaseTo != 3 || changes[0].String() != "partitions ride-events: 1 to 3 partitions" {
+		t.Errorf("grown %+v, changes %v", admin.grown, changes)
+	}
+}
+
+func TestEnsure_DryRun(t *testing.T) {
+	admin := cluster()
+	changes, err := Ensure(context.Background(), admin, Specs(DefaultSettings), Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 12 { // 10 to create, and 2 on ride-events
+		t.Errorf("changes = %v", changes)
+	}
+	for _, c := range changes {
+		if c.Applied {
+			t.Errorf("expected nothing to be applied, got %s", c)
+		}
+	}
+	if len(admin.created)+len(admin.grown)+len(admin.altered) != 0 {
+		t.Error("expected a dry run to change nothing")
+	}
+}
+
+func TestEnsure_Errors(t *testing.T) {
+	admin := cluster()
+	admin.failCreate = PaymentEvents
+	admin.replicas[RideState] = 3
+	changes, err := Ensure(context.Background(), admin, specsFor(DriverEvents, PaymentEvents, RideState), Options{Timeout: time.Second})
+	if err == nil || !strings.Contains(err.Error(), "create payment-events") {
+		t.Errorf("expected the failed creation to be returned, got %v", err)
+	}
+	applied := map[string]bool{}
+	for _, c := range changes {
+		applied[c.Topic+" "+c.Kind] = c.Applied
+	}
+	if !applied["driver-events create"] || applied["payment-events create"] || applied["ride-state mismatch"] {
+		t.Errorf("applied = %v", applied)
+	}
+}